@@ -0,0 +1,230 @@
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/awaisamjad/volk/internal/http"
+)
+
+// Cache stores responses by URL.
+type Cache interface {
+	// Get returns the response stored for the key, if any.
+	Get(key string) (Response, bool)
+	// Set stores the response for the key.
+	Set(key string, resp Response) error
+}
+
+// MemoryCache is a Cache that keeps responses in memory.
+type MemoryCache struct {
+	mu        sync.Mutex
+	responses map[string]Response
+}
+
+// NewMemoryCache creates an empty MemoryCache.
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{responses: make(map[string]Response)}
+}
+
+// Get returns the response stored for the key, if any.
+func (c *MemoryCache) Get(key string) (Response, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	resp, ok := c.responses[key]
+	return resp, ok
+}
+
+// Set stores the response for the key.
+func (c *MemoryCache) Set(key string, resp Response) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.responses[key] = resp
+	return nil
+}
+
+// DiskCache is a Cache that stores each response as a JSON file in a directory,
+// so that it survives between runs of the program.
+type DiskCache struct {
+	Dir string
+}
+
+// Get returns the response stored for the key, if any. Unreadable entries are treated as missing.
+func (c *DiskCache) Get(key string) (Response, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return Response{}, false
+	}
+	var entry CassetteResponse
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return Response{}, false
+	}
+	return entry.Response(), true
+}
+
+// Set stores the response for the key.
+func (c *DiskCache) Set(key string, resp Response) error {
+	data, err := json.Marshal(newCassetteResponse(resp))
+	if err != nil {
+		return fmt.Errorf("error encoding cache entry: %w", err)
+	}
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return fmt.Errorf("error creating cache directory: %w", err)
+	}
+
+	// Write to a temporary file first so that readers never see a partial entry.
+	tmp, err := os.CreateTemp(c.Dir, ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("error writing cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing cache entry: %w", err)
+	}
+	tmp.Close()
+	if err := os.Rename(tmp.Name(), c.path(key)); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing cache entry: %w", err)
+	}
+	return nil
+}
+
+// path returns the file the entry for the key is stored in.
+func (c *DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// CachingTransport is a Transport that stores GET responses carrying an ETag or
+// Last-Modified validator and revalidates them on the next request with
+// If-None-Match and If-Modified-Since. When the server answers 304 Not Modified,
+// the stored response is returned, so a repeated download costs one round trip
+// without a body.
+type CachingTransport struct {
+	// Transport sends the requests. If nil, DefaultTransport is used.
+	Transport Transport
+	// Cache stores the responses.
+	Cache Cache
+}
+
+// NewCachingTransport creates a CachingTransport that sends requests with
+// transport, or DefaultTransport if it is nil, and stores responses in cache.
+func NewCachingTransport(transport Transport, cache Cache) *CachingTransport {
+	return &CachingTransport{Transport: transport, Cache: cache}
+}
+
+// RoundTrip sends the request, revalidating a stored response if there is one.
+func (t *CachingTransport) RoundTrip(req *Request) (Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = DefaultTransport
+	}
+
+	if !cacheable(req) {
+		return transport.RoundTrip(req)
+	}
+
+	key := req.URL.String()
+	cached, ok := t.Cache.Get(key)
+
+	outgoing := req
+	if ok {
+		outgoing = withValidators(req, cached)
+	}
+
+	resp, err := transport.RoundTrip(outgoing)
+	if err != nil {
+		return resp, err
+	}
+
+	if ok && resp.GetStatusCode() == 304 {
+		cached.Headers = mergeHeaders(cached.Headers, resp.Headers)
+		if err := t.Cache.Set(key, cached); err != nil {
+			return cached, err
+		}
+		return cached, nil
+	}
+
+	if resp.GetStatusCode() == 200 && hasValidator(resp) && !noStore(resp) {
+		if err := t.Cache.Set(key, resp); err != nil {
+			return resp, err
+		}
+	}
+	return resp, nil
+}
+
+// cacheable reports whether the response to the request may be stored and revalidated.
+// Requests that carry their own validators or a Range are passed through untouched.
+func cacheable(req *Request) bool {
+	if req.Method != http.GET {
+		return false
+	}
+	for _, name := range []string{"If-None-Match", "If-Modified-Since", "Range"} {
+		if _, ok := req.Header(name); ok {
+			return false
+		}
+	}
+	return true
+}
+
+// withValidators returns a copy of the request with conditional headers for the cached response.
+func withValidators(req *Request, cached Response) *Request {
+	conditional := *req
+	conditional.Headers = append([]Header(nil), req.Headers...)
+	if etag, ok := http.GetHeader(cached.Headers, "ETag"); ok {
+		conditional.SetHeader("If-None-Match", etag)
+	}
+	if lastModified, ok := http.GetHeader(cached.Headers, "Last-Modified"); ok {
+		conditional.SetHeader("If-Modified-Since", lastModified)
+	}
+	return &conditional
+}
+
+// hasValidator reports whether the response has an ETag or Last-Modified header.
+func hasValidator(resp Response) bool {
+	if _, ok := http.GetHeader(resp.Headers, "ETag"); ok {
+		return true
+	}
+	_, ok := http.GetHeader(resp.Headers, "Last-Modified")
+	return ok
+}
+
+// noStore reports whether the response forbids caching with Cache-Control: no-store.
+func noStore(resp Response) bool {
+	cacheControl, _ := http.GetHeader(resp.Headers, "Cache-Control")
+	for _, directive := range strings.Split(cacheControl, ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeHeaders updates the stored headers with those of a 304 response, as
+// required by RFC 9111 section 4.3.4. Content-Length is kept from the stored response.
+func mergeHeaders(stored, updated []Header) []Header {
+	merged := append([]Header(nil), stored...)
+	for _, header := range updated {
+		if strings.EqualFold(header.Name, "Content-Length") {
+			continue
+		}
+		replaced := false
+		for i := range merged {
+			if strings.EqualFold(merged[i].Name, header.Name) {
+				merged[i].Value = header.Value
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, header)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"testing"
+
+	"github.com/awaisamjad/volk/internal/http"
+)
+
+// etagTransport serves a body with an ETag and answers 304 when the request's
+// If-None-Match matches it.
+type etagTransport struct {
+	etag     string
+	body     string
+	requests []*Request
+}
+
+func (t *etagTransport) RoundTrip(req *Request) (Response, error) {
+	t.requests = append(t.requests, req)
+	if match, ok := req.Header("If-None-Match"); ok && match == t.etag {
+		return Response{
+			StartLine: http.ResponseStartLine{Protocol: http.HTTP1_1, StatusCode: 304, StatusText: "Not Modified"},
+			Headers:   []Header{{Name: "ETag", Value: t.etag}, {Name: "Date", Value: "later"}},
+		}, nil
+	}
+	return Response{
+		StartLine: http.ResponseStartLine{Protocol: http.HTTP1_1, StatusCode: 200, StatusText: "OK"},
+		Headers:   []Header{{Name: "ETag", Value: t.etag}, {Name: "Date", Value: "earlier"}},
+		Body:      t.body,
+	}, nil
+}
+
+func TestCachingTransport(t *testing.T) {
+	caches := map[string]Cache{
+		"memory": NewMemoryCache(),
+		"disk":   &DiskCache{Dir: t.TempDir()},
+	}
+
+	for name, cache := range caches {
+		t.Run(name, func(t *testing.T) {
+			network := &etagTransport{etag: `"v1"`, body: "large file"}
+			c := &Client{Transport: NewCachingTransport(network, cache)}
+
+			for i := 0; i < 2; i++ {
+				resp, err := c.Get("http://example.com/file")
+				if err != nil {
+					t.Fatalf("Get returned an error: %v", err)
+				}
+				if resp.GetStatusCode() != 200 || resp.GetBody() != "large file" {
+					t.Errorf("Expected the full response on request %d, got %d %q", i+1, resp.GetStatusCode(), resp.GetBody())
+				}
+			}
+
+			if _, ok := network.requests[0].Header("If-None-Match"); ok {
+				t.Errorf("Expected no If-None-Match on the first request")
+			}
+			if match, _ := network.requests[1].Header("If-None-Match"); match != `"v1"` {
+				t.Errorf("Expected If-None-Match %q on the second request, got %q", `"v1"`, match)
+			}
+
+			cached, _ := cache.Get("http://example.com/file")
+			if date, _ := http.GetHeader(cached.Headers, "Date"); date != "later" {
+				t.Errorf("Expected the stored headers to be updated from the 304, got Date %q", date)
+			}
+
+			network.etag = `"v2"`
+			network.body = "new file"
+			resp, _ := c.Get("http://example.com/file")
+			if resp.GetBody() != "new file" {
+				t.Errorf("Expected the changed file after the ETag changed, got %q", resp.GetBody())
+			}
+		})
+	}
+}
+
+func TestCachingTransportSkipsUncacheable(t *testing.T) {
+	cache := NewMemoryCache()
+	network := &etagTransport{etag: `"v1"`, body: "data"}
+	c := &Client{Transport: NewCachingTransport(network, cache)}
+
+	if _, err := c.Post("http://example.com/form", "text/plain", "x"); err != nil {
+		t.Fatalf("Post returned an error: %v", err)
+	}
+	if _, ok := cache.Get("http://example.com/form"); ok {
+		t.Errorf("Expected POST responses not to be cached")
+	}
+
+	req, _ := NewRequest(http.GET, "http://example.com/range", "")
+	req.SetHeader("Range", "bytes=0-1")
+	if _, err := c.Do(req); err != nil {
+		t.Fatalf("Do returned an error: %v", err)
+	}
+	if _, ok := cache.Get("http://example.com/range"); ok {
+		t.Errorf("Expected Range responses not to be cached")
+	}
+}
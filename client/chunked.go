@@ -0,0 +1,123 @@
+package client
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/awaisamjad/volk/internal/http"
+)
+
+// ErrInvalidChunk is returned when a chunked response body is malformed.
+var ErrInvalidChunk = errors.New("invalid chunk in chunked response body")
+
+// maxChunkLine is the longest chunk size line or trailer field accepted.
+const maxChunkLine = 4096
+
+// chunked reports whether the body of resp is sent with the chunked transfer
+// coding, which then is its last coding.
+func chunked(resp Response) bool {
+	value, ok := http.GetHeader(resp.Headers, "Transfer-Encoding")
+	if !ok {
+		return false
+	}
+	codings := strings.Split(value, ",")
+	return strings.EqualFold(strings.TrimSpace(codings[len(codings)-1]), "chunked")
+}
+
+// chunkedReader decodes a chunked body (RFC 9112 section 7.1) read from r.
+// The trailer section is read and dropped, so r is left at the end of the
+// response.
+type chunkedReader struct {
+	r         *bufio.Reader
+	remaining int64 // Bytes left in the current chunk
+	needCRLF  bool  // The current chunk is read, but not the line break ending it
+	err       error // Returned by every Read once set, io.EOF after the last chunk
+}
+
+func newChunkedReader(r *bufio.Reader) *chunkedReader {
+	return &chunkedReader{r: r}
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	if c.remaining == 0 {
+		if c.err = c.nextChunk(); c.err != nil {
+			return 0, c.err
+		}
+	}
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+	if c.remaining == 0 {
+		c.needCRLF = true
+	}
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		c.err = err
+	}
+	return n, err
+}
+
+// nextChunk reads up to the data of the next chunk, or through the trailer
+// section after the last one, returning io.EOF.
+func (c *chunkedReader) nextChunk() error {
+	if c.needCRLF {
+		line, err := c.readLine()
+		if err != nil {
+			return err
+		}
+		if line != "" {
+			return fmt.Errorf("%w: missing line break after chunk data", ErrInvalidChunk)
+		}
+		c.needCRLF = false
+	}
+
+	line, err := c.readLine()
+	if err != nil {
+		return err
+	}
+	sizeField, _, _ := strings.Cut(line, ";")
+	size, err := strconv.ParseInt(strings.TrimSpace(sizeField), 16, 64)
+	if err != nil || size < 0 {
+		return fmt.Errorf("%w: chunk size %q", ErrInvalidChunk, sizeField)
+	}
+	if size > 0 {
+		c.remaining = size
+		return nil
+	}
+
+	for {
+		trailer, err := c.readLine()
+		if err != nil {
+			return err
+		}
+		if trailer == "" {
+			return io.EOF
+		}
+	}
+}
+
+// readLine reads a line without its line break.
+func (c *chunkedReader) readLine() (string, error) {
+	line, err := c.r.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) || len(line) > maxChunkLine {
+		return "", fmt.Errorf("%w: line too long", ErrInvalidChunk)
+	}
+	if err == io.EOF {
+		return "", io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading chunk: %w", err)
+	}
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
@@ -0,0 +1,224 @@
+// Package client implements a small HTTP client on top of volk's HTTP types.
+//
+// It is used by the volk CLI and by the volktest package, and can be used by
+// other projects to talk to volk (or any HTTP server) without net/http.
+// Plain http URLs use HTTP/1.1; https URLs use HTTP/2 when the server
+// negotiates it and HTTP/1.1 over TLS otherwise.
+package client
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/awaisamjad/volk/internal/http"
+)
+
+// Aliases for the HTTP types used in the client API, so that users of this
+// package can refer to them.
+type (
+	Method   = http.Method
+	Header   = http.Header
+	Response = http.Response
+)
+
+// DefaultUserAgent is sent with every request unless the request sets its own User-Agent.
+const DefaultUserAgent = "volk-client"
+
+// Request is an outgoing HTTP request.
+type Request struct {
+	Method  Method
+	URL     *url.URL
+	Headers []Header
+	Body    string
+
+	// GetBody, if set, opens the body to send instead of Body, so that a
+	// large body such as a multipart form with files is streamed rather
+	// than held in memory. It is called again for every attempt of a
+	// retried request. ContentLength is the length of the body it returns.
+	GetBody       func() (io.ReadCloser, error)
+	ContentLength int64
+
+	// Retry, if set, overrides the policy of a RetryTransport for this request.
+	Retry *RetryPolicy
+}
+
+// NewRequest creates a request for the given method and absolute http or https URL.
+func NewRequest(method Method, rawURL string, body string) (*Request, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL %q: %w", rawURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q: missing host", rawURL)
+	}
+
+	return &Request{
+		Method: method,
+		URL:    u,
+		Body:   body,
+	}, nil
+}
+
+// Header returns the value of the first header with the given name, compared case-insensitively.
+func (r *Request) Header(name string) (string, bool) {
+	return http.GetHeader(r.Headers, name)
+}
+
+// SetHeader replaces all headers with the given name by a single header.
+func (r *Request) SetHeader(name, value string) {
+	headers := r.Headers[:0]
+	for _, h := range r.Headers {
+		if !strings.EqualFold(h.Name, name) {
+			headers = append(headers, h)
+		}
+	}
+	r.Headers = append(headers, Header{Name: name, Value: value})
+}
+
+// Addr returns the host:port the request is sent to.
+func (r *Request) Addr() string {
+	port := r.URL.Port()
+	if port == "" {
+		port = "80"
+		if r.URL.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(r.URL.Hostname(), port)
+}
+
+// openBody returns the body of the request and its length.
+func (r *Request) openBody() (io.ReadCloser, int64, error) {
+	if r.GetBody == nil {
+		return io.NopCloser(strings.NewReader(r.Body)), int64(len(r.Body)), nil
+	}
+	body, err := r.GetBody()
+	if err != nil {
+		return nil, 0, fmt.Errorf("error opening request body: %w", err)
+	}
+	return body, r.ContentLength, nil
+}
+
+// bufferBody returns a copy of the request with a body from GetBody read
+// into Body, for transports that need the whole body, such as one signing it.
+func (r *Request) bufferBody() (*Request, error) {
+	if r.GetBody == nil {
+		return r, nil
+	}
+	body, _, err := r.openBody()
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading request body: %w", err)
+	}
+	buffered := *r
+	buffered.Body, buffered.GetBody, buffered.ContentLength = string(data), nil, 0
+	return &buffered, nil
+}
+
+// Wire returns the request as it is written on the connection. A body from
+// GetBody is not part of it.
+func (r *Request) Wire() http.Request {
+	target := http.RequestTarget{Path: r.URL.EscapedPath()}
+	if target.Path == "" {
+		target.Path = "/"
+	}
+	if r.URL.RawQuery != "" {
+		target.Query = "?" + r.URL.RawQuery
+	}
+
+	headers := []Header{{Name: "Host", Value: r.URL.Host}}
+	for _, h := range r.Headers {
+		if !strings.EqualFold(h.Name, "Host") {
+			headers = append(headers, h)
+		}
+	}
+	if _, ok := r.Header("User-Agent"); !ok {
+		headers = append(headers, Header{Name: "User-Agent", Value: DefaultUserAgent})
+	}
+	if _, ok := r.Header("Content-Length"); !ok {
+		if r.GetBody != nil {
+			headers = append(headers, Header{Name: "Content-Length", Value: strconv.FormatInt(r.ContentLength, 10)})
+		} else if r.Body != "" {
+			headers = append(headers, Header{Name: "Content-Length", Value: strconv.Itoa(len(r.Body))})
+		}
+	}
+	headers = append(headers, Header{Name: "Connection", Value: "close"})
+
+	return http.Request{
+		StartLine: http.RequestStartLine{
+			Method:        r.Method,
+			RequestTarget: target,
+			Protocol:      http.HTTP1_1,
+		},
+		Headers: headers,
+		Body:    r.Body,
+	}
+}
+
+// Transport performs a single HTTP exchange.
+type Transport interface {
+	RoundTrip(req *Request) (Response, error)
+}
+
+// Client sends requests through a Transport.
+type Client struct {
+	// Transport is used to send requests. If nil, DefaultTransport is used.
+	Transport Transport
+}
+
+// New creates a Client using DefaultTransport.
+func New() *Client {
+	return &Client{}
+}
+
+// DefaultTransport is the Transport used by clients without their own.
+var DefaultTransport Transport = &TCPTransport{Timeout: 30 * time.Second}
+
+// Do sends the request and returns the response.
+func (c *Client) Do(req *Request) (Response, error) {
+	transport := c.Transport
+	if transport == nil {
+		transport = DefaultTransport
+	}
+	return transport.RoundTrip(req)
+}
+
+// Get sends a GET request to the URL.
+func (c *Client) Get(rawURL string) (Response, error) {
+	req, err := NewRequest(http.GET, rawURL, "")
+	if err != nil {
+		return Response{}, err
+	}
+	return c.Do(req)
+}
+
+// Head sends a HEAD request to the URL.
+func (c *Client) Head(rawURL string) (Response, error) {
+	req, err := NewRequest(http.HEAD, rawURL, "")
+	if err != nil {
+		return Response{}, err
+	}
+	return c.Do(req)
+}
+
+// Post sends a POST request with the given content type and body to the URL.
+func (c *Client) Post(rawURL, contentType, body string) (Response, error) {
+	req, err := NewRequest(http.POST, rawURL, body)
+	if err != nil {
+		return Response{}, err
+	}
+	req.SetHeader("Content-Type", contentType)
+	return c.Do(req)
+}
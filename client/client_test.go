@@ -0,0 +1,71 @@
+package client
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/awaisamjad/volk/internal/http"
+)
+
+func TestRequestWire(t *testing.T) {
+	req, err := NewRequest(http.POST, "http://example.com:8080/submit?a=1#top", "hello")
+	if err != nil {
+		t.Fatalf("NewRequest returned an error: %v", err)
+	}
+	req.SetHeader("Content-Type", "text/plain")
+
+	expected := "POST /submit?a=1 HTTP/1.1\r\n" +
+		"Host: example.com:8080\r\n" +
+		"Content-Type: text/plain\r\n" +
+		"User-Agent: volk-client\r\n" +
+		"Content-Length: 5\r\n" +
+		"Connection: close\r\n" +
+		"\r\n" +
+		"hello"
+	if got := req.Wire().String(); got != expected {
+		t.Errorf("Wire() returned incorrect request.\nExpected: %q\nGot: %q", expected, got)
+	}
+
+	if req.Addr() != "example.com:8080" {
+		t.Errorf("Expected address example.com:8080, got %s", req.Addr())
+	}
+}
+
+func TestNewRequestInvalidURL(t *testing.T) {
+	tests := []string{"ftp://example.com/", "/relative", "http://"}
+	for _, rawURL := range tests {
+		t.Run(rawURL, func(t *testing.T) {
+			if _, err := NewRequest(http.GET, rawURL, ""); err == nil {
+				t.Errorf("NewRequest(%q) should have returned an error", rawURL)
+			}
+		})
+	}
+}
+
+func TestReadResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   Method
+		response string
+		body     string
+	}{
+		{"Content-Length", http.GET, "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\nhello trailing", "hello"},
+		{"Read until close", http.GET, "HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\n\r\nhello\r\n\r\nworld", "hello\r\n\r\nworld"},
+		{"HEAD has no body", http.HEAD, "HTTP/1.1 200 OK\r\nContent-Length: 5\r\n\r\n", ""},
+		{"304 has no body", http.GET, "HTTP/1.1 304 Not Modified\r\n\r\n", ""},
+		{"Chunked", http.GET, "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n5\r\nhello\r\n6;ext=1\r\n world\r\n0\r\nX-Checksum: abc\r\n\r\n", "hello world"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := ReadResponse(bufio.NewReader(strings.NewReader(tt.response)), tt.method)
+			if err != nil {
+				t.Fatalf("ReadResponse returned an error: %v", err)
+			}
+			if resp.GetBody() != tt.body {
+				t.Errorf("Expected body %q, got %q", tt.body, resp.GetBody())
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// DefaultFallbackDelay is the Connection Attempt Delay of RFC 8305: how long
+// Dialer waits for a connection attempt before starting the next one.
+const DefaultFallbackDelay = 250 * time.Millisecond
+
+// DefaultDNSTTL is how long a DNSCache created with a zero TTL keeps lookups.
+const DefaultDNSTTL = time.Minute
+
+// DefaultDialer is the Dialer used by transports that have none.
+var DefaultDialer = &Dialer{Cache: NewDNSCache(DefaultDNSTTL)}
+
+// DNSCache keeps the addresses hosts resolve to for a fixed time, so that
+// repeated requests to a host do not wait for the resolver. Failed lookups
+// are not cached. The standard resolver does not report record TTLs, so every
+// entry lives for TTL.
+type DNSCache struct {
+	// TTL is how long a lookup is kept.
+	TTL time.Duration
+	// Resolver looks up hosts. If nil, net.DefaultResolver is used.
+	Resolver *net.Resolver
+
+	mu      sync.Mutex
+	entries map[string]dnsEntry
+	now     func() time.Time                                             // replaced in tests
+	lookup  func(ctx context.Context, host string) ([]net.IPAddr, error) // replaced in tests
+}
+
+// dnsEntry is a cached lookup.
+type dnsEntry struct {
+	addrs   []net.IPAddr
+	expires time.Time
+}
+
+// NewDNSCache creates an empty DNSCache keeping lookups for ttl, or
+// DefaultDNSTTL if ttl is zero.
+func NewDNSCache(ttl time.Duration) *DNSCache {
+	if ttl == 0 {
+		ttl = DefaultDNSTTL
+	}
+	return &DNSCache{TTL: ttl}
+}
+
+// LookupIPAddr returns the addresses of host, from the cache if a lookup has
+// not expired yet.
+func (c *DNSCache) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	now := time.Now
+	if c.now != nil {
+		now = c.now
+	}
+
+	c.mu.Lock()
+	entry, ok := c.entries[host]
+	c.mu.Unlock()
+	if ok && now().Before(entry.expires) {
+		return entry.addrs, nil
+	}
+
+	lookup := c.lookup
+	if lookup == nil {
+		resolver := c.Resolver
+		if resolver == nil {
+			resolver = net.DefaultResolver
+		}
+		lookup = resolver.LookupIPAddr
+	}
+	addrs, err := lookup(ctx, host)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving %s: %w", host, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]dnsEntry)
+	}
+	c.entries[host] = dnsEntry{addrs: addrs, expires: now().Add(c.TTL)}
+	return addrs, nil
+}
+
+// Dialer opens TCP connections with the Happy Eyeballs algorithm of RFC 8305.
+// A host's addresses are tried alternating between IPv6 and IPv4, starting a
+// new attempt each time the previous one fails or FallbackDelay passes, and
+// the first connection to succeed is used. A network where IPv6 is broken
+// then costs FallbackDelay rather than a connection timeout.
+type Dialer struct {
+	// Cache resolves hosts. If nil, every dial looks the host up.
+	Cache *DNSCache
+	// FallbackDelay is how long an attempt runs alone before the next
+	// address is tried. If zero, DefaultFallbackDelay is used.
+	FallbackDelay time.Duration
+
+	// dial connects to a single address; replaced in tests.
+	dial func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// DialContext connects to addr, a host and port, over TCP.
+func (d *Dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %w", addr, err)
+	}
+
+	var addrs []net.IPAddr
+	if ip := net.ParseIP(host); ip != nil {
+		addrs = []net.IPAddr{{IP: ip}}
+	} else if d.Cache != nil {
+		addrs, err = d.Cache.LookupIPAddr(ctx, host)
+	} else {
+		addrs, err = net.DefaultResolver.LookupIPAddr(ctx, host)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("error resolving %s: no addresses", host)
+	}
+
+	targets := make([]string, 0, len(addrs))
+	for _, a := range interleaveFamilies(addrs) {
+		targets = append(targets, net.JoinHostPort(a.String(), port))
+	}
+	return d.race(ctx, network, targets)
+}
+
+// interleaveFamilies orders addresses alternating between IPv6 and IPv4,
+// starting with IPv6, as in RFC 8305 section 4. The resolver's order is kept
+// within each family.
+func interleaveFamilies(addrs []net.IPAddr) []net.IPAddr {
+	var v6, v4 []net.IPAddr
+	for _, a := range addrs {
+		if a.IP.To4() != nil {
+			v4 = append(v4, a)
+		} else {
+			v6 = append(v6, a)
+		}
+	}
+	ordered := make([]net.IPAddr, 0, len(addrs))
+	for i := 0; i < len(v6) || i < len(v4); i++ {
+		if i < len(v6) {
+			ordered = append(ordered, v6[i])
+		}
+		if i < len(v4) {
+			ordered = append(ordered, v4[i])
+		}
+	}
+	return ordered
+}
+
+// race starts staggered connection attempts to the targets in order and
+// returns the first connection made, closing any made after it.
+func (d *Dialer) race(ctx context.Context, network string, targets []string) (net.Conn, error) {
+	dial := d.dial
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	delay := d.FallbackDelay
+	if delay == 0 {
+		delay = DefaultFallbackDelay
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		conn net.Conn
+		err  error
+	}
+	results := make(chan result, len(targets))
+	start := func(target string) {
+		go func() {
+			conn, err := dial(ctx, network, target)
+			results <- result{conn, err}
+		}()
+	}
+
+	next, pending := 0, 0
+	startNext := func() {
+		if next < len(targets) {
+			start(targets[next])
+			next++
+			pending++
+		}
+	}
+	// closeLate closes the connections of attempts still running.
+	closeLate := func() {
+		go func(n int) {
+			for ; n > 0; n-- {
+				if late := <-results; late.conn != nil {
+					late.conn.Close()
+				}
+			}
+		}(pending)
+	}
+
+	var errs []error
+	startNext()
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for pending > 0 {
+		select {
+		case r := <-results:
+			pending--
+			if r.err == nil {
+				cancel()
+				closeLate()
+				return r.conn, nil
+			}
+			errs = append(errs, r.err)
+			// A failed attempt starts the next one without waiting.
+			startNext()
+			timer.Reset(delay)
+		case <-timer.C:
+			startNext()
+			timer.Reset(delay)
+		case <-ctx.Done():
+			closeLate()
+			return nil, ctx.Err()
+		}
+	}
+	return nil, errors.Join(errs...)
+}
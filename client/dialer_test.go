@@ -0,0 +1,164 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDNSCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	lookups := 0
+	fail := false
+	cache := NewDNSCache(time.Minute)
+	cache.now = func() time.Time { return now }
+	cache.lookup = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		lookups++
+		if fail {
+			return nil, errors.New("no such host")
+		}
+		return []net.IPAddr{{IP: net.ParseIP("192.0.2.1")}}, nil
+	}
+
+	tests := []struct {
+		name        string
+		advance     time.Duration
+		fail        bool
+		wantErr     bool
+		wantLookups int
+	}{
+		{"first lookup", 0, false, false, 1},
+		{"cached", 30 * time.Second, false, false, 1},
+		{"expired", 31 * time.Second, false, false, 2},
+		{"cached again", 59 * time.Second, false, false, 2},
+		{"failure not cached", time.Minute, true, true, 3},
+		{"retried after failure", 0, false, false, 4},
+	}
+
+	for _, tt := range tests {
+		now = now.Add(tt.advance)
+		fail = tt.fail
+		addrs, err := cache.LookupIPAddr(context.Background(), "example.com")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: Expected error %v, got %v", tt.name, tt.wantErr, err)
+		}
+		if !tt.wantErr && len(addrs) != 1 {
+			t.Errorf("%s: Expected 1 address, got %v", tt.name, addrs)
+		}
+		if lookups != tt.wantLookups {
+			t.Errorf("%s: Expected %d lookups, got %d", tt.name, tt.wantLookups, lookups)
+		}
+	}
+}
+
+func TestInterleaveFamilies(t *testing.T) {
+	addrs := func(ips ...string) []net.IPAddr {
+		var out []net.IPAddr
+		for _, ip := range ips {
+			out = append(out, net.IPAddr{IP: net.ParseIP(ip)})
+		}
+		return out
+	}
+
+	tests := []struct {
+		name string
+		in   []net.IPAddr
+		want []net.IPAddr
+	}{
+		{"IPv4 first", addrs("192.0.2.1", "192.0.2.2", "2001:db8::1"), addrs("2001:db8::1", "192.0.2.1", "192.0.2.2")},
+		{"alternating", addrs("2001:db8::1", "2001:db8::2", "192.0.2.1", "192.0.2.2"), addrs("2001:db8::1", "192.0.2.1", "2001:db8::2", "192.0.2.2")},
+		{"IPv4 only", addrs("192.0.2.1", "192.0.2.2"), addrs("192.0.2.1", "192.0.2.2")},
+	}
+
+	for _, tt := range tests {
+		if got := interleaveFamilies(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestDialerHappyEyeballs(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	cache := NewDNSCache(time.Minute)
+	cache.lookup = func(ctx context.Context, host string) ([]net.IPAddr, error) {
+		return []net.IPAddr{{IP: net.ParseIP("2001:db8::1")}, {IP: net.ParseIP("192.0.2.1")}}, nil
+	}
+
+	tests := []struct {
+		name    string
+		ipv6    func(ctx context.Context) (net.Conn, error)
+		maxTime time.Duration
+	}{
+		// An IPv6 attempt that never completes costs only the fallback delay.
+		{"IPv6 hangs", func(ctx context.Context) (net.Conn, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}, time.Second},
+		// An IPv6 attempt that fails starts IPv4 at once.
+		{"IPv6 refused", func(ctx context.Context) (net.Conn, error) {
+			return nil, errors.New("network unreachable")
+		}, 40 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			var dialed []string
+			d := &Dialer{Cache: cache, FallbackDelay: 50 * time.Millisecond}
+			d.dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				mu.Lock()
+				dialed = append(dialed, addr)
+				mu.Unlock()
+				if addr == "[2001:db8::1]:443" {
+					return tt.ipv6(ctx)
+				}
+				return net.Dial("tcp", ln.Addr().String())
+			}
+
+			start := time.Now()
+			conn, err := d.DialContext(context.Background(), "tcp", "example.com:443")
+			if err != nil {
+				t.Fatalf("Expected a connection, got %v", err)
+			}
+			conn.Close()
+			mu.Lock()
+			defer mu.Unlock()
+			if elapsed := time.Since(start); elapsed > tt.maxTime {
+				t.Errorf("Expected to connect within %v, took %v", tt.maxTime, elapsed)
+			}
+			if len(dialed) < 2 || dialed[0] != "[2001:db8::1]:443" || dialed[1] != "192.0.2.1:443" {
+				t.Errorf("Expected IPv6 then IPv4 attempts, got %v", dialed)
+			}
+		})
+	}
+}
+
+func TestDialerAllFail(t *testing.T) {
+	d := &Dialer{FallbackDelay: time.Millisecond}
+	d.dial = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return nil, errors.New("refused " + addr)
+	}
+
+	_, err := d.DialContext(context.Background(), "tcp", "192.0.2.1:80")
+	if err == nil || err.Error() != "refused 192.0.2.1:80" {
+		t.Errorf("Expected the attempt's error, got %v", err)
+	}
+}
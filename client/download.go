@@ -0,0 +1,236 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/awaisamjad/volk/internal/http"
+)
+
+// ErrRangesNotSupported is returned by Download when the server does not
+// announce byte ranges or the size of the resource.
+var ErrRangesNotSupported = errors.New("server does not support range requests")
+
+// downloadState is the progress of a parallel download, stored next to the
+// destination file with a .part suffix so that an interrupted download can resume.
+type downloadState struct {
+	URL          string    `json:"url"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	Segments     []segment `json:"segments"`
+}
+
+// segment is a byte range of the resource, End inclusive.
+type segment struct {
+	Start int64 `json:"start"`
+	End   int64 `json:"end"`
+	Done  bool  `json:"done"`
+}
+
+// Download fetches the URL into the file dest in parallel segments.
+//
+// It sends a HEAD request to learn the size of the resource and then GETs the
+// segments with Range requests on up to parallel connections. Progress is kept
+// in dest + ".part"; if a download is interrupted, calling Download again only
+// fetches the missing segments, provided the resource's ETag and Last-Modified
+// have not changed and dest still has the resource's size. Otherwise the
+// download starts over. The .part file is removed once the download is complete.
+func (c *Client) Download(rawURL, dest string, parallel int) error {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	head, err := c.Head(rawURL)
+	if err != nil {
+		return err
+	}
+	if head.GetStatusCode() != 200 {
+		return fmt.Errorf("error downloading %s: %d %s", rawURL, head.GetStatusCode(), head.GetStatusText())
+	}
+
+	acceptRanges, _ := http.GetHeader(head.Headers, "Accept-Ranges")
+	contentLength, _ := http.GetHeader(head.Headers, "Content-Length")
+	size, err := strconv.ParseInt(contentLength, 10, 64)
+	if !strings.EqualFold(acceptRanges, "bytes") || err != nil || size < 0 {
+		return ErrRangesNotSupported
+	}
+
+	etag, _ := http.GetHeader(head.Headers, "ETag")
+	lastModified, _ := http.GetHeader(head.Headers, "Last-Modified")
+
+	statePath := dest + ".part"
+	state, ok := loadDownloadState(statePath)
+	if !ok || state.URL != rawURL || state.Size != size || state.ETag != etag || state.LastModified != lastModified || !state.resumable(dest) {
+		state = &downloadState{
+			URL:          rawURL,
+			Size:         size,
+			ETag:         etag,
+			LastModified: lastModified,
+			Segments:     splitSegments(size, parallel),
+		}
+		if err := os.Remove(dest); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("error removing stale download: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening %s: %w", dest, err)
+	}
+	defer file.Close()
+	if err := file.Truncate(size); err != nil {
+		return fmt.Errorf("error allocating %s: %w", dest, err)
+	}
+	if err := state.save(statePath); err != nil {
+		return err
+	}
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+		queue    = make(chan int)
+	)
+	for w := 0; w < parallel; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				seg := state.Segments[i]
+				err := c.downloadSegment(rawURL, state.ETag, seg, file)
+
+				mu.Lock()
+				if err == nil {
+					state.Segments[i].Done = true
+					err = state.save(statePath)
+				}
+				if err != nil && firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for i, seg := range state.Segments {
+		if !seg.Done {
+			queue <- i
+		}
+	}
+	close(queue)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("error writing %s: %w", dest, err)
+	}
+	if err := os.Remove(statePath); err != nil {
+		return fmt.Errorf("error removing download state: %w", err)
+	}
+	return nil
+}
+
+// downloadSegment fetches one segment with a Range request and writes it at its offset.
+func (c *Client) downloadSegment(rawURL, etag string, seg segment, file *os.File) error {
+	req, err := NewRequest(http.GET, rawURL, "")
+	if err != nil {
+		return err
+	}
+	req.SetHeader("Range", fmt.Sprintf("bytes=%d-%d", seg.Start, seg.End))
+	if etag != "" {
+		req.SetHeader("If-Range", etag)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	if resp.GetStatusCode() != 206 {
+		return fmt.Errorf("error downloading bytes %d-%d: %d %s", seg.Start, seg.End, resp.GetStatusCode(), resp.GetStatusText())
+	}
+
+	contentRange, _ := http.GetHeader(resp.Headers, "Content-Range")
+	expected := fmt.Sprintf("bytes %d-%d/", seg.Start, seg.End)
+	if !strings.HasPrefix(contentRange, expected) || int64(len(resp.Body)) != seg.End-seg.Start+1 {
+		return fmt.Errorf("error downloading bytes %d-%d: unexpected range %q with %d bytes", seg.Start, seg.End, contentRange, len(resp.Body))
+	}
+
+	if _, err := file.WriteAt([]byte(resp.Body), seg.Start); err != nil {
+		return fmt.Errorf("error writing bytes %d-%d: %w", seg.Start, seg.End, err)
+	}
+	return nil
+}
+
+// splitSegments divides size bytes into n segments of nearly equal length.
+func splitSegments(size int64, n int) []segment {
+	if size == 0 {
+		return nil
+	}
+	if int64(n) > size {
+		n = int(size)
+	}
+
+	segments := make([]segment, 0, n)
+	length := size / int64(n)
+	var start int64
+	for i := 0; i < n; i++ {
+		end := start + length - 1
+		if i == n-1 {
+			end = size - 1
+		}
+		segments = append(segments, segment{Start: start, End: end})
+		start = end + 1
+	}
+	return segments
+}
+
+// loadDownloadState reads the state of an interrupted download, if there is one.
+func loadDownloadState(path string) (*downloadState, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	var state downloadState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, false
+	}
+	return &state, true
+}
+
+// resumable reports whether the segments marked done can be trusted to be in
+// dest: the segments must cover the resource without gaps, and dest, which is
+// allocated to the full size before any segment is fetched, must still exist
+// with that size. A destination that was deleted or replaced starts over.
+func (s *downloadState) resumable(dest string) bool {
+	var next int64
+	for _, seg := range s.Segments {
+		if seg.Start != next || seg.End < seg.Start {
+			return false
+		}
+		next = seg.End + 1
+	}
+	if next != s.Size {
+		return false
+	}
+	info, err := os.Stat(dest)
+	return err == nil && info.Mode().IsRegular() && info.Size() == s.Size
+}
+
+// save writes the state file.
+func (s *downloadState) save(path string) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("error encoding download state: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing download state: %w", err)
+	}
+	return nil
+}
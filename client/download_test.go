@@ -0,0 +1,180 @@
+package client
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/awaisamjad/volk/internal/http"
+)
+
+// rangeTransport serves a fixed body with support for HEAD and single byte ranges.
+type rangeTransport struct {
+	body string
+
+	mu     sync.Mutex
+	ranges []string
+	fail   bool
+}
+
+func (t *rangeTransport) RoundTrip(req *Request) (Response, error) {
+	headers := []Header{
+		{Name: "Accept-Ranges", Value: "bytes"},
+		{Name: "ETag", Value: `"abc"`},
+	}
+	if req.Method == http.HEAD {
+		headers = append(headers, Header{Name: "Content-Length", Value: fmt.Sprint(len(t.body))})
+		return Response{
+			StartLine: http.ResponseStartLine{Protocol: http.HTTP1_1, StatusCode: 200, StatusText: "OK"},
+			Headers:   headers,
+		}, nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.fail {
+		return Response{}, fmt.Errorf("connection reset")
+	}
+
+	rangeHeader, _ := req.Header("Range")
+	t.ranges = append(t.ranges, rangeHeader)
+	var start, end int
+	fmt.Sscanf(strings.TrimPrefix(rangeHeader, "bytes="), "%d-%d", &start, &end)
+	headers = append(headers, Header{Name: "Content-Range", Value: fmt.Sprintf("bytes %d-%d/%d", start, end, len(t.body))})
+	return Response{
+		StartLine: http.ResponseStartLine{Protocol: http.HTTP1_1, StatusCode: 206, StatusText: "Partial Content"},
+		Headers:   headers,
+		Body:      t.body[start : end+1],
+	}, nil
+}
+
+func TestDownload(t *testing.T) {
+	body := strings.Repeat("0123456789", 10) + "x"
+	network := &rangeTransport{body: body}
+	c := &Client{Transport: network}
+	dest := filepath.Join(t.TempDir(), "file.bin")
+
+	if err := c.Download("http://example.com/file.bin", dest, 4); err != nil {
+		t.Fatalf("Download returned an error: %v", err)
+	}
+
+	data, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != body {
+		t.Errorf("Expected the reassembled file to match the body, got %q", data)
+	}
+	if len(network.ranges) != 4 {
+		t.Errorf("Expected 4 range requests, got %v", network.ranges)
+	}
+	if _, err := os.Stat(dest + ".part"); !os.IsNotExist(err) {
+		t.Errorf("Expected the .part file to be removed after the download")
+	}
+}
+
+func TestDownloadResume(t *testing.T) {
+	body := strings.Repeat("abcdefghij", 4)
+	network := &rangeTransport{body: body}
+	c := &Client{Transport: network}
+	dest := filepath.Join(t.TempDir(), "file.bin")
+
+	// Simulate an interrupted download with the first two of four segments done.
+	state := &downloadState{URL: "http://example.com/file.bin", Size: int64(len(body)), ETag: `"abc"`, Segments: splitSegments(int64(len(body)), 4)}
+	state.Segments[0].Done = true
+	state.Segments[1].Done = true
+	if err := state.save(dest + ".part"); err != nil {
+		t.Fatal(err)
+	}
+	// The destination is allocated to the full size before segments are fetched.
+	if err := os.WriteFile(dest, []byte(body[:20]+strings.Repeat("\x00", 20)), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Download("http://example.com/file.bin", dest, 2); err != nil {
+		t.Fatalf("Download returned an error: %v", err)
+	}
+
+	sort.Strings(network.ranges)
+	expected := []string{"bytes=20-29", "bytes=30-39"}
+	if fmt.Sprint(network.ranges) != fmt.Sprint(expected) {
+		t.Errorf("Expected only the missing segments %v to be fetched, got %v", expected, network.ranges)
+	}
+	if data, _ := os.ReadFile(dest); string(data) != body {
+		t.Errorf("Expected the resumed file to match the body, got %q", data)
+	}
+}
+
+func TestDownloadRestartsWithoutDestination(t *testing.T) {
+	body := strings.Repeat("abcdefghij", 4)
+	state := &downloadState{URL: "http://example.com/file.bin", Size: int64(len(body)), ETag: `"abc"`, Segments: splitSegments(int64(len(body)), 4)}
+	state.Segments[0].Done = true
+	state.Segments[1].Done = true
+
+	tests := map[string]func(dest string) error{
+		"missing":   func(dest string) error { return nil },
+		"truncated": func(dest string) error { return os.WriteFile(dest, []byte(body[:20]), 0644) },
+	}
+
+	for name, prepare := range tests {
+		t.Run(name, func(t *testing.T) {
+			network := &rangeTransport{body: body}
+			c := &Client{Transport: network}
+			dest := filepath.Join(t.TempDir(), "file.bin")
+			if err := state.save(dest + ".part"); err != nil {
+				t.Fatal(err)
+			}
+			if err := prepare(dest); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := c.Download("http://example.com/file.bin", dest, 4); err != nil {
+				t.Fatalf("Download returned an error: %v", err)
+			}
+			if len(network.ranges) != 4 {
+				t.Errorf("Expected the download to start over with 4 range requests, got %v", network.ranges)
+			}
+			if data, _ := os.ReadFile(dest); string(data) != body {
+				t.Errorf("Expected the file to match the body, got %q", data)
+			}
+		})
+	}
+}
+
+func TestDownloadFailureKeepsState(t *testing.T) {
+	network := &rangeTransport{body: "some data", fail: true}
+	c := &Client{Transport: network}
+	dest := filepath.Join(t.TempDir(), "file.bin")
+
+	if err := c.Download("http://example.com/file.bin", dest, 2); err == nil {
+		t.Fatal("Expected Download to fail")
+	}
+	if _, ok := loadDownloadState(dest + ".part"); !ok {
+		t.Errorf("Expected the .part file to be kept for resuming")
+	}
+}
+
+func TestSplitSegments(t *testing.T) {
+	tests := []struct {
+		size     int64
+		n        int
+		expected []segment
+	}{
+		{10, 3, []segment{{0, 2, false}, {3, 5, false}, {6, 9, false}}},
+		{2, 4, []segment{{0, 0, false}, {1, 1, false}}},
+		{0, 4, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d/%d", tt.size, tt.n), func(t *testing.T) {
+			got := splitSegments(tt.size, tt.n)
+			if fmt.Sprint(got) != fmt.Sprint(tt.expected) {
+				t.Errorf("splitSegments(%d, %d) = %v, want %v", tt.size, tt.n, got, tt.expected)
+			}
+		})
+	}
+}
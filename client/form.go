@@ -0,0 +1,211 @@
+package client
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/awaisamjad/volk/internal/http"
+	"github.com/awaisamjad/volk/internal/mimetype"
+)
+
+// NewFormRequest creates a POST request with values as an
+// application/x-www-form-urlencoded body.
+func NewFormRequest(rawURL string, values url.Values) (*Request, error) {
+	req, err := NewRequest(http.POST, rawURL, values.Encode())
+	if err != nil {
+		return nil, err
+	}
+	req.SetHeader("Content-Type", "application/x-www-form-urlencoded")
+	return req, nil
+}
+
+// PostForm sends a POST request with values URL-encoded to the URL.
+func (c *Client) PostForm(rawURL string, values url.Values) (Response, error) {
+	req, err := NewFormRequest(rawURL, values)
+	if err != nil {
+		return Response{}, err
+	}
+	return c.Do(req)
+}
+
+// Multipart builds a multipart/form-data body (RFC 7578) of fields and files.
+// Files are only read when the body is sent, so a form can upload files
+// larger than memory.
+type Multipart struct {
+	boundary string
+	parts    []multipartPart
+}
+
+// multipartPart is a field of a Multipart: a value, or the file at path.
+type multipartPart struct {
+	header string // Boundary and part headers
+	value  string
+	path   string
+	size   int64
+}
+
+// multipartEscaper escapes names and file names in Content-Disposition.
+var multipartEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\r", "%0D", "\n", "%0A")
+
+// NewMultipart creates an empty form with a random boundary.
+func NewMultipart() *Multipart {
+	var b [16]byte
+	rand.Read(b[:])
+	return &Multipart{boundary: "volk-" + hex.EncodeToString(b[:])}
+}
+
+// AddField adds a field with a text value.
+func (m *Multipart) AddField(name, value string) {
+	header := fmt.Sprintf("--%s\r\nContent-Disposition: form-data; name=\"%s\"\r\n\r\n",
+		m.boundary, multipartEscaper.Replace(name))
+	m.parts = append(m.parts, multipartPart{header: header, value: value})
+}
+
+// AddFile adds the file at path as the value of the field, with the file's
+// base name and a content type guessed from its extension. The file must
+// not change size until the body is sent.
+func (m *Multipart) AddFile(name, path string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("error adding file %s: %w", path, err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("error adding file %s: not a regular file", path)
+	}
+	contentType := mimetype.TypeByName(path)
+	header := fmt.Sprintf("--%s\r\nContent-Disposition: form-data; name=\"%s\"; filename=\"%s\"\r\nContent-Type: %s\r\n\r\n",
+		m.boundary, multipartEscaper.Replace(name), multipartEscaper.Replace(filepath.Base(path)), contentType)
+	m.parts = append(m.parts, multipartPart{header: header, path: path, size: info.Size()})
+	return nil
+}
+
+// ContentType returns the Content-Type of the body, with its boundary.
+func (m *Multipart) ContentType() string {
+	return "multipart/form-data; boundary=" + m.boundary
+}
+
+// closing returns the delimiter ending the body.
+func (m *Multipart) closing() string {
+	return "--" + m.boundary + "--\r\n"
+}
+
+// Len returns the length of the body in bytes.
+func (m *Multipart) Len() int64 {
+	n := int64(len(m.closing()))
+	for _, part := range m.parts {
+		n += int64(len(part.header)) + int64(len(part.value)) + part.size + int64(len("\r\n"))
+	}
+	return n
+}
+
+// Open returns a reader of the body, opening each file when it is reached.
+func (m *Multipart) Open() (io.ReadCloser, error) {
+	return &multipartReader{m: m}, nil
+}
+
+// multipartReader reads the body of a Multipart part by part.
+type multipartReader struct {
+	m       *Multipart
+	next    int       // Index of the next part
+	current io.Reader // Rest of the part being read, nil before the next one
+	file    *os.File  // File of the part being read, if it is a file
+	done    bool
+}
+
+func (r *multipartReader) Read(p []byte) (int, error) {
+	for {
+		if r.current != nil {
+			n, err := r.current.Read(p)
+			if err != io.EOF {
+				return n, err
+			}
+			r.current = nil
+			if err := r.closeFile(); err != nil {
+				return n, err
+			}
+			if n > 0 {
+				return n, nil
+			}
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		if r.next == len(r.m.parts) {
+			r.current = strings.NewReader(r.m.closing())
+			r.done = true
+			continue
+		}
+
+		part := r.m.parts[r.next]
+		r.next++
+		content := io.Reader(strings.NewReader(part.value))
+		if part.path != "" {
+			f, err := os.Open(part.path)
+			if err != nil {
+				return 0, fmt.Errorf("error opening %s: %w", part.path, err)
+			}
+			r.file = f
+			// Exactly the announced size is sent, so Content-Length holds.
+			content = &exactReader{r: f, n: part.size, path: part.path}
+		}
+		r.current = io.MultiReader(strings.NewReader(part.header), content, strings.NewReader("\r\n"))
+	}
+}
+
+// closeFile closes the file of the part that was read, if any.
+func (r *multipartReader) closeFile() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+func (r *multipartReader) Close() error {
+	return r.closeFile()
+}
+
+// exactReader reads n bytes of a file, failing if it ends sooner.
+type exactReader struct {
+	r    io.Reader
+	n    int64
+	path string
+}
+
+func (e *exactReader) Read(p []byte) (int, error) {
+	if e.n <= 0 {
+		return 0, io.EOF
+	}
+	if int64(len(p)) > e.n {
+		p = p[:e.n]
+	}
+	n, err := e.r.Read(p)
+	e.n -= int64(n)
+	if err == io.EOF && e.n > 0 {
+		return n, fmt.Errorf("file %s shrank while it was sent", e.path)
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
+
+// NewMultipartRequest creates a POST request sending the form, streaming its
+// files from disk.
+func NewMultipartRequest(rawURL string, m *Multipart) (*Request, error) {
+	req, err := NewRequest(http.POST, rawURL, "")
+	if err != nil {
+		return nil, err
+	}
+	req.SetHeader("Content-Type", m.ContentType())
+	req.GetBody = m.Open
+	req.ContentLength = m.Len()
+	return req, nil
+}
@@ -0,0 +1,168 @@
+package client
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	nethttp "net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/internal/http"
+)
+
+func TestMultipartBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.csv")
+	content := strings.Repeat("a,b,c\n", 5000)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewMultipart()
+	m.AddField("title", "Q3 \"final\"")
+	if err := m.AddFile("report", path); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.AddFile("missing", filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Errorf("Expected an error adding a missing file")
+	}
+
+	body, err := m.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	data, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if int64(len(data)) != m.Len() {
+		t.Errorf("Expected %d bytes, got %d", m.Len(), len(data))
+	}
+
+	_, params, err := mime.ParseMediaType(m.ContentType())
+	if err != nil {
+		t.Fatal(err)
+	}
+	form, err := multipart.NewReader(strings.NewReader(string(data)), params["boundary"]).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatalf("Expected a valid multipart body, got %v", err)
+	}
+	if got := form.Value["title"]; len(got) != 1 || got[0] != "Q3 \"final\"" {
+		t.Errorf("Expected the title field, got %q", got)
+	}
+	files := form.File["report"]
+	if len(files) != 1 || files[0].Filename != "report.csv" || files[0].Size != int64(len(content)) {
+		t.Fatalf("Expected report.csv with %d bytes, got %+v", len(content), files)
+	}
+	if contentType := files[0].Header.Get("Content-Type"); !strings.HasPrefix(contentType, "text/csv") {
+		t.Errorf("Expected Content-Type text/csv, got %s", contentType)
+	}
+}
+
+func TestMultipartFileShrinks(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "log.txt")
+	if err := os.WriteFile(path, []byte("0123456789"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := NewMultipart()
+	if err := m.AddFile("log", path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("0123"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := m.Open()
+	defer body.Close()
+	if _, err := io.ReadAll(body); err == nil {
+		t.Errorf("Expected an error for a file that shrank")
+	}
+}
+
+// serveOnce answers one request on a local listener with handler and returns the URL.
+func serveOnce(t *testing.T, handler func(r *nethttp.Request)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r, err := nethttp.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		handler(r)
+		io.WriteString(conn, "HTTP/1.1 204 No Content\r\n\r\n")
+	}()
+	return "http://" + ln.Addr().String() + "/upload"
+}
+
+func TestMultipartRequest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "photo.jpg")
+	if err := os.WriteFile(path, []byte(strings.Repeat("\xff", 100000)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := NewMultipart()
+	m.AddField("album", "holidays")
+	if err := m.AddFile("photo", path); err != nil {
+		t.Fatal(err)
+	}
+
+	received := make(chan *multipart.Form, 1)
+	rawURL := serveOnce(t, func(r *nethttp.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("Expected a multipart form, got %v", err)
+		}
+		received <- r.MultipartForm
+	})
+
+	req, err := NewMultipartRequest(rawURL, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &Client{Transport: &TCPTransport{Timeout: 5 * time.Second}}
+	resp, err := c.Do(req)
+	if err != nil {
+		t.Fatalf("Do returned an error: %v", err)
+	}
+	if resp.GetStatusCode() != 204 {
+		t.Errorf("Expected status 204, got %d", resp.GetStatusCode())
+	}
+
+	form := <-received
+	if form == nil {
+		t.Fatal("Expected a form")
+	}
+	if form.Value["album"][0] != "holidays" || form.File["photo"][0].Size != 100000 {
+		t.Errorf("Unexpected form %+v", form)
+	}
+}
+
+func TestNewFormRequest(t *testing.T) {
+	req, err := NewFormRequest("http://example.com/login", url.Values{"user": {"ann"}, "note": {"a&b c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Method != http.POST {
+		t.Errorf("Expected method POST, got %s", req.Method)
+	}
+	if contentType, _ := req.Header("Content-Type"); contentType != "application/x-www-form-urlencoded" {
+		t.Errorf("Expected a URL-encoded Content-Type, got %s", contentType)
+	}
+	if req.Body != "note=a%26b+c&user=ann" {
+		t.Errorf("Expected body note=a%%26b+c&user=ann, got %s", req.Body)
+	}
+}
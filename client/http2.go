@@ -0,0 +1,458 @@
+package client
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awaisamjad/volk/internal/http"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// errConnClosed is returned for streams of a connection that was closed or received GOAWAY.
+var errConnClosed = errors.New("http2: connection closed")
+
+// http2 flow control defaults from RFC 9113 section 6.9.2.
+const (
+	http2InitialWindowSize = 65535
+	http2MaxFrameSize      = 16384
+)
+
+// http2Conn is a client HTTP/2 connection that multiplexes concurrent requests
+// as streams. A goroutine reads frames and dispatches them to the streams.
+type http2Conn struct {
+	conn   net.Conn
+	framer *http2.Framer
+
+	// wmu serializes writes to the framer and the use of the header encoder.
+	wmu  sync.Mutex
+	henc *hpack.Encoder
+	hbuf bytes.Buffer
+
+	mu            sync.Mutex
+	cond          *sync.Cond // Signalled when a send window grows or the connection closes
+	streams       map[uint32]*http2Stream
+	nextStreamID  uint32
+	err           error // Set once the connection can no longer be used
+	sendWindow    int64
+	initialWindow int64
+	maxFrameSize  uint32
+}
+
+// http2Stream is a single request/response exchange on an http2Conn.
+type http2Stream struct {
+	id         uint32
+	sendWindow int64
+
+	status  int
+	headers []Header
+	body    bytes.Buffer
+	err     error
+	done    chan struct{}
+}
+
+// newHTTP2Conn sends the client preface on an established connection that
+// negotiated h2 and starts reading frames.
+func newHTTP2Conn(conn net.Conn) (*http2Conn, error) {
+	c := &http2Conn{
+		conn:          conn,
+		framer:        http2.NewFramer(conn, conn),
+		streams:       make(map[uint32]*http2Stream),
+		nextStreamID:  1,
+		sendWindow:    http2InitialWindowSize,
+		initialWindow: http2InitialWindowSize,
+		maxFrameSize:  http2MaxFrameSize,
+	}
+	c.cond = sync.NewCond(&c.mu)
+	c.henc = hpack.NewEncoder(&c.hbuf)
+	c.framer.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+
+	if _, err := conn.Write([]byte(http2.ClientPreface)); err != nil {
+		return nil, fmt.Errorf("error writing HTTP/2 preface: %w", err)
+	}
+	if err := c.framer.WriteSettings(); err != nil {
+		return nil, fmt.Errorf("error writing HTTP/2 settings: %w", err)
+	}
+
+	go c.readLoop()
+	return c, nil
+}
+
+// usable reports whether new requests can be sent on the connection.
+func (c *http2Conn) usable() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err == nil
+}
+
+// RoundTrip sends the request as a new stream and waits for its response.
+// A timeout of zero waits indefinitely.
+func (c *http2Conn) RoundTrip(req *Request, timeout time.Duration) (Response, error) {
+	wire := req.Wire()
+	body, length, err := req.openBody()
+	if err != nil {
+		return Response{}, err
+	}
+	defer body.Close()
+	endStream := length == 0
+
+	c.wmu.Lock()
+	c.mu.Lock()
+	if c.err != nil {
+		err := c.err
+		c.mu.Unlock()
+		c.wmu.Unlock()
+		return Response{}, err
+	}
+	stream := &http2Stream{
+		id:         c.nextStreamID,
+		sendWindow: c.initialWindow,
+		done:       make(chan struct{}),
+	}
+	c.nextStreamID += 2
+	c.streams[stream.id] = stream
+	c.mu.Unlock()
+
+	// Stream IDs must be used in increasing order, so the HEADERS frame is
+	// written while still holding wmu.
+	err = c.writeHeaders(stream.id, wire, endStream)
+	c.wmu.Unlock()
+	if err != nil {
+		c.close(err)
+		return Response{}, err
+	}
+
+	if !endStream {
+		if err := c.writeBody(stream, body, length); err != nil {
+			return Response{}, err
+		}
+	}
+
+	var timer <-chan time.Time
+	if timeout > 0 {
+		timer = time.After(timeout)
+	}
+	select {
+	case <-stream.done:
+	case <-timer:
+		c.cancel(stream)
+		return Response{}, fmt.Errorf("http2: timeout waiting for response to %s %s", req.Method, req.URL)
+	}
+	if stream.err != nil {
+		return Response{}, stream.err
+	}
+
+	if !hasBody(req.Method, http.StatusCode(stream.status)) {
+		stream.body.Reset()
+	}
+	return Response{
+		StartLine: http.ResponseStartLine{
+			Protocol:   http.HTTP2,
+			StatusCode: http.StatusCode(stream.status),
+			StatusText: http.StatusCodeMap[http.StatusCode(stream.status)],
+		},
+		Headers: stream.headers,
+		Body:    stream.body.String(),
+	}, nil
+}
+
+// writeHeaders encodes the request head as pseudo-header and header fields and
+// writes it as a HEADERS frame followed by CONTINUATION frames if needed.
+// The caller must hold wmu.
+func (c *http2Conn) writeHeaders(streamID uint32, wire http.Request, endStream bool) error {
+	c.hbuf.Reset()
+
+	target := wire.GetRequestTarget()
+	authority, _ := http.GetHeader(wire.Headers, "Host")
+	c.henc.WriteField(hpack.HeaderField{Name: ":method", Value: string(wire.GetMethod())})
+	c.henc.WriteField(hpack.HeaderField{Name: ":scheme", Value: "https"})
+	c.henc.WriteField(hpack.HeaderField{Name: ":authority", Value: authority})
+	c.henc.WriteField(hpack.HeaderField{Name: ":path", Value: target.Path + target.Query})
+	for _, header := range wire.Headers {
+		name := strings.ToLower(header.Name)
+		switch name {
+		// Connection-specific headers are not allowed in HTTP/2 (RFC 9113 section 8.2.2).
+		case "host", "connection", "keep-alive", "proxy-connection", "transfer-encoding", "upgrade":
+			continue
+		}
+		c.henc.WriteField(hpack.HeaderField{Name: name, Value: header.Value})
+	}
+
+	block := c.hbuf.Bytes()
+	maxFrameSize := int(c.frameSize())
+	first := true
+	for first || len(block) > 0 {
+		chunk := block
+		if len(chunk) > maxFrameSize {
+			chunk = chunk[:maxFrameSize]
+		}
+		block = block[len(chunk):]
+
+		var err error
+		if first {
+			err = c.framer.WriteHeaders(http2.HeadersFrameParam{
+				StreamID:      streamID,
+				BlockFragment: chunk,
+				EndStream:     endStream,
+				EndHeaders:    len(block) == 0,
+			})
+			first = false
+		} else {
+			err = c.framer.WriteContinuation(streamID, len(block) == 0, chunk)
+		}
+		if err != nil {
+			return fmt.Errorf("error writing HTTP/2 headers: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeBody sends length bytes of body in DATA frames, waiting for the peer
+// to open the connection and stream send windows when they are exhausted.
+func (c *http2Conn) writeBody(stream *http2Stream, body io.Reader, length int64) error {
+	chunk := make([]byte, min(length, int64(c.frameSize())))
+	for length > 0 {
+		c.mu.Lock()
+		for c.err == nil && (c.sendWindow <= 0 || stream.sendWindow <= 0) {
+			c.cond.Wait()
+		}
+		if c.err != nil {
+			err := c.err
+			c.mu.Unlock()
+			return err
+		}
+		n := min(length, c.sendWindow, stream.sendWindow, int64(c.maxFrameSize), int64(len(chunk)))
+		c.sendWindow -= n
+		stream.sendWindow -= n
+		c.mu.Unlock()
+
+		if _, err := io.ReadFull(body, chunk[:n]); err != nil {
+			c.cancel(stream)
+			return fmt.Errorf("error reading request body: %w", err)
+		}
+		length -= n
+
+		c.wmu.Lock()
+		err := c.framer.WriteData(stream.id, length == 0, chunk[:n])
+		c.wmu.Unlock()
+		if err != nil {
+			c.close(err)
+			return fmt.Errorf("error writing HTTP/2 data: %w", err)
+		}
+	}
+	return nil
+}
+
+// frameSize returns the largest frame payload the peer accepts.
+func (c *http2Conn) frameSize() uint32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.maxFrameSize
+}
+
+// cancel resets a stream whose response is no longer awaited.
+func (c *http2Conn) cancel(stream *http2Stream) {
+	c.mu.Lock()
+	delete(c.streams, stream.id)
+	c.mu.Unlock()
+
+	c.wmu.Lock()
+	c.framer.WriteRSTStream(stream.id, http2.ErrCodeCancel)
+	c.wmu.Unlock()
+}
+
+// readLoop reads frames until the connection fails and dispatches them to the streams.
+func (c *http2Conn) readLoop() {
+	for {
+		frame, err := c.framer.ReadFrame()
+		if err != nil {
+			c.close(fmt.Errorf("error reading HTTP/2 frame: %w", err))
+			return
+		}
+
+		switch f := frame.(type) {
+		case *http2.SettingsFrame:
+			if f.IsAck() {
+				continue
+			}
+			c.applySettings(f)
+			c.wmu.Lock()
+			c.framer.WriteSettingsAck()
+			c.wmu.Unlock()
+
+		case *http2.MetaHeadersFrame:
+			c.handleHeaders(f)
+
+		case *http2.DataFrame:
+			c.handleData(f)
+
+		case *http2.WindowUpdateFrame:
+			c.mu.Lock()
+			if f.StreamID == 0 {
+				c.sendWindow += int64(f.Increment)
+			} else if stream, ok := c.streams[f.StreamID]; ok {
+				stream.sendWindow += int64(f.Increment)
+			}
+			c.cond.Broadcast()
+			c.mu.Unlock()
+
+		case *http2.RSTStreamFrame:
+			c.finish(f.StreamID, fmt.Errorf("http2: stream reset by server: %v", f.ErrCode))
+
+		case *http2.PingFrame:
+			if !f.IsAck() {
+				c.wmu.Lock()
+				c.framer.WritePing(true, f.Data)
+				c.wmu.Unlock()
+			}
+
+		case *http2.GoAwayFrame:
+			c.goAway(f.LastStreamID)
+		}
+	}
+}
+
+// applySettings applies the server's SETTINGS frame.
+func (c *http2Conn) applySettings(f *http2.SettingsFrame) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	f.ForeachSetting(func(s http2.Setting) error {
+		switch s.ID {
+		case http2.SettingMaxFrameSize:
+			c.maxFrameSize = s.Val
+		case http2.SettingInitialWindowSize:
+			// A change of the initial window applies to all open streams (RFC 9113 section 6.9.2).
+			delta := int64(s.Val) - c.initialWindow
+			for _, stream := range c.streams {
+				stream.sendWindow += delta
+			}
+			c.initialWindow = int64(s.Val)
+		}
+		return nil
+	})
+	c.cond.Broadcast()
+}
+
+// handleHeaders stores the response head; informational responses and trailers are skipped.
+func (c *http2Conn) handleHeaders(f *http2.MetaHeadersFrame) {
+	c.mu.Lock()
+	stream, ok := c.streams[f.StreamID]
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	if stream.status == 0 {
+		status, err := strconv.Atoi(f.PseudoValue("status"))
+		if err != nil {
+			c.finish(f.StreamID, fmt.Errorf("http2: invalid :status %q", f.PseudoValue("status")))
+			return
+		}
+		if status >= 200 {
+			stream.status = status
+			for _, field := range f.RegularFields() {
+				stream.headers = append(stream.headers, Header{Name: canonicalName(field.Name), Value: field.Value})
+			}
+		}
+	}
+
+	if f.StreamEnded() {
+		c.finish(f.StreamID, nil)
+	}
+}
+
+// handleData appends a DATA frame to its stream's body and returns the
+// consumed flow control window to the server.
+func (c *http2Conn) handleData(f *http2.DataFrame) {
+	c.mu.Lock()
+	stream, ok := c.streams[f.StreamID]
+	c.mu.Unlock()
+
+	if length := f.Length; length > 0 {
+		c.wmu.Lock()
+		c.framer.WriteWindowUpdate(0, length)
+		if ok && !f.StreamEnded() {
+			c.framer.WriteWindowUpdate(f.StreamID, length)
+		}
+		c.wmu.Unlock()
+	}
+
+	if !ok {
+		return
+	}
+	stream.body.Write(f.Data())
+	if f.StreamEnded() {
+		c.finish(f.StreamID, nil)
+	}
+}
+
+// finish completes a stream with an optional error.
+func (c *http2Conn) finish(streamID uint32, err error) {
+	c.mu.Lock()
+	stream, ok := c.streams[streamID]
+	delete(c.streams, streamID)
+	c.mu.Unlock()
+
+	if ok {
+		stream.err = err
+		close(stream.done)
+	}
+}
+
+// goAway stops new requests on the connection and fails the streams the server did not process.
+func (c *http2Conn) goAway(lastStreamID uint32) {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = errConnClosed
+	}
+	var failed []uint32
+	for id := range c.streams {
+		if id > lastStreamID {
+			failed = append(failed, id)
+		}
+	}
+	c.cond.Broadcast()
+	c.mu.Unlock()
+
+	for _, id := range failed {
+		c.finish(id, errConnClosed)
+	}
+}
+
+// close shuts the connection down and fails all open streams.
+func (c *http2Conn) close(err error) {
+	c.mu.Lock()
+	if c.err == nil {
+		c.err = err
+	}
+	streams := c.streams
+	c.streams = make(map[uint32]*http2Stream)
+	c.cond.Broadcast()
+	c.mu.Unlock()
+
+	c.conn.Close()
+	for _, stream := range streams {
+		stream.err = err
+		close(stream.done)
+	}
+}
+
+// canonicalName converts a lowercase HTTP/2 header name to the usual
+// capitalization, e.g. content-type to Content-Type.
+func canonicalName(name string) string {
+	parts := strings.Split(name, "-")
+	for i, part := range parts {
+		if part != "" {
+			parts[i] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return strings.Join(parts, "-")
+}
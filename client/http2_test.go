@@ -0,0 +1,108 @@
+package client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	nethttp "net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/internal/http"
+)
+
+// newTLSTestServer starts a TLS server that echoes the protocol, method and body
+// of each request, and a transport that trusts it. It counts the connections it accepts.
+func newTLSTestServer(t *testing.T, enableHTTP2 bool) (*httptest.Server, *TCPTransport, *atomic.Int32) {
+	t.Helper()
+
+	var conns atomic.Int32
+	server := httptest.NewUnstartedServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Header().Set("X-Echo-Length", fmt.Sprint(len(body)))
+		fmt.Fprintf(w, "%s %s %s", r.Proto, r.Method, r.URL.Path)
+	}))
+	server.EnableHTTP2 = enableHTTP2
+	server.Config.ConnState = func(_ net.Conn, state nethttp.ConnState) {
+		if state == nethttp.StateNew {
+			conns.Add(1)
+		}
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	transport := &TCPTransport{Timeout: 5 * time.Second, TLSConfig: &tls.Config{RootCAs: pool}}
+	return server, transport, &conns
+}
+
+func TestHTTP2Multiplexing(t *testing.T) {
+	server, transport, conns := newTLSTestServer(t, true)
+	c := &Client{Transport: transport}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			path := fmt.Sprintf("/file%d", i)
+			resp, err := c.Get(server.URL + path)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if expected := "HTTP/2.0 GET " + path; resp.GetBody() != expected {
+				errs <- fmt.Errorf("expected body %q, got %q", expected, resp.GetBody())
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	if n := conns.Load(); n != 1 {
+		t.Errorf("Expected all requests to share 1 connection, got %d", n)
+	}
+}
+
+func TestHTTP2RequestBody(t *testing.T) {
+	server, transport, _ := newTLSTestServer(t, true)
+	c := &Client{Transport: transport}
+
+	// Larger than the initial flow control window, so the body has to wait for WINDOW_UPDATE frames.
+	body := strings.Repeat("x", 200000)
+	resp, err := c.Post(server.URL+"/upload", "text/plain", body)
+	if err != nil {
+		t.Fatalf("Post returned an error: %v", err)
+	}
+	if resp.GetProtocol() != http.HTTP2 || resp.GetStatusCode() != 200 {
+		t.Errorf("Expected HTTP/2 200, got %s %d", resp.GetProtocol(), resp.GetStatusCode())
+	}
+	if length, _ := http.GetHeader(resp.Headers, "X-Echo-Length"); length != "200000" {
+		t.Errorf("Expected the server to receive 200000 bytes, got %q", length)
+	}
+}
+
+func TestHTTPSFallbackToHTTP1(t *testing.T) {
+	server, transport, _ := newTLSTestServer(t, false)
+	c := &Client{Transport: transport}
+
+	resp, err := c.Get(server.URL + "/page")
+	if err != nil {
+		t.Fatalf("Get returned an error: %v", err)
+	}
+	if resp.GetBody() != "HTTP/1.1 GET /page" {
+		t.Errorf("Expected an HTTP/1.1 exchange over TLS, got %q", resp.GetBody())
+	}
+}
@@ -0,0 +1,159 @@
+package client
+
+import (
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/awaisamjad/volk/internal/http"
+	"github.com/awaisamjad/volk/internal/httpdate"
+)
+
+// RetryPolicy describes how failed requests are retried.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt. Zero disables retries.
+	MaxRetries int
+	// BaseDelay is the delay before the first retry. It doubles with each further retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between attempts. A Retry-After longer than MaxDelay
+	// is not waited for; the response is returned instead.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is used by a RetryTransport without its own policy.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	BaseDelay:  200 * time.Millisecond,
+	MaxDelay:   10 * time.Second,
+}
+
+// RetryTransport is a Transport that retries idempotent requests that failed
+// with a connection error or a 502, 503 or 504 response.
+//
+// Delays grow exponentially from BaseDelay with random jitter, so that clients
+// failing together do not retry together. A Retry-After header on the response
+// replaces the computed delay. A request's Retry field overrides the policy.
+type RetryTransport struct {
+	// Transport sends the requests. If nil, DefaultTransport is used.
+	Transport Transport
+	// Policy is the retry policy. If nil, DefaultRetryPolicy is used.
+	Policy *RetryPolicy
+
+	// sleep waits between attempts; tests replace it.
+	sleep func(time.Duration)
+}
+
+// NewRetryTransport creates a RetryTransport that sends requests with
+// transport, or DefaultTransport if it is nil.
+func NewRetryTransport(transport Transport, policy RetryPolicy) *RetryTransport {
+	return &RetryTransport{Transport: transport, Policy: &policy}
+}
+
+// RoundTrip sends the request, retrying it according to the policy.
+func (t *RetryTransport) RoundTrip(req *Request) (Response, error) {
+	transport := t.transport()
+	return t.retry(req, func() (Response, error) {
+		return transport.RoundTrip(req)
+	})
+}
+
+// transport returns the Transport requests are sent with.
+func (t *RetryTransport) transport() Transport {
+	if t.Transport == nil {
+		return DefaultTransport
+	}
+	return t.Transport
+}
+
+// retry calls attempt for the request until it succeeds or the policy gives up.
+func (t *RetryTransport) retry(req *Request, attempt func() (Response, error)) (Response, error) {
+	policy := DefaultRetryPolicy
+	if t.Policy != nil {
+		policy = *t.Policy
+	}
+	if req.Retry != nil {
+		policy = *req.Retry
+	}
+	sleep := t.sleep
+	if sleep == nil {
+		sleep = time.Sleep
+	}
+
+	for n := 0; ; n++ {
+		resp, err := attempt()
+		if n >= policy.MaxRetries || !idempotent(req.Method) || !retryable(resp, err) {
+			return resp, err
+		}
+
+		delay := policy.backoff(n)
+		if err == nil {
+			if retryAfter, ok := parseRetryAfter(resp, time.Now()); ok {
+				if policy.MaxDelay > 0 && retryAfter > policy.MaxDelay {
+					return resp, nil
+				}
+				delay = retryAfter
+			}
+		}
+		sleep(delay)
+	}
+}
+
+// backoff returns the delay before retry number attempt+1: BaseDelay doubled
+// attempt times, capped at MaxDelay, with the upper half randomized.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 0; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+// idempotent reports whether a request with the method can safely be sent more than once.
+func idempotent(method Method) bool {
+	switch method {
+	case http.GET, http.HEAD, http.PUT, http.DELETE, http.OPTIONS, http.TRACE:
+		return true
+	}
+	return false
+}
+
+// retryable reports whether an attempt failed in a way worth retrying.
+func retryable(resp Response, err error) bool {
+	if err != nil {
+		return true
+	}
+	switch resp.GetStatusCode() {
+	case 502, 503, 504:
+		return true
+	}
+	return false
+}
+
+// parseRetryAfter returns the delay requested by the response's Retry-After
+// header, given either in seconds or as an HTTP date in any of its formats.
+func parseRetryAfter(resp Response, now time.Time) (time.Duration, bool) {
+	value, ok := http.GetHeader(resp.Headers, "Retry-After")
+	if !ok {
+		return 0, false
+	}
+	value = strings.TrimSpace(value)
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := httpdate.Parse(value); err == nil {
+		if delay := date.Sub(now); delay > 0 {
+			return delay, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
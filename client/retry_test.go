@@ -0,0 +1,140 @@
+package client
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/internal/http"
+)
+
+// flakyTransport fails with the given results before answering 200.
+type flakyTransport struct {
+	failures []any // error or status code
+	calls    int
+}
+
+func (t *flakyTransport) RoundTrip(req *Request) (Response, error) {
+	t.calls++
+	if t.calls <= len(t.failures) {
+		switch failure := t.failures[t.calls-1].(type) {
+		case error:
+			return Response{}, failure
+		case Response:
+			return failure, nil
+		}
+	}
+	return Response{StartLine: http.ResponseStartLine{Protocol: http.HTTP1_1, StatusCode: 200, StatusText: "OK"}}, nil
+}
+
+// statusResponse creates a response with the status and headers.
+func statusResponse(status http.StatusCode, headers ...Header) Response {
+	return Response{
+		StartLine: http.ResponseStartLine{Protocol: http.HTTP1_1, StatusCode: status, StatusText: http.StatusCodeMap[status]},
+		Headers:   headers,
+	}
+}
+
+func TestRetryTransport(t *testing.T) {
+	policy := RetryPolicy{MaxRetries: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: 2 * time.Second}
+	connErr := errors.New("connection refused")
+
+	tests := []struct {
+		name     string
+		method   Method
+		failures []any
+		override *RetryPolicy
+		calls    int
+		status   http.StatusCode
+		delays   []time.Duration // Exact delays; nil means only the count is checked
+	}{
+		{"success", http.GET, nil, nil, 1, 200, nil},
+		{"connection errors", http.GET, []any{connErr, connErr}, nil, 3, 200, nil},
+		{"gateway errors", http.GET, []any{statusResponse(502), statusResponse(504)}, nil, 3, 200, nil},
+		{"gives up", http.GET, []any{statusResponse(503), statusResponse(503), statusResponse(503), statusResponse(503)}, nil, 4, 503, nil},
+		{"not found is final", http.GET, []any{statusResponse(404)}, nil, 1, 404, nil},
+		{"POST is not retried", http.POST, []any{statusResponse(503)}, nil, 1, 503, nil},
+		{"Retry-After seconds", http.GET, []any{statusResponse(503, Header{Name: "Retry-After", Value: "1"})}, nil, 2, 200, []time.Duration{time.Second}},
+		{"Retry-After too long", http.GET, []any{statusResponse(503, Header{Name: "Retry-After", Value: "120"})}, nil, 1, 503, nil},
+		{"per-request override", http.GET, []any{statusResponse(503), statusResponse(503)}, &RetryPolicy{MaxRetries: 1}, 2, 503, []time.Duration{0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			network := &flakyTransport{failures: tt.failures}
+			var delays []time.Duration
+			transport := NewRetryTransport(network, policy)
+			transport.sleep = func(d time.Duration) { delays = append(delays, d) }
+
+			req, _ := NewRequest(tt.method, "http://example.com/", "")
+			req.Retry = tt.override
+			resp, _ := (&Client{Transport: transport}).Do(req)
+
+			if network.calls != tt.calls {
+				t.Errorf("Expected %d attempts, got %d", tt.calls, network.calls)
+			}
+			if resp.GetStatusCode() != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, resp.GetStatusCode())
+			}
+			if len(delays) != tt.calls-1 {
+				t.Errorf("Expected %d delays, got %v", tt.calls-1, delays)
+			}
+			if tt.delays != nil && len(delays) == len(tt.delays) {
+				for i := range delays {
+					if delays[i] != tt.delays[i] {
+						t.Errorf("Expected delay %d to be %v, got %v", i, tt.delays[i], delays[i])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+
+	tests := []struct {
+		attempt  int
+		min, max time.Duration
+	}{
+		{0, 50 * time.Millisecond, 100 * time.Millisecond},
+		{1, 100 * time.Millisecond, 200 * time.Millisecond},
+		{3, 400 * time.Millisecond, 800 * time.Millisecond},
+		{10, 500 * time.Millisecond, time.Second},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if d := policy.backoff(tt.attempt); d < tt.min || d > tt.max {
+				t.Errorf("backoff(%d) = %v, want between %v and %v", tt.attempt, d, tt.min, tt.max)
+			}
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value    string
+		expected time.Duration
+		ok       bool
+	}{
+		{"30", 30 * time.Second, true},
+		{"Mon, 01 Jan 2024 12:00:10 GMT", 10 * time.Second, true},
+		{"Mon, 01 Jan 2024 11:00:00 GMT", 0, true},
+		{"Monday, 01-Jan-24 12:00:20 GMT", 20 * time.Second, true},
+		{"Mon Jan  1 12:00:30 2024", 30 * time.Second, true},
+		{"soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			resp := statusResponse(503, Header{Name: "Retry-After", Value: tt.value})
+			got, ok := parseRetryAfter(resp, now)
+			if got != tt.expected || ok != tt.ok {
+				t.Errorf("parseRetryAfter(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.expected, tt.ok)
+			}
+		})
+	}
+}
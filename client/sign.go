@@ -0,0 +1,60 @@
+package client
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/awaisamjad/volk/internal/http"
+	"github.com/awaisamjad/volk/internal/signature"
+)
+
+// SigningTransport is a Transport that signs every request with an HMAC over a
+// shared secret, for servers that protect a location with signature_secret.
+// See the X-Volk-Timestamp and X-Volk-Signature headers in the volk documentation.
+type SigningTransport struct {
+	// Transport sends the requests. If nil, DefaultTransport is used.
+	Transport Transport
+	// Secret is the shared secret.
+	Secret []byte
+}
+
+// NewSigningTransport creates a SigningTransport that sends requests with
+// transport, or DefaultTransport if it is nil.
+func NewSigningTransport(transport Transport, secret []byte) *SigningTransport {
+	return &SigningTransport{Transport: transport, Secret: secret}
+}
+
+// RoundTrip signs a copy of the request and sends it. A body from GetBody is
+// read into memory, since the signature covers it.
+func (t *SigningTransport) RoundTrip(req *Request) (Response, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = DefaultTransport
+	}
+	req, err := req.bufferBody()
+	if err != nil {
+		return Response{}, err
+	}
+
+	timestamp := time.Now().Unix()
+	wire := req.Wire()
+	// The server verifies the path in the normal form it routes by.
+	path, err := http.NormalizePath(wire.GetRequestTarget().Path)
+	if err != nil {
+		return Response{}, err
+	}
+	host, _ := http.GetHeader(wire.Headers, "Host")
+
+	signed := *req
+	signed.Headers = append([]Header(nil), req.Headers...)
+	signed.SetHeader(signature.TimestampHeader, strconv.FormatInt(timestamp, 10))
+	signed.SetHeader(signature.SignatureHeader, signature.Sign(t.Secret, signature.Request{
+		Method: string(req.Method),
+		Host:   host,
+		Path:   path,
+		Query:  wire.GetRequestTarget().Query,
+		Body:   req.Body,
+	}, timestamp))
+
+	return transport.RoundTrip(&signed)
+}
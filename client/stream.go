@@ -0,0 +1,290 @@
+package client
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// ErrBodyTooLarge is returned when a streamed response body is larger than
+// the MaxSize of its StreamOptions.
+var ErrBodyTooLarge = errors.New("response body exceeds the maximum size")
+
+// StreamResponse is a response whose body is read from the connection as the
+// caller consumes it, instead of being held in memory. The caller must close
+// Body.
+type StreamResponse struct {
+	// Head is the status line and headers of the response; its Body is empty.
+	Head Response
+	// Body is the response body.
+	Body io.ReadCloser
+	// ContentLength is the length of the body, or -1 if it is not known.
+	ContentLength int64
+}
+
+// StreamOptions limit and report the reading of a streamed body.
+type StreamOptions struct {
+	// MaxSize is the largest body accepted, in bytes. A larger one fails
+	// with ErrBodyTooLarge, before any of it is read if its length is
+	// announced. Zero means no limit.
+	MaxSize int64
+	// Progress, if set, is called after each read of the body with the bytes
+	// read so far and the length of the body, -1 if unknown.
+	Progress func(read, total int64)
+}
+
+// StreamTransport is a Transport that can also return responses with their
+// body unread. Transports that are not StreamTransports are streamed from
+// a response read as a whole.
+type StreamTransport interface {
+	Transport
+	RoundTripStream(req *Request) (*StreamResponse, error)
+}
+
+// Stream sends the request and returns the response with its body unread,
+// limited and reported according to opts.
+func (c *Client) Stream(req *Request, opts StreamOptions) (*StreamResponse, error) {
+	transport := c.Transport
+	if transport == nil {
+		transport = DefaultTransport
+	}
+	resp, err := roundTripStream(transport, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.MaxSize > 0 && resp.ContentLength > opts.MaxSize {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %d bytes announced, at most %d accepted", ErrBodyTooLarge, resp.ContentLength, opts.MaxSize)
+	}
+	if opts.MaxSize > 0 || opts.Progress != nil {
+		resp.Body = &limitedBody{ReadCloser: resp.Body, max: opts.MaxSize, total: resp.ContentLength, progress: opts.Progress}
+	}
+	return resp, nil
+}
+
+// roundTripStream sends the request with transport, streaming the body if
+// the transport supports it.
+func roundTripStream(transport Transport, req *Request) (*StreamResponse, error) {
+	if st, ok := transport.(StreamTransport); ok {
+		return st.RoundTripStream(req)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	return bufferedStream(resp), nil
+}
+
+// bufferedStream returns a StreamResponse reading the body of a response
+// that was read as a whole.
+func bufferedStream(resp Response) *StreamResponse {
+	body := resp.Body
+	resp.Body = ""
+	return &StreamResponse{
+		Head:          resp,
+		Body:          io.NopCloser(strings.NewReader(body)),
+		ContentLength: int64(len(body)),
+	}
+}
+
+// limitedBody enforces StreamOptions on a body.
+type limitedBody struct {
+	io.ReadCloser
+	max      int64
+	total    int64
+	read     int64
+	progress func(read, total int64)
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.max > 0 && int64(len(p)) > b.max-b.read+1 {
+		// Read at most one byte past the limit, to detect a body exceeding it.
+		p = p[:b.max-b.read+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.read += int64(n)
+	if b.max > 0 && b.read > b.max {
+		return n - int(b.read-b.max), fmt.Errorf("%w: more than %d bytes", ErrBodyTooLarge, b.max)
+	}
+	if b.progress != nil && n > 0 {
+		b.progress(b.read, b.total)
+	}
+	return n, err
+}
+
+// RoundTripStream sends the request like RoundTrip, but returns as soon as the
+// response head is read. Timeout then limits each read of the body rather
+// than the whole exchange, so long downloads are not cut off. Over HTTP/2 the
+// body is read as a whole first.
+func (t *TCPTransport) RoundTripStream(req *Request) (*StreamResponse, error) {
+	var conn net.Conn
+	if req.URL.Scheme == "https" && !t.DisableHTTP2 {
+		h2, tlsConn, err := t.http2Conn(req.Addr())
+		if err != nil {
+			return nil, err
+		}
+		if h2 != nil {
+			resp, err := h2.RoundTrip(req, t.Timeout)
+			if err != nil {
+				return nil, err
+			}
+			return bufferedStream(resp), nil
+		}
+		conn = tlsConn
+	} else {
+		var err error
+		if conn, err = t.dial(req.Addr(), req.URL.Scheme == "https", []string{"http/1.1"}); err != nil {
+			return nil, err
+		}
+	}
+
+	if t.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(t.Timeout))
+	}
+	if err := writeRequest(conn, req); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	r := bufio.NewReader(conn)
+	head, err := readResponseHead(r)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if !hasBody(req.Method, head.GetStatusCode()) {
+		conn.Close()
+		return &StreamResponse{Head: head, Body: io.NopCloser(strings.NewReader("")), ContentLength: 0}, nil
+	}
+	if chunked(head) {
+		body := &connBody{r: newChunkedReader(r), conn: conn, timeout: t.Timeout, remaining: -1}
+		return &StreamResponse{Head: head, Body: body, ContentLength: -1}, nil
+	}
+	length, err := contentLength(head)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	body := &connBody{r: r, conn: conn, timeout: t.Timeout, remaining: length}
+	return &StreamResponse{Head: head, Body: body, ContentLength: length}, nil
+}
+
+// connBody reads a response body from its connection, up to the
+// Content-Length, the last chunk or until the server closes the connection,
+// and closes the connection when it is closed.
+type connBody struct {
+	r         io.Reader
+	conn      net.Conn
+	timeout   time.Duration
+	remaining int64 // Bytes left to read, -1 to read until the connection is closed
+}
+
+func (b *connBody) Read(p []byte) (int, error) {
+	if b.remaining == 0 {
+		return 0, io.EOF
+	}
+	if b.remaining > 0 && int64(len(p)) > b.remaining {
+		p = p[:b.remaining]
+	}
+	if b.timeout > 0 {
+		b.conn.SetReadDeadline(time.Now().Add(b.timeout))
+	}
+	n, err := b.r.Read(p)
+	if b.remaining > 0 {
+		b.remaining -= int64(n)
+		if err == io.EOF && b.remaining > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+	}
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		err = fmt.Errorf("error reading response body: %w", err)
+	}
+	return n, err
+}
+
+func (b *connBody) Close() error {
+	return b.conn.Close()
+}
+
+// RoundTripStream sends the request like RoundTrip, retrying before the body
+// of a response is read. The body of a response that is retried is discarded.
+func (t *RetryTransport) RoundTripStream(req *Request) (*StreamResponse, error) {
+	var resp *StreamResponse
+	_, err := t.retry(req, func() (Response, error) {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		var err error
+		resp, err = roundTripStream(t.transport(), req)
+		if err != nil {
+			resp = nil
+			return Response{}, err
+		}
+		return resp.Head, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// maxCachedStream is the largest streamed body CachingTransport stores.
+// Larger bodies, and bodies of unknown length, are streamed without being cached.
+const maxCachedStream = 8 << 20
+
+// RoundTripStream sends the request like RoundTrip. A revalidated response is
+// returned from the cache; a new response is stored only if its body is
+// announced to be at most 8 MiB, otherwise it is streamed uncached.
+func (t *CachingTransport) RoundTripStream(req *Request) (*StreamResponse, error) {
+	transport := t.Transport
+	if transport == nil {
+		transport = DefaultTransport
+	}
+
+	if !cacheable(req) {
+		return roundTripStream(transport, req)
+	}
+
+	key := req.URL.String()
+	cached, ok := t.Cache.Get(key)
+
+	outgoing := req
+	if ok {
+		outgoing = withValidators(req, cached)
+	}
+
+	resp, err := roundTripStream(transport, outgoing)
+	if err != nil {
+		return nil, err
+	}
+
+	if ok && resp.Head.GetStatusCode() == 304 {
+		resp.Body.Close()
+		cached.Headers = mergeHeaders(cached.Headers, resp.Head.Headers)
+		if err := t.Cache.Set(key, cached); err != nil {
+			return nil, err
+		}
+		return bufferedStream(cached), nil
+	}
+
+	if resp.Head.GetStatusCode() == 200 && hasValidator(resp.Head) && !noStore(resp.Head) &&
+		resp.ContentLength >= 0 && resp.ContentLength <= maxCachedStream {
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return nil, err
+		}
+		full := resp.Head
+		full.Body = string(body)
+		if err := t.Cache.Set(key, full); err != nil {
+			return nil, err
+		}
+		return bufferedStream(full), nil
+	}
+	return resp, nil
+}
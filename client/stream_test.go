@@ -0,0 +1,180 @@
+package client
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/internal/http"
+)
+
+// respondOnce serves a single connection with the raw response and returns
+// the URL to request.
+func respondOnce(t *testing.T, response string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 4096)
+		conn.Read(buf)
+		io.WriteString(conn, response)
+	}()
+	return "http://" + ln.Addr().String() + "/file"
+}
+
+func TestStream(t *testing.T) {
+	body := strings.Repeat("volk", 10000)
+	tests := []struct {
+		name     string
+		response string
+		maxSize  int64
+		want     string
+		wantErr  bool
+		wantLen  int64
+	}{
+		{"content length", "HTTP/1.1 200 OK\r\nContent-Length: 40000\r\n\r\n" + body, 0, body, false, 40000},
+		{"until close", "HTTP/1.1 200 OK\r\nConnection: close\r\n\r\n" + body, 0, body, false, -1},
+		{"within limit", "HTTP/1.1 200 OK\r\nContent-Length: 40000\r\n\r\n" + body, 40000, body, false, 40000},
+		{"announced too large", "HTTP/1.1 200 OK\r\nContent-Length: 40000\r\n\r\n" + body, 100, "", true, 0},
+		{"too large without length", "HTTP/1.1 200 OK\r\nConnection: close\r\n\r\n" + body, 100, "", true, -1},
+		{"truncated", "HTTP/1.1 200 OK\r\nContent-Length: 50000\r\n\r\n" + body, 0, "", true, 50000},
+		{"no content", "HTTP/1.1 204 No Content\r\n\r\n", 0, "", false, 0},
+		{"chunked", "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n4e20\r\n" + body[:20000] + "\r\n4e20\r\n" + body[20000:] + "\r\n0\r\n\r\n", 0, body, false, -1},
+		{"chunked too large", "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n4e20\r\n" + body[:20000] + "\r\n0\r\n\r\n", 100, "", true, -1},
+		{"chunked without last chunk", "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n4e20\r\n" + body[:20000] + "\r\n", 0, "", true, -1},
+		{"invalid chunk", "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\nzz\r\nvolk\r\n0\r\n\r\n", 0, "", true, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := NewRequest(http.GET, respondOnce(t, tt.response), "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			c := &Client{Transport: &TCPTransport{}}
+			resp, err := c.Stream(req, StreamOptions{MaxSize: tt.maxSize})
+			if err != nil {
+				if !tt.wantErr {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				if !errors.Is(err, ErrBodyTooLarge) {
+					t.Errorf("Expected ErrBodyTooLarge, got %v", err)
+				}
+				return
+			}
+			defer resp.Body.Close()
+			if resp.ContentLength != tt.wantLen {
+				t.Errorf("Expected content length %d, got %d", tt.wantLen, resp.ContentLength)
+			}
+			got, err := io.ReadAll(resp.Body)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected an error reading the body, got %d bytes", len(got))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Expected %d bytes, got %d", len(tt.want), len(got))
+			}
+		})
+	}
+}
+
+func TestStreamProgress(t *testing.T) {
+	body := strings.Repeat("x", 100000)
+	req, err := NewRequest(http.GET, respondOnce(t, "HTTP/1.1 200 OK\r\nContent-Length: 100000\r\n\r\n"+body), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var calls int
+	var last, total int64
+	c := &Client{Transport: &TCPTransport{}}
+	resp, err := c.Stream(req, StreamOptions{Progress: func(read, length int64) {
+		calls++
+		if read < last {
+			t.Errorf("Expected progress to increase, got %d after %d", read, last)
+		}
+		last, total = read, length
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		t.Fatal(err)
+	}
+
+	if calls == 0 {
+		t.Fatal("Expected progress to be reported")
+	}
+	if last != 100000 || total != 100000 {
+		t.Errorf("Expected 100000/100000, got %d/%d", last, total)
+	}
+}
+
+func TestStreamFallback(t *testing.T) {
+	// flakyTransport is not a StreamTransport, so its responses are buffered.
+	transport := &flakyTransport{failures: []any{statusResponse(503)}}
+	retry := NewRetryTransport(transport, RetryPolicy{MaxRetries: 1})
+	retry.sleep = func(time.Duration) {}
+	c := &Client{Transport: retry}
+	req, err := NewRequest(http.GET, "http://example.com/", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := c.Stream(req, StreamOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.Head.GetStatusCode() != 200 {
+		t.Errorf("Expected 200 after a retry, got %d", resp.Head.GetStatusCode())
+	}
+	if transport.calls != 2 {
+		t.Errorf("Expected 2 calls, got %d", transport.calls)
+	}
+}
+
+func TestCachingTransportStream(t *testing.T) {
+	cache := NewMemoryCache()
+	rawURL := respondOnce(t, "HTTP/1.1 200 OK\r\nETag: \"v1\"\r\nContent-Length: 5\r\n\r\nhello")
+	req, err := NewRequest(http.GET, rawURL, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{Transport: NewCachingTransport(&TCPTransport{}, cache)}
+	resp, err := c.Stream(req, StreamOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "hello" {
+		t.Errorf("Expected hello, got %q", body)
+	}
+
+	cached, ok := cache.Get(rawURL)
+	if !ok {
+		t.Fatal("Expected the response to be cached")
+	}
+	if cached.GetBody() != "hello" {
+		t.Errorf("Expected cached body hello, got %q", cached.GetBody())
+	}
+}
@@ -0,0 +1,257 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awaisamjad/volk/internal/http"
+)
+
+// TCPTransport sends each HTTP/1.1 request over a new TCP connection.
+//
+// For https URLs it offers HTTP/2 during the TLS handshake. If the server
+// accepts, the connection is kept and concurrent requests to the same host are
+// multiplexed over it as HTTP/2 streams.
+type TCPTransport struct {
+	// Timeout limits the whole exchange, from dialing to reading the body.
+	// Zero means no timeout.
+	Timeout time.Duration
+
+	// TLSConfig is used for https URLs. If nil, the default configuration is used.
+	TLSConfig *tls.Config
+
+	// DisableHTTP2 prevents negotiating HTTP/2 for https URLs.
+	DisableHTTP2 bool
+
+	// Dialer opens the connections. If nil, DefaultDialer is used, which
+	// caches DNS lookups and races IPv6 and IPv4 addresses.
+	Dialer *Dialer
+
+	mu     sync.Mutex
+	h2     map[string]*http2Conn
+	dialMu map[string]*sync.Mutex
+}
+
+// RoundTrip dials the request's host, writes the request and reads the response.
+func (t *TCPTransport) RoundTrip(req *Request) (Response, error) {
+	if req.URL.Scheme == "https" && !t.DisableHTTP2 {
+		h2, conn, err := t.http2Conn(req.Addr())
+		if err != nil {
+			return Response{}, err
+		}
+		if h2 != nil {
+			return h2.RoundTrip(req, t.Timeout)
+		}
+		return t.exchange(conn, req)
+	}
+
+	conn, err := t.dial(req.Addr(), req.URL.Scheme == "https", []string{"http/1.1"})
+	if err != nil {
+		return Response{}, err
+	}
+	return t.exchange(conn, req)
+}
+
+// http2Conn returns the HTTP/2 connection to addr, dialing one if needed.
+// If the server does not negotiate HTTP/2, the TLS connection is returned
+// instead, for a single HTTP/1.1 exchange.
+func (t *TCPTransport) http2Conn(addr string) (*http2Conn, net.Conn, error) {
+	// Only one connection to a host is dialed at a time, so that concurrent
+	// requests share it instead of each opening their own.
+	t.mu.Lock()
+	if t.dialMu == nil {
+		t.dialMu = make(map[string]*sync.Mutex)
+		t.h2 = make(map[string]*http2Conn)
+	}
+	dialMu, ok := t.dialMu[addr]
+	if !ok {
+		dialMu = &sync.Mutex{}
+		t.dialMu[addr] = dialMu
+	}
+	t.mu.Unlock()
+
+	dialMu.Lock()
+	defer dialMu.Unlock()
+
+	t.mu.Lock()
+	h2 := t.h2[addr]
+	t.mu.Unlock()
+	if h2 != nil && h2.usable() {
+		return h2, nil, nil
+	}
+
+	conn, err := t.dial(addr, true, []string{"h2", "http/1.1"})
+	if err != nil {
+		return nil, nil, err
+	}
+	if conn.(*tls.Conn).ConnectionState().NegotiatedProtocol != "h2" {
+		return nil, conn, nil
+	}
+
+	h2, err = newHTTP2Conn(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	t.mu.Lock()
+	t.h2[addr] = h2
+	t.mu.Unlock()
+	return h2, nil, nil
+}
+
+// dial connects to addr, with TLS offering the given ALPN protocols if useTLS is set.
+func (t *TCPTransport) dial(addr string, useTLS bool, protocols []string) (net.Conn, error) {
+	dialer := t.Dialer
+	if dialer == nil {
+		dialer = DefaultDialer
+	}
+	ctx := context.Background()
+	if t.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.Timeout)
+		defer cancel()
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to %s: %w", addr, err)
+	}
+	if !useTLS {
+		return conn, nil
+	}
+
+	var config *tls.Config
+	if t.TLSConfig != nil {
+		config = t.TLSConfig.Clone()
+	} else {
+		config = &tls.Config{}
+	}
+	config.NextProtos = protocols
+	if config.ServerName == "" {
+		config.ServerName, _, _ = net.SplitHostPort(addr)
+	}
+
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error connecting to %s: %w", addr, err)
+	}
+	return tlsConn, nil
+}
+
+// exchange writes the request as HTTP/1.1 on the connection, reads the response and closes the connection.
+func (t *TCPTransport) exchange(conn net.Conn, req *Request) (Response, error) {
+	defer conn.Close()
+
+	if t.Timeout > 0 {
+		conn.SetDeadline(time.Now().Add(t.Timeout))
+	}
+
+	if err := writeRequest(conn, req); err != nil {
+		return Response{}, err
+	}
+	return ReadResponse(bufio.NewReader(conn), req.Method)
+}
+
+// writeRequest writes the request as HTTP/1.1 on the connection.
+func writeRequest(conn net.Conn, req *Request) error {
+	if _, err := io.WriteString(conn, req.Wire().String()); err != nil {
+		return fmt.Errorf("error writing request: %w", err)
+	}
+	if req.GetBody != nil {
+		body, length, err := req.openBody()
+		if err != nil {
+			return err
+		}
+		_, err = io.CopyN(conn, body, length)
+		body.Close()
+		if err != nil {
+			return fmt.Errorf("error writing request body: %w", err)
+		}
+	}
+	return nil
+}
+
+// ReadResponse reads a response from r. The method of the request is needed
+// to know whether the response has a body.
+func ReadResponse(r *bufio.Reader, method Method) (Response, error) {
+	resp, err := readResponseHead(r)
+	if err != nil {
+		return Response{}, err
+	}
+	if !hasBody(method, resp.GetStatusCode()) {
+		return resp, nil
+	}
+
+	var body []byte
+	length, err := contentLength(resp)
+	if err != nil {
+		return Response{}, err
+	}
+	if chunked(resp) {
+		body, err = io.ReadAll(newChunkedReader(r))
+		if err != nil {
+			return Response{}, fmt.Errorf("error reading response body: %w", err)
+		}
+	} else if length >= 0 {
+		body = make([]byte, length)
+		if _, err := io.ReadFull(r, body); err != nil {
+			return Response{}, fmt.Errorf("error reading response body: %w", err)
+		}
+	} else {
+		body, err = io.ReadAll(r)
+		if err != nil {
+			return Response{}, fmt.Errorf("error reading response body: %w", err)
+		}
+	}
+
+	resp.Body = string(body)
+	return resp, nil
+}
+
+// readResponseHead reads the status line and headers of a response from r.
+func readResponseHead(r *bufio.Reader) (Response, error) {
+	var head strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return Response{}, fmt.Errorf("error reading response head: %w", err)
+		}
+		if line == http.CRLF || line == "\n" {
+			break
+		}
+		head.WriteString(strings.TrimRight(line, "\r\n"))
+		head.WriteString(http.CRLF)
+	}
+
+	return http.NewResponse(strings.TrimSuffix(head.String(), http.CRLF) + http.HeaderBodySeparator)
+}
+
+// contentLength returns the Content-Length of the response, or -1 if it has none.
+func contentLength(resp Response) (int64, error) {
+	value, ok := http.GetHeader(resp.Headers, "Content-Length")
+	if !ok {
+		return -1, nil
+	}
+	length, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || length < 0 {
+		return 0, fmt.Errorf("invalid Content-Length %q", value)
+	}
+	return length, nil
+}
+
+// hasBody reports whether a response to the given method with the given status carries a body.
+func hasBody(method Method, status http.StatusCode) bool {
+	if method == http.HEAD {
+		return false
+	}
+	return status >= 200 && status != 204 && status != 304
+}
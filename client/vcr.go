@@ -0,0 +1,209 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/awaisamjad/volk/internal/http"
+)
+
+// RecorderMode selects how a Recorder treats requests.
+type RecorderMode int
+
+const (
+	// ModeReplay only answers requests from the cassette and never touches the network.
+	ModeReplay RecorderMode = iota
+	// ModeRecord sends every request and records its response, replacing the cassette.
+	ModeRecord
+	// ModeReplayOrRecord answers from the cassette when possible and records everything else.
+	ModeReplayOrRecord
+)
+
+// ErrInteractionNotFound is returned in ModeReplay when the cassette has no matching response.
+var ErrInteractionNotFound = errors.New("no recorded interaction matches the request")
+
+// CassetteRequest is the recorded part of a request, used to match replays.
+type CassetteRequest struct {
+	Method  Method   `json:"method"`
+	URL     string   `json:"url"`
+	Headers []Header `json:"headers,omitempty"`
+	Body    string   `json:"body,omitempty"`
+}
+
+// CassetteResponse is a recorded response.
+type CassetteResponse struct {
+	Protocol   http.Protocol   `json:"protocol"`
+	StatusCode http.StatusCode `json:"status_code"`
+	StatusText http.StatusText `json:"status_text"`
+	Headers    []Header        `json:"headers,omitempty"`
+	Body       string          `json:"body,omitempty"`
+}
+
+// newCassetteResponse converts a response to its recorded form.
+func newCassetteResponse(resp Response) CassetteResponse {
+	return CassetteResponse{
+		Protocol:   resp.GetProtocol(),
+		StatusCode: resp.GetStatusCode(),
+		StatusText: resp.GetStatusText(),
+		Headers:    resp.GetHeaders(),
+		Body:       resp.GetBody(),
+	}
+}
+
+// Response returns the recorded response.
+func (r CassetteResponse) Response() Response {
+	return http.Response{
+		StartLine: http.ResponseStartLine{
+			Protocol:   r.Protocol,
+			StatusCode: r.StatusCode,
+			StatusText: r.StatusText,
+		},
+		Headers: append([]Header(nil), r.Headers...),
+		Body:    r.Body,
+	}
+}
+
+// Interaction is a request and the response recorded for it.
+type Interaction struct {
+	Request  CassetteRequest  `json:"request"`
+	Response CassetteResponse `json:"response"`
+}
+
+// Cassette is the on-disk format of recorded interactions.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Recorder is a Transport that records responses to a cassette file and replays them,
+// so code using the client can be tested without network access.
+//
+// Requests are matched on method, URL and body. Each recorded interaction is
+// replayed once, in order, so repeated requests can get different responses.
+type Recorder struct {
+	path      string
+	mode      RecorderMode
+	transport Transport
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// NewRecorder creates a Recorder for the cassette at path. Requests that are not
+// replayed are sent with transport, or DefaultTransport if it is nil.
+// In ModeReplay the cassette must exist.
+func NewRecorder(path string, mode RecorderMode, transport Transport) (*Recorder, error) {
+	if transport == nil {
+		transport = DefaultTransport
+	}
+
+	r := &Recorder{path: path, mode: mode, transport: transport}
+	if mode == ModeRecord {
+		return r, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) && mode == ModeReplayOrRecord {
+			return r, nil
+		}
+		return nil, fmt.Errorf("error reading cassette: %w", err)
+	}
+	if err := json.Unmarshal(data, &r.cassette); err != nil {
+		return nil, fmt.Errorf("error decoding cassette %s: %w", path, err)
+	}
+	r.used = make([]bool, len(r.cassette.Interactions))
+
+	return r, nil
+}
+
+// RoundTrip replays a recorded response for the request or, depending on the mode,
+// sends it and records the response. A body from GetBody is read into memory
+// to be recorded.
+func (r *Recorder) RoundTrip(req *Request) (Response, error) {
+	req, err := req.bufferBody()
+	if err != nil {
+		return Response{}, err
+	}
+	recorded := CassetteRequest{
+		Method:  req.Method,
+		URL:     req.URL.String(),
+		Headers: req.Headers,
+		Body:    req.Body,
+	}
+
+	if r.mode != ModeRecord {
+		if resp, ok := r.replay(recorded); ok {
+			return resp, nil
+		}
+		if r.mode == ModeReplay {
+			return Response{}, fmt.Errorf("%w: %s %s", ErrInteractionNotFound, req.Method, recorded.URL)
+		}
+	}
+
+	resp, err := r.transport.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if err := r.record(recorded, resp); err != nil {
+		return resp, err
+	}
+	return resp, nil
+}
+
+// replay returns the first unused interaction matching the request.
+func (r *Recorder) replay(req CassetteRequest) (Response, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] {
+			continue
+		}
+		recorded := interaction.Request
+		if recorded.Method != req.Method || recorded.URL != req.URL || recorded.Body != req.Body {
+			continue
+		}
+
+		r.used[i] = true
+		return interaction.Response.Response(), true
+	}
+
+	return Response{}, false
+}
+
+// record appends an interaction and saves the cassette.
+func (r *Recorder) record(req CassetteRequest, resp Response) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cassette.Interactions = append(r.cassette.Interactions, Interaction{
+		Request:  req,
+		Response: newCassetteResponse(resp),
+	})
+	r.used = append(r.used, true)
+
+	return r.save()
+}
+
+// save writes the cassette to disk. The caller must hold r.mu.
+func (r *Recorder) save() error {
+	data, err := json.MarshalIndent(r.cassette, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding cassette: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return fmt.Errorf("error creating cassette directory: %w", err)
+	}
+	if err := os.WriteFile(r.path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing cassette: %w", err)
+	}
+
+	return nil
+}
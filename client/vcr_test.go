@@ -0,0 +1,100 @@
+package client
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/awaisamjad/volk/internal/http"
+)
+
+// countingTransport answers every request with a numbered response.
+type countingTransport struct {
+	calls int
+}
+
+func (t *countingTransport) RoundTrip(req *Request) (Response, error) {
+	t.calls++
+	return Response{
+		StartLine: http.ResponseStartLine{Protocol: http.HTTP1_1, StatusCode: 200, StatusText: "OK"},
+		Headers:   []Header{{Name: "Content-Type", Value: "text/plain"}},
+		Body:      req.URL.Path + " #" + string(rune('0'+t.calls)),
+	}, nil
+}
+
+func TestRecorderRecordAndReplay(t *testing.T) {
+	cassette := filepath.Join(t.TempDir(), "cassettes", "test.json")
+	network := &countingTransport{}
+
+	recorder, err := NewRecorder(cassette, ModeRecord, network)
+	if err != nil {
+		t.Fatalf("NewRecorder returned an error: %v", err)
+	}
+	c := &Client{Transport: recorder}
+	for _, path := range []string{"/a", "/a", "/b"} {
+		if _, err := c.Get("http://example.com" + path); err != nil {
+			t.Fatalf("Get returned an error while recording: %v", err)
+		}
+	}
+	if network.calls != 3 {
+		t.Fatalf("Expected 3 requests to be sent while recording, got %d", network.calls)
+	}
+
+	replayer, err := NewRecorder(cassette, ModeReplay, network)
+	if err != nil {
+		t.Fatalf("NewRecorder returned an error: %v", err)
+	}
+	c = &Client{Transport: replayer}
+
+	expected := []struct{ path, body string }{{"/a", "/a #1"}, {"/b", "/b #3"}, {"/a", "/a #2"}}
+	for _, e := range expected {
+		resp, err := c.Get("http://example.com" + e.path)
+		if err != nil {
+			t.Fatalf("Get returned an error while replaying: %v", err)
+		}
+		if resp.GetBody() != e.body {
+			t.Errorf("Expected replayed body %q, got %q", e.body, resp.GetBody())
+		}
+	}
+	if network.calls != 3 {
+		t.Errorf("Expected no requests to be sent while replaying, got %d", network.calls-3)
+	}
+
+	if _, err := c.Get("http://example.com/a"); !errors.Is(err, ErrInteractionNotFound) {
+		t.Errorf("Expected ErrInteractionNotFound once the cassette is used up, got %v", err)
+	}
+}
+
+func TestRecorderReplayOrRecord(t *testing.T) {
+	cassette := filepath.Join(t.TempDir(), "test.json")
+	network := &countingTransport{}
+
+	recorder, err := NewRecorder(cassette, ModeReplayOrRecord, network)
+	if err != nil {
+		t.Fatalf("NewRecorder returned an error: %v", err)
+	}
+	c := &Client{Transport: recorder}
+	if _, err := c.Get("http://example.com/a"); err != nil {
+		t.Fatalf("Get returned an error: %v", err)
+	}
+
+	recorder, err = NewRecorder(cassette, ModeReplayOrRecord, network)
+	if err != nil {
+		t.Fatalf("NewRecorder returned an error: %v", err)
+	}
+	c = &Client{Transport: recorder}
+	for _, path := range []string{"/a", "/b"} {
+		if _, err := c.Get("http://example.com" + path); err != nil {
+			t.Fatalf("Get returned an error: %v", err)
+		}
+	}
+	if network.calls != 2 {
+		t.Errorf("Expected 2 requests to be sent, got %d", network.calls)
+	}
+}
+
+func TestRecorderReplayMissingCassette(t *testing.T) {
+	if _, err := NewRecorder(filepath.Join(t.TempDir(), "missing.json"), ModeReplay, nil); err == nil {
+		t.Errorf("Expected an error for a missing cassette in replay mode")
+	}
+}
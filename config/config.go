@@ -5,44 +5,442 @@ import (
 	// "log"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 )
 
 const configFileName = "volk_config.toml"
 
+// SearchPaths returns the locations checked, in order, for a configuration file
+// when none is given explicitly: volk_config.toml and server.toml in the working
+// directory, $XDG_CONFIG_HOME/volk/server.toml (falling back to ~/.config) and
+// /etc/volk/server.toml.
+func SearchPaths() []string {
+	paths := []string{configFileName, "server.toml"}
+
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			configHome = filepath.Join(home, ".config")
+		}
+	}
+	if configHome != "" {
+		paths = append(paths, filepath.Join(configHome, "volk", "server.toml"))
+	}
+
+	return append(paths, filepath.Join("/etc", "volk", "server.toml"))
+}
+
+// FindConfigFile returns the first existing file from SearchPaths,
+// or an empty string if there is none.
+func FindConfigFile() string {
+	for _, path := range SearchPaths() {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
 // ServerConfig holds HTTP server configuration
 type ServerConfig struct {
-	Port        int `toml:"port"`
-	ReadTimeout int `toml:"read_timeout"` // seconds
+	Host        string `toml:"host"`
+	Port        int    `toml:"port"`
+	ReadTimeout int    `toml:"read_timeout"`  // seconds
+	MaxBodySize int64  `toml:"max_body_size"` // Largest accepted request body in bytes, 0 for no limit
+	ReusePort   bool   `toml:"reuse_port"`    // Set SO_REUSEPORT so several processes can listen on the port
+
+	PIDFile         string `toml:"pid_file"`         // File the process ID is written to, used by volk upgrade
+	ShutdownTimeout int    `toml:"shutdown_timeout"` // Seconds to wait for open connections to finish during an upgrade or after SIGINT or SIGTERM
+	DrainDelay      int    `toml:"drain_delay"`      // Seconds to keep serving with the readiness probe failing after SIGTERM in container mode, so load balancers stop sending requests
+
+	TCPNoDelay        bool `toml:"tcp_nodelay"`        // Send small writes immediately instead of coalescing them (Nagle's algorithm off)
+	KeepAlive         int  `toml:"keepalive"`          // Seconds a connection is idle before keep-alive probes start; 0 for the default, -1 to disable
+	KeepAliveInterval int  `toml:"keepalive_interval"` // Seconds between keep-alive probes; 0 for the default
+	KeepAliveCount    int  `toml:"keepalive_count"`    // Unanswered probes before a connection is dropped; 0 for the default
+	ReadBuffer        int  `toml:"read_buffer"`        // Socket receive buffer (SO_RCVBUF) in bytes; 0 for the system default
+	WriteBuffer       int  `toml:"write_buffer"`       // Socket send buffer (SO_SNDBUF) in bytes; 0 for the system default
+	Backlog           int  `toml:"backlog"`            // Queue length of connections waiting to be accepted; 0 for the system default
+
+	MaxConnectionsPerIP int `toml:"max_connections_per_ip"` // Open connections allowed per client IP (IPv6: per /64); excess ones get a 429. 0 for no limit
+
+	Dev bool `toml:"dev"` // Development mode: show details such as stack traces in error pages
+
+	ServerHeader  string `toml:"server_header"`  // Value of the Server response header, e.g. volk; empty (the default) to omit it
+	ExposeVersion bool   `toml:"expose_version"` // Add the volk version to a non-empty Server header, e.g. volk/1.4.0
+
+	MethodOverride bool `toml:"method_override"` // Let POST requests stand for PUT, PATCH or DELETE with X-HTTP-Method-Override or a _method form field
+
+	HandleTimeout        int  `toml:"handle_timeout"`         // Seconds handlers are given to answer, passed to them as the request's deadline; 0 for none
+	RequestTimeoutHeader bool `toml:"request_timeout_header"` // Let clients shorten the deadline with a Request-Timeout or X-Request-Timeout header
+
+	Hardened bool `toml:"hardened"` // Read files beneath the document root only, refuse settings that write outside log files or start programs, and refuse extra capabilities
+
+	Mode        string `toml:"mode"`         // Connection handling: "goroutine" (one per connection) or "epoll" (Linux only)
+	PollWorkers int    `toml:"poll_workers"` // Goroutines handling readable connections in epoll mode; 0 for 16 per CPU
 }
 
 // FileServerConfig holds file serving configuration
 type FileServerConfig struct {
 	DocumentRoot string `toml:"document_root"`
 	DefaultFile  string `toml:"default_file"`
+	Beneath      bool   `toml:"beneath"` // Never leave the document root, even through symbolic links (openat2 RESOLVE_BENEATH on Linux); set by server.hardened
+
+	MimeTypes   map[string]string `toml:"mime_types"`   // Content types by extension, e.g. ".tar.gz" = "application/gzip"; looked up case-insensitively before the built-in and system types
+	DefaultType string            `toml:"default_type"` // Content type of files with an unknown extension, default application/octet-stream
+
+	ETag string `toml:"etag"` // ETags of files: "weak" (modification time and size), "strong" (SHA-256 of the content, computed in the background) or empty for none
+
+	Watch bool `toml:"watch"` // Watch the document roots and drop the cached ETag sums and access files of changed files at once
 }
 
 // LogConfig holds logging configuration
 type LogConfig struct {
 	Format     string `toml:"format"`      // plain, verbose
-	FilePath   string `toml:"file_path"`   // Path to log file, empty for stdout
+	FilePath   string `toml:"file_path"`   // Path to log file, empty for stdout; may contain %Y, %m, %d and %H to roll over
 	AccessLogs bool   `toml:"access_logs"` // Enable HTTP access logging
+
+	Output        string `toml:"output"`         // file (default), stdout, syslog or journald
+	SyslogAddress string `toml:"syslog_address"` // udp://host:514, tcp://host:514 or unix:///path; empty for the local syslog
+	Facility      string `toml:"facility"`       // Syslog facility, e.g. daemon (default) or local0
+	Tag           string `toml:"tag"`            // Program name in syslog and journald, default volk
+	Compress      bool   `toml:"compress"`       // Compress log files with gzip when file_path rolls over
+
+	Sinks []LogSinkConfig `toml:"sink"` // Outputs written at once; replace output and file_path when set
+
+	AnonymizeIPs bool `toml:"anonymize_ips"` // Zero the last octet (IPv4) or 80 bits (IPv6) of client addresses in logs and the audit log
+
+	RedactHeaders []string `toml:"redact_headers"` // Headers whose values debug logs and dev error pages hide, default Authorization, Proxy-Authorization, Cookie and Set-Cookie
+
+	SlowRequestThresholdMs int `toml:"slow_request_threshold_ms"` // Log a warning with timings for requests taking longer, 0 to disable
+}
+
+// LogSinkConfig holds one of several outputs the log is written to at once
+type LogSinkConfig struct {
+	Output   string `toml:"output"`    // file, stdout, stderr, syslog or journald
+	FilePath string `toml:"file_path"` // File of a file sink, a pattern as in [logging]
+	Compress bool   `toml:"compress"`  // Compress the file with gzip when it rolls over
+	Format   string `toml:"format"`    // plain (default), verbose or json; system log sinks format lines themselves
+	Level    string `toml:"level"`     // Least severe lines written: debug (default), info, warning or error
+
+	SyslogAddress string `toml:"syslog_address"` // As in [logging], for syslog sinks
+	Facility      string `toml:"facility"`       // As in [logging], for syslog and journald sinks
+	Tag           string `toml:"tag"`            // As in [logging], for syslog and journald sinks
+}
+
+// StatsConfig holds settings for persisting aggregate request statistics
+type StatsConfig struct {
+	Enabled       bool   `toml:"enabled"`        // Enable statistics collection
+	FilePath      string `toml:"file_path"`      // File the statistics are appended to
+	FlushInterval int    `toml:"flush_interval"` // seconds between writes to the file
+	Beacon        bool   `toml:"beacon"`         // Count page views reported to BeaconPath
+	BeaconPath    string `toml:"beacon_path"`    // Path of the beacon endpoint, default /_beacon
+}
+
+// SummaryConfig holds settings for the periodic summary of requests in the log
+type SummaryConfig struct {
+	Enabled  bool `toml:"enabled"`  // Log a summary line every interval
+	Interval int  `toml:"interval"` // Seconds between summaries
+	Window   int  `toml:"window"`   // Seconds of requests each summary covers, rounded up to whole intervals; 0 for one interval
+	Top      int  `toml:"top"`      // Number of busiest paths listed
+}
+
+// AuditConfig holds settings for the audit log of write requests
+type AuditConfig struct {
+	FilePath string `toml:"file_path"` // Hash-chained JSON Lines file write requests are recorded in; empty to disable
+	Key      string `toml:"key"`       // Secret the chain's HMACs are computed with; required with file_path
+}
+
+// TeeConfig holds the mirror served responses are copied to
+type TeeConfig struct {
+	Directory string `toml:"directory"`  // Directory the response bodies are written to, keeping a static copy of the site
+	URL       string `toml:"url"`        // URL the responses are PUT to, followed by their path
+	Token     string `toml:"token"`      // Bearer token sent to the URL
+	QueueSize int    `toml:"queue_size"` // Responses waiting for the mirror before new ones are dropped
+}
+
+// GeoIPConfig holds settings for looking up client countries
+type GeoIPConfig struct {
+	Database string `toml:"database"` // Path to a MaxMind GeoLite2/GeoIP2 .mmdb file, empty to disable
+}
+
+// BotRuleConfig holds a rule for crawlers whose User-Agent matches Pattern
+type BotRuleConfig struct {
+	Pattern   string `toml:"pattern"`    // Regular expression matched against the User-Agent header
+	Action    string `toml:"action"`     // block, rate_limit or robots
+	RateLimit int    `toml:"rate_limit"` // Requests per minute and client, for the rate_limit action
+	Name      string `toml:"name"`       // User-agent token of the bot in robots.txt
+}
+
+// RobotsConfig holds settings for the generated robots.txt and sitemap.xml
+type RobotsConfig struct {
+	Generate        bool     `toml:"generate"`         // Serve a generated robots.txt when the document root has none
+	Sitemap         bool     `toml:"sitemap"`          // Serve a sitemap.xml of the HTML pages when the document root has none
+	BaseURL         string   `toml:"base_url"`         // Absolute URL of the site used in sitemap.xml, e.g. https://example.com; defaults to the request's Host
+	Deny            []string `toml:"deny"`             // Path patterns left out of sitemap.xml and disallowed in robots.txt
+	RefreshInterval int      `toml:"refresh_interval"` // Seconds between rescans of the document root for sitemap.xml
+}
+
+// OIDCConfig holds the OpenID Connect provider used by locations with auth = "oidc"
+type OIDCConfig struct {
+	Issuer          string   `toml:"issuer"`           // Issuer URL, e.g. https://accounts.example.com
+	ClientID        string   `toml:"client_id"`        // Client ID registered at the provider
+	ClientSecret    string   `toml:"client_secret"`    // Client secret registered at the provider
+	RedirectURL     string   `toml:"redirect_url"`     // Callback URL registered at the provider, e.g. https://example.com/_oidc/callback
+	CookieSecret    string   `toml:"cookie_secret"`    // Secret the session cookie is signed with
+	Scopes          []string `toml:"scopes"`           // Requested scopes, default openid, email and profile
+	SessionLifetime int      `toml:"session_lifetime"` // Seconds a session lasts, default 8 hours
+}
+
+// DownloadConfig holds settings for downloading directories as archives
+type DownloadConfig struct {
+	Enabled bool     `toml:"enabled"`  // Serve archives of directories for ?download=zip and ?download=tar.gz
+	Deny    []string `toml:"deny"`     // Path patterns left out of archives
+	MaxSize int64    `toml:"max_size"` // Bytes of files an archive may hold, 0 for no limit
+}
+
+// ThumbnailConfig holds settings for the image resizing endpoint
+type ThumbnailConfig struct {
+	Enabled  bool   `toml:"enabled"`   // Serve resized images of the document root
+	Path     string `toml:"path"`      // Path prefix, followed by <width>x<height> and the image path
+	CacheDir string `toml:"cache_dir"` // Directory resized images are cached in
+	MaxSize  int    `toml:"max_size"`  // Largest width and height in pixels
+}
+
+// KVConfig holds settings for the key-value JSON API
+type KVConfig struct {
+	Enabled  bool   `toml:"enabled"`  // Serve the key-value API
+	Database string `toml:"database"` // Path of the database file
+	Path     string `toml:"path"`     // Path prefix the API is served under
+	Quota    int64  `toml:"quota"`    // Bytes the keys and values may use, 0 for no limit
+}
+
+// DeployConfig holds settings for the deploy endpoint
+type DeployConfig struct {
+	Enabled  bool   `toml:"enabled"`  // Serve the deploy endpoint
+	Path     string `toml:"path"`     // Path of the endpoint
+	Token    string `toml:"token"`    // Bearer token required to deploy
+	Releases string `toml:"releases"` // Directory releases are extracted into
+	Keep     int    `toml:"keep"`     // Number of previous releases kept for rollback
+	Quota    int64  `toml:"quota"`    // Bytes the kept releases may use, 0 for no limit
+}
+
+// UploadConfig holds settings for the resumable upload endpoint
+type UploadConfig struct {
+	Enabled   bool   `toml:"enabled"`   // Serve the tus upload endpoint
+	Path      string `toml:"path"`      // Path prefix of the endpoint
+	Token     string `toml:"token"`     // Bearer token required to upload
+	Directory string `toml:"directory"` // Directory uploads are stored in
+	MaxSize   int64  `toml:"max_size"`  // Bytes an upload may have, 0 for no limit
+	Quota     int64  `toml:"quota"`     // Bytes the uploads may announce together, 0 for no limit
+
+	Storage StorageConfig `toml:"storage"` // Where uploads are stored, default the directory
+}
+
+// StorageConfig holds where objects such as uploads are stored: on the local
+// disk or in an S3-compatible object store
+type StorageConfig struct {
+	Type      string `toml:"type"`       // disk (default) or s3
+	Endpoint  string `toml:"endpoint"`   // URL of the S3 API, default https://s3.<region>.amazonaws.com
+	Region    string `toml:"region"`     // Region the requests are signed for, e.g. us-east-1 or auto for R2
+	Bucket    string `toml:"bucket"`     // Bucket the objects are stored in
+	Prefix    string `toml:"prefix"`     // Prepended to the keys of the objects, e.g. uploads/
+	AccessKey string `toml:"access_key"` // Access key ID
+	SecretKey string `toml:"secret_key"` // Secret access key
+	PathStyle bool   `toml:"path_style"` // Address the bucket in the path rather than the host name, as MinIO needs
+}
+
+// ScanConfig holds the antivirus scanner deploy archives and uploads are checked with
+type ScanConfig struct {
+	Command []string `toml:"command"` // Command the content is piped to, exiting with 0 for clean and 1 for infected, e.g. ["clamdscan", "--no-summary", "-"]
+	Clamd   string   `toml:"clamd"`   // clamd socket path or host:port; mutually exclusive with command
+	Timeout int      `toml:"timeout"` // Seconds a scan may take
+}
+
+// AdminConfig holds settings for the admin endpoint used by deploy orchestration
+type AdminConfig struct {
+	Enabled bool   `toml:"enabled"` // Serve the admin endpoint
+	Path    string `toml:"path"`    // Path prefix of the endpoint
+	Token   string `toml:"token"`   // Bearer token required to start or stop draining
+}
+
+// MetricsConfig holds settings for the OpenMetrics endpoint scraped by Prometheus
+type MetricsConfig struct {
+	Enabled bool      `toml:"enabled"` // Serve the metrics endpoint
+	Path    string    `toml:"path"`    // Path of the endpoint
+	Token   string    `toml:"token"`   // Bearer token required to scrape, empty for none
+	Buckets []float64 `toml:"buckets"` // Upper bounds in seconds of the request duration histogram, ascending
+	Disable []string  `toml:"disable"` // Metric families left out, e.g. ["volk_response_bytes"]
+	Hosts   []string  `toml:"hosts"`   // Hosts the byte counters are broken down by; requests for others are counted as "other"
+}
+
+// WebhookConfig holds settings for a webhook endpoint
+type WebhookConfig struct {
+	Path     string `toml:"path"`     // Path deliveries are posted to
+	Provider string `toml:"provider"` // Signature scheme: github or stripe
+	Secret   string `toml:"secret"`   // Secret shared with the provider
+	Spool    string `toml:"spool"`    // JSON Lines file verified deliveries are appended to
+	Command  string `toml:"command"`  // Shell command run for each delivery, with the payload on standard input
+
+	CommandTimeout int `toml:"command_timeout"` // Seconds a command may run before it is killed, default 60
+	MaxCommands    int `toml:"max_commands"`    // Commands of the webhook running at once, default 4; later deliveries wait for one to finish
+}
+
+// SessionConfig holds settings for cookies the server signs, such as flash messages
+type SessionConfig struct {
+	Secret string `toml:"secret"` // Secret cookies are signed with; a random one is generated at startup if empty
+}
+
+// TrapConfig holds a honeypot path, requested by scanners but no real visitor
+type TrapConfig struct {
+	Path       string `toml:"path"`        // Path pattern in path.Match syntax; a trailing slash matches a whole directory
+	Action     string `toml:"action"`      // tarpit or ban
+	Delay      int    `toml:"delay"`       // Seconds the tarpit response is dripped over, default 60
+	BanMinutes int    `toml:"ban_minutes"` // Minutes the client is denied every request, for the ban action, default 60
+}
+
+// PluginConfig holds a Go plugin loaded at startup that provides handlers
+type PluginConfig struct {
+	Path    string            `toml:"path"`    // Shared object built with go build -buildmode=plugin
+	Options map[string]string `toml:"options"` // Settings passed to the plugin's Handlers function
+}
+
+// LocationConfig holds settings that apply to requests whose path starts with Path
+type LocationConfig struct {
+	Path         string `toml:"path"`          // Path prefix the location applies to
+	DebugLogging bool   `toml:"debug_logging"` // Log headers, resolved file and timings for matching requests
+
+	AllowCountries []string `toml:"allow_countries"` // ISO country codes allowed to access the location (requires [geoip])
+	DenyCountries  []string `toml:"deny_countries"`  // ISO country codes denied access to the location (requires [geoip])
+
+	SignatureSecret string `toml:"signature_secret"` // Require requests signed with this HMAC secret
+	SignatureWindow int    `toml:"signature_window"` // Seconds a signed request stays valid, default 300
+
+	Auth string `toml:"auth"` // Authentication required for the location: "oidc" or empty for none
+
+	Scripts       []ScriptConfig `toml:"script"`         // Rules evaluated in order for each request of the location
+	ScriptHeaders []string       `toml:"script_headers"` // Request headers the location's scripts may read; empty for all but logging.redact_headers
+
+	Variants      []VariantConfig `toml:"variant"`        // Document roots the location's traffic is split between
+	VariantCookie string          `toml:"variant_cookie"` // Cookie that keeps a visitor on their variant, default volk_variant
+
+	Flavors []FlavorConfig `toml:"flavor"` // Document roots chosen by a request header, checked before variants
+
+	DisableCompression bool `toml:"disable_compression"` // Never compress the responses of the location, e.g. for a path serving secrets next to attacker-controlled text
+}
+
+// CompressionConfig holds settings for compressing responses with gzip
+type CompressionConfig struct {
+	Enabled      bool     `toml:"enabled"`       // Compress responses for clients that accept gzip
+	Level        int      `toml:"level"`         // gzip level from 1 (fastest) to 9 (smallest); 0 for the default, 6
+	MinSize      int      `toml:"min_size"`      // Smallest body in bytes worth compressing
+	Types        []string `toml:"types"`         // Content types to compress, e.g. text/* or application/*+json; empty for every type not excluded
+	ExcludeTypes []string `toml:"exclude_types"` // Content types never compressed, on top of images, video, audio, fonts and archives, which are compressed already
+}
+
+// QueryConfig holds settings for splitting and decoding query strings, which
+// applications disagree on
+type QueryConfig struct {
+	Semicolons  bool `toml:"semicolons"`    // Treat ';' as a parameter separator like '&', as legacy applications expect
+	Arrays      bool `toml:"arrays"`        // Parse key[]=a&key[]=b as the values a and b of key
+	PlusAsSpace bool `toml:"plus_as_space"` // Decode '+' as a space in query strings; forms always do
+}
+
+// UserDirConfig holds settings for serving the directories of users on their subdomains
+type UserDirConfig struct {
+	Enabled  bool              `toml:"enabled"`  // Serve the directory of a user on a subdomain, e.g. /home/alice/public_html on alice.example.com
+	Domain   string            `toml:"domain"`   // Domain the user subdomains are below, e.g. example.com
+	Template string            `toml:"template"` // Directory of a user, {user} being replaced by the name; default /home/{user}/public_html
+	Users    map[string]string `toml:"users"`    // Directories of users that differ from the template, by user name
+	Deny     []string          `toml:"deny"`     // Users whose directories are never served, e.g. root
+}
+
+// OpenAPIConfig holds settings for checking API requests against an OpenAPI description
+type OpenAPIConfig struct {
+	Spec     string `toml:"spec"`      // OpenAPI 3 document in JSON or YAML; requests to its paths are validated before they are handled, empty to disable
+	BasePath string `toml:"base_path"` // Path prefix of the API, e.g. /api when the document's /items is served at /api/items
+	Path     string `toml:"path"`      // Path the document is served at, e.g. /openapi.yaml; empty to not serve it
+}
+
+// FlavorConfig holds a document root served to requests carrying a header value
+type FlavorConfig struct {
+	Header       string `toml:"header"`        // Request header to look at, e.g. X-Env
+	Value        string `toml:"value"`         // Value the header must have
+	DocumentRoot string `toml:"document_root"` // Directory the flavor's files are served from
+}
+
+// VariantConfig holds one side of an A/B test: a document root and its share of the traffic
+type VariantConfig struct {
+	Name         string `toml:"name"`          // Name stored in the cookie and shown in the access log
+	Weight       int    `toml:"weight"`        // Share of new visitors, relative to the weights of the other variants
+	DocumentRoot string `toml:"document_root"` // Directory the variant's files are served from
+}
+
+// ScriptConfig holds a rule that redirects, rewrites or changes headers of the requests matching a condition
+type ScriptConfig struct {
+	If              string            `toml:"if"`               // Condition, e.g. header("User-Agent") contains "Mobile"; empty always matches
+	Redirect        string            `toml:"redirect"`         // Redirect to this URL; variables such as $path are expanded
+	Status          int               `toml:"status"`           // Redirect status, default 302
+	Rewrite         string            `toml:"rewrite"`          // Serve this path instead; variables are expanded
+	RequestHeaders  map[string]string `toml:"request_headers"`  // Headers set on the request; variables are expanded
+	ResponseHeaders map[string]string `toml:"response_headers"` // Headers added to the response; variables are expanded
+	Last            bool              `toml:"last"`             // Skip the following rules if this one matches
+}
+
+// ProbesConfig holds settings for the liveness and readiness endpoints of container orchestrators
+type ProbesConfig struct {
+	Enabled   bool   `toml:"enabled"`   // Serve the probe endpoints, without authentication
+	Liveness  string `toml:"liveness"`  // Path answering 200 while the process runs
+	Readiness string `toml:"readiness"` // Path answering 200, or 503 while the server drains
 }
 
 // Config is the root configuration structure
 type Config struct {
-	Server     ServerConfig     `toml:"server"`
-	FileServer FileServerConfig `toml:"file_server"`
-	Logging    LogConfig        `toml:"logging"`
+	Server      ServerConfig      `toml:"server"`
+	FileServer  FileServerConfig  `toml:"file_server"`
+	Compression CompressionConfig `toml:"compression"`
+	Query       QueryConfig       `toml:"query"`
+	UserDir     UserDirConfig     `toml:"userdir"`
+	OpenAPI     OpenAPIConfig     `toml:"openapi"`
+	Logging     LogConfig         `toml:"logging"`
+	Stats       StatsConfig       `toml:"stats"`
+	Summary     SummaryConfig     `toml:"summary"`
+	Audit       AuditConfig       `toml:"audit"`
+	Tee         TeeConfig         `toml:"tee"`
+	GeoIP       GeoIPConfig       `toml:"geoip"`
+	Robots      RobotsConfig      `toml:"robots"`
+	OIDC        OIDCConfig        `toml:"oidc"`
+	Session     SessionConfig     `toml:"session"`
+	Download    DownloadConfig    `toml:"download"`
+	Thumbnail   ThumbnailConfig   `toml:"thumbnail"`
+	KV          KVConfig          `toml:"kv"`
+	Webhooks    []WebhookConfig   `toml:"webhook"`
+	Deploy      DeployConfig      `toml:"deploy"`
+	Upload      UploadConfig      `toml:"upload"`
+	Scan        ScanConfig        `toml:"scan"`
+	Admin       AdminConfig       `toml:"admin"`
+	Metrics     MetricsConfig     `toml:"metrics"`
+	Probes      ProbesConfig      `toml:"probes"`
+	BotRules    []BotRuleConfig   `toml:"bot_rule"`
+	Traps       []TrapConfig      `toml:"trap"`
+	Plugins     []PluginConfig    `toml:"plugin"`
+	Locations   []LocationConfig  `toml:"location"`
 }
 
 // DefaultConfig returns the default configuration
 func DefaultConfig() Config {
 	return Config{
 		Server: ServerConfig{
+			Host:        "localhost",
 			Port:        6543,
 			ReadTimeout: 30,
+			MaxBodySize: 10 << 20,
+
+			ShutdownTimeout: 30,
+			TCPNoDelay:      true,
+			Mode:            "goroutine",
 		},
 		FileServer: FileServerConfig{
 			DocumentRoot: ".",
@@ -52,40 +450,164 @@ func DefaultConfig() Config {
 			Format:     "plain",
 			FilePath:   "",
 			AccessLogs: true,
+
+			RedactHeaders: []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"},
+		},
+		Stats: StatsConfig{
+			Enabled:       false,
+			FilePath:      "volk_stats.jsonl",
+			FlushInterval: 60,
+			BeaconPath:    "/_beacon",
 		},
+		Summary: SummaryConfig{
+			Interval: 60,
+			Top:      10,
+		},
+		Compression: CompressionConfig{
+			MinSize: 1024,
+			Types: []string{
+				"text/*", "application/json", "application/*+json", "application/javascript",
+				"application/xml", "application/*+xml", "application/wasm", "image/svg+xml",
+			},
+		},
+		Tee: TeeConfig{
+			QueueSize: 1000,
+		},
+		Robots: RobotsConfig{
+			RefreshInterval: 60,
+		},
+		Download: DownloadConfig{
+			MaxSize: 1 << 30,
+		},
+		Thumbnail: ThumbnailConfig{
+			Path:     "/img/",
+			CacheDir: "volk_thumbnails",
+			MaxSize:  2000,
+		},
+		KV: KVConfig{
+			Database: "volk_kv.db",
+			Path:     "/api/kv/",
+		},
+		Deploy: DeployConfig{
+			Path:     "/_deploy",
+			Releases: "releases",
+			Keep:     5,
+		},
+		Upload: UploadConfig{
+			Path:      "/_uploads/",
+			Directory: "uploads",
+			MaxSize:   1 << 30,
+		},
+		Scan: ScanConfig{
+			Timeout: 60,
+		},
+		Admin: AdminConfig{
+			Path: "/_admin",
+		},
+		Metrics: MetricsConfig{
+			Path:    "/metrics",
+			Buckets: []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		},
+		Probes: ProbesConfig{
+			Liveness:  "/healthz",
+			Readiness: "/readyz",
+		},
+	}
+}
+
+// ContainerConfig returns the default configuration of container mode: the
+// server listens on every interface, logs JSON lines to standard output,
+// serves the probe endpoints and drains for 5 seconds after SIGTERM.
+func ContainerConfig() Config {
+	config := DefaultConfig()
+	config.Server.Host = "0.0.0.0"
+	config.Server.DrainDelay = 5
+	config.Logging.Output = "stdout"
+	config.Logging.Sinks = []LogSinkConfig{{Output: "stdout", Format: "json"}}
+	config.Probes.Enabled = true
+	return config
+}
+
+// LoadEnv returns the configuration of container mode, read from the
+// environment variables in environ instead of a file. See ApplyEnv.
+func LoadEnv(environ []string) (Config, error) {
+	config := ContainerConfig()
+	if err := ApplyEnv(&config, environ); err != nil {
+		return config, fmt.Errorf("error reading configuration from the environment: %w", err)
 	}
+
+	if !filepath.IsAbs(config.FileServer.DocumentRoot) {
+		absPath, err := filepath.Abs(config.FileServer.DocumentRoot)
+		if err != nil {
+			return config, fmt.Errorf("could not determine absolute path for document_root: %w", err)
+		}
+		config.FileServer.DocumentRoot = absPath
+	}
+	return config, nil
 }
 
 func (c Config) String() string {
-	return fmt.Sprintf(`
-[server]
-port = %d
-read_timeout = %d
+	var builder strings.Builder
+	encoder := toml.NewEncoder(&builder)
+	encoder.Indent = ""
+	if err := encoder.Encode(c); err != nil {
+		return fmt.Sprintf("error encoding config: %v", err)
+	}
+	return strings.TrimRight(builder.String(), "\n")
+}
+
+// foldLocationCase reports whether locations match paths without regard to
+// case, as the default file systems of Windows and macOS name files. Otherwise
+// /Private/secret.txt would serve /private/secret.txt without its location.
+var foldLocationCase = runtime.GOOS == "windows" || runtime.GOOS == "darwin"
 
-[file_server]
-document_root = "%s"
-default_file = "%s"
+// Location returns the location whose path is the longest prefix of the
+// request path that ends at a segment boundary.
+func (c Config) Location(path string) (LocationConfig, bool) {
+	var match LocationConfig
+	found := false
+	for _, location := range c.Locations {
+		if locationMatches(location.Path, path, foldLocationCase) && (!found || len(location.Path) > len(match.Path)) {
+			match = location
+			found = true
+		}
+	}
+	return match, found
+}
 
-[logging]
-format = "%s"
-file_path = "%s"
-access_logs = %t`,
-		c.Server.Port, c.Server.ReadTimeout,
-		c.FileServer.DocumentRoot, c.FileServer.DefaultFile,
-		c.Logging.Format, c.Logging.FilePath, c.Logging.AccessLogs)
+// locationMatches reports whether the location path prefix covers path. The
+// prefix must end at a segment boundary, so /api covers /api and /api/users
+// but not /apiary, and a trailing slash is optional: /p/ also covers /p.
+func locationMatches(prefix, path string, foldCase bool) bool {
+	if foldCase {
+		prefix, path = strings.ToLower(prefix), strings.ToLower(path)
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	rest, ok := strings.CutPrefix(path, prefix)
+	return ok && (rest == "" || rest[0] == '/')
 }
 
 // LoadConfig loads configuration from a TOML file.
+// If path is empty, the file is looked up with FindConfigFile and the default
+// configuration is returned when none exists. An explicitly given path must exist.
 // It returns the configuration and an error, if any.
-func LoadConfig() (Config, error) {
+func LoadConfig(path string) (Config, error) {
 	config := DefaultConfig()
 
-	if _, err := os.Stat(configFileName); os.IsNotExist(err) {
-		return DefaultConfig(), nil
+	if path == "" {
+		path = FindConfigFile()
+		if path == "" {
+			return DefaultConfig(), nil
+		}
+	} else if _, err := os.Stat(path); err != nil {
+		return config, fmt.Errorf("error reading config file: %w", err)
 	}
 
-	_, err := toml.DecodeFile(configFileName, &config)
+	data, err := os.ReadFile(path)
 	if err != nil {
+		return config, fmt.Errorf("error reading config file: %w", err)
+	}
+	if err := decodeFile(path, string(data), &config); err != nil {
 		return config, fmt.Errorf("error decoding config file: %w", err)
 	}
 
@@ -0,0 +1,75 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestLocation(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Locations = []LocationConfig{
+		{Path: "/"},
+		{Path: "/api/", DebugLogging: true},
+		{Path: "/api/v2/"},
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/index.html", "/"},
+		{"/api/users", "/api/"},
+		{"/api/v2/users", "/api/v2/"},
+		{"/apis", "/"},
+		{"/api", "/api/"},
+		{"/api/v2", "/api/v2/"},
+		{"/api/v20", "/api/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			location, ok := cfg.Location(tt.path)
+			if !ok {
+				t.Fatalf("Location(%q) found no location, expected %q", tt.path, tt.expected)
+			}
+			if location.Path != tt.expected {
+				t.Errorf("Location(%q) returned %q, expected %q", tt.path, location.Path, tt.expected)
+			}
+		})
+	}
+
+	cfg.Locations = []LocationConfig{{Path: "/api"}}
+	if _, ok := cfg.Location("/apiary"); ok {
+		t.Errorf("Location(%q) matched /api, expected locations to end at a segment boundary", "/apiary")
+	}
+
+	if _, ok := DefaultConfig().Location("/index.html"); ok {
+		t.Errorf("Location should find nothing when no locations are configured")
+	}
+}
+
+func TestLocationMatches(t *testing.T) {
+	tests := []struct {
+		prefix   string
+		path     string
+		foldCase bool
+		expected bool
+	}{
+		{"/", "/", false, true},
+		{"/", "/a", false, true},
+		{"/api", "/api", false, true},
+		{"/api", "/api/users", false, true},
+		{"/api", "/apiary", false, false},
+		{"/p/", "/p", false, true},
+		{"/p/", "/p/x", false, true},
+		{"/p/", "/px", false, false},
+		{"/private/", "/Private/secret.txt", false, false},
+		{"/private/", "/Private/secret.txt", true, true},
+		{"/private/", "/PRIVATE", true, true},
+	}
+
+	for _, tt := range tests {
+		if got := locationMatches(tt.prefix, tt.path, tt.foldCase); got != tt.expected {
+			t.Errorf("locationMatches(%q, %q, %v) = %v, expected %v", tt.prefix, tt.path, tt.foldCase, got, tt.expected)
+		}
+	}
+}
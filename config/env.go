@@ -0,0 +1,161 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the names of the environment variables that set
+// configuration keys: server.port is set by VOLK_SERVER_PORT and
+// file_server.document_root by VOLK_FILE_SERVER_DOCUMENT_ROOT.
+const EnvPrefix = "VOLK_"
+
+// envFileSuffix ends the name of a variable holding the path of a file with
+// the value, such as a Kubernetes secret or downward API volume:
+// VOLK_ADMIN_TOKEN_FILE=/etc/podinfo/token.
+const envFileSuffix = "_FILE"
+
+// envField is a key that can be set from the environment.
+type envField struct {
+	key   string // Dotted key, e.g. server.port
+	value reflect.Value
+}
+
+// envFields returns the keys of the tables of cfg holding strings, booleans,
+// numbers or arrays of them, by environment variable. Arrays of tables and
+// tables of values cannot be set from the environment.
+func envFields(cfg *Config) map[string]envField {
+	fields := map[string]envField{}
+	var walk func(prefix string, v reflect.Value)
+	walk = func(prefix string, v reflect.Value) {
+		t := v.Type()
+		for i := range t.NumField() {
+			name := tomlName(t.Field(i))
+			if name == "" {
+				continue
+			}
+			key := prefix + name
+			value := v.Field(i)
+			switch {
+			case value.Kind() == reflect.Struct:
+				walk(key+".", value)
+			case value.Kind() == reflect.Map:
+			case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Struct:
+			case prefix != "":
+				env := EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+				fields[env] = envField{key: key, value: value}
+			}
+		}
+	}
+	walk("", reflect.ValueOf(cfg).Elem())
+	return fields
+}
+
+// ApplyEnv sets the keys of cfg named by the variables of environ, a list of
+// NAME=value pairs as returned by os.Environ. A variable ending in _FILE sets
+// the key to the contents of the file it names, without a trailing newline.
+// Arrays are given as comma-separated values. A variable that starts with
+// the name of a table but names none of its keys is an error, like an unknown
+// key in the configuration file.
+func ApplyEnv(cfg *Config, environ []string) error {
+	fields := envFields(cfg)
+	var tables []string
+	configType := reflect.TypeOf(*cfg)
+	for i := range configType.NumField() {
+		if name := tomlName(configType.Field(i)); name != "" {
+			tables = append(tables, EnvPrefix+strings.ToUpper(name)+"_")
+		}
+	}
+
+	var unknown []string
+	for _, pair := range environ {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || !strings.HasPrefix(name, EnvPrefix) {
+			continue
+		}
+		field, ok := fields[name]
+		if !ok {
+			if base, isFile := strings.CutSuffix(name, envFileSuffix); isFile {
+				if field, ok = fields[base]; ok {
+					data, err := os.ReadFile(value)
+					if err != nil {
+						return fmt.Errorf("error reading %s: %w", name, err)
+					}
+					value = strings.TrimRight(string(data), "\r\n")
+				}
+			}
+		}
+		if !ok {
+			for _, table := range tables {
+				if strings.HasPrefix(name, table) {
+					unknown = append(unknown, name)
+					break
+				}
+			}
+			continue
+		}
+		if err := setEnvValue(field.value, value); err != nil {
+			return fmt.Errorf("error setting %s from %s: %w", field.key, name, err)
+		}
+	}
+
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("unknown environment variables: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// setEnvValue sets v from the text of an environment variable.
+func setEnvValue(v reflect.Value, text string) error {
+	if v.Kind() == reflect.Slice {
+		items := []string{}
+		if text != "" {
+			items = strings.Split(text, ",")
+		}
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setEnvValue(slice.Index(i), strings.TrimSpace(item)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", text)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", text)
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", text)
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(text, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", text)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("%s values cannot be set from the environment", v.Kind())
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestApplyEnv(t *testing.T) {
+	token := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(token, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig()
+	err := ApplyEnv(&cfg, []string{
+		"VOLK_SERVER_PORT=8080",
+		"VOLK_SERVER_HARDENED=true",
+		"VOLK_FILE_SERVER_DOCUMENT_ROOT=/srv/www",
+		"VOLK_SERVER_MAX_BODY_SIZE=1048576",
+		"VOLK_METRICS_BUCKETS=0.1, 1,10",
+		"VOLK_ADMIN_TOKEN_FILE=" + token,
+		"VOLK_WORKER=1",
+		"HOME=/root",
+		"malformed",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		got      any
+		expected any
+	}{
+		{"integer", cfg.Server.Port, 8080},
+		{"boolean", cfg.Server.Hardened, true},
+		{"table with an underscore", cfg.FileServer.DocumentRoot, "/srv/www"},
+		{"int64", cfg.Server.MaxBodySize, int64(1 << 20)},
+		{"array", cfg.Metrics.Buckets, []float64{0.1, 1, 10}},
+		{"file", cfg.Admin.Token, "s3cret"},
+		{"unset", cfg.Server.Host, "localhost"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, tt.got)
+			}
+		})
+	}
+}
+
+func TestApplyEnvErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		env  string
+	}{
+		{"unknown key", "VOLK_SERVER_PROT=8080"},
+		{"invalid integer", "VOLK_SERVER_PORT=http"},
+		{"invalid boolean", "VOLK_PROBES_ENABLED=maybe"},
+		{"missing file", "VOLK_ADMIN_TOKEN_FILE=/nonexistent/token"},
+		{"array of tables", "VOLK_LOCATION_PATH=/api/"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			if err := ApplyEnv(&cfg, []string{tt.env}); err == nil {
+				t.Errorf("Expected an error for %s, got none", tt.env)
+			}
+		})
+	}
+}
+
+func TestLoadEnv(t *testing.T) {
+	cfg, err := LoadEnv([]string{"VOLK_PROBES_READINESS=/ready", "VOLK_FILE_SERVER_DOCUMENT_ROOT=public"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if cfg.Server.Host != "0.0.0.0" {
+		t.Errorf("Expected host 0.0.0.0, got %s", cfg.Server.Host)
+	}
+	if !cfg.Probes.Enabled || cfg.Probes.Readiness != "/ready" || cfg.Probes.Liveness != "/healthz" {
+		t.Errorf("Expected probes at /healthz and /ready, got %+v", cfg.Probes)
+	}
+	if len(cfg.Logging.Sinks) != 1 || cfg.Logging.Sinks[0].Format != "json" {
+		t.Errorf("Expected a JSON log sink, got %+v", cfg.Logging.Sinks)
+	}
+	if !filepath.IsAbs(cfg.FileServer.DocumentRoot) {
+		t.Errorf("Expected an absolute document root, got %s", cfg.FileServer.DocumentRoot)
+	}
+}
@@ -0,0 +1,168 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// ConfigError is an error in a configuration file, with where it is and how
+// to fix it.
+type ConfigError struct {
+	Path    string // Configuration file
+	Line    int    // Line of the error, 0 if unknown
+	Key     string // Dotted key the error is about, empty if unknown
+	Message string
+	Hint    string // Suggested fix, empty if there is none
+}
+
+func (e *ConfigError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Path)
+	if e.Line > 0 {
+		fmt.Fprintf(&b, ":%d", e.Line)
+	}
+	b.WriteString(": ")
+	if e.Key != "" {
+		b.WriteString(e.Key + ": ")
+	}
+	b.WriteString(e.Message)
+	if e.Hint != "" {
+		b.WriteString(" (" + e.Hint + ")")
+	}
+	return b.String()
+}
+
+// decodeFile decodes data, the configuration file at path, into cfg, rejecting
+// keys cfg does not have.
+func decodeFile(path string, data string, cfg *Config) error {
+	meta, err := toml.Decode(data, cfg)
+	if err != nil {
+		return decodeError(path, err)
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		key := undecoded[0].String()
+		e := &ConfigError{Path: path, Line: keyLine(data, key), Key: key, Message: "unknown key"}
+		if suggestion := suggestKey(key); suggestion != "" {
+			e.Hint = fmt.Sprintf("did you mean %s?", suggestion)
+		}
+		return e
+	}
+	return nil
+}
+
+// typeError matches the errors of the toml package for values of the wrong type.
+var typeError = regexp.MustCompile(`^toml: line (\d+) \(last key "([^"]+)"\): (.*)$`)
+
+// decodeError converts an error of the toml package to a ConfigError.
+func decodeError(path string, err error) error {
+	var parseErr toml.ParseError
+	if errors.As(err, &parseErr) {
+		e := &ConfigError{Path: path, Line: parseErr.Position.Line, Key: parseErr.LastKey, Message: parseErr.Message}
+		if strings.HasPrefix(parseErr.Message, "expected value") {
+			e.Hint = typeHint(e.Key)
+		}
+		return e
+	}
+	if m := typeError.FindStringSubmatch(err.Error()); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		return &ConfigError{Path: path, Line: line, Key: m[2], Message: m[3], Hint: typeHint(m[2])}
+	}
+	return &ConfigError{Path: path, Message: err.Error()}
+}
+
+// typeHint returns a hint naming the type of the value of key.
+func typeHint(key string) string {
+	field, ok := schemaField(key)
+	if !ok || field.Default == "" {
+		return ""
+	}
+	name := key[strings.LastIndex(key, ".")+1:]
+	return fmt.Sprintf("%s takes %s %s, e.g. %s = %s", key, article(field.Type), field.Type, name, field.Default)
+}
+
+// article returns the indefinite article of a type name.
+func article(typ string) string {
+	if strings.ContainsAny(typ[:1], "aeiou") {
+		return "an"
+	}
+	return "a"
+}
+
+// keyLine returns the line of the file data where the dotted key is set or
+// its table starts, or 0 if it cannot be found.
+func keyLine(data, key string) int {
+	table := ""
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") {
+			end := strings.Index(line, "]")
+			if end < 0 {
+				continue
+			}
+			table = strings.Trim(strings.TrimSpace(strings.Trim(line[:end], "[")), `"`)
+			if table == key {
+				return i + 1
+			}
+			continue
+		}
+		name, _, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		name = strings.Trim(strings.TrimSpace(name), `"`)
+		if table != "" {
+			name = table + "." + name
+		}
+		if name == key {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// suggestKey returns the known key closest to an unknown one in the same
+// table, or "" if none is close.
+func suggestKey(key string) string {
+	parent, name := "", key
+	if i := strings.LastIndex(key, "."); i >= 0 {
+		parent, name = key[:i+1], key[i+1:]
+	}
+
+	best, bestDistance := "", max(2, len(name)/3)+1
+	for _, field := range Schema() {
+		candidate, ok := strings.CutPrefix(field.Key, parent)
+		if !ok || strings.Contains(candidate, ".") {
+			continue
+		}
+		if d := editDistance(name, candidate); d < bestDistance {
+			best, bestDistance = field.Key, d
+		}
+	}
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(prev[j]+1, current[j-1]+1, prev[j-1]+cost)
+		}
+		prev = current
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected ConfigError
+	}{
+		{"wrong type", "[server]\nport = \"abc\"\n", ConfigError{Line: 2, Key: "server.port", Message: "incompatible types: TOML value has type string; destination has type integer", Hint: "server.port takes an integer, e.g. port = 6543"}},
+		{"missing value", "[server]\n\nhost =\n", ConfigError{Line: 3, Key: "server.host", Message: "expected value but found '\\n' instead", Hint: `server.host takes a string, e.g. host = "localhost"`}},
+		{"misspelled key", "[server]\nport = 80\nreed_timeout = 5\n", ConfigError{Line: 3, Key: "server.reed_timeout", Message: "unknown key", Hint: "did you mean server.read_timeout?"}},
+		{"misspelled table", "[logging]\naccess_logs = true\n\n[stat]\nenabled = true\n", ConfigError{Line: 4, Key: "stat", Message: "unknown key", Hint: "did you mean stats?"}},
+		{"array of tables", "[[location]]\npath = \"/\"\n\n[[location]]\npath = \"/api/\"\ndebug_loging = true\n", ConfigError{Line: 6, Key: "location.debug_loging", Message: "unknown key", Hint: "did you mean location.debug_logging?"}},
+		{"unknown key without suggestion", "[server]\ncolour = \"blue\"\n", ConfigError{Line: 2, Key: "server.colour", Message: "unknown key"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "volk_config.toml")
+			if err := os.WriteFile(path, []byte(tt.data), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := LoadConfig(path)
+			var got *ConfigError
+			if !errors.As(err, &got) {
+				t.Fatalf("Expected a ConfigError, got %v", err)
+			}
+			tt.expected.Path = path
+			if *got != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, *got)
+			}
+		})
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"port", "port", 0},
+		{"prot", "port", 2},
+		{"stat", "stats", 1},
+		{"", "abc", 3},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.expected {
+			t.Errorf("Expected the distance between %q and %q to be %d, got %d", tt.a, tt.b, tt.expected, got)
+		}
+	}
+}
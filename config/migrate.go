@@ -0,0 +1,162 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// migration moves or removes a key of an older configuration layout.
+type migration struct {
+	Old  string // Dotted key in the old layout
+	New  string // Dotted key it moved to, empty if it was removed
+	Note string // Why the key was removed, or what to use instead
+}
+
+// migrations are the keys older versions accepted, in the order they are applied.
+var migrations = []migration{
+	{Old: "security.max_request_size", New: "server.max_body_size"},
+	{Old: "server.write_timeout", Note: "responses have no write timeout"},
+	{Old: "server.max_connections", Note: "limit the connections per client with server.max_connections_per_ip instead"},
+	{Old: "file_server.allow_directory_listing", Note: "directory listings are not served"},
+	{Old: "file_server.mime_type_overrides", New: "file_server.mime_types"},
+	{Old: "security.allow_directory_traversal", Note: "paths can never leave the document root"},
+	{Old: "security.allowed_origins", Note: "set CORS headers with response_headers in a [[location.script]] rule instead"},
+}
+
+// Migrate converts a configuration file in an older layout to the current
+// one. Moved keys are moved, security.rate_limit becomes a rate_limit bot
+// rule for every client, and removed or unknown keys are dropped. Every
+// change is described in the returned warnings. Comments are not kept.
+func Migrate(data string) (string, []string, error) {
+	tree := map[string]any{}
+	if _, err := toml.Decode(data, &tree); err != nil {
+		return "", nil, decodeError("config", err)
+	}
+
+	var warnings []string
+	for _, m := range migrations {
+		value, ok := removeKey(tree, m.Old)
+		if !ok {
+			continue
+		}
+		if m.New == "" {
+			warnings = append(warnings, fmt.Sprintf("%s was removed: %s", m.Old, m.Note))
+			continue
+		}
+		if _, exists := lookupKey(tree, m.New); exists {
+			warnings = append(warnings, fmt.Sprintf("%s was dropped: %s is already set", m.Old, m.New))
+			continue
+		}
+		setKey(tree, m.New, value)
+		warnings = append(warnings, fmt.Sprintf("%s was renamed to %s", m.Old, m.New))
+	}
+
+	if limit, ok := removeKey(tree, "security.rate_limit"); ok {
+		rules, _ := tree["bot_rule"].([]map[string]any)
+		tree["bot_rule"] = append(rules, map[string]any{"pattern": "", "action": "rate_limit", "rate_limit": limit})
+		warnings = append(warnings, "security.rate_limit became a [[bot_rule]] limiting every client with rate_limit")
+	}
+
+	warnings = append(warnings, dropUnknown(tree, "")...)
+
+	var b strings.Builder
+	encoder := toml.NewEncoder(&b)
+	encoder.Indent = ""
+	if err := encoder.Encode(tree); err != nil {
+		return "", warnings, fmt.Errorf("error encoding config: %w", err)
+	}
+	migrated := b.String()
+
+	cfg := DefaultConfig()
+	if err := decodeFile("migrated config", migrated, &cfg); err != nil {
+		return "", warnings, err
+	}
+	return migrated, warnings, nil
+}
+
+// lookupKey returns the value of a dotted key below the tables of tree.
+func lookupKey(tree map[string]any, key string) (any, bool) {
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		table, ok := tree[part].(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		tree = table
+	}
+	value, ok := tree[parts[len(parts)-1]]
+	return value, ok
+}
+
+// removeKey removes a dotted key and returns its value. A table left empty
+// is removed too.
+func removeKey(tree map[string]any, key string) (any, bool) {
+	table, name, found := strings.Cut(key, ".")
+	if !found {
+		value, ok := tree[key]
+		delete(tree, key)
+		return value, ok
+	}
+	child, ok := tree[table].(map[string]any)
+	if !ok {
+		return nil, false
+	}
+	value, ok := removeKey(child, name)
+	if len(child) == 0 {
+		delete(tree, table)
+	}
+	return value, ok
+}
+
+// setKey sets a dotted key, creating its tables.
+func setKey(tree map[string]any, key string, value any) {
+	parts := strings.Split(key, ".")
+	for _, part := range parts[:len(parts)-1] {
+		table, ok := tree[part].(map[string]any)
+		if !ok {
+			table = map[string]any{}
+			tree[part] = table
+		}
+		tree = table
+	}
+	tree[parts[len(parts)-1]] = value
+}
+
+// dropUnknown removes the keys of tree, whose keys start with prefix, that
+// are not in the schema, and returns a warning for each.
+func dropUnknown(tree map[string]any, prefix string) []string {
+	names := make([]string, 0, len(tree))
+	for name := range tree {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var warnings []string
+	for _, name := range names {
+		key := prefix + name
+		field, ok := schemaField(key)
+		if !ok {
+			warning := fmt.Sprintf("%s is unknown and was dropped", key)
+			if suggestion := suggestKey(key); suggestion != "" {
+				warning += fmt.Sprintf(" (did you mean %s?)", suggestion)
+			}
+			warnings = append(warnings, warning)
+			delete(tree, name)
+			continue
+		}
+		switch value := tree[name].(type) {
+		case map[string]any:
+			if field.Type == "table" {
+				warnings = append(warnings, dropUnknown(value, key+".")...)
+			}
+		case []map[string]any:
+			for _, table := range value {
+				warnings = append(warnings, dropUnknown(table, key+".")...)
+			}
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+)
+
+func TestMigrate(t *testing.T) {
+	data, err := os.ReadFile("default-config.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = append(data, "\n[stat]\nenabled = true\n\n[[location]]\npath = \"/\"\ndebug_loging = true\n"...)
+
+	migrated, warnings, err := Migrate(string(data))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	cfg := DefaultConfig()
+	if _, err := toml.Decode(migrated, &cfg); err != nil {
+		t.Fatalf("Expected the migrated config to decode, got %v", err)
+	}
+	if cfg.Server.MaxBodySize != 1048576 || cfg.Server.Port != 8000 || cfg.Logging.Format != "plain" {
+		t.Errorf("Expected the settings to be kept, got %+v", cfg.Server)
+	}
+	if len(cfg.BotRules) != 1 || cfg.BotRules[0] != (BotRuleConfig{Action: "rate_limit", RateLimit: 60}) {
+		t.Errorf("Expected the rate limit to become a bot rule, got %+v", cfg.BotRules)
+	}
+	if cfg.FileServer.MimeTypes[".custom"] != "text/plain" {
+		t.Errorf("Expected the MIME type overrides to be kept, got %v", cfg.FileServer.MimeTypes)
+	}
+	if len(cfg.Locations) != 1 || cfg.Locations[0].Path != "/" {
+		t.Errorf("Expected the location to be kept, got %+v", cfg.Locations)
+	}
+
+	for _, warning := range []string{
+		"security.max_request_size was renamed to server.max_body_size",
+		"server.write_timeout was removed: responses have no write timeout",
+		"file_server.mime_type_overrides was renamed to file_server.mime_types",
+		"security.rate_limit became a [[bot_rule]] limiting every client with rate_limit",
+		"stat is unknown and was dropped (did you mean stats?)",
+		"location.debug_loging is unknown and was dropped (did you mean location.debug_logging?)",
+	} {
+		if !slices.Contains(warnings, warning) {
+			t.Errorf("Expected the warning %q, got %q", warning, warnings)
+		}
+	}
+}
+
+func TestMigrateKeepsNewKey(t *testing.T) {
+	migrated, warnings, err := Migrate("[server]\nmax_body_size = 5\n\n[security]\nmax_request_size = 10\n")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if migrated != "[server]\nmax_body_size = 5\n" {
+		t.Errorf("Expected only the current key, got %q", migrated)
+	}
+	if want := []string{"security.max_request_size was dropped: server.max_body_size is already set"}; !slices.Equal(warnings, want) {
+		t.Errorf("Expected warnings %q, got %q", want, warnings)
+	}
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// RedactedValue replaces secrets in Redacted.
+const RedactedValue = "********"
+
+// Redacted returns a copy of c with its tokens and secrets replaced by
+// RedactedValue, for showing the configuration to people.
+func (c Config) Redacted() Config {
+	redactValue(reflect.ValueOf(&c).Elem())
+	return c
+}
+
+// redactValue replaces the non-empty secret string fields of the struct v,
+// copying the slices of structs it changes so the original keeps its secrets.
+func redactValue(v reflect.Value) {
+	t := v.Type()
+	for i := range t.NumField() {
+		field := v.Field(i)
+		switch field.Kind() {
+		case reflect.Struct:
+			redactValue(field)
+		case reflect.Slice:
+			if field.Type().Elem().Kind() != reflect.Struct || field.Len() == 0 {
+				continue
+			}
+			copied := reflect.MakeSlice(field.Type(), field.Len(), field.Len())
+			reflect.Copy(copied, field)
+			for j := range copied.Len() {
+				redactValue(copied.Index(j))
+			}
+			field.Set(copied)
+		case reflect.String:
+			if isSecret(tomlName(t.Field(i))) && field.String() != "" {
+				field.SetString(RedactedValue)
+			}
+		}
+	}
+}
+
+// isSecret reports whether a key holds a secret.
+func isSecret(key string) bool {
+	return key == "token" || key == "secret" || strings.HasSuffix(key, "_token") || strings.HasSuffix(key, "_secret") || strings.HasPrefix(key, "secret_") || strings.HasSuffix(key, "password")
+}
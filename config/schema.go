@@ -0,0 +1,179 @@
+package config
+
+import (
+	_ "embed"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// source is this package's configuration types, whose field comments
+// describe the keys of the schema.
+//
+//go:embed config.go
+var source string
+
+// Field describes a key of the configuration file.
+type Field struct {
+	Key         string // Dotted key, e.g. server.port or location.variant.weight
+	Type        string // TOML type, e.g. string, integer or array of tables
+	Default     string // Default value in TOML syntax, empty for tables and arrays of tables
+	Description string // From the comment of the field
+}
+
+// Schema returns every key of the configuration file, in file order, with
+// its type, default value and description.
+func Schema() []Field {
+	var fields []Field
+	schemaFields(&fields, "", reflect.ValueOf(DefaultConfig()))
+	return fields
+}
+
+// schemaFields appends the fields of the struct v, whose keys start with prefix.
+func schemaFields(fields *[]Field, prefix string, v reflect.Value) {
+	t := v.Type()
+	for i := range t.NumField() {
+		name := tomlName(t.Field(i))
+		if name == "" {
+			continue
+		}
+		field := Field{Key: prefix + name, Description: fieldComments()[t.Name()+"."+t.Field(i).Name]}
+		value := v.Field(i)
+
+		switch {
+		case value.Kind() == reflect.Struct:
+			field.Type = "table"
+			*fields = append(*fields, field)
+			schemaFields(fields, field.Key+".", value)
+		case value.Kind() == reflect.Slice && value.Type().Elem().Kind() == reflect.Struct:
+			field.Type = "array of tables"
+			*fields = append(*fields, field)
+			schemaFields(fields, field.Key+".", reflect.New(value.Type().Elem()).Elem())
+		default:
+			field.Type = tomlType(value.Type())
+			field.Default = tomlValue(value)
+			*fields = append(*fields, field)
+		}
+	}
+}
+
+// tomlName returns the key of a struct field, or "" if it has none.
+func tomlName(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("toml"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+// tomlType returns the TOML type of values of t.
+func tomlType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "float"
+	case reflect.Slice:
+		return "array of " + plural(tomlType(t.Elem()))
+	case reflect.Map:
+		return "table of " + plural(tomlType(t.Elem()))
+	default:
+		return t.Kind().String()
+	}
+}
+
+// plural returns the plural of a TOML type name.
+func plural(typ string) string {
+	if strings.HasPrefix(typ, "array of ") || strings.HasPrefix(typ, "table of ") {
+		return typ
+	}
+	return typ + "s"
+}
+
+// tomlValue returns v in TOML syntax.
+func tomlValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return fmt.Sprintf("%q", v.String())
+	case reflect.Slice:
+		items := make([]string, v.Len())
+		for i := range v.Len() {
+			items[i] = tomlValue(v.Index(i))
+		}
+		return "[" + strings.Join(items, ", ") + "]"
+	case reflect.Map:
+		if v.Len() == 0 {
+			return "{}"
+		}
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+		items := make([]string, len(keys))
+		for i, key := range keys {
+			items[i] = fmt.Sprintf("%q = %s", key.String(), tomlValue(v.MapIndex(key)))
+		}
+		return "{ " + strings.Join(items, ", ") + " }"
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
+
+var (
+	commentsOnce sync.Once
+	comments     map[string]string // By type and field name, e.g. ServerConfig.Port
+)
+
+// fieldComments returns the line comments of the fields of the
+// configuration types.
+func fieldComments() map[string]string {
+	commentsOnce.Do(func() {
+		comments = map[string]string{}
+		file, err := parser.ParseFile(token.NewFileSet(), "config.go", source, parser.ParseComments)
+		if err != nil {
+			return
+		}
+		ast.Inspect(file, func(n ast.Node) bool {
+			spec, ok := n.(*ast.TypeSpec)
+			if !ok {
+				return true
+			}
+			st, ok := spec.Type.(*ast.StructType)
+			if !ok {
+				return false
+			}
+			for _, field := range st.Fields.List {
+				comment := field.Comment
+				if comment == nil {
+					comment = field.Doc
+				}
+				if comment == nil {
+					continue
+				}
+				for _, name := range field.Names {
+					comments[spec.Name.Name+"."+name.Name] = strings.TrimSpace(comment.Text())
+				}
+			}
+			return false
+		})
+	})
+	return comments
+}
+
+// schemaField returns the schema field of a dotted key.
+func schemaField(key string) (Field, bool) {
+	for _, field := range Schema() {
+		if field.Key == key {
+			return field, true
+		}
+	}
+	return Field{}, false
+}
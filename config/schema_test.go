@@ -0,0 +1,31 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	fields := map[string]Field{}
+	for _, field := range Schema() {
+		fields[field.Key] = field
+	}
+
+	tests := []Field{
+		{Key: "server", Type: "table"},
+		{Key: "server.port", Type: "integer", Default: "6543"},
+		{Key: "server.mode", Type: "string", Default: `"goroutine"`, Description: `Connection handling: "goroutine" (one per connection) or "epoll" (Linux only)`},
+		{Key: "logging.access_logs", Type: "boolean", Default: "true", Description: "Enable HTTP access logging"},
+		{Key: "location", Type: "array of tables"},
+		{Key: "location.variant.weight", Type: "integer", Default: "0", Description: "Share of new visitors, relative to the weights of the other variants"},
+		{Key: "location.allow_countries", Type: "array of strings", Default: "[]", Description: "ISO country codes allowed to access the location (requires [geoip])"},
+		{Key: "plugin.options", Type: "table of strings", Default: "{}", Description: "Settings passed to the plugin's Handlers function"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.Key, func(t *testing.T) {
+			if got := fields[tt.Key]; got != tt {
+				t.Errorf("Expected %+v, got %+v", tt, got)
+			}
+		})
+	}
+}
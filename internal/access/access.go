@@ -0,0 +1,227 @@
+// Package access implements per-directory access control lists: a
+// .volkaccess file in a directory of the document root restricts who may
+// request the files below it. The file is TOML:
+//
+//	allow_ips = ["10.0.0.0/8", "192.0.2.7"] # Only these clients; empty allows all
+//	deny_ips = ["10.0.13.0/24"]             # Never these clients, checked first
+//	allow_users = ["alice", "bob@example.com"] # Only these users (OIDC subject or email); empty allows all
+//	methods = ["GET"]                       # Only these methods; empty allows all
+//
+// Every access file from the document root down to the requested file's
+// directory applies, so a subdirectory can only narrow what its parents allow.
+package access
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// FileName is the name of access files.
+const FileName = ".volkaccess"
+
+// Decisions of Check.
+type Decision int
+
+const (
+	Allow Decision = iota
+	Forbid
+	MethodNotAllowed
+)
+
+// Request is what access rules are checked against.
+type Request struct {
+	Method string
+	IP     string   // Client address without port
+	Users  []string // Names of the authenticated user, such as the subject and email; empty if not logged in
+}
+
+// ACL is a parsed access file.
+type ACL struct {
+	AllowIPs   []string `toml:"allow_ips"`
+	DenyIPs    []string `toml:"deny_ips"`
+	AllowUsers []string `toml:"allow_users"`
+	Methods    []string `toml:"methods"`
+
+	allow []netip.Prefix
+	deny  []netip.Prefix
+}
+
+// Parse parses an access file.
+func Parse(data []byte) (*ACL, error) {
+	var acl ACL
+	meta, err := toml.Decode(string(data), &acl)
+	if err != nil {
+		return nil, err
+	}
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		return nil, fmt.Errorf("unknown key %s", undecoded[0])
+	}
+	if acl.allow, err = parsePrefixes(acl.AllowIPs); err != nil {
+		return nil, err
+	}
+	if acl.deny, err = parsePrefixes(acl.DenyIPs); err != nil {
+		return nil, err
+	}
+	for i, method := range acl.Methods {
+		acl.Methods[i] = strings.ToUpper(method)
+	}
+	return &acl, nil
+}
+
+// parsePrefixes parses addresses and CIDR networks.
+func parsePrefixes(values []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		if !strings.Contains(value, "/") {
+			addr, err := netip.ParseAddr(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q", value)
+			}
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q", value)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// Check decides whether the ACL allows req.
+func (a *ACL) Check(req Request) Decision {
+	addr, err := netip.ParseAddr(req.IP)
+	if err == nil {
+		addr = addr.Unmap()
+	}
+	contains := func(prefixes []netip.Prefix) bool {
+		return err == nil && slices.ContainsFunc(prefixes, func(p netip.Prefix) bool { return p.Contains(addr) })
+	}
+
+	if contains(a.deny) {
+		return Forbid
+	}
+	if len(a.allow) > 0 && !contains(a.allow) {
+		return Forbid
+	}
+	if len(a.AllowUsers) > 0 && !slices.ContainsFunc(req.Users, func(user string) bool { return user != "" && slices.Contains(a.AllowUsers, user) }) {
+		return Forbid
+	}
+	if len(a.Methods) > 0 && !slices.Contains(a.Methods, req.Method) {
+		return MethodNotAllowed
+	}
+	return Allow
+}
+
+// Checker loads the access files of a document root, caching them until
+// they change.
+type Checker struct {
+	mu    sync.Mutex
+	cache map[string]cachedACL // By file path
+}
+
+type cachedACL struct {
+	modTime time.Time
+	size    int64
+	acl     *ACL
+}
+
+// NewChecker creates a Checker.
+func NewChecker() *Checker {
+	return &Checker{cache: map[string]cachedACL{}}
+}
+
+// Check checks req against the access files of every directory from root
+// down to dir, which must be root or below it. The methods of the access
+// files that apply are returned for MethodNotAllowed. An access file that
+// cannot be read or parsed denies every request, so a typo never opens up a
+// directory.
+func (c *Checker) Check(root, dir string, req Request) (Decision, []string, error) {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return Forbid, nil, fmt.Errorf("%s is not below %s", dir, root)
+	}
+
+	dirs := []string{root}
+	if rel != "." {
+		current := root
+		for _, part := range strings.Split(rel, string(filepath.Separator)) {
+			current = filepath.Join(current, part)
+			dirs = append(dirs, current)
+		}
+	}
+
+	for _, d := range dirs {
+		acl, err := c.load(filepath.Join(d, FileName))
+		if err != nil {
+			return Forbid, nil, err
+		}
+		if acl == nil {
+			continue
+		}
+		if decision := acl.Check(req); decision != Allow {
+			return decision, acl.Methods, nil
+		}
+	}
+	return Allow, nil, nil
+}
+
+// Purge drops the cached access files at path and below it, or every cached
+// access file if path is empty, so they are read again. It returns the number
+// of files dropped.
+func (c *Checker) Purge(path string) int {
+	dir := strings.TrimSuffix(path, string(filepath.Separator)) + string(filepath.Separator)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	purged := 0
+	for file := range c.cache {
+		if path == "" || file == path || strings.HasPrefix(file, dir) {
+			delete(c.cache, file)
+			purged++
+		}
+	}
+	return purged
+}
+
+// load returns the parsed access file at path, or nil if there is none.
+func (c *Checker) load(path string) (*ACL, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading access file %s: %w", path, err)
+	}
+
+	c.mu.Lock()
+	cached, ok := c.cache[path]
+	c.mu.Unlock()
+	if ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.acl, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading access file %s: %w", path, err)
+	}
+	acl, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing access file %s: %w", path, err)
+	}
+
+	c.mu.Lock()
+	c.cache[path] = cachedACL{modTime: info.ModTime(), size: info.Size(), acl: acl}
+	c.mu.Unlock()
+	return acl, nil
+}
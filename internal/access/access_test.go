@@ -0,0 +1,151 @@
+package access
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestACLCheck(t *testing.T) {
+	acl, err := Parse([]byte(`
+allow_ips = ["10.0.0.0/8", "192.0.2.7", "2001:db8::/32"]
+deny_ips = ["10.0.13.0/24"]
+allow_users = ["alice", "bob@example.com"]
+methods = ["get", "HEAD"]
+`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name string
+		req  Request
+		want Decision
+	}{
+		{"allowed", Request{Method: "GET", IP: "10.1.2.3", Users: []string{"alice", ""}}, Allow},
+		{"allowed by email", Request{Method: "GET", IP: "192.0.2.7", Users: []string{"123", "bob@example.com"}}, Allow},
+		{"allowed IPv6", Request{Method: "HEAD", IP: "2001:db8::1", Users: []string{"alice"}}, Allow},
+		{"IPv4-mapped address", Request{Method: "GET", IP: "::ffff:10.1.2.3", Users: []string{"alice"}}, Allow},
+		{"denied network", Request{Method: "GET", IP: "10.0.13.5", Users: []string{"alice"}}, Forbid},
+		{"not allowed IP", Request{Method: "GET", IP: "192.0.2.8", Users: []string{"alice"}}, Forbid},
+		{"invalid IP", Request{Method: "GET", IP: "pipe", Users: []string{"alice"}}, Forbid},
+		{"not logged in", Request{Method: "GET", IP: "10.1.2.3"}, Forbid},
+		{"other user", Request{Method: "GET", IP: "10.1.2.3", Users: []string{"mallory"}}, Forbid},
+		{"method", Request{Method: "PUT", IP: "10.1.2.3", Users: []string{"alice"}}, MethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := acl.Check(tt.req); got != tt.want {
+				t.Errorf("Expected decision %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		`allow_ips = ["10.0.0.0/33"]`,
+		`deny_ips = ["not an address"]`,
+		`allow_user = ["alice"]`,
+		`allow_ips = `,
+	}
+
+	for _, data := range tests {
+		t.Run(data, func(t *testing.T) {
+			if _, err := Parse([]byte(data)); err == nil {
+				t.Error("Expected an error, got nil")
+			}
+		})
+	}
+}
+
+func TestCheckerCheck(t *testing.T) {
+	root := t.TempDir()
+	private := filepath.Join(root, "private")
+	team := filepath.Join(private, "team")
+	if err := os.MkdirAll(team, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(dir, data string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, FileName), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(private, `allow_ips = ["10.0.0.0/8"]`)
+	write(team, `allow_users = ["alice"]`)
+
+	checker := NewChecker()
+	tests := []struct {
+		name string
+		dir  string
+		req  Request
+		want Decision
+	}{
+		{"public", root, Request{Method: "GET", IP: "192.0.2.1"}, Allow},
+		{"private from inside", private, Request{Method: "GET", IP: "10.0.0.1"}, Allow},
+		{"private from outside", private, Request{Method: "GET", IP: "192.0.2.1"}, Forbid},
+		{"team needs both", team, Request{Method: "GET", IP: "192.0.2.1", Users: []string{"alice"}}, Forbid},
+		{"team", team, Request{Method: "GET", IP: "10.0.0.1", Users: []string{"alice"}}, Allow},
+		{"outside the root", filepath.Dir(root), Request{Method: "GET", IP: "10.0.0.1"}, Forbid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, _ := checker.Check(root, tt.dir, tt.req)
+			if got != tt.want {
+				t.Errorf("Expected decision %d, got %d", tt.want, got)
+			}
+		})
+	}
+
+	t.Run("changed file", func(t *testing.T) {
+		write(private, `allow_ips = ["192.0.2.0/24"]`)
+		got, _, _ := checker.Check(root, private, Request{Method: "GET", IP: "192.0.2.1"})
+		if got != Allow {
+			t.Errorf("Expected the changed file to allow the request, got %d", got)
+		}
+	})
+
+	t.Run("invalid file denies", func(t *testing.T) {
+		write(private, `allow_ips = [`)
+		got, _, err := checker.Check(root, private, Request{Method: "GET", IP: "192.0.2.1"})
+		if got != Forbid || err == nil {
+			t.Errorf("Expected Forbid and an error, got %d and %v", got, err)
+		}
+	})
+}
+
+func TestCheckerPurge(t *testing.T) {
+	root := t.TempDir()
+	private := filepath.Join(root, "private")
+	if err := os.MkdirAll(private, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{root, private} {
+		if err := os.WriteFile(filepath.Join(dir, FileName), []byte(`deny_ips = ["192.0.2.66"]`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	checker := NewChecker()
+	load := func() {
+		t.Helper()
+		if _, _, err := checker.Check(root, private, Request{Method: "GET", IP: "192.0.2.1"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	load()
+	if purged := checker.Purge(private); purged != 1 {
+		t.Errorf("Expected 1 access file purged below %s, got %d", private, purged)
+	}
+	if purged := checker.Purge(filepath.Join(root, "priv")); purged != 0 {
+		t.Errorf("Expected no access file purged for a sibling prefix, got %d", purged)
+	}
+	load()
+	if purged := checker.Purge(""); purged != 2 {
+		t.Errorf("Expected 2 access files purged, got %d", purged)
+	}
+}
@@ -0,0 +1,226 @@
+// Package audit keeps a tamper-evident log of write requests. Entries are
+// JSON Lines, and each one holds the HMAC-SHA256 of the previous entry and
+// its own, keyed with a secret, so editing, removing or reordering entries
+// breaks the chain, which Verify detects. Without the key, whoever can write
+// the file cannot compute the hashes of forged entries. Truncating the end of
+// the log cannot be detected from the log alone; keep the last hash elsewhere
+// for that.
+package audit
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrBrokenChain is returned by Verify for a log that was tampered with.
+	ErrBrokenChain = errors.New("audit log hash chain is broken")
+	// ErrNoKey is returned by Open and Verify without a key.
+	ErrNoKey = errors.New("audit log needs a key")
+)
+
+// Entry records one write request.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Principal string    `json:"principal,omitempty"` // Authenticated user, or how the client authenticated
+	RemoteIP  string    `json:"remote_ip"`
+	Size      int       `json:"size"` // Bytes in the request body
+	Status    int       `json:"status"`
+
+	Prev string `json:"prev"` // Hash of the previous entry, empty for the first
+	Hash string `json:"hash"` // Hash of this entry, computed with Hash empty
+}
+
+// hash returns the HMAC of e with key, which covers every field but Hash.
+func (e Entry) hash(key []byte) string {
+	e.Hash = ""
+	data, _ := json.Marshal(e)
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Log appends entries to an audit log file. Several processes may append to
+// the same file, such as the workers of volk serve --workers or the old and
+// new process during an upgrade: each append holds a lock on the file and
+// continues the chain from the entries the others wrote since.
+type Log struct {
+	key []byte
+
+	mu      sync.Mutex
+	file    *os.File
+	last    string // Hash of the last entry
+	size    int64  // Size of the file after the last entry
+	written uint64 // Number of entries written by this Log
+
+	syncMu sync.Mutex
+	synced uint64 // Number of entries known to be on disk
+}
+
+// Open opens the audit log at path, creating it if needed, and continues its
+// hash chain with key. It fails if the existing log does not verify with key.
+func Open(path string, key []byte) (*Log, error) {
+	if len(key) == 0 {
+		return nil, ErrNoKey
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("error opening audit log: %w", err)
+	}
+	l := &Log{key: key, file: file}
+	unlock, err := lockFile(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error locking audit log %s: %w", path, err)
+	}
+	err = l.catchUp()
+	unlock()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("error opening audit log %s: %w", path, err)
+	}
+	return l, nil
+}
+
+// catchUp verifies the entries appended to the file since l last saw it and
+// continues the chain from the last of them. The caller holds the file lock.
+func (l *Log) catchUp() error {
+	info, err := l.file.Stat()
+	if err != nil {
+		return fmt.Errorf("error reading audit log: %w", err)
+	}
+	if info.Size() == l.size {
+		return nil
+	}
+	last, _, err := verify(io.NewSectionReader(l.file, l.size, info.Size()-l.size), l.key, l.last)
+	if err != nil {
+		return err
+	}
+	l.last, l.size = last, info.Size()
+	return nil
+}
+
+// Record appends e to the log, filling in its chain hashes, and returns once
+// the entry is synced to disk. Entries recorded at the same time share a
+// sync, so the latency a write request pays is about one fsync however many
+// are waiting.
+func (l *Log) Record(e Entry) error {
+	n, err := l.append(e)
+	if err != nil {
+		return err
+	}
+	return l.sync(n)
+}
+
+// append writes e after the last entry of the file, whichever process wrote
+// it, and returns the number of entries l has written.
+func (l *Log) append(e Entry) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	unlock, err := lockFile(l.file)
+	if err != nil {
+		return 0, fmt.Errorf("error locking audit log: %w", err)
+	}
+	defer unlock()
+	if err := l.catchUp(); err != nil {
+		return 0, err
+	}
+
+	e.Prev = l.last
+	e.Hash = e.hash(l.key)
+	data, err := json.Marshal(e)
+	if err != nil {
+		return 0, fmt.Errorf("error encoding audit entry: %w", err)
+	}
+	data = append(data, '\n')
+	if _, err := l.file.Write(data); err != nil {
+		return 0, fmt.Errorf("error writing audit log: %w", err)
+	}
+	l.last = e.Hash
+	l.size += int64(len(data))
+	l.written++
+	return l.written, nil
+}
+
+// sync waits until the first n entries are on disk. One fsync covers every
+// entry written before it starts, so it is skipped when another Record
+// already synced entry n.
+func (l *Log) sync(n uint64) error {
+	l.syncMu.Lock()
+	defer l.syncMu.Unlock()
+	if l.synced >= n {
+		return nil
+	}
+
+	l.mu.Lock()
+	written := l.written
+	l.mu.Unlock()
+	if err := l.file.Sync(); err != nil {
+		return fmt.Errorf("error writing audit log: %w", err)
+	}
+	l.synced = written
+	return nil
+}
+
+// Close closes the log file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.file.Close()
+}
+
+// Verify checks the hash chain of the log in r with key and returns the
+// number of entries.
+func Verify(r io.Reader, key []byte) (int, error) {
+	if len(key) == 0 {
+		return 0, ErrNoKey
+	}
+	_, count, err := verify(r, key, "")
+	return count, err
+}
+
+// verify checks the hash chain of the log in r with key, whose first entry
+// follows the entry with hash last, and returns the hash of its last entry
+// and the number of entries.
+func verify(r io.Reader, key []byte, last string) (string, int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	count := 0
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		count++
+
+		var e Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return "", count, fmt.Errorf("%w: entry %d is not valid JSON", ErrBrokenChain, count)
+		}
+		if e.Prev != last {
+			return "", count, fmt.Errorf("%w: entry %d does not follow entry %d", ErrBrokenChain, count, count-1)
+		}
+		if !hmac.Equal([]byte(e.hash(key)), []byte(e.Hash)) {
+			return "", count, fmt.Errorf("%w: entry %d was modified", ErrBrokenChain, count)
+		}
+		last = e.Hash
+	}
+	if err := scanner.Err(); err != nil {
+		return "", count, fmt.Errorf("error reading audit log: %w", err)
+	}
+	return last, count, nil
+}
@@ -0,0 +1,176 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var testKey = []byte("audit key")
+
+func writeLog(t *testing.T, path string, entries ...Entry) {
+	t.Helper()
+	l, err := Open(path, testKey)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer l.Close()
+	for _, e := range entries {
+		if err := l.Record(e); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+}
+
+func TestLogChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	writeLog(t, path,
+		Entry{Time: now, Method: "PUT", Path: "/api/kv/a", RemoteIP: "192.0.2.1", Size: 12, Status: 201},
+		Entry{Time: now, Method: "DELETE", Path: "/api/kv/a", Principal: "alice", RemoteIP: "192.0.2.1", Status: 204})
+	// Reopening continues the chain.
+	writeLog(t, path, Entry{Time: now, Method: "PUT", Path: "/_deploy", Principal: "token", RemoteIP: "192.0.2.9", Size: 4096, Status: 201})
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count, err := Verify(strings.NewReader(string(data)), testKey)
+	if err != nil || count != 3 {
+		t.Fatalf("Expected 3 valid entries, got %d and %v", count, err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	tests := []struct {
+		name  string
+		lines []string
+	}{
+		{"modified", []string{lines[0], strings.Replace(lines[1], `"alice"`, `"bob"`, 1), lines[2]}},
+		{"removed", []string{lines[0], lines[2]}},
+		{"reordered", []string{lines[1], lines[0], lines[2]}},
+		{"garbage", []string{lines[0], "not json"}},
+		{"rehashed", []string{lines[0], rehash(t, strings.Replace(lines[1], `"alice"`, `"bob"`, 1), []byte("guessed key")), lines[2]}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Verify(strings.NewReader(strings.Join(tt.lines, "\n")), testKey); !errors.Is(err, ErrBrokenChain) {
+				t.Errorf("Expected ErrBrokenChain, got %v", err)
+			}
+		})
+	}
+}
+
+func TestOpenTamperedLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	writeLog(t, path, Entry{Method: "PUT", Path: "/a"})
+
+	data, _ := os.ReadFile(path)
+	if err := os.WriteFile(path, []byte(strings.Replace(string(data), "/a", "/b", 1)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path, testKey); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("Expected ErrBrokenChain, got %v", err)
+	}
+}
+
+// rehash returns the entry in line with its hash recomputed with key.
+func rehash(t *testing.T, line string, key []byte) string {
+	t.Helper()
+	var e Entry
+	if err := json.Unmarshal([]byte(line), &e); err != nil {
+		t.Fatal(err)
+	}
+	e.Hash = e.hash(key)
+	data, _ := json.Marshal(e)
+	return string(data)
+}
+
+func TestLogKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	writeLog(t, path, Entry{Method: "PUT", Path: "/a"})
+
+	if _, err := Open(path, nil); !errors.Is(err, ErrNoKey) {
+		t.Errorf("Expected ErrNoKey without a key, got %v", err)
+	}
+	if _, err := Open(path, []byte("other key")); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("Expected ErrBrokenChain with another key, got %v", err)
+	}
+}
+
+func TestLogConcurrentRecords(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := Open(path, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.Record(Entry{Method: "PUT", Path: fmt.Sprintf("/%d", i)}); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	l.Close()
+
+	data, _ := os.ReadFile(path)
+	if count, err := Verify(strings.NewReader(string(data)), testKey); err != nil || count != 50 {
+		t.Errorf("Expected 50 valid entries, got %d and %v", count, err)
+	}
+	if l.synced != 50 {
+		t.Errorf("Expected all 50 entries to be synced, got %d", l.synced)
+	}
+}
+
+func TestLogSharedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	// Two Logs on one file stand for two worker processes.
+	first, err := Open(path, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := Open(path, testKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for i := range 20 {
+		l := first
+		if i%2 == 1 {
+			l = second
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.Record(Entry{Method: "PUT", Path: fmt.Sprintf("/%d", i)}); err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	first.Close()
+	second.Close()
+
+	data, _ := os.ReadFile(path)
+	if count, err := Verify(strings.NewReader(string(data)), testKey); err != nil || count != 20 {
+		t.Errorf("Expected 20 valid entries, got %d and %v", count, err)
+	}
+	l, err := Open(path, testKey)
+	if err != nil {
+		t.Fatalf("Expected the log to open again, got %v", err)
+	}
+	l.Close()
+}
@@ -0,0 +1,11 @@
+//go:build !unix
+
+package audit
+
+import "os"
+
+// lockFile does nothing: workers and upgrades, which let several processes
+// write the log, are not supported on this platform.
+func lockFile(file *os.File) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,167 @@
+// Package bots matches crawlers by User-Agent and decides how to treat them:
+// block them, rate-limit them, or hold them to the rules of robots.txt.
+package bots
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// Actions a bot rule can take.
+const (
+	ActionBlock     = "block"      // Answer every request with 403
+	ActionRateLimit = "rate_limit" // Answer with 429 beyond rate_limit requests per minute and client
+	ActionRobots    = "robots"     // Answer with 403 for paths robots.txt disallows for the bot
+)
+
+// Verdict is the outcome of checking a request against the bot rules.
+type Verdict int
+
+const (
+	// Allow lets the request through.
+	Allow Verdict = iota
+	// Forbid answers the request with 403 Forbidden.
+	Forbid
+	// Throttle answers the request with 429 Too Many Requests.
+	Throttle
+)
+
+// Rule is a compiled bot rule.
+type Rule struct {
+	config.BotRuleConfig
+	pattern *regexp.Regexp
+}
+
+// Bots holds the compiled rules and the state of their rate limits.
+type Bots struct {
+	rules []Rule
+
+	mu      sync.Mutex
+	windows map[string]*window
+}
+
+// window counts the requests of one client for one rule in the current minute.
+type window struct {
+	start time.Time
+	count int
+}
+
+// New compiles the bot rules.
+func New(rules []config.BotRuleConfig) (*Bots, error) {
+	b := &Bots{windows: map[string]*window{}}
+	for i, rule := range rules {
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern in bot rule %d: %w", i+1, err)
+		}
+		switch rule.Action {
+		case ActionBlock, ActionRobots:
+		case ActionRateLimit:
+			if rule.RateLimit <= 0 {
+				return nil, fmt.Errorf("bot rule %d: rate_limit must be positive", i+1)
+			}
+		default:
+			return nil, fmt.Errorf("bot rule %d: unknown action %q", i+1, rule.Action)
+		}
+		b.rules = append(b.rules, Rule{BotRuleConfig: rule, pattern: pattern})
+	}
+	return b, nil
+}
+
+// Rules returns the compiled rules.
+func (b *Bots) Rules() []Rule {
+	return b.rules
+}
+
+// Match returns the first rule whose pattern matches the user agent.
+func (b *Bots) Match(userAgent string) (Rule, bool) {
+	for _, rule := range b.rules {
+		if rule.pattern.MatchString(userAgent) {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Check decides how to treat a request from the client with the user agent for the path.
+// robots is called to get the site's robots.txt for rules with the robots action.
+func (b *Bots) Check(userAgent, client, path string, robots func() *Robots) Verdict {
+	rule, ok := b.Match(userAgent)
+	if !ok {
+		return Allow
+	}
+
+	switch rule.Action {
+	case ActionBlock:
+		return Forbid
+	case ActionRateLimit:
+		if !b.take(rule.Pattern+"\x00"+client, rule.RateLimit, time.Now()) {
+			return Throttle
+		}
+	case ActionRobots:
+		// robots.txt itself must always be reachable.
+		if path != "/robots.txt" && !robots().Allowed(rule.agent(userAgent), path) {
+			return Forbid
+		}
+	}
+	return Allow
+}
+
+// take counts a request in the client's window and reports whether it is within the limit.
+func (b *Bots) take(key string, limit int, now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	w, ok := b.windows[key]
+	if !ok || now.Sub(w.start) >= time.Minute {
+		// Drop expired windows now and then so idle clients do not accumulate.
+		if len(b.windows) > 10000 {
+			for k, old := range b.windows {
+				if now.Sub(old.start) >= time.Minute {
+					delete(b.windows, k)
+				}
+			}
+		}
+		w = &window{start: now}
+		b.windows[key] = w
+	}
+
+	w.count++
+	return w.count <= limit
+}
+
+// agent returns the token the rule's bot is known by in robots.txt.
+func (r Rule) agent(userAgent string) string {
+	if r.Name != "" {
+		return r.Name
+	}
+	return userAgent
+}
+
+// DefaultRobots generates a robots.txt that disallows everything for the named
+// bots of block rules and the disallow paths for everyone else. If sitemapURL is
+// not empty, it is announced with a Sitemap line.
+func (b *Bots) DefaultRobots(disallow []string, sitemapURL string) string {
+	var builder strings.Builder
+	for _, rule := range b.rules {
+		if rule.Action == ActionBlock && rule.Name != "" {
+			fmt.Fprintf(&builder, "User-agent: %s\nDisallow: /\n\n", rule.Name)
+		}
+	}
+	builder.WriteString("User-agent: *\n")
+	if len(disallow) == 0 {
+		builder.WriteString("Disallow:\n")
+	}
+	for _, path := range disallow {
+		fmt.Fprintf(&builder, "Disallow: %s\n", path)
+	}
+	if sitemapURL != "" {
+		fmt.Fprintf(&builder, "\nSitemap: %s\n", sitemapURL)
+	}
+	return builder.String()
+}
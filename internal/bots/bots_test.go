@@ -0,0 +1,160 @@
+package bots
+
+import (
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestParseRobots(t *testing.T) {
+	robots := ParseRobots(`# comment
+User-agent: BadBot
+User-agent: WorseBot
+Disallow: /
+
+User-agent: *
+Disallow: /private/
+Allow: /private/public.html
+Disallow: /tmp # trailing comment
+`)
+
+	tests := []struct {
+		agent   string
+		path    string
+		allowed bool
+	}{
+		{"Mozilla/5.0 (compatible; BadBot/1.0)", "/index.html", false},
+		{"worsebot", "/", false},
+		{"GoodBot", "/index.html", true},
+		{"GoodBot", "/private/secret.html", false},
+		{"GoodBot", "/private/public.html", true},
+		{"GoodBot", "/tmp/file", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.agent+" "+tt.path, func(t *testing.T) {
+			if got := robots.Allowed(tt.agent, tt.path); got != tt.allowed {
+				t.Errorf("Allowed(%q, %q) = %t, want %t", tt.agent, tt.path, got, tt.allowed)
+			}
+		})
+	}
+
+	if !ParseRobots("").Allowed("AnyBot", "/anything") {
+		t.Errorf("An empty robots.txt should allow everything")
+	}
+}
+
+func TestNewRejectsInvalidRules(t *testing.T) {
+	tests := []struct {
+		name string
+		rule config.BotRuleConfig
+	}{
+		{"Invalid pattern", config.BotRuleConfig{Pattern: "(", Action: ActionBlock}},
+		{"Unknown action", config.BotRuleConfig{Pattern: "bot", Action: "ignore"}},
+		{"Missing rate limit", config.BotRuleConfig{Pattern: "bot", Action: ActionRateLimit}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New([]config.BotRuleConfig{tt.rule}); err == nil {
+				t.Errorf("New should have returned an error")
+			}
+		})
+	}
+}
+
+func TestCheck(t *testing.T) {
+	b, err := New([]config.BotRuleConfig{
+		{Pattern: "(?i)badbot", Action: ActionBlock, Name: "BadBot"},
+		{Pattern: "(?i)greedybot", Action: ActionRateLimit, RateLimit: 2},
+		{Pattern: "(?i)politebot", Action: ActionRobots, Name: "PoliteBot"},
+	})
+	if err != nil {
+		t.Fatalf("New returned an error: %v", err)
+	}
+	robots := func() *Robots {
+		return ParseRobots("User-agent: PoliteBot\nDisallow: /private/\n")
+	}
+
+	if v := b.Check("Mozilla/5.0 Firefox", "1.2.3.4", "/", robots); v != Allow {
+		t.Errorf("Expected browsers to be allowed, got %v", v)
+	}
+	if v := b.Check("BadBot/1.0", "1.2.3.4", "/", robots); v != Forbid {
+		t.Errorf("Expected blocked bot to be forbidden, got %v", v)
+	}
+
+	for i := 0; i < 2; i++ {
+		if v := b.Check("GreedyBot", "1.2.3.4", "/", robots); v != Allow {
+			t.Errorf("Expected request %d within the rate limit to be allowed, got %v", i+1, v)
+		}
+	}
+	if v := b.Check("GreedyBot", "1.2.3.4", "/", robots); v != Throttle {
+		t.Errorf("Expected request beyond the rate limit to be throttled, got %v", v)
+	}
+	if v := b.Check("GreedyBot", "5.6.7.8", "/", robots); v != Allow {
+		t.Errorf("Expected rate limits to be per client, got %v", v)
+	}
+
+	if v := b.Check("PoliteBot/2.0", "1.2.3.4", "/private/page.html", robots); v != Forbid {
+		t.Errorf("Expected path disallowed by robots.txt to be forbidden, got %v", v)
+	}
+	if v := b.Check("PoliteBot/2.0", "1.2.3.4", "/public/page.html", robots); v != Allow {
+		t.Errorf("Expected path allowed by robots.txt to be allowed, got %v", v)
+	}
+}
+
+func TestRateLimitWindowExpires(t *testing.T) {
+	b, err := New(nil)
+	if err != nil {
+		t.Fatalf("New returned an error: %v", err)
+	}
+
+	now := time.Now()
+	if !b.take("key", 1, now) {
+		t.Errorf("Expected the first request to be allowed")
+	}
+	if b.take("key", 1, now.Add(30*time.Second)) {
+		t.Errorf("Expected the second request in the same minute to be refused")
+	}
+	if !b.take("key", 1, now.Add(61*time.Second)) {
+		t.Errorf("Expected a request in the next minute to be allowed")
+	}
+}
+
+func TestDefaultRobots(t *testing.T) {
+	b, err := New([]config.BotRuleConfig{
+		{Pattern: "badbot", Action: ActionBlock, Name: "BadBot"},
+		{Pattern: "unnamed", Action: ActionBlock},
+	})
+	if err != nil {
+		t.Fatalf("New returned an error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		disallow   []string
+		sitemapURL string
+		expected   string
+	}{
+		{
+			name:     "allow everything",
+			expected: "User-agent: BadBot\nDisallow: /\n\nUser-agent: *\nDisallow:\n",
+		},
+		{
+			name:       "disallow and sitemap",
+			disallow:   []string{"/private/", "*.bak"},
+			sitemapURL: "https://example.com/sitemap.xml",
+			expected: "User-agent: BadBot\nDisallow: /\n\nUser-agent: *\n" +
+				"Disallow: /private/\nDisallow: *.bak\n\nSitemap: https://example.com/sitemap.xml\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.DefaultRobots(tt.disallow, tt.sitemapURL); got != tt.expected {
+				t.Errorf("DefaultRobots() = %q, want %q", got, tt.expected)
+			}
+		})
+	}
+}
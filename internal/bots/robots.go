@@ -0,0 +1,113 @@
+package bots
+
+import (
+	"bufio"
+	"strings"
+)
+
+// Robots is a parsed robots.txt file.
+type Robots struct {
+	groups []robotsGroup
+}
+
+// robotsGroup is a set of user agents and the rules that apply to them.
+type robotsGroup struct {
+	agents []string
+	rules  []robotsRule
+}
+
+// robotsRule is a single Allow or Disallow line.
+type robotsRule struct {
+	allow bool
+	path  string
+}
+
+// ParseRobots parses the contents of a robots.txt file.
+// Unknown lines and comments are ignored.
+func ParseRobots(content string) *Robots {
+	robots := &Robots{}
+	var group *robotsGroup
+	lastWasAgent := false
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i != -1 {
+			line = line[:i]
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// Consecutive User-agent lines share one group.
+			if group == nil || !lastWasAgent {
+				robots.groups = append(robots.groups, robotsGroup{})
+				group = &robots.groups[len(robots.groups)-1]
+			}
+			group.agents = append(group.agents, strings.ToLower(value))
+			lastWasAgent = true
+		case "allow", "disallow":
+			lastWasAgent = false
+			if group == nil {
+				continue
+			}
+			// An empty Disallow allows everything and adds no rule.
+			if value == "" {
+				continue
+			}
+			group.rules = append(group.rules, robotsRule{allow: key == "allow", path: value})
+		default:
+			lastWasAgent = false
+		}
+	}
+
+	return robots
+}
+
+// Allowed reports whether the crawler with the given user agent may fetch the path.
+// The group naming the agent is used, falling back to the "*" group; within it the
+// longest matching rule wins, with Allow winning ties (RFC 9309).
+func (r *Robots) Allowed(userAgent, path string) bool {
+	group := r.group(strings.ToLower(userAgent))
+	if group == nil {
+		return true
+	}
+
+	allowed := true
+	longest := -1
+	for _, rule := range group.rules {
+		if !strings.HasPrefix(path, rule.path) {
+			continue
+		}
+		if len(rule.path) > longest || (len(rule.path) == longest && rule.allow) {
+			longest = len(rule.path)
+			allowed = rule.allow
+		}
+	}
+	return allowed
+}
+
+// group returns the group whose agent token is contained in the user agent, or the "*" group.
+func (r *Robots) group(userAgent string) *robotsGroup {
+	var wildcard *robotsGroup
+	for i := range r.groups {
+		for _, agent := range r.groups[i].agents {
+			if agent == "*" {
+				if wildcard == nil {
+					wildcard = &r.groups[i]
+				}
+				continue
+			}
+			if agent != "" && strings.Contains(userAgent, agent) {
+				return &r.groups[i]
+			}
+		}
+	}
+	return wildcard
+}
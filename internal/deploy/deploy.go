@@ -0,0 +1,373 @@
+// Package deploy publishes site archives as releases and switches the document
+// root between them.
+//
+// The document root is a symbolic link to the current release directory. A
+// deploy extracts the archive into a new release directory and then replaces
+// the link with a rename, so requests see either the old or the new release,
+// never a mix of both.
+package deploy
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// releaseFormat names release directories so that they sort by age.
+const releaseFormat = "20060102T150405.000000000"
+
+var (
+	ErrUnsupportedArchive = errors.New("archive is neither tar.gz nor zip")
+	ErrUnsafePath         = errors.New("archive entry escapes the release directory")
+	ErrRootNotSymlink     = errors.New("document root exists and is not a symbolic link")
+	ErrNoPreviousRelease  = errors.New("no previous release")
+	ErrQuotaExceeded      = errors.New("releases would exceed the storage quota")
+)
+
+// Deployer publishes releases for one document root.
+type Deployer struct {
+	root     string
+	releases string
+	keep     int
+	quota    int64 // Bytes the kept releases may use, 0 for no limit
+
+	mu  sync.Mutex // Serializes deploys and rollbacks
+	now func() time.Time
+}
+
+// New creates a Deployer that links root to releases kept below cfg.Releases.
+func New(root string, cfg config.DeployConfig) (*Deployer, error) {
+	if cfg.Token == "" {
+		return nil, fmt.Errorf("deploy: token is required")
+	}
+	releases, err := filepath.Abs(cfg.Releases)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving releases directory: %w", err)
+	}
+	if err := os.MkdirAll(releases, 0755); err != nil {
+		return nil, fmt.Errorf("error creating releases directory: %w", err)
+	}
+	return &Deployer{root: root, releases: releases, keep: cfg.Keep, quota: cfg.Quota, now: time.Now}, nil
+}
+
+// Deploy extracts a tar.gz or zip archive into a new release, makes it the
+// current one and removes releases beyond the ones kept. It returns the name
+// of the new release.
+func (d *Deployer) Deploy(archive []byte) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.checkRoot(); err != nil {
+		return "", err
+	}
+
+	name := d.now().UTC().Format(releaseFormat)
+	dir := filepath.Join(d.releases, name)
+	if err := os.Mkdir(dir, 0755); err != nil {
+		return "", fmt.Errorf("error creating release directory: %w", err)
+	}
+
+	// The quota is checked while extracting, so that an archive that
+	// expands to far more than its size, like a gzip bomb, stops early.
+	limit, err := d.extractLimit(name)
+	if err == nil {
+		b := &budget{left: limit}
+		switch {
+		case bytes.HasPrefix(archive, []byte{0x1f, 0x8b}):
+			err = extractTarGz(archive, dir, b)
+		case bytes.HasPrefix(archive, []byte("PK\x03\x04")):
+			err = extractZip(archive, dir, b)
+		default:
+			err = ErrUnsupportedArchive
+		}
+	}
+	if err == nil {
+		err = d.link(name)
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", err
+	}
+
+	d.prune(name)
+	return name, nil
+}
+
+// Rollback makes the release before the current one current again and returns its name.
+func (d *Deployer) Rollback() (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	current := d.current()
+	releases, err := d.Releases()
+	if err != nil {
+		return "", err
+	}
+	previous := ""
+	for _, release := range releases {
+		if release < current || current == "" {
+			previous = release
+		}
+	}
+	if previous == "" || previous == current {
+		return "", ErrNoPreviousRelease
+	}
+	if err := d.link(previous); err != nil {
+		return "", err
+	}
+	return previous, nil
+}
+
+// Current returns the name of the release the document root links to, or ""
+// if it does not link to a release.
+func (d *Deployer) Current() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.current()
+}
+
+func (d *Deployer) current() string {
+	target, err := os.Readlink(d.root)
+	if err != nil || filepath.Dir(target) != d.releases {
+		return ""
+	}
+	return filepath.Base(target)
+}
+
+// Releases returns the names of the releases, oldest first.
+func (d *Deployer) Releases() ([]string, error) {
+	entries, err := os.ReadDir(d.releases)
+	if err != nil {
+		return nil, fmt.Errorf("error reading releases directory: %w", err)
+	}
+	var releases []string
+	for _, entry := range entries {
+		if _, err := time.Parse(releaseFormat, entry.Name()); entry.IsDir() && err == nil {
+			releases = append(releases, entry.Name())
+		}
+	}
+	sort.Strings(releases)
+	return releases, nil
+}
+
+// Usage returns the bytes used by the releases and the quota, 0 if there is none.
+func (d *Deployer) Usage() (used, quota int64, err error) {
+	releases, err := d.Releases()
+	if err != nil {
+		return 0, d.quota, err
+	}
+	for _, release := range releases {
+		used += dirSize(filepath.Join(d.releases, release))
+	}
+	return used, d.quota, nil
+}
+
+// extractLimit returns the bytes the new release may extract so that it and
+// the releases kept after pruning fit in the quota, or -1 if there is none.
+func (d *Deployer) extractLimit(name string) (int64, error) {
+	if d.quota <= 0 {
+		return -1, nil
+	}
+	releases, err := d.Releases()
+	if err != nil {
+		return 0, err
+	}
+	var previous []string
+	for _, release := range releases {
+		if release < name {
+			previous = append(previous, release)
+		}
+	}
+	var kept int64
+	for _, release := range previous[max(len(previous)-d.keep, 0):] {
+		kept += dirSize(filepath.Join(d.releases, release))
+	}
+	return max(d.quota-kept, 0), nil
+}
+
+// budget counts the bytes extracted from an archive against a limit.
+type budget struct {
+	left int64 // Bytes that may still be extracted, -1 for no limit
+}
+
+// dirSize returns the total size of the regular files below dir.
+func dirSize(dir string) int64 {
+	var size int64
+	filepath.WalkDir(dir, func(_ string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info, err := entry.Info(); err == nil && info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+// checkRoot makes sure switching the document root will not replace a real directory.
+func (d *Deployer) checkRoot() error {
+	info, err := os.Lstat(d.root)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error checking document root: %w", err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return ErrRootNotSymlink
+	}
+	return nil
+}
+
+// link atomically points the document root at the named release.
+func (d *Deployer) link(name string) error {
+	tmp := d.root + ".deploy"
+	os.Remove(tmp)
+	if err := os.Symlink(filepath.Join(d.releases, name), tmp); err != nil {
+		return fmt.Errorf("error linking release: %w", err)
+	}
+	if err := os.Rename(tmp, d.root); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error switching document root: %w", err)
+	}
+	return nil
+}
+
+// prune removes the oldest releases, keeping the current one and the d.keep before it.
+func (d *Deployer) prune(current string) {
+	releases, err := d.Releases()
+	if err != nil {
+		return
+	}
+	var previous []string
+	for _, release := range releases {
+		if release < current {
+			previous = append(previous, release)
+		}
+	}
+	for len(previous) > d.keep {
+		os.RemoveAll(filepath.Join(d.releases, previous[0]))
+		previous = previous[1:]
+	}
+}
+
+// target returns where an archive entry is extracted to, rejecting entries
+// that would end up outside dir.
+func target(dir, name string) (string, error) {
+	name = strings.ReplaceAll(name, `\`, "/")
+	if path.IsAbs(name) {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+	cleaned := path.Clean(name)
+	if cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", fmt.Errorf("%w: %s", ErrUnsafePath, name)
+	}
+	return filepath.Join(dir, filepath.FromSlash(cleaned)), nil
+}
+
+// writeFile creates the file at dest with the contents of r, which it charges
+// to b. It stops writing once b is used up and returns ErrQuotaExceeded.
+func writeFile(dest string, r io.Reader, b *budget) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return fmt.Errorf("error creating directory: %w", err)
+	}
+	file, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	if b.left >= 0 {
+		r = io.LimitReader(r, b.left+1)
+	}
+	n, err := io.Copy(file, r)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("error writing file: %w", err)
+	}
+	if b.left >= 0 {
+		if b.left -= n; b.left < 0 {
+			file.Close()
+			return fmt.Errorf("%w: the archive extracts to more than the quota allows", ErrQuotaExceeded)
+		}
+	}
+	return file.Close()
+}
+
+// extractTarGz extracts the directories and regular files of a tar.gz archive
+// into dir, charging them to b. Other entries, such as links, are skipped.
+func extractTarGz(archive []byte, dir string, b *budget) error {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return fmt.Errorf("error reading archive: %w", err)
+	}
+	reader := tar.NewReader(gz)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error reading archive: %w", err)
+		}
+		dest, err := target(dir, header.Name)
+		if err != nil {
+			return err
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(dest, 0755); err != nil {
+				return fmt.Errorf("error creating directory: %w", err)
+			}
+		case tar.TypeReg:
+			if err := writeFile(dest, reader, b); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// extractZip extracts the directories and regular files of a zip archive into
+// dir, charging them to b. Other entries, such as links, are skipped.
+func extractZip(archive []byte, dir string, b *budget) error {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return fmt.Errorf("error reading archive: %w", err)
+	}
+	for _, file := range reader.File {
+		dest, err := target(dir, file.Name)
+		if err != nil {
+			return err
+		}
+		mode := file.Mode()
+		switch {
+		case mode.IsDir():
+			if err := os.MkdirAll(dest, 0755); err != nil {
+				return fmt.Errorf("error creating directory: %w", err)
+			}
+		case mode.IsRegular():
+			contents, err := file.Open()
+			if err != nil {
+				return fmt.Errorf("error reading archive: %w", err)
+			}
+			err = writeFile(dest, contents, b)
+			contents.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package deploy
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// tarGz builds a tar.gz archive of the files.
+func tarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, contents := range files {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(contents)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(contents))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+// zipArchive builds a zip archive of the files.
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, contents := range files {
+		w, _ := zw.Create(name)
+		w.Write([]byte(contents))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func newTestDeployer(t *testing.T, keep int) (*Deployer, string) {
+	t.Helper()
+	dir := t.TempDir()
+	root := filepath.Join(dir, "public")
+	deployer, err := New(root, config.DeployConfig{Token: "token", Releases: filepath.Join(dir, "releases"), Keep: keep})
+	if err != nil {
+		t.Fatalf("New returned an error: %v", err)
+	}
+	clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	deployer.now = func() time.Time {
+		clock = clock.Add(time.Second)
+		return clock
+	}
+	return deployer, root
+}
+
+func readIndex(t *testing.T, root string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(root, "index.html"))
+	if err != nil {
+		t.Fatalf("could not read index.html: %v", err)
+	}
+	return string(data)
+}
+
+func TestDeployAndRollback(t *testing.T) {
+	deployer, root := newTestDeployer(t, 1)
+
+	first, err := deployer.Deploy(tarGz(t, map[string]string{"index.html": "v1"}))
+	if err != nil {
+		t.Fatalf("Deploy returned an error: %v", err)
+	}
+	if got := readIndex(t, root); got != "v1" {
+		t.Errorf("Expected v1, got %q", got)
+	}
+
+	second, err := deployer.Deploy(zipArchive(t, map[string]string{"index.html": "v2", "css/site.css": "body{}"}))
+	if err != nil {
+		t.Fatalf("Deploy returned an error: %v", err)
+	}
+	if got := readIndex(t, root); got != "v2" {
+		t.Errorf("Expected v2, got %q", got)
+	}
+	if deployer.Current() != second {
+		t.Errorf("Expected current release %s, got %s", second, deployer.Current())
+	}
+
+	release, err := deployer.Rollback()
+	if err != nil {
+		t.Fatalf("Rollback returned an error: %v", err)
+	}
+	if release != first || readIndex(t, root) != "v1" {
+		t.Errorf("Expected rollback to %s serving v1, got %s serving %q", first, release, readIndex(t, root))
+	}
+	if _, err := deployer.Rollback(); !errors.Is(err, ErrNoPreviousRelease) {
+		t.Errorf("Expected ErrNoPreviousRelease, got %v", err)
+	}
+}
+
+func TestDeployKeepsReleases(t *testing.T) {
+	deployer, _ := newTestDeployer(t, 2)
+
+	for i := 0; i < 5; i++ {
+		if _, err := deployer.Deploy(tarGz(t, map[string]string{"index.html": "v"})); err != nil {
+			t.Fatalf("Deploy returned an error: %v", err)
+		}
+	}
+	releases, err := deployer.Releases()
+	if err != nil {
+		t.Fatalf("Releases returned an error: %v", err)
+	}
+	if len(releases) != 3 {
+		t.Errorf("Expected the current and 2 previous releases, got %v", releases)
+	}
+	if releases[len(releases)-1] != deployer.Current() {
+		t.Errorf("Expected newest release %s to be current, got %s", releases[len(releases)-1], deployer.Current())
+	}
+}
+
+func TestDeployRejects(t *testing.T) {
+	tests := []struct {
+		name    string
+		archive func(t *testing.T) []byte
+		err     error
+	}{
+		{"not an archive", func(t *testing.T) []byte { return []byte("hello") }, ErrUnsupportedArchive},
+		{"tar traversal", func(t *testing.T) []byte { return tarGz(t, map[string]string{"../evil": "x"}) }, ErrUnsafePath},
+		{"zip traversal", func(t *testing.T) []byte { return zipArchive(t, map[string]string{"a/../../evil": "x"}) }, ErrUnsafePath},
+		{"absolute path", func(t *testing.T) []byte { return tarGz(t, map[string]string{"/etc/evil": "x"}) }, ErrUnsafePath},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deployer, root := newTestDeployer(t, 1)
+			if _, err := deployer.Deploy(tt.archive(t)); !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if _, err := os.Lstat(root); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("Expected document root to stay absent, got %v", err)
+			}
+			if releases, _ := deployer.Releases(); len(releases) != 0 {
+				t.Errorf("Expected failed release to be removed, got %v", releases)
+			}
+		})
+	}
+}
+
+func TestDeployRootNotSymlink(t *testing.T) {
+	deployer, root := newTestDeployer(t, 1)
+	if err := os.Mkdir(root, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := deployer.Deploy(tarGz(t, map[string]string{"index.html": "v1"})); !errors.Is(err, ErrRootNotSymlink) {
+		t.Errorf("Expected ErrRootNotSymlink, got %v", err)
+	}
+}
+
+func TestDeployQuota(t *testing.T) {
+	deployer, root := newTestDeployer(t, 1)
+	deployer.quota = 10
+
+	steps := []struct {
+		name     string
+		contents string
+		err      error
+		wantUsed int64
+	}{
+		{"first", "12345", nil, 5},
+		{"too large with the kept release", "123456", ErrQuotaExceeded, 5},
+		{"fits", "1234", nil, 9},
+		{"oldest release is pruned", "12345", nil, 9},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if _, err := deployer.Deploy(tarGz(t, map[string]string{"index.html": step.contents})); !errors.Is(err, step.err) {
+				t.Fatalf("Expected error %v, got %v", step.err, err)
+			}
+			used, quota, err := deployer.Usage()
+			if err != nil || used != step.wantUsed || quota != 10 {
+				t.Errorf("Expected usage %d of 10, got %d of %d (%v)", step.wantUsed, used, quota, err)
+			}
+		})
+	}
+	if got := readIndex(t, root); got != "12345" {
+		t.Errorf("Expected the last release to be current, got %q", got)
+	}
+}
+
+func TestDeployQuotaStopsExtraction(t *testing.T) {
+	deployer, _ := newTestDeployer(t, 1)
+	deployer.quota = 1000
+	// A megabyte of zeros compresses to about a kilobyte.
+	bomb := strings.Repeat("0", 1<<20)
+
+	for name, archive := range map[string][]byte{
+		"tar.gz": tarGz(t, map[string]string{"a.txt": "small", "bomb.txt": bomb}),
+		"zip":    zipArchive(t, map[string]string{"a.txt": "small", "bomb.txt": bomb}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := deployer.Deploy(archive); !errors.Is(err, ErrQuotaExceeded) {
+				t.Fatalf("Expected ErrQuotaExceeded, got %v", err)
+			}
+			if releases, _ := deployer.Releases(); len(releases) != 0 {
+				t.Errorf("Expected the partial release to be removed, got %v", releases)
+			}
+		})
+	}
+}
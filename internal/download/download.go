@@ -0,0 +1,179 @@
+// Package download writes archives of directories, so a whole folder of a
+// file share can be fetched at once. Archives are generated on the fly: the
+// files are collected first, which enforces the size limit before anything is
+// sent, and then streamed into the archive one by one.
+package download
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Archive formats.
+const (
+	FormatZip   = "zip"
+	FormatTarGz = "tar.gz"
+)
+
+var (
+	ErrUnsupportedFormat = errors.New("unsupported archive format")
+	ErrTooLarge          = errors.New("directory is too large to download")
+)
+
+// File is a file to archive.
+type File struct {
+	Path    string // Path on disk
+	Name    string // Slash-separated path inside the archive
+	Size    int64
+	ModTime time.Time
+}
+
+// ContentType returns the content type of an archive format.
+func ContentType(format string) (string, error) {
+	switch format {
+	case FormatZip:
+		return "application/zip", nil
+	case FormatTarGz:
+		return "application/gzip", nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+}
+
+// Collect returns the regular files below dir, skipping hidden files and
+// directories, symbolic links and what include rejects. include is called
+// with the slash-separated path relative to dir, ending in a slash for
+// directories; a rejected directory is skipped as a whole. If the files add
+// up to more than maxSize bytes, ErrTooLarge is returned; 0 means no limit.
+// The walk stops with the error of ctx once it is done.
+func Collect(ctx context.Context, dir string, maxSize int64, include func(name string) bool) ([]File, error) {
+	var files []File
+	var total int64
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if p == dir {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if strings.HasPrefix(d.Name(), ".") || !include(name+"/") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || strings.HasPrefix(d.Name(), ".") || !include(name) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		total += info.Size()
+		if maxSize > 0 && total > maxSize {
+			return fmt.Errorf("%w: more than %d bytes", ErrTooLarge, maxSize)
+		}
+		files = append(files, File{Path: p, Name: name, Size: info.Size(), ModTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// Write writes an archive of files in format to w. It stops with the error
+// of ctx once it is done, leaving the archive cut short.
+func Write(ctx context.Context, w io.Writer, format string, files []File) error {
+	switch format {
+	case FormatZip:
+		return writeZip(ctx, w, files)
+	case FormatTarGz:
+		return writeTarGz(ctx, w, files)
+	}
+	return fmt.Errorf("%w: %s", ErrUnsupportedFormat, format)
+}
+
+func writeZip(ctx context.Context, w io.Writer, files []File) error {
+	zw := zip.NewWriter(w)
+	for _, file := range files {
+		entry, err := zw.CreateHeader(&zip.FileHeader{Name: file.Name, Method: zip.Deflate, Modified: file.ModTime})
+		if err != nil {
+			return fmt.Errorf("error writing archive: %w", err)
+		}
+		if err := copyFile(ctx, entry, file.Path); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return fmt.Errorf("error writing archive: %w", err)
+	}
+	return nil
+}
+
+func writeTarGz(ctx context.Context, w io.Writer, files []File) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, file := range files {
+		header := &tar.Header{Typeflag: tar.TypeReg, Name: file.Name, Mode: 0644, Size: file.Size, ModTime: file.ModTime}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("error writing archive: %w", err)
+		}
+		if err := copyFile(ctx, tw, file.Path); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("error writing archive: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("error writing archive: %w", err)
+	}
+	return nil
+}
+
+// copyFile copies the file at path to w. In a tar archive, a file whose size
+// changed since it was collected fails the archive rather than corrupting it.
+func copyFile(ctx context.Context, w io.Writer, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %w", path, err)
+	}
+	defer f.Close()
+	if _, err := io.Copy(w, ctxReader{ctx, f}); err != nil {
+		return fmt.Errorf("error archiving %s: %w", path, err)
+	}
+	return nil
+}
+
+// ctxReader reads from r until ctx is done.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c ctxReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
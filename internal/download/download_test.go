@@ -0,0 +1,160 @@
+package download
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func writeTree(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, contents := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func names(files []File) []string {
+	var names []string
+	for _, file := range files {
+		names = append(names, file.Name)
+	}
+	return names
+}
+
+func TestCollect(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"a.txt":          "aaaa",
+		"sub/b.txt":      "bb",
+		"sub/.hidden":    "x",
+		".git/config":    "x",
+		"secret/c.txt":   "x",
+		"sub/skip.log":   "x",
+		"sub/deep/d.txt": "d",
+	})
+	include := func(name string) bool {
+		return name != "secret/" && !strings.HasSuffix(name, ".log")
+	}
+
+	files, err := Collect(context.Background(), dir, 0, include)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	want := []string{"a.txt", "sub/b.txt", "sub/deep/d.txt"}
+	if got := names(files); !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	if _, err := Collect(context.Background(), dir, 6, include); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge, got %v", err)
+	}
+	if _, err := Collect(context.Background(), dir, 7, include); err != nil {
+		t.Errorf("Expected the files to fit in 7 bytes, got %v", err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a.txt": "hello", "sub/b.txt": "world"})
+	files, err := Collect(context.Background(), dir, 0, func(string) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"a.txt": "hello", "sub/b.txt": "world"}
+
+	tests := []struct {
+		format string
+		read   func(t *testing.T, data []byte) map[string]string
+	}{
+		{FormatZip, readZip},
+		{FormatTarGz, readTarGz},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(context.Background(), &buf, tt.format, files); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			got := tt.read(t, buf.Bytes())
+			if len(got) != len(want) || got["a.txt"] != want["a.txt"] || got["sub/b.txt"] != want["sub/b.txt"] {
+				t.Errorf("Expected %v, got %v", want, got)
+			}
+		})
+	}
+
+	if err := Write(context.Background(), io.Discard, "rar", files); !errors.Is(err, ErrUnsupportedFormat) {
+		t.Errorf("Expected ErrUnsupportedFormat, got %v", err)
+	}
+}
+
+func readZip(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Expected a zip archive, got %v", err)
+	}
+	files := map[string]string{}
+	for _, file := range reader.File {
+		r, _ := file.Open()
+		contents, _ := io.ReadAll(r)
+		r.Close()
+		files[file.Name] = string(contents)
+	}
+	return files
+}
+
+func readTarGz(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("Expected a gzip stream, got %v", err)
+	}
+	reader := tar.NewReader(gz)
+	files := map[string]string{}
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return files
+		}
+		if err != nil {
+			t.Fatalf("Expected a tar archive, got %v", err)
+		}
+		contents, _ := io.ReadAll(reader)
+		files[header.Name] = string(contents)
+	}
+}
+
+func TestCancelled(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a.txt": "a", "b/c.txt": "c"})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := Collect(ctx, dir, 0, func(string) bool { return true }); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected Collect to stop with context.Canceled, got %v", err)
+	}
+	files, err := Collect(context.Background(), dir, 0, func(string) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, format := range []string{FormatZip, FormatTarGz} {
+		if err := Write(ctx, io.Discard, format, files); !errors.Is(err, context.Canceled) {
+			t.Errorf("Expected Write of %s to stop with context.Canceled, got %v", format, err)
+		}
+	}
+}
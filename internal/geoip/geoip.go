@@ -0,0 +1,69 @@
+// Package geoip looks up the country of client addresses in a MaxMind
+// GeoLite2/GeoIP2 database and evaluates country allow/deny rules.
+package geoip
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/oschwald/geoip2-golang"
+)
+
+// DB is an open GeoIP database.
+type DB struct {
+	reader *geoip2.Reader
+}
+
+// Open opens the MaxMind database (.mmdb) at path. Both Country and City databases are supported.
+func Open(path string) (*DB, error) {
+	reader, err := geoip2.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening GeoIP database: %w", err)
+	}
+	return &DB{reader: reader}, nil
+}
+
+// Close closes the database.
+func (db *DB) Close() error {
+	return db.reader.Close()
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of the country the IP address
+// belongs to, or an empty string if it is unknown.
+func (db *DB) Country(ip string) string {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ""
+	}
+
+	record, err := db.reader.Country(parsed)
+	if err != nil {
+		return ""
+	}
+	return record.Country.IsoCode
+}
+
+// Allowed reports whether a client from the country may be served given the
+// allow and deny lists of ISO country codes. A non-empty allow list only admits
+// the listed countries, which excludes clients whose country is unknown.
+// The deny list is checked after the allow list.
+func Allowed(country string, allow, deny []string) bool {
+	if len(allow) > 0 && !contains(allow, country) {
+		return false
+	}
+	return !contains(deny, country)
+}
+
+// contains reports whether the list has the country code, compared case-insensitively.
+func contains(list []string, country string) bool {
+	if country == "" {
+		return false
+	}
+	for _, code := range list {
+		if strings.EqualFold(code, country) {
+			return true
+		}
+	}
+	return false
+}
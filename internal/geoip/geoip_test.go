@@ -0,0 +1,40 @@
+package geoip
+
+import (
+	"testing"
+)
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name    string
+		country string
+		allow   []string
+		deny    []string
+		allowed bool
+	}{
+		{"No rules", "DE", nil, nil, true},
+		{"Unknown country without rules", "", nil, nil, true},
+		{"In allow list", "DE", []string{"DE", "FR"}, nil, true},
+		{"Allow list is case-insensitive", "de", []string{"DE"}, nil, true},
+		{"Not in allow list", "US", []string{"DE", "FR"}, nil, false},
+		{"Unknown country with allow list", "", []string{"DE"}, nil, false},
+		{"In deny list", "CN", nil, []string{"CN"}, false},
+		{"Not in deny list", "DE", nil, []string{"CN"}, true},
+		{"Unknown country with deny list", "", nil, []string{"CN"}, true},
+		{"Allowed and denied", "DE", []string{"DE"}, []string{"DE"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Allowed(tt.country, tt.allow, tt.deny); got != tt.allowed {
+				t.Errorf("Allowed(%q, %v, %v) = %t, want %t", tt.country, tt.allow, tt.deny, got, tt.allowed)
+			}
+		})
+	}
+}
+
+func TestOpenMissingDatabase(t *testing.T) {
+	if _, err := Open("testdata/missing.mmdb"); err == nil {
+		t.Errorf("Expected an error opening a missing database")
+	}
+}
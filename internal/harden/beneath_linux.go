@@ -0,0 +1,39 @@
+package harden
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"golang.org/x/sys/unix"
+)
+
+// openBeneath opens name below root with openat2 and RESOLVE_BENEATH, which
+// makes the kernel refuse every step of the resolution that leaves root.
+func openBeneath(root, name string) (*os.File, error) {
+	dir, err := unix.Open(root, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &os.PathError{Op: "open", Path: root, Err: err}
+	}
+	defer unix.Close(dir)
+
+	how := unix.OpenHow{
+		Flags:   unix.O_RDONLY | unix.O_CLOEXEC,
+		Resolve: unix.RESOLVE_BENEATH | unix.RESOLVE_NO_MAGICLINKS,
+	}
+	for {
+		fd, err := unix.Openat2(dir, name, &how)
+		switch {
+		case err == nil:
+			return os.NewFile(uintptr(fd), filepath.Join(root, name)), nil
+		case errors.Is(err, unix.EAGAIN):
+			// A rename raced with the resolution; the kernel asks to try again.
+			continue
+		case errors.Is(err, unix.ENOSYS):
+			return openRoot(root, name)
+		case errors.Is(err, unix.EXDEV):
+			err = ErrOutsideRoot
+		}
+		return nil, &os.PathError{Op: "openat2", Path: filepath.Join(root, name), Err: err}
+	}
+}
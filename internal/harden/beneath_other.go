@@ -0,0 +1,10 @@
+//go:build !linux
+
+package harden
+
+import "os"
+
+// openBeneath opens name below root with os.Root.
+func openBeneath(root, name string) (*os.File, error) {
+	return openRoot(root, name)
+}
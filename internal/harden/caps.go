@@ -0,0 +1,59 @@
+package harden
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// capNetBindService is the one capability allowed in hardened mode, needed to
+// listen on ports below 1024 without running as root.
+const capNetBindService = 10
+
+// capabilityNames are the names of the Linux capabilities, by number.
+var capabilityNames = []string{
+	"cap_chown", "cap_dac_override", "cap_dac_read_search", "cap_fowner",
+	"cap_fsetid", "cap_kill", "cap_setgid", "cap_setuid", "cap_setpcap",
+	"cap_linux_immutable", "cap_net_bind_service", "cap_net_broadcast",
+	"cap_net_admin", "cap_net_raw", "cap_ipc_lock", "cap_ipc_owner",
+	"cap_sys_module", "cap_sys_rawio", "cap_sys_chroot", "cap_sys_ptrace",
+	"cap_sys_pacct", "cap_sys_admin", "cap_sys_boot", "cap_sys_nice",
+	"cap_sys_resource", "cap_sys_time", "cap_sys_tty_config", "cap_mknod",
+	"cap_lease", "cap_audit_write", "cap_audit_control", "cap_setfcap",
+	"cap_mac_override", "cap_mac_admin", "cap_syslog", "cap_wake_alarm",
+	"cap_block_suspend", "cap_audit_read", "cap_perfmon", "cap_bpf",
+	"cap_checkpoint_restore",
+}
+
+// effectiveCapabilities returns the CapEff mask of a /proc/<pid>/status file.
+func effectiveCapabilities(status string) (uint64, error) {
+	for line := range strings.SplitSeq(status, "\n") {
+		value, ok := strings.CutPrefix(line, "CapEff:")
+		if !ok {
+			continue
+		}
+		mask, err := strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid CapEff %q: %w", strings.TrimSpace(value), err)
+		}
+		return mask, nil
+	}
+	return 0, fmt.Errorf("no CapEff line in the process status")
+}
+
+// extraCapabilities returns the names of the capabilities in mask other than
+// CAP_NET_BIND_SERVICE.
+func extraCapabilities(mask uint64) []string {
+	var extra []string
+	for bit := range 64 {
+		if mask&(1<<bit) == 0 || bit == capNetBindService {
+			continue
+		}
+		if bit < len(capabilityNames) {
+			extra = append(extra, capabilityNames[bit])
+		} else {
+			extra = append(extra, "cap_"+strconv.Itoa(bit))
+		}
+	}
+	return extra
+}
@@ -0,0 +1,25 @@
+package harden
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CheckCapabilities returns an error if the process holds effective
+// capabilities other than CAP_NET_BIND_SERVICE, as it does when run as root
+// without a capability bounding set.
+func CheckCapabilities() error {
+	status, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return fmt.Errorf("error reading the capabilities of the process: %w", err)
+	}
+	mask, err := effectiveCapabilities(string(status))
+	if err != nil {
+		return err
+	}
+	if extra := extraCapabilities(mask); len(extra) > 0 {
+		return fmt.Errorf("hardened mode allows no capabilities but cap_net_bind_service, the process has %s", strings.Join(extra, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package harden
+
+// CheckCapabilities returns nil: capabilities are specific to Linux.
+func CheckCapabilities() error {
+	return nil
+}
@@ -0,0 +1,137 @@
+// Package harden implements the hardened mode of the server, set with
+// server.hardened. In hardened mode the server only serves files beneath the
+// document root, writes nothing but its log files, starts no other programs
+// and refuses to run with capabilities beyond CAP_NET_BIND_SERVICE, so it
+// can run on a read-only file system under a tight seccomp or AppArmor
+// profile. Check refuses the settings that would do otherwise.
+package harden
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// ErrOutsideRoot is returned by OpenBeneath for names that resolve outside
+// the root, such as symbolic links pointing out of it.
+var ErrOutsideRoot = errors.New("path resolves outside the root")
+
+// Check returns an error naming the settings of cfg, and the number of
+// worker processes of volk serve --workers, that hardened mode refuses: those
+// that serve files from outside the document root, those that write outside
+// the log, statistics and audit files, and those that start programs, the
+// volk binary included, or load code.
+func Check(cfg config.Config, workers int) error {
+	var refused []string
+	if workers > 1 {
+		refused = append(refused, "--workers")
+	}
+	if cfg.Server.PIDFile != "" {
+		refused = append(refused, "server.pid_file (used by volk upgrade)")
+	}
+	if cfg.UserDir.Enabled {
+		refused = append(refused, "userdir.enabled")
+	}
+	for _, location := range cfg.Locations {
+		for _, flavor := range location.Flavors {
+			if !beneath(cfg.FileServer.DocumentRoot, flavor.DocumentRoot) {
+				refused = append(refused, fmt.Sprintf("location %s flavor document_root %s", location.Path, flavor.DocumentRoot))
+			}
+		}
+		for _, variant := range location.Variants {
+			if !beneath(cfg.FileServer.DocumentRoot, variant.DocumentRoot) {
+				refused = append(refused, fmt.Sprintf("location %s variant document_root %s", location.Path, variant.DocumentRoot))
+			}
+		}
+	}
+	if cfg.Download.Enabled {
+		refused = append(refused, "download.enabled")
+	}
+	if cfg.Thumbnail.Enabled {
+		refused = append(refused, "thumbnail.enabled")
+	}
+	if cfg.KV.Enabled {
+		refused = append(refused, "kv.enabled")
+	}
+	if cfg.Deploy.Enabled {
+		refused = append(refused, "deploy.enabled")
+	}
+	if cfg.Upload.Enabled {
+		refused = append(refused, "upload.enabled")
+	}
+	if cfg.Tee.Directory != "" {
+		refused = append(refused, "tee.directory")
+	}
+	for _, webhook := range cfg.Webhooks {
+		if webhook.Spool != "" {
+			refused = append(refused, fmt.Sprintf("webhook %s spool", webhook.Path))
+		}
+		if webhook.Command != "" {
+			refused = append(refused, fmt.Sprintf("webhook %s command", webhook.Path))
+		}
+	}
+	if len(cfg.Plugins) > 0 {
+		refused = append(refused, "plugin")
+	}
+	if len(refused) > 0 {
+		return fmt.Errorf("hardened mode does not allow %s", strings.Join(refused, ", "))
+	}
+	return nil
+}
+
+// beneath reports whether dir is root or a directory below it, going by
+// their paths.
+func beneath(root, dir string) bool {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return false
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(absRoot, absDir)
+	return err == nil && filepath.IsLocal(rel)
+}
+
+// OpenBeneath opens the named file below root for reading. Unlike os.Open of
+// the joined path, it fails with ErrOutsideRoot when a ".." or a symbolic link
+// would leave root, also when the tree changes while the name is resolved.
+// On Linux it uses openat2 with RESOLVE_BENEATH; elsewhere, and on kernels
+// older than 5.6, it uses os.Root.
+func OpenBeneath(root, name string) (*os.File, error) {
+	return openBeneath(root, name)
+}
+
+// OpenIn opens the named file below the open directory root for reading,
+// failing with ErrOutsideRoot like OpenBeneath. As root is a handle and not
+// a path, the directory cannot be swapped for another one between checking
+// it and opening files in it.
+func OpenIn(root *os.Root, name string) (*os.File, error) {
+	f, err := root.Open(name)
+	if err != nil && escapes(err) {
+		err = &os.PathError{Op: "open", Path: filepath.Join(root.Name(), name), Err: ErrOutsideRoot}
+	}
+	return f, err
+}
+
+// escapes reports whether err is the error of os.Root for a name leaving the
+// root, which the os package does not export.
+func escapes(err error) bool {
+	var pathErr *os.PathError
+	return errors.As(err, &pathErr) && pathErr.Err.Error() == "path escapes from parent"
+}
+
+// openRoot opens name below root with os.Root.
+func openRoot(root, name string) (*os.File, error) {
+	r, err := os.OpenRoot(root)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return OpenIn(r, name)
+}
@@ -0,0 +1,166 @@
+package harden
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(cfg *config.Config)
+		refused string
+	}{
+		{"Default", func(cfg *config.Config) {}, ""},
+		{"Log files", func(cfg *config.Config) {
+			cfg.Logging.FilePath = "/var/log/volk/access.log"
+			cfg.Stats.Enabled = true
+			cfg.Audit.FilePath = "/var/log/volk/audit.jsonl"
+		}, ""},
+		{"PID file", func(cfg *config.Config) { cfg.Server.PIDFile = "/run/volk.pid" }, "server.pid_file"},
+		{"Key-value API", func(cfg *config.Config) { cfg.KV.Enabled = true }, "kv.enabled"},
+		{"Uploads", func(cfg *config.Config) { cfg.Upload.Enabled = true }, "upload.enabled"},
+		{"Tee directory", func(cfg *config.Config) { cfg.Tee.Directory = "/srv/mirror" }, "tee.directory"},
+		{"Webhook command", func(cfg *config.Config) {
+			cfg.Webhooks = []config.WebhookConfig{{Path: "/hook", Command: "make deploy"}}
+		}, "webhook /hook command"},
+		{"Plugins", func(cfg *config.Config) { cfg.Plugins = []config.PluginConfig{{Path: "auth.so"}} }, "plugin"},
+		{"User directories", func(cfg *config.Config) { cfg.UserDir.Enabled = true }, "userdir.enabled"},
+		{"Roots beneath the document root", func(cfg *config.Config) {
+			cfg.FileServer.DocumentRoot = "/srv/www"
+			cfg.Locations = []config.LocationConfig{{
+				Path:     "/",
+				Flavors:  []config.FlavorConfig{{Header: "X-Env", Value: "staging", DocumentRoot: "/srv/www/staging"}},
+				Variants: []config.VariantConfig{{Name: "a", DocumentRoot: "/srv/www"}},
+			}}
+		}, ""},
+		{"Flavor outside the document root", func(cfg *config.Config) {
+			cfg.FileServer.DocumentRoot = "/srv/www"
+			cfg.Locations = []config.LocationConfig{{Path: "/", Flavors: []config.FlavorConfig{{Header: "X-Env", Value: "staging", DocumentRoot: "/srv/staging"}}}}
+		}, "location / flavor document_root /srv/staging"},
+		{"Variant outside the document root", func(cfg *config.Config) {
+			cfg.FileServer.DocumentRoot = "/srv/www"
+			cfg.Locations = []config.LocationConfig{{Path: "/", Variants: []config.VariantConfig{{Name: "b", DocumentRoot: "/srv/www/../b"}}}}
+		}, "location / variant document_root /srv/www/../b"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			test.modify(&cfg)
+			err := Check(cfg, 0)
+			if test.refused == "" && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if test.refused != "" && (err == nil || !strings.Contains(err.Error(), test.refused)) {
+				t.Errorf("Expected an error naming %s, got %v", test.refused, err)
+			}
+		})
+	}
+}
+
+func TestOpenBeneath(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "docs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "docs", "index.html"), []byte("docs"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	outside := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(outside, []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(root, "absolute")); err != nil {
+		t.Skipf("symbolic links not available: %v", err)
+	}
+	if err := os.Symlink(filepath.Join("..", filepath.Base(filepath.Dir(outside)), "secret.txt"), filepath.Join(root, "relative")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join("docs", "index.html"), filepath.Join(root, "inside")); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, name := range []string{filepath.Join("docs", "index.html"), "inside"} {
+		f, err := OpenBeneath(root, name)
+		if err != nil {
+			t.Errorf("Expected %s to open, got %v", name, err)
+			continue
+		}
+		f.Close()
+	}
+
+	dir, err := os.OpenRoot(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dir.Close()
+	for _, name := range []string{"absolute", "relative", filepath.Join("..", "secret.txt")} {
+		for open, f := range map[string]func(string) (*os.File, error){
+			"OpenBeneath": func(name string) (*os.File, error) { return OpenBeneath(root, name) },
+			"OpenIn":      func(name string) (*os.File, error) { return OpenIn(dir, name) },
+		} {
+			file, err := f(name)
+			if err == nil {
+				file.Close()
+				t.Errorf("Expected %s not to open %s", open, name)
+				continue
+			}
+			if !errors.Is(err, ErrOutsideRoot) {
+				t.Errorf("Expected ErrOutsideRoot from %s for %s, got %v", open, name, err)
+			}
+		}
+	}
+
+	if _, err := OpenBeneath(root, "missing.html"); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected os.ErrNotExist, got %v", err)
+	}
+}
+
+func TestCheckWorkers(t *testing.T) {
+	if err := Check(config.DefaultConfig(), 1); err != nil {
+		t.Errorf("Expected a single process to be allowed, got %v", err)
+	}
+	if err := Check(config.DefaultConfig(), 4); err == nil || !strings.Contains(err.Error(), "--workers") {
+		t.Errorf("Expected an error naming --workers, got %v", err)
+	}
+}
+
+func TestEffectiveCapabilities(t *testing.T) {
+	status := "Name:\tvolk\nCapInh:\t0000000000000000\nCapPrm:\t0000000000000400\nCapEff:\t0000000000000400\n"
+	mask, err := effectiveCapabilities(status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mask != 1<<capNetBindService {
+		t.Errorf("Expected mask %x, got %x", 1<<capNetBindService, mask)
+	}
+
+	if _, err := effectiveCapabilities("Name:\tvolk\n"); err == nil {
+		t.Errorf("Expected an error for a status without CapEff")
+	}
+}
+
+func TestExtraCapabilities(t *testing.T) {
+	tests := []struct {
+		mask     uint64
+		expected []string
+	}{
+		{0, nil},
+		{1 << capNetBindService, nil},
+		{1<<capNetBindService | 1<<21, []string{"cap_sys_admin"}},
+		{1<<0 | 1<<7 | 1<<50, []string{"cap_chown", "cap_setuid", "cap_50"}},
+	}
+
+	for _, test := range tests {
+		if got := extraCapabilities(test.mask); !slices.Equal(got, test.expected) {
+			t.Errorf("Expected %v for mask %x, got %v", test.expected, test.mask, got)
+		}
+	}
+}
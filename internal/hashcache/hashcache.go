@@ -0,0 +1,156 @@
+// Package hashcache computes SHA-256 sums of files in the background and
+// caches them.
+//
+// A sum is looked up by the file's path, size and modification time, so a
+// file that changes is hashed again. Looking up a file that has no sum yet
+// schedules hashing it and returns at once: callers fall back to something
+// cheaper for the requests that arrive while a large file is being read.
+package hashcache
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultWorkers is the number of files hashed at the same time.
+	DefaultWorkers = 2
+	// DefaultMaxEntries is the number of sums kept.
+	DefaultMaxEntries = 10000
+)
+
+// key identifies a version of a file.
+type key struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// Cache holds the sums of files. The zero value is not usable; create one
+// with New.
+type Cache struct {
+	maxEntries int
+	workers    chan struct{} // Holds a token per running hash
+
+	mu      sync.Mutex
+	sums    map[string]entry // By path; only the current version is kept
+	pending map[key]bool
+}
+
+// entry is the sum of a version of a file.
+type entry struct {
+	key key
+	sum string
+}
+
+// New creates a cache hashing up to workers files at the same time and
+// keeping up to maxEntries sums. Zero values select the defaults.
+func New(workers, maxEntries int) *Cache {
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &Cache{
+		maxEntries: maxEntries,
+		workers:    make(chan struct{}, workers),
+		sums:       make(map[string]entry),
+		pending:    make(map[key]bool),
+	}
+}
+
+// Sum returns the SHA-256 sum of the file at path with the given size and
+// modification time, encoded as unpadded base64url, and true. If the sum is
+// not known yet, the file is hashed in the background, reading it from the
+// reader open returns, and Sum returns false. When all workers are busy, the
+// file is left for a later lookup.
+func (c *Cache) Sum(path string, size int64, modTime time.Time, open func() (io.ReadCloser, error)) (string, bool) {
+	k := key{path: path, size: size, modTime: modTime}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.sums[path]; ok && e.key.size == size && e.key.modTime.Equal(modTime) {
+		return e.sum, true
+	}
+	if c.pending[k] {
+		return "", false
+	}
+	select {
+	case c.workers <- struct{}{}:
+	default:
+		return "", false
+	}
+	c.pending[k] = true
+	go c.hash(k, open)
+	return "", false
+}
+
+// hash computes the sum of a version of a file and stores it.
+func (c *Cache) hash(k key, open func() (io.ReadCloser, error)) {
+	defer func() { <-c.workers }()
+
+	sum, err := hashFile(open)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.pending, k)
+	if err != nil {
+		log.Printf("Error hashing %s: %v", k.path, err)
+		return
+	}
+	if _, ok := c.sums[k.path]; !ok && len(c.sums) >= c.maxEntries {
+		// Make room by dropping any entry; files that are still requested are hashed again.
+		for path := range c.sums {
+			delete(c.sums, path)
+			break
+		}
+	}
+	c.sums[k.path] = entry{key: k, sum: sum}
+}
+
+// hashFile returns the encoded sum of what open returns.
+func hashFile(open func() (io.ReadCloser, error)) (string, error) {
+	f, err := open()
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// Purge drops the sums of the file at path and the files below it, or every
+// sum if path is empty, and returns the number of sums dropped. Files being
+// hashed are not affected.
+func (c *Cache) Purge(path string) int {
+	dir := strings.TrimSuffix(path, string(filepath.Separator)) + string(filepath.Separator)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	purged := 0
+	for file := range c.sums {
+		if path == "" || file == path || strings.HasPrefix(file, dir) {
+			delete(c.sums, file)
+			purged++
+		}
+	}
+	return purged
+}
+
+// Len returns the number of sums kept.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.sums)
+}
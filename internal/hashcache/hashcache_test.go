@@ -0,0 +1,148 @@
+package hashcache
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// opener returns an open function reading content, which blocks until
+// release is closed if it is not nil.
+func opener(content string, release chan struct{}) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		if release != nil {
+			<-release
+		}
+		return io.NopCloser(strings.NewReader(content)), nil
+	}
+}
+
+// waitSum looks the file up until its sum is known.
+func waitSum(t *testing.T, c *Cache, path string, size int64, modTime time.Time, open func() (io.ReadCloser, error)) string {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if sum, ok := c.Sum(path, size, modTime, open); ok {
+			return sum
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("Expected the sum of %s to be computed", path)
+	return ""
+}
+
+func TestSum(t *testing.T) {
+	c := New(1, 0)
+	modTime := time.Unix(1700000000, 0)
+
+	if _, ok := c.Sum("/a", 5, modTime, opener("hello", nil)); ok {
+		t.Error("Expected no sum before the file is hashed")
+	}
+	sum := waitSum(t, c, "/a", 5, modTime, opener("hello", nil))
+	// SHA-256 of "hello"
+	if expected := "LPJNul-wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ"; sum != expected {
+		t.Errorf("Expected %q, got %q", expected, sum)
+	}
+
+	// A new version of the file is hashed again.
+	if _, ok := c.Sum("/a", 5, modTime.Add(time.Second), opener("world", nil)); ok {
+		t.Error("Expected no sum for a changed file")
+	}
+	if sum := waitSum(t, c, "/a", 5, modTime.Add(time.Second), opener("world", nil)); sum == "" || sum == "LPJNul-wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ" {
+		t.Errorf("Expected the sum of the new content, got %q", sum)
+	}
+	if c.Len() != 1 {
+		t.Errorf("Expected 1 entry, got %d", c.Len())
+	}
+}
+
+func TestSumBusyWorkers(t *testing.T) {
+	c := New(1, 0)
+	modTime := time.Unix(1700000000, 0)
+	release := make(chan struct{})
+
+	var mu sync.Mutex
+	opened := 0
+	counting := func() (io.ReadCloser, error) {
+		mu.Lock()
+		opened++
+		mu.Unlock()
+		return opener("b", nil)()
+	}
+
+	c.Sum("/slow", 1, modTime, opener("a", release))
+	// The only worker is busy: the file is not scheduled.
+	if _, ok := c.Sum("/b", 1, modTime, counting); ok {
+		t.Error("Expected no sum while the worker is busy")
+	}
+	// Looking up a file being hashed does not schedule it twice.
+	c.Sum("/slow", 1, modTime, opener("a", release))
+	close(release)
+
+	waitSum(t, c, "/slow", 1, modTime, opener("a", nil))
+	waitSum(t, c, "/b", 1, modTime, counting)
+	mu.Lock()
+	defer mu.Unlock()
+	if opened != 1 {
+		t.Errorf("Expected /b to be opened once, got %d", opened)
+	}
+}
+
+func TestSumMaxEntries(t *testing.T) {
+	c := New(1, 2)
+	modTime := time.Unix(1700000000, 0)
+	for _, path := range []string{"/a", "/b", "/c"} {
+		waitSum(t, c, path, 1, modTime, opener(path, nil))
+	}
+	if c.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", c.Len())
+	}
+}
+
+func TestSumOpenError(t *testing.T) {
+	c := New(1, 0)
+	failing := func() (io.ReadCloser, error) { return nil, errors.New("permission denied") }
+	c.Sum("/a", 1, time.Unix(1700000000, 0), failing)
+
+	// The failed file is not kept, and is tried again once the worker is free.
+	deadline := time.Now().Add(5 * time.Second)
+	for len(c.workers) > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if c.Len() != 0 {
+		t.Errorf("Expected no entries, got %d", c.Len())
+	}
+	if sum := waitSum(t, c, "/a", 1, time.Unix(1700000000, 0), opener("a", nil)); sum == "" {
+		t.Error("Expected a sum after the file could be read")
+	}
+}
+
+func TestPurge(t *testing.T) {
+	c := New(1, 0)
+	modTime := time.Unix(1700000000, 0)
+	for _, path := range []string{"/srv/www/index.html", "/srv/www/assets/app.js", "/srv/www/assets/app.css", "/srv/www/assets-old/app.js"} {
+		waitSum(t, c, path, 1, modTime, opener(path, nil))
+	}
+
+	tests := []struct {
+		path     string
+		expected int
+	}{
+		{"/srv/www/assets/app.js", 1},
+		{"/srv/www/assets/", 1},
+		{"/srv/www/assets", 0},
+		{"", 2},
+	}
+
+	for _, tt := range tests {
+		if purged := c.Purge(tt.path); purged != tt.expected {
+			t.Errorf("Purge(%q): expected %d, got %d", tt.path, tt.expected, purged)
+		}
+	}
+	if c.Len() != 0 {
+		t.Errorf("Expected no entries, got %d", c.Len())
+	}
+}
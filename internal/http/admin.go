@@ -0,0 +1,171 @@
+package http
+
+import (
+	"encoding/json"
+	"log"
+	"runtime"
+	"time"
+)
+
+// recentGCPauses is the number of most recent garbage collection pauses the
+// vars endpoint reports.
+const recentGCPauses = 16
+
+// Drain puts the server in draining mode before a deploy: it keeps serving
+// requests, but every response carries Connection: close and the readiness
+// endpoint fails, so load balancers stop sending traffic.
+func (s *Server) Drain() {
+	if !s.draining.Swap(true) {
+		log.Printf("Draining: %d requests in flight", s.InFlight())
+	}
+}
+
+// Resume ends draining mode.
+func (s *Server) Resume() {
+	if s.draining.Swap(false) {
+		log.Printf("Draining stopped")
+	}
+}
+
+// Draining reports whether the server is in draining mode.
+func (s *Server) Draining() bool {
+	return s.draining.Load()
+}
+
+// InFlight returns the number of requests being handled.
+func (s *Server) InFlight() int64 {
+	return s.inFlight.Load()
+}
+
+// readyHandler serves the readiness endpoint: 200 normally, 503 while draining.
+// It needs no token, so probes can query it.
+func readyHandler(s *Server) ResponseFunc {
+	return func(req *Request) Response {
+		if s.Draining() {
+			return jsonResponse(req, 503, `{"ready":false,"draining":true}`)
+		}
+		return jsonResponse(req, 200, `{"ready":true,"draining":false}`)
+	}
+}
+
+// liveHandler serves the liveness probe, which answers as long as the server
+// handles requests.
+func liveHandler(req *Request) Response {
+	return jsonResponse(req, 200, `{"alive":true}`)
+}
+
+// drainHandler serves the draining endpoint. Every request needs the token as
+// a bearer token.
+//
+//	GET    path   report whether the server drains and how many requests are in flight
+//	POST   path   start draining
+//	DELETE path   stop draining
+//
+// The requests in flight do not count the request to the endpoint itself, so
+// a drained server reports zero and drained = true.
+func drainHandler(s *Server, token string) ResponseFunc {
+	return func(req *Request) Response {
+		if resp, ok := checkBearer(req, token, "admin"); !ok {
+			return resp
+		}
+
+		status := StatusCode(200)
+		switch req.GetMethod() {
+		case POST:
+			s.Drain()
+			status = 202
+		case DELETE:
+			s.Resume()
+		}
+
+		inFlight := max(s.InFlight()-1, 0)
+		body, _ := json.Marshal(map[string]any{
+			"draining":  s.Draining(),
+			"in_flight": inFlight,
+			"drained":   s.Draining() && inFlight == 0,
+		})
+		return jsonResponse(req, status, string(body))
+	}
+}
+
+// usageHandler serves the storage usage endpoint, which reports the bytes
+// used by the key-value store, the deploy releases and the uploads against
+// their quotas.
+// A quota of 0 means there is none. It needs the token as a bearer token.
+func usageHandler(s *Server, token string) ResponseFunc {
+	return func(req *Request) Response {
+		if resp, ok := checkBearer(req, token, "admin"); !ok {
+			return resp
+		}
+
+		usage := map[string]any{}
+		if s.KV != nil {
+			used, quota := s.KV.Usage()
+			usage["kv"] = map[string]int64{"used": used, "quota": quota}
+		}
+		if s.Deployer != nil {
+			used, quota, err := s.Deployer.Usage()
+			if err != nil {
+				log.Printf("Error reading deploy usage: %v", err)
+				return jsonResponse(req, 500, `{"error":"internal server error"}`)
+			}
+			usage["deploy"] = map[string]int64{"used": used, "quota": quota}
+		}
+		if s.Uploads != nil {
+			used, quota, err := s.Uploads.Usage()
+			if err != nil {
+				log.Printf("Error reading upload usage: %v", err)
+				return jsonResponse(req, 500, `{"error":"internal server error"}`)
+			}
+			usage["upload"] = map[string]int64{"used": used, "quota": quota}
+		}
+		body, _ := json.Marshal(usage)
+		return jsonResponse(req, 200, string(body))
+	}
+}
+
+// varsHandler serves the runtime variables endpoint: the server's counters and
+// the Go runtime's goroutines, memory and garbage collection statistics, in
+// JSON for monitoring without Prometheus. Reading the memory statistics stops
+// the world briefly, so poll it every few seconds at most. It needs the token
+// as a bearer token.
+func varsHandler(s *Server, token string) ResponseFunc {
+	return func(req *Request) Response {
+		if resp, ok := checkBearer(req, token, "admin"); !ok {
+			return resp
+		}
+
+		var mem runtime.MemStats
+		runtime.ReadMemStats(&mem)
+		pauses := make([]uint64, 0, recentGCPauses)
+		for i := uint32(0); i < min(mem.NumGC, recentGCPauses); i++ {
+			// PauseNs is a circular buffer with the latest pause at (NumGC+255)%256.
+			pauses = append(pauses, mem.PauseNs[(mem.NumGC-i+255)%256])
+		}
+
+		body, _ := json.Marshal(map[string]any{
+			"server": map[string]any{
+				"uptime_seconds": int64(time.Since(s.started).Seconds()),
+				"requests":       s.requests.Load(),
+				"bytes_sent":     s.bytesSent.Load(),
+				"in_flight":      max(s.InFlight()-1, 0),
+				"draining":       s.Draining(),
+			},
+			"runtime": map[string]any{
+				"go_version":        runtime.Version(),
+				"goroutines":        runtime.NumGoroutine(),
+				"cpus":              runtime.NumCPU(),
+				"heap_alloc":        mem.HeapAlloc,
+				"heap_sys":          mem.HeapSys,
+				"heap_objects":      mem.HeapObjects,
+				"total_alloc":       mem.TotalAlloc,
+				"sys":               mem.Sys,
+				"next_gc":           mem.NextGC,
+				"num_gc":            mem.NumGC,
+				"gc_pause_total_ns": mem.PauseTotalNs,
+				"gc_pauses_ns":      pauses,
+			},
+		})
+		return jsonResponse(req, 200, string(body))
+	}
+}
@@ -0,0 +1,203 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestAdminDrain(t *testing.T) {
+	log.SetOutput(&bytes.Buffer{})
+	defer log.SetOutput(os.Stderr)
+
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Admin = config.AdminConfig{Enabled: true, Path: "/_admin", Token: "secret"}
+	})
+	auth := "Authorization: Bearer secret"
+
+	steps := []struct {
+		name           string
+		method         Method
+		path           string
+		headers        []string
+		wantCode       StatusCode
+		wantDraining   bool
+		wantConnection string
+	}{
+		{"ready", GET, "/_admin/ready", nil, 200, false, ""},
+		{"unauthorized", POST, "/_admin/drain", nil, 401, false, ""},
+		{"start draining", POST, "/_admin/drain", []string{auth}, 202, true, "close"},
+		{"not ready", GET, "/_admin/ready", nil, 503, true, "close"},
+		{"files are still served", GET, "/index.html", nil, 200, true, "close"},
+		{"status", GET, "/_admin/drain", []string{auth}, 200, true, "close"},
+		{"stop draining", DELETE, "/_admin/drain", []string{auth}, 200, false, ""},
+		{"ready again", GET, "/_admin/ready", nil, 200, false, ""},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			resp := send(t, server, step.method, step.path, "", step.headers...)
+			if resp.GetStatusCode() != step.wantCode {
+				t.Errorf("Expected status %d, got %d", step.wantCode, resp.GetStatusCode())
+			}
+			if server.Draining() != step.wantDraining {
+				t.Errorf("Expected draining %v, got %v", step.wantDraining, server.Draining())
+			}
+			if connection, _ := GetHeader(resp.Headers, "Connection"); connection != step.wantConnection {
+				t.Errorf("Expected Connection %q, got %q", step.wantConnection, connection)
+			}
+		})
+	}
+}
+
+func TestProbes(t *testing.T) {
+	log.SetOutput(&bytes.Buffer{})
+	defer log.SetOutput(os.Stderr)
+
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Probes = config.ProbesConfig{Enabled: true, Liveness: "/healthz", Readiness: "/readyz"}
+	})
+
+	steps := []struct {
+		name     string
+		path     string
+		drain    bool
+		wantCode StatusCode
+	}{
+		{"alive", "/healthz", false, 200},
+		{"ready", "/readyz", false, 200},
+		{"alive while draining", "/healthz", true, 200},
+		{"not ready while draining", "/readyz", true, 503},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if step.drain {
+				server.Drain()
+			}
+			resp := get(t, server, step.path)
+			if resp.GetStatusCode() != step.wantCode {
+				t.Errorf("Expected status %d, got %d", step.wantCode, resp.GetStatusCode())
+			}
+		})
+	}
+}
+
+func TestAdminDrainInFlight(t *testing.T) {
+	log.SetOutput(&bytes.Buffer{})
+	defer log.SetOutput(os.Stderr)
+
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Admin = config.AdminConfig{Enabled: true, Path: "/_admin", Token: "secret"}
+	})
+	release := make(chan struct{})
+	server.Handle("/slow", func(w ResponseWriter, req *Request) {
+		<-release
+	})
+
+	done := make(chan struct{})
+	go func() {
+		get(t, server, "/slow")
+		close(done)
+	}()
+	for server.InFlight() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	status := func() map[string]any {
+		resp := send(t, server, POST, "/_admin/drain", "", "Authorization: Bearer secret")
+		var body map[string]any
+		if err := json.Unmarshal([]byte(resp.GetBody()), &body); err != nil {
+			t.Fatalf("Expected a JSON body, got %q", resp.GetBody())
+		}
+		return body
+	}
+
+	if body := status(); body["in_flight"] != 1.0 || body["drained"] != false {
+		t.Errorf("Expected one request in flight, got %v", body)
+	}
+
+	close(release)
+	<-done
+	for server.InFlight() != 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if body := status(); body["in_flight"] != 0.0 || body["drained"] != true {
+		t.Errorf("Expected the server to be drained, got %v", body)
+	}
+}
+
+func TestAdminRequiresToken(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Admin.Enabled = true
+	if _, err := NewServer(cfg); err == nil {
+		t.Error("Expected an error for an admin endpoint without a token, got nil")
+	}
+}
+
+func TestAdminUsage(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Admin = config.AdminConfig{Enabled: true, Path: "/_admin", Token: "secret"}
+		cfg.KV = config.KVConfig{Enabled: true, Database: filepath.Join(t.TempDir(), "kv.db"), Path: "/api/kv", Quota: 20}
+	})
+	defer server.Close()
+
+	if resp := send(t, server, PUT, "/api/kv/todo", `{"done":false}`); resp.GetStatusCode() != 201 {
+		t.Fatalf("Expected status 201, got %d", resp.GetStatusCode())
+	}
+	if resp := send(t, server, PUT, "/api/kv/x", `[1,2]`); resp.GetStatusCode() != 507 {
+		t.Errorf("Expected status 507 over the quota, got %d", resp.GetStatusCode())
+	}
+
+	if resp := send(t, server, GET, "/_admin/usage", ""); resp.GetStatusCode() != 401 {
+		t.Errorf("Expected status 401 without the token, got %d", resp.GetStatusCode())
+	}
+	resp := send(t, server, GET, "/_admin/usage", "", "Authorization: Bearer secret")
+	if want := `{"kv":{"quota":20,"used":18}}`; resp.GetBody() != want {
+		t.Errorf("Expected %s, got %s", want, resp.GetBody())
+	}
+}
+
+func TestAdminVars(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Admin = config.AdminConfig{Enabled: true, Path: "/_admin", Token: "secret"}
+	})
+	get(t, server, "/index.html")
+	runtime.GC()
+
+	if resp := send(t, server, GET, "/_admin/vars", ""); resp.GetStatusCode() != 401 {
+		t.Errorf("Expected status 401 without the token, got %d", resp.GetStatusCode())
+	}
+	resp := send(t, server, GET, "/_admin/vars", "", "Authorization: Bearer secret")
+	var vars struct {
+		Server struct {
+			Requests  int64 `json:"requests"`
+			BytesSent int64 `json:"bytes_sent"`
+			InFlight  int64 `json:"in_flight"`
+		} `json:"server"`
+		Runtime struct {
+			Goroutines int      `json:"goroutines"`
+			HeapAlloc  uint64   `json:"heap_alloc"`
+			NumGC      uint32   `json:"num_gc"`
+			GCPauses   []uint64 `json:"gc_pauses_ns"`
+		} `json:"runtime"`
+	}
+	if err := json.Unmarshal([]byte(resp.GetBody()), &vars); err != nil {
+		t.Fatalf("Expected a JSON body, got %q", resp.GetBody())
+	}
+	// The request for /index.html and the one without the token
+	if vars.Server.Requests != 2 || vars.Server.BytesSent == 0 || vars.Server.InFlight != 0 {
+		t.Errorf("Unexpected server counters %+v", vars.Server)
+	}
+	if vars.Runtime.Goroutines == 0 || vars.Runtime.HeapAlloc == 0 || vars.Runtime.NumGC == 0 || len(vars.Runtime.GCPauses) == 0 {
+		t.Errorf("Unexpected runtime statistics %+v", vars.Runtime)
+	}
+}
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"net"
+	"net/netip"
+	"strings"
+)
+
+// forwardingHeaders carry client addresses set by proxies, which debug
+// logging anonymizes like the client address itself.
+var forwardingHeaders = []string{"X-Forwarded-For", "X-Real-IP", "Forwarded"}
+
+// anonymizeIP zeroes the host part of an IP address: the last octet of an
+// IPv4 address and the last 80 bits of an IPv6 address. Values that are not
+// addresses are returned unchanged.
+func anonymizeIP(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	addr = addr.Unmap()
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, _ := addr.WithZone("").Prefix(bits)
+	return prefix.Addr().String()
+}
+
+// logIP returns the client address addr (an IP, with or without a port, or a
+// connection limit key) as it may be logged: anonymized without its port if
+// logging.anonymize_ips is set, otherwise unchanged.
+func (s *Server) logIP(addr string) string {
+	if !s.Config.Logging.AnonymizeIPs {
+		return addr
+	}
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	if prefix, err := netip.ParsePrefix(host); err == nil {
+		host = prefix.Addr().String()
+	}
+	return anonymizeIP(host)
+}
+
+// logHeader returns a header as it may be logged: redacted with redactHeader,
+// and with the client addresses of forwarding headers anonymized if
+// logging.anonymize_ips is set.
+func (s *Server) logHeader(header Header) Header {
+	header = s.redactHeader(header)
+	if !s.Config.Logging.AnonymizeIPs {
+		return header
+	}
+	for _, name := range forwardingHeaders {
+		if !strings.EqualFold(header.Name, name) {
+			continue
+		}
+		elements := strings.Split(header.Value, ",")
+		for i, element := range elements {
+			if strings.EqualFold(name, "Forwarded") {
+				elements[i] = s.anonymizeForwarded(element)
+			} else {
+				elements[i] = s.logIP(strings.TrimSpace(element))
+			}
+		}
+		return Header{Name: header.Name, Value: strings.Join(elements, ", ")}
+	}
+	return header
+}
+
+// anonymizeForwarded anonymizes the for parameter of an element of a
+// Forwarded header, e.g. for="[2001:db8::1]:4711";proto=https.
+func (s *Server) anonymizeForwarded(element string) string {
+	pairs := strings.Split(strings.TrimSpace(element), ";")
+	for i, pair := range pairs {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || !strings.EqualFold(name, "for") {
+			continue
+		}
+		value = strings.Trim(value, `"`)
+		if strings.HasPrefix(value, "[") {
+			if host, _, err := net.SplitHostPort(value); err == nil {
+				value = host
+			} else {
+				value = strings.Trim(value, "[]")
+			}
+		}
+		ip := s.logIP(value)
+		if strings.Contains(ip, ":") {
+			ip = `"[` + ip + `]"`
+		}
+		pairs[i] = name + "=" + ip
+	}
+	return strings.Join(pairs, ";")
+}
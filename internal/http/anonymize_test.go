@@ -0,0 +1,59 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestLogIP(t *testing.T) {
+	s := &Server{Config: config.Config{Logging: config.LogConfig{AnonymizeIPs: true}}}
+
+	tests := []struct {
+		addr     string
+		expected string
+	}{
+		{"192.0.2.77", "192.0.2.0"},
+		{"192.0.2.77:4711", "192.0.2.0"},
+		{"[::ffff:192.0.2.77]:4711", "192.0.2.0"},
+		{"2001:db8:1234:5678:9abc::1", "2001:db8:1234::"},
+		{"[2001:db8:1234:5678::1]:443", "2001:db8:1234::"},
+		{"2001:db8:1234:5678::/64", "2001:db8:1234::"},
+		{"pipe", "pipe"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := s.logIP(tt.addr); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+
+	s.Config.Logging.AnonymizeIPs = false
+	if got := s.logIP("192.0.2.77:4711"); got != "192.0.2.77:4711" {
+		t.Errorf("Expected the address unchanged, got %q", got)
+	}
+}
+
+func TestLogHeader(t *testing.T) {
+	s := &Server{Config: config.Config{Logging: config.LogConfig{AnonymizeIPs: true}}}
+
+	tests := []struct {
+		header   Header
+		expected string
+	}{
+		{Header{Name: "X-Forwarded-For", Value: "203.0.113.9, 2001:db8:1:2::3"}, "203.0.113.0, 2001:db8:1::"},
+		{Header{Name: "x-real-ip", Value: "203.0.113.9"}, "203.0.113.0"},
+		{Header{Name: "Forwarded", Value: `for=203.0.113.9;proto=https, for="[2001:db8:1:2::3]:4711"`}, `for=203.0.113.0;proto=https, for="[2001:db8:1::]"`},
+		{Header{Name: "User-Agent", Value: "curl 203.0.113.9"}, "curl 203.0.113.9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header.Name, func(t *testing.T) {
+			if got := s.logHeader(tt.header); got.Value != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got.Value)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package http
+
+import (
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/awaisamjad/volk/internal/audit"
+	"github.com/awaisamjad/volk/internal/signature"
+)
+
+// auditedMethods are the methods of write requests, which are recorded in the audit log.
+var auditedMethods = []Method{POST, PUT, PATCH, DELETE}
+
+// audit records a write request and its response status in the audit log.
+func (s *Server) audit(req *Request, resp Response) {
+	if !slices.Contains(auditedMethods, req.GetMethod()) {
+		return
+	}
+
+	err := s.Audit.Record(audit.Entry{
+		Time:      time.Now().UTC(),
+		RequestID: req.ID,
+		Method:    string(req.GetMethod()),
+		Path:      req.GetRequestTarget().Path,
+		Principal: auditPrincipal(req),
+		RemoteIP:  s.logIP(req.RemoteIP()),
+		Size:      len(req.Body),
+		Status:    int(resp.StartLine.StatusCode),
+	})
+	if err != nil {
+		log.Printf("Error recording request %s in the audit log: %v", req.ID, err)
+	}
+}
+
+// auditPrincipal returns who made the request: the user logged in with OIDC,
+// or how the client authenticated when it has no user name.
+func auditPrincipal(req *Request) string {
+	if req.Identity != nil {
+		if req.Identity.Email != "" {
+			return req.Identity.Email
+		}
+		return req.Identity.Subject
+	}
+	if authorization, _ := GetHeader(req.Headers, "Authorization"); strings.HasPrefix(authorization, "Bearer ") {
+		return "bearer-token"
+	}
+	if _, ok := GetHeader(req.Headers, signature.SignatureHeader); ok {
+		return "signature"
+	}
+	return ""
+}
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+	"github.com/awaisamjad/volk/internal/audit"
+)
+
+func TestServerAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Audit.FilePath = path
+		cfg.Audit.Key = "audit key"
+	})
+	server.Handle("/upload", func(w ResponseWriter, req *Request) {
+		w.WriteHeader(201)
+	}, PUT, DELETE)
+
+	send(t, server, PUT, "/upload", "twelve bytes", "Authorization: Bearer secret")
+	send(t, server, GET, "/index.html", "")
+	send(t, server, DELETE, "/upload", "")
+	send(t, server, POST, "/upload", "x")
+	server.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count, err := audit.Verify(bytes.NewReader(data), []byte("audit key")); err != nil || count != 3 {
+		t.Fatalf("Expected 3 valid entries, got %d and %v", count, err)
+	}
+
+	want := []audit.Entry{
+		{Method: "PUT", Path: "/upload", Principal: "bearer-token", RemoteIP: "pipe", Size: 12, Status: 201},
+		{Method: "DELETE", Path: "/upload", RemoteIP: "pipe", Status: 201},
+		{Method: "POST", Path: "/upload", RemoteIP: "pipe", Size: 1, Status: 405},
+	}
+	for i, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var got audit.Entry
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.RequestID == "" || got.Time.IsZero() {
+			t.Errorf("Expected entry %d to have a request ID and a time, got %+v", i+1, got)
+		}
+		if got.Method != want[i].Method || got.Path != want[i].Path || got.Principal != want[i].Principal ||
+			got.RemoteIP != want[i].RemoteIP || got.Size != want[i].Size || got.Status != want[i].Status {
+			t.Errorf("Expected entry %d to be %+v, got %+v", i+1, want[i], got)
+		}
+	}
+}
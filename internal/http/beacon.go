@@ -0,0 +1,98 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/awaisamjad/volk/internal/stats"
+)
+
+// maxBeaconPage is the longest page path a beacon may report.
+const maxBeaconPage = 1024
+
+// beaconHandler counts page views reported by pages, e.g. with
+// navigator.sendBeacon("/_beacon?page=" + location.pathname). The page and
+// its referrer come from the page and referrer fields of the query string, a
+// URL-encoded form or a JSON object; without a page, the Referer header is
+// the page. Neither cookies nor client addresses are recorded: only the page
+// path and the host of an external referrer are counted.
+func beaconHandler(collector *stats.Collector) ResponseFunc {
+	return func(req *Request) Response {
+		page, referrer, err := beaconFields(req)
+		if errors.Is(err, ErrUnsupportedMediaType) {
+			return newTextResponse(req.GetProtocol(), 415, "415 Unsupported Media Type")
+		}
+		if err != nil {
+			return newTextResponse(req.GetProtocol(), 400, "400 Bad Request: Invalid beacon")
+		}
+		if page == "" {
+			page, _ = GetHeader(req.Headers, "Referer")
+		}
+		page = beaconPage(page)
+		if page == "" || len(page) > maxBeaconPage {
+			return newTextResponse(req.GetProtocol(), 400, "400 Bad Request: Invalid beacon page")
+		}
+
+		host, _ := GetHeader(req.Headers, "Host")
+		collector.View(page, beaconReferrer(referrer, host))
+
+		resp := newTextResponse(req.GetProtocol(), 204, "")
+		resp.Headers = []Header{{Name: "Cache-Control", Value: "no-store"}}
+		return resp
+	}
+}
+
+// beaconFields returns the page and referrer a beacon reports.
+func beaconFields(req *Request) (page, referrer string, err error) {
+	contentType, _ := GetHeader(req.Headers, "Content-Type")
+	if mediaType, _, _ := mime.ParseMediaType(contentType); req.Body != "" && mediaType == "application/json" {
+		var fields struct {
+			Page     string `json:"page"`
+			Referrer string `json:"referrer"`
+		}
+		if err := json.Unmarshal([]byte(req.Body), &fields); err != nil {
+			return "", "", err
+		}
+		return fields.Page, fields.Referrer, nil
+	}
+
+	values, err := req.ParseForm()
+	if err != nil {
+		return "", "", err
+	}
+	return values.Get("page"), values.Get("referrer"), nil
+}
+
+// beaconPage returns the path of a reported page, which may be a path or an
+// absolute URL, without its query string and fragment, or "" if it is neither.
+func beaconPage(page string) string {
+	u, err := url.Parse(page)
+	if err != nil || !strings.HasPrefix(u.Path, "/") {
+		return ""
+	}
+	if u.Host == "" && u.Scheme != "" {
+		return ""
+	}
+	return u.Path
+}
+
+// beaconReferrer returns the lower-cased host of a reported referrer, or ""
+// if there is none or it is the site itself (host), so navigation within the
+// site is not counted as a referral.
+func beaconReferrer(referrer, host string) string {
+	u, err := url.Parse(referrer)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	if strings.EqualFold(u.Hostname(), host) {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+	"github.com/awaisamjad/volk/internal/stats"
+)
+
+func TestBeacon(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.jsonl")
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Stats = config.StatsConfig{Enabled: true, FilePath: path, Beacon: true, BeaconPath: "/_beacon"}
+	})
+
+	tests := []struct {
+		name     string
+		method   Method
+		path     string
+		body     string
+		headers  []string
+		wantCode StatusCode
+	}{
+		{"query", GET, "/_beacon?page=/&referrer=https://News.example.com/story", "", nil, 204},
+		{"form", POST, "/_beacon", "page=%2Fabout%3Futm_source%3Dfeed&referrer=https%3A%2F%2Flocalhost%2F", []string{"Content-Type: application/x-www-form-urlencoded"}, 204},
+		{"JSON", POST, "/_beacon", `{"page":"http://localhost/about#team","referrer":"https://news.example.com/"}`, []string{"Content-Type: application/json"}, 204},
+		{"Referer header", GET, "/_beacon", "", []string{"Referer: http://localhost/"}, 204},
+		{"no page", GET, "/_beacon", "", nil, 400},
+		{"relative page", GET, "/_beacon?page=about", "", nil, 400},
+		{"invalid JSON", POST, "/_beacon", `{"page":`, []string{"Content-Type: application/json"}, 400},
+		{"other body", POST, "/_beacon", "/", []string{"Content-Type: text/plain"}, 415},
+		{"method", PUT, "/_beacon", "", nil, 405},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := send(t, server, tt.method, tt.path, tt.body, tt.headers...)
+			if resp.GetStatusCode() != tt.wantCode {
+				t.Errorf("Expected status %d, got %d", tt.wantCode, resp.GetStatusCode())
+			}
+		})
+	}
+
+	if err := server.Stats.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := stats.Load(path, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedPages := []stats.Count{{Key: "/", Count: 2}, {Key: "/about", Count: 2}}
+	if pages := snapshot.TopPageViews(10); !slices.Equal(pages, expectedPages) {
+		t.Errorf("Expected page views %v, got %v", expectedPages, pages)
+	}
+	expectedReferrers := []stats.Count{{Key: "news.example.com", Count: 2}}
+	if referrers := snapshot.TopReferrers(10); !slices.Equal(referrers, expectedReferrers) {
+		t.Errorf("Expected referrers %v, got %v", expectedReferrers, referrers)
+	}
+}
+
+func TestBeaconRequiresStats(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Stats.Beacon = true
+	if _, err := NewServer(cfg); err == nil {
+		t.Error("Expected an error for beacons without statistics, got nil")
+	}
+}
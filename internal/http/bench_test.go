@@ -0,0 +1,166 @@
+package http
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// Benchmarks for the stages of the request path: parsing, routing, file
+// serving and serialization, and for the whole exchange over a connection.
+// Run them with `just bench`, which compares against a saved baseline.
+
+const benchRequest = "GET /index.html?page=2&sort=name HTTP/1.1\r\n" +
+	"Host: localhost\r\n" +
+	"User-Agent: Mozilla/5.0 (X11; Linux x86_64) volk-bench\r\n" +
+	"Accept: text/html,application/xhtml+xml\r\n" +
+	"Accept-Encoding: gzip, deflate\r\n" +
+	"Connection: close\r\n" +
+	"\r\n"
+
+// newBenchServer creates a Server serving a document root with a small and a
+// large file, with logging off.
+func newBenchServer(b *testing.B) *Server {
+	b.Helper()
+
+	log.SetOutput(io.Discard)
+	b.Cleanup(func() { log.SetOutput(os.Stderr) })
+
+	root := b.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "index.html"), bytes.Repeat([]byte("<p>volk</p>\n"), 100), 0644); err != nil {
+		b.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "large.bin"), bytes.Repeat([]byte{0xAB}, 1<<20), 0644); err != nil {
+		b.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.FileServer.DocumentRoot = root
+	cfg.Logging.AccessLogs = false
+	server, err := NewServer(cfg)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { server.Close() })
+	return server
+}
+
+func BenchmarkParseRequest(b *testing.B) {
+	b.ReportAllocs()
+	for b.Loop() {
+		if _, err := NewRequest(benchRequest); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRespond(b *testing.B) {
+	server := newBenchServer(b)
+	server.Handle("/api/", ResponseHandler(func(req *Request) Response {
+		return newTextResponse(req.GetProtocol(), 200, "ok")
+	}))
+
+	tests := []struct {
+		name string
+		path string
+	}{
+		{"file", "/index.html"},
+		{"large file", "/large.bin"},
+		{"handler", "/api/items"},
+		{"not found", "/missing.html"},
+	}
+
+	for _, tt := range tests {
+		b.Run(tt.name, func(b *testing.B) {
+			req, err := NewRequest("GET " + tt.path + " HTTP/1.1\r\nHost: localhost\r\n\r\n")
+			if err != nil {
+				b.Fatal(err)
+			}
+			b.ReportAllocs()
+			for b.Loop() {
+				server.respond(&req)
+			}
+		})
+	}
+}
+
+func BenchmarkResponseString(b *testing.B) {
+	server := newBenchServer(b)
+	req, err := NewRequest(benchRequest)
+	if err != nil {
+		b.Fatal(err)
+	}
+	resp := server.respond(&req)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(resp.Body)))
+	for b.Loop() {
+		_ = resp.String()
+	}
+}
+
+func BenchmarkExchange(b *testing.B) {
+	server := newBenchServer(b)
+
+	tests := []struct {
+		name    string
+		request string
+	}{
+		{"small file", benchRequest},
+		{"large file", "GET /large.bin HTTP/1.1\r\nHost: localhost\r\n\r\n"},
+	}
+
+	for _, tt := range tests {
+		b.Run(tt.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				exchange(server, []byte(tt.request))
+			}
+		})
+	}
+}
+
+func BenchmarkServeTCP(b *testing.B) {
+	for _, mode := range []string{"goroutine", "epoll"} {
+		b.Run(mode, func(b *testing.B) {
+			if mode == "epoll" && runtime.GOOS != "linux" {
+				b.Skip("epoll mode is only supported on Linux")
+			}
+			server := newBenchServer(b)
+			server.Config.Server.Mode = mode
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				b.Fatal(err)
+			}
+			go server.Serve(ln)
+			addr := ln.Addr().String()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				buf := make([]byte, 64<<10)
+				for pb.Next() {
+					conn, err := net.Dial("tcp", addr)
+					if err != nil {
+						b.Error(err)
+						return
+					}
+					conn.Write([]byte(benchRequest))
+					for {
+						if _, err := conn.Read(buf); err != nil {
+							break
+						}
+					}
+					conn.Close()
+				}
+			})
+		})
+	}
+}
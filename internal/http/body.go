@@ -0,0 +1,158 @@
+package http
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrBodyTooLarge              = errors.New("request body exceeds the maximum size")
+	ErrInvalidChunk              = errors.New("invalid chunk in chunked request body")
+	ErrUnsupportedTransferCoding = errors.New("unsupported transfer coding")
+)
+
+// readBody reads the request body announced by the Content-Length or
+// Transfer-Encoding: chunked header into req.Body, and the trailer fields of a
+// chunked body into req.Trailers. Bodies larger than limit bytes are rejected
+// with ErrBodyTooLarge; a limit of zero or less means no limit.
+func readBody(r *bufio.Reader, req *Request, limit int64) error {
+	if transferEncoding, ok := GetHeader(req.Headers, "Transfer-Encoding"); ok {
+		if !strings.EqualFold(strings.TrimSpace(transferEncoding), "chunked") {
+			return fmt.Errorf("%w: %s", ErrUnsupportedTransferCoding, transferEncoding)
+		}
+		body, trailers, err := readChunked(r, limit)
+		if err != nil {
+			return err
+		}
+		req.Body = body
+		req.Trailers = trailers
+		return nil
+	}
+
+	value, ok := GetHeader(req.Headers, "Content-Length")
+	if !ok {
+		return nil
+	}
+	// The value was validated by validateFraming.
+	length, _ := strconv.ParseInt(value, 10, 64)
+	if limit > 0 && length > limit {
+		return ErrBodyTooLarge
+	}
+
+	// The buffer grows with the bytes received rather than the length
+	// announced, which a client can set to anything without a limit.
+	var body strings.Builder
+	if _, err := io.CopyN(&body, r, length); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("error reading request body: %w", err)
+	}
+	req.Body = body.String()
+	return nil
+}
+
+// readChunked decodes a chunked body (RFC 9112 section 7.1) and returns it with
+// its trailer fields. Chunk extensions are discarded, and so are trailer fields
+// that must not be sent in a trailer, such as Content-Length or Host.
+// The trailer section counts towards limit.
+func readChunked(r *bufio.Reader, limit int64) (string, []Header, error) {
+	var body strings.Builder
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", nil, fmt.Errorf("error reading chunk size: %w", err)
+		}
+		sizeField, _, _ := strings.Cut(strings.TrimRight(line, "\r\n"), ";")
+		size, ok := parseChunkSize(strings.TrimRight(sizeField, " \t"))
+		if !ok {
+			return "", nil, ErrInvalidChunk
+		}
+
+		if size == 0 {
+			trailers, err := readTrailers(r, limit, int64(body.Len()))
+			if err != nil {
+				return "", nil, err
+			}
+			return body.String(), trailers, nil
+		}
+
+		if limit > 0 && int64(body.Len())+size > limit {
+			return "", nil, ErrBodyTooLarge
+		}
+		if _, err := io.CopyN(&body, r, size); err != nil {
+			return "", nil, fmt.Errorf("error reading chunk: %w", err)
+		}
+		if line, err := r.ReadString('\n'); err != nil || (line != CRLF && line != "\n") {
+			return "", nil, ErrInvalidChunk
+		}
+	}
+}
+
+// parseChunkSize parses the size of a chunk, which is hex digits only
+// (RFC 9112 section 7.1). Signs, prefixes and leading whitespace, which
+// strconv would accept and another server in front might read differently,
+// are rejected, as are sizes that do not fit in an int64.
+func parseChunkSize(field string) (int64, bool) {
+	if field == "" || len(field) > 15 {
+		return 0, false
+	}
+	for i := 0; i < len(field); i++ {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", rune(field[i])) {
+			return 0, false
+		}
+	}
+	size, err := strconv.ParseInt(field, 16, 64)
+	return size, err == nil
+}
+
+// forbiddenTrailers are fields that frame, route or authenticate a message or
+// describe its content, which a sender must not put in a trailer
+// (RFC 9110 section 6.5.1).
+var forbiddenTrailers = map[string]bool{
+	"authorization":     true,
+	"cache-control":     true,
+	"content-encoding":  true,
+	"content-length":    true,
+	"content-range":     true,
+	"content-type":      true,
+	"expect":            true,
+	"host":              true,
+	"max-forwards":      true,
+	"set-cookie":        true,
+	"te":                true,
+	"trailer":           true,
+	"transfer-encoding": true,
+}
+
+// readTrailers reads the trailer section of a chunked body up to the final
+// empty line. The section is rejected with ErrBodyTooLarge when it takes the
+// size of the message past limit, with read bytes of body already read.
+func readTrailers(r *bufio.Reader, limit, read int64) ([]Header, error) {
+	var trailers []Header
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("error reading trailer: %w", err)
+		}
+		if line == CRLF || line == "\n" {
+			return trailers, nil
+		}
+		read += int64(len(line))
+		if limit > 0 && read > limit {
+			return nil, ErrBodyTooLarge
+		}
+
+		trailer, err := parseHeader(strings.TrimRight(line, "\r\n"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid trailer: %v", err)
+		}
+		if !forbiddenTrailers[strings.ToLower(trailer.Name)] {
+			trailers = append(trailers, trailer)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReadBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  []Header
+		input    string
+		limit    int64
+		expected string
+		err      error
+	}{
+		{"no body", nil, "ignored", 0, "", nil},
+		{"Content-Length", []Header{{Name: "Content-Length", Value: "5"}}, "hello world", 0, "hello", nil},
+		{"chunked", []Header{{Name: "Transfer-Encoding", Value: "chunked"}}, "5\r\nhello\r\n6;ext=1\r\n world\r\n0\r\nX-Trailer: 1\r\n\r\n", 0, "hello world", nil},
+		{"Content-Length over limit", []Header{{Name: "Content-Length", Value: "11"}}, "hello world", 10, "", ErrBodyTooLarge},
+		{"chunked over limit", []Header{{Name: "Transfer-Encoding", Value: "chunked"}}, "5\r\nhello\r\n6\r\n world\r\n0\r\n\r\n", 10, "", ErrBodyTooLarge},
+		{"invalid chunk size", []Header{{Name: "Transfer-Encoding", Value: "chunked"}}, "zz\r\nhello\r\n0\r\n\r\n", 0, "", ErrInvalidChunk},
+		{"signed chunk size", []Header{{Name: "Transfer-Encoding", Value: "chunked"}}, "+5\r\nhello\r\n0\r\n\r\n", 0, "", ErrInvalidChunk},
+		{"negative zero chunk size", []Header{{Name: "Transfer-Encoding", Value: "chunked"}}, "5\r\nhello\r\n-0\r\n\r\n", 0, "", ErrInvalidChunk},
+		{"prefixed chunk size", []Header{{Name: "Transfer-Encoding", Value: "chunked"}}, "0x5\r\nhello\r\n0\r\n\r\n", 0, "", ErrInvalidChunk},
+		{"leading space in chunk size", []Header{{Name: "Transfer-Encoding", Value: "chunked"}}, " 5\r\nhello\r\n0\r\n\r\n", 0, "", ErrInvalidChunk},
+		{"oversized chunk size", []Header{{Name: "Transfer-Encoding", Value: "chunked"}}, "ffffffffffffffff\r\nhello\r\n0\r\n\r\n", 0, "", ErrInvalidChunk},
+		{"space before extension", []Header{{Name: "Transfer-Encoding", Value: "chunked"}}, "5 ;ext\r\nhello\r\n0\r\n\r\n", 0, "hello", nil},
+		{"huge Content-Length without limit", []Header{{Name: "Content-Length", Value: "9223372036854775807"}}, "hello", 0, "", io.ErrUnexpectedEOF},
+		{"missing chunk terminator", []Header{{Name: "Transfer-Encoding", Value: "chunked"}}, "5\r\nhelloXX0\r\n\r\n", 0, "", ErrInvalidChunk},
+		{"unsupported coding", []Header{{Name: "Transfer-Encoding", Value: "gzip"}}, "", 0, "", ErrUnsupportedTransferCoding},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := Request{Headers: tt.headers}
+			err := readBody(bufio.NewReader(strings.NewReader(tt.input)), &req, tt.limit)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if req.Body != tt.expected {
+				t.Errorf("Expected body %q, got %q", tt.expected, req.Body)
+			}
+		})
+	}
+}
+
+func TestReadBodyTrailers(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		limit    int64
+		expected []Header
+		err      error
+	}{
+		{"no trailers", "5\r\nhello\r\n0\r\n\r\n", 0, nil, nil},
+		{"trailers", "5\r\nhello\r\n0\r\nDigest: sha-256=abc\r\nX-Checksum: 42\r\n\r\n", 0, []Header{{Name: "Digest", Value: "sha-256=abc"}, {Name: "X-Checksum", Value: "42"}}, nil},
+		{"forbidden fields dropped", "5\r\nhello\r\n0\r\nContent-Length: 5\r\nHost: evil\r\nX-Checksum: 42\r\n\r\n", 0, []Header{{Name: "X-Checksum", Value: "42"}}, nil},
+		{"trailers over limit", "5\r\nhello\r\n0\r\nX-Checksum: 42\r\n\r\n", 10, nil, ErrBodyTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := Request{Headers: []Header{{Name: "Transfer-Encoding", Value: "chunked"}}}
+			err := readBody(bufio.NewReader(strings.NewReader(tt.input)), &req, tt.limit)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if len(req.Trailers) != len(tt.expected) {
+				t.Fatalf("Expected trailers %v, got %v", tt.expected, req.Trailers)
+			}
+			for i, trailer := range tt.expected {
+				if req.Trailers[i] != trailer {
+					t.Errorf("Expected trailer %v, got %v", trailer, req.Trailers[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,168 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"mime"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// compressedTypes are content types whose bodies are compressed already, or
+// are unknown, so gzip would spend time without making them smaller. They are
+// skipped whatever the compression settings allow.
+var compressedTypes = []string{
+	"image/*", "video/*", "audio/*",
+	"font/woff", "font/woff2",
+	"application/gzip", "application/x-gzip", "application/zip", "application/zstd",
+	"application/x-bzip2", "application/x-xz", "application/x-7z-compressed", "application/x-rar-compressed",
+	"application/pdf", "application/octet-stream",
+}
+
+// compressibleImages are the image types that are text or uncompressed, and
+// so not covered by image/* in compressedTypes.
+var compressibleImages = []string{"image/svg+xml", "image/bmp", "image/x-icon", "image/vnd.microsoft.icon"}
+
+// compressedMagic are the first bytes of compressed formats, which give away
+// a compressed body sent under another type, such as text/plain.
+var compressedMagic = [][]byte{
+	{0x1f, 0x8b},                       // gzip
+	{'P', 'K', 0x03, 0x04},             // zip, and formats built on it
+	{0x28, 0xb5, 0x2f, 0xfd},           // zstd
+	{0xfd, '7', 'z', 'X', 'Z', 0x00},   // xz
+	{'B', 'Z', 'h'},                    // bzip2
+	{'7', 'z', 0xbc, 0xaf, 0x27, 0x1c}, // 7z
+	{0x89, 'P', 'N', 'G'},              // PNG
+	{0xff, 0xd8, 0xff},                 // JPEG
+	{'%', 'P', 'D', 'F'},               // PDF
+	{'w', 'O', 'F', '2'},               // WOFF2
+}
+
+// compress returns resp with its body compressed with gzip, if the request
+// accepts it and the response is worth compressing, following [compression]:
+//
+//   - The response has a body of at least min_size bytes, no Content-Encoding
+//     and no Cache-Control: no-transform, and is not a 206.
+//   - Its content type matches types and neither exclude_types nor the types
+//     that are compressed already, and the body does not start like a
+//     compressed format.
+//   - The location of the request does not set disable_compression.
+//
+// Every response that could be compressed carries Vary: Accept-Encoding. A
+// strong ETag is made weak, as the compressed bytes differ from the
+// representation it names. A body that gzip does not make smaller is sent
+// as it is.
+func (s *Server) compress(req *Request, resp Response) Response {
+	cfg := s.Config.Compression
+	status := resp.GetStatusCode()
+	if !cfg.Enabled || status < 200 || status == 204 || status == 206 || status == 304 || len(resp.Body) < cfg.MinSize {
+		return resp
+	}
+	if _, ok := GetHeader(resp.Headers, "Content-Encoding"); ok {
+		return resp
+	}
+	if cacheControl, _ := GetHeader(resp.Headers, "Cache-Control"); strings.Contains(strings.ToLower(cacheControl), "no-transform") {
+		return resp
+	}
+	if location, ok := s.Config.Location(req.GetRequestTarget().Path); ok && location.DisableCompression {
+		return resp
+	}
+	contentType, _ := GetHeader(resp.Headers, "Content-Type")
+	if !compressibleType(contentType, cfg.Types, cfg.ExcludeTypes) || compressedBody(resp.Body) {
+		return resp
+	}
+
+	req.Vary("Accept-Encoding")
+	accept, _ := GetHeader(req.Headers, "Accept-Encoding")
+	if !acceptsGzip(accept) {
+		return resp
+	}
+
+	level := cfg.Level
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, level)
+	zw.Write([]byte(resp.Body))
+	zw.Close()
+	if buf.Len() >= len(resp.Body) {
+		return resp
+	}
+
+	resp.Body = buf.String()
+	resp.Headers = setHeader(resp.Headers, "Content-Encoding", "gzip")
+	if _, ok := GetHeader(resp.Headers, "Content-Length"); ok || req.GetMethod() == HEAD {
+		resp.Headers = setHeader(resp.Headers, "Content-Length", strconv.Itoa(buf.Len()))
+	}
+	if etag, ok := GetHeader(resp.Headers, "ETag"); ok && !strings.HasPrefix(etag, "W/") {
+		resp.Headers = setHeader(resp.Headers, "ETag", "W/"+etag)
+	}
+	return resp
+}
+
+// compressibleType reports whether a response of the content type is
+// compressed: it matches one of types, or types is empty, and it matches
+// neither exclude nor the types that are compressed already. Patterns are
+// media types without parameters, and * matches any type or subtype, as in
+// text/* or application/*+json.
+func compressibleType(contentType string, types, exclude []string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	if matchesType(mediaType, exclude) {
+		return false
+	}
+	if matchesType(mediaType, compressedTypes) && !matchesType(mediaType, compressibleImages) {
+		return false
+	}
+	return len(types) == 0 || matchesType(mediaType, types)
+}
+
+// matchesType reports whether the lower-case media type matches one of the
+// patterns.
+func matchesType(mediaType string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), mediaType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// compressedBody reports whether body starts like a compressed format.
+func compressedBody(body string) bool {
+	for _, magic := range compressedMagic {
+		if strings.HasPrefix(body, string(magic)) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsGzip reports whether an Accept-Encoding header accepts gzip, by name
+// or through *, with a quality above zero.
+func acceptsGzip(accept string) bool {
+	gzipQ, anyQ := -1.0, -1.0
+	for _, part := range strings.Split(accept, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if name, value, ok := strings.Cut(strings.TrimSpace(params), "="); ok && strings.EqualFold(strings.TrimSpace(name), "q") {
+			if parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64); err == nil {
+				q = parsed
+			}
+		}
+		switch strings.ToLower(strings.TrimSpace(coding)) {
+		case "gzip", "x-gzip":
+			gzipQ = q
+		case "*":
+			anyQ = q
+		}
+	}
+	if gzipQ >= 0 {
+		return gzipQ > 0
+	}
+	return anyQ > 0
+}
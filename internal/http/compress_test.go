@@ -0,0 +1,166 @@
+package http
+
+import (
+	"compress/gzip"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	tests := []struct {
+		accept   string
+		expected bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"GZIP", true},
+		{"deflate, gzip;q=0.5, br", true},
+		{"gzip;q=0", false},
+		{"gzip; q=0.0", false},
+		{"*", true},
+		{"*;q=0", false},
+		{"gzip;q=0, *", false},
+		{"identity", false},
+		{"x-gzip", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.accept, func(t *testing.T) {
+			if result := acceptsGzip(tt.accept); result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestCompressibleType(t *testing.T) {
+	types := config.DefaultConfig().Compression.Types
+	tests := []struct {
+		contentType string
+		exclude     []string
+		expected    bool
+	}{
+		{"text/html; charset=utf-8", nil, true},
+		{"TEXT/CSS", nil, true},
+		{"application/json", nil, true},
+		{"application/ld+json", nil, true},
+		{"application/atom+xml", nil, true},
+		{"image/svg+xml", nil, true},
+		{"image/png", nil, false},
+		{"video/mp4", nil, false},
+		{"application/zip", nil, false},
+		{"application/octet-stream", nil, false},
+		{"font/woff2", nil, false},
+		{"text/event-stream", []string{"text/event-stream"}, false},
+		{"text/csv", []string{"text/*"}, false},
+		{"", nil, false},
+		{"not a type", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			if result := compressibleType(tt.contentType, types, tt.exclude); result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+
+	if !compressibleType("application/x-custom", nil, nil) {
+		t.Error("Expected every type not excluded to be compressed without types")
+	}
+	if compressibleType("image/jpeg", []string{"image/*"}, nil) {
+		t.Error("Expected compressed types to be skipped even when allowed")
+	}
+}
+
+// gunzip returns the decompressed body.
+func gunzip(t *testing.T, body string) string {
+	t.Helper()
+	zr, err := gzip.NewReader(strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("Expected a gzip body: %v", err)
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestServerCompression(t *testing.T) {
+	text := strings.Repeat("volk compresses text responses. ", 100) + "The end."
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Compression.Enabled = true
+		cfg.Locations = []config.LocationConfig{{Path: "/secret/", DisableCompression: true}}
+	})
+	respond := func(contentType, body string, headers ...Header) ResponseFunc {
+		return func(req *Request) Response {
+			resp := newTextResponse(req.GetProtocol(), 200, body)
+			resp.Headers = append([]Header{{Name: "Content-Type", Value: contentType}, {Name: "Content-Length", Value: strconv.Itoa(len(body))}}, headers...)
+			return resp
+		}
+	}
+	server.Handle("/text", ResponseHandler(respond("text/plain", text, Header{Name: "ETag", Value: `"v1"`})))
+	server.Handle("/small", ResponseHandler(respond("text/plain", "short")))
+	server.Handle("/png", ResponseHandler(respond("image/png", text)))
+	server.Handle("/disguised", ResponseHandler(respond("text/plain", "\x1f\x8b"+text)))
+	server.Handle("/no-transform", ResponseHandler(respond("text/plain", text, Header{Name: "Cache-Control", Value: "no-transform"})))
+	server.Handle("/secret/token", ResponseHandler(respond("text/plain", text)))
+
+	gzipAccepted := "Accept-Encoding: gzip, br"
+	tests := []struct {
+		name       string
+		path       string
+		headers    []string
+		wantGzip   bool
+		wantVary   bool
+		wantWeakly bool
+	}{
+		{"compressed", "/text", []string{gzipAccepted}, true, true, true},
+		{"not accepted", "/text", nil, false, true, false},
+		{"refused", "/text", []string{"Accept-Encoding: gzip;q=0"}, false, true, false},
+		{"too small", "/small", []string{gzipAccepted}, false, false, false},
+		{"compressed type", "/png", []string{gzipAccepted}, false, false, false},
+		{"compressed body", "/disguised", []string{gzipAccepted}, false, false, false},
+		{"no-transform", "/no-transform", []string{gzipAccepted}, false, false, false},
+		{"disabled location", "/secret/token", []string{gzipAccepted}, false, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := get(t, server, tt.path, tt.headers...)
+			encoding, _ := GetHeader(resp.Headers, "Content-Encoding")
+			if (encoding == "gzip") != tt.wantGzip {
+				t.Fatalf("Expected gzip %v, got Content-Encoding %q", tt.wantGzip, encoding)
+			}
+			vary, _ := GetHeader(resp.Headers, "Vary")
+			if strings.Contains(vary, "Accept-Encoding") != tt.wantVary {
+				t.Errorf("Expected Vary with Accept-Encoding %v, got %q", tt.wantVary, vary)
+			}
+			if length, _ := GetHeader(resp.Headers, "Content-Length"); length != strconv.Itoa(len(resp.GetBody())) {
+				t.Errorf("Expected Content-Length %d, got %s", len(resp.GetBody()), length)
+			}
+			if !tt.wantGzip {
+				return
+			}
+			if body := gunzip(t, resp.GetBody()); body != text {
+				t.Errorf("Expected the original body after decompressing, got %d bytes", len(body))
+			}
+			if etag, _ := GetHeader(resp.Headers, "ETag"); tt.wantWeakly && etag != `W/"v1"` {
+				t.Errorf(`Expected ETag W/"v1", got %q`, etag)
+			}
+		})
+	}
+}
+
+func TestCompressionLevel(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Compression.Level = 10
+	if _, err := NewServer(cfg); err == nil {
+		t.Error("Expected an error for compression level 10, got nil")
+	}
+}
@@ -0,0 +1,104 @@
+package http
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/awaisamjad/volk/internal/httpdate"
+)
+
+// EvaluatePreconditions evaluates the conditional headers of req against the
+// current validators of the target resource, in the order of RFC 9110
+// section 13.2.2: If-Match, If-Unmodified-Since, If-None-Match and
+// If-Modified-Since. etag is the entity tag including its quotes and W/
+// prefix, and is empty if the resource has none or does not exist; modTime
+// is zero if the modification time is unknown.
+//
+// It returns 304 Not Modified for a GET or HEAD request that can reuse the
+// client's copy, 412 Precondition Failed for a request whose precondition
+// does not hold, or 0 if the request should be handled normally.
+func EvaluatePreconditions(req *Request, etag string, modTime time.Time) StatusCode {
+	safe := req.GetMethod() == GET || req.GetMethod() == HEAD
+	modTime = modTime.Truncate(time.Second)
+
+	if ifMatch, ok := GetHeader(req.Headers, "If-Match"); ok {
+		if !matchETag(ifMatch, etag, false) {
+			return 412
+		}
+	} else if since, ok := headerTime(req, "If-Unmodified-Since"); ok && !modTime.IsZero() {
+		if modTime.After(since) {
+			return 412
+		}
+	}
+
+	if ifNoneMatch, ok := GetHeader(req.Headers, "If-None-Match"); ok {
+		if matchETag(ifNoneMatch, etag, true) {
+			if safe {
+				return 304
+			}
+			return 412
+		}
+	} else if since, ok := headerTime(req, "If-Modified-Since"); ok && safe && !modTime.IsZero() {
+		if !modTime.After(since) {
+			return 304
+		}
+	}
+
+	return 0
+}
+
+// PreconditionResponse creates the response for a status returned by
+// EvaluatePreconditions. A 304 response carries the validators, so the
+// client can update its stored copy.
+func PreconditionResponse(req *Request, status StatusCode, etag string, modTime time.Time) Response {
+	if status != 304 {
+		return newTextResponse(req.GetProtocol(), status, fmt.Sprintf("%d %s", status, StatusCodeMap[status]))
+	}
+	resp := newTextResponse(req.GetProtocol(), 304, "")
+	resp.Headers = nil
+	if etag != "" {
+		resp.Headers = append(resp.Headers, Header{Name: "ETag", Value: etag})
+	}
+	if !modTime.IsZero() {
+		resp.Headers = append(resp.Headers, Header{Name: "Last-Modified", Value: httpdate.Format(modTime)})
+	}
+	return resp
+}
+
+// matchETag reports whether a list of entity tags, or "*", matches etag.
+// If-Match compares strongly, so weak tags never match; If-None-Match
+// compares weakly, ignoring the W/ prefix.
+func matchETag(list, etag string, weak bool) bool {
+	if etag == "" {
+		return false
+	}
+	if strings.TrimSpace(list) == "*" {
+		return true
+	}
+	if !weak && strings.HasPrefix(etag, "W/") {
+		return false
+	}
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		} else if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// headerTime parses a date header of req, in any of the HTTP date formats.
+// Invalid dates are ignored, as RFC 9110 requires for conditional headers.
+func headerTime(req *Request, name string) (time.Time, bool) {
+	value, ok := GetHeader(req.Headers, name)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := httpdate.Parse(value)
+	return t, err == nil
+}
@@ -0,0 +1,74 @@
+package http
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEvaluatePreconditions(t *testing.T) {
+	modTime := time.Date(2025, 6, 1, 12, 0, 0, 500, time.UTC)
+	before := "Sat, 31 May 2025 12:00:00 GMT"
+	at := "Sun, 01 Jun 2025 12:00:00 GMT"
+	etag := `"v2"`
+
+	tests := []struct {
+		name    string
+		method  Method
+		headers string
+		etag    string
+		want    StatusCode
+	}{
+		{"no conditions", GET, "", etag, 0},
+		{"If-None-Match matches", GET, `If-None-Match: "v1", "v2"`, etag, 304},
+		{"If-None-Match matches weakly", HEAD, `If-None-Match: W/"v2"`, etag, 304},
+		{"If-None-Match differs", GET, `If-None-Match: "v1"`, etag, 0},
+		{"If-None-Match on PUT", PUT, `If-None-Match: *`, etag, 412},
+		{"If-None-Match * for a new resource", PUT, `If-None-Match: *`, "", 0},
+		{"If-Match matches", PUT, `If-Match: "v2"`, etag, 0},
+		{"If-Match differs", PUT, `If-Match: "v1"`, etag, 412},
+		{"If-Match is strong", PUT, `If-Match: W/"v2"`, `W/"v2"`, 412},
+		{"If-Match * without the resource", DELETE, `If-Match: *`, "", 412},
+		{"If-Modified-Since unchanged", GET, "If-Modified-Since: " + at, "", 304},
+		{"If-Modified-Since changed", GET, "If-Modified-Since: " + before, "", 0},
+		{"If-Modified-Since ignored with If-None-Match", GET, "If-None-Match: \"v1\"\r\nIf-Modified-Since: " + at, etag, 0},
+		{"If-Modified-Since on POST", POST, "If-Modified-Since: " + at, "", 0},
+		{"If-Modified-Since in RFC 850 format", GET, "If-Modified-Since: Sunday, 01-Jun-25 12:00:00 GMT", "", 304},
+		{"If-Modified-Since in asctime format", GET, "If-Modified-Since: Sun Jun  1 12:00:00 2025", "", 304},
+		{"If-Modified-Since invalid", GET, "If-Modified-Since: yesterday", "", 0},
+		{"If-Unmodified-Since holds", PUT, "If-Unmodified-Since: " + at, "", 0},
+		{"If-Unmodified-Since fails", PUT, "If-Unmodified-Since: " + before, "", 412},
+		{"If-Unmodified-Since ignored with If-Match", PUT, "If-Match: \"v2\"\r\nIf-Unmodified-Since: " + before, etag, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := string(tt.method) + " /doc HTTP/1.1\r\nHost: localhost\r\n"
+			if tt.headers != "" {
+				raw += tt.headers + "\r\n"
+			}
+			req, err := NewRequest(raw + "\r\n")
+			if err != nil {
+				t.Fatalf("could not parse request: %v", err)
+			}
+			if got := EvaluatePreconditions(&req, tt.etag, modTime); got != tt.want {
+				t.Errorf("Expected status %d, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestPreconditionResponse(t *testing.T) {
+	req, _ := NewRequest("GET /doc HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	modTime := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	resp := PreconditionResponse(&req, 304, `"v2"`, modTime)
+	if resp.GetStatusCode() != 304 || resp.GetBody() != "" {
+		t.Errorf("Expected an empty 304, got %d with %q", resp.GetStatusCode(), resp.GetBody())
+	}
+	if etag, _ := GetHeader(resp.Headers, "ETag"); etag != `"v2"` {
+		t.Errorf("Expected ETag \"v2\", got %q", etag)
+	}
+	if lastModified, _ := GetHeader(resp.Headers, "Last-Modified"); lastModified != "Sun, 01 Jun 2025 12:00:00 GMT" {
+		t.Errorf("Expected Last-Modified, got %q", lastModified)
+	}
+}
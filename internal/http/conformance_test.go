@@ -0,0 +1,99 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+var update = flag.Bool("update", false, "update the golden files")
+
+// TestConformance replays the raw requests in testdata/conformance/cases through
+// a Server and compares the bytes written back with the golden .response files.
+// Run `go test -run TestConformance -update` to regenerate the golden files.
+func TestConformance(t *testing.T) {
+	requests, err := filepath.Glob(filepath.Join("testdata", "conformance", "cases", "*.request"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) == 0 {
+		t.Fatal("no conformance cases found")
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.FileServer.DocumentRoot = filepath.Join("testdata", "conformance", "root")
+	cfg.Logging.AccessLogs = false
+	server, err := NewServer(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	for _, requestFile := range requests {
+		name := strings.TrimSuffix(filepath.Base(requestFile), ".request")
+		t.Run(name, func(t *testing.T) {
+			request, err := os.ReadFile(requestFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := exchange(server, request)
+
+			goldenFile := strings.TrimSuffix(requestFile, ".request") + ".response"
+			if *update {
+				if err := os.WriteFile(goldenFile, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			expected, err := os.ReadFile(goldenFile)
+			if err != nil {
+				t.Fatalf("missing golden file, run with -update: %v", err)
+			}
+			if !bytes.Equal(got, expected) {
+				t.Errorf("response mismatch.\nExpected: %q\nGot: %q", expected, got)
+			}
+		})
+	}
+}
+
+// exchange writes the raw request to a connection handled by the server and
+// returns everything the server writes back before closing it.
+func exchange(server *Server, request []byte) []byte {
+	clientConn, serverConn := net.Pipe()
+	go server.handleConnection(serverConn)
+
+	go func() {
+		clientConn.Write(request)
+	}()
+
+	response, _ := io.ReadAll(clientConn)
+	clientConn.Close()
+	return response
+}
+
+// exchangeResponse is exchange, parsing the response and decoding a chunked body.
+func exchangeResponse(server *Server, request []byte) (Response, error) {
+	response := string(exchange(server, request))
+	head, body, ok := strings.Cut(response, HeaderBodySeparator)
+	if ok && strings.Contains(head, "\r\nTransfer-Encoding: chunked\r\n") {
+		decoded, _, err := readChunked(bufio.NewReader(strings.NewReader(body)), 0)
+		if err != nil {
+			return Response{}, err
+		}
+		response = head + HeaderBodySeparator + decoded
+	}
+	return NewResponse(response)
+}
@@ -0,0 +1,107 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ipLimiter counts the open connections of each client address.
+type ipLimiter struct {
+	max int
+
+	mu    sync.Mutex
+	conns map[string]int
+}
+
+// newIPLimiter creates a limiter allowing max connections per client.
+func newIPLimiter(max int) *ipLimiter {
+	return &ipLimiter{max: max, conns: make(map[string]int)}
+}
+
+// acquire counts a connection from key and reports whether it is within the limit.
+func (l *ipLimiter) acquire(key string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conns[key] >= l.max {
+		return false
+	}
+	l.conns[key]++
+	return true
+}
+
+// release uncounts a connection from key.
+func (l *ipLimiter) release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.conns[key] <= 1 {
+		delete(l.conns, key)
+		return
+	}
+	l.conns[key]--
+}
+
+// clientKey returns the key a connection is counted under: its IP address, or
+// for IPv6 its /64 network, which a single host can draw addresses from freely.
+func clientKey(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return host
+	}
+	if ip.To4() == nil {
+		return ip.Mask(net.CIDRMask(64, 128)).String() + "/64"
+	}
+	return ip.String()
+}
+
+// limitedConn is a connection counted by an ipLimiter until it is closed.
+type limitedConn struct {
+	net.Conn
+	release func()
+	once    sync.Once
+}
+
+func (c *limitedConn) Close() error {
+	c.once.Do(c.release)
+	return c.Conn.Close()
+}
+
+// SyscallConn returns the raw connection, for epoll mode.
+func (c *limitedConn) SyscallConn() (syscall.RawConn, error) {
+	sc, ok := c.Conn.(syscall.Conn)
+	if !ok {
+		return nil, errors.New("connection has no file descriptor")
+	}
+	return sc.SyscallConn()
+}
+
+// limitConn applies server.max_connections_per_ip to an accepted connection.
+// A connection within the limit is returned wrapped so that closing it frees
+// its slot. An excess connection gets a 429 response and is closed, and
+// limitConn reports false.
+func (s *Server) limitConn(conn net.Conn) (net.Conn, bool) {
+	if s.connLimit == nil {
+		return conn, true
+	}
+	key := clientKey(conn.RemoteAddr().String())
+	if !s.connLimit.acquire(key) {
+		if s.Config.Logging.AccessLogs {
+			log.Printf("Access: %s - connection limit reached", s.logIP(key))
+		}
+		resp := newTextResponse(HTTP1_1, 429, "429 Too Many Requests")
+		resp.Headers = append(resp.Headers, Header{Name: "Retry-After", Value: "1"}, Header{Name: "Connection", Value: "close"})
+		// The request is not read, so a client that does not read either cannot hold the accept loop.
+		conn.SetWriteDeadline(time.Now().Add(time.Second))
+		writeResponse(conn, resp)
+		conn.Close()
+		return nil, false
+	}
+	return &limitedConn{Conn: conn, release: func() { s.connLimit.release(key) }}, true
+}
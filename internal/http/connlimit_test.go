@@ -0,0 +1,89 @@
+package http
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestClientKey(t *testing.T) {
+	tests := []struct {
+		addr     string
+		expected string
+	}{
+		{"192.0.2.1:1234", "192.0.2.1"},
+		{"[2001:db8:1:2:3:4:5:6]:443", "2001:db8:1:2::/64"},
+		{"[2001:db8:1:2:ffff::1]:443", "2001:db8:1:2::/64"},
+		{"[::ffff:192.0.2.1]:80", "192.0.2.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			if got := clientKey(tt.addr); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMaxConnectionsPerIP(t *testing.T) {
+	for _, mode := range []string{"goroutine", "epoll"} {
+		t.Run(mode, func(t *testing.T) {
+			if mode == "epoll" && runtime.GOOS != "linux" {
+				t.Skip("epoll mode is only supported on Linux")
+			}
+			server := newTestServer(t, func(cfg *config.Config) {
+				cfg.Server.Mode = mode
+				cfg.Server.MaxConnectionsPerIP = 1
+			})
+			addr := serveTest(t, server)
+
+			// The first connection holds the only slot while it sends nothing.
+			idle, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer idle.Close()
+			time.Sleep(50 * time.Millisecond)
+
+			if status := statusOf(t, addr); status != "HTTP/1.1 429 Too Many Requests" {
+				t.Errorf("Expected a 429 for the second connection, got %q", status)
+			}
+
+			// Closing the first connection frees its slot.
+			idle.Close()
+			deadline := time.Now().Add(2 * time.Second)
+			status := statusOf(t, addr)
+			for status != "HTTP/1.1 200 OK" && time.Now().Before(deadline) {
+				time.Sleep(20 * time.Millisecond)
+				status = statusOf(t, addr)
+			}
+			if status != "HTTP/1.1 200 OK" {
+				t.Errorf("Expected a 200 after the first connection closed, got %q", status)
+			}
+		})
+	}
+}
+
+// statusOf requests /index.html from addr and returns the status line of the response.
+func statusOf(t *testing.T, addr string) string {
+	t.Helper()
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	conn.Write([]byte("GET /index.html HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil && err != io.EOF {
+		t.Fatalf("could not read the status line: %v", err)
+	}
+	return strings.TrimRight(line, "\r\n")
+}
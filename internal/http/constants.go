@@ -1,6 +1,8 @@
 // Package http implements a simple HTTP server and related utilities.
 package http
 
+import "github.com/awaisamjad/volk/internal/httpdate"
+
 // CRLF is the standard HTTP line ending
 const CRLF = "\r\n"
 
@@ -10,6 +12,10 @@ const HeaderBodySeparator = CRLF + CRLF
 // HeaderSeparator is the separator between HTTP header name and value
 const HeaderSeparator = ": "
 
+// TimeFormat is the format of dates in HTTP headers such as Last-Modified.
+// Times must be in UTC; httpdate.Format converts them.
+const TimeFormat = httpdate.IMFFixdate
+
 // Method represents an HTTP method
 type Method string
 
@@ -31,6 +37,7 @@ type Protocol string
 
 // HTTP protocol versions
 const (
+	HTTP2   Protocol = "HTTP/2"
 	HTTP1_1 Protocol = "HTTP/1.1"
 	HTTP1_0 Protocol = "HTTP/1.0"
 	HTTP0_9 Protocol = "HTTP/0.9"
@@ -44,19 +51,29 @@ type StatusText string
 
 // StatusCodeMap maps status codes to their text representations
 var StatusCodeMap = map[StatusCode]StatusText{
+	101: "Switching Protocols",
 	200: "OK",
 	201: "Created",
+	202: "Accepted",
 	204: "No Content",
 	301: "Moved Permanently",
 	302: "Found",
+	303: "See Other",
 	304: "Not Modified",
 	400: "Bad Request",
 	401: "Unauthorized",
 	403: "Forbidden",
 	404: "Not Found",
 	405: "Method Not Allowed",
+	409: "Conflict",
+	412: "Precondition Failed",
+	413: "Payload Too Large",
+	415: "Unsupported Media Type",
+	422: "Unprocessable Content",
+	429: "Too Many Requests",
 	500: "Internal Server Error",
 	501: "Not Implemented",
 	502: "Bad Gateway",
 	503: "Service Unavailable",
+	507: "Insufficient Storage",
 }
@@ -0,0 +1,102 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Headers a client announces how long it waits for the response with.
+const (
+	RequestTimeoutHeader  = "Request-Timeout"
+	XRequestTimeoutHeader = "X-Request-Timeout"
+)
+
+// requestDeadline returns the deadline of a request that arrived at start:
+// server.handle_timeout after it, shortened to the time the client announced
+// with a Request-Timeout or X-Request-Timeout header if server.request_timeout_header
+// is set. The header can only shorten the configured timeout. The zero time
+// means no deadline.
+func (s *Server) requestDeadline(req *Request, start time.Time) time.Time {
+	timeout := time.Duration(s.Config.Server.HandleTimeout) * time.Second
+	if s.Config.Server.RequestTimeoutHeader {
+		for _, name := range []string{RequestTimeoutHeader, XRequestTimeoutHeader} {
+			value, ok := GetHeader(req.Headers, name)
+			if !ok {
+				continue
+			}
+			if requested, err := parseRequestTimeout(value); err == nil && (timeout == 0 || requested < timeout) {
+				timeout = requested
+			}
+			break
+		}
+	}
+	if timeout == 0 {
+		return time.Time{}
+	}
+	return start.Add(timeout)
+}
+
+// parseRequestTimeout parses the value of a request timeout header: a
+// number of milliseconds, or a number followed by ms or s, such as 1500,
+// 250ms or 2.5s. The timeout must be positive.
+func parseRequestTimeout(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	var d time.Duration
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		d = time.Duration(n) * time.Millisecond
+	} else if parsed, err := time.ParseDuration(value); err == nil && strings.HasSuffix(value, "s") {
+		d = parsed
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid request timeout %q", value)
+	}
+	return d, nil
+}
+
+// deadlineResponse returns the 503 response to a request whose deadline
+// passed before it was handled, such as one that waited long for its body.
+// Its client no longer waits for the answer.
+func deadlineResponse(req *Request) (Response, bool) {
+	if remaining, ok := req.Remaining(); !ok || remaining > 0 {
+		return Response{}, false
+	}
+	return newTextResponse(req.GetProtocol(), 503, "503 Service Unavailable: Request deadline exceeded"), true
+}
+
+// Remaining returns the time left until the deadline of the request, and
+// false if it has none. The time left is negative once the deadline passed.
+func (r *Request) Remaining() (time.Duration, bool) {
+	if r.Deadline.IsZero() {
+		return 0, false
+	}
+	return time.Until(r.Deadline), true
+}
+
+// TimeoutHeader returns the Request-Timeout header a handler sends to
+// the services it calls, carrying the rest of the request's budget in
+// milliseconds, and false if the request has no deadline.
+func (r *Request) TimeoutHeader() (Header, bool) {
+	remaining, ok := r.Remaining()
+	if !ok {
+		return Header{}, false
+	}
+	return Header{Name: RequestTimeoutHeader, Value: strconv.FormatInt(max(remaining.Milliseconds(), 1), 10)}, true
+}
+
+// Context returns a context that is done at the deadline of the request, for
+// handlers calling other services; requests the Server handles also end it
+// once they are answered. The cancel function must be called once the
+// context is no longer needed.
+func (r *Request) Context() (context.Context, context.CancelFunc) {
+	parent := r.ctx
+	if parent == nil {
+		parent = context.Background()
+	}
+	if r.Deadline.IsZero() {
+		return context.WithCancel(parent)
+	}
+	return context.WithDeadline(parent, r.Deadline)
+}
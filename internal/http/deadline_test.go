@@ -0,0 +1,159 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestParseRequestTimeout(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected time.Duration
+		valid    bool
+	}{
+		{"1500", 1500 * time.Millisecond, true},
+		{" 250ms ", 250 * time.Millisecond, true},
+		{"2.5s", 2500 * time.Millisecond, true},
+		{"0", 0, false},
+		{"-100", 0, false},
+		{"1h", 0, false},
+		{"soon", 0, false},
+		{"", 0, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.value, func(t *testing.T) {
+			got, err := parseRequestTimeout(test.value)
+			if test.valid && (err != nil || got != test.expected) {
+				t.Errorf("Expected %v, got %v (%v)", test.expected, got, err)
+			}
+			if !test.valid && err == nil {
+				t.Errorf("Expected an error, got %v", got)
+			}
+		})
+	}
+}
+
+func TestRequestDeadline(t *testing.T) {
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name          string
+		handleTimeout int
+		honorHeader   bool
+		headers       []Header
+		expected      time.Duration // 0 for no deadline
+	}{
+		{"None", 0, true, nil, 0},
+		{"Configured", 10, false, nil, 10 * time.Second},
+		{"Header ignored", 10, false, []Header{{Name: "Request-Timeout", Value: "500"}}, 10 * time.Second},
+		{"Header shortens", 10, true, []Header{{Name: "Request-Timeout", Value: "500"}}, 500 * time.Millisecond},
+		{"Header cannot extend", 10, true, []Header{{Name: "X-Request-Timeout", Value: "60s"}}, 10 * time.Second},
+		{"Header without timeout", 0, true, []Header{{Name: "X-Request-Timeout", Value: "2s"}}, 2 * time.Second},
+		{"Invalid header", 10, true, []Header{{Name: "Request-Timeout", Value: "never"}}, 10 * time.Second},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Server.HandleTimeout = test.handleTimeout
+			cfg.Server.RequestTimeoutHeader = test.honorHeader
+			s := &Server{Config: cfg}
+
+			got := s.requestDeadline(&Request{Headers: test.headers}, start)
+			if test.expected == 0 && !got.IsZero() {
+				t.Errorf("Expected no deadline, got %v", got)
+			}
+			if test.expected != 0 && !got.Equal(start.Add(test.expected)) {
+				t.Errorf("Expected deadline %v, got %v", start.Add(test.expected), got)
+			}
+		})
+	}
+}
+
+func TestRequestTimeoutHeader(t *testing.T) {
+	req := &Request{}
+	if _, ok := req.TimeoutHeader(); ok {
+		t.Errorf("Expected no header without a deadline")
+	}
+
+	req.Deadline = time.Now().Add(2 * time.Second)
+	header, ok := req.TimeoutHeader()
+	if !ok || header.Name != RequestTimeoutHeader {
+		t.Fatalf("Expected a %s header, got %+v", RequestTimeoutHeader, header)
+	}
+	if ms, err := strconv.Atoi(header.Value); err != nil || ms <= 1000 || ms > 2000 {
+		t.Errorf("Expected about 2000 milliseconds, got %s", header.Value)
+	}
+
+	ctx, cancel := req.Context()
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); !ok || !deadline.Equal(req.Deadline) {
+		t.Errorf("Expected the context deadline %v, got %v", req.Deadline, deadline)
+	}
+}
+
+func TestServerRequestTimeout(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Server.HandleTimeout = 30
+		cfg.Server.RequestTimeoutHeader = true
+	})
+	var remaining time.Duration
+	var ctxErr error
+	server.Handle("/budget", ResponseHandler(func(req *Request) Response {
+		remaining, _ = req.Remaining()
+		ctx, cancel := req.Context()
+		defer cancel()
+		ctxErr = ctx.Err()
+		return newTextResponse(req.GetProtocol(), 200, "ok")
+	}))
+
+	resp := get(t, server, "/budget", "Request-Timeout: 5s")
+	if resp.GetStatusCode() != 200 {
+		t.Fatalf("Expected status 200, got %d", resp.GetStatusCode())
+	}
+	if remaining <= 0 || remaining > 5*time.Second {
+		t.Errorf("Expected at most 5s left, got %v", remaining)
+	}
+	if ctxErr != nil {
+		t.Errorf("Expected the context to be live, got %v", ctxErr)
+	}
+
+	// A deadline that has passed by the time the request is handled is answered with a 503.
+	req := &Request{Deadline: time.Now().Add(-time.Millisecond), StartLine: RequestStartLine{Protocol: HTTP1_1}}
+	resp, expired := deadlineResponse(req)
+	if !expired || resp.GetStatusCode() != 503 {
+		t.Errorf("Expected a 503 for an expired deadline, got %d", resp.GetStatusCode())
+	}
+	ctx, cancel := req.Context()
+	defer cancel()
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Errorf("Expected context.DeadlineExceeded, got %v", ctx.Err())
+	}
+}
+
+func TestServerSlowHandlerDeadline(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Server.RequestTimeoutHeader = true
+	})
+	var ctx context.Context
+	server.Handle("/slow", ResponseHandler(func(req *Request) Response {
+		var cancel context.CancelFunc
+		ctx, cancel = req.Context()
+		defer cancel()
+		<-ctx.Done()
+		return newTextResponse(req.GetProtocol(), 200, "too late")
+	}))
+
+	resp := get(t, server, "/slow", "Request-Timeout: 50ms")
+	if resp.GetStatusCode() != 503 {
+		t.Errorf("Expected status 503 for a response finished after the deadline, got %d", resp.GetStatusCode())
+	}
+	if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		t.Errorf("Expected the handler's context to end at the deadline, got %v", ctx.Err())
+	}
+}
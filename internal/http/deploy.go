@@ -0,0 +1,90 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/awaisamjad/volk/internal/deploy"
+	"github.com/awaisamjad/volk/internal/scan"
+)
+
+// deployMethods are the methods the deploy endpoint accepts; its rollback
+// path accepts POST only.
+var deployMethods = []Method{GET, HEAD, PUT}
+
+// deployHandler serves the deploy endpoint at path. Every request needs the
+// token as a bearer token. It is registered with deployMethods, so the server
+// answers other methods. If scanner is not nil, archives are scanned before
+// they are extracted and infected ones are rejected with 422.
+//
+//	GET  path            list the releases and the current one
+//	PUT  path            deploy the tar.gz or zip archive in the body
+//	POST path/rollback   switch back to the previous release
+func deployHandler(deployer *deploy.Deployer, scanner scan.Scanner, path, token string) ResponseFunc {
+	return func(req *Request) Response {
+		if resp, ok := checkBearer(req, token, "deploy"); !ok {
+			return resp
+		}
+
+		if req.GetRequestTarget().Path == path+"/rollback" {
+			release, err := deployer.Rollback()
+			if err != nil {
+				return deployError(req, err)
+			}
+			body, _ := json.Marshal(map[string]string{"release": release})
+			return jsonResponse(req, 200, string(body))
+		}
+
+		switch req.GetMethod() {
+		case GET, HEAD:
+			releases, err := deployer.Releases()
+			if err != nil {
+				return deployError(req, err)
+			}
+			body, _ := json.Marshal(map[string]any{"current": deployer.Current(), "releases": releases})
+			return jsonResponse(req, 200, string(body))
+
+		case PUT:
+			if scanner != nil {
+				if err := scanner.Scan(strings.NewReader(req.GetBody())); err != nil {
+					return deployError(req, err)
+				}
+			}
+			release, err := deployer.Deploy([]byte(req.GetBody()))
+			if err != nil {
+				return deployError(req, err)
+			}
+			log.Printf("Deployed release %s", release)
+			body, _ := json.Marshal(map[string]string{"release": release})
+			return jsonResponse(req, 201, string(body))
+
+		default:
+			return methodNotAllowed(req, allowHeader(deployMethods))
+		}
+	}
+}
+
+// deployError maps a deploy error to a JSON error response.
+func deployError(req *Request, err error) Response {
+	var status StatusCode
+	switch {
+	case errors.Is(err, deploy.ErrUnsupportedArchive):
+		status = 415
+	case errors.Is(err, deploy.ErrUnsafePath):
+		status = 400
+	case errors.Is(err, deploy.ErrRootNotSymlink), errors.Is(err, deploy.ErrNoPreviousRelease):
+		status = 409
+	case errors.Is(err, scan.ErrInfected):
+		log.Printf("Rejected infected archive: %v", err)
+		status = 422
+	case errors.Is(err, deploy.ErrQuotaExceeded):
+		status = 507
+	default:
+		log.Printf("Error deploying: %v", err)
+		status = 500
+	}
+	body, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return jsonResponse(req, status, string(body))
+}
@@ -0,0 +1,82 @@
+package http
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestDeployEndpoint(t *testing.T) {
+	dir := t.TempDir()
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.FileServer.DocumentRoot = filepath.Join(dir, "public")
+		cfg.Deploy = config.DeployConfig{Enabled: true, Path: "/_deploy", Token: "secret", Releases: filepath.Join(dir, "releases"), Keep: 1}
+	})
+	defer server.Close()
+
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: "index.html", Mode: 0644, Size: 5, Typeflag: tar.TypeReg})
+	tw.Write([]byte("hello"))
+	tw.Close()
+	gz.Close()
+
+	auth := "Authorization: Bearer secret"
+	tests := []struct {
+		name    string
+		method  Method
+		path    string
+		body    string
+		headers []string
+		status  StatusCode
+	}{
+		{"missing token", PUT, "/_deploy", archive.String(), nil, 401},
+		{"wrong token", PUT, "/_deploy", archive.String(), []string{"Authorization: Bearer wrong"}, 401},
+		{"not an archive", PUT, "/_deploy", "hello", []string{auth}, 415},
+		{"deploy", PUT, "/_deploy", archive.String(), []string{auth}, 201},
+		{"list", GET, "/_deploy", "", []string{auth}, 200},
+		{"no previous release", POST, "/_deploy/rollback", "", []string{auth}, 409},
+		{"method not allowed", DELETE, "/_deploy", "", []string{auth}, 405},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := send(t, server, tt.method, tt.path, tt.body, tt.headers...)
+			if resp.GetStatusCode() != tt.status {
+				t.Errorf("Expected status %d, got %d: %s", tt.status, resp.GetStatusCode(), resp.GetBody())
+			}
+		})
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "public", "index.html"))
+	if err != nil || string(data) != "hello" {
+		t.Errorf("Expected deployed index.html to contain hello, got %q (%v)", data, err)
+	}
+	if resp := get(t, server, "/index.html"); resp.GetBody() != "hello" {
+		t.Errorf("Expected served index.html to contain hello, got %q", resp.GetBody())
+	}
+}
+
+func TestDeployScan(t *testing.T) {
+	dir := t.TempDir()
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.FileServer.DocumentRoot = filepath.Join(dir, "public")
+		cfg.Deploy = config.DeployConfig{Enabled: true, Path: "/_deploy", Token: "secret", Releases: filepath.Join(dir, "releases")}
+		cfg.Scan.Command = []string{"sh", "-c", "if grep -q EICAR; then exit 1; fi"}
+	})
+	defer server.Close()
+
+	resp := send(t, server, PUT, "/_deploy", "EICAR", "Authorization: Bearer secret")
+	if resp.GetStatusCode() != 422 {
+		t.Errorf("Expected status 422, got %d: %s", resp.GetStatusCode(), resp.GetBody())
+	}
+	if releases, _ := server.Deployer.Releases(); len(releases) != 0 {
+		t.Errorf("Expected no release, got %v", releases)
+	}
+}
@@ -0,0 +1,123 @@
+package http
+
+import (
+	"html/template"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/awaisamjad/volk/config"
+)
+
+// devPage is the error page shown to browsers in dev mode.
+var devPage = template.Must(template.New("dev").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Status}} {{.StatusText}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+h1 { color: #b00; }
+pre { background: #f4f4f4; padding: 1em; overflow-x: auto; }
+table { border-collapse: collapse; }
+td { padding: 0.2em 1em 0.2em 0; vertical-align: top; font-family: monospace; }
+</style>
+</head>
+<body>
+<h1>{{.Status}} {{.StatusText}}</h1>
+<pre>{{.Body}}</pre>
+<h2>Request</h2>
+<table>
+<tr><td>Request line</td><td>{{.RequestLine}}</td></tr>
+<tr><td>Request ID</td><td>{{.ID}}</td></tr>
+<tr><td>Remote address</td><td>{{.RemoteAddr}}</td></tr>
+<tr><td>Resolved file</td><td>{{if .FilePath}}{{.FilePath}}{{else}}none{{end}}</td></tr>
+</table>
+<h2>Request Headers</h2>
+<table>
+{{range .Headers}}<tr><td>{{.Name}}</td><td>{{.Value}}</td></tr>
+{{end}}</table>
+{{if .Panic}}<h2>Stack Trace</h2>
+<pre>{{.Panic}}</pre>
+{{end}}<h2>Configuration</h2>
+<pre>{{.Config}}</pre>
+<p>This page is shown because the server runs in dev mode. Never enable dev mode in production.</p>
+</body>
+</html>
+`))
+
+// devErrorPage replaces the body of an error response to a browser by a page
+// describing the request, the file it resolved to, the configuration that
+// applied and the panic, if any. Other responses are returned unchanged.
+func (s *Server) devErrorPage(req *Request, resp Response) Response {
+	if !s.Config.Server.Dev || resp.StartLine.StatusCode < 400 || req.GetMethod() == HEAD {
+		return resp
+	}
+	req.Vary("Accept")
+	if accept, _ := GetHeader(req.Headers, "Accept"); !strings.Contains(accept, "text/html") {
+		return resp
+	}
+
+	data := struct {
+		Status      StatusCode
+		StatusText  StatusText
+		Body        string
+		RequestLine string
+		ID          string
+		RemoteAddr  string
+		FilePath    string
+		Headers     []Header
+		Panic       string
+		Config      string
+	}{
+		Status:      resp.StartLine.StatusCode,
+		StatusText:  resp.StartLine.StatusText,
+		Body:        resp.Body,
+		RequestLine: req.StartLine.String(),
+		ID:          req.ID,
+		RemoteAddr:  req.RemoteAddr,
+		Headers:     s.redactHeaders(req.Headers),
+		Config:      s.devConfig(req.GetRequestTarget().Path),
+	}
+	if req.Trace != nil {
+		data.FilePath = req.Trace.FilePath
+		data.Panic = req.Trace.Panic
+	}
+
+	var body strings.Builder
+	if err := devPage.Execute(&body, data); err != nil {
+		return resp
+	}
+
+	headers := []Header{{Name: "Content-Type", Value: "text/html; charset=utf-8"}}
+	for _, header := range resp.Headers {
+		if !strings.EqualFold(header.Name, "Content-Type") && !strings.EqualFold(header.Name, "Content-Length") {
+			headers = append(headers, header)
+		}
+	}
+	resp.Headers = headers
+	resp.Body = body.String()
+	return resp
+}
+
+// devConfig returns the configuration that applies to requests for path as
+// TOML: the file server settings and the matching location, with secrets hidden.
+func (s *Server) devConfig(path string) string {
+	snippet := struct {
+		FileServer config.FileServerConfig `toml:"file_server"`
+		Locations  []config.LocationConfig `toml:"location,omitempty"`
+	}{FileServer: s.Config.FileServer}
+	if location, ok := s.Config.Location(path); ok {
+		if location.SignatureSecret != "" {
+			location.SignatureSecret = "********"
+		}
+		snippet.Locations = []config.LocationConfig{location}
+	}
+
+	var builder strings.Builder
+	encoder := toml.NewEncoder(&builder)
+	encoder.Indent = ""
+	if err := encoder.Encode(snippet); err != nil {
+		return "error encoding config: " + err.Error()
+	}
+	return strings.TrimRight(builder.String(), "\n")
+}
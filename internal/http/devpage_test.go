@@ -0,0 +1,66 @@
+package http
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestDevErrorPage(t *testing.T) {
+	tests := []struct {
+		name     string
+		dev      bool
+		path     string
+		accept   string
+		wantHTML bool
+		want     []string
+		notWant  []string
+	}{
+		{"not found", true, "/missing.html", "text/html", true, []string{"404 Not Found", "GET /missing.html", "HTTP/1.1", "document_root", "Accept"}, nil},
+		{"panic shows the stack", true, "/boom", "text/html,application/xhtml+xml", true, []string{"panic: boom", "devpage_test.go"}, nil},
+		{"location secret is hidden", true, "/private/doc.html", "text/html", true, []string{"[[location]]", "/private/", "********"}, []string{"s3cret"}},
+		{"not a browser", true, "/missing.html", "", false, nil, []string{"<html>"}},
+		{"production", false, "/missing.html", "text/html", false, nil, []string{"<html>", "document_root"}},
+		{"success is unchanged", true, "/index.html", "text/html", true, nil, []string{"Request ID"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log.SetOutput(&bytes.Buffer{})
+			defer log.SetOutput(os.Stderr)
+
+			server := newTestServer(t, func(cfg *config.Config) {
+				cfg.Server.Dev = tt.dev
+				cfg.Locations = []config.LocationConfig{{Path: "/private/", SignatureSecret: "s3cret"}}
+			})
+			server.Handle("/boom", func(w ResponseWriter, req *Request) {
+				panic("boom")
+			})
+
+			var headers []string
+			if tt.accept != "" {
+				headers = append(headers, "Accept: "+tt.accept)
+			}
+			resp := get(t, server, tt.path, headers...)
+
+			contentType, _ := GetHeader(resp.Headers, "Content-Type")
+			if isHTML := strings.HasPrefix(contentType, "text/html"); isHTML != tt.wantHTML {
+				t.Errorf("Expected an HTML page %v, got Content-Type %q", tt.wantHTML, contentType)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(resp.GetBody(), want) {
+					t.Errorf("Expected the body to contain %q, got %q", want, resp.GetBody())
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(resp.GetBody(), notWant) {
+					t.Errorf("Expected the body not to contain %q, got %q", notWant, resp.GetBody())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/awaisamjad/volk/config"
+	"github.com/awaisamjad/volk/internal/access"
+	"github.com/awaisamjad/volk/internal/download"
+	"github.com/awaisamjad/volk/internal/geoip"
+	"github.com/awaisamjad/volk/internal/sitemap"
+)
+
+// downloadBufferSize is how much of an archive is buffered before it is flushed to the client.
+const downloadBufferSize = 64 << 10
+
+// downloadFormat returns the archive format asked for with the download
+// query parameter, or "" for a request that does not ask for an archive.
+func downloadFormat(req *Request) string {
+	query, _ := url.ParseQuery(strings.TrimPrefix(req.GetRequestTarget().Query, "?"))
+	return query.Get("download")
+}
+
+// serveDownload streams an archive of the directory at urlPath, such as
+// GET /photos/?download=zip. Hidden files, the deny patterns of the download
+// settings, directories the access files deny the client and files of
+// locations whose rules the request does not pass are left out.
+// The archive is sent as it is written, so an error after the first bytes,
+// such as the request deadline passing, can only cut it short.
+func (s *Server) serveDownload(req *Request, urlPath, format string, fs *FileServer) Response {
+	contentType, err := download.ContentType(format)
+	if err != nil {
+		return newTextResponse(req.GetProtocol(), 400, "400 Bad Request: Unsupported archive format")
+	}
+
+	cfg := s.Config.Download
+	root := fs.documentRoot()
+	dir, err := resolve(root, urlPath)
+	if err != nil {
+		return newTextResponse(req.GetProtocol(), 404, "404 Not Found")
+	}
+	if resp, denied := fs.checkAccess(req, root, dir); denied {
+		return resp
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() || sitemap.Denied(cfg.Deny, strings.TrimSuffix(urlPath, "/")+"/") {
+		return newTextResponse(req.GetProtocol(), 404, "404 Not Found")
+	}
+
+	ctx, cancel := req.Context()
+	defer cancel()
+	accessReq := accessRequest(req)
+	files, err := download.Collect(ctx, dir, cfg.MaxSize, func(name string) bool {
+		isDir := strings.HasSuffix(name, "/")
+		urlName := path.Join(urlPath, name)
+		if isDir {
+			urlName += "/"
+		}
+		if sitemap.Denied(cfg.Deny, urlName) {
+			return false
+		}
+		if location, ok := s.Config.Location(urlName); ok && !s.locationOpen(req, location) {
+			return false
+		}
+		if !isDir {
+			return true
+		}
+		decision, _, err := fs.access.Check(root, filepath.Join(dir, filepath.FromSlash(name)), accessReq)
+		return err == nil && decision == access.Allow
+	})
+	if errors.Is(err, download.ErrTooLarge) {
+		return newTextResponse(req.GetProtocol(), 403, "403 Forbidden: Directory is too large to download")
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		resp, _ := deadlineResponse(req)
+		return resp
+	}
+	if err != nil {
+		log.Printf("Error collecting files of %s: %v", dir, err)
+		return newTextResponse(req.GetProtocol(), 500, "500 Internal Server Error")
+	}
+
+	name := path.Base(path.Clean("/" + urlPath))
+	if name == "/" {
+		name = "site"
+	}
+	w := req.writer
+	if w == nil {
+		w = newResponseWriter(nil, nil, req)
+	}
+	w.AddHeader("Content-Type", contentType)
+	w.AddHeader("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, name, format))
+
+	out := bufio.NewWriterSize(flushWriter{w}, downloadBufferSize)
+	err = download.Write(ctx, out, format, files)
+	if err == nil {
+		err = out.Flush()
+	}
+	if err != nil {
+		log.Printf("Error sending archive of %s: %v", dir, err)
+	}
+	return w.response()
+}
+
+// locationOpen reports whether the files of location may go into an archive
+// requested by req without checking anything of the request again. That
+// holds for the locations req already passed and for ones without country,
+// signature or login rules; a signature covers the path it was made for and
+// a login redirect cannot be sent in the middle of an archive, so the files
+// of other protected locations are left out.
+func (s *Server) locationOpen(req *Request, location config.LocationConfig) bool {
+	if slices.Contains(req.allowedLocations, location.Path) {
+		return true
+	}
+	if !geoip.Allowed(req.Country, location.AllowCountries, location.DenyCountries) {
+		return false
+	}
+	if _, signed := s.verifiers[location.Path]; signed {
+		return false
+	}
+	return location.Auth != "oidc" || req.Identity != nil
+}
+
+// flushWriter sends everything written to it right away.
+type flushWriter struct {
+	w ResponseWriter
+}
+
+func (f flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if err == nil {
+		err = f.w.Flush()
+	}
+	return n, err
+}
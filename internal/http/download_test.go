@@ -0,0 +1,150 @@
+package http
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestDirectoryDownload(t *testing.T) {
+	root := t.TempDir()
+	for name, data := range map[string]string{
+		"photos/a.jpg":               "aaaa",
+		"photos/raw/b.cr2":           "bbbb",
+		"photos/drafts/c.jpg":        "cccc",
+		"photos/private/d.jpg":       "dddd",
+		"photos/private/.volkaccess": `allow_ips = ["10.0.0.0/8"]`,
+		"photos/big/large.bin":       strings.Repeat("x", 100),
+		"secret/.volkaccess":         `allow_ips = ["10.0.0.0/8"]`,
+		"secret/e.txt":               "e",
+	} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.FileServer.DocumentRoot = root
+		cfg.Download = config.DownloadConfig{Enabled: true, Deny: []string{"/photos/drafts/", "*.cr2"}, MaxSize: 50}
+	})
+
+	resp := get(t, server, "/photos/?download=zip")
+	if resp.GetStatusCode() != 403 {
+		t.Errorf("Expected status 403 over the size limit, got %d", resp.GetStatusCode())
+	}
+	os.RemoveAll(filepath.Join(root, "photos", "big"))
+
+	resp = get(t, server, "/photos/?download=zip")
+	if resp.GetStatusCode() != 200 {
+		t.Fatalf("Expected status 200, got %d: %s", resp.GetStatusCode(), resp.GetBody())
+	}
+	if disposition, _ := GetHeader(resp.Headers, "Content-Disposition"); disposition != `attachment; filename="photos.zip"` {
+		t.Errorf("Expected the archive to be named photos.zip, got %q", disposition)
+	}
+	reader, err := zip.NewReader(strings.NewReader(resp.GetBody()), int64(len(resp.GetBody())))
+	if err != nil {
+		t.Fatalf("Expected a zip archive, got %v", err)
+	}
+	var names []string
+	for _, file := range reader.File {
+		names = append(names, file.Name)
+	}
+	sort.Strings(names)
+	if want := []string{"a.jpg"}; !slices.Equal(names, want) {
+		t.Errorf("Expected %v, got %v", want, names)
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		status StatusCode
+	}{
+		{"tar.gz", "/photos/?download=tar.gz", 200},
+		{"unsupported format", "/photos/?download=rar", 400},
+		{"missing directory", "/missing/?download=zip", 404},
+		{"denied directory", "/photos/drafts/?download=zip", 404},
+		{"access file", "/secret/?download=zip", 403},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if resp := get(t, server, tt.path); resp.GetStatusCode() != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, resp.GetStatusCode())
+			}
+		})
+	}
+}
+
+func TestDirectoryDownloadProtectedLocations(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"index.html", "private/secret.txt", "private/deep/more.txt", "local/only.txt", "public/open.txt"} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.FileServer.DocumentRoot = root
+		cfg.Download = config.DownloadConfig{Enabled: true}
+		cfg.Locations = []config.LocationConfig{
+			{Path: "/private/", SignatureSecret: "s3cret"},
+			{Path: "/local/", AllowCountries: []string{"DE"}},
+			{Path: "/public/"},
+		}
+	})
+
+	if resp := get(t, server, "/private/secret.txt"); resp.GetStatusCode() != 401 {
+		t.Fatalf("Expected status 401 for an unsigned request, got %d", resp.GetStatusCode())
+	}
+	resp := get(t, server, "/?download=zip")
+	if resp.GetStatusCode() != 200 {
+		t.Fatalf("Expected status 200, got %d: %s", resp.GetStatusCode(), resp.GetBody())
+	}
+	reader, err := zip.NewReader(strings.NewReader(resp.GetBody()), int64(len(resp.GetBody())))
+	if err != nil {
+		t.Fatalf("Expected a zip archive, got %v", err)
+	}
+	var names []string
+	for _, file := range reader.File {
+		names = append(names, file.Name)
+	}
+	sort.Strings(names)
+	if want := []string{"index.html", "public/open.txt"}; !slices.Equal(names, want) {
+		t.Errorf("Expected %v, got %v", want, names)
+	}
+}
+
+func TestDirectoryDownloadDeadline(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.FileServer.DocumentRoot = root
+		cfg.Download = config.DownloadConfig{Enabled: true}
+	})
+
+	req := &Request{
+		StartLine: RequestStartLine{Method: GET, RequestTarget: RequestTarget{Path: "/"}, Protocol: HTTP1_1},
+		Deadline:  time.Now().Add(-time.Millisecond),
+	}
+	resp := server.serveDownload(req, "/", "zip", server.FileServer)
+	if resp.GetStatusCode() != 503 {
+		t.Errorf("Expected status 503 once the deadline passed, got %d", resp.GetStatusCode())
+	}
+}
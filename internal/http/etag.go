@@ -0,0 +1,35 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// etag returns the ETag of the file at filePath below root, as chosen by
+// file_server.etag, or an empty string if files have none.
+//
+// A strong tag is the SHA-256 sum of the content, which is the same on every
+// server a deployment copies the file to, whatever its modification time.
+// Files are hashed in the background, and the weak tag stands in until the
+// sum of the current version is known.
+func (fs *FileServer) etag(root, filePath string, info os.FileInfo) string {
+	switch fs.Config.ETag {
+	case "weak":
+		return weakETag(info)
+	case "strong":
+		open := func() (io.ReadCloser, error) { return fs.open(root, filePath) }
+		if sum, ok := fs.hashes.Sum(filePath, info.Size(), info.ModTime(), open); ok {
+			return `"` + sum + `"`
+		}
+		return weakETag(info)
+	}
+	return ""
+}
+
+// weakETag returns a weak ETag made of the modification time and the size
+// of a file, which costs nothing to compute but changes with the
+// modification time alone.
+func weakETag(info os.FileInfo) string {
+	return fmt.Sprintf(`W/"%x-%x"`, info.ModTime().UnixNano(), info.Size())
+}
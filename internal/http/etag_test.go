@@ -0,0 +1,116 @@
+package http
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// serveFile sends a GET request for path with the extra header lines to fs.
+func serveFile(t *testing.T, fs *FileServer, path string, headers ...string) Response {
+	t.Helper()
+	req, err := NewRequest("GET " + path + " HTTP/1.1\r\nHost: localhost\r\n" + strings.Join(append(headers, ""), "\r\n") + "\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fs.ServeFile(&req)
+}
+
+// etagRoot creates a document root holding index.html, modified at modTime.
+func etagRoot(t *testing.T, modTime time.Time) string {
+	t.Helper()
+	root := t.TempDir()
+	path := filepath.Join(root, "index.html")
+	if err := os.WriteFile(path, []byte("<h1>Hello</h1>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestFileETag(t *testing.T) {
+	modTime := time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		etag       string
+		wantPrefix string
+	}{
+		{"none", "", ""},
+		{"weak", "weak", `W/"`},
+		{"strong", "strong", `"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := NewFileServer(config.FileServerConfig{DocumentRoot: etagRoot(t, modTime), DefaultFile: "index.html", ETag: tt.etag})
+
+			resp := serveFile(t, fs, "/")
+			etag, ok := GetHeader(resp.Headers, "ETag")
+			if tt.wantPrefix == "" {
+				if ok {
+					t.Errorf("Expected no ETag, got %q", etag)
+				}
+				return
+			}
+			if tt.etag == "strong" {
+				// The weak tag stands in until the file is hashed.
+				deadline := time.Now().Add(5 * time.Second)
+				for strings.HasPrefix(etag, "W/") && time.Now().Before(deadline) {
+					time.Sleep(time.Millisecond)
+					etag, _ = GetHeader(serveFile(t, fs, "/").Headers, "ETag")
+				}
+			}
+			if !strings.HasPrefix(etag, tt.wantPrefix) || (tt.etag == "strong" && strings.HasPrefix(etag, "W/")) {
+				t.Errorf("Expected an ETag starting with %s, got %q", tt.wantPrefix, etag)
+			}
+			if lastModified, _ := GetHeader(resp.Headers, "Last-Modified"); lastModified != "Thu, 01 Oct 2026 12:00:00 GMT" {
+				t.Errorf("Expected Last-Modified, got %q", lastModified)
+			}
+
+			resp = serveFile(t, fs, "/", "If-None-Match: "+etag)
+			if resp.GetStatusCode() != 304 {
+				t.Errorf("Expected status 304, got %d", resp.GetStatusCode())
+			}
+			if value, _ := GetHeader(resp.Headers, "ETag"); value != etag {
+				t.Errorf("Expected ETag %q on the 304, got %q", etag, value)
+			}
+			resp = serveFile(t, fs, "/", `If-None-Match: "other"`)
+			if resp.GetStatusCode() != 200 {
+				t.Errorf("Expected status 200, got %d", resp.GetStatusCode())
+			}
+		})
+	}
+}
+
+// TestStrongETagAcrossServers checks that copies of a file with different
+// modification times, as rsync leaves them on each server, share strong ETags.
+func TestStrongETagAcrossServers(t *testing.T) {
+	var etags []string
+	for _, modTime := range []time.Time{time.Unix(1700000000, 0), time.Unix(1800000000, 0)} {
+		fs := NewFileServer(config.FileServerConfig{DocumentRoot: etagRoot(t, modTime), DefaultFile: "index.html", ETag: "strong"})
+		etag := ""
+		deadline := time.Now().Add(5 * time.Second)
+		for (etag == "" || strings.HasPrefix(etag, "W/")) && time.Now().Before(deadline) {
+			etag, _ = GetHeader(serveFile(t, fs, "/index.html").Headers, "ETag")
+			time.Sleep(time.Millisecond)
+		}
+		etags = append(etags, etag)
+	}
+	if etags[0] != etags[1] || strings.HasPrefix(etags[0], "W/") {
+		t.Errorf("Expected the same strong ETag, got %q", etags)
+	}
+}
+
+func TestFileETagInvalidConfig(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.FileServer.ETag = "md5"
+	if _, err := NewServer(cfg); err == nil {
+		t.Error("Expected an error for an unknown etag setting, got nil")
+	}
+}
@@ -1,26 +1,174 @@
 package http
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"log"
-	"mime"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/awaisamjad/volk/config"
+	"github.com/awaisamjad/volk/internal/access"
+	"github.com/awaisamjad/volk/internal/harden"
+	"github.com/awaisamjad/volk/internal/hashcache"
+	"github.com/awaisamjad/volk/internal/httpdate"
+	"github.com/awaisamjad/volk/internal/mimetype"
 )
 
 // FileServer handles serving files
 type FileServer struct {
 	Config config.FileServerConfig
+
+	mu       sync.Mutex
+	rootLink os.FileInfo // The document root link when root was resolved
+	root     string      // The document root with symbolic links resolved
+	pinned   *os.Root    // Directory files are opened in instead of the document root's path, see pin
+
+	access *access.Checker    // Loads the .volkaccess files of the document root
+	types  *mimetype.Resolver // Content types of the files
+	hashes *hashcache.Cache   // Sums of the files for strong ETags, nil without them
 }
 
 // NewFileServer creates a new FileServer instance.
 func NewFileServer(config config.FileServerConfig) *FileServer {
-	return &FileServer{
+	fs := &FileServer{
 		Config: config,
+		access: access.NewChecker(),
+		types:  mimetype.New(config.MimeTypes, config.DefaultType),
+	}
+	if config.ETag == "strong" {
+		fs.hashes = hashcache.New(0, 0)
+	}
+	return fs
+}
+
+// documentRoot returns the document root with symbolic links resolved.
+//
+// When the document root is a symbolic link that deploys replace to switch
+// releases, resolving it once per request keeps every file of a response in
+// the same release. The resolved path is cached until the link itself changes,
+// which costs a single lstat per request.
+func (fs *FileServer) documentRoot() string {
+	if fs.pinnedRoot() != nil {
+		return fs.Config.DocumentRoot
+	}
+	link, err := os.Lstat(fs.Config.DocumentRoot)
+	if err != nil || link.Mode()&os.ModeSymlink == 0 {
+		return fs.Config.DocumentRoot
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if fs.rootLink != nil && os.SameFile(fs.rootLink, link) && fs.rootLink.ModTime().Equal(link.ModTime()) {
+		return fs.root
+	}
+	root, err := filepath.EvalSymlinks(fs.Config.DocumentRoot)
+	if err != nil {
+		return fs.Config.DocumentRoot
+	}
+	fs.rootLink, fs.root = link, root
+	return root
+}
+
+// pin makes the FileServer open its files in dir, an open handle of the
+// document root, instead of looking the document root up by its path, which
+// could lead elsewhere by the time a file is opened. It closes the directory
+// pinned before, if any.
+func (fs *FileServer) pin(dir *os.Root) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	if fs.pinned != nil {
+		fs.pinned.Close()
+	}
+	fs.pinned = dir
+}
+
+// pinnedRoot returns the directory set with pin, or nil.
+func (fs *FileServer) pinnedRoot() *os.Root {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+	return fs.pinned
+}
+
+// resolve returns the path on disk below root for a clean URL path. It
+// returns ErrUnsafePath for paths that the file system of the platform could
+// take for another file, see checkPathSegments.
+func resolve(root, urlPath string) (string, error) {
+	cleanPath := path.Clean("/" + urlPath)
+	if err := checkPathSegments(cleanPath, windowsPaths); err != nil {
+		return "", err
+	}
+	return filepath.Join(root, cleanPath[1:]), nil
+}
+
+// Exists reports whether a regular file exists at the URL path.
+func (fs *FileServer) Exists(urlPath string) bool {
+	root := fs.documentRoot()
+	filePath, err := resolve(root, urlPath)
+	if err != nil {
+		return false
 	}
+	info, err := fs.stat(root, filePath)
+	return err == nil && info.Mode().IsRegular()
+}
+
+// ReadFile returns the contents of the file at the URL path.
+func (fs *FileServer) ReadFile(urlPath string) ([]byte, error) {
+	root := fs.documentRoot()
+	filePath, err := resolve(root, urlPath)
+	if err != nil {
+		return nil, err
+	}
+	return fs.readFile(root, filePath)
+}
+
+// open opens filePath, a path returned by resolve for root. With Beneath set
+// it is opened with harden.OpenBeneath, or in the pinned directory, so
+// symbolic links cannot lead out of the document root.
+func (fs *FileServer) open(root, filePath string) (*os.File, error) {
+	if !fs.Config.Beneath {
+		return os.Open(filePath)
+	}
+	name, err := filepath.Rel(root, filePath)
+	if err != nil {
+		return nil, err
+	}
+	if dir := fs.pinnedRoot(); dir != nil {
+		return harden.OpenIn(dir, name)
+	}
+	return harden.OpenBeneath(root, name)
+}
+
+// stat is os.Stat for a path below root, following the rules of open.
+func (fs *FileServer) stat(root, filePath string) (os.FileInfo, error) {
+	if !fs.Config.Beneath {
+		return os.Stat(filePath)
+	}
+	f, err := fs.open(root, filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Stat()
+}
+
+// readFile is os.ReadFile for a path below root, following the rules of open.
+func (fs *FileServer) readFile(root, filePath string) ([]byte, error) {
+	if !fs.Config.Beneath {
+		return os.ReadFile(filePath)
+	}
+	f, err := fs.open(root, filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(f)
 }
 
 // ServeFile handles file serving based on a request.
@@ -36,14 +184,19 @@ func (fs *FileServer) ServeFile(req *Request) Response {
 			},
 			Headers: []Header{
 				{Name: "Content-Type", Value: "text/plain"},
-				{Name: "Allow", Value: "GET"},
+				{Name: "Allow", Value: allowHeader(fileMethods)},
 			},
 			Body: "405 Method Not Allowed: Only GET is supported for file serving",
 		}
 	}
 
+	root := fs.documentRoot()
 	urlPath := req.GetRequestTarget()
 	err := req.ValidatePath()
+	filePath := ""
+	if err == nil {
+		filePath, err = resolve(root, urlPath.Path)
+	}
 	if err != nil {
 		return Response{
 			StartLine: ResponseStartLine{
@@ -58,11 +211,24 @@ func (fs *FileServer) ServeFile(req *Request) Response {
 		}
 	}
 
-	cleanPath := path.Clean(urlPath.Path)
-	filePath := filepath.Join(fs.Config.DocumentRoot, cleanPath[1:])
-	fileInfo, err := os.Stat(filePath)
+	req.traceFile(filePath)
+	defer req.traceFileIO(time.Now())
+	fileInfo, err := fs.stat(root, filePath)
+
+	// Access files apply to their directory, and are never served themselves.
+	dir := filepath.Dir(filePath)
+	if err == nil && fileInfo.IsDir() {
+		dir = filePath
+	}
+	if resp, denied := fs.checkAccess(req, root, dir); denied {
+		return resp
+	}
+	if isAccessFile(filepath.Base(filePath)) {
+		err = os.ErrNotExist
+	}
+
 	if err != nil {
-		if os.IsNotExist(err) {
+		if os.IsNotExist(err) || errors.Is(err, harden.ErrOutsideRoot) {
 			log.Println(err)
 			return Response{
 				StartLine: ResponseStartLine{
@@ -91,7 +257,8 @@ func (fs *FileServer) ServeFile(req *Request) Response {
 
 	if fileInfo.IsDir() {
 		filePath = filepath.Join(filePath, fs.Config.DefaultFile)
-		_, err := os.Stat(filePath)
+		req.traceFile(filePath)
+		fileInfo, err = fs.stat(root, filePath)
 		if err != nil {
 			log.Println(err)
 			return Response{
@@ -108,7 +275,14 @@ func (fs *FileServer) ServeFile(req *Request) Response {
 		}
 	}
 
-	content, err := os.ReadFile(filePath)
+	etag := fs.etag(root, filePath, fileInfo)
+	if etag != "" {
+		if status := EvaluatePreconditions(req, etag, fileInfo.ModTime()); status != 0 {
+			return PreconditionResponse(req, status, etag, fileInfo.ModTime())
+		}
+	}
+
+	content, err := fs.readFile(root, filePath)
 	if err != nil {
 		log.Println(err)
 		return Response{
@@ -124,12 +298,9 @@ func (fs *FileServer) ServeFile(req *Request) Response {
 		}
 	}
 
-	contentType := mime.TypeByExtension(filepath.Ext(filePath))
-	if contentType == "" {
-		contentType = "application/octet-stream"
-	}
+	contentType := fs.types.TypeByName(filePath)
 
-	return Response{
+	resp := Response{
 		StartLine: ResponseStartLine{
 			Protocol:   req.StartLine.Protocol,
 			StatusCode: 200,
@@ -141,4 +312,36 @@ func (fs *FileServer) ServeFile(req *Request) Response {
 		},
 		Body: string(content),
 	}
+	if etag != "" {
+		resp.Headers = append(resp.Headers,
+			Header{Name: "ETag", Value: etag},
+			Header{Name: "Last-Modified", Value: httpdate.Format(fileInfo.ModTime())},
+		)
+	}
+	return resp
+}
+
+// checkAccess checks the request against the access files from the document
+// root down to dir, and returns the response for a denied request with true.
+func (fs *FileServer) checkAccess(req *Request, root, dir string) (Response, bool) {
+	decision, methods, err := fs.access.Check(root, dir, accessRequest(req))
+	if err != nil {
+		log.Printf("Error checking access to %s: %v", req.GetRequestTarget().Path, err)
+	}
+	switch decision {
+	case access.Forbid:
+		return newTextResponse(req.GetProtocol(), 403, "403 Forbidden"), true
+	case access.MethodNotAllowed:
+		return methodNotAllowed(req, strings.Join(methods, ", ")), true
+	}
+	return Response{}, false
+}
+
+// accessRequest returns what the access files are checked against for req.
+func accessRequest(req *Request) access.Request {
+	accessReq := access.Request{Method: string(req.GetMethod()), IP: req.RemoteIP()}
+	if req.Identity != nil {
+		accessReq.Users = []string{req.Identity.Subject, req.Identity.Email}
+	}
+	return accessReq
 }
@@ -0,0 +1,162 @@
+package http
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+	"github.com/awaisamjad/volk/internal/oidc"
+)
+
+func TestFileServerSymlinkRoot(t *testing.T) {
+	dir := t.TempDir()
+	for _, release := range []string{"blue", "green"} {
+		if err := os.MkdirAll(filepath.Join(dir, release), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, release, "index.html"), []byte(release), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	root := filepath.Join(dir, "public")
+	if err := os.Symlink(filepath.Join(dir, "blue"), root); err != nil {
+		t.Fatal(err)
+	}
+
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.FileServer.DocumentRoot = root
+	})
+	defer server.Close()
+
+	if resp := get(t, server, "/"); resp.GetBody() != "blue" {
+		t.Errorf("Expected blue before the switch, got %q", resp.GetBody())
+	}
+
+	// Switch releases the way deploys do: a new link renamed over the old one.
+	if err := os.Symlink(filepath.Join(dir, "green"), root+".tmp"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(root+".tmp", root); err != nil {
+		t.Fatal(err)
+	}
+
+	if resp := get(t, server, "/"); resp.GetBody() != "green" {
+		t.Errorf("Expected green after the switch, got %q", resp.GetBody())
+	}
+	if !server.FileServer.Exists("/index.html") {
+		t.Errorf("Expected index.html to exist in the new release")
+	}
+}
+
+func TestFileServerAccessFiles(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"index.html":                "public",
+		".volkaccess":               `deny_ips = ["192.0.2.66"]`,
+		"staff/index.html":          "staff",
+		"staff/.volkaccess":         `allow_users = ["alice@example.com"]`,
+		"office/index.html":         "office",
+		"office/.volkaccess":        `allow_ips = ["10.0.0.0/8"]`,
+		"readonly/index.html":       "readonly",
+		"readonly/.volkaccess":      `methods = ["HEAD"]`,
+		"staff/reports/q1.html":     "q1",
+		"staff/reports/.volkaccess": `allow_users = ["bob"]`,
+		"broken/index.html":         "broken",
+		"broken/.volkaccess":        `allow_ips = [`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fs := NewFileServer(config.FileServerConfig{DocumentRoot: root, DefaultFile: "index.html"})
+
+	tests := []struct {
+		name       string
+		path       string
+		remoteAddr string
+		identity   *oidc.Identity
+		wantCode   StatusCode
+	}{
+		{"public", "/index.html", "192.0.2.1:1234", nil, 200},
+		{"denied client", "/index.html", "192.0.2.66:1234", nil, 403},
+		{"access file", "/.volkaccess", "192.0.2.1:1234", nil, 404},
+		{"user", "/staff/", "192.0.2.1:1234", &oidc.Identity{Subject: "1", Email: "alice@example.com"}, 200},
+		{"not logged in", "/staff/", "192.0.2.1:1234", nil, 403},
+		{"missing file below a protected directory", "/staff/missing.html", "192.0.2.1:1234", nil, 403},
+		{"parent rules apply", "/staff/reports/q1.html", "192.0.2.1:1234", &oidc.Identity{Subject: "bob"}, 403},
+		{"office network", "/office/", "10.1.2.3:1234", nil, 200},
+		{"outside the office", "/office/", "192.0.2.1:1234", nil, 403},
+		{"method", "/readonly/", "192.0.2.1:1234", nil, 405},
+		{"invalid access file", "/broken/", "192.0.2.1:1234", nil, 403},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := NewRequest("GET " + tt.path + " HTTP/1.1\r\nHost: localhost\r\n\r\n")
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.RemoteAddr = tt.remoteAddr
+			req.Identity = tt.identity
+
+			resp := fs.ServeFile(&req)
+			if resp.GetStatusCode() != tt.wantCode {
+				t.Errorf("Expected status %d, got %d", tt.wantCode, resp.GetStatusCode())
+			}
+		})
+	}
+}
+
+func TestFileServerContentTypes(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"PHOTO.JPG", "INDEX.HTML", "release.tar.gz", "app.min.js", "export.Data", "README"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(name), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fs := NewFileServer(config.FileServerConfig{
+		DocumentRoot: root,
+		DefaultFile:  "index.html",
+		MimeTypes:    map[string]string{".data": "application/x-volk-data"},
+		DefaultType:  "text/plain",
+	})
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"/PHOTO.JPG", "image/jpeg"},
+		{"/INDEX.HTML", "text/html; charset=utf-8"},
+		{"/release.tar.gz", "application/gzip"},
+		{"/app.min.js", "text/javascript; charset=utf-8"},
+		{"/export.Data", "application/x-volk-data"},
+		{"/README", "text/plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req, err := NewRequest("GET " + tt.path + " HTTP/1.1\r\nHost: localhost\r\n\r\n")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp := fs.ServeFile(&req)
+			if contentType, _ := GetHeader(resp.Headers, "Content-Type"); contentType != tt.expected {
+				t.Errorf("Expected Content-Type %q, got %q", tt.expected, contentType)
+			}
+		})
+	}
+}
+
+func TestFileServerInvalidMimeTypes(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.FileServer.MimeTypes = map[string]string{"gz": "application/gzip"}
+	if _, err := NewServer(cfg); err == nil {
+		t.Error("Expected an error for an extension without a dot, got nil")
+	}
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// flavor is a document root served to requests with a header value.
+type flavor struct {
+	header     string
+	value      string
+	fileServer *FileServer
+}
+
+// flavorRoutes are the header routes of a location, in configuration order.
+type flavorRoutes struct {
+	flavors []flavor
+	headers []string // Names of the headers the routes look at
+}
+
+// newFlavorRoutes creates the header routes of a location, with a FileServer
+// per route that only differs from fsConfig in its document root.
+func newFlavorRoutes(fsConfig config.FileServerConfig, location config.LocationConfig) (*flavorRoutes, error) {
+	routes := &flavorRoutes{}
+	var names []string
+	for _, f := range location.Flavors {
+		if f.Header == "" || f.DocumentRoot == "" {
+			return nil, errors.New("a flavor needs a header and a document root")
+		}
+		if strings.ContainsAny(f.Header, " \t:") {
+			return nil, fmt.Errorf("invalid flavor header %q", f.Header)
+		}
+		flavorConfig := fsConfig
+		flavorConfig.DocumentRoot = f.DocumentRoot
+		routes.flavors = append(routes.flavors, flavor{header: f.Header, value: f.Value, fileServer: NewFileServer(flavorConfig)})
+		if !slices.ContainsFunc(names, func(name string) bool { return strings.EqualFold(name, f.Header) }) {
+			names = append(names, f.Header)
+		}
+	}
+	routes.headers = names
+	return routes, nil
+}
+
+// choose returns the FileServer of the first route matching the request, if
+// any. The response depends on the headers of all routes, matched or not.
+func (r *flavorRoutes) choose(req *Request) (*FileServer, bool) {
+	req.Vary(r.headers...)
+	for _, f := range r.flavors {
+		if value, ok := GetHeader(req.Headers, f.header); ok && strings.TrimSpace(value) == f.value {
+			return f.fileServer, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,72 @@
+package http
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestServerFlavors(t *testing.T) {
+	staging, preview := t.TempDir(), t.TempDir()
+	for dir, body := range map[string]string{staging: "staging", preview: "preview"} {
+		if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	production, _ := os.ReadFile(filepath.Join("testdata", "conformance", "root", "index.html"))
+
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Locations = []config.LocationConfig{{
+			Path: "/",
+			Flavors: []config.FlavorConfig{
+				{Header: "X-Env", Value: "staging", DocumentRoot: staging},
+				{Header: "X-Build", Value: "preview", DocumentRoot: preview},
+			},
+		}}
+	})
+
+	tests := []struct {
+		name     string
+		headers  []string
+		wantBody string
+	}{
+		{"no header", nil, string(production)},
+		{"staging", []string{"X-Env: staging"}, "staging"},
+		{"header names are case-insensitive", []string{"x-env: staging"}, "staging"},
+		{"other value", []string{"X-Env: qa"}, string(production)},
+		{"second route", []string{"X-Build: preview"}, "preview"},
+		{"first route wins", []string{"X-Build: preview", "X-Env: staging"}, "staging"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := get(t, server, "/index.html", tt.headers...)
+			if resp.GetBody() != tt.wantBody {
+				t.Errorf("Expected body %q, got %q", tt.wantBody, resp.GetBody())
+			}
+			if vary, _ := GetHeader(resp.Headers, "Vary"); vary != "X-Env, X-Build" {
+				t.Errorf("Expected Vary %q, got %q", "X-Env, X-Build", vary)
+			}
+		})
+	}
+}
+
+func TestServerFlavorsError(t *testing.T) {
+	tests := []config.FlavorConfig{
+		{Value: "staging", DocumentRoot: "staging"},
+		{Header: "X-Env", Value: "staging"},
+		{Header: "X Env", Value: "staging", DocumentRoot: "staging"},
+	}
+
+	for _, flavor := range tests {
+		t.Run(flavor.Header, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.Locations = []config.LocationConfig{{Path: "/", Flavors: []config.FlavorConfig{flavor}}}
+			if _, err := NewServer(cfg); err == nil {
+				t.Error("Expected an error, got nil")
+			}
+		})
+	}
+}
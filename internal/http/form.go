@@ -0,0 +1,101 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"mime"
+	"net/url"
+
+	"github.com/awaisamjad/volk/internal/session"
+)
+
+// ErrUnsupportedMediaType is returned by ParseForm for bodies that are not URL-encoded forms.
+var ErrUnsupportedMediaType = errors.New("unsupported media type")
+
+// flashCookie holds the flash messages waiting to be shown.
+const flashCookie = "volk_flash"
+
+// ParseForm returns the fields of an application/x-www-form-urlencoded body
+// followed by the fields of the query string.
+func (r Request) ParseForm() (url.Values, error) {
+	values := url.Values{}
+
+	if r.Body != "" {
+		contentType, _ := GetHeader(r.Headers, "Content-Type")
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if mediaType != "application/x-www-form-urlencoded" {
+			return nil, ErrUnsupportedMediaType
+		}
+		body, err := parseParams(r.Body, r.formOptions(), 0)
+		if err != nil {
+			return nil, err
+		}
+		for key, vs := range body {
+			values[key] = append(values[key], vs...)
+		}
+	}
+
+	query, err := parseQueryWith(r.GetRequestTarget().Query, r.formOptions())
+	if err != nil {
+		return nil, err
+	}
+	for key, vs := range query.Params {
+		values[key] = append(values[key], vs...)
+	}
+
+	return values, nil
+}
+
+// formOptions are the QueryOptions of the request with '+' decoded to a
+// space, as browsers encode it in forms whatever the application expects.
+func (r Request) formOptions() QueryOptions {
+	opts := r.QueryOptions
+	opts.PlusAsSpace = true
+	return opts
+}
+
+// SeeOther returns a 303 See Other response redirecting to location. After
+// handling a form post, it sends the browser to a page it can reload without
+// posting the form again.
+func SeeOther(req *Request, location string) Response {
+	return newRedirectResponse(req.GetProtocol(), 303, location)
+}
+
+// AddFlash adds a message to show on the next page the client requests,
+// typically together with SeeOther. Messages are kept in a signed cookie.
+func (s *Server) AddFlash(req *Request, resp *Response, message string) {
+	messages := append(s.pendingFlashes(req), message)
+	payload, _ := json.Marshal(messages)
+	resp.Headers = append(resp.Headers, Header{
+		Name:  "Set-Cookie",
+		Value: session.SetCookie(flashCookie, s.Sessions.Sign(payload), session.CookieOptions{MaxAge: 300}),
+	})
+}
+
+// Flashes returns the flash messages of the request and clears them by adding a cookie to the response.
+func (s *Server) Flashes(req *Request, resp *Response) []string {
+	messages := s.pendingFlashes(req)
+	if len(messages) > 0 {
+		resp.Headers = append(resp.Headers, Header{
+			Name:  "Set-Cookie",
+			Value: session.SetCookie(flashCookie, "", session.CookieOptions{}),
+		})
+	}
+	return messages
+}
+
+// pendingFlashes returns the messages in the request's flash cookie.
+func (s *Server) pendingFlashes(req *Request) []string {
+	cookie, _ := GetHeader(req.Headers, "Cookie")
+	value, ok := session.Cookie(cookie, flashCookie)
+	if !ok {
+		return nil
+	}
+	payload, ok := s.Sessions.Verify(value)
+	if !ok {
+		return nil
+	}
+	var messages []string
+	json.Unmarshal(payload, &messages)
+	return messages
+}
@@ -0,0 +1,108 @@
+package http
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseForm(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		query       string
+		expected    map[string]string
+		err         error
+	}{
+		{"urlencoded", "application/x-www-form-urlencoded", "name=Jane+Doe&msg=hi%21", "", map[string]string{"name": "Jane Doe", "msg": "hi!"}, nil},
+		{"charset parameter", "application/x-www-form-urlencoded; charset=utf-8", "a=1", "?b=2", map[string]string{"a": "1", "b": "2"}, nil},
+		{"query only", "", "", "?q=volk", map[string]string{"q": "volk"}, nil},
+		{"json body", "application/json", `{"a":1}`, "", nil, ErrUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := Request{
+				StartLine: RequestStartLine{Method: POST, RequestTarget: RequestTarget{Path: "/form", Query: tt.query}},
+				Headers:   []Header{{Name: "Content-Type", Value: tt.contentType}},
+				Body:      tt.body,
+			}
+			values, err := req.ParseForm()
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			for key, value := range tt.expected {
+				if values.Get(key) != value {
+					t.Errorf("Expected %s=%q, got %q", key, value, values.Get(key))
+				}
+			}
+		})
+	}
+}
+
+func TestParseFormQueryOptions(t *testing.T) {
+	req := Request{
+		StartLine:    RequestStartLine{Method: POST, RequestTarget: RequestTarget{Path: "/form", Query: "?tag[]=c"}},
+		Headers:      []Header{{Name: "Content-Type", Value: "application/x-www-form-urlencoded"}},
+		Body:         "tag[]=a+b;tag[]=" + strings.Repeat("x&", MaxQueryParams+1),
+		QueryOptions: QueryOptions{Semicolons: true, Arrays: true},
+	}
+	values, err := req.ParseForm()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tags := values["tag"]
+	if len(tags) != 3 || tags[0] != "a b" || tags[1] != "x" || tags[2] != "c" {
+		t.Errorf("Expected tags [a b, x, c], got %q", tags)
+	}
+}
+
+func TestFormPostRedirectWithFlash(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.Handle("/contact", ResponseHandler(func(req *Request) Response {
+		form, err := req.ParseForm()
+		if err != nil {
+			return newTextResponse(req.GetProtocol(), 400, "400 Bad Request")
+		}
+		resp := SeeOther(req, "/thanks")
+		server.AddFlash(req, &resp, "Thanks, "+form.Get("name")+"!")
+		return resp
+	}))
+	server.Handle("/thanks", ResponseHandler(func(req *Request) Response {
+		resp := newTextResponse(req.GetProtocol(), 200, "")
+		resp.Body = strings.Join(server.Flashes(req, &resp), "\n")
+		return resp
+	}))
+
+	body := "name=Jane"
+	request := "POST /contact HTTP/1.1\r\nHost: localhost\r\n" +
+		"Content-Type: application/x-www-form-urlencoded\r\n" +
+		"Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body
+	resp, err := exchangeResponse(server, []byte(request))
+	if err != nil {
+		t.Fatalf("could not parse response: %v", err)
+	}
+	if resp.GetStatusCode() != 303 {
+		t.Fatalf("Expected status 303, got %d", resp.GetStatusCode())
+	}
+	if location, _ := GetHeader(resp.Headers, "Location"); location != "/thanks" {
+		t.Errorf("Expected a redirect to /thanks, got %q", location)
+	}
+	setCookie, _ := GetHeader(resp.Headers, "Set-Cookie")
+	cookie, _, _ := strings.Cut(setCookie, ";")
+
+	resp = get(t, server, "/thanks", "Cookie: "+cookie)
+	if resp.GetBody() != "Thanks, Jane!" {
+		t.Errorf("Expected the flash message, got %q", resp.GetBody())
+	}
+	if setCookie, _ := GetHeader(resp.Headers, "Set-Cookie"); !strings.Contains(setCookie, "Max-Age=0") {
+		t.Errorf("Expected the flash cookie to be cleared, got %q", setCookie)
+	}
+
+	resp = get(t, server, "/thanks", "Cookie: volk_flash=forged.value")
+	if resp.GetBody() != "" {
+		t.Errorf("Expected a forged flash cookie to be ignored, got %q", resp.GetBody())
+	}
+}
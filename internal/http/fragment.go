@@ -19,25 +19,19 @@ var (
 
 // findFragment extracts the fragment from a request target.
 // It returns the fragment, its starting index in the request target, and an error if any.
+// Trailing whitespace is not part of the fragment.
 //
 // Returns:
 //   - fragment: The extracted fragment string, including the '#' prefix.
 //   - fragmentIndex: The index of the '#' character in the requestTarget string.
 //   - error: An error if the fragment is not found.  Will be ErrFragmentNotFound if no fragment exists.
 func findFragment(requestTarget string) (Fragment, int, error) {
-	fragment := ""
-
-	fragmentIdx := strings.Index(requestTarget, "#")
-
+	fragmentIdx := strings.IndexByte(requestTarget, '#')
 	if fragmentIdx == -1 {
 		return "", -1, ErrFragmentNotFound
 	}
 
-	for i := fragmentIdx; i < len(requestTarget); i++ {
-		fragment += string(requestTarget[i])
-	}
-
-	return Fragment(fragment), fragmentIdx, nil
+	return Fragment(strings.TrimRight(requestTarget[fragmentIdx:], " \t")), fragmentIdx, nil
 }
 
 // parseFragment validates and parses a fragment.
@@ -149,3 +149,18 @@ func TestFindAndParseFragment(t *testing.T) {
 		})
 	}
 }
+
+func FuzzFindFragment(f *testing.F) {
+	for _, seed := range []string{"/page#section", "/page?q#", "/page# \t", "##", "/page"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, target string) {
+		fragment, idx, err := findFragment(target)
+		if err != nil {
+			return
+		}
+		if !strings.HasPrefix(string(fragment), "#") || !strings.HasPrefix(target[idx:], string(fragment)) {
+			t.Errorf("findFragment(%q) returned %q at %d, not a part of the target", target, fragment, idx)
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package http
+
+import (
+	"crypto/subtle"
+	"sort"
+	"strings"
+)
+
+// Handler writes the response to a request with w.
+type Handler func(w ResponseWriter, req *Request)
+
+// route is a registered handler with the methods it accepts.
+type route struct {
+	handler Handler
+	methods []Method // Empty if the handler accepts any method
+}
+
+// Handle registers a handler for a path. A path ending in a slash also matches
+// every path below it; otherwise only the exact path matches. When several
+// patterns match, the longest wins. Requests without a handler are served from
+// the document root.
+//
+// If methods are given, the server answers requests with other methods with
+// 405 Method Not Allowed, and OPTIONS requests with the methods in the Allow
+// header, unless OPTIONS is one of them. Without methods, the handler gets
+// every request and answers for itself.
+//
+// Handlers run after the server's access rules (country rules, signatures,
+// authentication and bot rules). Handle must be called before Serve.
+func (s *Server) Handle(pattern string, handler Handler, methods ...Method) {
+	if s.handlers == nil {
+		s.handlers = make(map[string]route)
+	}
+	s.handlers[pattern] = route{handler: handler, methods: methods}
+}
+
+// handler returns the route registered for the path, if any.
+func (s *Server) handler(path string) (route, bool) {
+	if r, ok := s.handlers[path]; ok {
+		return r, true
+	}
+
+	var match string
+	for pattern := range s.handlers {
+		if strings.HasSuffix(pattern, "/") && strings.HasPrefix(path, pattern) && len(pattern) > len(match) {
+			match = pattern
+		}
+	}
+	if match == "" {
+		return route{}, false
+	}
+	return s.handlers[match], true
+}
+
+// checkMethod answers a request whose method the route does not accept, with
+// 405 or, for OPTIONS, with the methods it does accept. It reports false for
+// requests that go on to the handler.
+func (r route) checkMethod(req *Request) (Response, bool) {
+	if len(r.methods) == 0 {
+		return Response{}, false
+	}
+	for _, method := range r.methods {
+		if req.GetMethod() == method {
+			return Response{}, false
+		}
+	}
+	if req.GetMethod() == OPTIONS {
+		return optionsResponse(req, r.methods), true
+	}
+	return methodNotAllowed(req, allowHeader(r.methods)), true
+}
+
+// fileMethods are the methods the document root is served with.
+var fileMethods = []Method{GET}
+
+// allowHeader returns the Allow header value for the methods, which always
+// include OPTIONS as the server answers it.
+func allowHeader(methods []Method) string {
+	allow := make([]string, 0, len(methods)+1)
+	hasOptions := false
+	for _, method := range methods {
+		allow = append(allow, string(method))
+		hasOptions = hasOptions || method == OPTIONS
+	}
+	if !hasOptions {
+		allow = append(allow, string(OPTIONS))
+	}
+	return strings.Join(allow, ", ")
+}
+
+// optionsResponse creates the 204 response to an OPTIONS request for a
+// resource accepting the methods.
+func optionsResponse(req *Request, methods []Method) Response {
+	return Response{
+		StartLine: ResponseStartLine{
+			Protocol:   req.GetProtocol(),
+			StatusCode: 204,
+			StatusText: StatusCodeMap[204],
+		},
+		Headers: []Header{{Name: "Allow", Value: allowHeader(methods)}},
+	}
+}
+
+// serverMethods returns the methods accepted anywhere on the server, for
+// OPTIONS *: those of the document root and of every handler registered with
+// methods, in pattern order.
+func (s *Server) serverMethods() []Method {
+	methods := append([]Method{}, fileMethods...)
+	seen := map[Method]bool{}
+	for _, method := range methods {
+		seen[method] = true
+	}
+	patterns := make([]string, 0, len(s.handlers))
+	for pattern := range s.handlers {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		for _, method := range s.handlers[pattern].methods {
+			if !seen[method] {
+				seen[method] = true
+				methods = append(methods, method)
+			}
+		}
+	}
+	return methods
+}
+
+// checkBearer checks that a request carries token as a bearer token. If it
+// does not, it returns the 401 response to send, challenging for realm.
+func checkBearer(req *Request, token, realm string) (Response, bool) {
+	authorization, _ := GetHeader(req.Headers, "Authorization")
+	if subtle.ConstantTimeCompare([]byte(authorization), []byte("Bearer "+token)) != 1 {
+		resp := jsonResponse(req, 401, `{"error":"unauthorized"}`)
+		resp.Headers = append(resp.Headers, Header{Name: "WWW-Authenticate", Value: `Bearer realm="` + realm + `"`})
+		return resp, false
+	}
+	return Response{}, true
+}
@@ -0,0 +1,48 @@
+package http
+
+import (
+	"testing"
+)
+
+func TestHandlerAllow(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.Handle("/api/items/", func(w ResponseWriter, req *Request) {
+		w.WriteHeader(200)
+	}, GET, PUT, DELETE)
+	server.Handle("/hook", func(w ResponseWriter, req *Request) {
+		w.WriteHeader(202)
+	}, POST)
+	server.Handle("/any", func(w ResponseWriter, req *Request) {
+		w.WriteHeader(200)
+	})
+
+	tests := []struct {
+		name      string
+		method    Method
+		path      string
+		wantCode  StatusCode
+		wantAllow string
+	}{
+		{"registered method", PUT, "/api/items/1", 200, ""},
+		{"unregistered method", POST, "/api/items/1", 405, "GET, PUT, DELETE, OPTIONS"},
+		{"options on a handler", OPTIONS, "/api/items/1", 204, "GET, PUT, DELETE, OPTIONS"},
+		{"post only", GET, "/hook", 405, "POST, OPTIONS"},
+		{"handler without methods", DELETE, "/any", 200, ""},
+		{"options on a file", OPTIONS, "/index.html", 204, "GET, OPTIONS"},
+		{"options on the server", OPTIONS, "*", 204, "GET, PUT, DELETE, POST, OPTIONS"},
+		{"file with another method", PUT, "/index.html", 501, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := send(t, server, tt.method, tt.path, "")
+			if resp.GetStatusCode() != tt.wantCode {
+				t.Errorf("Expected status %d, got %d", tt.wantCode, resp.GetStatusCode())
+			}
+			allow, _ := GetHeader(resp.Headers, "Allow")
+			if allow != tt.wantAllow {
+				t.Errorf("Expected Allow %q, got %q", tt.wantAllow, allow)
+			}
+		})
+	}
+}
@@ -27,6 +27,11 @@ func parseHeader(header string) (Header, error) {
 		return Header{}, fmt.Errorf("invalid header format: missing colon")
 	}
 
+	// RFC 7230 section 3.2.4 forbids whitespace between the field name and the colon
+	if firstColonIdx > 0 && (header[firstColonIdx-1] == ' ' || header[firstColonIdx-1] == '\t') {
+		return Header{}, fmt.Errorf("invalid header format: whitespace before colon")
+	}
+
 	name := strings.TrimSpace(header[:firstColonIdx])
 	value := strings.TrimSpace(header[firstColonIdx+1:])
 
@@ -44,3 +49,101 @@ func parseHeader(header string) (Header, error) {
 		Value: value,
 	}, nil
 }
+
+// GetHeader returns the value of the first header with the given name.
+// Header names are compared case-insensitively.
+func GetHeader(headers []Header, name string) (string, bool) {
+	for _, h := range headers {
+		if strings.EqualFold(h.Name, name) {
+			return h.Value, true
+		}
+	}
+	return "", false
+}
+
+// removeHeader returns headers without the headers with the given name.
+func removeHeader(headers []Header, name string) []Header {
+	kept := headers[:0:0]
+	for _, h := range headers {
+		if !strings.EqualFold(h.Name, name) {
+			kept = append(kept, h)
+		}
+	}
+	return kept
+}
+
+// singleValueHeaders are the response header fields, in lower case, that may
+// appear only once: a second value does not add to the first one but
+// contradicts it, and clients pick either.
+var singleValueHeaders = map[string]bool{
+	"access-control-allow-origin": true,
+	"age":                         true,
+	"content-disposition":         true,
+	"content-encoding":            true,
+	"content-length":              true,
+	"content-location":            true,
+	"content-range":               true,
+	"content-type":                true,
+	"date":                        true,
+	"etag":                        true,
+	"expires":                     true,
+	"last-modified":               true,
+	"location":                    true,
+	"retry-after":                 true,
+	"server":                      true,
+}
+
+// setHeader returns headers with the header with the given name set to value.
+// The first header with the name keeps its position and later ones are
+// dropped; without one, the header is added at the end. headers is not
+// modified.
+func setHeader(headers []Header, name, value string) []Header {
+	result := make([]Header, 0, len(headers)+1)
+	set := false
+	for _, h := range headers {
+		if !strings.EqualFold(h.Name, name) {
+			result = append(result, h)
+		} else if !set {
+			result = append(result, Header{Name: name, Value: value})
+			set = true
+		}
+	}
+	if !set {
+		result = append(result, Header{Name: name, Value: value})
+	}
+	return result
+}
+
+// addHeader adds header to headers. Layers that add to a response, such as
+// location headers on top of a file, go through addHeader: a field that may
+// appear only once replaces the value set before, and others are appended.
+func addHeader(headers []Header, header Header) []Header {
+	if singleValueHeaders[strings.ToLower(header.Name)] {
+		return setHeader(headers, header.Name, header.Value)
+	}
+	return append(headers, header)
+}
+
+// mergeHeaders adds each of extra to headers with addHeader.
+func mergeHeaders(headers []Header, extra ...Header) []Header {
+	for _, header := range extra {
+		headers = addHeader(headers, header)
+	}
+	return headers
+}
+
+// hasDuplicateHeaders reports whether a field that may appear only once
+// appears more than once in headers.
+func hasDuplicateHeaders(headers []Header) bool {
+	for i, h := range headers {
+		if !singleValueHeaders[strings.ToLower(h.Name)] {
+			continue
+		}
+		for _, other := range headers[i+1:] {
+			if strings.EqualFold(h.Name, other.Name) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -77,3 +78,84 @@ func TestParseHeader(t *testing.T) {
 		})
 	}
 }
+
+func TestAddHeader(t *testing.T) {
+	base := []Header{
+		{Name: "Content-Type", Value: "text/html"},
+		{Name: "Vary", Value: "Accept"},
+		{Name: "Content-Length", Value: "5"},
+	}
+
+	tests := []struct {
+		name     string
+		header   Header
+		expected []Header
+	}{
+		{"replaces a single value in place", Header{Name: "content-type", Value: "text/plain"}, []Header{
+			{Name: "content-type", Value: "text/plain"},
+			{Name: "Vary", Value: "Accept"},
+			{Name: "Content-Length", Value: "5"},
+		}},
+		{"appends a list value", Header{Name: "Vary", Value: "Cookie"}, []Header{
+			{Name: "Content-Type", Value: "text/html"},
+			{Name: "Vary", Value: "Accept"},
+			{Name: "Content-Length", Value: "5"},
+			{Name: "Vary", Value: "Cookie"},
+		}},
+		{"appends a new single value", Header{Name: "ETag", Value: `"abc"`}, []Header{
+			{Name: "Content-Type", Value: "text/html"},
+			{Name: "Vary", Value: "Accept"},
+			{Name: "Content-Length", Value: "5"},
+			{Name: "ETag", Value: `"abc"`},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := append([]Header(nil), base...)
+			result := addHeader(headers, tt.header)
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+			if !reflect.DeepEqual(headers, base) {
+				t.Errorf("Expected the headers to be unchanged, got %v", headers)
+			}
+		})
+	}
+}
+
+func TestSetHeaderDropsDuplicates(t *testing.T) {
+	headers := []Header{
+		{Name: "Content-Length", Value: "50"},
+		{Name: "Content-Type", Value: "text/plain"},
+		{Name: "content-length", Value: "60"},
+	}
+	expected := []Header{
+		{Name: "Content-Length", Value: "5"},
+		{Name: "Content-Type", Value: "text/plain"},
+	}
+	if result := setHeader(headers, "Content-Length", "5"); !reflect.DeepEqual(result, expected) {
+		t.Errorf("Expected %v, got %v", expected, result)
+	}
+}
+
+func TestHasDuplicateHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  []Header
+		expected bool
+	}{
+		{"none", nil, false},
+		{"distinct", []Header{{Name: "Content-Type", Value: "a"}, {Name: "Content-Length", Value: "1"}}, false},
+		{"list values", []Header{{Name: "Set-Cookie", Value: "a=1"}, {Name: "Set-Cookie", Value: "b=2"}}, false},
+		{"single values", []Header{{Name: "Content-Type", Value: "a"}, {Name: "content-type", Value: "b"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := hasDuplicateHeaders(tt.headers); result != tt.expected {
+				t.Errorf("Expected %v, got %v", tt.expected, result)
+			}
+		})
+	}
+}
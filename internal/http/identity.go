@@ -0,0 +1,23 @@
+package http
+
+// ServerHeader returns the value of the Server header of responses: the
+// configured server.server_header, followed by the version with
+// server.expose_version. It is empty if the header is not sent.
+func (s *Server) ServerHeader() string {
+	name := s.Config.Server.ServerHeader
+	if name == "" || !s.Config.Server.ExposeVersion || s.Version == "" {
+		return name
+	}
+	return name + "/" + s.Version
+}
+
+// setServerHeader replaces the Server headers set by handlers or plugins with
+// value, or removes them if value is empty, so that the configuration alone
+// decides how the server identifies itself.
+func setServerHeader(headers []Header, value string) []Header {
+	headers = removeHeader(headers, "Server")
+	if value == "" {
+		return headers
+	}
+	return append(headers, Header{Name: "Server", Value: value})
+}
@@ -0,0 +1,71 @@
+package http
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestServerHeader(t *testing.T) {
+	tests := []struct {
+		name          string
+		header        string
+		exposeVersion bool
+		version       string
+		expected      string
+	}{
+		{"omitted by default", "", false, "1.4.0", ""},
+		{"name", "volk", false, "1.4.0", "volk"},
+		{"version", "volk", true, "1.4.0", "volk/1.4.0"},
+		{"custom name", "web", true, "1.4.0", "web/1.4.0"},
+		{"version without a name", "", true, "1.4.0", ""},
+		{"unknown version", "volk", true, "", "volk"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, func(cfg *config.Config) {
+				cfg.Server.ServerHeader = tt.header
+				cfg.Server.ExposeVersion = tt.exposeVersion
+			})
+			server.Version = tt.version
+			// Handlers cannot identify the server differently.
+			server.Handle("/whole", ResponseHandler(func(req *Request) Response {
+				resp := newTextResponse(req.GetProtocol(), 200, "whole")
+				resp.Headers = append(resp.Headers, Header{Name: "Server", Value: "Apache/2.4.1"})
+				return resp
+			}))
+			server.Handle("/streamed", func(w ResponseWriter, req *Request) {
+				w.AddHeader("Server", "Apache/2.4.1")
+				w.Write([]byte("streamed"))
+				w.Flush()
+			})
+
+			for _, path := range []string{"/index.html", "/whole", "/streamed"} {
+				resp := get(t, server, path)
+				value, ok := GetHeader(resp.Headers, "Server")
+				if tt.expected == "" && ok {
+					t.Errorf("%s: Expected no Server header, got %q", path, value)
+				}
+				if tt.expected != "" && value != tt.expected {
+					t.Errorf("%s: Expected Server %q, got %q", path, tt.expected, value)
+				}
+				if count := countHeaders(resp.Headers, "Server"); count > 1 {
+					t.Errorf("%s: Expected a single Server header, got %d", path, count)
+				}
+			}
+		})
+	}
+}
+
+// countHeaders returns the number of headers with the name.
+func countHeaders(headers []Header, name string) int {
+	count := 0
+	for _, h := range headers {
+		if strings.EqualFold(h.Name, name) {
+			count++
+		}
+	}
+	return count
+}
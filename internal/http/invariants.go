@@ -0,0 +1,66 @@
+package http
+
+import (
+	"log"
+	"strconv"
+)
+
+// enforceInvariants makes a response that is sent whole frame its body
+// correctly, whatever the handler that produced it did:
+//
+//   - Header fields that may appear only once, such as Content-Type, do;
+//     the last value given is kept.
+//   - 1xx, 204 and 304 responses have no body, and 1xx and 204 responses no
+//     Content-Length.
+//   - Responses to HEAD have no body. Handlers may answer HEAD like GET; the
+//     body is dropped and its length kept as the Content-Length.
+//   - Other responses have no Transfer-Encoding, as the body is not sent
+//     chunked, and a Content-Length, if any, matching their body. Without
+//     one, closing the connection ends the body.
+//
+// Violations a handler should not commit are logged, so the handler can be fixed.
+func enforceInvariants(req *Request, resp Response) Response {
+	status := resp.GetStatusCode()
+	violation := func(message string) {
+		log.Printf("Fixed response %d to %s %s: %s", status, req.GetMethod(), req.GetRequestTarget().Path, message)
+	}
+
+	if hasDuplicateHeaders(resp.Headers) {
+		violation("duplicate headers that may appear only once, the last value is kept")
+		resp.Headers = mergeHeaders(nil, resp.Headers...)
+	}
+
+	if _, ok := GetHeader(resp.Headers, "Transfer-Encoding"); ok {
+		violation("Transfer-Encoding on a response that is not streamed")
+		resp.Headers = removeHeader(resp.Headers, "Transfer-Encoding")
+	}
+
+	noBody := status < 200 || status == 204 || status == 304
+	if noBody {
+		if resp.Body != "" {
+			violation("body on a response that cannot have one")
+			resp.Body = ""
+		}
+		if _, ok := GetHeader(resp.Headers, "Content-Length"); ok && status != 304 {
+			violation("Content-Length on a response without a body")
+			resp.Headers = removeHeader(resp.Headers, "Content-Length")
+		}
+		return resp
+	}
+
+	length := strconv.Itoa(len(resp.Body))
+	contentLength, ok := GetHeader(resp.Headers, "Content-Length")
+	if req.GetMethod() == HEAD {
+		if !ok && resp.Body != "" {
+			resp.Headers = append(resp.Headers, Header{Name: "Content-Length", Value: length})
+		}
+		resp.Body = ""
+		return resp
+	}
+
+	if ok && contentLength != length {
+		violation("Content-Length " + contentLength + " for a body of " + length + " bytes")
+		resp.Headers = setHeader(resp.Headers, "Content-Length", length)
+	}
+	return resp
+}
@@ -0,0 +1,86 @@
+package http
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestEnforceInvariants(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	tests := []struct {
+		name              string
+		method            Method
+		status            StatusCode
+		headers           []Header
+		body              string
+		wantBody          string
+		wantContentLength string
+		wantLogged        bool
+	}{
+		{"ordinary", GET, 200, nil, "hello", "hello", "", false},
+		{"matching length", GET, 200, []Header{{Name: "Content-Length", Value: "5"}}, "hello", "hello", "5", false},
+		{"wrong length", GET, 200, []Header{{Name: "Content-Length", Value: "50"}}, "hello", "hello", "5", true},
+		{"duplicate length", GET, 200, []Header{{Name: "Content-Length", Value: "50"}, {Name: "Content-Length", Value: "5"}}, "hello", "hello", "5", true},
+		{"duplicate type", GET, 200, []Header{{Name: "Content-Type", Value: "text/html"}}, "hello", "hello", "", true},
+		{"transfer encoding", GET, 200, []Header{{Name: "Transfer-Encoding", Value: "chunked"}}, "hello", "hello", "", true},
+		{"HEAD answered like GET", HEAD, 200, nil, "hello", "", "5", false},
+		{"HEAD keeps the GET length", HEAD, 200, []Header{{Name: "Content-Length", Value: "500"}}, "", "", "500", false},
+		{"204 with body", PUT, 204, []Header{{Name: "Content-Length", Value: "5"}}, "hello", "", "", true},
+		{"304 with body", GET, 304, nil, "hello", "", "", true},
+		{"304 keeps the length", GET, 304, []Header{{Name: "Content-Length", Value: "500"}}, "", "", "500", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.Reset()
+			req, err := NewRequest(string(tt.method) + " /doc HTTP/1.1\r\nHost: localhost\r\n\r\n")
+			if err != nil {
+				t.Fatalf("could not parse request: %v", err)
+			}
+			resp := newTextResponse(HTTP1_1, tt.status, tt.body)
+			resp.Headers = append(resp.Headers, tt.headers...)
+
+			resp = enforceInvariants(&req, resp)
+			if resp.GetBody() != tt.wantBody {
+				t.Errorf("Expected body %q, got %q", tt.wantBody, resp.GetBody())
+			}
+			if contentLength, _ := GetHeader(resp.Headers, "Content-Length"); contentLength != tt.wantContentLength {
+				t.Errorf("Expected Content-Length %q, got %q", tt.wantContentLength, contentLength)
+			}
+			if _, ok := GetHeader(resp.Headers, "Transfer-Encoding"); ok {
+				t.Error("Expected no Transfer-Encoding")
+			}
+			if logged := strings.Contains(logs.String(), "Fixed response"); logged != tt.wantLogged {
+				t.Errorf("Expected logged %v, got %q", tt.wantLogged, logs.String())
+			}
+		})
+	}
+}
+
+func TestInvariantsOnTheWire(t *testing.T) {
+	log.SetOutput(&bytes.Buffer{})
+	defer log.SetOutput(os.Stderr)
+
+	server := newTestServer(t, nil)
+	server.Handle("/created", ResponseHandler(func(req *Request) Response {
+		return newTextResponse(req.GetProtocol(), 204, "done")
+	}))
+	server.Handle("/page", ResponseHandler(func(req *Request) Response {
+		return newTextResponse(req.GetProtocol(), 200, "page")
+	}), GET, HEAD)
+
+	response := string(exchange(server, []byte("GET /created HTTP/1.1\r\nHost: localhost\r\n\r\n")))
+	if !strings.HasSuffix(response, "\r\n\r\n") {
+		t.Errorf("Expected a 204 without a body, got %q", response)
+	}
+	response = string(exchange(server, []byte("HEAD /page HTTP/1.1\r\nHost: localhost\r\n\r\n")))
+	if !strings.HasSuffix(response, "Content-Length: 4\r\n\r\n") {
+		t.Errorf("Expected a HEAD response with the GET length and no body, got %q", response)
+	}
+}
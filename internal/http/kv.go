@@ -0,0 +1,119 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+
+	"github.com/awaisamjad/volk/internal/kv"
+)
+
+// kvMethods are the methods the key-value API accepts. Listing the keys
+// accepts GET and HEAD only.
+var kvMethods = []Method{GET, HEAD, PUT, DELETE}
+
+// kvHandler serves the key-value JSON API below prefix:
+//
+//	GET    prefix        list the keys
+//	GET    prefix<key>   read a value (If-None-Match supported)
+//	PUT    prefix<key>   create or replace a value (If-Match and If-None-Match supported)
+//	DELETE prefix<key>   remove a value (If-Match supported)
+func kvHandler(store *kv.Store, prefix string) ResponseFunc {
+	return func(req *Request) Response {
+		key := strings.TrimPrefix(req.GetRequestTarget().Path, prefix)
+
+		if key == "" {
+			if req.GetMethod() != GET && req.GetMethod() != HEAD {
+				return methodNotAllowed(req, allowHeader([]Method{GET, HEAD}))
+			}
+			keys, err := store.Keys()
+			if err != nil {
+				return kvError(req, err)
+			}
+			body, _ := json.Marshal(keys)
+			return jsonResponse(req, 200, string(body))
+		}
+
+		precondition := kv.Precondition{}
+		precondition.IfMatch, _ = GetHeader(req.Headers, "If-Match")
+		precondition.IfNoneMatch, _ = GetHeader(req.Headers, "If-None-Match")
+
+		switch req.GetMethod() {
+		case GET, HEAD:
+			value, etag, err := store.Get(key)
+			if err != nil {
+				return kvError(req, err)
+			}
+			if precondition.IfNoneMatch == etag || precondition.IfNoneMatch == "*" {
+				resp := newTextResponse(req.GetProtocol(), 304, "")
+				resp.Headers = []Header{{Name: "ETag", Value: etag}}
+				return resp
+			}
+			resp := jsonResponse(req, 200, string(value))
+			resp.Headers = append(resp.Headers, Header{Name: "ETag", Value: etag})
+			return resp
+
+		case PUT:
+			etag, created, err := store.Put(key, []byte(req.GetBody()), precondition)
+			if err != nil {
+				return kvError(req, err)
+			}
+			status := StatusCode(204)
+			if created {
+				status = 201
+			}
+			resp := newTextResponse(req.GetProtocol(), status, "")
+			resp.Headers = []Header{{Name: "ETag", Value: etag}}
+			return resp
+
+		case DELETE:
+			if err := store.Delete(key, precondition); err != nil {
+				return kvError(req, err)
+			}
+			resp := newTextResponse(req.GetProtocol(), 204, "")
+			resp.Headers = nil
+			return resp
+
+		default:
+			return methodNotAllowed(req, allowHeader(kvMethods))
+		}
+	}
+}
+
+// jsonResponse creates a response with a JSON body.
+func jsonResponse(req *Request, status StatusCode, body string) Response {
+	resp := newTextResponse(req.GetProtocol(), status, body)
+	resp.Headers = []Header{{Name: "Content-Type", Value: "application/json"}}
+	if req.GetMethod() == HEAD {
+		resp.Body = ""
+	}
+	return resp
+}
+
+// methodNotAllowed creates a 405 response listing the allowed methods.
+func methodNotAllowed(req *Request, allow string) Response {
+	resp := newTextResponse(req.GetProtocol(), 405, "405 Method Not Allowed")
+	resp.Headers = append(resp.Headers, Header{Name: "Allow", Value: allow})
+	return resp
+}
+
+// kvError maps a store error to a JSON error response.
+func kvError(req *Request, err error) Response {
+	var status StatusCode
+	switch {
+	case errors.Is(err, kv.ErrNotFound):
+		status = 404
+	case errors.Is(err, kv.ErrInvalidJSON):
+		status = 400
+	case errors.Is(err, kv.ErrPreconditionFailed):
+		status = 412
+	case errors.Is(err, kv.ErrQuotaExceeded):
+		status = 507
+	default:
+		log.Printf("Error accessing kv store: %v", err)
+		status = 500
+	}
+	body, _ := json.Marshal(map[string]string{"error": err.Error()})
+	return jsonResponse(req, status, string(body))
+}
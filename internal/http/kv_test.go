@@ -0,0 +1,79 @@
+package http
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// send writes a request with the method, path, extra header lines and body to the server and parses the response.
+func send(t *testing.T, server *Server, method Method, path, body string, headers ...string) Response {
+	t.Helper()
+
+	request := string(method) + " " + path + " HTTP/1.1\r\nHost: localhost\r\n"
+	for _, header := range headers {
+		request += header + "\r\n"
+	}
+	if body != "" {
+		request += "Content-Length: " + strconv.Itoa(len(body)) + "\r\n"
+	}
+	request += "\r\n" + body
+
+	resp, err := exchangeResponse(server, []byte(request))
+	if err != nil {
+		t.Fatalf("could not parse response: %v", err)
+	}
+	return resp
+}
+
+func TestKVAPI(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.KV = config.KVConfig{Enabled: true, Database: filepath.Join(t.TempDir(), "kv.db"), Path: "/api/kv"}
+	})
+	defer server.Close()
+
+	steps := []struct {
+		name    string
+		method  Method
+		path    string
+		body    string
+		headers []string
+		status  StatusCode
+		resBody string
+	}{
+		{"missing key", GET, "/api/kv/todo", "", nil, 404, ""},
+		{"create", PUT, "/api/kv/todo", `{"done":false}`, nil, 201, ""},
+		{"read", GET, "/api/kv/todo", "", nil, 200, `{"done":false}`},
+		{"not modified", GET, "/api/kv/todo", "", []string{`If-None-Match: "ETAG"`}, 304, ""},
+		{"invalid JSON", PUT, "/api/kv/todo", `{"done":`, nil, 400, ""},
+		{"stale If-Match", PUT, "/api/kv/todo", `{"done":true}`, []string{`If-Match: "stale"`}, 412, ""},
+		{"replace", PUT, "/api/kv/todo", `{"done":true}`, []string{`If-Match: "ETAG"`}, 204, ""},
+		{"list", GET, "/api/kv/", "", nil, 200, `["todo"]`},
+		{"method not allowed", POST, "/api/kv/todo", `{}`, nil, 405, ""},
+		{"delete", DELETE, "/api/kv/todo", "", nil, 204, ""},
+		{"deleted", GET, "/api/kv/todo", "", nil, 404, ""},
+	}
+
+	etag := ""
+	for _, step := range steps {
+		// "ETAG" stands for the ETag of the previous response.
+		headers := make([]string, len(step.headers))
+		for i, header := range step.headers {
+			headers[i] = strings.ReplaceAll(header, `"ETAG"`, etag)
+		}
+
+		resp := send(t, server, step.method, step.path, step.body, headers...)
+		if resp.GetStatusCode() != step.status {
+			t.Fatalf("%s: expected status %d, got %d %q", step.name, step.status, resp.GetStatusCode(), resp.GetBody())
+		}
+		if step.resBody != "" && resp.GetBody() != step.resBody {
+			t.Errorf("%s: expected body %s, got %s", step.name, step.resBody, resp.GetBody())
+		}
+		if value, ok := GetHeader(resp.Headers, "ETag"); ok {
+			etag = value
+		}
+	}
+}
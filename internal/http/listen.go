@@ -0,0 +1,65 @@
+package http
+
+import (
+	"context"
+	"log"
+	"net"
+	"time"
+)
+
+// Listen opens a listener on the configured address with the socket options
+// of the [server] section: SO_REUSEPORT, keep-alive probes and the backlog.
+func (s *Server) Listen() (net.Listener, error) {
+	cfg := s.Config.Server
+
+	lc := net.ListenConfig{KeepAliveConfig: net.KeepAliveConfig{
+		Enable:   cfg.KeepAlive >= 0,
+		Idle:     time.Duration(cfg.KeepAlive) * time.Second,
+		Interval: time.Duration(cfg.KeepAliveInterval) * time.Second,
+		Count:    cfg.KeepAliveCount,
+	}}
+	if cfg.KeepAlive < 0 {
+		lc.KeepAlive = -1
+	}
+	if cfg.ReusePort {
+		if err := reusePort(&lc); err != nil {
+			return nil, err
+		}
+	}
+
+	ln, err := lc.Listen(context.Background(), "tcp", s.Addr())
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Backlog > 0 {
+		if err := setBacklog(ln, cfg.Backlog); err != nil {
+			ln.Close()
+			return nil, err
+		}
+	}
+	return ln, nil
+}
+
+// tuneConn applies the per-connection socket options of the [server] section
+// to an accepted connection. Options the platform rejects are logged.
+func (s *Server) tuneConn(conn net.Conn) {
+	tcp, ok := conn.(*net.TCPConn)
+	if !ok {
+		return
+	}
+	cfg := s.Config.Server
+
+	if err := tcp.SetNoDelay(cfg.TCPNoDelay); err != nil {
+		log.Printf("Error setting TCP_NODELAY: %v", err)
+	}
+	if cfg.ReadBuffer > 0 {
+		if err := tcp.SetReadBuffer(cfg.ReadBuffer); err != nil {
+			log.Printf("Error setting read buffer: %v", err)
+		}
+	}
+	if cfg.WriteBuffer > 0 {
+		if err := tcp.SetWriteBuffer(cfg.WriteBuffer); err != nil {
+			log.Printf("Error setting write buffer: %v", err)
+		}
+	}
+}
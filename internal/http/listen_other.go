@@ -0,0 +1,18 @@
+//go:build !unix
+
+package http
+
+import (
+	"errors"
+	"net"
+)
+
+// reusePort returns an error: SO_REUSEPORT is not available on this platform.
+func reusePort(lc *net.ListenConfig) error {
+	return errors.New("reuse_port is not supported on this platform")
+}
+
+// setBacklog returns an error: the backlog cannot be changed on this platform.
+func setBacklog(ln net.Listener, backlog int) error {
+	return errors.New("backlog is not supported on this platform")
+}
@@ -0,0 +1,53 @@
+//go:build unix
+
+package http
+
+import (
+	"fmt"
+	"net"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// reusePort makes the listener created with lc set SO_REUSEPORT, so that
+// several processes can listen on the same port and the kernel spreads
+// connections between them.
+func reusePort(lc *net.ListenConfig) error {
+	lc.Control = func(network, address string, c syscall.RawConn) error {
+		var sockErr error
+		err := c.Control(func(fd uintptr) {
+			sockErr = unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_REUSEPORT, 1)
+		})
+		if err != nil {
+			return err
+		}
+		return sockErr
+	}
+	return nil
+}
+
+// setBacklog changes the length of the queue of pending connections of a
+// listening socket by calling listen again, which updates the backlog.
+// The kernel caps it at its own limit, such as net.core.somaxconn on Linux.
+func setBacklog(ln net.Listener, backlog int) error {
+	sc, ok := ln.(syscall.Conn)
+	if !ok {
+		return fmt.Errorf("cannot set backlog on listener of type %T", ln)
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return fmt.Errorf("error setting backlog: %w", err)
+	}
+	var listenErr error
+	err = raw.Control(func(fd uintptr) {
+		listenErr = unix.Listen(int(fd), backlog)
+	})
+	if err == nil {
+		err = listenErr
+	}
+	if err != nil {
+		return fmt.Errorf("error setting backlog: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+//go:build unix
+
+package http
+
+import (
+	"net"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+	"golang.org/x/sys/unix"
+)
+
+func TestListenReusePort(t *testing.T) {
+	listen := func(port int, reusePort bool) (net.Listener, error) {
+		server := newTestServer(t, func(cfg *config.Config) {
+			cfg.Server.Host = "127.0.0.1"
+			cfg.Server.Port = port
+			cfg.Server.ReusePort = reusePort
+		})
+		return server.Listen()
+	}
+
+	first, err := listen(0, true)
+	if err != nil {
+		t.Fatalf("Listen returned an error: %v", err)
+	}
+	defer first.Close()
+	port := first.Addr().(*net.TCPAddr).Port
+
+	second, err := listen(port, true)
+	if err != nil {
+		t.Fatalf("Expected a second listener with reuse_port on port %d, got %v", port, err)
+	}
+	second.Close()
+
+	if third, err := listen(port, false); err == nil {
+		third.Close()
+		t.Errorf("Expected listening without reuse_port on port %d to fail", port)
+	}
+}
+
+func TestListenSocketOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		noDelay   bool
+		keepAlive int
+		wantDelay int
+		wantAlive int
+	}{
+		{"defaults", true, 0, 1, 1},
+		{"nagle and no keep-alive", false, -1, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newTestServer(t, func(cfg *config.Config) {
+				cfg.Server.Host = "127.0.0.1"
+				cfg.Server.Port = 0
+				cfg.Server.TCPNoDelay = tt.noDelay
+				cfg.Server.KeepAlive = tt.keepAlive
+				cfg.Server.ReadBuffer = 64 << 10
+				cfg.Server.Backlog = 16
+			})
+			ln, err := server.Listen()
+			if err != nil {
+				t.Fatalf("Listen returned an error: %v", err)
+			}
+			defer ln.Close()
+
+			client, err := net.Dial("tcp", ln.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer client.Close()
+			conn, err := ln.Accept()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			server.tuneConn(conn)
+
+			raw, err := conn.(*net.TCPConn).SyscallConn()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var noDelay, keepAlive, readBuffer int
+			raw.Control(func(fd uintptr) {
+				noDelay, _ = unix.GetsockoptInt(int(fd), unix.IPPROTO_TCP, unix.TCP_NODELAY)
+				keepAlive, _ = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_KEEPALIVE)
+				readBuffer, _ = unix.GetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_RCVBUF)
+			})
+			if (noDelay != 0) != (tt.wantDelay != 0) {
+				t.Errorf("Expected TCP_NODELAY %d, got %d", tt.wantDelay, noDelay)
+			}
+			if (keepAlive != 0) != (tt.wantAlive != 0) {
+				t.Errorf("Expected SO_KEEPALIVE %d, got %d", tt.wantAlive, keepAlive)
+			}
+			if readBuffer < 64<<10 {
+				t.Errorf("Expected SO_RCVBUF of at least %d, got %d", 64<<10, readBuffer)
+			}
+		})
+	}
+}
@@ -1,11 +1,23 @@
 // Package http implements a simple HTTP server and related utilities.
 package http
 
-// Response generates an HTTP response based on the request method
+import "github.com/awaisamjad/volk/config"
+
+// Response generates an HTTP response based on the request method,
+// serving files with DefaultFileServer
 func (rq *Request) Response() Response {
+	if DefaultFileServer == nil {
+		DefaultFileServer = NewFileServer(config.DefaultConfig().FileServer)
+	}
+	return rq.ResponseWith(DefaultFileServer)
+}
+
+// ResponseWith generates an HTTP response based on the request method,
+// serving files with the given FileServer
+func (rq *Request) ResponseWith(fs *FileServer) Response {
 	switch rq.GetMethod() {
 	case GET:
-		return rq.GET()
+		return rq.get(fs)
 	default:
 		return Response{
 			StartLine: ResponseStartLine{
@@ -21,8 +33,16 @@ func (rq *Request) Response() Response {
 	}
 }
 
-// GET handles GET requests
+// GET handles GET requests using DefaultFileServer
 func (rq *Request) GET() Response {
+	if DefaultFileServer == nil {
+		DefaultFileServer = NewFileServer(config.DefaultConfig().FileServer)
+	}
+	return rq.get(DefaultFileServer)
+}
+
+// get handles GET requests using the given FileServer
+func (rq *Request) get(fs *FileServer) Response {
 	path := rq.GetRequestTarget()
 	pathStr := path.String()
 	switch pathStr {
@@ -55,5 +75,5 @@ func (rq *Request) GET() Response {
 			}
 		}
 	}
-	return DefaultFileServer.ServeFile(rq)
-}
\ No newline at end of file
+	return fs.ServeFile(rq)
+}
@@ -0,0 +1,46 @@
+package http
+
+import (
+	"strings"
+
+	"github.com/awaisamjad/volk/internal/metrics"
+)
+
+// metricsHandler serves the metrics of the server, in the OpenMetrics format
+// when the Accept header asks for it and in the Prometheus text format
+// otherwise. With a token, it needs the token as a bearer token.
+func metricsHandler(s *Server, token string) ResponseFunc {
+	return func(req *Request) Response {
+		if token != "" {
+			if resp, ok := checkBearer(req, token, "metrics"); !ok {
+				return resp
+			}
+		}
+
+		req.Vary("Accept")
+		accept, _ := GetHeader(req.Headers, "Accept")
+		openMetrics := strings.Contains(accept, "application/openmetrics-text")
+		contentType := metrics.PrometheusType
+		if openMetrics {
+			contentType = metrics.OpenMetricsType
+		}
+
+		var body strings.Builder
+		// The scrape itself is in flight; it is not counted.
+		if err := s.Metrics.Write(&body, openMetrics, max(s.InFlight()-1, 0)); err != nil {
+			return newTextResponse(req.GetProtocol(), 500, "500 Internal Server Error")
+		}
+		return Response{
+			StartLine: ResponseStartLine{
+				Protocol:   req.GetProtocol(),
+				StatusCode: 200,
+				StatusText: StatusCodeMap[200],
+			},
+			Headers: []Header{
+				{Name: "Content-Type", Value: contentType},
+				{Name: "Cache-Control", Value: "no-store"},
+			},
+			Body: body.String(),
+		}
+	}
+}
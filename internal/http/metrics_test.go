@@ -0,0 +1,128 @@
+package http
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+	"github.com/awaisamjad/volk/internal/metrics"
+)
+
+func TestMetricsEndpoint(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Metrics.Enabled = true
+		cfg.Metrics.Token = "secret"
+		cfg.Metrics.Buckets = []float64{0.5, 30}
+		cfg.Metrics.Disable = []string{metrics.InFlight}
+	})
+	get(t, server, "/index.html")
+	get(t, server, "/missing.html")
+
+	if resp := get(t, server, "/metrics"); resp.GetStatusCode() != 401 {
+		t.Errorf("Expected status 401 without the token, got %d", resp.GetStatusCode())
+	}
+
+	resp := get(t, server, "/metrics", "Authorization: Bearer secret")
+	if contentType, _ := GetHeader(resp.Headers, "Content-Type"); contentType != metrics.PrometheusType {
+		t.Errorf("Expected Content-Type %s, got %s", metrics.PrometheusType, contentType)
+	}
+	body := resp.GetBody()
+	for _, line := range []string{
+		`volk_requests_total{code="200",method="GET"} 1`,
+		`volk_requests_total{code="404",method="GET"} 1`,
+		`volk_requests_total{code="401",method="GET"} 1`,
+		`volk_request_duration_seconds_bucket{le="30.0"} 3`,
+		`volk_request_duration_seconds_count 3`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected the line %s in:\n%s", line, body)
+		}
+	}
+	if strings.Contains(body, metrics.InFlight) {
+		t.Errorf("Expected the disabled family %s to be left out", metrics.InFlight)
+	}
+
+	resp = get(t, server, "/metrics", "Authorization: Bearer secret", "Accept: application/openmetrics-text; version=1.0.0")
+	if contentType, _ := GetHeader(resp.Headers, "Content-Type"); contentType != metrics.OpenMetricsType {
+		t.Errorf("Expected Content-Type %s, got %s", metrics.OpenMetricsType, contentType)
+	}
+	if !strings.HasSuffix(resp.GetBody(), "# EOF\n") {
+		t.Errorf("Expected the OpenMetrics body to end with # EOF, got %q", resp.GetBody())
+	}
+}
+
+func TestMetricsInvalidBuckets(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Metrics.Enabled = true
+	cfg.Metrics.Buckets = []float64{1, 0.5}
+	if _, err := NewServer(cfg); err == nil {
+		t.Errorf("Expected an error for descending buckets")
+	}
+}
+
+func TestSummary(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Summary.Enabled = true
+	})
+	get(t, server, "/index.html")
+	get(t, server, "/index.html")
+	get(t, server, "/missing.html")
+
+	summary := server.Summary.Summary()
+	if summary.Requests != 3 {
+		t.Errorf("Expected 3 requests, got %d", summary.Requests)
+	}
+	if summary.Statuses[200] != 2 || summary.Statuses[404] != 1 {
+		t.Errorf("Expected 2 200s and 1 404, got %v", summary.Statuses)
+	}
+	if len(summary.Top) == 0 || summary.Top[0].Path != "/index.html" {
+		t.Errorf("Expected /index.html as the busiest path, got %v", summary.Top)
+	}
+}
+
+func TestMetricsBytesByHostAndLocation(t *testing.T) {
+	root := t.TempDir()
+	page := strings.Repeat("<p>volk</p>", 200)
+	if err := os.MkdirAll(filepath.Join(root, "docs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "docs", "index.html"), []byte(page), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.FileServer.DocumentRoot = root
+		cfg.Compression.Enabled = true
+		cfg.Metrics.Enabled = true
+		cfg.Metrics.Hosts = []string{"example.com"}
+		cfg.Locations = []config.LocationConfig{{Path: "/docs/"}}
+	})
+
+	resp, err := exchangeResponse(server, []byte("GET /docs/index.html HTTP/1.1\r\nHost: example.com\r\nAccept-Encoding: gzip\r\n\r\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encoding, _ := GetHeader(resp.Headers, "Content-Encoding"); encoding != "gzip" {
+		t.Fatalf("Expected a compressed response, got headers %v", resp.Headers)
+	}
+	compressed := len(resp.GetBody())
+	if _, err := exchangeResponse(server, []byte("GET /docs/index.html HTTP/1.1\r\nHost: attacker.example\r\n\r\n")); err != nil {
+		t.Fatal(err)
+	}
+
+	body := get(t, server, "/metrics").GetBody()
+	for _, line := range []string{
+		fmt.Sprintf(`volk_compression_input_bytes_total{host="example.com",location="/docs/"} %d`, len(page)),
+		fmt.Sprintf(`volk_compression_output_bytes_total{host="example.com",location="/docs/"} %d`, compressed),
+		`volk_compression_input_bytes_total{host="other",location="/docs/"} 0`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("Expected the line %s in:\n%s", line, body)
+		}
+	}
+	if strings.Contains(body, "attacker.example") {
+		t.Errorf("Expected hosts that are not configured to be counted as other, got:\n%s", body)
+	}
+}
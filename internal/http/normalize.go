@@ -0,0 +1,190 @@
+package http
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/idna"
+)
+
+var (
+	// ErrInvalidPercentEncoding is returned for a path with a % not followed by two hex digits.
+	ErrInvalidPercentEncoding = errors.New("path contains an invalid percent-encoding")
+	// ErrInvalidHost is returned for an internationalized host name that IDNA cannot convert.
+	ErrInvalidHost = errors.New("invalid internationalized host name")
+)
+
+// normalize brings the request target and Host header into the normal form of
+// RFC 3986 section 6.2.2, so that requests for the same resource are routed
+// and matched alike however the client spelled them. Internationalized host
+// names are converted to their ASCII (punycode) form.
+func (r *Request) normalize() error {
+	path, err := NormalizePath(r.StartLine.RequestTarget.Path)
+	if err != nil {
+		return err
+	}
+	r.StartLine.RequestTarget.Path = path
+
+	for i, header := range r.Headers {
+		if strings.EqualFold(header.Name, "Host") {
+			host, err := normalizeHost(header.Value)
+			if err != nil {
+				return err
+			}
+			r.Headers[i].Value = host
+		}
+	}
+	return nil
+}
+
+// normalizeHost lower-cases a host, with an optional port, and converts an
+// internationalized name to ASCII with IDNA, so that "Bücher.example" and
+// "xn--bcher-kva.example" are the same host.
+func normalizeHost(host string) (string, error) {
+	if isASCII(host) {
+		return strings.ToLower(host), nil
+	}
+
+	name, port, err := net.SplitHostPort(host)
+	if err != nil {
+		name, port = host, ""
+	}
+	ascii, err := idna.Lookup.ToASCII(name)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidHost, err)
+	}
+	if port != "" {
+		return net.JoinHostPort(ascii, port), nil
+	}
+	return ascii, nil
+}
+
+// normalizeURL converts the host of an absolute URL, such as a configured
+// base URL, with normalizeHost.
+func normalizeURL(rawURL string) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	host, err := normalizeHost(u.Host)
+	if err != nil {
+		return "", err
+	}
+	u.Host = host
+	return u.String(), nil
+}
+
+// isASCII reports whether s contains only ASCII characters.
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizePath normalizes an origin-form path, as the server does before
+// routing a request:
+//
+//   - percent-encoded unreserved characters are decoded, and the hex digits of
+//     other percent-encodings are upper-cased
+//   - runs of slashes are collapsed into one
+//   - "." and ".." segments are removed
+//
+// A ".." segment that would climb above the root is rejected with
+// ErrDirectoryTraversal rather than dropped, and so is an encoded one, since
+// unreserved characters are decoded first. Other paths, such as "*", are
+// returned unchanged.
+func NormalizePath(p string) (string, error) {
+	if !strings.HasPrefix(p, "/") {
+		return p, nil
+	}
+
+	decoded, err := decodeUnreserved(p)
+	if err != nil {
+		return "", err
+	}
+
+	segments := strings.Split(decoded[1:], "/")
+	stack := make([]string, 0, len(segments))
+	trailingSlash := false
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		switch segment {
+		case "":
+			trailingSlash = last
+		case ".":
+			trailingSlash = last
+		case "..":
+			if len(stack) == 0 {
+				return "", ErrDirectoryTraversal
+			}
+			stack = stack[:len(stack)-1]
+			trailingSlash = last
+		default:
+			stack = append(stack, segment)
+			trailingSlash = false
+		}
+	}
+
+	normalized := "/" + strings.Join(stack, "/")
+	if trailingSlash && len(stack) > 0 {
+		normalized += "/"
+	}
+	return normalized, nil
+}
+
+// decodeUnreserved decodes the percent-encoded unreserved characters of p
+// (RFC 3986 section 2.3) and upper-cases the hex digits of the others.
+func decodeUnreserved(p string) (string, error) {
+	if !strings.Contains(p, "%") {
+		return p, nil
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(p))
+	for i := 0; i < len(p); i++ {
+		if p[i] != '%' {
+			sb.WriteByte(p[i])
+			continue
+		}
+		if i+2 >= len(p) || !isHex(p[i+1]) || !isHex(p[i+2]) {
+			return "", ErrInvalidPercentEncoding
+		}
+		c := unhex(p[i+1])<<4 | unhex(p[i+2])
+		if isUnreserved(c) {
+			sb.WriteByte(c)
+		} else {
+			sb.WriteByte('%')
+			sb.WriteString(strings.ToUpper(p[i+1 : i+3]))
+		}
+		i += 2
+	}
+	return sb.String(), nil
+}
+
+// isUnreserved reports whether c may appear in a URI without percent-encoding.
+func isUnreserved(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case c >= '0' && c <= '9':
+		return c - '0'
+	case c >= 'a' && c <= 'f':
+		return c - 'a' + 10
+	default:
+		return c - 'A' + 10
+	}
+}
@@ -0,0 +1,187 @@
+package http
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestNormalizePath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected string
+		err      error
+	}{
+		{"/", "/", nil},
+		{"/index.html", "/index.html", nil},
+		{"/docs/", "/docs/", nil},
+		{"/%7Euser/%41%62c", "/~user/Abc", nil},
+		{"/a%2fb", "/a%2Fb", nil},
+		{"/caf%c3%a9", "/caf%C3%A9", nil},
+		{"//a///b//", "/a/b/", nil},
+		{"/a/./b/.", "/a/b/", nil},
+		{"/a/b/../c", "/a/c", nil},
+		{"/a/b/..", "/a/", nil},
+		{"/a/..", "/", nil},
+		{"/a/%2e%2E/b", "/b", nil},
+		{"*", "*", nil},
+		{"/bad%zz", "", ErrInvalidPercentEncoding},
+		{"/bad%4", "", ErrInvalidPercentEncoding},
+		{"/..", "", ErrDirectoryTraversal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := NormalizePath(tt.path)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestNormalizePathTraversal checks that no spelling of a parent directory
+// survives normalization: every attempt is rejected or stays below the root.
+func TestNormalizePathTraversal(t *testing.T) {
+	attempts := []string{
+		"/../etc/passwd",
+		"/files/../../../etc/passwd",
+		"/%2e%2e/etc/passwd",
+		"/%2E%2E/%2E%2E/etc/passwd",
+		"/.%2e/etc/passwd",
+		"/%2e./etc/passwd",
+		"//../etc/passwd",
+		"/./../etc/passwd",
+		"/a/b/../../../etc/passwd",
+		"/a/%2e%2e/%2e%2e/etc/passwd",
+	}
+
+	root := filepath.Join("srv", "www")
+	for _, attempt := range attempts {
+		t.Run(attempt, func(t *testing.T) {
+			got, err := NormalizePath(attempt)
+			if err != nil {
+				if !errors.Is(err, ErrDirectoryTraversal) {
+					t.Errorf("Expected ErrDirectoryTraversal, got %v", err)
+				}
+				return
+			}
+			for _, segment := range strings.Split(got, "/") {
+				if segment == ".." || segment == "." {
+					t.Errorf("Expected no dot segments, got %q", got)
+				}
+			}
+			if resolved, err := resolve(root, got); err != nil || !strings.HasPrefix(resolved, root+string(filepath.Separator)) {
+				t.Errorf("Expected %q to resolve below %s, got %s", got, root, resolved)
+			}
+		})
+	}
+}
+
+func TestServerNormalizesRequests(t *testing.T) {
+	server := newTestServer(t, nil)
+	var host string
+	server.Handle("/host", ResponseHandler(func(req *Request) Response {
+		host, _ = GetHeader(req.Headers, "Host")
+		return newTextResponse(req.GetProtocol(), 200, req.GetRequestTarget().Path)
+	}))
+
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/%69ndex.html", 200},
+		{"//index.html", 200},
+		{"/missing/../index.html", 200},
+		{"/../index.html", 400},
+		{"/%2e%2e/index.html", 400},
+		{"/index%zz.html", 400},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if resp := get(t, server, tt.path); resp.GetStatusCode() != StatusCode(tt.status) {
+				t.Errorf("Expected status %d, got %d", tt.status, resp.GetStatusCode())
+			}
+		})
+	}
+
+	resp, err := NewResponse(string(exchange(server, []byte("GET /./host HTTP/1.1\r\nHost: WWW.Example.COM\r\n\r\n"))))
+	if err != nil {
+		t.Fatalf("could not parse response: %v", err)
+	}
+	if resp.GetBody() != "/host" || host != "www.example.com" {
+		t.Errorf("Expected path /host and host www.example.com, got %q and %q", resp.GetBody(), host)
+	}
+}
+
+func TestNormalizeHost(t *testing.T) {
+	tests := []struct {
+		host     string
+		expected string
+		err      error
+	}{
+		{"Example.COM", "example.com", nil},
+		{"example.com:8080", "example.com:8080", nil},
+		{"bücher.example", "xn--bcher-kva.example", nil},
+		{"Bücher.Example:8443", "xn--bcher-kva.example:8443", nil},
+		{"xn--bcher-kva.example", "xn--bcher-kva.example", nil},
+		{"[2001:DB8::1]:80", "[2001:db8::1]:80", nil},
+		{"bü cher.example", "", ErrInvalidHost},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.host, func(t *testing.T) {
+			got, err := normalizeHost(tt.host)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestServerIDNHost(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Robots.Generate = true
+		cfg.Robots.Sitemap = true
+		cfg.Robots.BaseURL = "https://Bücher.example/"
+	})
+	if server.Config.Robots.BaseURL != "https://xn--bcher-kva.example/" {
+		t.Errorf("Expected the base URL host in punycode, got %q", server.Config.Robots.BaseURL)
+	}
+
+	var host string
+	server.Handle("/host", ResponseHandler(func(req *Request) Response {
+		host, _ = GetHeader(req.Headers, "Host")
+		return newTextResponse(req.GetProtocol(), 200, "")
+	}))
+	exchange(server, []byte("GET /host HTTP/1.1\r\nHost: bücher.example\r\n\r\n"))
+	if host != "xn--bcher-kva.example" {
+		t.Errorf("Expected host xn--bcher-kva.example, got %q", host)
+	}
+
+	resp, err := NewResponse(string(exchange(server, []byte("GET /host HTTP/1.1\r\nHost: bü cher.example\r\n\r\n"))))
+	if err != nil {
+		t.Fatalf("could not parse response: %v", err)
+	}
+	if resp.GetStatusCode() != 400 || resp.GetBody() != "400 Bad Request: Invalid host" {
+		t.Errorf("Expected a 400 for an invalid host, got %d with %q", resp.GetStatusCode(), resp.GetBody())
+	}
+
+	if _, err := NewServer(func() config.Config {
+		cfg := config.DefaultConfig()
+		cfg.Robots.BaseURL = "https://bü cher.example/"
+		return cfg
+	}()); err == nil {
+		t.Error("Expected an error for an invalid base_url")
+	}
+}
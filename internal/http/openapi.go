@@ -0,0 +1,62 @@
+package http
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/awaisamjad/volk/internal/openapi"
+)
+
+// validateAPI checks a request to a path of the OpenAPI description. A 405
+// for methods the path does not have, or a 400 listing the violations, is
+// returned with false.
+func (s *Server) validateAPI(req *Request, path string) (Response, bool) {
+	apiPath := path
+	if base := strings.TrimSuffix(s.Config.OpenAPI.BasePath, "/"); base != "" {
+		var ok bool
+		if apiPath, ok = strings.CutPrefix(path, base); !ok || !strings.HasPrefix(apiPath, "/") {
+			return Response{}, true
+		}
+	}
+
+	// Invalid query strings were answered with 400 while parsing the request.
+	query, _ := req.QueryParams()
+	contentType, _ := GetHeader(req.Headers, "Content-Type")
+	result := s.OpenAPI.Validate(openapi.Request{
+		Method:      string(req.GetMethod()),
+		Path:        apiPath,
+		Query:       query,
+		Header:      func(name string) (string, bool) { return GetHeader(req.Headers, name) },
+		ContentType: contentType,
+		HasBody:     req.Body != "",
+	})
+	if len(result.Allow) > 0 {
+		return methodNotAllowed(req, strings.Join(result.Allow, ", ")), false
+	}
+	if len(result.Violations) == 0 {
+		return Response{}, true
+	}
+
+	body, _ := json.Marshal(struct {
+		Error      string              `json:"error"`
+		Violations []openapi.Violation `json:"violations"`
+	}{"request does not match the API description", result.Violations})
+	return jsonResponse(req, 400, string(body)), false
+}
+
+// openAPIHandler serves the OpenAPI description loaded from file.
+func openAPIHandler(spec *openapi.Spec, file string) ResponseFunc {
+	contentType := "application/yaml"
+	if strings.EqualFold(filepath.Ext(file), ".json") {
+		contentType = "application/json"
+	}
+	return func(req *Request) Response {
+		resp := newTextResponse(req.GetProtocol(), 200, string(spec.Raw))
+		resp.Headers = []Header{{Name: "Content-Type", Value: contentType}}
+		if req.GetMethod() == HEAD {
+			resp.Body = ""
+		}
+		return resp
+	}
+}
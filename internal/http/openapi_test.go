@@ -0,0 +1,85 @@
+package http
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+const testAPISpec = `{
+  "openapi": "3.0.3",
+  "paths": {
+    "/items": {
+      "get": {"parameters": [{"name": "limit", "in": "query", "schema": {"type": "integer"}}]},
+      "post": {"requestBody": {"required": true, "content": {"application/json": {}}}}
+    }
+  }
+}`
+
+func TestServerOpenAPI(t *testing.T) {
+	specPath := filepath.Join(t.TempDir(), "openapi.json")
+	if err := os.WriteFile(specPath, []byte(testAPISpec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.OpenAPI = config.OpenAPIConfig{Spec: specPath, BasePath: "/api/", Path: "/openapi.json"}
+	})
+	server.Handle("/api/items", ResponseHandler(func(req *Request) Response {
+		return newTextResponse(req.GetProtocol(), 200, "items")
+	}), GET, HEAD, POST, DELETE)
+
+	tests := []struct {
+		name       string
+		method     Method
+		target     string
+		body       string
+		headers    []string
+		wantStatus StatusCode
+		wantBody   string
+		wantHeader Header
+	}{
+		{"valid", GET, "/api/items?limit=10", "", nil, 200, "items", Header{}},
+		{"invalid parameter", GET, "/api/items?limit=ten", "", nil, 400,
+			`{"error":"request does not match the API description","violations":[{"in":"query","name":"limit","message":"must be an integer"}]}`,
+			Header{Name: "Content-Type", Value: "application/json"}},
+		{"missing body", POST, "/api/items", "", nil, 400,
+			`{"error":"request does not match the API description","violations":[{"in":"body","message":"is required"}]}`, Header{}},
+		{"valid body", POST, "/api/items", `{"name":"a"}`, []string{"Content-Type: application/json"}, 200, "items", Header{}},
+		{"method not described", DELETE, "/api/items", "", nil, 405, "", Header{Name: "Allow", Value: "GET, POST, HEAD"}},
+		{"outside the base path", GET, "/index.html?limit=ten", "", nil, 200, "", Header{}},
+		{"document", GET, "/openapi.json", "", nil, 200, testAPISpec, Header{Name: "Content-Type", Value: "application/json"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := send(t, server, tt.method, tt.target, tt.body, tt.headers...)
+			if resp.GetStatusCode() != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, resp.GetStatusCode())
+			}
+			if tt.wantBody != "" && resp.GetBody() != tt.wantBody {
+				t.Errorf("Expected body %q, got %q", tt.wantBody, resp.GetBody())
+			}
+			if tt.wantHeader.Name != "" {
+				if value, _ := GetHeader(resp.Headers, tt.wantHeader.Name); value != tt.wantHeader.Value {
+					t.Errorf("Expected %s %q, got %q", tt.wantHeader.Name, tt.wantHeader.Value, value)
+				}
+			}
+		})
+	}
+}
+
+func TestServerOpenAPIError(t *testing.T) {
+	specPath := filepath.Join(t.TempDir(), "openapi.json")
+	if err := os.WriteFile(specPath, []byte(`{"swagger": "2.0"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, spec := range []string{specPath, filepath.Join(t.TempDir(), "missing.json")} {
+		cfg := config.DefaultConfig()
+		cfg.OpenAPI.Spec = spec
+		if _, err := NewServer(cfg); err == nil {
+			t.Errorf("Expected an error for %s, got nil", spec)
+		}
+	}
+}
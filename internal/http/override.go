@@ -0,0 +1,49 @@
+package http
+
+import (
+	"mime"
+	"net/url"
+	"strings"
+)
+
+// MethodOverrideHeader names the method a POST request stands for, for
+// clients that can only send GET and POST.
+const MethodOverrideHeader = "X-HTTP-Method-Override"
+
+// MethodOverrideField is the form field naming the method of a POSTed HTML form.
+const MethodOverrideField = "_method"
+
+// overrideMethods are the methods a POST request may be overridden to.
+var overrideMethods = map[Method]bool{PUT: true, PATCH: true, DELETE: true}
+
+// overrideMethod replaces the method of a POST request by the one named in its
+// X-HTTP-Method-Override header or, for a URL-encoded form, its _method field.
+// Only PUT, PATCH and DELETE are accepted, so a request cannot become a GET and
+// be cached or replayed as one. It reports whether the method was replaced.
+func (r *Request) overrideMethod() bool {
+	if r.GetMethod() != POST {
+		return false
+	}
+
+	method, ok := GetHeader(r.Headers, MethodOverrideHeader)
+	if !ok {
+		contentType, _ := GetHeader(r.Headers, "Content-Type")
+		mediaType, _, _ := mime.ParseMediaType(contentType)
+		if mediaType != "application/x-www-form-urlencoded" {
+			return false
+		}
+		form, err := url.ParseQuery(r.Body)
+		if err != nil {
+			return false
+		}
+		method = form.Get(MethodOverrideField)
+	}
+
+	override := Method(strings.ToUpper(strings.TrimSpace(method)))
+	if !overrideMethods[override] {
+		return false
+	}
+	r.SentMethod = r.StartLine.Method
+	r.StartLine.Method = override
+	return true
+}
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestOverrideMethod(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     Method
+		headers    []Header
+		body       string
+		expected   Method
+		overridden bool
+	}{
+		{"header", POST, []Header{{Name: "X-HTTP-Method-Override", Value: "delete"}}, "", DELETE, true},
+		{"form field", POST, []Header{{Name: "Content-Type", Value: "application/x-www-form-urlencoded"}}, "_method=PUT&name=x", PUT, true},
+		{"header wins over form field", POST, []Header{{Name: "X-HTTP-Method-Override", Value: "PATCH"}, {Name: "Content-Type", Value: "application/x-www-form-urlencoded"}}, "_method=PUT", PATCH, true},
+		{"form field needs a form", POST, []Header{{Name: "Content-Type", Value: "application/json"}}, "_method=PUT", POST, false},
+		{"GET is not overridden", GET, []Header{{Name: "X-HTTP-Method-Override", Value: "DELETE"}}, "", GET, false},
+		{"cannot become GET", POST, []Header{{Name: "X-HTTP-Method-Override", Value: "GET"}}, "", POST, false},
+		{"unknown method", POST, []Header{{Name: "X-HTTP-Method-Override", Value: "PURGE"}}, "", POST, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := Request{
+				StartLine: RequestStartLine{Method: tt.method, RequestTarget: RequestTarget{Path: "/items/1"}, Protocol: HTTP1_1},
+				Headers:   tt.headers,
+				Body:      tt.body,
+			}
+			if overridden := req.overrideMethod(); overridden != tt.overridden {
+				t.Errorf("Expected overridden %v, got %v", tt.overridden, overridden)
+			}
+			if req.GetMethod() != tt.expected {
+				t.Errorf("Expected method %s, got %s", tt.expected, req.GetMethod())
+			}
+		})
+	}
+}
+
+func TestServerMethodOverride(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		t.Run("enabled="+strconv.FormatBool(enabled), func(t *testing.T) {
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			defer log.SetOutput(os.Stderr)
+
+			server := newTestServer(t, func(cfg *config.Config) {
+				cfg.Server.MethodOverride = enabled
+				cfg.Logging.AccessLogs = true
+			})
+			server.Handle("/items/1", ResponseHandler(func(req *Request) Response {
+				return newTextResponse(req.GetProtocol(), 200, string(req.GetMethod()))
+			}))
+
+			resp := send(t, server, POST, "/items/1", "", "X-HTTP-Method-Override: DELETE")
+			expected := "POST"
+			if enabled {
+				expected = "DELETE"
+			}
+			if resp.GetBody() != expected {
+				t.Errorf("Expected the handler to see %s, got %s", expected, resp.GetBody())
+			}
+			if logged := strings.Contains(logs.String(), "Access: DELETE /items/1") && strings.Contains(logs.String(), "200 OK override=POST"); logged != enabled {
+				t.Errorf("Expected the override logged %v, got %q", enabled, logs.String())
+			}
+		})
+	}
+}
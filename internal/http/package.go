@@ -11,10 +11,48 @@
 // - fragment.go: Fragment handling
 // - request_target.go: RequestTarget type and operations
 // - request.go: Request type, parsing, and validation
+// - normalize.go: Normalization of request paths and hosts before routing
 // - response.go: Response type and creation
 // - methods.go: HTTP method implementations (GET, POST, etc.)
-// - server.go: FileServer and server-related code
-// - util.go: Utility functions for HTTP operations
+// - fileserver.go: FileServer for serving static files
+// - winpath.go: URL path checks against names Windows file systems map to other files
+// - server.go: Server that accepts connections and dispatches requests
+// - handler.go: Handlers registered on a Server for paths
+// - conditional.go: Evaluation of conditional request headers for handlers
+// - writer.go: ResponseWriter handed to handlers, with streaming and hijacking
+// - invariants.go: Framing fixes applied to every response sent whole
+// - identity.go: Server header sent with responses
+// - vary.go: Vary header built from the request headers responses depend on
+// - serialize.go: Response serialization through pooled buffered writers
+// - etag.go: ETags of files, weak or strong
+// - purge.go: Purging the file caches through the admin endpoint
+// - watch.go: Dropping cache entries of files as the document roots change
+// - compress.go: gzip compression of responses
+// - upgrade.go: Handlers for protocols switched to with the Upgrade header
+// - recover.go: Request IDs and recovery from panics while responding
+// - traceparent.go: W3C Trace Context of requests
+// - deadline.go: Request deadlines from handle_timeout and Request-Timeout headers
+// - devpage.go: Detailed error pages in dev mode
+// - form.go: Form parsing, redirect-after-post and flash messages for handlers
+// - kv.go: Key-value JSON API handler
+// - webhook.go: Webhook receiver handler
+// - deploy.go: Deploy endpoint handler
+// - upload.go: Resumable upload endpoint speaking the tus protocol
+// - beacon.go: Page views reported by pages for the statistics
+// - admin.go: Draining, readiness, storage usage and runtime variables endpoints
+// - metrics.go: OpenMetrics endpoint for Prometheus
+// - openapi.go: Validation of API requests against an OpenAPI description
+// - audit.go: Audit log of write requests
+// - anonymize.go: Client addresses shortened for logs
+// - redact.go: Secret header values hidden from debug logs and dev error pages
+// - plugin.go: Adapter for net/http handlers provided by plugins
+// - script.go: Per-location request scripts
+// - split.go: A/B tests splitting locations between document roots
+// - flavor.go: Document roots chosen by a request header
+// - userdir.go: Directories of users served on their subdomains
+// - download.go: Archives of directories downloaded with ?download=zip or tar.gz
+// - thumbnail.go: Resized images of the document root
+// - tee.go: Copies of public responses pushed to a mirror
 // - package.go: Package documentation and initialization
 package http
 
@@ -31,14 +69,11 @@ type HTTPMessage interface {
 	String() string
 }
 
-// DefaultFileServer is the default file server used for serving static files
+// DefaultFileServer is the file server used by Request.Response and Request.GET.
+// If it is nil when a request is handled, a FileServer with the default configuration is used.
+// A Server always uses its own FileServer.
 var DefaultFileServer *FileServer
 
-func init() {
-	// Initialize with nil, will be set when the server starts
-	DefaultFileServer = nil
-}
-
 // SetDefaultFileServer sets the default file server
 func SetDefaultFileServer(fs *FileServer) {
 	DefaultFileServer = fs
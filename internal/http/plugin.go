@@ -0,0 +1,106 @@
+package http
+
+import (
+	"io"
+	nethttp "net/http"
+	"net/url"
+	"strings"
+)
+
+// StdHandler adapts a handler of the standard library's net/http, such as one
+// provided by a plugin, to a Handler. Header fields prefixed with
+// net/http's TrailerPrefix after the handler returns are sent as trailers.
+func StdHandler(handler nethttp.Handler) Handler {
+	return func(w ResponseWriter, req *Request) {
+		sw := &stdResponseWriter{w: w, header: nethttp.Header{}}
+		ctx, cancel := req.Context()
+		defer cancel()
+		handler.ServeHTTP(sw, stdRequest(req).WithContext(ctx))
+		sw.writeHeader(nethttp.StatusOK)
+		for name, values := range sw.header {
+			if trailer, ok := strings.CutPrefix(name, nethttp.TrailerPrefix); ok {
+				for _, value := range values {
+					w.AddTrailer(trailer, value)
+				}
+			}
+		}
+	}
+}
+
+// stdRequest converts req to a net/http request.
+func stdRequest(req *Request) *nethttp.Request {
+	target := req.GetRequestTarget()
+	rawQuery := strings.TrimPrefix(target.Query, "?")
+	requestURI := target.Path
+	if rawQuery != "" {
+		requestURI += "?" + rawQuery
+	}
+
+	r := &nethttp.Request{
+		Method:     string(req.GetMethod()),
+		URL:        &url.URL{Path: target.Path, RawQuery: rawQuery},
+		RequestURI: requestURI,
+		Proto:      string(req.GetProtocol()),
+		Header:     nethttp.Header{},
+		Body:       io.NopCloser(strings.NewReader(req.GetBody())),
+		RemoteAddr: req.RemoteAddr,
+	}
+	r.ProtoMajor, r.ProtoMinor, _ = nethttp.ParseHTTPVersion(r.Proto)
+	r.ContentLength = int64(len(req.GetBody()))
+	for _, header := range req.Headers {
+		r.Header.Add(header.Name, header.Value)
+	}
+	r.Host, _ = GetHeader(req.Headers, "Host")
+	r.URL.Host = r.Host
+	if len(req.Trailers) > 0 {
+		r.Trailer = nethttp.Header{}
+		for _, trailer := range req.Trailers {
+			r.Trailer.Add(trailer.Name, trailer.Value)
+		}
+	}
+	return r
+}
+
+// stdResponseWriter implements net/http's ResponseWriter and Flusher on top
+// of a ResponseWriter.
+type stdResponseWriter struct {
+	w           ResponseWriter
+	header      nethttp.Header
+	wroteHeader bool
+}
+
+func (sw *stdResponseWriter) Header() nethttp.Header {
+	return sw.header
+}
+
+func (sw *stdResponseWriter) WriteHeader(status int) {
+	sw.writeHeader(status)
+}
+
+func (sw *stdResponseWriter) Write(p []byte) (int, error) {
+	sw.writeHeader(nethttp.StatusOK)
+	return sw.w.Write(p)
+}
+
+func (sw *stdResponseWriter) Flush() {
+	sw.writeHeader(nethttp.StatusOK)
+	sw.w.Flush()
+}
+
+// writeHeader passes the status and the header fields on, the first time it
+// is called.
+func (sw *stdResponseWriter) writeHeader(status int) {
+	if sw.wroteHeader {
+		return
+	}
+	sw.wroteHeader = true
+	for name, values := range sw.header {
+		if strings.HasPrefix(name, nethttp.TrailerPrefix) {
+			continue
+		}
+		for _, value := range values {
+			sw.w.AddHeader(name, value)
+		}
+	}
+	sw.w.WriteHeader(StatusCode(status))
+}
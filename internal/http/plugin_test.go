@@ -0,0 +1,69 @@
+package http
+
+import (
+	"io"
+	nethttp "net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestStdHandler(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.Handle("/std/", StdHandler(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		switch r.URL.Path {
+		case "/std/echo":
+			body, _ := io.ReadAll(r.Body)
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("X-Method", r.Method)
+			w.Header().Set("X-Host", r.Host)
+			w.WriteHeader(nethttp.StatusCreated)
+			w.Write([]byte(r.URL.Query().Get("name") + ":" + string(body)))
+		case "/std/teapot":
+			w.WriteHeader(nethttp.StatusTeapot)
+		default:
+			nethttp.NotFound(w, r)
+		}
+	})))
+
+	tests := []struct {
+		name       string
+		method     Method
+		path       string
+		body       string
+		wantCode   StatusCode
+		wantBody   string
+		wantHeader Header
+	}{
+		{"echo", PUT, "/std/echo?name=volk", "hello", 201, "volk:hello", Header{Name: "X-Method", Value: "PUT"}},
+		{"host", GET, "/std/echo", "", 201, ":", Header{Name: "X-Host", Value: "localhost"}},
+		{"status without body", GET, "/std/teapot", "", 418, "", Header{}},
+		{"not found", GET, "/std/missing", "", 404, "404 page not found\n", Header{Name: "X-Content-Type-Options", Value: "nosniff"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := send(t, server, tt.method, tt.path, tt.body)
+			if resp.GetStatusCode() != tt.wantCode {
+				t.Errorf("Expected status %d, got %d", tt.wantCode, resp.GetStatusCode())
+			}
+			if resp.GetBody() != tt.wantBody {
+				t.Errorf("Expected body %q, got %q", tt.wantBody, resp.GetBody())
+			}
+			if tt.wantHeader.Name != "" {
+				if value, _ := GetHeader(resp.Headers, tt.wantHeader.Name); value != tt.wantHeader.Value {
+					t.Errorf("Expected %s %q, got %q", tt.wantHeader.Name, tt.wantHeader.Value, value)
+				}
+			}
+		})
+	}
+}
+
+func TestServerPluginError(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Plugins = []config.PluginConfig{{Path: filepath.Join(t.TempDir(), "missing.so")}}
+	if _, err := NewServer(cfg); err == nil {
+		t.Error("Expected an error for a missing plugin, got nil")
+	}
+}
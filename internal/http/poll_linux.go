@@ -0,0 +1,211 @@
+//go:build linux
+
+package http
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// pollWaitTimeout is how often the epoll loop checks whether the server was closed.
+const pollWaitTimeout = time.Second
+
+// servePoll accepts connections on ln without a goroutine per connection.
+// Accepted connections wait in an epoll set until the client sends data, and
+// only then are handed to a fixed pool of goroutines. Connections that are
+// open but have not started their request cost a map entry each instead of a
+// goroutine stack. There is no keep-alive: handleConnection closes every
+// connection after its response, so connections are never idle between
+// requests.
+//
+// Connections that send nothing within server.read_timeout are closed.
+func (s *Server) servePoll(ln net.Listener) error {
+	epfd, err := unix.EpollCreate1(unix.EPOLL_CLOEXEC)
+	if err != nil {
+		return fmt.Errorf("error creating epoll instance: %w", err)
+	}
+
+	workers := s.Config.Server.PollWorkers
+	if workers <= 0 {
+		workers = 16 * runtime.GOMAXPROCS(0)
+	}
+	p := &poller{
+		epfd:  epfd,
+		conns: make(map[int]*polledConn),
+		ready: make(chan net.Conn, workers),
+		done:  s.conns.Done,
+	}
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for conn := range p.ready {
+				s.handleConnection(conn)
+				s.conns.Done()
+			}
+		}()
+	}
+	go p.wait()
+	if s.Config.Server.ReadTimeout > 0 {
+		go p.reap(time.Duration(s.Config.Server.ReadTimeout) * time.Second)
+	}
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			p.close()
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			return fmt.Errorf("error accepting connection: %w", err)
+		}
+		s.tuneConn(conn)
+		conn, ok := s.limitConn(conn)
+		if !ok {
+			continue
+		}
+		s.conns.Add(1)
+		if err := p.add(conn); err != nil {
+			log.Printf("Error polling connection: %v", err)
+			conn.Close()
+			s.conns.Done()
+		}
+	}
+}
+
+// polledConn is a connection waiting for its request.
+type polledConn struct {
+	conn     net.Conn
+	accepted time.Time
+}
+
+// poller is the epoll set of the connections waiting for their request.
+type poller struct {
+	epfd  int
+	ready chan net.Conn // Readable connections, for the worker goroutines
+	done  func()        // Called for connections closed without being handled
+
+	mu     sync.Mutex
+	conns  map[int]*polledConn // By file descriptor
+	closed bool
+}
+
+// add waits for conn to become readable.
+func (p *poller) add(conn net.Conn) error {
+	sc, ok := conn.(syscall.Conn)
+	if !ok {
+		return fmt.Errorf("connection of type %T has no file descriptor", conn)
+	}
+	raw, err := sc.SyscallConn()
+	if err != nil {
+		return err
+	}
+	fd := -1
+	raw.Control(func(f uintptr) { fd = int(f) })
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return errors.New("server closed")
+	}
+
+	// The connection is registered before epoll can report it.
+	p.conns[fd] = &polledConn{conn: conn, accepted: time.Now()}
+	event := unix.EpollEvent{Events: unix.EPOLLIN | unix.EPOLLRDHUP | unix.EPOLLONESHOT, Fd: int32(fd)}
+	if err := unix.EpollCtl(p.epfd, unix.EPOLL_CTL_ADD, fd, &event); err != nil {
+		delete(p.conns, fd)
+		return fmt.Errorf("error adding connection to epoll: %w", err)
+	}
+	return nil
+}
+
+// remove takes the connection with the file descriptor out of the set.
+// It must be called with p.mu held.
+func (p *poller) remove(fd int) *polledConn {
+	pc, ok := p.conns[fd]
+	if !ok {
+		return nil
+	}
+	delete(p.conns, fd)
+	// Removed before the connection is closed, so that a new connection
+	// reusing the descriptor number can be added.
+	unix.EpollCtl(p.epfd, unix.EPOLL_CTL_DEL, fd, nil)
+	return pc
+}
+
+// wait hands readable connections to the workers until the poller is closed.
+func (p *poller) wait() {
+	defer close(p.ready)
+	defer unix.Close(p.epfd)
+
+	events := make([]unix.EpollEvent, 256)
+	for {
+		n, err := unix.EpollWait(p.epfd, events, int(pollWaitTimeout/time.Millisecond))
+		if err != nil && !errors.Is(err, unix.EINTR) {
+			log.Printf("Error waiting for connections: %v", err)
+			p.close()
+		}
+
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return
+		}
+		var readable []net.Conn
+		for _, event := range events[:max(n, 0)] {
+			if pc := p.remove(int(event.Fd)); pc != nil {
+				readable = append(readable, pc.conn)
+			}
+		}
+		p.mu.Unlock()
+
+		for _, conn := range readable {
+			p.ready <- conn
+		}
+	}
+}
+
+// reap closes connections that have not sent anything for the timeout.
+func (p *poller) reap(timeout time.Duration) {
+	ticker := time.NewTicker(timeout / 2)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		p.mu.Lock()
+		if p.closed {
+			p.mu.Unlock()
+			return
+		}
+		for fd, pc := range p.conns {
+			if now.Sub(pc.accepted) > timeout {
+				p.remove(fd)
+				pc.conn.Close()
+				p.done()
+			}
+		}
+		p.mu.Unlock()
+	}
+}
+
+// close stops the poller and closes the connections that are still waiting
+// for their request. Connections being handled are not interrupted.
+func (p *poller) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return
+	}
+	p.closed = true
+	for fd, pc := range p.conns {
+		p.remove(fd)
+		pc.conn.Close()
+		p.done()
+	}
+}
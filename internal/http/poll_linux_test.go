@@ -0,0 +1,84 @@
+//go:build linux
+
+package http
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestServePoll(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Server.Mode = "epoll"
+		cfg.Server.PollWorkers = 2
+		cfg.Server.ReadTimeout = 1
+	})
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- server.Serve(ln) }()
+	addr := ln.Addr().String()
+
+	// An idle connection must not hold up the others.
+	idle, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer idle.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			defer conn.Close()
+			conn.Write([]byte("GET /index.html HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+			response, _ := io.ReadAll(conn)
+			if !strings.HasPrefix(string(response), "HTTP/1.1 200") {
+				t.Errorf("Expected a 200 response, got %q", response)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// The idle connection is closed after the read timeout.
+	idle.SetReadDeadline(time.Now().Add(5 * time.Second))
+	start := time.Now()
+	if n, err := idle.Read(make([]byte, 1)); n != 0 || err != io.EOF {
+		t.Errorf("Expected the idle connection to be closed, got %d bytes and %v", n, err)
+	}
+	if elapsed := time.Since(start); elapsed > 3*time.Second {
+		t.Errorf("Expected the idle connection to be closed after about 1s, took %s", elapsed)
+	}
+
+	// Connections still waiting for their request do not block shutdown.
+	waiting, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer waiting.Close()
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Errorf("Expected Shutdown to return nil, got %v", err)
+	}
+	if err := <-served; err != ErrServerClosed {
+		t.Errorf("Expected Serve to return ErrServerClosed, got %v", err)
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !linux
+
+package http
+
+import (
+	"errors"
+	"net"
+)
+
+// servePoll returns an error: epoll is only available on Linux.
+func (s *Server) servePoll(ln net.Listener) error {
+	ln.Close()
+	return errors.New(`server mode "epoll" is only supported on Linux`)
+}
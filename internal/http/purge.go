@@ -0,0 +1,114 @@
+package http
+
+import (
+	"encoding/json"
+	"log"
+	"net/url"
+	"strings"
+)
+
+// PurgeResult counts the cache entries dropped by PurgeCache.
+type PurgeResult struct {
+	ETags       int `json:"etags"`        // Sums behind strong ETags
+	AccessFiles int `json:"access_files"` // Parsed .volkaccess files
+	Thumbnails  int `json:"thumbnails"`   // Cached thumbnails
+}
+
+// PurgeCache drops what the server caches about the files at the URL path
+// and below it, or about every file if path is empty: the sums behind strong
+// ETags, the parsed access files and the thumbnails. Every document root is
+// purged, including those of variants and flavors. The caches notice most
+// changes by themselves; purging covers a deploy that keeps the size and
+// modification time of a file while changing its content.
+func (s *Server) PurgeCache(path string) (PurgeResult, error) {
+	var result PurgeResult
+	for _, fs := range s.fileServers() {
+		filePath, ok := fs.purgePath(path)
+		if !ok {
+			continue
+		}
+		if fs.hashes != nil {
+			result.ETags += fs.hashes.Purge(filePath)
+		}
+		result.AccessFiles += fs.access.Purge(filePath)
+	}
+
+	if s.thumbnails != nil {
+		if filePath, ok := s.FileServer.purgePath(path); ok {
+			removed, err := s.thumbnails.Purge(filePath)
+			result.Thumbnails = removed
+			if err != nil {
+				return result, err
+			}
+		}
+	}
+	target := path
+	if target == "" {
+		target = "every file"
+	}
+	log.Printf("Purged the caches of %s: %d ETags, %d access files, %d thumbnails", target, result.ETags, result.AccessFiles, result.Thumbnails)
+	return result, nil
+}
+
+// purgePath returns the file path the caches of fs know the file at the URL
+// path by, empty for every file, and false if the URL path cannot name a file.
+func (fs *FileServer) purgePath(path string) (string, bool) {
+	if path == "" {
+		return "", true
+	}
+	filePath, err := resolve(fs.documentRoot(), path)
+	return filePath, err == nil
+}
+
+// fileServers returns the FileServers of the server: the main one, those of
+// the variants and flavors of locations and those of the user directories
+// served so far.
+func (s *Server) fileServers() []*FileServer {
+	servers := []*FileServer{s.FileServer}
+	for _, split := range s.splits {
+		for _, fs := range split.fileServers {
+			servers = append(servers, fs)
+		}
+	}
+	for _, routes := range s.flavors {
+		for _, f := range routes.flavors {
+			servers = append(servers, f.fileServer)
+		}
+	}
+	if s.userDirs != nil {
+		servers = append(servers, s.userDirs.fileServers()...)
+	}
+	return servers
+}
+
+// cacheHandler serves the cache endpoint. Every request needs the token as a
+// bearer token.
+//
+//	DELETE path?path=/docs/   purge the caches of /docs/ and the files below it
+//	DELETE path?all=true      purge the caches of every file
+//
+// The response counts the entries dropped from each cache.
+func cacheHandler(s *Server, token string) ResponseFunc {
+	return func(req *Request) Response {
+		if resp, ok := checkBearer(req, token, "admin"); !ok {
+			return resp
+		}
+
+		query, _ := url.ParseQuery(strings.TrimPrefix(req.GetRequestTarget().Query, "?"))
+		path := query.Get("path")
+		switch {
+		case query.Get("all") == "true" && path == "":
+		case strings.HasPrefix(path, "/"):
+		default:
+			return jsonResponse(req, 400, `{"error":"give the path to purge, starting with /, or all=true"}`)
+		}
+
+		result, err := s.PurgeCache(path)
+		if err != nil {
+			log.Printf("Error purging caches: %v", err)
+			return jsonResponse(req, 500, `{"error":"internal server error"}`)
+		}
+		body, _ := json.Marshal(map[string]any{"path": path, "purged": result})
+		return jsonResponse(req, 200, string(body))
+	}
+}
@@ -0,0 +1,73 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestAdminCachePurge(t *testing.T) {
+	log.SetOutput(&bytes.Buffer{})
+	defer log.SetOutput(os.Stderr)
+
+	root := etagRoot(t, time.Unix(1700000000, 0))
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.FileServer.DocumentRoot = root
+		cfg.FileServer.ETag = "strong"
+		cfg.Admin = config.AdminConfig{Enabled: true, Path: "/_admin", Token: "secret"}
+	})
+	auth := "Authorization: Bearer secret"
+
+	// Wait for the strong ETag, so the sum is cached.
+	deadline := time.Now().Add(5 * time.Second)
+	for etag := ""; !strings.HasPrefix(etag, `"`) && time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		etag, _ = GetHeader(get(t, server, "/index.html").Headers, "ETag")
+	}
+
+	tests := []struct {
+		name      string
+		target    string
+		headers   []string
+		wantCode  StatusCode
+		wantETags int
+	}{
+		{"unauthorized", "/_admin/cache?all=true", nil, 401, 0},
+		{"no path", "/_admin/cache", []string{auth}, 400, 0},
+		{"relative path", "/_admin/cache?path=index.html", []string{auth}, 400, 0},
+		{"other path", "/_admin/cache?path=/assets/", []string{auth}, 200, 0},
+		{"file", "/_admin/cache?path=/index.html", []string{auth}, 200, 1},
+		{"everything", "/_admin/cache?all=true", []string{auth}, 200, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := send(t, server, DELETE, tt.target, "", tt.headers...)
+			if resp.GetStatusCode() != tt.wantCode {
+				t.Fatalf("Expected status %d, got %d: %s", tt.wantCode, resp.GetStatusCode(), resp.GetBody())
+			}
+			if tt.wantCode != 200 {
+				return
+			}
+			var result struct {
+				Purged PurgeResult `json:"purged"`
+			}
+			if err := json.Unmarshal([]byte(resp.GetBody()), &result); err != nil {
+				t.Fatalf("Expected JSON, got %q", resp.GetBody())
+			}
+			if result.Purged.ETags != tt.wantETags {
+				t.Errorf("Expected %d ETags purged, got %d", tt.wantETags, result.Purged.ETags)
+			}
+		})
+	}
+
+	// The purged file gets the weak tag again until it is hashed anew.
+	if etag, _ := GetHeader(get(t, server, "/index.html").Headers, "ETag"); !strings.HasPrefix(etag, "W/") {
+		t.Errorf("Expected the weak ETag after purging, got %q", etag)
+	}
+}
@@ -3,14 +3,28 @@ package http
 
 import (
 	"errors"
+	"fmt"
+	"net/url"
 	"strings"
 )
 
+// Limits of parsed query strings, which keep a pathological request target
+// from costing more than a few allocations per byte.
+const (
+	// MaxQueryLength is the longest query string accepted, in bytes, without the '?'.
+	MaxQueryLength = 8192
+	// MaxQueryParams is the largest number of parameters a query string may have.
+	MaxQueryParams = 1000
+)
+
 // Query errors
 var (
 	ErrQueryNotFound       = errors.New("query not found")
 	ErrFragmentBeforeQuery = errors.New("fragment comes before query")
 	ErrQueryEmpty          = errors.New("query is empty")
+	ErrQueryTooLong        = errors.New("query is too long")
+	ErrTooManyQueryParams  = errors.New("query has too many parameters")
+	ErrInvalidQueryEscape  = errors.New("query has an invalid percent-encoding")
 )
 
 // Query represents HTTP query parameters
@@ -18,85 +32,134 @@ type Query struct {
 	Params map[string][]string
 }
 
-// findQuery extracts the query string from a request target.
+// QueryOptions choose how a query string is split and decoded beyond '&'
+// between parameters, '=' between key and value and percent-encoding.
+// Applications disagree on these, so they are configured with [query].
+type QueryOptions struct {
+	Semicolons  bool // ';' separates parameters too, as legacy applications expect
+	Arrays      bool // key[]=a&key[]=b are the values a and b of key
+	PlusAsSpace bool // '+' decodes to a space, as HTML forms encode it
+}
+
+// findQuery extracts the query string from a request target. Trailing
+// whitespace is not part of the query.
 //
 // Returns:
 //   - query: The extracted query string, including the '?' prefix.
 //   - queryIndex: The index of the '?' character in the requestTarget string.
 //   - error: An error if the query is not found.  Will be ErrQueryNotFound if no query exists.
 func findQuery(requestTarget string) (string, int, error) {
-	query := ""
-
-	queryIdx := strings.Index(requestTarget, "?")
-
+	queryIdx := strings.IndexByte(requestTarget, '?')
 	if queryIdx == -1 {
 		return "", -1, ErrQueryNotFound
 	}
 
-	isThereAFragment := false
-	fragmentIdx := strings.Index(requestTarget, "#")
-
-	if fragmentIdx != -1 {
-		isThereAFragment = true
-	}
-
-	if isThereAFragment {
+	end := len(requestTarget)
+	if fragmentIdx := strings.IndexByte(requestTarget, '#'); fragmentIdx != -1 {
 		if fragmentIdx < queryIdx {
 			return "", -1, ErrFragmentBeforeQuery
 		}
-
-		query = requestTarget[queryIdx:fragmentIdx]
-	} else {
-		query = requestTarget[queryIdx:]
+		end = fragmentIdx
 	}
 
-	if query == "" {
-		return "", -1, ErrQueryEmpty
-	}
-
-	return query, queryIdx, nil
+	return strings.TrimRight(requestTarget[queryIdx:end], " \t"), queryIdx, nil
 }
 
 // parseQuery parses a query string into a Query struct.
 //
 // The query string should be in the format "key1=value1&key2=value2...".
 // It handles cases where values are missing (e.g., "key1&key2=value2") by assigning an empty string.
+// Keys and values are percent-decoded; '+' is kept as it is.
 //
 // Returns:
 //   - Query: A Query struct containing the parsed parameters.
-//   - error: An error if parsing fails.
+//   - error: An error if parsing fails: ErrQueryTooLong, ErrTooManyQueryParams
+//     or ErrInvalidQueryEscape.
 func parseQuery(query string) (Query, error) {
-	if len(query) > 0 && query[0] == '?' {
-		query = query[1:]
+	return parseQueryWith(query, QueryOptions{})
+}
+
+// parseQueryWith is parseQuery, splitting and decoding the query as opts say.
+func parseQueryWith(query string, opts QueryOptions) (Query, error) {
+	query = strings.TrimPrefix(query, "?")
+	if len(query) > MaxQueryLength {
+		return Query{}, fmt.Errorf("%w: %d bytes, at most %d", ErrQueryTooLong, len(query), MaxQueryLength)
+	}
+	params, err := parseParams(query, opts, MaxQueryParams)
+	if err != nil {
+		return Query{}, err
 	}
+	return Query{Params: params}, nil
+}
 
-	if query == "" {
-		return Query{Params: map[string][]string{}}, ErrQueryEmpty
+// parseParams parses the parameters of a query string or URL-encoded form,
+// at most maxParams of them unless it is 0.
+func parseParams(s string, opts QueryOptions, maxParams int) (map[string][]string, error) {
+	separators := "&"
+	if opts.Semicolons {
+		separators = "&;"
 	}
 
 	params := make(map[string][]string)
-	// querySplit := strings.Split(query, "&")
-	queryIter := strings.SplitSeq(query, "&")
-
-	for param := range queryIter {
+	count := 0
+	for s != "" {
+		param := s
+		if i := strings.IndexAny(s, separators); i != -1 {
+			param, s = s[:i], s[i+1:]
+		} else {
+			s = ""
+		}
 		// Skip empty parameters
 		if param == "" {
 			continue
 		}
+		if count++; maxParams > 0 && count > maxParams {
+			return nil, fmt.Errorf("%w: more than %d", ErrTooManyQueryParams, maxParams)
+		}
 
-		keyValue := strings.SplitN(param, "=", 2)
-		key := keyValue[0]
-
-		// Handle case where there's no value
-		if len(keyValue) == 1 {
-			params[key] = append(params[key], "")
-		} else {
-			value := keyValue[1]
-			params[key] = append(params[key], value)
+		key, value, _ := strings.Cut(param, "=")
+		key, err := unescapeQuery(key, opts.PlusAsSpace)
+		if err != nil {
+			return nil, err
+		}
+		value, err = unescapeQuery(value, opts.PlusAsSpace)
+		if err != nil {
+			return nil, err
 		}
+		if opts.Arrays {
+			key = strings.TrimSuffix(key, "[]")
+		}
+		params[key] = append(params[key], value)
 	}
 
-	return Query{Params: params}, nil
+	return params, nil
+}
+
+// unescapeQuery percent-decodes a key or value of a query string, and '+'
+// to a space if plusAsSpace is set, failing with ErrInvalidQueryEscape on a
+// '%' not followed by two hex digits.
+func unescapeQuery(s string, plusAsSpace bool) (string, error) {
+	if plusAsSpace {
+		s = strings.ReplaceAll(s, "+", " ")
+	}
+	if !strings.Contains(s, "%") {
+		return s, nil
+	}
+	unescaped, err := url.PathUnescape(s)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q", ErrInvalidQueryEscape, s)
+	}
+	return unescaped, nil
+}
+
+// QueryParams returns the parameters of the query string, split and decoded
+// as the QueryOptions of the request say.
+func (r Request) QueryParams() (url.Values, error) {
+	query, err := parseQueryWith(r.GetRequestTarget().Query, r.QueryOptions)
+	if err != nil {
+		return nil, err
+	}
+	return query.Params, nil
 }
 
 // FindAndParseQuery extracts and parses the query string from a request target.
@@ -118,5 +181,8 @@ func FindAndParseQuery(requestTarget string) (Query, int, error) {
 		return Query{}, -1, err
 	}
 	parsedQuery, err := parseQuery(query)
+	if err != nil {
+		return Query{}, -1, err
+	}
 	return parsedQuery, queryIndex, nil
 }
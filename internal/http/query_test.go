@@ -1,7 +1,9 @@
 package http
 
 import (
+	"errors"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -209,3 +211,141 @@ func TestFindAndParseQuery(t *testing.T) {
 		})
 	}
 }
+
+func TestParseQueryLimits(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		err   error
+	}{
+		{"longest", "?q=" + strings.Repeat("a", MaxQueryLength-2), nil},
+		{"too long", "?q=" + strings.Repeat("a", MaxQueryLength-1), ErrQueryTooLong},
+		{"most parameters", "?" + strings.Repeat("a&", MaxQueryParams), nil},
+		{"too many parameters", "?" + strings.Repeat("a&", MaxQueryParams+1), ErrTooManyQueryParams},
+		{"empty parameters are not counted", "?" + strings.Repeat("&", MaxQueryParams+1), nil},
+		{"encoded separators", "?a%26b=c%3Dd", nil},
+		{"truncated escape", "?q=100%", ErrInvalidQueryEscape},
+		{"invalid escape in key", "?%zz=1", ErrInvalidQueryEscape},
+		{"invalid escape in value", "?q=%2", ErrInvalidQueryEscape},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseQuery(tt.query); !errors.Is(err, tt.err) {
+				t.Errorf("Expected error %v, got %v", tt.err, err)
+			}
+		})
+	}
+
+	query, err := parseQuery("?a%26b=c%3Dd&plus=a+b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string][]string{"a&b": {"c=d"}, "plus": {"a+b"}}
+	if !reflect.DeepEqual(query.Params, expected) {
+		t.Errorf("Expected %v, got %v", expected, query.Params)
+	}
+}
+
+func TestParseQueryOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		opts     QueryOptions
+		expected map[string][]string
+	}{
+		{"defaults", "?a=1;b=2&c[]=3&d=x+y", QueryOptions{}, map[string][]string{"a": {"1;b=2"}, "c[]": {"3"}, "d": {"x+y"}}},
+		{"semicolons", "?a=1;b=2&c=3;;", QueryOptions{Semicolons: true}, map[string][]string{"a": {"1"}, "b": {"2"}, "c": {"3"}}},
+		{"encoded semicolon", "?a=1%3Bb=2", QueryOptions{Semicolons: true}, map[string][]string{"a": {"1;b=2"}}},
+		{"arrays", "?tag[]=a&tag[]=b&tag=c", QueryOptions{Arrays: true}, map[string][]string{"tag": {"a", "b", "c"}}},
+		{"encoded brackets", "?tag%5B%5D=a", QueryOptions{Arrays: true}, map[string][]string{"tag": {"a"}}},
+		{"nested brackets", "?m[k]=a&m[k][]=b", QueryOptions{Arrays: true}, map[string][]string{"m[k]": {"a", "b"}}},
+		{"plus as space", "?q=x+y&p=%2B", QueryOptions{PlusAsSpace: true}, map[string][]string{"q": {"x y"}, "p": {"+"}}},
+		{"all", "?q[]=x+y;q[]=z", QueryOptions{Semicolons: true, Arrays: true, PlusAsSpace: true}, map[string][]string{"q": {"x y", "z"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := Request{
+				StartLine:    RequestStartLine{Method: GET, RequestTarget: RequestTarget{Path: "/", Query: tt.query}},
+				QueryOptions: tt.opts,
+			}
+			params, err := req.QueryParams()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(map[string][]string(params), tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, params)
+			}
+		})
+	}
+
+	if _, err := parseQueryWith("?"+strings.Repeat("a;", MaxQueryParams+1), QueryOptions{Semicolons: true}); !errors.Is(err, ErrTooManyQueryParams) {
+		t.Errorf("Expected error %v, got %v", ErrTooManyQueryParams, err)
+	}
+}
+
+func TestServerInvalidQuery(t *testing.T) {
+	server := newTestServer(t, nil)
+	tests := []struct {
+		name    string
+		target  string
+		message string
+	}{
+		{"invalid escape", "/index.html?q=%zz", "400 Bad Request: Invalid query encoding"},
+		{"too long", "/index.html?q=" + strings.Repeat("a", MaxQueryLength), "400 Bad Request: Query too long"},
+		{"too many parameters", "/index.html?" + strings.Repeat("a&", MaxQueryParams+1), "400 Bad Request: Too many query parameters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := get(t, server, tt.target)
+			if resp.GetStatusCode() != 400 || resp.GetBody() != tt.message {
+				t.Errorf("Expected 400 with %q, got %d with %q", tt.message, resp.GetStatusCode(), resp.GetBody())
+			}
+		})
+	}
+}
+
+func FuzzFindQuery(f *testing.F) {
+	for _, seed := range []string{"/page?name=value", "/page?a=1#frag", "/page#frag?a=1", "/?", "??", "/page?q= \t"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, target string) {
+		query, idx, err := findQuery(target)
+		if err != nil {
+			return
+		}
+		if !strings.HasPrefix(query, "?") || !strings.HasPrefix(target[idx:], query) {
+			t.Errorf("findQuery(%q) returned %q at %d, not a part of the target", target, query, idx)
+		}
+		if strings.Contains(query, "#") {
+			t.Errorf("findQuery(%q) returned %q, which includes the fragment", target, query)
+		}
+	})
+}
+
+func FuzzParseQuery(f *testing.F) {
+	for _, seed := range []string{"?name=value&name=other", "?a%26b=c%3Dd", "?%", "?%zz", "?&&=&", "?k=v=w", "?" + strings.Repeat("a&", 10)} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, query string) {
+		parsed, err := parseQuery(query)
+		if err != nil {
+			if !errors.Is(err, ErrQueryTooLong) && !errors.Is(err, ErrTooManyQueryParams) && !errors.Is(err, ErrInvalidQueryEscape) {
+				t.Errorf("parseQuery(%q) returned an unexpected error: %v", query, err)
+			}
+			return
+		}
+		if len(query) > MaxQueryLength+1 {
+			t.Errorf("parseQuery accepted a query of %d bytes", len(query))
+		}
+		count := 0
+		for _, values := range parsed.Params {
+			count += len(values)
+		}
+		if count > MaxQueryParams {
+			t.Errorf("parseQuery(%q) returned %d parameters", query, count)
+		}
+	})
+}
@@ -0,0 +1,80 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"runtime/debug"
+)
+
+// RequestIDHeader carries the ID of a request, set by a proxy in front of the
+// server or generated by the server.
+const RequestIDHeader = "X-Request-Id"
+
+// requestID returns the ID a proxy gave the request, if it is reasonable to
+// log, or a new random one.
+func requestID(req *Request) string {
+	if id, ok := GetHeader(req.Headers, RequestIDHeader); ok && validRequestID(id) {
+		return id
+	}
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// validRequestID reports whether id is short and free of characters that could forge log lines.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// safeRespond is respond, recovering from a panic while producing the
+// response. The panic and its stack trace are logged with the request ID, and
+// the client gets a 500 response, with the stack trace in dev mode. A panic
+// after the response started streaming ends the response where it was, without
+// the last chunk of a chunked body, so the client can tell it was cut.
+func (s *Server) safeRespond(w *responseWriter, req *Request) (resp Response) {
+	defer func() {
+		value := recover()
+		if value == nil {
+			return
+		}
+		stack := debug.Stack()
+		log.Printf("Panic serving %s %s (request %s): %v\n%s", req.GetMethod(), req.GetRequestTarget().Path, req.ID, value, stack)
+		if req.Trace != nil {
+			req.Trace.Panic = fmt.Sprintf("panic: %v\n\n%s", value, stack)
+		}
+
+		switch {
+		case w.hijacked:
+			w.conn.Close()
+			resp = w.response()
+		case w.streaming:
+			w.body.Reset()
+			w.aborted = true
+			resp = w.response()
+		default:
+			// The 500 response replaces the headers the handler added.
+			w.headers = nil
+			resp = s.panicResponse(req, value, stack)
+		}
+	}()
+	return s.respond(req)
+}
+
+// panicResponse creates the 500 response to a request whose handler panicked.
+func (s *Server) panicResponse(req *Request, value any, stack []byte) Response {
+	body := "500 Internal Server Error\n\nRequest ID: " + req.ID + "\n"
+	if s.Config.Server.Dev {
+		body += fmt.Sprintf("\npanic: %v\n\n%s", value, stack)
+	}
+	return newTextResponse(req.GetProtocol(), 500, body)
+}
@@ -0,0 +1,71 @@
+package http
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestHandlerPanic(t *testing.T) {
+	tests := []struct {
+		name      string
+		dev       bool
+		requestID string
+		wantBody  []string
+		wantLog   []string
+	}{
+		{"production", false, "", []string{"500 Internal Server Error", "Request ID: "}, []string{"Panic serving GET /boom", "boom"}},
+		{"dev mode shows the stack", true, "", []string{"panic: boom", "recover_test.go"}, nil},
+		{"proxy request ID", false, "abc-123", []string{"Request ID: abc-123"}, []string{"(request abc-123)"}},
+		{"invalid request ID is replaced", false, "bad id\x01", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			defer log.SetOutput(os.Stderr)
+
+			server := newTestServer(t, func(cfg *config.Config) {
+				cfg.Server.Dev = tt.dev
+			})
+			server.Handle("/boom", func(w ResponseWriter, req *Request) {
+				panic("boom")
+			})
+
+			var headers []string
+			if tt.requestID != "" {
+				headers = append(headers, "X-Request-Id: "+tt.requestID)
+			}
+			resp := get(t, server, "/boom", headers...)
+			if resp.GetStatusCode() != 500 {
+				t.Fatalf("Expected status 500, got %d", resp.GetStatusCode())
+			}
+			for _, want := range tt.wantBody {
+				if !strings.Contains(resp.GetBody(), want) {
+					t.Errorf("Expected the body to contain %q, got %q", want, resp.GetBody())
+				}
+			}
+			if !tt.dev && strings.Contains(resp.GetBody(), "goroutine") {
+				t.Errorf("Expected no stack trace outside dev mode, got %q", resp.GetBody())
+			}
+			if strings.Contains(resp.GetBody(), "bad id") {
+				t.Errorf("Expected the invalid request ID to be replaced, got %q", resp.GetBody())
+			}
+			for _, want := range tt.wantLog {
+				if !strings.Contains(logs.String(), want) {
+					t.Errorf("Expected the log to contain %q, got %q", want, logs.String())
+				}
+			}
+
+			// The server keeps serving after a panic.
+			if resp := get(t, server, "/index.html"); resp.GetStatusCode() != 200 {
+				t.Errorf("Expected status 200 after the panic, got %d", resp.GetStatusCode())
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package http
+
+import (
+	"strings"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// redactHeader returns a header as debug logs and dev error pages may show
+// it: with its value replaced by config.RedactedValue if logging.redact_headers
+// names it, otherwise unchanged.
+func (s *Server) redactHeader(header Header) Header {
+	for _, name := range s.Config.Logging.RedactHeaders {
+		if strings.EqualFold(header.Name, name) {
+			return Header{Name: header.Name, Value: config.RedactedValue}
+		}
+	}
+	return header
+}
+
+// redactHeaders returns a copy of headers with redactHeader applied to each.
+func (s *Server) redactHeaders(headers []Header) []Header {
+	redacted := make([]Header, len(headers))
+	for i, header := range headers {
+		redacted[i] = s.redactHeader(header)
+	}
+	return redacted
+}
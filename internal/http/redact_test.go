@@ -0,0 +1,61 @@
+package http
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestRedactHeader(t *testing.T) {
+	s := &Server{Config: config.DefaultConfig()}
+
+	tests := []struct {
+		header   Header
+		expected string
+	}{
+		{Header{Name: "Authorization", Value: "Bearer s3cret"}, config.RedactedValue},
+		{Header{Name: "cookie", Value: "session=s3cret"}, config.RedactedValue},
+		{Header{Name: "Set-Cookie", Value: "session=s3cret; HttpOnly"}, config.RedactedValue},
+		{Header{Name: "Proxy-Authorization", Value: "Basic czNjcmV0"}, config.RedactedValue},
+		{Header{Name: "User-Agent", Value: "curl"}, "curl"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header.Name, func(t *testing.T) {
+			if got := s.logHeader(tt.header); got.Name != tt.header.Name || got.Value != tt.expected {
+				t.Errorf("Expected %s: %q, got %s: %q", tt.header.Name, tt.expected, got.Name, got.Value)
+			}
+		})
+	}
+
+	s.Config.Logging.RedactHeaders = []string{"X-Api-Key"}
+	if got := s.redactHeader(Header{Name: "x-api-key", Value: "s3cret"}); got.Value != config.RedactedValue {
+		t.Errorf("Expected %q, got %q", config.RedactedValue, got.Value)
+	}
+	if got := s.redactHeader(Header{Name: "Cookie", Value: "session=s3cret"}); got.Value != "session=s3cret" {
+		t.Errorf("Expected %q, got %q", "session=s3cret", got.Value)
+	}
+}
+
+func TestRedactedDebugOutput(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Server.Dev = true
+		cfg.Locations = []config.LocationConfig{{Path: "/", DebugLogging: true}}
+	})
+	resp := get(t, server, "/missing.html", "Accept: text/html", "Authorization: Bearer s3cret", "Cookie: session=s3cret")
+
+	if !strings.Contains(resp.GetBody(), config.RedactedValue) || strings.Contains(resp.GetBody(), "s3cret") {
+		t.Errorf("Expected the dev error page to redact the credentials, got %q", resp.GetBody())
+	}
+	if !strings.Contains(logs.String(), "Authorization: "+config.RedactedValue) || strings.Contains(logs.String(), "s3cret") {
+		t.Errorf("Expected the debug log to redact the credentials, got %q", logs.String())
+	}
+}
@@ -2,9 +2,16 @@
 package http
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net"
+	"net/url"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/awaisamjad/volk/internal/oidc"
 )
 
 // Request errors
@@ -13,6 +20,11 @@ var (
 	ErrDirectoryTraversal   = errors.New("path attempts directory traversal")
 	ErrEmptyPath            = errors.New("path cannot be empty")
 	ErrForbiddenPathSegment = errors.New("path contains forbidden segment")
+	ErrObsoleteLineFolding  = errors.New("header uses obsolete line folding")
+	ErrMissingHost          = errors.New("HTTP/1.1 request is missing the Host header")
+	ErrMultipleHosts        = errors.New("request has more than one Host header")
+	ErrAmbiguousLength      = errors.New("request has both Content-Length and Transfer-Encoding")
+	ErrInvalidContentLength = errors.New("request has an invalid Content-Length")
 )
 
 // RequestStartLine represents the first line of an HTTP request
@@ -31,6 +43,58 @@ type Request struct {
 	StartLine RequestStartLine
 	Headers   []Header
 	Body      string
+
+	// Trailers are the trailer fields sent after a chunked request body.
+	Trailers []Header
+
+	// RemoteAddr is the network address of the client, set by the Server.
+	RemoteAddr string
+
+	// Country is the ISO country code of the client, set by the Server when GeoIP is enabled.
+	Country string
+
+	// Identity is the user logged in with OIDC, set by the Server in locations with auth = "oidc".
+	Identity *oidc.Identity
+
+	// Trace is set by the Server and records how the request was handled.
+	Trace *Trace
+
+	// ID identifies the request in logs, set by the Server from the
+	// X-Request-Id header or generated.
+	ID string
+
+	// TraceContext is the W3C Trace Context of the request, set by the Server
+	// from the traceparent and tracestate headers; nil if it has none.
+	TraceContext *TraceContext
+
+	// Deadline is when the client stops waiting for the response, set by
+	// the Server from server.handle_timeout and a Request-Timeout header;
+	// zero if there is none. Handlers pass the rest of it on with
+	// TimeoutHeader and Context. Serving files and archives stops once
+	// it passes, and a response finished after it is replaced with a 503.
+	Deadline time.Time
+
+	// SentMethod is the method of the request line when method override
+	// replaced it, empty otherwise.
+	SentMethod Method
+
+	// QueryOptions choose how QueryParams and ParseForm split and decode
+	// the query string, set by the Server from [query].
+	QueryOptions QueryOptions
+
+	// writer sends the response of a handler on the request's connection.
+	writer *responseWriter
+
+	// varies are the request headers the response depends on, recorded with Vary.
+	varies []string
+
+	// allowedLocations are the paths of the locations whose rules the request
+	// passed, so that checkLocation applies each of them once.
+	allowedLocations []string
+
+	// ctx is done at the deadline of the request and once it was answered,
+	// set by the Server; nil outside of it.
+	ctx context.Context
 }
 
 func (r Request) String() string {
@@ -74,6 +138,15 @@ func (r Request) GetProtocol() Protocol {
 	return r.StartLine.Protocol
 }
 
+// RemoteIP returns the IP address part of RemoteAddr.
+func (r Request) RemoteIP() string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 // NewRequest creates a new Request from a request string
 func NewRequest(request_string string) (Request, error) {
 	request, err := parseRequest(request_string)
@@ -147,9 +220,27 @@ func parseRequest(request string) (Request, error) {
 	request_target_str := startline_split[1]
 	protocol := Protocol(startline_split[2])
 
+	// RFC 7230 section 5.3.2: the absolute-form is accepted and its authority
+	// replaces the Host header
+	absoluteHost := ""
+	if isAbsoluteForm(request_target_str) {
+		u, err := url.Parse(request_target_str)
+		if err != nil || u.Host == "" {
+			return Request{}, fmt.Errorf("invalid request target: invalid absolute-form %q", request_target_str)
+		}
+		absoluteHost = u.Host
+		request_target_str = u.EscapedPath()
+		if request_target_str == "" {
+			request_target_str = "/"
+		}
+		if u.RawQuery != "" || u.ForceQuery {
+			request_target_str += "?" + u.RawQuery
+		}
+	}
+
 	path, err := parseRequestTarget(request_target_str)
 	if err != nil {
-		return Request{}, fmt.Errorf("invalid request target: %v", err)
+		return Request{}, fmt.Errorf("invalid request target: %w", err)
 	}
 
 	request_target := RequestTarget{
@@ -158,21 +249,11 @@ func parseRequest(request string) (Request, error) {
 		Fragment: "",
 	}
 
-	query, _, err := FindAndParseQuery(request_target_str)
+	// The query is kept as sent, in its order and encoding; parseRequestTarget
+	// has checked that it parses.
+	query, _, err := findQuery(request_target_str)
 	if err == nil {
-		request_target.Query = "?" + strings.Join(func() []string {
-			queryParts := []string{}
-			for k, vs := range query.Params {
-				for _, v := range vs {
-					if v == "" {
-						queryParts = append(queryParts, k)
-					} else {
-						queryParts = append(queryParts, k+"="+v)
-					}
-				}
-			}
-			return queryParts
-		}(), "&")
+		request_target.Query = query
 	}
 
 	fragment, _, err := FindAndParseFragment(request_target_str)
@@ -186,6 +267,11 @@ func parseRequest(request string) (Request, error) {
 			continue
 		}
 
+		// RFC 7230 section 3.2.4: a server must reject obs-fold with 400
+		if header_str[0] == ' ' || header_str[0] == '\t' {
+			return Request{}, fmt.Errorf("invalid header: %w", ErrObsoleteLineFolding)
+		}
+
 		header, err := parseHeader(header_str)
 		if err != nil {
 			return Request{}, fmt.Errorf("invalid header: %v", err)
@@ -194,6 +280,14 @@ func parseRequest(request string) (Request, error) {
 		headers = append(headers, header)
 	}
 
+	if absoluteHost != "" {
+		headers = replaceHost(headers, absoluteHost)
+	}
+
+	if err := validateFraming(protocol, headers); err != nil {
+		return Request{}, err
+	}
+
 	return Request{
 		StartLine: RequestStartLine{
 			Method:        method,
@@ -204,3 +298,57 @@ func parseRequest(request string) (Request, error) {
 		Body:    body,
 	}, nil
 }
+
+// isAbsoluteForm reports whether a request target is in absolute-form (RFC 7230 section 5.3.2).
+func isAbsoluteForm(requestTarget string) bool {
+	lower := strings.ToLower(requestTarget)
+	return strings.HasPrefix(lower, "http://") || strings.HasPrefix(lower, "https://")
+}
+
+// replaceHost replaces any Host headers by a single one with the given value.
+func replaceHost(headers []Header, host string) []Header {
+	replaced := []Header{{Name: "Host", Value: host}}
+	for _, header := range headers {
+		if !strings.EqualFold(header.Name, "Host") {
+			replaced = append(replaced, header)
+		}
+	}
+	return replaced
+}
+
+// validateFraming rejects requests whose Host or message length headers are
+// missing or ambiguous, as these are used for request smuggling.
+func validateFraming(protocol Protocol, headers []Header) error {
+	hosts := 0
+	contentLength := ""
+	hasTransferEncoding := false
+
+	for _, header := range headers {
+		switch {
+		case strings.EqualFold(header.Name, "Host"):
+			hosts++
+		case strings.EqualFold(header.Name, "Transfer-Encoding"):
+			hasTransferEncoding = true
+		case strings.EqualFold(header.Name, "Content-Length"):
+			if _, err := strconv.ParseUint(header.Value, 10, 63); err != nil {
+				return ErrInvalidContentLength
+			}
+			if contentLength != "" && contentLength != header.Value {
+				return ErrInvalidContentLength
+			}
+			contentLength = header.Value
+		}
+	}
+
+	if hosts > 1 {
+		return ErrMultipleHosts
+	}
+	if hosts == 0 && protocol == HTTP1_1 {
+		return ErrMissingHost
+	}
+	if hasTransferEncoding && contentLength != "" {
+		return ErrAmbiguousLength
+	}
+
+	return nil
+}
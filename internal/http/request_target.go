@@ -16,25 +16,30 @@ func (r RequestTarget) String() string {
 	return fmt.Sprintf("%s%s%s", r.Path, r.Query, r.Fragment)
 }
 
-// parseRequestTarget extracts the path from a request target
+// parseRequestTarget extracts the path from a request target. It fails if the
+// target is empty, or its fragment or query is invalid.
 func parseRequestTarget(requestTarget string) (string, error) {
-	fragment, fragmentIdx, err := FindAndParseFragment(requestTarget)
+	if requestTarget == "" {
+		return "", ErrEmptyPath
+	}
+
+	_, fragmentIdx, err := FindAndParseFragment(requestTarget)
 	if err != nil && err != ErrFragmentNotFound {
 		return "", err
 	}
 
-	query, queryIdx, err := FindAndParseQuery(requestTarget)
-	if err != nil && err != ErrQueryNotFound && err != ErrQueryEmpty {
+	_, queryIdx, err := FindAndParseQuery(requestTarget)
+	if err != nil {
 		return "", err
 	}
 
 	path := requestTarget
 
-	if fragment != "" {
-		path = requestTarget[:fragmentIdx]
+	if fragmentIdx != -1 {
+		path = path[:fragmentIdx]
 	}
 
-	if len(query.Params) > 0 {
+	if queryIdx != -1 {
 		path = path[:queryIdx]
 	}
 
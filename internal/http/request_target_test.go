@@ -1,6 +1,7 @@
 package http
 
 import (
+	"strings"
 	"testing"
 )
 
@@ -106,8 +107,14 @@ func TestParseRequestTarget(t *testing.T) {
 		},
 		{
 			name:          "Path with special characters",
+			requestTarget: "/path/!@$^&*()/resource",
+			expectedPath:  "/path/!@$^&*()/resource",
+			expectedError: false,
+		},
+		{
+			name:          "Path with special characters and fragment",
 			requestTarget: "/path/!@#$%^&*()/resource",
-			expectedPath:  "/path/!@#$%^&*()/resource",
+			expectedPath:  "/path/!@",
 			expectedError: false,
 		},
 		{
@@ -122,6 +129,17 @@ func TestParseRequestTarget(t *testing.T) {
 			expectedPath:  "/path/%20/resource",
 			expectedError: false,
 		},
+		{
+			name:          "Path with empty query",
+			requestTarget: "/search?",
+			expectedPath:  "/search",
+			expectedError: false,
+		},
+		{
+			name:          "Invalid query escape",
+			requestTarget: "/search?q=%zz",
+			expectedError: true,
+		},
 		{
 			name:          "Empty path",
 			requestTarget: "",
@@ -150,3 +168,18 @@ func TestParseRequestTarget(t *testing.T) {
 		})
 	}
 }
+
+func FuzzParseRequestTarget(f *testing.F) {
+	for _, seed := range []string{"/index.html", "/search?q=keyword#results", "/a#b?c", "/?%", "*", ""} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, target string) {
+		path, err := parseRequestTarget(target)
+		if err != nil {
+			return
+		}
+		if !strings.HasPrefix(target, path) || strings.ContainsAny(path, "?#") {
+			t.Errorf("parseRequestTarget(%q) returned path %q", target, path)
+		}
+	})
+}
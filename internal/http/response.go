@@ -65,6 +65,28 @@ func (r Response) GetBody() string {
 	return r.Body
 }
 
+// newTextResponse creates a plain text response with the given status.
+func newTextResponse(protocol Protocol, statusCode StatusCode, body string) Response {
+	return Response{
+		StartLine: ResponseStartLine{
+			Protocol:   protocol,
+			StatusCode: statusCode,
+			StatusText: StatusCodeMap[statusCode],
+		},
+		Headers: []Header{
+			{Name: "Content-Type", Value: "text/plain"},
+		},
+		Body: body,
+	}
+}
+
+// newRedirectResponse creates a response redirecting to location with the given 3xx status.
+func newRedirectResponse(protocol Protocol, statusCode StatusCode, location string) Response {
+	resp := newTextResponse(protocol, statusCode, fmt.Sprintf("%d %s", statusCode, StatusCodeMap[statusCode]))
+	resp.Headers = append(resp.Headers, Header{Name: "Location", Value: location})
+	return resp
+}
+
 // NewResponse creates a new Response from a response string
 func NewResponse(response_string string) (Response, error) {
 	response, err := parseResponse(response_string)
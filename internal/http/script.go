@@ -0,0 +1,142 @@
+package http
+
+import (
+	"log"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/awaisamjad/volk/config"
+	"github.com/awaisamjad/volk/internal/script"
+	"github.com/awaisamjad/volk/internal/session"
+)
+
+// requestEnv gives script conditions access to a request of a location.
+// Scripts only see the headers the location allows them to read, and the
+// values of headers named in logging.redact_headers are hidden from them
+// unless the location allows those by name.
+type requestEnv struct {
+	s        *Server
+	req      *Request
+	location config.LocationConfig
+}
+
+func (e requestEnv) Var(name string) string {
+	switch name {
+	case "method":
+		return string(e.req.GetMethod())
+	case "path":
+		return e.req.GetRequestTarget().Path
+	case "query":
+		return strings.TrimPrefix(e.req.GetRequestTarget().Query, "?")
+	case "host":
+		host, _ := GetHeader(e.req.Headers, "Host")
+		return host
+	case "protocol":
+		return string(e.req.GetProtocol())
+	case "remote_ip":
+		return e.req.RemoteIP()
+	case "country":
+		return e.req.Country
+	}
+	return ""
+}
+
+func (e requestEnv) Call(fn, arg string) string {
+	switch fn {
+	case "header":
+		e.req.Vary(arg)
+		return e.header(arg)
+	case "cookie":
+		e.req.Vary("Cookie")
+		cookie, _ := GetHeader(e.req.Headers, "Cookie")
+		value, _ := session.Cookie(cookie, arg)
+		return value
+	case "param":
+		query, _ := e.req.QueryParams()
+		return query.Get(arg)
+	case "segment":
+		return e.segment(arg)
+	}
+	return ""
+}
+
+// header returns the value of the request header name as scripts may see it:
+// empty for a header the location does not let scripts read, and
+// config.RedactedValue for a redacted one that was sent, so conditions can
+// still test that it is there.
+func (e requestEnv) header(name string) string {
+	allowed := func(names []string) bool {
+		return slices.ContainsFunc(names, func(n string) bool { return strings.EqualFold(n, name) })
+	}
+	if len(e.location.ScriptHeaders) > 0 {
+		if !allowed(e.location.ScriptHeaders) {
+			return ""
+		}
+	} else if allowed(e.s.Config.Logging.RedactHeaders) {
+		if _, ok := GetHeader(e.req.Headers, name); ok {
+			return config.RedactedValue
+		}
+		return ""
+	}
+	value, _ := GetHeader(e.req.Headers, name)
+	return value
+}
+
+// segment returns the path segment at the 1-based index arg, counted from
+// the location's path, so in the location /users/ segment("1") of
+// /users/42/edit is 42. It is empty for a segment the path does not have.
+func (e requestEnv) segment(arg string) string {
+	i, err := strconv.Atoi(arg)
+	path := e.req.GetRequestTarget().Path
+	if err != nil || i < 1 || len(path) < len(e.location.Path) {
+		return ""
+	}
+	rest := strings.Trim(path[len(e.location.Path):], "/")
+	if rest == "" {
+		return ""
+	}
+	segments := strings.Split(rest, "/")
+	if i > len(segments) {
+		return ""
+	}
+	return segments[i-1]
+}
+
+// runScripts evaluates the script rules of the request's location. A
+// redirect is returned with true. Otherwise the request headers and the path
+// are changed as the rules say, and the headers to add to the response are
+// returned.
+func (s *Server) runScripts(req *Request, location config.LocationConfig, rules []script.Rule) (Response, []Header, bool) {
+	result := script.Run(rules, requestEnv{s: s, req: req, location: location})
+
+	var responseHeaders []Header
+	for _, header := range result.ResponseHeaders {
+		responseHeaders = append(responseHeaders, Header{Name: header.Name, Value: header.Value})
+	}
+
+	if result.Redirect != "" {
+		resp := newRedirectResponse(req.GetProtocol(), StatusCode(result.Status), result.Redirect)
+		resp.Headers = mergeHeaders(resp.Headers, responseHeaders...)
+		return resp, nil, true
+	}
+
+	for _, header := range result.RequestHeaders {
+		req.Headers = append(removeHeader(req.Headers, header.Name), Header{Name: header.Name, Value: header.Value})
+	}
+
+	if result.Rewrite != "" {
+		rewrite, query, hasQuery := strings.Cut(result.Rewrite, "?")
+		path, err := NormalizePath(rewrite)
+		if err != nil || !strings.HasPrefix(path, "/") {
+			log.Printf("Error rewriting %s to %s: invalid path", req.GetRequestTarget().Path, result.Rewrite)
+			return newTextResponse(req.GetProtocol(), 500, "500 Internal Server Error"), nil, true
+		}
+		req.StartLine.RequestTarget.Path = path
+		if hasQuery {
+			req.StartLine.RequestTarget.Query = "?" + query
+		}
+	}
+
+	return Response{}, responseHeaders, false
+}
@@ -0,0 +1,151 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestServerScripts(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Locations = []config.LocationConfig{{
+			Path: "/",
+			Scripts: []config.ScriptConfig{
+				{If: `header("User-Agent") contains "Mobile"`, Redirect: "https://m.example.com$path", Status: 301},
+				{If: `cookie("variant") == "b"`, Rewrite: "/index.html", ResponseHeaders: map[string]string{"X-Variant": "b"}},
+				{If: `path == "/home" && param("lang") == "en"`, Rewrite: "/index.html"},
+				{RequestHeaders: map[string]string{"X-Seen-Path": "$path"}},
+			},
+		}}
+	})
+	server.Handle("/echo", func(w ResponseWriter, req *Request) {
+		seen, _ := GetHeader(req.Headers, "X-Seen-Path")
+		w.Write([]byte(seen))
+	})
+
+	tests := []struct {
+		name       string
+		path       string
+		headers    []string
+		wantCode   StatusCode
+		wantHeader Header
+		wantBody   string
+	}{
+		{"redirect", "/docs", []string{"User-Agent: Mobile Safari"}, 301, Header{Name: "Location", Value: "https://m.example.com/docs"}, ""},
+		{"rewrite with a cookie", "/anything", []string{"Cookie: variant=b"}, 200, Header{Name: "X-Variant", Value: "b"}, ""},
+		{"rewrite with a query parameter", "/home?lang=en", nil, 200, Header{}, ""},
+		{"no match", "/home?lang=de", nil, 404, Header{}, ""},
+		{"request header", "/echo", nil, 200, Header{}, "/echo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := get(t, server, tt.path, tt.headers...)
+			if resp.GetStatusCode() != tt.wantCode {
+				t.Errorf("Expected status %d, got %d", tt.wantCode, resp.GetStatusCode())
+			}
+			if tt.wantHeader.Name != "" {
+				if value, _ := GetHeader(resp.Headers, tt.wantHeader.Name); value != tt.wantHeader.Value {
+					t.Errorf("Expected %s %q, got %q", tt.wantHeader.Name, tt.wantHeader.Value, value)
+				}
+			}
+			if tt.wantBody != "" && resp.GetBody() != tt.wantBody {
+				t.Errorf("Expected body %q, got %q", tt.wantBody, resp.GetBody())
+			}
+		})
+	}
+}
+
+func TestServerScriptRewriteChecksLocation(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Locations = []config.LocationConfig{
+			{Path: "/", Scripts: []config.ScriptConfig{
+				{If: `path == "/public/index.html"`, Rewrite: "/index.html"},
+				{If: `path == "/leak"`, Rewrite: "/internal/index.html"},
+			}},
+			{Path: "/internal/", SignatureSecret: "s3cret"},
+		}
+	})
+
+	if resp := get(t, server, "/public/index.html"); resp.GetStatusCode() != 200 {
+		t.Errorf("Expected status 200 for a rewrite within the location, got %d", resp.GetStatusCode())
+	}
+	if resp := get(t, server, "/leak"); resp.GetStatusCode() != 401 {
+		t.Errorf("Expected status 401 for an unsigned rewrite into a signed location, got %d", resp.GetStatusCode())
+	}
+}
+
+func TestServerScriptError(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Locations = []config.LocationConfig{{Path: "/", Scripts: []config.ScriptConfig{{If: `path ===`}}}}
+	if _, err := NewServer(cfg); err == nil {
+		t.Error("Expected an error for an invalid script, got nil")
+	}
+}
+
+func TestServerScriptReplacesFileHeaders(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Locations = []config.LocationConfig{{
+			Path:    "/",
+			Scripts: []config.ScriptConfig{{ResponseHeaders: map[string]string{"Content-Type": "text/plain"}}},
+		}}
+	})
+
+	resp := get(t, server, "/index.html")
+	if count := countHeaders(resp.Headers, "Content-Type"); count != 1 {
+		t.Errorf("Expected 1 Content-Type header, got %d: %v", count, resp.Headers)
+	}
+	if contentType, _ := GetHeader(resp.Headers, "Content-Type"); contentType != "text/plain" {
+		t.Errorf("Expected Content-Type text/plain, got %q", contentType)
+	}
+	if count := countHeaders(resp.Headers, "Content-Length"); count != 1 {
+		t.Errorf("Expected 1 Content-Length header, got %d", count)
+	}
+}
+
+func TestServerScriptVariables(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Locations = []config.LocationConfig{
+			{Path: "/users/", Scripts: []config.ScriptConfig{
+				{If: `segment("2") == "edit"`, Redirect: "/login", Status: 302},
+				{If: `header("Authorization") == "********"`, ResponseHeaders: map[string]string{"X-Auth": "redacted"}},
+				{If: `header("Authorization") prefix "Bearer"`, ResponseHeaders: map[string]string{"X-Auth": "read"}},
+				{If: `header("User-Agent") != ""`, ResponseHeaders: map[string]string{"X-Agent": "seen"}},
+				{If: `segment("1") != ""`, RequestHeaders: map[string]string{"X-User": "set"}},
+			}},
+			{Path: "/api/", ScriptHeaders: []string{"authorization"}, Scripts: []config.ScriptConfig{
+				{If: `header("Authorization") prefix "Bearer"`, ResponseHeaders: map[string]string{"X-Auth": "read"}},
+				{If: `header("User-Agent") != ""`, ResponseHeaders: map[string]string{"X-Agent": "seen"}},
+			}},
+		}
+	})
+
+	tests := []struct {
+		name        string
+		path        string
+		headers     []string
+		wantCode    StatusCode
+		wantHeaders map[string]string
+	}{
+		{"segment", "/users/42/edit", nil, 302, map[string]string{"Location": "/login"}},
+		{"segment out of range", "/users/42", nil, 404, nil},
+		{"redacted header", "/users/42", []string{"Authorization: Bearer abc"}, 404, map[string]string{"X-Auth": "redacted"}},
+		{"header", "/users/42", []string{"User-Agent: curl"}, 404, map[string]string{"X-Agent": "seen", "X-Auth": ""}},
+		{"allowed header", "/api/items", []string{"Authorization: Bearer abc"}, 404, map[string]string{"X-Auth": "read"}},
+		{"header not allowed", "/api/items", []string{"User-Agent: curl"}, 404, map[string]string{"X-Agent": ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := get(t, server, tt.path, tt.headers...)
+			if resp.GetStatusCode() != tt.wantCode {
+				t.Errorf("Expected status %d, got %d", tt.wantCode, resp.GetStatusCode())
+			}
+			for name, want := range tt.wantHeaders {
+				if value, _ := GetHeader(resp.Headers, name); value != want {
+					t.Errorf("Expected %s %q, got %q", name, want, value)
+				}
+			}
+		})
+	}
+}
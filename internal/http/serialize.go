@@ -0,0 +1,120 @@
+package http
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+)
+
+// ErrInvalidResponseHeader is returned when a response header cannot be sent
+// as is: its name is not a token, or its value contains a line break or NUL,
+// which would let the value add headers of its own or end the head early.
+var ErrInvalidResponseHeader = errors.New("invalid response header")
+
+// bufferSize is the size of the buffered writers responses are serialized
+// with. Heads and small bodies go out in a single write.
+const bufferSize = 4096
+
+// writers reuses buffered writers between responses.
+var writers = sync.Pool{
+	New: func() any { return bufio.NewWriterSize(nil, bufferSize) },
+}
+
+// writeResponse writes resp to w through a pooled buffered writer and returns
+// the number of bytes written. The headers are checked before anything is
+// written, so an invalid response writes nothing. resp is not modified.
+func writeResponse(w io.Writer, resp Response) (int, error) {
+	bw := writers.Get().(*bufio.Writer)
+	bw.Reset(w)
+	defer func() {
+		bw.Reset(nil)
+		writers.Put(bw)
+	}()
+
+	n, err := serializeResponse(bw, resp, true)
+	if errors.Is(err, ErrInvalidResponseHeader) {
+		return 0, err
+	}
+	if err == nil {
+		err = bw.Flush()
+	}
+	if err != nil {
+		// A failed writer keeps what it could not write in its buffer.
+		return n - bw.Buffered(), fmt.Errorf("error writing response: %w", err)
+	}
+	return n, nil
+}
+
+// serializeResponse writes the head of resp to bw, followed by the body if
+// withBody is set, and returns the number of bytes written to bw. The
+// headers are checked first; nothing is written when one is invalid.
+func serializeResponse(bw *bufio.Writer, resp Response, withBody bool) (int, error) {
+	if err := checkResponseHeaders(resp.Headers); err != nil {
+		return 0, err
+	}
+
+	var n int
+	write := func(s string) {
+		m, _ := bw.WriteString(s)
+		n += m
+	}
+	var scratch [20]byte
+	write(string(resp.StartLine.Protocol))
+	write(" ")
+	write(string(strconv.AppendInt(scratch[:0], int64(resp.StartLine.StatusCode), 10)))
+	write(" ")
+	write(string(resp.StartLine.StatusText))
+	write(CRLF)
+	for _, header := range resp.Headers {
+		write(header.Name)
+		write(": ")
+		write(header.Value)
+		write(CRLF)
+	}
+	write(CRLF)
+	if withBody {
+		// Bodies larger than the buffer go out in several writes.
+		write(resp.Body)
+	}
+	// The writer's error is sticky: a failed write fails every later one.
+	_, err := bw.Write(nil)
+	return n, err
+}
+
+// checkResponseHeaders returns ErrInvalidResponseHeader for the first header
+// that cannot be sent as is.
+func checkResponseHeaders(headers []Header) error {
+	for _, header := range headers {
+		if header.Name == "" {
+			return fmt.Errorf("%w: empty name", ErrInvalidResponseHeader)
+		}
+		for i := 0; i < len(header.Name); i++ {
+			if !isTokenChar(header.Name[i]) {
+				return fmt.Errorf("%w: name %q", ErrInvalidResponseHeader, header.Name)
+			}
+		}
+		for i := 0; i < len(header.Value); i++ {
+			if c := header.Value[i]; c == '\r' || c == '\n' || c == 0 {
+				return fmt.Errorf("%w: value of %s contains %q", ErrInvalidResponseHeader, header.Name, c)
+			}
+		}
+	}
+	return nil
+}
+
+// isTokenChar reports whether c may appear in a token, as defined in RFC 9110
+// section 5.6.2.
+func isTokenChar(c byte) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		return true
+	}
+	switch c {
+	case '!', '#', '$', '%', '&', '\'', '*', '+', '-', '.', '^', '_', '`', '|', '~':
+		return true
+	}
+	return false
+}
@@ -0,0 +1,219 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+// gzipped returns s compressed with gzip, as a binary response body.
+func gzipped(t *testing.T, s string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	zw.Write([]byte(s))
+	zw.Close()
+	// The header holds no modification time, so the bytes are stable.
+	return buf.String()
+}
+
+// checkGolden compares got with testdata/serialize/name.golden, or writes the
+// file with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	golden := filepath.Join("testdata", "serialize", name+".golden")
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(golden), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	expected, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("missing golden file, run with -update: %v", err)
+	}
+	if !bytes.Equal(got, expected) {
+		t.Errorf("serialized bytes mismatch.\nExpected: %q\nGot: %q", expected, got)
+	}
+}
+
+// TestWriteResponseGolden serializes responses of each status class and body
+// encoding and compares the bytes with the golden files in testdata/serialize.
+// Run `go test -run TestWriteResponseGolden -update` to regenerate them.
+func TestWriteResponseGolden(t *testing.T) {
+	status := func(protocol Protocol, code StatusCode) ResponseStartLine {
+		return ResponseStartLine{Protocol: protocol, StatusCode: code, StatusText: StatusCodeMap[code]}
+	}
+	html := "<h1>Hello World</h1>\n"
+	compressed := gzipped(t, html)
+
+	tests := []struct {
+		name string
+		resp Response
+	}{
+		{"200_identity", Response{status(HTTP1_1, 200), []Header{{"Content-Type", "text/html; charset=utf-8"}, {"Content-Length", "21"}}, html}},
+		{"200_gzip", Response{status(HTTP1_1, 200), []Header{{"Content-Type", "text/html; charset=utf-8"}, {"Content-Encoding", "gzip"}, {"Vary", "Accept-Encoding"}, {"Content-Length", strconv.Itoa(len(compressed))}}, compressed}},
+		{"200_http10", Response{status(HTTP1_0, 200), []Header{{"Content-Type", "text/plain"}, {"Content-Length", "2"}}, "ok"}},
+		{"200_empty", Response{status(HTTP1_1, 200), []Header{{"Content-Length", "0"}}, ""}},
+		{"204_no_content", Response{status(HTTP1_1, 204), nil, ""}},
+		{"206_partial", Response{status(HTTP1_1, 206), []Header{{"Content-Range", "bytes 0-4/21"}, {"Content-Length", "5"}}, "<h1>H"}},
+		{"301_redirect", newRedirectResponse(HTTP1_1, 301, "/docs/")},
+		{"304_not_modified", Response{status(HTTP1_1, 304), []Header{{"ETag", `"abc"`}, {"Vary", "Accept-Encoding"}}, ""}},
+		{"404_text", newTextResponse(HTTP1_1, 404, "404 Not Found")},
+		{"500_text", newTextResponse(HTTP1_1, 500, "500 Internal Server Error")},
+		{"large_body", Response{status(HTTP1_1, 200), []Header{{"Content-Length", "10000"}}, string(bytes.Repeat([]byte("0123456789"), 1000))}},
+		{"duplicate_headers", Response{status(HTTP1_1, 200), []Header{{"Set-Cookie", "a=1"}, {"Set-Cookie", "b=2"}, {"Content-Length", "0"}}, ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := Response{StartLine: tt.resp.StartLine, Headers: append([]Header(nil), tt.resp.Headers...), Body: tt.resp.Body}
+
+			var buf bytes.Buffer
+			n, err := writeResponse(&buf, tt.resp)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if n != buf.Len() {
+				t.Errorf("Expected %d bytes reported, got %d", buf.Len(), n)
+			}
+			if !reflect.DeepEqual(tt.resp, before) {
+				t.Errorf("Expected the response to be unchanged, got %+v", tt.resp)
+			}
+			if buf.String() != tt.resp.String() {
+				t.Errorf("Expected the same bytes as String.\nExpected: %q\nGot: %q", tt.resp.String(), buf.String())
+			}
+			checkGolden(t, tt.name, buf.Bytes())
+		})
+	}
+}
+
+// recordingConn is a connection that records what is written to it.
+type recordingConn struct {
+	net.Conn
+	buf bytes.Buffer
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	return c.buf.Write(p)
+}
+
+func TestResponseWriterGolden(t *testing.T) {
+	tests := []struct {
+		name     string
+		method   Method
+		protocol Protocol
+		handler  func(w ResponseWriter)
+	}{
+		{"streamed_chunked", GET, HTTP1_1, func(w ResponseWriter) {
+			w.AddHeader("Content-Type", "text/event-stream")
+			w.Write([]byte("data: first\n\n"))
+			w.Flush()
+			w.Write([]byte("data: second\n\n"))
+		}},
+		{"streamed_http10", GET, HTTP1_0, func(w ResponseWriter) {
+			w.AddHeader("Content-Type", "text/event-stream")
+			w.Write([]byte("data: first\n\n"))
+			w.Flush()
+			w.Write([]byte("data: second\n\n"))
+		}},
+		{"streamed_content_length", GET, HTTP1_1, func(w ResponseWriter) {
+			w.AddHeader("Content-Type", "text/plain")
+			w.AddHeader("Content-Length", "11")
+			w.Write([]byte("hello "))
+			w.Flush()
+			w.Write([]byte("world"))
+		}},
+		{"streamed_aborted", GET, HTTP1_1, func(w ResponseWriter) {
+			w.AddHeader("Content-Type", "application/zip")
+			w.Write([]byte("PK\x03\x04"))
+			w.Flush()
+			w.(*responseWriter).aborted = true
+		}},
+		{"streamed_chunked_trailers", GET, HTTP1_1, func(w ResponseWriter) {
+			w.AddHeader("Trailer", "X-Checksum")
+			w.Write([]byte("hello "))
+			w.Flush()
+			w.Write([]byte("world"))
+			w.AddTrailer("X-Checksum", "abc")
+			w.AddTrailer("Content-Length", "11")
+		}},
+		{"streamed_head", HEAD, HTTP1_1, func(w ResponseWriter) {
+			w.AddHeader("Content-Type", "text/plain")
+			w.Write([]byte("not sent"))
+			w.Flush()
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &recordingConn{}
+			req := &Request{StartLine: RequestStartLine{Method: tt.method, Protocol: tt.protocol}}
+			w := newResponseWriter(conn, bufio.NewReader(&bytes.Buffer{}), req)
+			tt.handler(w)
+			if err := w.finish(); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if w.written != conn.buf.Len() {
+				t.Errorf("Expected %d bytes counted, got %d", conn.buf.Len(), w.written)
+			}
+			checkGolden(t, tt.name, conn.buf.Bytes())
+		})
+	}
+}
+
+func TestWriteResponseInvalidHeaders(t *testing.T) {
+	tests := []struct {
+		name   string
+		header Header
+	}{
+		{"line feed in value", Header{Name: "Location", Value: "/a\nSet-Cookie: admin=1"}},
+		{"carriage return in value", Header{Name: "X-Note", Value: "a\rb"}},
+		{"NUL in value", Header{Name: "X-Note", Value: "a\x00b"}},
+		{"space in name", Header{Name: "X Note", Value: "a"}},
+		{"colon in name", Header{Name: "X-Note:", Value: "a"}},
+		{"empty name", Header{Name: "", Value: "a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := newTextResponse(HTTP1_1, 200, "ok")
+			resp.Headers = append(resp.Headers, tt.header)
+			var buf bytes.Buffer
+			n, err := writeResponse(&buf, resp)
+			if !errors.Is(err, ErrInvalidResponseHeader) {
+				t.Errorf("Expected ErrInvalidResponseHeader, got %v", err)
+			}
+			if n != 0 || buf.Len() != 0 {
+				t.Errorf("Expected nothing written, got %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestServerInvalidResponseHeader(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.Handle("/split", ResponseHandler(func(req *Request) Response {
+		resp := newTextResponse(req.GetProtocol(), 302, "302 Found")
+		resp.Headers = append(resp.Headers, Header{Name: "Location", Value: "/\r\nSet-Cookie: admin=1"})
+		return resp
+	}))
+
+	resp := get(t, server, "/split")
+	if resp.GetStatusCode() != 500 {
+		t.Errorf("Expected status 500, got %d", resp.GetStatusCode())
+	}
+	if _, ok := GetHeader(resp.Headers, "Set-Cookie"); ok {
+		t.Errorf("Expected no injected header, got %v", resp.Headers)
+	}
+}
@@ -0,0 +1,1256 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+	"github.com/awaisamjad/volk/internal/audit"
+	"github.com/awaisamjad/volk/internal/bots"
+	"github.com/awaisamjad/volk/internal/deploy"
+	"github.com/awaisamjad/volk/internal/geoip"
+	"github.com/awaisamjad/volk/internal/harden"
+	"github.com/awaisamjad/volk/internal/httpdate"
+	"github.com/awaisamjad/volk/internal/kv"
+	"github.com/awaisamjad/volk/internal/metrics"
+	"github.com/awaisamjad/volk/internal/mimetype"
+	"github.com/awaisamjad/volk/internal/oidc"
+	"github.com/awaisamjad/volk/internal/openapi"
+	"github.com/awaisamjad/volk/internal/plugin"
+	"github.com/awaisamjad/volk/internal/scan"
+	"github.com/awaisamjad/volk/internal/script"
+	"github.com/awaisamjad/volk/internal/session"
+	"github.com/awaisamjad/volk/internal/signature"
+	"github.com/awaisamjad/volk/internal/sitemap"
+	"github.com/awaisamjad/volk/internal/stats"
+	"github.com/awaisamjad/volk/internal/storage"
+	"github.com/awaisamjad/volk/internal/summary"
+	"github.com/awaisamjad/volk/internal/tee"
+	"github.com/awaisamjad/volk/internal/thumbnail"
+	"github.com/awaisamjad/volk/internal/trap"
+	"github.com/awaisamjad/volk/internal/upload"
+	"github.com/awaisamjad/volk/internal/watch"
+	"github.com/awaisamjad/volk/internal/webhook"
+)
+
+// ErrServerClosed is returned by Serve and ListenAndServe after Close is called.
+var ErrServerClosed = errors.New("server closed")
+
+// Server accepts connections and answers HTTP requests using a FileServer.
+type Server struct {
+	Config     config.Config
+	FileServer *FileServer
+
+	// Version is the version of volk, sent in the Server header with server.expose_version.
+	Version string
+
+	// Stats, if set, counts every request. It is flushed periodically while the server runs.
+	Stats *stats.Collector
+	// Summary, if set, counts every request for the summary logged periodically while the server runs.
+	Summary *summary.Window
+
+	// Audit, if set, records every write request in a tamper-evident log.
+	Audit *audit.Log
+
+	// Tee, if set, copies public responses to a mirror.
+	Tee *tee.Tee
+
+	// GeoIP, if set, is used to tag requests with the client's country and to
+	// enforce the country rules of locations.
+	GeoIP *geoip.DB
+
+	// Bots holds the User-Agent rules for crawlers.
+	Bots *bots.Bots
+
+	// Traps holds the honeypot paths and the clients they banned, if any are configured.
+	Traps *trap.Traps
+
+	// Sitemap, if set, generates sitemap.xml for document roots without one.
+	Sitemap *sitemap.Generator
+
+	// RequestHook, if set, is called with every request and its response
+	// before the response is written.
+	RequestHook func(req Request, resp Response)
+
+	// OIDC, if set, logs users in for locations with auth = "oidc".
+	OIDC *oidc.Authenticator
+
+	// KV, if set, stores the values of the key-value JSON API.
+	KV *kv.Store
+
+	// Deployer, if set, publishes the archives uploaded to the deploy endpoint.
+	Deployer *deploy.Deployer
+
+	// Uploads, if set, stores the resumable uploads of the upload endpoint.
+	Uploads *upload.Store
+
+	// Webhooks receive the deliveries posted to the configured webhook paths.
+	Webhooks []*webhook.Receiver
+
+	// Metrics, if set, counts requests for the metrics endpoint.
+	Metrics *metrics.Registry
+
+	// OpenAPI, if set, validates the requests to the paths of the API it describes.
+	OpenAPI *openapi.Spec
+
+	// Sessions signs the cookies of the server, such as flash messages.
+	Sessions *session.Signer
+
+	// handlers are the handlers registered with Handle, by path pattern.
+	handlers map[string]route
+
+	// connLimit counts the connections per client when server.max_connections_per_ip is set.
+	connLimit *ipLimiter
+
+	// upgrades are the handlers registered with HandleUpgrade, by lowercase protocol token.
+	upgrades map[string]UpgradeHandler
+
+	// verifiers check the signed requests of locations with a signature_secret, by location path.
+	verifiers map[string]*signature.Verifier
+
+	// scripts are the compiled script rules of locations, by location path.
+	scripts map[string][]script.Rule
+
+	// splits are the A/B tests of locations with variants, by location path.
+	splits map[string]*variantSplit
+	// flavors are the header routes of locations with flavors, by location path.
+	flavors map[string]*flavorRoutes
+	// userDirs serves the directories of users on their subdomains, if userdir is enabled.
+	userDirs *userDirs
+
+	// thumbnails generates the thumbnails of the thumbnail endpoint, if enabled.
+	thumbnails *thumbnail.Generator
+
+	// watchers drop cache entries of changed files, if file_server.watch is set.
+	watchers []*watch.Watcher
+
+	// robotsFile keeps the parsed robots.txt for the bot rules with the robots action.
+	robotsFile robotsCache
+
+	mu       sync.Mutex
+	listener net.Listener
+	closed   bool
+	conns    sync.WaitGroup // Connections being handled, waited for by Shutdown
+
+	draining atomic.Bool  // Set by Drain, cleared by Resume
+	inFlight atomic.Int64 // Requests being handled
+
+	started   time.Time    // When the server was created, for the uptime
+	requests  atomic.Int64 // Requests answered
+	bytesSent atomic.Int64 // Bytes of the responses written
+}
+
+// NewServer creates a new Server from the given configuration.
+// It returns an error if a resource named in the configuration cannot be loaded.
+func NewServer(cfg config.Config) (*Server, error) {
+	if cfg.Server.Hardened {
+		if err := harden.Check(cfg, 0); err != nil {
+			return nil, err
+		}
+		cfg.FileServer.Beneath = true
+	}
+
+	server := &Server{
+		Config:     cfg,
+		FileServer: NewFileServer(cfg.FileServer),
+		Sessions:   session.NewSigner(cfg.Session.Secret),
+		verifiers:  make(map[string]*signature.Verifier),
+		scripts:    make(map[string][]script.Rule),
+		splits:     make(map[string]*variantSplit),
+		flavors:    make(map[string]*flavorRoutes),
+		started:    time.Now(),
+	}
+
+	if err := mimetype.Check(cfg.FileServer.MimeTypes); err != nil {
+		return nil, fmt.Errorf("invalid file_server.mime_types: %w", err)
+	}
+
+	if cfg.Compression.Level < 0 || cfg.Compression.Level > 9 {
+		return nil, fmt.Errorf("compression level %d is not between 1 and 9", cfg.Compression.Level)
+	}
+
+	switch cfg.FileServer.ETag {
+	case "", "weak", "strong":
+	default:
+		return nil, fmt.Errorf("unknown file_server.etag %q", cfg.FileServer.ETag)
+	}
+
+	switch cfg.Server.Mode {
+	case "", "goroutine", "epoll":
+	default:
+		return nil, fmt.Errorf("unknown server mode %q", cfg.Server.Mode)
+	}
+
+	if cfg.Robots.BaseURL != "" {
+		baseURL, err := normalizeURL(cfg.Robots.BaseURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid robots base_url: %w", err)
+		}
+		server.Config.Robots.BaseURL = baseURL
+	}
+
+	if cfg.Server.MaxConnectionsPerIP > 0 {
+		server.connLimit = newIPLimiter(cfg.Server.MaxConnectionsPerIP)
+	}
+
+	if cfg.Audit.FilePath != "" {
+		auditLog, err := audit.Open(cfg.Audit.FilePath, []byte(cfg.Audit.Key))
+		if err != nil {
+			return nil, err
+		}
+		server.Audit = auditLog
+	}
+
+	mirror, err := tee.New(cfg.Tee)
+	if err != nil {
+		return nil, err
+	}
+	server.Tee = mirror
+
+	if cfg.Stats.Enabled {
+		server.Stats = stats.NewCollector(cfg.Stats.FilePath)
+	}
+	if cfg.Summary.Enabled {
+		window, err := summary.New(time.Duration(cfg.Summary.Interval)*time.Second, time.Duration(cfg.Summary.Window)*time.Second, cfg.Summary.Top)
+		if err != nil {
+			return nil, err
+		}
+		server.Summary = window
+	}
+	if cfg.Stats.Beacon {
+		if server.Stats == nil {
+			return nil, errors.New("the beacon endpoint needs [stats] enabled")
+		}
+		server.Handle(cfg.Stats.BeaconPath, ResponseHandler(beaconHandler(server.Stats)), GET, POST)
+	}
+
+	if cfg.GeoIP.Database != "" {
+		db, err := geoip.Open(cfg.GeoIP.Database)
+		if err != nil {
+			return nil, err
+		}
+		server.GeoIP = db
+	}
+
+	botRules, err := bots.New(cfg.BotRules)
+	if err != nil {
+		return nil, err
+	}
+	server.Bots = botRules
+
+	if len(cfg.Traps) > 0 {
+		traps, err := trap.New(cfg.Traps)
+		if err != nil {
+			return nil, err
+		}
+		server.Traps = traps
+	}
+
+	for _, location := range cfg.Locations {
+		switch location.Auth {
+		case "":
+		case "oidc":
+			if server.OIDC == nil {
+				authenticator, err := oidc.New(cfg.OIDC)
+				if err != nil {
+					return nil, err
+				}
+				server.OIDC = authenticator
+			}
+		default:
+			return nil, fmt.Errorf("location %s: unknown auth %q", location.Path, location.Auth)
+		}
+
+		if location.SignatureSecret != "" {
+			window := time.Duration(location.SignatureWindow) * time.Second
+			server.verifiers[location.Path] = signature.NewVerifier([]byte(location.SignatureSecret), window)
+		}
+
+		if len(location.Scripts) > 0 {
+			rules, err := script.New(location.Scripts)
+			if err != nil {
+				return nil, fmt.Errorf("location %s: %w", location.Path, err)
+			}
+			server.scripts[location.Path] = rules
+		}
+
+		if len(location.Variants) > 0 {
+			variants, err := newVariantSplit(cfg.FileServer, location)
+			if err != nil {
+				return nil, fmt.Errorf("location %s: %w", location.Path, err)
+			}
+			server.splits[location.Path] = variants
+		}
+
+		if len(location.Flavors) > 0 {
+			flavors, err := newFlavorRoutes(cfg.FileServer, location)
+			if err != nil {
+				return nil, fmt.Errorf("location %s: %w", location.Path, err)
+			}
+			server.flavors[location.Path] = flavors
+		}
+	}
+
+	if cfg.UserDir.Enabled {
+		dirs, err := newUserDirs(cfg.FileServer, cfg.UserDir)
+		if err != nil {
+			return nil, err
+		}
+		server.userDirs = dirs
+	}
+
+	if cfg.Thumbnail.Enabled {
+		generator, err := thumbnail.New(cfg.Thumbnail.CacheDir, cfg.Thumbnail.MaxSize)
+		if err != nil {
+			return nil, err
+		}
+		server.thumbnails = generator
+		prefix := cfg.Thumbnail.Path
+		if !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		server.Handle(prefix, ResponseHandler(server.thumbnailHandler(generator, prefix)), fileMethods...)
+	}
+
+	if cfg.KV.Enabled {
+		store, err := kv.Open(cfg.KV.Database, cfg.KV.Quota)
+		if err != nil {
+			return nil, err
+		}
+		server.KV = store
+		prefix := cfg.KV.Path
+		if !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		server.Handle(prefix, ResponseHandler(kvHandler(store, prefix)), kvMethods...)
+	}
+
+	scanner, err := scan.New(cfg.Scan)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Deploy.Enabled {
+		deployer, err := deploy.New(cfg.FileServer.DocumentRoot, cfg.Deploy)
+		if err != nil {
+			return nil, err
+		}
+		server.Deployer = deployer
+		path := strings.TrimSuffix(cfg.Deploy.Path, "/")
+		handler := ResponseHandler(deployHandler(deployer, scanner, path, cfg.Deploy.Token))
+		server.Handle(path, handler, deployMethods...)
+		server.Handle(path+"/rollback", handler, POST)
+	}
+
+	if cfg.Upload.Enabled {
+		if cfg.Upload.Token == "" {
+			return nil, fmt.Errorf("upload endpoint needs a token")
+		}
+		uploads, err := storage.New(cfg.Upload.Storage, cfg.Upload.Directory)
+		if err != nil {
+			return nil, fmt.Errorf("upload storage: %w", err)
+		}
+		store := upload.New(uploads, cfg.Upload.MaxSize, cfg.Upload.Quota)
+		server.Uploads = store
+		prefix := cfg.Upload.Path
+		if !strings.HasSuffix(prefix, "/") {
+			prefix += "/"
+		}
+		server.Handle(prefix, ResponseHandler(uploadHandler(store, scanner, prefix, cfg.Upload.Token)), uploadMethods...)
+	}
+
+	for _, hook := range cfg.Webhooks {
+		receiver, err := webhook.New(hook)
+		if err != nil {
+			return nil, err
+		}
+		server.Webhooks = append(server.Webhooks, receiver)
+		server.Handle(hook.Path, ResponseHandler(webhookHandler(receiver)), POST)
+	}
+
+	if cfg.Admin.Enabled {
+		if cfg.Admin.Token == "" {
+			return nil, fmt.Errorf("admin endpoint needs a token")
+		}
+		path := strings.TrimSuffix(cfg.Admin.Path, "/")
+		server.Handle(path+"/ready", ResponseHandler(readyHandler(server)), GET, HEAD)
+		server.Handle(path+"/drain", ResponseHandler(drainHandler(server, cfg.Admin.Token)), GET, HEAD, POST, DELETE)
+		server.Handle(path+"/usage", ResponseHandler(usageHandler(server, cfg.Admin.Token)), GET, HEAD)
+		server.Handle(path+"/vars", ResponseHandler(varsHandler(server, cfg.Admin.Token)), GET, HEAD)
+		server.Handle(path+"/cache", ResponseHandler(cacheHandler(server, cfg.Admin.Token)), DELETE)
+	}
+
+	if cfg.Probes.Enabled {
+		server.Handle(cfg.Probes.Liveness, ResponseHandler(liveHandler), GET, HEAD)
+		server.Handle(cfg.Probes.Readiness, ResponseHandler(readyHandler(server)), GET, HEAD)
+	}
+
+	if cfg.Metrics.Enabled {
+		registry, err := metrics.New(cfg.Metrics)
+		if err != nil {
+			return nil, err
+		}
+		server.Metrics = registry
+		server.Handle(cfg.Metrics.Path, ResponseHandler(metricsHandler(server, cfg.Metrics.Token)), GET, HEAD)
+	}
+
+	if cfg.OpenAPI.Spec != "" {
+		spec, err := openapi.Load(cfg.OpenAPI.Spec)
+		if err != nil {
+			return nil, err
+		}
+		server.OpenAPI = spec
+		if cfg.OpenAPI.Path != "" {
+			server.Handle(cfg.OpenAPI.Path, ResponseHandler(openAPIHandler(spec, cfg.OpenAPI.Spec)), GET, HEAD)
+		}
+	}
+
+	// Plugins come last, so they can replace a built-in handler.
+	for _, p := range cfg.Plugins {
+		handlers, err := plugin.Load(p)
+		if err != nil {
+			return nil, err
+		}
+		for pattern, handler := range handlers {
+			server.Handle(pattern, StdHandler(handler))
+		}
+		log.Printf("Loaded plugin %s with %d handlers", p.Path, len(handlers))
+	}
+
+	if cfg.FileServer.Watch {
+		if err := server.watchRoots(); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Robots.Sitemap {
+		interval := time.Duration(cfg.Robots.RefreshInterval) * time.Second
+		server.Sitemap = sitemap.NewGenerator(cfg.FileServer.DocumentRoot, cfg.FileServer.DefaultFile, cfg.Robots.Deny, interval)
+	}
+
+	return server, nil
+}
+
+// Addr returns the address the server listens on.
+func (s *Server) Addr() string {
+	return net.JoinHostPort(s.Config.Server.Host, strconv.Itoa(s.Config.Server.Port))
+}
+
+// ListenAndServe listens on the configured address and serves connections.
+func (s *Server) ListenAndServe() error {
+	ln, err := s.Listen()
+	if err != nil {
+		return err
+	}
+	return s.Serve(ln)
+}
+
+// Serve accepts connections on the listener and handles each one in its own
+// goroutine, or with epoll and a pool of goroutines if server.mode is "epoll".
+// It returns ErrServerClosed after Close is called, or an error if accepting a connection fails.
+func (s *Server) Serve(ln net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		ln.Close()
+		return ErrServerClosed
+	}
+	s.listener = ln
+	s.mu.Unlock()
+
+	if s.Stats != nil {
+		interval := time.Duration(s.Config.Stats.FlushInterval) * time.Second
+		if interval <= 0 {
+			interval = time.Minute
+		}
+		s.Stats.Start(interval)
+	}
+	if s.Summary != nil {
+		s.Summary.Start()
+	}
+
+	if s.Config.Server.Mode == "epoll" {
+		return s.servePoll(ln)
+	}
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			if s.isClosed() {
+				return ErrServerClosed
+			}
+			return fmt.Errorf("error accepting connection: %w", err)
+		}
+		s.tuneConn(conn)
+		conn, ok := s.limitConn(conn)
+		if !ok {
+			continue
+		}
+		s.conns.Add(1)
+		go func() {
+			defer s.conns.Done()
+			s.handleConnection(conn)
+		}()
+	}
+}
+
+// Close stops the server from accepting new connections, waits for the
+// connections that are being handled to finish and then flushes the
+// statistics and closes the resources they use. Unlike Shutdown, it waits
+// for as long as the connections take.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	s.mu.Unlock()
+
+	s.conns.Wait()
+
+	s.mu.Lock()
+	s.release()
+	s.mu.Unlock()
+	return err
+}
+
+// Shutdown stops the server from accepting new connections and waits for the
+// connections that are being handled to finish before flushing the statistics.
+// If ctx is done first, Shutdown stops waiting and returns its error.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	var err error
+	if s.listener != nil {
+		err = s.listener.Close()
+	}
+	s.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		s.conns.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	s.mu.Lock()
+	s.release()
+	s.mu.Unlock()
+	return err
+}
+
+// release flushes the statistics and closes the resources opened by NewServer.
+func (s *Server) release() {
+	if s.Stats != nil && s.listener != nil {
+		if err := s.Stats.Stop(); err != nil {
+			log.Printf("Error flushing statistics: %v", err)
+		}
+	}
+	if s.Summary != nil {
+		s.Summary.Stop()
+	}
+	if s.GeoIP != nil {
+		s.GeoIP.Close()
+	}
+	if s.KV != nil {
+		s.KV.Close()
+	}
+	if s.Audit != nil {
+		s.Audit.Close()
+	}
+	if s.Tee != nil {
+		s.Tee.Close()
+	}
+	for _, receiver := range s.Webhooks {
+		receiver.Wait()
+	}
+	s.closeWatchers()
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+// handleConnection reads a single request from the connection and writes the response.
+func (s *Server) handleConnection(conn net.Conn) {
+	var w *responseWriter
+	defer func() {
+		if w == nil || !w.hijacked {
+			conn.Close()
+		}
+	}()
+
+	if s.Config.Server.ReadTimeout > 0 {
+		deadline := time.Now().Add(time.Duration(s.Config.Server.ReadTimeout) * time.Second)
+		conn.SetReadDeadline(deadline)
+	}
+
+	s.inFlight.Add(1)
+	defer s.inFlight.Add(-1)
+
+	trace := &Trace{Start: time.Now()}
+	reader := bufio.NewReader(conn)
+	var requestBuilder strings.Builder
+	startLine, err := reader.ReadString('\n')
+
+	if err != nil {
+		log.Printf("Error reading start line: %v", err)
+		return
+	}
+	requestBuilder.WriteString(startLine)
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			log.Printf("Error reading header line: %v", err)
+			return
+		}
+
+		requestBuilder.WriteString(line)
+
+		if line == "\r\n" || line == "\n" {
+			break
+		}
+	}
+
+	req, err := NewRequest(requestBuilder.String())
+	if err != nil {
+		log.Printf("Error parsing request: %v", err)
+		message := ""
+		switch {
+		case errors.Is(err, ErrQueryTooLong):
+			message = "400 Bad Request: Query too long"
+		case errors.Is(err, ErrTooManyQueryParams):
+			message = "400 Bad Request: Too many query parameters"
+		case errors.Is(err, ErrInvalidQueryEscape):
+			message = "400 Bad Request: Invalid query encoding"
+		}
+		if message != "" {
+			writeResponse(conn, newTextResponse(HTTP1_1, 400, message))
+			return
+		}
+		conn.Write([]byte("HTTP/1.1 400 Bad Request\r\nContent-Type: text/plain\r\n\r\nBad Request"))
+		return
+	}
+
+	if err := req.normalize(); err != nil {
+		log.Printf("Error normalizing request: %v", err)
+		message := "400 Bad Request: Invalid path"
+		if errors.Is(err, ErrInvalidHost) {
+			message = "400 Bad Request: Invalid host"
+		}
+		writeResponse(conn, newTextResponse(req.GetProtocol(), 400, message))
+		return
+	}
+
+	if err := readBody(reader, &req, s.Config.Server.MaxBodySize); err != nil {
+		log.Printf("Error reading request body: %v", err)
+		status := StatusCode(400)
+		switch {
+		case errors.Is(err, ErrBodyTooLarge):
+			status = 413
+		case errors.Is(err, ErrUnsupportedTransferCoding):
+			status = 501
+		}
+		writeResponse(conn, newTextResponse(req.GetProtocol(), status, fmt.Sprintf("%d %s", status, StatusCodeMap[status])))
+		return
+	}
+
+	trace.Read = time.Since(trace.Start)
+	req.Trace = trace
+	req.RemoteAddr = conn.RemoteAddr().String()
+	req.ID = requestID(&req)
+	req.TraceContext = traceContext(&req)
+	req.Deadline = s.requestDeadline(&req, trace.Start)
+	ctx, cancel := req.Context()
+	defer cancel()
+	req.ctx = ctx
+	req.QueryOptions = QueryOptions{
+		Semicolons:  s.Config.Query.Semicolons,
+		Arrays:      s.Config.Query.Arrays,
+		PlusAsSpace: s.Config.Query.PlusAsSpace,
+	}
+
+	// The access log shows the method a request was handled as, and the one sent if it was overridden.
+	if s.Config.Server.MethodOverride {
+		req.overrideMethod()
+	}
+
+	w = newResponseWriter(conn, reader, &req)
+	w.server = s.ServerHeader()
+	req.writer = w
+
+	handleStart := time.Now()
+	resp := s.safeRespond(w, &req)
+	trace.Handle = time.Since(handleStart)
+
+	if w.hijacked {
+		if s.Config.Logging.AccessLogs {
+			log.Printf("Access: %s %s %s - hijacked", req.StartLine.Method, req.StartLine.RequestTarget, req.StartLine.Protocol)
+		}
+		return
+	}
+
+	// Streamed responses and responses with trailers are sent by the writer.
+	streamed := w.streaming || w.announcesTrailers()
+	// The mirror gets the response as it was before compression.
+	var uncompressed Response
+	if !streamed {
+		// The client stopped waiting for a response finished after the deadline.
+		if expired, ok := deadlineResponse(&req); ok {
+			log.Printf("Request deadline exceeded: %s %s answered after %s", req.GetMethod(), req.GetRequestTarget().Path, time.Since(trace.Start).Round(time.Millisecond))
+			resp = expired
+		}
+		resp = s.devErrorPage(&req, resp)
+		uncompressed = resp
+		uncompressed.Headers = slices.Clone(resp.Headers)
+		resp = enforceInvariants(&req, s.compress(&req, resp))
+		resp.Headers = setServerHeader(resp.Headers, w.server)
+		resp.Headers = mergeVary(resp.Headers, req.Varies())
+		uncompressed.Headers = mergeVary(uncompressed.Headers, req.Varies())
+	}
+	// Streamed responses always close the connection.
+	if !streamed && s.Draining() {
+		resp.Headers = append(removeHeader(resp.Headers, "Connection"), Header{Name: "Connection", Value: "close"})
+	}
+
+	if s.RequestHook != nil {
+		s.RequestHook(req, resp)
+	}
+
+	writeStart := time.Now()
+	var written int
+	if streamed {
+		// Send what the handler has not flushed, and the trailers.
+		if err := w.finish(); err != nil {
+			log.Print(err)
+		}
+		written = w.written
+	} else {
+		written, err = writeResponse(conn, resp)
+		if errors.Is(err, ErrInvalidResponseHeader) {
+			log.Printf("Error sending response %d to %s %s: %v", resp.StartLine.StatusCode, req.GetMethod(), req.GetRequestTarget().Path, err)
+			resp = newTextResponse(req.GetProtocol(), 500, "500 Internal Server Error")
+			written, err = writeResponse(conn, resp)
+		}
+		if err != nil {
+			log.Print(err)
+		}
+	}
+	trace.Write = time.Since(writeStart)
+	s.requests.Add(1)
+	s.bytesSent.Add(int64(written))
+
+	if s.Metrics != nil {
+		s.Metrics.Observe(s.observation(&req, uncompressed, resp, requestBuilder.Len(), written, trace.Total()))
+	}
+	if s.Stats != nil {
+		s.Stats.Add(s.statsEntry(&req, resp, requestBuilder.Len(), written))
+	}
+	if s.Summary != nil {
+		s.Summary.Observe(req.GetRequestTarget().Path, int(resp.StartLine.StatusCode), trace.Total())
+	}
+	if s.Audit != nil {
+		s.audit(&req, resp)
+	}
+	if s.Tee != nil && !streamed {
+		s.tee(&req, uncompressed)
+	}
+
+	if s.Config.Logging.AccessLogs {
+		country := ""
+		if req.Country != "" {
+			country = " country=" + req.Country
+		}
+		override := ""
+		if req.SentMethod != "" {
+			override = " override=" + string(req.SentMethod)
+		}
+		variant := ""
+		if trace.Variant != "" {
+			variant = " variant=" + trace.Variant
+		}
+		traceID := ""
+		if req.TraceContext != nil {
+			traceID = " trace=" + req.TraceContext.TraceID
+		}
+		log.Printf("Access: %s %s %s - %d %s%s%s%s%s",
+			req.StartLine.Method,
+			req.StartLine.RequestTarget,
+			req.StartLine.Protocol,
+			resp.StartLine.StatusCode,
+			resp.StartLine.StatusText,
+			country,
+			override,
+			variant,
+			traceID)
+	}
+
+	if location, ok := s.Config.Location(req.GetRequestTarget().Path); ok && location.DebugLogging {
+		s.logDebug(location, req, resp)
+	}
+
+	if threshold := time.Duration(s.Config.Logging.SlowRequestThresholdMs) * time.Millisecond; threshold > 0 && trace.Total() > threshold {
+		logSlow(req, resp)
+	}
+}
+
+// respond applies the rules of the request's location and produces the response.
+func (s *Server) respond(req *Request) Response {
+	if resp, expired := deadlineResponse(req); expired {
+		return resp
+	}
+	stripIdentityHeaders(req)
+	if s.GeoIP != nil {
+		req.Country = s.GeoIP.Country(req.RemoteIP())
+	}
+
+	path := req.GetRequestTarget().Path
+	if s.Traps != nil {
+		if resp, trapped := s.checkTraps(req, path); trapped {
+			return resp
+		}
+	}
+
+	if s.userDirs != nil {
+		// Subdomains that are not the directory of a user, such as www,
+		// belong to the main site.
+		if user, ok := s.userDirs.user(req); ok {
+			if fs, ok := s.userDirs.fileServer(user); ok {
+				return s.serveUser(req, fs)
+			}
+		}
+	}
+
+	location, ok := s.Config.Location(path)
+	if ok {
+		if resp, allowed := s.checkLocation(req, location, path, path); !allowed {
+			return resp
+		}
+	}
+
+	if s.OIDC != nil && path == s.OIDC.CallbackPath() {
+		return s.oidcCallback(req)
+	}
+
+	if s.Bots != nil {
+		userAgent, _ := GetHeader(req.Headers, "User-Agent")
+		switch s.Bots.Check(userAgent, req.RemoteIP(), path, s.robots) {
+		case bots.Forbid:
+			return newTextResponse(req.GetProtocol(), 403, "403 Forbidden")
+		case bots.Throttle:
+			resp := newTextResponse(req.GetProtocol(), 429, "429 Too Many Requests")
+			resp.Headers = append(resp.Headers, Header{Name: "Retry-After", Value: "60"})
+			return resp
+		}
+	}
+
+	var headers []Header
+	if rules := s.scripts[location.Path]; ok && len(rules) > 0 {
+		resp, responseHeaders, answered := s.runScripts(req, location, rules)
+		if answered {
+			return resp
+		}
+		headers = responseHeaders
+
+		// A rewrite into another location must pass that location's rules too.
+		sentPath := path
+		path = req.GetRequestTarget().Path
+		if path != sentPath {
+			location, ok = s.Config.Location(path)
+			if ok {
+				if resp, allowed := s.checkLocation(req, location, path, sentPath); !allowed {
+					resp.Headers = mergeHeaders(resp.Headers, headers...)
+					return resp
+				}
+			}
+		}
+	}
+
+	if s.OpenAPI != nil {
+		if resp, valid := s.validateAPI(req, path); !valid {
+			resp.Headers = mergeHeaders(resp.Headers, headers...)
+			return resp
+		}
+	}
+
+	fileServer := s.FileServer
+	routed := false
+	if flavors := s.flavors[location.Path]; ok && flavors != nil {
+		var flavorServer *FileServer
+		flavorServer, routed = flavors.choose(req)
+		if routed {
+			fileServer = flavorServer
+		}
+	}
+	if variants := s.splits[location.Path]; ok && variants != nil && !routed {
+		var variantHeaders []Header
+		fileServer, variantHeaders = variants.choose(req, location.Path)
+		headers = append(headers, variantHeaders...)
+	}
+
+	if protocol, handler, ok := s.upgradeProtocol(req); ok {
+		if resp, upgraded := s.upgrade(req, protocol, handler); upgraded {
+			return resp
+		}
+	}
+
+	if route, ok := s.handler(path); ok {
+		if resp, answered := route.checkMethod(req); answered {
+			return resp
+		}
+		w := req.writer
+		if w == nil {
+			w = newResponseWriter(nil, nil, req)
+		}
+		for _, header := range headers {
+			w.AddHeader(header.Name, header.Value)
+		}
+		route.handler(w, req)
+		return w.response()
+	}
+
+	resp := s.serve(req, path, fileServer)
+	resp.Headers = mergeHeaders(resp.Headers, headers...)
+	return resp
+}
+
+// checkLocation applies the country, signature and login rules of location to
+// a request for path, answering the OIDC callback path without a login.
+// sentPath is the normalized path of the request line, which the client
+// signed. The rules of a location are applied once per request. It returns
+// the response rejecting the request, if any.
+func (s *Server) checkLocation(req *Request, location config.LocationConfig, path, sentPath string) (Response, bool) {
+	if slices.Contains(req.allowedLocations, location.Path) {
+		return Response{}, true
+	}
+
+	if !geoip.Allowed(req.Country, location.AllowCountries, location.DenyCountries) {
+		return newTextResponse(req.GetProtocol(), 403, "403 Forbidden"), false
+	}
+
+	if verifier, found := s.verifiers[location.Path]; found {
+		// The client signed the method it sent, before any override.
+		method := req.GetMethod()
+		if req.SentMethod != "" {
+			method = req.SentMethod
+		}
+		host, _ := GetHeader(req.Headers, "Host")
+		timestamp, _ := GetHeader(req.Headers, signature.TimestampHeader)
+		sig, _ := GetHeader(req.Headers, signature.SignatureHeader)
+		signed := signature.Request{Method: string(method), Host: host, Path: sentPath, Query: req.GetRequestTarget().Query, Body: req.GetBody()}
+		if err := verifier.Verify(signed, timestamp, sig, time.Now()); err != nil {
+			log.Printf("Rejected request to %s from %s: %v", path, s.logIP(req.RemoteAddr), err)
+			return newTextResponse(req.GetProtocol(), 401, "401 Unauthorized"), false
+		}
+	}
+
+	callback := s.OIDC != nil && path == s.OIDC.CallbackPath()
+	if location.Auth == "oidc" && req.Identity == nil && !callback {
+		if resp, authenticated := s.authenticate(req); !authenticated {
+			return resp, false
+		}
+	}
+
+	req.allowedLocations = append(req.allowedLocations, location.Path)
+	return Response{}, true
+}
+
+// serve answers requests without a handler: OPTIONS, the generated
+// robots.txt and sitemap.xml, directory archives and files from fileServer.
+func (s *Server) serve(req *Request, path string, fileServer *FileServer) Response {
+	// Location rules and scripts may have used up the time left.
+	if resp, expired := deadlineResponse(req); expired {
+		return resp
+	}
+	if req.GetMethod() == OPTIONS {
+		if path == "*" {
+			return optionsResponse(req, s.serverMethods())
+		}
+		return optionsResponse(req, fileMethods)
+	}
+
+	if req.GetMethod() == GET {
+		if path == "/robots.txt" && s.Config.Robots.Generate && !s.FileServer.Exists(path) {
+			return s.generatedRobots(req)
+		}
+		if path == "/sitemap.xml" && s.Sitemap != nil && !s.FileServer.Exists(path) {
+			return s.generatedSitemap(req)
+		}
+	}
+
+	if req.GetMethod() == GET && s.Config.Download.Enabled {
+		if format := downloadFormat(req); format != "" {
+			return s.serveDownload(req, path, format, fileServer)
+		}
+	}
+
+	return req.ResponseWith(fileServer)
+}
+
+// identityHeaders are set on authenticated requests for upstreams. Clients
+// cannot set them: stripIdentityHeaders removes them from every request.
+var identityHeaders = []string{"X-Forwarded-User", "X-Forwarded-Email", "X-Forwarded-Name"}
+
+// stripIdentityHeaders removes the identity headers a client sent, so that
+// only authenticate sets them, whatever the location.
+func stripIdentityHeaders(req *Request) {
+	headers := req.Headers[:0]
+	for _, header := range req.Headers {
+		if !slices.ContainsFunc(identityHeaders, func(name string) bool { return strings.EqualFold(name, header.Name) }) {
+			headers = append(headers, header)
+		}
+	}
+	req.Headers = headers
+}
+
+// authenticate checks the OIDC session of a request and adds the identity
+// headers. Without a session, browsers are redirected to the provider's login
+// page and other clients get a 401; the response is returned with false.
+func (s *Server) authenticate(req *Request) (Response, bool) {
+	cookie, _ := GetHeader(req.Headers, "Cookie")
+	if identity, ok := s.OIDC.Session(cookie); ok {
+		req.Identity = &identity
+		req.Headers = append(req.Headers,
+			Header{Name: "X-Forwarded-User", Value: identity.Subject},
+			Header{Name: "X-Forwarded-Email", Value: identity.Email},
+			Header{Name: "X-Forwarded-Name", Value: identity.Name})
+		return Response{}, true
+	}
+
+	// Browsers are redirected to log in, other clients get a 401.
+	req.Vary("Accept")
+	accept, _ := GetHeader(req.Headers, "Accept")
+	if req.GetMethod() != GET || !strings.Contains(accept, "text/html") {
+		return newTextResponse(req.GetProtocol(), 401, "401 Unauthorized"), false
+	}
+
+	target := req.GetRequestTarget()
+	returnTo := target.Path
+	if len(target.Query) > 1 {
+		returnTo += target.Query
+	}
+	location, cookieValue, err := s.OIDC.Login(returnTo)
+	if err != nil {
+		log.Printf("Error starting OIDC login: %v", err)
+		return newTextResponse(req.GetProtocol(), 502, "502 Bad Gateway"), false
+	}
+	resp := newRedirectResponse(req.GetProtocol(), 302, location)
+	resp.Headers = append(resp.Headers, Header{Name: "Set-Cookie", Value: cookieValue})
+	return resp, false
+}
+
+// oidcCallback completes an OIDC login and redirects the user back to the page they asked for.
+func (s *Server) oidcCallback(req *Request) Response {
+	cookie, _ := GetHeader(req.Headers, "Cookie")
+	identity, returnTo, cookies, err := s.OIDC.Callback(strings.TrimPrefix(req.GetRequestTarget().Query, "?"), cookie)
+	if err != nil {
+		log.Printf("OIDC login failed for %s: %v", s.logIP(req.RemoteAddr), err)
+		return newTextResponse(req.GetProtocol(), 401, "401 Unauthorized")
+	}
+	log.Printf("OIDC login: %s (%s)", identity.Subject, identity.Email)
+
+	resp := newRedirectResponse(req.GetProtocol(), 302, returnTo)
+	for _, value := range cookies {
+		resp.Headers = append(resp.Headers, Header{Name: "Set-Cookie", Value: value})
+	}
+	return resp
+}
+
+// generatedRobots answers with the generated robots.txt.
+func (s *Server) generatedRobots(req *Request) Response {
+	sitemapURL := ""
+	if s.Sitemap != nil || s.FileServer.Exists("/sitemap.xml") {
+		sitemapURL = s.baseURL(req) + "/sitemap.xml"
+	}
+
+	resp := newTextResponse(req.GetProtocol(), 200, s.Bots.DefaultRobots(sitemap.RobotsRules(s.Config.Robots.Deny), sitemapURL))
+	resp.Headers = append(resp.Headers, Header{Name: "Content-Length", Value: strconv.Itoa(len(resp.Body))})
+	return resp
+}
+
+// generatedSitemap answers with the sitemap of the document root.
+func (s *Server) generatedSitemap(req *Request) Response {
+	sm, err := s.Sitemap.Sitemap()
+	if err != nil {
+		log.Printf("Error generating sitemap: %v", err)
+		return newTextResponse(req.GetProtocol(), 500, "500 Internal Server Error")
+	}
+
+	if status := EvaluatePreconditions(req, "", sm.LastModified); status != 0 {
+		return PreconditionResponse(req, status, "", sm.LastModified)
+	}
+
+	resp := newTextResponse(req.GetProtocol(), 200, sm.XML(s.baseURL(req)))
+	resp.Headers = []Header{
+		{Name: "Content-Type", Value: "application/xml"},
+		{Name: "Content-Length", Value: strconv.Itoa(len(resp.Body))},
+	}
+	if !sm.LastModified.IsZero() {
+		resp.Headers = append(resp.Headers, Header{Name: "Last-Modified", Value: httpdate.Format(sm.LastModified)})
+	}
+	return resp
+}
+
+// baseURL returns the absolute URL of the site: the configured base_url, or
+// one derived from the request's Host header.
+func (s *Server) baseURL(req *Request) string {
+	if s.Config.Robots.BaseURL != "" {
+		return strings.TrimSuffix(s.Config.Robots.BaseURL, "/")
+	}
+	host, ok := GetHeader(req.Headers, "Host")
+	if !ok {
+		host = s.Addr()
+	}
+	return "http://" + host
+}
+
+// robotsCache holds the parsed robots.txt in effect until the file in the
+// document root appears, changes or disappears.
+type robotsCache struct {
+	mu      sync.Mutex
+	robots  *bots.Robots
+	exists  bool      // Whether robots was read from the document root
+	modTime time.Time // Modification time of the file read
+	size    int64     // Size of the file read
+}
+
+// robots returns the robots.txt in effect: the one in the document root, or
+// the generated one. It is parsed again only when the file's modification
+// time or size changes.
+func (s *Server) robots() *bots.Robots {
+	root := s.FileServer.documentRoot()
+	filePath, err := resolve(root, "/robots.txt")
+	var info os.FileInfo
+	if err == nil {
+		info, err = s.FileServer.stat(root, filePath)
+	}
+	exists := err == nil && info.Mode().IsRegular()
+
+	c := &s.robotsFile
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.robots != nil && c.exists == exists && (!exists || info.ModTime().Equal(c.modTime) && info.Size() == c.size) {
+		return c.robots
+	}
+
+	var content []byte
+	if exists {
+		if content, err = s.FileServer.readFile(root, filePath); err != nil {
+			exists = false
+		}
+	}
+	if !exists && s.Config.Robots.Generate {
+		content = []byte(s.Bots.DefaultRobots(sitemap.RobotsRules(s.Config.Robots.Deny), ""))
+	}
+	c.robots = bots.ParseRobots(string(content))
+	c.exists = exists
+	if exists {
+		c.modTime, c.size = info.ModTime(), info.Size()
+	}
+	return c.robots
+}
+
+// observation describes an answered request for the metrics. uncompressed
+// is the response as it was before compression, empty for streamed ones.
+func (s *Server) observation(req *Request, uncompressed, resp Response, read, written int, duration time.Duration) metrics.Observation {
+	host, _ := GetHeader(req.Headers, "Host")
+	location, _ := s.Config.Location(req.GetRequestTarget().Path)
+	o := metrics.Observation{
+		Method:        string(req.GetMethod()),
+		Code:          int(resp.StartLine.StatusCode),
+		Duration:      duration,
+		Host:          host,
+		Location:      location.Path,
+		RequestBytes:  read,
+		ResponseBytes: written,
+	}
+	_, wasEncoded := GetHeader(uncompressed.Headers, "Content-Encoding")
+	if encoding, _ := GetHeader(resp.Headers, "Content-Encoding"); encoding == "gzip" && !wasEncoded && uncompressed.StartLine.StatusCode != 0 {
+		o.UncompressedBytes, o.CompressedBytes = len(uncompressed.Body), len(resp.Body)
+	}
+	return o
+}
+
+// statsEntry describes the answered request for the statistics, with the
+// size of its head and the bytes written.
+func (s *Server) statsEntry(req *Request, resp Response, headBytes, written int) stats.Entry {
+	host, _ := GetHeader(req.Headers, "Host")
+	if name, _, err := net.SplitHostPort(host); err == nil {
+		host = name
+	}
+	location, _ := s.Config.Location(req.GetRequestTarget().Path)
+	return stats.Entry{
+		Path:          req.GetRequestTarget().Path,
+		Host:          strings.ToLower(host),
+		Location:      location.Path,
+		Status:        int(resp.StartLine.StatusCode),
+		RequestBytes:  headBytes + len(req.Body),
+		ResponseBytes: written,
+	}
+}
+
+// logSlow logs a warning for a request that took longer than the slow request
+// threshold, with the time spent in each stage.
+func logSlow(req Request, resp Response) {
+	trace := req.Trace
+	log.Printf("Warning: Slow request %s (request %s) status=%d total=%s read=%s handle=%s file_io=%s write=%s file=%q",
+		req.StartLine,
+		req.ID,
+		resp.StartLine.StatusCode,
+		trace.Total(),
+		trace.Read,
+		trace.Handle,
+		trace.FileIO,
+		trace.Write,
+		trace.FilePath)
+}
+
+// logDebug logs the full request headers, the resolved file and the timing
+// breakdown of a request in a location with debug logging enabled.
+func (s *Server) logDebug(location config.LocationConfig, req Request, resp Response) {
+	trace := req.Trace
+	log.Printf("Debug: %s location=%s status=%d file=%q read=%s handle=%s file_io=%s write=%s total=%s",
+		req.StartLine,
+		location.Path,
+		resp.StartLine.StatusCode,
+		trace.FilePath,
+		trace.Read,
+		trace.Handle,
+		trace.FileIO,
+		trace.Write,
+		trace.Total())
+
+	for _, header := range req.Headers {
+		log.Printf("Debug:   > %s", s.logHeader(header))
+	}
+	for _, header := range resp.Headers {
+		log.Printf("Debug:   < %s", s.redactHeader(header))
+	}
+}
@@ -0,0 +1,358 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+	"github.com/awaisamjad/volk/internal/signature"
+)
+
+// newTestServer creates a Server serving the conformance document root.
+func newTestServer(t *testing.T, modify func(cfg *config.Config)) *Server {
+	t.Helper()
+
+	cfg := config.DefaultConfig()
+	cfg.FileServer.DocumentRoot = filepath.Join("testdata", "conformance", "root")
+	cfg.Logging.AccessLogs = false
+	if modify != nil {
+		modify(&cfg)
+	}
+
+	server, err := NewServer(cfg)
+	if err != nil {
+		t.Fatalf("NewServer returned an error: %v", err)
+	}
+	return server
+}
+
+// get sends a GET request with the extra header lines to the server and parses the response.
+func get(t *testing.T, server *Server, path string, headers ...string) Response {
+	t.Helper()
+
+	request := "GET " + path + " HTTP/1.1\r\nHost: localhost\r\n"
+	for _, header := range headers {
+		request += header + "\r\n"
+	}
+	request += "\r\n"
+
+	resp, err := exchangeResponse(server, []byte(request))
+	if err != nil {
+		t.Fatalf("could not parse response: %v", err)
+	}
+	return resp
+}
+
+func TestServerBotRules(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Robots.Generate = true
+		cfg.BotRules = []config.BotRuleConfig{
+			{Pattern: "(?i)badbot", Action: "block", Name: "BadBot"},
+		}
+	})
+
+	resp := get(t, server, "/index.html", "User-Agent: Mozilla/5.0 (compatible; BadBot/1.0)")
+	if resp.GetStatusCode() != 403 {
+		t.Errorf("Expected status 403 for a blocked bot, got %d", resp.GetStatusCode())
+	}
+
+	resp = get(t, server, "/index.html", "User-Agent: Mozilla/5.0 Firefox/120.0")
+	if resp.GetStatusCode() != 200 {
+		t.Errorf("Expected status 200 for a browser, got %d", resp.GetStatusCode())
+	}
+
+	resp = get(t, server, "/robots.txt")
+	if resp.GetStatusCode() != 200 {
+		t.Fatalf("Expected status 200 for the generated robots.txt, got %d", resp.GetStatusCode())
+	}
+	if !strings.Contains(resp.GetBody(), "User-agent: BadBot\nDisallow: /\n") {
+		t.Errorf("Expected the generated robots.txt to disallow BadBot, got %q", resp.GetBody())
+	}
+}
+
+func TestServerRobotsNotGenerated(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	resp := get(t, server, "/robots.txt")
+	if resp.GetStatusCode() != 404 {
+		t.Errorf("Expected status 404 for robots.txt when generation is disabled, got %d", resp.GetStatusCode())
+	}
+}
+
+func TestServerSitemap(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Robots.Generate = true
+		cfg.Robots.Sitemap = true
+		cfg.Robots.Deny = []string{"/private/", "*.bak"}
+	})
+
+	resp := get(t, server, "/sitemap.xml")
+	if resp.GetStatusCode() != 200 {
+		t.Fatalf("Expected status 200 for the generated sitemap.xml, got %d", resp.GetStatusCode())
+	}
+	if !strings.Contains(resp.GetBody(), "<loc>http://localhost/</loc>") {
+		t.Errorf("Expected the index page in the sitemap, got %q", resp.GetBody())
+	}
+	if _, ok := GetHeader(resp.Headers, "Last-Modified"); !ok {
+		t.Errorf("Expected a Last-Modified header on the sitemap")
+	}
+
+	resp = get(t, server, "/robots.txt")
+	expected := "Disallow: /private/\nDisallow: /*.bak$\n\nSitemap: http://localhost/sitemap.xml\n"
+	if !strings.HasSuffix(resp.GetBody(), expected) {
+		t.Errorf("Expected the robots.txt to end with %q, got %q", expected, resp.GetBody())
+	}
+}
+
+func TestServerOIDCLocation(t *testing.T) {
+	var provider *httptest.Server
+	provider = httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 provider.URL,
+			"authorization_endpoint": provider.URL + "/authorize",
+			"token_endpoint":         provider.URL + "/token",
+		})
+	}))
+	defer provider.Close()
+
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.OIDC = config.OIDCConfig{
+			Issuer:       provider.URL,
+			ClientID:     "volk",
+			RedirectURL:  "http://localhost/_oidc/callback",
+			CookieSecret: "secret",
+		}
+		cfg.Locations = []config.LocationConfig{{Path: "/", Auth: "oidc"}}
+	})
+
+	resp := get(t, server, "/index.html", "Accept: text/html,*/*")
+	if resp.GetStatusCode() != 302 {
+		t.Fatalf("Expected a browser to be redirected to the provider, got %d", resp.GetStatusCode())
+	}
+	if location, _ := GetHeader(resp.Headers, "Location"); !strings.HasPrefix(location, provider.URL+"/authorize?") {
+		t.Errorf("Expected a redirect to the authorization endpoint, got %q", location)
+	}
+
+	resp = get(t, server, "/index.html", "Accept: application/json")
+	if resp.GetStatusCode() != 401 {
+		t.Errorf("Expected status 401 for a non-browser client, got %d", resp.GetStatusCode())
+	}
+
+	resp = get(t, server, "/_oidc/callback?code=x&state=y")
+	if resp.GetStatusCode() != 401 {
+		t.Errorf("Expected status 401 for a callback without login state, got %d", resp.GetStatusCode())
+	}
+}
+
+func TestServerStripsIdentityHeaders(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.Handle("/whoami", ResponseHandler(func(req *Request) Response {
+		user, _ := GetHeader(req.Headers, "X-Forwarded-User")
+		return newTextResponse(req.GetProtocol(), 200, user)
+	}), GET)
+
+	resp := get(t, server, "/whoami", "X-Forwarded-User: admin", "x-forwarded-email: admin@example.com")
+	if resp.GetBody() != "" {
+		t.Errorf("Expected the identity headers of the client to be removed, got user %q", resp.GetBody())
+	}
+}
+
+func TestServerRobotsCache(t *testing.T) {
+	root := t.TempDir()
+	robotsPath := filepath.Join(root, "robots.txt")
+	for name, body := range map[string]string{"a.html": "a", "b.html": "b", "robots.txt": "User-agent: *\nDisallow: /a.html\n"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.FileServer.DocumentRoot = root
+		cfg.BotRules = []config.BotRuleConfig{{Pattern: "crawler", Action: "robots", Name: "crawler"}}
+	})
+	status := func(path string) StatusCode {
+		return get(t, server, path, "User-Agent: crawler").GetStatusCode()
+	}
+
+	if status("/a.html") != 403 || status("/b.html") != 200 {
+		t.Fatalf("Expected robots.txt to disallow /a.html only")
+	}
+	if first, second := server.robots(), server.robots(); first != second {
+		t.Errorf("Expected an unchanged robots.txt to be parsed once")
+	}
+
+	// A changed file is read again, told apart by its size and modification time.
+	if err := os.WriteFile(robotsPath, []byte("User-agent: *\nDisallow: /b.html\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(robotsPath, later, later)
+	if status("/a.html") != 200 || status("/b.html") != 403 {
+		t.Errorf("Expected the changed robots.txt to disallow /b.html only")
+	}
+
+	if err := os.Remove(robotsPath); err != nil {
+		t.Fatal(err)
+	}
+	if status("/b.html") != 200 {
+		t.Errorf("Expected a removed robots.txt to allow everything")
+	}
+}
+
+func TestServerSignedLocation(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Server.MethodOverride = true
+		cfg.Locations = []config.LocationConfig{{Path: "/signed", SignatureSecret: "s3cret"}}
+	})
+	server.Handle("/signed", ResponseHandler(func(req *Request) Response {
+		return newTextResponse(req.GetProtocol(), 200, string(req.GetMethod()))
+	}), GET, DELETE)
+
+	now := time.Now().Unix()
+	sign := func(method, path, query string) []string {
+		sig := signature.Sign([]byte("s3cret"), signature.Request{Method: method, Host: "localhost", Path: path, Query: query}, now)
+		return []string{signature.TimestampHeader + ": " + strconv.FormatInt(now, 10), signature.SignatureHeader + ": " + sig}
+	}
+
+	tests := []struct {
+		name     string
+		method   Method
+		target   string
+		headers  []string
+		expected StatusCode
+	}{
+		{"signed", GET, "/signed?id=1", sign("GET", "/signed", "id=1"), 200},
+		{"unnormalized path", GET, "//signed?id=3", sign("GET", "/signed", "id=3"), 200},
+		{"tampered query", GET, "/signed?id=2", sign("GET", "/signed", "id=1"), 401},
+		{"added query", GET, "/signed?id=1&all=1", sign("GET", "/signed", "id=1"), 401},
+		{"overridden method", POST, "/signed", append(sign("POST", "/signed", ""), MethodOverrideHeader+": DELETE"), 200},
+		{"override signed as target method", POST, "/signed", append(sign("DELETE", "/signed", ""), MethodOverrideHeader+": DELETE"), 401},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := send(t, server, tt.method, tt.target, "", tt.headers...)
+			if resp.GetStatusCode() != tt.expected {
+				t.Errorf("Expected status %d, got %d", tt.expected, resp.GetStatusCode())
+			}
+		})
+	}
+}
+
+func TestServerStopWaitsForConnections(t *testing.T) {
+	tests := map[string]func(server *Server) error{
+		"Shutdown": func(server *Server) error { return server.Shutdown(context.Background()) },
+		"Close":    func(server *Server) error { return server.Close() },
+	}
+
+	for name, stop := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := newTestServer(t, nil)
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			served := make(chan error, 1)
+			go func() { served <- server.Serve(ln) }()
+
+			conn, err := net.Dial("tcp", ln.Addr().String())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			conn.Write([]byte("GET /index.html HTTP/1.1\r\n"))
+			time.Sleep(50 * time.Millisecond) // Let the server accept the connection
+
+			stopped := make(chan error, 1)
+			go func() { stopped <- stop(server) }()
+
+			select {
+			case err := <-stopped:
+				t.Fatalf("Expected %s to wait for the open connection, it returned %v", name, err)
+			case <-time.After(50 * time.Millisecond):
+			}
+
+			conn.Write([]byte("Host: localhost\r\n\r\n"))
+			response, _ := io.ReadAll(conn)
+			if !strings.HasPrefix(string(response), "HTTP/1.1 200") {
+				t.Errorf("Expected the open request to be answered, got %q", response)
+			}
+			if err := <-stopped; err != nil {
+				t.Errorf("Expected %s to return nil, got %v", name, err)
+			}
+			if err := <-served; err != ErrServerClosed {
+				t.Errorf("Expected Serve to return ErrServerClosed, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNewServerMode(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Server.Mode = "threads"
+	if _, err := NewServer(cfg); err == nil {
+		t.Errorf("Expected an error for an unknown server mode")
+	}
+}
+
+func TestServerStatsEntry(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Locations = []config.LocationConfig{{Path: "/docs/"}}
+	})
+
+	head := "POST /docs/page HTTP/1.1\r\nHost: Example.COM:8080\r\nContent-Length: 5\r\n\r\n"
+	req, err := NewRequest(head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Body = "hello"
+	resp := newTextResponse(HTTP1_1, 201, "")
+
+	entry := server.statsEntry(&req, resp, len(head), 42)
+	if entry.Host != "example.com" {
+		t.Errorf("Expected host example.com, got %q", entry.Host)
+	}
+	if entry.Location != "/docs/" {
+		t.Errorf("Expected location /docs/, got %q", entry.Location)
+	}
+	if entry.RequestBytes != len(head)+5 || entry.ResponseBytes != 42 || entry.Status != 201 {
+		t.Errorf("Expected %d request bytes, 42 response bytes and status 201, got %+v", len(head)+5, entry)
+	}
+}
+
+func TestServerSlowRequestLog(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Logging.SlowRequestThresholdMs = 20
+	})
+	server.Handle("/slow", ResponseHandler(func(req *Request) Response {
+		time.Sleep(50 * time.Millisecond)
+		return newTextResponse(req.GetProtocol(), 200, "done")
+	}))
+
+	get(t, server, "/index.html")
+	if strings.Contains(logs.String(), "Slow request") {
+		t.Errorf("Expected no warning for a fast request, got %q", logs.String())
+	}
+
+	get(t, server, "/slow", "X-Request-Id: slow-1")
+	for _, want := range []string{"Warning: Slow request GET /slow", "(request slow-1)", "status=200", "handle=", "file_io=", "write="} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("Expected the log to contain %q, got %q", want, logs.String())
+		}
+	}
+}
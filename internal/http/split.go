@@ -0,0 +1,59 @@
+package http
+
+import (
+	"github.com/awaisamjad/volk/config"
+	"github.com/awaisamjad/volk/internal/session"
+	"github.com/awaisamjad/volk/internal/split"
+)
+
+// variantCookieMaxAge is how long a visitor keeps their variant, in seconds.
+const variantCookieMaxAge = 30 * 24 * 60 * 60
+
+// variantSplit is the A/B test of a location.
+type variantSplit struct {
+	splitter    *split.Splitter
+	cookie      string
+	fileServers map[string]*FileServer // By variant name
+}
+
+// newVariantSplit creates the A/B test of a location with variants, with a
+// FileServer per variant that only differs from fsConfig in its document root.
+func newVariantSplit(fsConfig config.FileServerConfig, location config.LocationConfig) (*variantSplit, error) {
+	splitter, err := split.New(location.Variants)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &variantSplit{splitter: splitter, cookie: location.VariantCookie, fileServers: map[string]*FileServer{}}
+	if v.cookie == "" {
+		v.cookie = split.DefaultCookie
+	}
+	for _, variant := range location.Variants {
+		variantConfig := fsConfig
+		variantConfig.DocumentRoot = variant.DocumentRoot
+		v.fileServers[variant.Name] = NewFileServer(variantConfig)
+	}
+	return v, nil
+}
+
+// choose returns the FileServer of the request's variant and the headers
+// that keep the visitor on it. The variant is recorded in the trace.
+func (v *variantSplit) choose(req *Request, path string) (*FileServer, []Header) {
+	cookie, _ := GetHeader(req.Headers, "Cookie")
+	current, _ := session.Cookie(cookie, v.cookie)
+	variant, isNew := v.splitter.Choose(current)
+	if req.Trace != nil {
+		req.Trace.Variant = variant.Name
+	}
+
+	// Caches must not hand one visitor's variant to another.
+	req.Vary("Cookie")
+	var headers []Header
+	if isNew {
+		headers = append(headers, Header{Name: "Set-Cookie", Value: session.SetCookie(v.cookie, variant.Name, session.CookieOptions{
+			Path:   path,
+			MaxAge: variantCookieMaxAge,
+		})})
+	}
+	return v.fileServers[variant.Name], headers
+}
@@ -0,0 +1,73 @@
+package http
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestServerVariants(t *testing.T) {
+	rootB := t.TempDir()
+	if err := os.WriteFile(filepath.Join(rootB, "index.html"), []byte("variant b"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rootA := filepath.Join("testdata", "conformance", "root")
+
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Logging.AccessLogs = true
+		cfg.Locations = []config.LocationConfig{{
+			Path: "/",
+			Variants: []config.VariantConfig{
+				{Name: "a", Weight: 0, DocumentRoot: rootA},
+				{Name: "b", Weight: 1, DocumentRoot: rootB},
+			},
+		}}
+	})
+
+	tests := []struct {
+		name       string
+		headers    []string
+		wantBody   string
+		wantCookie string
+	}{
+		{"new visitor", nil, "variant b", "volk_variant=b; Path=/;"},
+		{"sticky", []string{"Cookie: volk_variant=b"}, "variant b", ""},
+		{"variant without weight", []string{"Cookie: volk_variant=a"}, "variant b", "volk_variant=b;"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			log.SetOutput(&logs)
+			defer log.SetOutput(os.Stderr)
+
+			resp := get(t, server, "/index.html", tt.headers...)
+			if resp.GetBody() != tt.wantBody {
+				t.Errorf("Expected body %q, got %q", tt.wantBody, resp.GetBody())
+			}
+			cookie, _ := GetHeader(resp.Headers, "Set-Cookie")
+			if tt.wantCookie == "" && cookie != "" || !strings.HasPrefix(cookie, tt.wantCookie) {
+				t.Errorf("Expected Set-Cookie starting with %q, got %q", tt.wantCookie, cookie)
+			}
+			if vary, _ := GetHeader(resp.Headers, "Vary"); vary != "Cookie" {
+				t.Errorf("Expected Vary Cookie, got %q", vary)
+			}
+			if !strings.Contains(logs.String(), "variant=b") {
+				t.Errorf("Expected the access log to show the variant, got %q", logs.String())
+			}
+		})
+	}
+}
+
+func TestServerVariantsError(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.Locations = []config.LocationConfig{{Path: "/", Variants: []config.VariantConfig{{Name: "a"}}}}
+	if _, err := NewServer(cfg); err == nil {
+		t.Error("Expected an error for invalid variants, got nil")
+	}
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"log"
+	nethttp "net/http"
+	"strings"
+
+	"github.com/awaisamjad/volk/internal/tee"
+)
+
+// tee copies a response to the mirror if it is the same for every client: a
+// 200 to a GET without a query or credentials, not private to a user, and
+// not chosen by request headers, such as a flavor or variant. Encoded
+// responses, such as precompressed files, are not mirrored, since the mirror
+// serves its files as they are.
+func (s *Server) tee(req *Request, resp Response) {
+	if req.GetMethod() != GET || resp.GetStatusCode() != 200 || req.Identity != nil {
+		return
+	}
+	if strings.TrimPrefix(req.GetRequestTarget().Query, "?") != "" {
+		return
+	}
+	for _, name := range []string{"Authorization", "Cookie"} {
+		if _, ok := GetHeader(req.Headers, name); ok {
+			return
+		}
+	}
+	if _, ok := GetHeader(resp.Headers, "Set-Cookie"); ok {
+		return
+	}
+	if _, ok := GetHeader(resp.Headers, "Content-Encoding"); ok {
+		return
+	}
+	cacheControl, _ := GetHeader(resp.Headers, "Cache-Control")
+	if strings.Contains(cacheControl, "private") || strings.Contains(cacheControl, "no-store") {
+		return
+	}
+	// Accept-Encoding only chooses the encoding, and the mirror gets the
+	// response before compression.
+	vary, _ := GetHeader(resp.Headers, "Vary")
+	for name := range strings.SplitSeq(vary, ",") {
+		if name = strings.TrimSpace(name); name != "" && !strings.EqualFold(name, "Accept-Encoding") {
+			return
+		}
+	}
+
+	header := nethttp.Header{}
+	for _, h := range resp.Headers {
+		header.Add(h.Name, h.Value)
+	}
+	path := req.GetRequestTarget().Path
+	if !s.Tee.Push(tee.Response{Path: path, Header: header, Body: []byte(resp.Body), Default: s.Config.FileServer.DefaultFile}) {
+		log.Printf("Mirror queue is full, not mirroring %s", path)
+	}
+}
@@ -0,0 +1,73 @@
+package http
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestTeeMirrorsPublicResponses(t *testing.T) {
+	root, dir := t.TempDir(), t.TempDir()
+	for _, name := range []string{"index.html", "query.html", "cookie.html", "auth.html"} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.FileServer.DocumentRoot = root
+		cfg.Tee = config.TeeConfig{Directory: dir, QueueSize: 10}
+	})
+
+	get(t, server, "/")
+	get(t, server, "/query.html?utm_source=feed")
+	get(t, server, "/cookie.html", "Cookie: session=abc")
+	get(t, server, "/auth.html", "Authorization: Bearer token")
+	get(t, server, "/missing.html")
+	server.Close()
+
+	entries, _ := os.ReadDir(dir)
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if want := []string{"index.html"}; !slices.Equal(names, want) {
+		t.Errorf("Expected the mirror to hold %v, got %v", want, names)
+	}
+}
+
+func TestTeeMirrorsUncompressedSharedResponses(t *testing.T) {
+	root, dir := t.TempDir(), t.TempDir()
+	page := strings.Repeat("<p>hello</p>", 100)
+	if err := os.WriteFile(filepath.Join(root, "index.html"), []byte(page), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.FileServer.DocumentRoot = root
+		cfg.Compression.Enabled = true
+		cfg.Tee = config.TeeConfig{Directory: dir, QueueSize: 10}
+	})
+	server.Handle("/agent.html", func(w ResponseWriter, req *Request) {
+		req.Vary("User-Agent")
+		w.AddHeader("Content-Type", "text/html")
+		w.Write([]byte(page))
+	})
+
+	resp := get(t, server, "/index.html", "Accept-Encoding: gzip")
+	if encoding, _ := GetHeader(resp.Headers, "Content-Encoding"); encoding != "gzip" {
+		t.Fatalf("Expected a compressed response, got headers %v", resp.Headers)
+	}
+	get(t, server, "/agent.html", "Accept-Encoding: gzip", "User-Agent: Mobile")
+	server.Close()
+
+	data, err := os.ReadFile(filepath.Join(dir, "index.html"))
+	if err != nil || string(data) != page {
+		t.Errorf("Expected the mirror to hold the uncompressed page, got %q and %v", data[:min(len(data), 16)], err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "agent.html")); !os.IsNotExist(err) {
+		t.Errorf("Expected a response that varies by User-Agent not to be mirrored, got %v", err)
+	}
+}
@@ -0,0 +1,71 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/awaisamjad/volk/internal/thumbnail"
+)
+
+// thumbnailHandler serves resized images of the document root below prefix,
+// e.g. <prefix>200x150/photos/cat.jpg for a 200x150 crop of /photos/cat.jpg
+// or <prefix>200x/photos/cat.jpg for a 200 pixels wide version. The rules of
+// the image's location and the access files of the document root apply as
+// they do to the images themselves.
+func (s *Server) thumbnailHandler(generator *thumbnail.Generator, prefix string) ResponseFunc {
+	fs := s.FileServer
+	return func(req *Request) Response {
+		size, urlPath, ok := strings.Cut(strings.TrimPrefix(req.GetRequestTarget().Path, prefix), "/")
+		if !ok {
+			return newTextResponse(req.GetProtocol(), 404, "404 Not Found")
+		}
+		width, height, err := thumbnail.ParseSize(size)
+		if err != nil {
+			return newTextResponse(req.GetProtocol(), 400, "400 Bad Request: Invalid thumbnail size")
+		}
+
+		imagePath := "/" + urlPath
+		if location, ok := s.Config.Location(imagePath); ok {
+			if resp, allowed := s.checkLocation(req, location, imagePath, req.GetRequestTarget().Path); !allowed {
+				return resp
+			}
+		}
+
+		root := fs.documentRoot()
+		filePath, err := resolve(root, urlPath)
+		if err != nil {
+			return newTextResponse(req.GetProtocol(), 404, "404 Not Found")
+		}
+		if resp, denied := fs.checkAccess(req, root, filepath.Dir(filePath)); denied {
+			return resp
+		}
+		if isAccessFile(filepath.Base(filePath)) {
+			return newTextResponse(req.GetProtocol(), 404, "404 Not Found")
+		}
+
+		data, contentType, err := generator.Thumbnail(filePath, width, height)
+		switch {
+		case err == nil:
+		case errors.Is(err, os.ErrNotExist):
+			return newTextResponse(req.GetProtocol(), 404, "404 Not Found")
+		case errors.Is(err, thumbnail.ErrInvalidSize):
+			return newTextResponse(req.GetProtocol(), 400, "400 Bad Request: Invalid thumbnail size")
+		case errors.Is(err, thumbnail.ErrUnsupportedFormat):
+			return newTextResponse(req.GetProtocol(), 415, "415 Unsupported Media Type")
+		default:
+			log.Printf("Error creating thumbnail of %s: %v", filePath, err)
+			return newTextResponse(req.GetProtocol(), 500, "500 Internal Server Error")
+		}
+
+		resp := newTextResponse(req.GetProtocol(), 200, string(data))
+		resp.Headers = []Header{
+			{Name: "Content-Type", Value: contentType},
+			{Name: "Content-Length", Value: strconv.Itoa(len(data))},
+		}
+		return resp
+	}
+}
@@ -0,0 +1,81 @@
+package http
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestThumbnailEndpoint(t *testing.T) {
+	root := t.TempDir()
+	var photo bytes.Buffer
+	png.Encode(&photo, image.NewNRGBA(image.Rect(0, 0, 400, 200)))
+	for _, dir := range []string{"private", "signed", "local"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, data := range map[string]string{
+		"photo.png":           photo.String(),
+		"notes.txt":           "hello",
+		"private/photo.png":   photo.String(),
+		"private/.volkaccess": `allow_ips = ["10.0.0.0/8"]`,
+		"signed/photo.png":    photo.String(),
+		"local/photo.png":     photo.String(),
+	} {
+		if err := os.WriteFile(filepath.Join(root, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.FileServer.DocumentRoot = root
+		cfg.Thumbnail = config.ThumbnailConfig{Enabled: true, Path: "/img/", CacheDir: filepath.Join(t.TempDir(), "cache"), MaxSize: 1000}
+		cfg.Locations = []config.LocationConfig{
+			{Path: "/signed/", SignatureSecret: "s3cret"},
+			{Path: "/local/", AllowCountries: []string{"DE"}},
+		}
+	})
+
+	tests := []struct {
+		name                  string
+		path                  string
+		status                StatusCode
+		wantWidth, wantHeight int
+	}{
+		{"crop", "/img/100x100/photo.png", 200, 100, 100},
+		{"width only", "/img/100x/photo.png", 200, 100, 50},
+		{"invalid size", "/img/big/photo.png", 400, 0, 0},
+		{"too large", "/img/5000x/photo.png", 400, 0, 0},
+		{"missing", "/img/100x/missing.png", 404, 0, 0},
+		{"not an image", "/img/100x/notes.txt", 415, 0, 0},
+		{"access file applies", "/img/100x/private/photo.png", 403, 0, 0},
+		{"signed location applies", "/img/100x/signed/photo.png", 401, 0, 0},
+		{"country location applies", "/img/100x/local/photo.png", 403, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := get(t, server, tt.path)
+			if resp.GetStatusCode() != tt.status {
+				t.Fatalf("Expected status %d, got %d: %s", tt.status, resp.GetStatusCode(), resp.GetBody())
+			}
+			if tt.status != 200 {
+				return
+			}
+			if contentType, _ := GetHeader(resp.Headers, "Content-Type"); contentType != "image/png" {
+				t.Errorf("Expected Content-Type image/png, got %q", contentType)
+			}
+			config, err := png.DecodeConfig(strings.NewReader(resp.GetBody()))
+			if err != nil || config.Width != tt.wantWidth || config.Height != tt.wantHeight {
+				t.Errorf("Expected a %dx%d PNG, got %dx%d (%v)", tt.wantWidth, tt.wantHeight, config.Width, config.Height, err)
+			}
+		})
+	}
+}
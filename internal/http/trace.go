@@ -0,0 +1,37 @@
+package http
+
+import (
+	"time"
+)
+
+// Trace collects diagnostics about how a request was handled.
+// The Server attaches one to every request it reads.
+type Trace struct {
+	Start    time.Time     // When reading the request started
+	Read     time.Duration // Time spent reading the request
+	Handle   time.Duration // Time spent producing the response
+	Write    time.Duration // Time spent writing the response
+	FilePath string        // File resolved by the FileServer, if any
+	FileIO   time.Duration // Time the FileServer spent reading the file system, part of Handle
+	Panic    string        // Panic value and stack trace, if producing the response panicked
+	Variant  string        // A/B test variant the request was served from, if any
+}
+
+// Total returns the time from the start of reading the request to the end of writing the response.
+func (t *Trace) Total() time.Duration {
+	return t.Read + t.Handle + t.Write
+}
+
+// traceFileIO adds the time since start to the file system time of the request, if it is being traced.
+func (r *Request) traceFileIO(start time.Time) {
+	if r.Trace != nil {
+		r.Trace.FileIO += time.Since(start)
+	}
+}
+
+// traceFile records the file resolved for the request, if it is being traced.
+func (r *Request) traceFile(filePath string) {
+	if r.Trace != nil {
+		r.Trace.FilePath = filePath
+	}
+}
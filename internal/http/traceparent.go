@@ -0,0 +1,145 @@
+package http
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Headers of W3C Trace Context.
+const (
+	TraceparentHeader = "traceparent"
+	TracestateHeader  = "tracestate"
+)
+
+// maxTracestateMembers is the number of list members a tracestate may have.
+const maxTracestateMembers = 32
+
+// ErrInvalidTraceparent is returned by ParseTraceparent for malformed values.
+var ErrInvalidTraceparent = errors.New("invalid traceparent")
+
+// TraceContext is the W3C Trace Context of a request: the trace it is part
+// of and the span of the caller.
+type TraceContext struct {
+	TraceID  string // 32 lower-case hex digits
+	ParentID string // 16 lower-case hex digits, the span of the caller
+	Flags    byte   // Trace flags; bit 0 is sampled
+	State    string // Vendor-specific tracestate list, empty if none or invalid
+}
+
+// Sampled reports whether the caller may be recording the trace.
+func (tc TraceContext) Sampled() bool {
+	return tc.Flags&1 == 1
+}
+
+// Traceparent returns the traceparent header value of tc.
+func (tc TraceContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", tc.TraceID, tc.ParentID, tc.Flags)
+}
+
+// Child returns the trace context to send with a request made while
+// handling the request of tc: the same trace with a new parent ID.
+func (tc TraceContext) Child() TraceContext {
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	tc.ParentID = hex.EncodeToString(buf)
+	return tc
+}
+
+// Headers returns the traceparent and, if set, tracestate headers of tc, to
+// propagate the trace.
+func (tc TraceContext) Headers() []Header {
+	headers := []Header{{Name: TraceparentHeader, Value: tc.Traceparent()}}
+	if tc.State != "" {
+		headers = append(headers, Header{Name: TracestateHeader, Value: tc.State})
+	}
+	return headers
+}
+
+// ParseTraceparent parses a traceparent header value. Versions after 00 are
+// accepted as long as they start like version 00, as the specification asks.
+func ParseTraceparent(value string) (TraceContext, error) {
+	value = strings.TrimSpace(value)
+	if len(value) < 55 || len(value) > 55 && value[55] != '-' {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+	parts := strings.Split(value[:55], "-")
+	if len(parts) != 4 || len(parts[0]) != 2 || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+	for _, part := range parts {
+		if !isLowerHex(part) {
+			return TraceContext{}, ErrInvalidTraceparent
+		}
+	}
+	version, traceID, parentID, flags := parts[0], parts[1], parts[2], parts[3]
+	if version == "ff" || version == "00" && len(value) != 55 {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+	if strings.Trim(traceID, "0") == "" || strings.Trim(parentID, "0") == "" {
+		return TraceContext{}, ErrInvalidTraceparent
+	}
+
+	flagBytes, _ := hex.DecodeString(flags)
+	return TraceContext{TraceID: traceID, ParentID: parentID, Flags: flagBytes[0]}, nil
+}
+
+// isLowerHex reports whether s consists of lower-case hex digits.
+func isLowerHex(s string) bool {
+	for _, c := range s {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// validTracestate reports whether a tracestate list has at most 32 members
+// of the form key=value, without characters that could forge log lines.
+func validTracestate(state string) bool {
+	members := 0
+	for _, member := range strings.Split(state, ",") {
+		member = strings.TrimSpace(member)
+		if member == "" {
+			continue
+		}
+		members++
+		key, value, ok := strings.Cut(member, "=")
+		if !ok || key == "" || value == "" || len(key) > 256 || len(value) > 256 {
+			return false
+		}
+		for _, c := range member {
+			if c < 0x20 || c > 0x7e {
+				return false
+			}
+		}
+	}
+	return members <= maxTracestateMembers
+}
+
+// traceContext returns the trace context of a request, or nil if it has no
+// valid traceparent header. An invalid tracestate is dropped, keeping the
+// traceparent.
+func traceContext(req *Request) *TraceContext {
+	value, ok := GetHeader(req.Headers, TraceparentHeader)
+	if !ok {
+		return nil
+	}
+	tc, err := ParseTraceparent(value)
+	if err != nil {
+		return nil
+	}
+
+	var states []string
+	for _, header := range req.Headers {
+		if strings.EqualFold(header.Name, TracestateHeader) {
+			states = append(states, header.Value)
+		}
+	}
+	if state := strings.Join(states, ","); validTracestate(state) {
+		tc.State = state
+	}
+	return &tc
+}
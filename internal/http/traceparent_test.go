@@ -0,0 +1,105 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	tests := []struct {
+		value    string
+		expected TraceContext
+		wantErr  bool
+	}{
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", ParentID: "00f067aa0ba902b7", Flags: 1}, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00", TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", ParentID: "00f067aa0ba902b7"}, false},
+		{"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-later", TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", ParentID: "00f067aa0ba902b7", Flags: 1}, false},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-later", TraceContext{}, true},
+		{"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", TraceContext{}, true},
+		{"00-00000000000000000000000000000000-00f067aa0ba902b7-01", TraceContext{}, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01", TraceContext{}, true},
+		{"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01", TraceContext{}, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e473-600f067aa0ba902b7-01", TraceContext{}, true},
+		{"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7", TraceContext{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseTraceparent(tt.value)
+			if tt.wantErr != errors.Is(err, ErrInvalidTraceparent) {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestTraceContextChild(t *testing.T) {
+	tc := TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", ParentID: "00f067aa0ba902b7", Flags: 1, State: "congo=t61rcWkgMzE"}
+	child := tc.Child()
+	if child.TraceID != tc.TraceID || child.ParentID == tc.ParentID || len(child.ParentID) != 16 || !child.Sampled() {
+		t.Errorf("Expected the same sampled trace with a new parent ID, got %+v", child)
+	}
+
+	headers := child.Headers()
+	if len(headers) != 2 || headers[0].Value != "00-4bf92f3577b34da6a3ce929d0e0e4736-"+child.ParentID+"-01" || headers[1].Value != tc.State {
+		t.Errorf("Unexpected headers %v", headers)
+	}
+}
+
+func TestServerTraceContext(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Logging.AccessLogs = true
+	})
+	var got *TraceContext
+	server.Handle("/traced", func(w ResponseWriter, req *Request) {
+		got = req.TraceContext
+	})
+
+	tests := []struct {
+		name     string
+		headers  []string
+		expected *TraceContext
+	}{
+		{"none", nil, nil},
+		{"invalid", []string{"traceparent: 00-xyz"}, nil},
+		{"with state", []string{
+			"traceparent: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			"tracestate: rojo=00f067aa0ba902b7",
+			"tracestate: congo=t61rcWkgMzE",
+		}, &TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", ParentID: "00f067aa0ba902b7", Flags: 1, State: "rojo=00f067aa0ba902b7,congo=t61rcWkgMzE"}},
+		{"invalid state", []string{
+			"traceparent: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00",
+			"tracestate: no-value",
+		}, &TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", ParentID: "00f067aa0ba902b7"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got = nil
+			get(t, server, "/traced", tt.headers...)
+			if (got == nil) != (tt.expected == nil) || got != nil && *got != *tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+
+	t.Run("access log", func(t *testing.T) {
+		var logs bytes.Buffer
+		log.SetOutput(&logs)
+		defer log.SetOutput(os.Stderr)
+
+		get(t, server, "/traced", "traceparent: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		if !strings.Contains(logs.String(), "trace=4bf92f3577b34da6a3ce929d0e0e4736") {
+			t.Errorf("Expected the access log to show the trace ID, got %q", logs.String())
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package http
+
+import (
+	"log"
+	"time"
+
+	"github.com/awaisamjad/volk/internal/trap"
+)
+
+// checkTraps answers requests from banned clients and requests for honeypot
+// paths. It reports false for requests that go on to be served normally.
+func (s *Server) checkTraps(req *Request, path string) (Response, bool) {
+	client := clientKey(req.RemoteAddr)
+	if s.Traps.Banned(client, time.Now()) {
+		return newTextResponse(req.GetProtocol(), 403, "403 Forbidden"), true
+	}
+
+	rule, ok := s.Traps.Match(path)
+	if !ok {
+		return Response{}, false
+	}
+	switch rule.Action {
+	case trap.ActionBan:
+		log.Printf("Banned %s for %d minutes after a request for trap %s", s.logIP(client), rule.BanMinutes, path)
+		s.Traps.Ban(client, time.Now().Add(time.Duration(rule.BanMinutes)*time.Minute))
+		return newTextResponse(req.GetProtocol(), 403, "403 Forbidden"), true
+	default:
+		return s.tarpit(req, time.Duration(rule.Delay)*time.Second), true
+	}
+}
+
+// tarpit keeps the client of a trap waiting: it sends a 200 response one byte
+// per second until delay has passed, the client leaves or the server closes.
+func (s *Server) tarpit(req *Request, delay time.Duration) Response {
+	w := req.writer
+	if w == nil {
+		return newTextResponse(req.GetProtocol(), 404, "404 Not Found")
+	}
+
+	w.AddHeader("Content-Type", "text/html")
+	end := time.Now().Add(delay)
+	for time.Now().Before(end) && !s.isClosed() {
+		w.Write([]byte("\n"))
+		if err := w.Flush(); err != nil {
+			break
+		}
+		time.Sleep(min(time.Second, time.Until(end)))
+	}
+	return w.response()
+}
@@ -0,0 +1,55 @@
+package http
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestTrapBan(t *testing.T) {
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Traps = []config.TrapConfig{{Path: "/wp-login.php", Action: "ban", BanMinutes: 5}}
+	})
+
+	if resp := get(t, server, "/index.html"); resp.GetStatusCode() != 200 {
+		t.Fatalf("Expected status 200 before the trap, got %d", resp.GetStatusCode())
+	}
+	if resp := get(t, server, "/wp-login.php"); resp.GetStatusCode() != 403 {
+		t.Errorf("Expected status 403 for the trap, got %d", resp.GetStatusCode())
+	}
+	if resp := get(t, server, "/index.html"); resp.GetStatusCode() != 403 {
+		t.Errorf("Expected status 403 once banned, got %d", resp.GetStatusCode())
+	}
+	if !strings.Contains(logs.String(), "Banned pipe for 5 minutes") {
+		t.Errorf("Expected the ban to be logged, got %q", logs.String())
+	}
+}
+
+func TestTrapTarpit(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Traps = []config.TrapConfig{{Path: "/wp-login.php", Action: "tarpit", Delay: 1}}
+	})
+
+	start := time.Now()
+	resp := get(t, server, "/wp-login.php")
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Expected the response to take at least 1s, took %s", elapsed)
+	}
+	if resp.GetStatusCode() != 200 || strings.TrimSpace(resp.GetBody()) != "" {
+		t.Errorf("Expected a 200 with a blank body, got %d with %q", resp.GetStatusCode(), resp.GetBody())
+	}
+
+	// Only the trap is slow; the client is not banned.
+	if resp := get(t, server, "/index.html"); resp.GetStatusCode() != 200 {
+		t.Errorf("Expected status 200 for other paths, got %d", resp.GetStatusCode())
+	}
+}
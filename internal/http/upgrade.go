@@ -0,0 +1,106 @@
+package http
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+)
+
+// UpgradeHandler serves a protocol that connections switch to with the
+// Upgrade header (RFC 9110 section 7.8), such as h2c or WebSocket.
+type UpgradeHandler struct {
+	// Accept, if set, checks the request before the server switches
+	// protocols. It returns headers to add to the 101 response, or an error
+	// to refuse the upgrade with 400 Bad Request.
+	Accept func(req *Request) ([]Header, error)
+
+	// Serve speaks the protocol on the connection after the 101 response has
+	// been sent. The ReadWriter holds bytes the client sent after the request.
+	// The server neither reads from nor closes the connection afterwards.
+	Serve func(conn net.Conn, rw *bufio.ReadWriter, req *Request)
+}
+
+// HandleUpgrade registers a handler for connections upgrading to the protocol
+// named by token. Tokens are compared case-insensitively. Upgrades run after
+// the server's access rules, for any path, and take precedence over path
+// handlers. HandleUpgrade must be called before Serve.
+func (s *Server) HandleUpgrade(token string, handler UpgradeHandler) {
+	if s.upgrades == nil {
+		s.upgrades = make(map[string]UpgradeHandler)
+	}
+	s.upgrades[strings.ToLower(token)] = handler
+}
+
+// upgradeProtocol returns the first protocol of the request's Upgrade header
+// with a registered handler. HTTP/1.0 requests and requests whose Connection
+// header does not list "upgrade" are not upgraded.
+func (s *Server) upgradeProtocol(req *Request) (string, UpgradeHandler, bool) {
+	if len(s.upgrades) == 0 || req.GetProtocol() != HTTP1_1 {
+		return "", UpgradeHandler{}, false
+	}
+	connection, _ := GetHeader(req.Headers, "Connection")
+	if !hasToken(connection, "upgrade") {
+		return "", UpgradeHandler{}, false
+	}
+	upgrade, _ := GetHeader(req.Headers, "Upgrade")
+	for _, protocol := range strings.Split(upgrade, ",") {
+		protocol = strings.TrimSpace(protocol)
+		if handler, ok := s.upgrades[strings.ToLower(protocol)]; ok {
+			return protocol, handler, true
+		}
+	}
+	return "", UpgradeHandler{}, false
+}
+
+// upgrade switches the connection of req to protocol and hands it to the
+// handler. It reports false when the connection cannot be taken over, for
+// requests not read from a connection.
+func (s *Server) upgrade(req *Request, protocol string, handler UpgradeHandler) (Response, bool) {
+	if req.writer == nil {
+		return Response{}, false
+	}
+
+	var headers []Header
+	if handler.Accept != nil {
+		var err error
+		if headers, err = handler.Accept(req); err != nil {
+			return newTextResponse(req.GetProtocol(), 400, fmt.Sprintf("400 Bad Request\n\n%v", err)), true
+		}
+	}
+
+	conn, rw, err := req.writer.Hijack()
+	if err != nil {
+		return Response{}, false
+	}
+
+	resp := Response{
+		StartLine: ResponseStartLine{Protocol: req.GetProtocol(), StatusCode: 101, StatusText: StatusCodeMap[101]},
+		Headers: append([]Header{
+			{Name: "Connection", Value: "Upgrade"},
+			{Name: "Upgrade", Value: protocol},
+		}, headers...),
+	}
+	if _, err = serializeResponse(rw.Writer, resp, false); err == nil {
+		err = rw.Flush()
+	}
+	if err != nil {
+		log.Printf("Error switching to %s: %v", protocol, err)
+		conn.Close()
+		return req.writer.response(), true
+	}
+
+	handler.Serve(conn, rw, req)
+	return req.writer.response(), true
+}
+
+// hasToken reports whether the comma-separated list contains token, compared case-insensitively.
+func hasToken(list, token string) bool {
+	for _, element := range strings.Split(list, ",") {
+		if strings.EqualFold(strings.TrimSpace(element), token) {
+			return true
+		}
+	}
+	return false
+}
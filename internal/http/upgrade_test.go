@@ -0,0 +1,84 @@
+package http
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleUpgrade(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.HandleUpgrade("Echo", UpgradeHandler{
+		Accept: func(req *Request) ([]Header, error) {
+			if _, ok := GetHeader(req.Headers, "X-Refuse"); ok {
+				return nil, errors.New("refused")
+			}
+			return []Header{{Name: "X-Echo-Version", Value: "1"}}, nil
+		},
+		Serve: func(conn net.Conn, rw *bufio.ReadWriter, req *Request) {
+			defer conn.Close()
+			line, _ := rw.ReadString('\n')
+			rw.WriteString(req.GetRequestTarget().Path + " " + line)
+			rw.Flush()
+		},
+	})
+	addr := serveTest(t, server)
+
+	tests := []struct {
+		name     string
+		protocol string
+		headers  string
+		status   int
+		echo     string
+	}{
+		{"upgrade", "HTTP/1.1", "Connection: keep-alive, Upgrade\r\nUpgrade: h2c, echo\r\n", 101, "/index.html ping\n"},
+		{"refused by Accept", "HTTP/1.1", "Connection: Upgrade\r\nUpgrade: echo\r\nX-Refuse: 1\r\n", 400, ""},
+		{"unknown protocol", "HTTP/1.1", "Connection: Upgrade\r\nUpgrade: h2c\r\n", 200, ""},
+		{"Connection without upgrade", "HTTP/1.1", "Upgrade: echo\r\n", 200, ""},
+		{"HTTP/1.0", "HTTP/1.0", "Connection: Upgrade\r\nUpgrade: echo\r\n", 200, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn, err := net.Dial("tcp", addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			conn.SetDeadline(time.Now().Add(5 * time.Second))
+			// The first bytes of the new protocol follow the request right away.
+			conn.Write([]byte("GET /index.html " + tt.protocol + "\r\nHost: localhost\r\n" + tt.headers + "\r\nping\n"))
+
+			reader := textproto.NewReader(bufio.NewReader(conn))
+			statusLine, err := reader.ReadLine()
+			if err != nil {
+				t.Fatalf("could not read the status line: %v", err)
+			}
+			if !strings.Contains(statusLine, " "+strconv.Itoa(tt.status)+" ") {
+				t.Fatalf("Expected status %d, got %q", tt.status, statusLine)
+			}
+			head, err := reader.ReadMIMEHeader()
+			if err != nil {
+				t.Fatalf("could not read the headers: %v", err)
+			}
+			if tt.status != 101 {
+				return
+			}
+			if head.Get("Upgrade") != "echo" || head.Get("X-Echo-Version") != "1" {
+				t.Errorf("Expected Upgrade: echo and the headers from Accept, got %v", head)
+			}
+			echo, err := reader.R.ReadString('\n')
+			if err != nil {
+				t.Fatalf("could not read the echo: %v", err)
+			}
+			if echo != tt.echo {
+				t.Errorf("Expected %q, got %q", tt.echo, echo)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/awaisamjad/volk/internal/scan"
+	"github.com/awaisamjad/volk/internal/upload"
+)
+
+// tusVersion is the version of the tus resumable upload protocol served.
+const tusVersion = "1.0.0"
+
+// uploadMethods are the methods the upload endpoint accepts. Creating an
+// upload accepts POST only, an upload itself HEAD, PATCH and DELETE.
+var uploadMethods = []Method{OPTIONS, POST, HEAD, PATCH, DELETE}
+
+// uploadHandler serves resumable uploads below prefix with the tus protocol
+// (https://tus.io), with the creation and termination extensions. Every
+// request but OPTIONS needs the token as a bearer token.
+//
+//	OPTIONS prefix        report the protocol version, extensions and maximum size
+//	POST    prefix        create an upload of Upload-Length bytes
+//	HEAD    prefix<id>    report the Upload-Offset to resume from
+//	PATCH   prefix<id>    append the body at Upload-Offset
+//	DELETE  prefix<id>    remove an upload
+//
+// If scanner is not nil, a completed upload is scanned and removed again if
+// it is infected.
+func uploadHandler(store *upload.Store, scanner scan.Scanner, prefix, token string) ResponseFunc {
+	return func(req *Request) Response {
+		id := strings.TrimPrefix(req.GetRequestTarget().Path, prefix)
+
+		if req.GetMethod() == OPTIONS {
+			resp := tusResponse(req, 204)
+			resp.Headers = append(resp.Headers,
+				Header{Name: "Tus-Version", Value: tusVersion},
+				Header{Name: "Tus-Extension", Value: "creation,termination"})
+			if store.MaxSize() > 0 {
+				resp.Headers = append(resp.Headers, Header{Name: "Tus-Max-Size", Value: strconv.FormatInt(store.MaxSize(), 10)})
+			}
+			return resp
+		}
+		if resp, ok := checkBearer(req, token, "upload"); !ok {
+			return resp
+		}
+		if version, _ := GetHeader(req.Headers, "Tus-Resumable"); version != tusVersion {
+			resp := tusResponse(req, 412)
+			resp.Headers = append(resp.Headers, Header{Name: "Tus-Version", Value: tusVersion})
+			return resp
+		}
+
+		if id == "" {
+			if req.GetMethod() != POST {
+				return methodNotAllowed(req, allowHeader([]Method{OPTIONS, POST}))
+			}
+			value, _ := GetHeader(req.Headers, "Upload-Length")
+			length, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || length < 0 {
+				return tusError(req, 400, "invalid Upload-Length")
+			}
+			metadata, _ := GetHeader(req.Headers, "Upload-Metadata")
+			info, err := store.Create(length, metadata)
+			if err != nil {
+				return uploadError(req, err)
+			}
+			resp := tusResponse(req, 201)
+			resp.Headers = append(resp.Headers, Header{Name: "Location", Value: prefix + info.ID})
+			return resp
+		}
+
+		switch req.GetMethod() {
+		case HEAD:
+			info, err := store.Info(id)
+			if err != nil {
+				return uploadError(req, err)
+			}
+			resp := tusResponse(req, 200)
+			resp.Headers = append(resp.Headers,
+				Header{Name: "Upload-Offset", Value: strconv.FormatInt(info.Offset, 10)},
+				Header{Name: "Upload-Length", Value: strconv.FormatInt(info.Length, 10)},
+				Header{Name: "Cache-Control", Value: "no-store"})
+			if info.Metadata != "" {
+				resp.Headers = append(resp.Headers, Header{Name: "Upload-Metadata", Value: info.Metadata})
+			}
+			return resp
+
+		case PATCH:
+			if contentType, _ := GetHeader(req.Headers, "Content-Type"); contentType != "application/offset+octet-stream" {
+				return tusError(req, 415, "Content-Type must be application/offset+octet-stream")
+			}
+			value, _ := GetHeader(req.Headers, "Upload-Offset")
+			offset, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || offset < 0 {
+				return tusError(req, 400, "invalid Upload-Offset")
+			}
+			info, err := store.Append(id, offset, []byte(req.GetBody()))
+			if err != nil {
+				return uploadError(req, err)
+			}
+			if info.Complete() && scanner != nil {
+				if err := scanUpload(store, scanner, id); err != nil {
+					return uploadError(req, err)
+				}
+			}
+			resp := tusResponse(req, 204)
+			resp.Headers = append(resp.Headers, Header{Name: "Upload-Offset", Value: strconv.FormatInt(info.Offset, 10)})
+			return resp
+
+		case DELETE:
+			if err := store.Remove(id); err != nil {
+				return uploadError(req, err)
+			}
+			return tusResponse(req, 204)
+
+		default:
+			return methodNotAllowed(req, allowHeader([]Method{OPTIONS, HEAD, PATCH, DELETE}))
+		}
+	}
+}
+
+// scanUpload streams a completed upload to the scanner and removes it if it
+// is infected.
+func scanUpload(store *upload.Store, scanner scan.Scanner, id string) error {
+	r, err := store.Open(id)
+	if err != nil {
+		return err
+	}
+	err = scanner.Scan(r)
+	r.Close()
+	if errors.Is(err, scan.ErrInfected) {
+		store.Remove(id)
+	}
+	return err
+}
+
+// tusResponse creates an empty response carrying the Tus-Resumable header.
+func tusResponse(req *Request, status StatusCode) Response {
+	resp := newTextResponse(req.GetProtocol(), status, "")
+	resp.Headers = []Header{{Name: "Tus-Resumable", Value: tusVersion}}
+	return resp
+}
+
+// tusError creates a JSON error response carrying the Tus-Resumable header.
+func tusError(req *Request, status StatusCode, message string) Response {
+	body, _ := json.Marshal(map[string]string{"error": message})
+	resp := jsonResponse(req, status, string(body))
+	resp.Headers = append(resp.Headers, Header{Name: "Tus-Resumable", Value: tusVersion})
+	return resp
+}
+
+// uploadError maps an upload or scan error to a JSON error response.
+func uploadError(req *Request, err error) Response {
+	var status StatusCode
+	switch {
+	case errors.Is(err, upload.ErrNotFound):
+		status = 404
+	case errors.Is(err, upload.ErrOffsetMismatch):
+		status = 409
+	case errors.Is(err, upload.ErrTooLarge):
+		status = 413
+	case errors.Is(err, upload.ErrQuotaExceeded):
+		status = 507
+	case errors.Is(err, scan.ErrInfected):
+		log.Printf("Rejected infected upload: %v", err)
+		status = 422
+	default:
+		log.Printf("Error handling upload: %v", err)
+		status = 500
+	}
+	return tusError(req, status, err.Error())
+}
@@ -0,0 +1,108 @@
+package http
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestUploadEndpoint(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "uploads")
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Upload = config.UploadConfig{Enabled: true, Path: "/_uploads/", Token: "secret", Directory: dir, MaxSize: 100}
+	})
+	tus := []string{"Authorization: Bearer secret", "Tus-Resumable: 1.0.0"}
+	chunk := append([]string{"Content-Type: application/offset+octet-stream"}, tus...)
+
+	resp := send(t, server, OPTIONS, "/_uploads/", "")
+	if version, _ := GetHeader(resp.Headers, "Tus-Version"); resp.GetStatusCode() != 204 || version != "1.0.0" {
+		t.Errorf("Expected 204 with Tus-Version 1.0.0, got %d and %q", resp.GetStatusCode(), version)
+	}
+
+	if resp := send(t, server, POST, "/_uploads/", "", append([]string{"Upload-Length: 1000"}, tus...)...); resp.GetStatusCode() != 413 {
+		t.Errorf("Expected status 413 above the maximum size, got %d", resp.GetStatusCode())
+	}
+
+	resp = send(t, server, POST, "/_uploads/", "", append([]string{"Upload-Length: 11"}, tus...)...)
+	location, _ := GetHeader(resp.Headers, "Location")
+	if resp.GetStatusCode() != 201 || !strings.HasPrefix(location, "/_uploads/") {
+		t.Fatalf("Expected 201 with a Location, got %d and %q", resp.GetStatusCode(), location)
+	}
+
+	steps := []struct {
+		name       string
+		method     Method
+		body       string
+		headers    []string
+		status     StatusCode
+		wantOffset string
+	}{
+		{"missing token", HEAD, "", []string{"Tus-Resumable: 1.0.0"}, 401, ""},
+		{"missing version", HEAD, "", []string{"Authorization: Bearer secret"}, 412, ""},
+		{"offset", HEAD, "", tus, 200, "0"},
+		{"first chunk", PATCH, "hello", append([]string{"Upload-Offset: 0"}, chunk...), 204, "5"},
+		{"interrupted chunk resent", PATCH, "hello", append([]string{"Upload-Offset: 0"}, chunk...), 409, ""},
+		{"wrong content type", PATCH, " world", append([]string{"Upload-Offset: 5", "Content-Type: text/plain"}, tus...), 415, ""},
+		{"resume", HEAD, "", tus, 200, "5"},
+		{"last chunk", PATCH, " world", append([]string{"Upload-Offset: 5"}, chunk...), 204, "11"},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			resp := send(t, server, step.method, location, step.body, step.headers...)
+			if resp.GetStatusCode() != step.status {
+				t.Fatalf("Expected status %d, got %d: %s", step.status, resp.GetStatusCode(), resp.GetBody())
+			}
+			if offset, _ := GetHeader(resp.Headers, "Upload-Offset"); offset != step.wantOffset {
+				t.Errorf("Expected Upload-Offset %q, got %q", step.wantOffset, offset)
+			}
+		})
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, strings.TrimPrefix(location, "/_uploads/")))
+	if err != nil || string(data) != "hello world" {
+		t.Errorf("Expected the upload to hold hello world, got %q (%v)", data, err)
+	}
+
+	if resp := send(t, server, DELETE, location, "", tus...); resp.GetStatusCode() != 204 {
+		t.Errorf("Expected status 204, got %d", resp.GetStatusCode())
+	}
+	if resp := send(t, server, HEAD, location, "", tus...); resp.GetStatusCode() != 404 {
+		t.Errorf("Expected status 404 after deleting, got %d", resp.GetStatusCode())
+	}
+}
+
+func TestUploadScan(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Upload = config.UploadConfig{Enabled: true, Path: "/_uploads/", Token: "secret", Directory: t.TempDir()}
+		cfg.Scan.Command = []string{"sh", "-c", "if grep -q EICAR; then exit 1; fi"}
+	})
+	tus := []string{"Authorization: Bearer secret", "Tus-Resumable: 1.0.0"}
+
+	resp := send(t, server, POST, "/_uploads/", "", append([]string{"Upload-Length: 5"}, tus...)...)
+	location, _ := GetHeader(resp.Headers, "Location")
+	resp = send(t, server, PATCH, location, "EICAR", append([]string{"Upload-Offset: 0", "Content-Type: application/offset+octet-stream"}, tus...)...)
+	if resp.GetStatusCode() != 422 {
+		t.Errorf("Expected status 422 for an infected upload, got %d", resp.GetStatusCode())
+	}
+	if resp := send(t, server, HEAD, location, "", tus...); resp.GetStatusCode() != 404 {
+		t.Errorf("Expected the infected upload to be removed, got %d", resp.GetStatusCode())
+	}
+}
+
+func TestUploadQuota(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Upload = config.UploadConfig{Enabled: true, Path: "/_uploads/", Token: "secret", Directory: t.TempDir(), Quota: 8}
+	})
+	tus := []string{"Authorization: Bearer secret", "Tus-Resumable: 1.0.0"}
+
+	if resp := send(t, server, POST, "/_uploads/", "", append([]string{"Upload-Length: 5"}, tus...)...); resp.GetStatusCode() != 201 {
+		t.Fatalf("Expected status 201 for an upload within the quota, got %d", resp.GetStatusCode())
+	}
+	if resp := send(t, server, POST, "/_uploads/", "", append([]string{"Upload-Length: 5"}, tus...)...); resp.GetStatusCode() != 507 {
+		t.Errorf("Expected status 507 for an upload beyond the quota, got %d", resp.GetStatusCode())
+	}
+}
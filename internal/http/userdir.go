@@ -0,0 +1,190 @@
+package http
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// userPlaceholder is replaced by the user name in userdir.template.
+const userPlaceholder = "{user}"
+
+// defaultUserTemplate is the directory of a user when userdir.template is empty.
+const defaultUserTemplate = "/home/{user}/public_html"
+
+// userName matches the user names taken from subdomains. Names that could
+// leave the template's directory, like .. or ones with a slash, never do.
+var userName = regexp.MustCompile(`^[a-z0-9_][a-z0-9_-]{0,31}$`)
+
+// userDirs serves the directories of users on their subdomains, such as
+// /home/alice/public_html on alice.example.com.
+type userDirs struct {
+	domain   string            // Domain the subdomains are below, without a leading dot
+	template string            // Directory of a user, containing userPlaceholder
+	base     string            // Directory of the template above the user's part
+	users    map[string]string // Directories that differ from the template, by user
+	deny     []string          // Users whose directories are never served
+	fsConfig config.FileServerConfig
+
+	mu      sync.Mutex
+	servers map[string]*FileServer // FileServers of the directories served so far, by directory
+}
+
+// newUserDirs creates the user directories of cfg, with a FileServer per
+// directory that only differs from fsConfig in its document root. The
+// FileServers never follow symbolic links out of a user's directory.
+func newUserDirs(fsConfig config.FileServerConfig, cfg config.UserDirConfig) (*userDirs, error) {
+	domain := strings.Trim(strings.ToLower(cfg.Domain), ".")
+	if domain == "" {
+		return nil, errors.New("userdir needs a domain")
+	}
+	template := cfg.Template
+	if template == "" {
+		template = defaultUserTemplate
+	}
+	before, _, ok := strings.Cut(template, userPlaceholder)
+	if !ok {
+		return nil, errors.New("userdir template must contain " + userPlaceholder)
+	}
+	users := make(map[string]string, len(cfg.Users))
+	for user, dir := range cfg.Users {
+		users[strings.ToLower(user)] = dir
+	}
+	deny := make([]string, len(cfg.Deny))
+	for i, user := range cfg.Deny {
+		deny[i] = strings.ToLower(user)
+	}
+	fsConfig.Beneath = true
+	return &userDirs{
+		domain:   domain,
+		template: template,
+		base:     filepath.Dir(before),
+		users:    users,
+		deny:     deny,
+		fsConfig: fsConfig,
+		servers:  make(map[string]*FileServer),
+	}, nil
+}
+
+// user returns the user whose subdomain the request is for, if any.
+func (u *userDirs) user(req *Request) (string, bool) {
+	host, _ := GetHeader(req.Headers, "Host")
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	user, ok := strings.CutSuffix(host, "."+u.domain)
+	if !ok || user == "" || strings.Contains(user, ".") {
+		return "", false
+	}
+	return user, true
+}
+
+// fileServer returns the FileServer of a user's directory. It returns false
+// for invalid and denied user names and for users without a directory.
+//
+// The FileServer of a directory from the template opens its files in the
+// directory that was checked to be reached without symbolic links, pinned
+// with os.Root, so replacing the directory with a link after the check does
+// not lead it elsewhere. The pinned directory is replaced when the one at the
+// path changes.
+func (u *userDirs) fileServer(user string) (*FileServer, bool) {
+	if !userName.MatchString(user) || slices.Contains(u.deny, user) {
+		return nil, false
+	}
+	dir, configured := u.users[user]
+	var info os.FileInfo
+	if configured {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return nil, false
+		}
+	} else {
+		dir = strings.ReplaceAll(u.template, userPlaceholder, user)
+		var ok bool
+		if info, ok = linkFree(u.base, dir); !ok {
+			return nil, false
+		}
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	fs, ok := u.servers[dir]
+	if !ok {
+		fsConfig := u.fsConfig
+		fsConfig.DocumentRoot = dir
+		fs = NewFileServer(fsConfig)
+		u.servers[dir] = fs
+	}
+	if !configured && !pinnedTo(fs.pinnedRoot(), info) {
+		pinned, err := os.OpenRoot(dir)
+		if err != nil {
+			return nil, false
+		}
+		if !pinnedTo(pinned, info) {
+			// The directory changed since it was checked.
+			pinned.Close()
+			return nil, false
+		}
+		fs.pin(pinned)
+	}
+	return fs, true
+}
+
+// pinnedTo reports whether dir is open and is the directory info describes.
+func pinnedTo(dir *os.Root, info os.FileInfo) bool {
+	if dir == nil {
+		return false
+	}
+	pinned, err := dir.Stat(".")
+	return err == nil && os.SameFile(pinned, info)
+}
+
+// serveUser answers a request on a user's subdomain with a file from fs, the
+// FileServer of the user's directory. Nothing else of the main site applies
+// there: not its locations and scripts, nor its handlers, such as the admin
+// and upload endpoints, nor its flavors and variants.
+func (s *Server) serveUser(req *Request, fs *FileServer) Response {
+	if req.GetMethod() == OPTIONS {
+		return optionsResponse(req, fileMethods)
+	}
+	return req.ResponseWith(fs)
+}
+
+// fileServers returns the FileServers of the directories served so far.
+func (u *userDirs) fileServers() []*FileServer {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	servers := make([]*FileServer, 0, len(u.servers))
+	for _, fs := range u.servers {
+		servers = append(servers, fs)
+	}
+	return servers
+}
+
+// linkFree reports whether dir is a directory below base that is reached
+// without following a symbolic link, and returns what Lstat says about it.
+// Users own the part of their template directory below base, so a link
+// there, like public_html pointing at /etc or at another user's home, could
+// serve files the server can read but the user cannot.
+func linkFree(base, dir string) (os.FileInfo, bool) {
+	rel, err := filepath.Rel(base, dir)
+	if err != nil || !filepath.IsLocal(rel) {
+		return nil, false
+	}
+	path := base
+	var info os.FileInfo
+	for part := range strings.SplitSeq(rel, string(filepath.Separator)) {
+		path = filepath.Join(path, part)
+		if info, err = os.Lstat(path); err != nil || info.Mode()&os.ModeSymlink != 0 {
+			return nil, false
+		}
+	}
+	return info, info.IsDir()
+}
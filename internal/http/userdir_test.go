@@ -0,0 +1,172 @@
+package http
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestServerUserDirs(t *testing.T) {
+	home, other := t.TempDir(), t.TempDir()
+	files := map[string]string{
+		filepath.Join(home, "alice", "public_html", "index.html"): "alice",
+		filepath.Join(home, "root", "public_html", "index.html"):  "root",
+		filepath.Join(home, "alice", "secret.txt"):                "secret",
+		filepath.Join(other, "index.html"):                        "bob",
+	}
+	for name, body := range files {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(home, "alice", "secret.txt"), filepath.Join(home, "alice", "public_html", "secret.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(home, "mallory"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(home, "alice"), filepath.Join(home, "mallory", "public_html")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(home, "alice"), filepath.Join(home, "eve")); err != nil {
+		t.Fatal(err)
+	}
+	production, _ := os.ReadFile(filepath.Join("testdata", "conformance", "root", "index.html"))
+
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.UserDir = config.UserDirConfig{
+			Enabled:  true,
+			Domain:   "example.com",
+			Template: filepath.Join(home, "{user}", "public_html"),
+			Users:    map[string]string{"Bob": other},
+			Deny:     []string{"root"},
+		}
+		cfg.Locations = []config.LocationConfig{{Path: "/", Scripts: []config.ScriptConfig{{ResponseHeaders: map[string]string{"X-Site": "main"}}}}}
+	})
+	server.Handle("/hook", ResponseHandler(func(req *Request) Response {
+		return newTextResponse(req.GetProtocol(), 200, "hook")
+	}))
+
+	tests := []struct {
+		name       string
+		host       string
+		path       string
+		wantStatus StatusCode
+		wantBody   string
+	}{
+		{"user", "alice.example.com", "/index.html", 200, "alice"},
+		{"port and case", "Alice.Example.com:6543", "/index.html", 200, "alice"},
+		{"override", "bob.example.com", "/index.html", 200, "bob"},
+		{"denied user", "root.example.com", "/index.html", 200, string(production)},
+		{"unknown user", "carol.example.com", "/index.html", 200, string(production)},
+		{"not a user", "www.example.com", "/index.html", 200, string(production)},
+		{"invalid user name", "a~b.example.com", "/index.html", 200, string(production)},
+		{"link out of the directory", "alice.example.com", "/secret.txt", 404, ""},
+		{"linked directory", "mallory.example.com", "/secret.txt", 404, "404 Not Found"},
+		{"linked home", "eve.example.com", "/index.html", 200, string(production)},
+		{"domain itself", "example.com", "/index.html", 200, string(production)},
+		{"nested subdomain", "www.alice.example.com", "/index.html", 200, string(production)},
+		{"other domain", "alice.example.org", "/index.html", 200, string(production)},
+		{"handler on the main host", "example.com", "/hook", 200, "hook"},
+		{"no handlers for users", "alice.example.com", "/hook", 404, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := exchangeResponse(server, []byte("GET "+tt.path+" HTTP/1.1\r\nHost: "+tt.host+"\r\n\r\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.GetStatusCode() != tt.wantStatus {
+				t.Errorf("Expected status %d, got %d", tt.wantStatus, resp.GetStatusCode())
+			}
+			if tt.wantBody != "" && resp.GetBody() != tt.wantBody {
+				t.Errorf("Expected body %q, got %q", tt.wantBody, resp.GetBody())
+			}
+		})
+	}
+
+	for host, expected := range map[string]string{"example.com": "main", "alice.example.com": ""} {
+		resp, err := exchangeResponse(server, []byte("GET /index.html HTTP/1.1\r\nHost: "+host+"\r\n\r\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if site, _ := GetHeader(resp.Headers, "X-Site"); site != expected {
+			t.Errorf("Expected X-Site %q from the locations on %s, got %q", expected, host, site)
+		}
+	}
+}
+
+func TestServerUserDirReplaced(t *testing.T) {
+	home := t.TempDir()
+	dir := filepath.Join(home, "alice", "public_html")
+	for name, body := range map[string]string{filepath.Join(dir, "index.html"): "first", filepath.Join(home, "bob", "index.html"): "bob"} {
+		if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(name, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.UserDir = config.UserDirConfig{Enabled: true, Domain: "example.com", Template: filepath.Join(home, "{user}", "public_html")}
+	})
+	body := func() string {
+		resp, err := exchangeResponse(server, []byte("GET /index.html HTTP/1.1\r\nHost: alice.example.com\r\n\r\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.GetBody()
+	}
+
+	if got := body(); got != "first" {
+		t.Fatalf("Expected %q, got %q", "first", got)
+	}
+
+	// A new directory in place of the old one is served once it is there.
+	if err := os.Rename(dir, dir+".old"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "index.html"), []byte("second"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := body(); got != "second" {
+		t.Errorf("Expected %q from the new directory, got %q", "second", got)
+	}
+
+	// A link in its place is not followed, even though the directory was served before.
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(home, "bob"), dir); err != nil {
+		t.Fatal(err)
+	}
+	if got := body(); got == "bob" {
+		t.Errorf("Expected the linked directory not to be served, got %q", got)
+	}
+}
+
+func TestServerUserDirsError(t *testing.T) {
+	tests := []config.UserDirConfig{
+		{Enabled: true, Template: "/home/{user}/public_html"},
+		{Enabled: true, Domain: "example.com", Template: "/home/public_html"},
+	}
+
+	for _, userDir := range tests {
+		t.Run(userDir.Template, func(t *testing.T) {
+			cfg := config.DefaultConfig()
+			cfg.UserDir = userDir
+			if _, err := NewServer(cfg); err == nil {
+				t.Error("Expected an error, got nil")
+			}
+		})
+	}
+}
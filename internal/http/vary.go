@@ -0,0 +1,63 @@
+package http
+
+import (
+	"slices"
+	"strings"
+)
+
+// Vary records that the response to the request depends on the request
+// headers with the given names, because they chose its content: the route of
+// a flavor, the variant of an A/B test in the Cookie header, a condition of a
+// request script, and so on. The Server lists them in the Vary header of the
+// response, so that caches keep one response per value of those headers.
+// Handlers call Vary for the headers they read to select their response.
+func (r *Request) Vary(names ...string) {
+	for _, name := range names {
+		if !slices.ContainsFunc(r.varies, func(n string) bool { return strings.EqualFold(n, name) }) {
+			r.varies = append(r.varies, name)
+		}
+	}
+}
+
+// Varies returns the names of the headers recorded with Vary, in the order
+// they were recorded.
+func (r *Request) Varies() []string {
+	return r.varies
+}
+
+// mergeVary returns the headers with their Vary headers merged with names
+// into a single Vary header without duplicates, which is "*" if any of them
+// is. The header is removed if there is nothing to list.
+func mergeVary(headers []Header, names []string) []Header {
+	if _, ok := GetHeader(headers, "Vary"); !ok && len(names) == 0 {
+		return headers
+	}
+
+	var merged []string
+	add := func(name string) {
+		name = strings.TrimSpace(name)
+		if name != "" && !slices.ContainsFunc(merged, func(n string) bool { return strings.EqualFold(n, name) }) {
+			merged = append(merged, name)
+		}
+	}
+	for _, h := range headers {
+		if strings.EqualFold(h.Name, "Vary") {
+			for _, name := range strings.Split(h.Value, ",") {
+				add(name)
+			}
+		}
+	}
+	for _, name := range names {
+		add(name)
+	}
+
+	headers = removeHeader(headers, "Vary")
+	if len(merged) == 0 {
+		return headers
+	}
+	value := strings.Join(merged, ", ")
+	if slices.Contains(merged, "*") {
+		value = "*"
+	}
+	return append(headers, Header{Name: "Vary", Value: value})
+}
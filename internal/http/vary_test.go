@@ -0,0 +1,91 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestMergeVary(t *testing.T) {
+	tests := []struct {
+		name     string
+		headers  []Header
+		names    []string
+		expected string
+	}{
+		{"nothing", nil, nil, ""},
+		{"recorded", nil, []string{"Accept", "Cookie"}, "Accept, Cookie"},
+		{"handler header", []Header{{Name: "Vary", Value: "Origin"}}, []string{"Accept"}, "Origin, Accept"},
+		{"duplicates", []Header{{Name: "vary", Value: "cookie, Accept"}, {Name: "Vary", Value: "Origin"}}, []string{"Cookie", "origin"}, "cookie, Accept, Origin"},
+		{"star", []Header{{Name: "Vary", Value: "*"}}, []string{"Accept"}, "*"},
+		{"empty values", []Header{{Name: "Vary", Value: " , "}}, nil, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := mergeVary(tt.headers, tt.names)
+			var values []string
+			for _, h := range headers {
+				if h.Name == "Vary" || h.Name == "vary" {
+					values = append(values, h.Value)
+				}
+			}
+			if tt.expected == "" {
+				if len(values) > 0 {
+					t.Errorf("Expected no Vary header, got %v", values)
+				}
+				return
+			}
+			if len(values) != 1 || values[0] != tt.expected {
+				t.Errorf("Expected Vary %q, got %v", tt.expected, values)
+			}
+		})
+	}
+}
+
+func TestServerVary(t *testing.T) {
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Locations = []config.LocationConfig{{
+			Path: "/",
+			Scripts: []config.ScriptConfig{
+				{If: `header("User-Agent") contains "Mobile"`, Redirect: "https://m.example.com$path"},
+				{If: `cookie("beta") == "1"`, ResponseHeaders: map[string]string{"X-Beta": "1"}},
+			},
+		}}
+	})
+	server.Handle("/negotiated", func(w ResponseWriter, req *Request) {
+		req.Vary("Accept-Language")
+		w.AddHeader("Vary", "Origin")
+		w.Write([]byte("hello"))
+	})
+	server.Handle("/streamed", func(w ResponseWriter, req *Request) {
+		req.Vary("Accept")
+		w.Write([]byte("hello"))
+		w.Flush()
+	})
+
+	tests := []struct {
+		name     string
+		path     string
+		headers  []string
+		expected string
+	}{
+		// The second condition is not evaluated once the first one matches.
+		{"redirect", "/docs", []string{"User-Agent: Mobile Safari"}, "User-Agent"},
+		{"file", "/index.html", nil, "User-Agent, Cookie"},
+		{"handler", "/negotiated", nil, "Origin, User-Agent, Cookie, Accept-Language"},
+		{"streamed", "/streamed", nil, "User-Agent, Cookie, Accept"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := get(t, server, tt.path, tt.headers...)
+			if vary, _ := GetHeader(resp.Headers, "Vary"); vary != tt.expected {
+				t.Errorf("Expected Vary %q, got %q", tt.expected, vary)
+			}
+			if count := countHeaders(resp.Headers, "Vary"); count != 1 {
+				t.Errorf("Expected a single Vary header, got %d", count)
+			}
+		})
+	}
+}
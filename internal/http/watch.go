@@ -0,0 +1,54 @@
+package http
+
+import (
+	"github.com/awaisamjad/volk/internal/watch"
+)
+
+// watchRoots watches the document roots of the file servers, so the sums
+// behind strong ETags and the parsed access files of a file are dropped as
+// soon as it changes, rather than when the caches notice a new modification
+// time or size. A document root that is a symbolic link is watched where it
+// points when the server starts; switching the link to another release
+// starts with fresh cache entries anyway.
+func (s *Server) watchRoots() error {
+	servers := make(map[string][]*FileServer)
+	var roots []string
+	for _, fs := range s.fileServers() {
+		root := fs.documentRoot()
+		if _, ok := servers[root]; !ok {
+			roots = append(roots, root)
+		}
+		servers[root] = append(servers[root], fs)
+	}
+
+	for _, root := range roots {
+		watching := servers[root]
+		watcher, err := watch.New(root, func(path string) {
+			for _, fs := range watching {
+				fs.invalidate(path)
+			}
+		})
+		if err != nil {
+			s.closeWatchers()
+			return err
+		}
+		s.watchers = append(s.watchers, watcher)
+	}
+	return nil
+}
+
+// closeWatchers stops watching the document roots.
+func (s *Server) closeWatchers() {
+	for _, watcher := range s.watchers {
+		watcher.Close()
+	}
+	s.watchers = nil
+}
+
+// invalidate drops what fs caches about the file or directory at path.
+func (fs *FileServer) invalidate(path string) {
+	if fs.hashes != nil {
+		fs.hashes.Purge(path)
+	}
+	fs.access.Purge(path)
+}
@@ -0,0 +1,62 @@
+package http
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestServerWatchInvalidatesETags(t *testing.T) {
+	modTime := time.Unix(1700000000, 0)
+	root := etagRoot(t, modTime)
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.FileServer.DocumentRoot = root
+		cfg.FileServer.ETag = "strong"
+		cfg.FileServer.Watch = true
+	})
+	defer server.Close()
+
+	strongETag := func() string {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			if etag, _ := GetHeader(get(t, server, "/index.html").Headers, "ETag"); strings.HasPrefix(etag, `"`) {
+				return etag
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatal("Expected a strong ETag")
+		return ""
+	}
+	before := strongETag()
+
+	// A deploy that keeps the size and modification time changes the content.
+	path := filepath.Join(root, "index.html")
+	if err := os.WriteFile(path, []byte("<h1>Howdy</h1>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for strongETag() == before {
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the ETag to change from %s", before)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestServerWatchMissingRoot(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.FileServer.DocumentRoot = filepath.Join(t.TempDir(), "missing")
+	cfg.FileServer.Watch = true
+	if _, err := NewServer(cfg); err == nil {
+		t.Errorf("Expected an error watching a missing document root")
+	}
+}
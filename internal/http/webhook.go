@@ -0,0 +1,28 @@
+package http
+
+import (
+	"errors"
+	"log"
+
+	"github.com/awaisamjad/volk/internal/webhook"
+)
+
+// webhookHandler accepts webhook deliveries posted to a configured path.
+// Deliveries with a missing or invalid signature are rejected with 401. It is
+// registered for POST only.
+func webhookHandler(receiver *webhook.Receiver) ResponseFunc {
+	return func(req *Request) Response {
+		header := func(name string) string {
+			value, _ := GetHeader(req.Headers, name)
+			return value
+		}
+		if _, err := receiver.Receive(header, req.GetBody()); err != nil {
+			if errors.Is(err, webhook.ErrMissingSignature) || errors.Is(err, webhook.ErrInvalidSignature) {
+				return newTextResponse(req.GetProtocol(), 401, "401 Unauthorized")
+			}
+			log.Printf("Error receiving webhook %s: %v", req.GetRequestTarget().Path, err)
+			return newTextResponse(req.GetProtocol(), 500, "500 Internal Server Error")
+		}
+		return newTextResponse(req.GetProtocol(), 202, "202 Accepted")
+	}
+}
@@ -0,0 +1,44 @@
+package http
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestWebhookEndpoint(t *testing.T) {
+	const secret = "topsecret"
+	const body = `{"ref":"refs/heads/main"}`
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	signature := "X-Hub-Signature-256: sha256=" + hex.EncodeToString(mac.Sum(nil))
+
+	server := newTestServer(t, func(cfg *config.Config) {
+		cfg.Webhooks = []config.WebhookConfig{{Path: "/hooks/github", Provider: "github", Secret: secret}}
+	})
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		method  Method
+		headers []string
+		status  StatusCode
+	}{
+		{"valid signature", POST, []string{signature, "X-GitHub-Event: push"}, 202},
+		{"missing signature", POST, nil, 401},
+		{"invalid signature", POST, []string{"X-Hub-Signature-256: sha256=00"}, 401},
+		{"wrong method", PUT, []string{signature}, 405},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := send(t, server, tt.method, "/hooks/github", body, tt.headers...)
+			if resp.GetStatusCode() != tt.status {
+				t.Errorf("Expected status %d, got %d", tt.status, resp.GetStatusCode())
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+
+	"github.com/awaisamjad/volk/internal/access"
+)
+
+// ErrUnsafePath is returned for URL paths that could name a different file
+// than the one they appear to on the platform the server runs on.
+var ErrUnsafePath = errors.New("path names a file in a way that is unsafe on this platform")
+
+// windowsPaths reports whether URL paths are checked for the names that
+// Windows file systems treat specially.
+var windowsPaths = filepath.Separator == '\\'
+
+// reservedNames are the device names of Windows, which refer to the device
+// in any directory and with any extension.
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true,
+	"COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true,
+	"LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// checkPathSegments returns ErrUnsafePath if a segment of the URL path
+// contains a backslash, which Windows takes for a separator, so that
+// "/..\..\secret" would climb out of the document root. When windows is set,
+// it also rejects the segments that Windows file systems map to another name:
+//
+//   - a colon, which starts a drive letter or an alternate data stream
+//     (".volkaccess::$DATA" reads the access file)
+//   - a trailing dot or space, which are dropped ("index.html." is "index.html")
+//   - an 8.3 short name such as "VOLKAC~1", an alias of a longer name
+//   - a device name such as "CON" or "nul.txt"
+func checkPathSegments(urlPath string, windows bool) error {
+	for segment := range strings.SplitSeq(urlPath, "/") {
+		if strings.Contains(segment, `\`) {
+			return ErrUnsafePath
+		}
+		if !windows || segment == "" {
+			continue
+		}
+		if strings.Contains(segment, ":") || strings.HasSuffix(segment, ".") || strings.HasSuffix(segment, " ") {
+			return ErrUnsafePath
+		}
+		name, _, _ := strings.Cut(segment, ".")
+		if isShortName(name) || reservedNames[strings.ToUpper(strings.TrimRight(name, " "))] {
+			return ErrUnsafePath
+		}
+	}
+	return nil
+}
+
+// isShortName reports whether name looks like the base of an 8.3 short name:
+// at most eight characters ending in a tilde and a number.
+func isShortName(name string) bool {
+	i := strings.LastIndexByte(name, '~')
+	if i < 1 || i == len(name)-1 || len(name) > 8 {
+		return false
+	}
+	for _, c := range name[i+1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isAccessFile reports whether name is the name of an access file. The
+// comparison ignores case, as the file systems of Windows and macOS do.
+func isAccessFile(name string) bool {
+	return strings.EqualFold(name, access.FileName)
+}
@@ -0,0 +1,76 @@
+package http
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckPathSegments(t *testing.T) {
+	tests := []struct {
+		path    string
+		windows bool
+		unsafe  bool
+	}{
+		{"/index.html", false, false},
+		{"/index.html", true, false},
+		{"/docs/", true, false},
+		{"/a:b.html", false, false},
+		{"/~user/index.html", true, false},
+		{"/release~2024.tar", true, false},
+		{"/console.html", true, false},
+		{"/..\\..\\secret", false, true},
+		{"/docs\\index.html", true, true},
+		{"/.volkaccess::$DATA", true, true},
+		{"/C:/Windows/win.ini", true, true},
+		{"/index.html.", true, true},
+		{"/index.html ", true, true},
+		{"/VOLKAC~1", true, true},
+		{"/progra~2/file", true, true},
+		{"/CON", true, true},
+		{"/docs/nul.txt", true, true},
+		{"/Lpt1 .log", true, true},
+		{"/CON", false, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.path, func(t *testing.T) {
+			err := checkPathSegments(test.path, test.windows)
+			if test.unsafe && !errors.Is(err, ErrUnsafePath) {
+				t.Errorf("Expected ErrUnsafePath for %q (windows %v), got %v", test.path, test.windows, err)
+			}
+			if !test.unsafe && err != nil {
+				t.Errorf("Expected no error for %q (windows %v), got %v", test.path, test.windows, err)
+			}
+		})
+	}
+}
+
+func TestIsAccessFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected bool
+	}{
+		{".volkaccess", true},
+		{".VolkAccess", true},
+		{".VOLKACCESS", true},
+		{"volkaccess", false},
+		{".volkaccess.bak", false},
+	}
+
+	for _, test := range tests {
+		if got := isAccessFile(test.name); got != test.expected {
+			t.Errorf("Expected isAccessFile(%q) to be %v, got %v", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestFileServerRejectsBackslashes(t *testing.T) {
+	server := newTestServer(t, nil)
+
+	for _, target := range []string{"/..\\..\\etc\\passwd", "/sub\\index.html"} {
+		resp := get(t, server, target)
+		if resp.StartLine.StatusCode != 400 {
+			t.Errorf("Expected status 400 for %q, got %d", target, resp.StartLine.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,271 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrHijacked is returned by writes to a ResponseWriter whose connection was hijacked.
+	ErrHijacked = errors.New("connection has been hijacked")
+	// ErrNotHijackable is returned by Hijack when the response is not written to
+	// a connection or its header has already been sent.
+	ErrNotHijackable = errors.New("connection cannot be hijacked")
+)
+
+// ResponseWriter is handed to a Handler to write its response.
+//
+// The body is buffered, and a handler that returns without calling Flush or
+// Hijack produces an ordinary response, like one built as a Response. Flush
+// sends what has been written so far and switches to streaming: the rest of
+// the body follows as it is flushed, and the connection is closed at its end.
+//
+// A streamed body whose length the handler does not set with Content-Length,
+// such as a generated archive, is sent chunked on HTTP/1.1, so clients can
+// tell a complete body from a cut one. HTTP/1.0 clients read it until the
+// connection is closed.
+//
+// A handler announcing trailer fields with a Trailer header, such as a checksum
+// of the body computed while streaming, gets a chunked response on HTTP/1.1,
+// and the fields given to AddTrailer are sent after the body.
+type ResponseWriter interface {
+	// AddHeader adds a response header. A header that may appear only once,
+	// such as Content-Type, replaces the value added before. Headers added
+	// after the first Flush are ignored.
+	AddHeader(name, value string)
+
+	// WriteHeader sets the status code of the response, 200 if it is never called.
+	WriteHeader(status StatusCode)
+
+	// Write appends to the body of the response.
+	Write(p []byte) (int, error)
+
+	// AddTrailer adds a trailer field, sent after the body. The field must be
+	// announced in a Trailer header before the first Flush. Trailers are
+	// dropped on HTTP/1.0, which has no chunked encoding.
+	AddTrailer(name, value string)
+
+	// Flush sends the header, if it has not been sent yet, and the body written so far.
+	Flush() error
+
+	// Hijack takes over the connection, for protocols such as WebSocket. The
+	// server neither writes a response nor closes the connection afterwards.
+	// The returned ReadWriter holds request bytes read ahead by the server.
+	Hijack() (net.Conn, *bufio.ReadWriter, error)
+}
+
+// responseWriter is the ResponseWriter of the server. Without a connection,
+// the response is only recorded, and Flush does not send anything.
+type responseWriter struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	protocol Protocol
+	method   Method
+
+	status    StatusCode
+	headers   []Header
+	body      bytes.Buffer
+	trailers  []Header
+	streaming bool // The header has been sent by Flush
+	chunked   bool // The body is sent in chunks, followed by the trailers
+	aborted   bool // The body is cut short, so a chunked one is not ended
+	hijacked  bool
+	written   int    // Bytes sent on the connection
+	server    string // Server header sent with a streamed response, none if empty
+	req       *Request
+}
+
+// newResponseWriter creates a writer for the response to req, sent on conn if it is not nil.
+func newResponseWriter(conn net.Conn, reader *bufio.Reader, req *Request) *responseWriter {
+	return &responseWriter{conn: conn, reader: reader, protocol: req.GetProtocol(), method: req.GetMethod(), status: 200, req: req}
+}
+
+func (w *responseWriter) AddHeader(name, value string) {
+	if w.streaming || w.hijacked {
+		return
+	}
+	w.headers = addHeader(w.headers, Header{Name: name, Value: value})
+}
+
+func (w *responseWriter) WriteHeader(status StatusCode) {
+	if w.streaming || w.hijacked {
+		return
+	}
+	w.status = status
+}
+
+func (w *responseWriter) Write(p []byte) (int, error) {
+	if w.hijacked {
+		return 0, ErrHijacked
+	}
+	return w.body.Write(p)
+}
+
+func (w *responseWriter) AddTrailer(name, value string) {
+	if w.hijacked {
+		return
+	}
+	w.trailers = append(w.trailers, Header{Name: name, Value: value})
+}
+
+func (w *responseWriter) Flush() error {
+	if w.hijacked {
+		return ErrHijacked
+	}
+	if w.conn == nil {
+		return nil
+	}
+
+	bw := writers.Get().(*bufio.Writer)
+	bw.Reset(w.conn)
+	defer func() {
+		bw.Reset(nil)
+		writers.Put(bw)
+	}()
+
+	var n int
+	if !w.streaming {
+		w.streaming = true
+		if w.announcesTrailers() || w.unknownLength() {
+			w.chunked = true
+			w.headers = removeHeader(w.headers, "Content-Length")
+			w.headers = setHeader(w.headers, "Transfer-Encoding", "chunked")
+		}
+		// The server closes the connection after a streamed response, which
+		// also ends a body sent without a length to HTTP/1.0 clients.
+		w.headers = append(w.headers, Header{Name: "Connection", Value: "close"})
+		w.headers = setServerHeader(w.headers, w.server)
+		w.headers = mergeVary(w.headers, w.req.Varies())
+		m, err := serializeResponse(bw, w.response(), false)
+		if errors.Is(err, ErrInvalidResponseHeader) {
+			// Nothing was sent, so the server answers with an error once the handler returns.
+			w.streaming, w.chunked = false, false
+			return err
+		}
+		n += m
+	}
+	if w.method != HEAD {
+		if w.chunked && w.body.Len() > 0 {
+			m, _ := fmt.Fprintf(bw, "%x\r\n", w.body.Len())
+			n += m
+			m, _ = bw.Write(w.body.Bytes())
+			n += m
+			m, _ = bw.WriteString(CRLF)
+			n += m
+		} else if !w.chunked {
+			m, _ := bw.Write(w.body.Bytes())
+			n += m
+		}
+	}
+	w.body.Reset()
+
+	return w.send(bw, n)
+}
+
+// finish sends the rest of a streamed response or a response with trailers,
+// ending a chunked body with the last chunk and the trailer section.
+func (w *responseWriter) finish() error {
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if !w.chunked || w.aborted || w.conn == nil {
+		return nil
+	}
+
+	bw := writers.Get().(*bufio.Writer)
+	bw.Reset(w.conn)
+	defer func() {
+		bw.Reset(nil)
+		writers.Put(bw)
+	}()
+
+	n, _ := bw.WriteString("0" + CRLF)
+	for _, trailer := range w.trailers {
+		if !forbiddenTrailers[strings.ToLower(trailer.Name)] && checkResponseHeaders([]Header{trailer}) == nil {
+			m, _ := bw.WriteString(trailer.String() + CRLF)
+			n += m
+		}
+	}
+	m, _ := bw.WriteString(CRLF)
+	return w.send(bw, n+m)
+}
+
+// announcesTrailers reports whether the response is to be sent chunked with
+// trailers: it has a Trailer header and the request allows a chunked body.
+func (w *responseWriter) announcesTrailers() bool {
+	if w.protocol != HTTP1_1 || w.method == HEAD {
+		return false
+	}
+	_, ok := GetHeader(w.headers, "Trailer")
+	return ok
+}
+
+// unknownLength reports whether the response is to be sent chunked because
+// its length is not known when streaming starts: it has a body, no
+// Content-Length, and the request allows a chunked body.
+func (w *responseWriter) unknownLength() bool {
+	if w.protocol != HTTP1_1 || w.method == HEAD || w.status < 200 || w.status == 204 || w.status == 304 {
+		return false
+	}
+	_, ok := GetHeader(w.headers, "Content-Length")
+	return !ok
+}
+
+// send flushes what bw holds to the connection and counts the n bytes written
+// to bw, less any a failed write left in the buffer.
+func (w *responseWriter) send(bw *bufio.Writer, n int) error {
+	err := bw.Flush()
+	w.written += n - bw.Buffered()
+	if err != nil {
+		return fmt.Errorf("error writing response: %w", err)
+	}
+	return nil
+}
+
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if w.conn == nil || w.streaming || w.hijacked {
+		return nil, nil, ErrNotHijackable
+	}
+	w.hijacked = true
+	// The handler decides how long the connection may stay open.
+	w.conn.SetDeadline(time.Time{})
+	return w.conn, bufio.NewReadWriter(w.reader, bufio.NewWriter(w.conn)), nil
+}
+
+// response returns the response written so far. Once streaming, its body is
+// only what has not been flushed yet.
+func (w *responseWriter) response() Response {
+	return Response{
+		StartLine: ResponseStartLine{
+			Protocol:   w.protocol,
+			StatusCode: w.status,
+			StatusText: StatusCodeMap[w.status],
+		},
+		Headers: w.headers,
+		Body:    w.body.String(),
+	}
+}
+
+// ResponseFunc produces the whole response to a request at once.
+type ResponseFunc func(req *Request) Response
+
+// ResponseHandler returns a Handler that writes the response produced by fn.
+func ResponseHandler(fn ResponseFunc) Handler {
+	return func(w ResponseWriter, req *Request) {
+		WriteResponse(w, fn(req))
+	}
+}
+
+// WriteResponse writes the status, headers and body of resp to w.
+func WriteResponse(w ResponseWriter, resp Response) {
+	for _, header := range resp.Headers {
+		w.AddHeader(header.Name, header.Value)
+	}
+	w.WriteHeader(resp.GetStatusCode())
+	w.Write([]byte(resp.Body))
+}
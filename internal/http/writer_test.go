@@ -0,0 +1,179 @@
+package http
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serveTest serves the server on a local port for the duration of the test and returns its address.
+func serveTest(t *testing.T, server *Server) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go server.Serve(ln)
+	t.Cleanup(func() { server.Close() })
+	return ln.Addr().String()
+}
+
+func TestResponseWriterRecorded(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.Handle("/hello", func(w ResponseWriter, req *Request) {
+		w.AddHeader("Content-Type", "text/plain")
+		w.WriteHeader(201)
+		w.Write([]byte("hello, "))
+		w.Write([]byte("world"))
+		w.Flush()
+		if _, _, err := w.Hijack(); err != ErrNotHijackable {
+			t.Errorf("Expected ErrNotHijackable without a connection, got %v", err)
+		}
+	})
+
+	req, err := NewRequest("GET /hello HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := server.respond(&req)
+	if resp.GetStatusCode() != 201 || resp.GetBody() != "hello, world" {
+		t.Errorf("Expected 201 with hello, world, got %d with %q", resp.GetStatusCode(), resp.GetBody())
+	}
+	if contentType, _ := GetHeader(resp.Headers, "Content-Type"); contentType != "text/plain" {
+		t.Errorf("Expected Content-Type text/plain, got %q", contentType)
+	}
+}
+
+func TestResponseWriterStreaming(t *testing.T) {
+	server := newTestServer(t, nil)
+	next := make(chan struct{})
+	server.Handle("/events", func(w ResponseWriter, req *Request) {
+		w.AddHeader("Content-Type", "text/event-stream")
+		w.Write([]byte("data: first\n\n"))
+		if err := w.Flush(); err != nil {
+			t.Errorf("Flush returned an error: %v", err)
+		}
+		<-next
+		w.AddHeader("X-Ignored", "too late")
+		w.Write([]byte("data: second\n\n"))
+	})
+	addr := serveTest(t, server)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	conn.Write([]byte("GET /events HTTP/1.1\r\nHost: localhost\r\n\r\n"))
+
+	// The first event arrives while the handler is still running.
+	reader := bufio.NewReader(conn)
+	var head strings.Builder
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("could not read response head: %v", err)
+		}
+		head.WriteString(line)
+		if line == "\r\n" {
+			break
+		}
+	}
+	if !strings.HasPrefix(head.String(), "HTTP/1.1 200 OK\r\n") || !strings.Contains(head.String(), "Transfer-Encoding: chunked\r\n") {
+		t.Errorf("Expected a chunked 200 head, got %q", head.String())
+	}
+	first := make([]byte, len("d\r\ndata: first\n\n\r\n"))
+	io.ReadFull(reader, first)
+	if string(first) != "d\r\ndata: first\n\n\r\n" {
+		t.Errorf("Expected the first event, got %q", first)
+	}
+
+	close(next)
+	rest, _ := io.ReadAll(reader)
+	if string(rest) != "e\r\ndata: second\n\n\r\n0\r\n\r\n" {
+		t.Errorf("Expected the second event and the last chunk before the connection closes, got %q", rest)
+	}
+	if strings.Contains(head.String(), "X-Ignored") {
+		t.Errorf("Expected headers added after Flush to be ignored")
+	}
+}
+
+func TestResponseWriterHijack(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.Handle("/echo", func(w ResponseWriter, req *Request) {
+		conn, rw, err := w.Hijack()
+		if err != nil {
+			t.Errorf("Hijack returned an error: %v", err)
+			return
+		}
+		defer conn.Close()
+		rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: echo\r\n\r\n")
+		rw.Flush()
+		line, _ := rw.ReadString('\n')
+		rw.WriteString("echo: " + line)
+		rw.Flush()
+		if _, err := w.Write([]byte("x")); err != ErrHijacked {
+			t.Errorf("Expected ErrHijacked, got %v", err)
+		}
+	})
+	addr := serveTest(t, server)
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	// The message follows the request immediately, so the server has read it ahead.
+	conn.Write([]byte("GET /echo HTTP/1.1\r\nHost: localhost\r\nUpgrade: echo\r\n\r\nping\n"))
+
+	response, _ := io.ReadAll(conn)
+	expected := "HTTP/1.1 101 Switching Protocols\r\nUpgrade: echo\r\n\r\necho: ping\n"
+	if string(response) != expected {
+		t.Errorf("Expected %q, got %q", expected, response)
+	}
+}
+
+func TestResponseWriterTrailers(t *testing.T) {
+	server := newTestServer(t, nil)
+	server.Handle("/data", func(w ResponseWriter, req *Request) {
+		w.AddHeader("Content-Type", "text/plain")
+		w.AddHeader("Content-Length", "11")
+		w.AddHeader("Trailer", "X-Checksum")
+		w.Write([]byte("hello"))
+		w.Flush()
+		w.Write([]byte(" world"))
+		w.AddTrailer("X-Checksum", "5eb63bbb")
+		w.AddTrailer("Content-Length", "99")
+	})
+
+	tests := []struct {
+		name     string
+		request  string
+		expected string
+	}{
+		{
+			"HTTP/1.1 chunked with trailers",
+			"GET /data HTTP/1.1\r\nHost: localhost\r\n\r\n",
+			"HTTP/1.1 200 OK\r\nContent-Type: text/plain\r\nTrailer: X-Checksum\r\nTransfer-Encoding: chunked\r\nConnection: close\r\n\r\n" +
+				"5\r\nhello\r\n6\r\n world\r\n0\r\nX-Checksum: 5eb63bbb\r\n\r\n",
+		},
+		{
+			"HTTP/1.0 drops trailers",
+			"GET /data HTTP/1.0\r\n\r\n",
+			"HTTP/1.0 200 OK\r\nContent-Type: text/plain\r\nContent-Length: 11\r\nTrailer: X-Checksum\r\nConnection: close\r\n\r\nhello world",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(exchange(server, []byte(tt.request))); got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
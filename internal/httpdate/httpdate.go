@@ -0,0 +1,63 @@
+// Package httpdate formats and parses the dates of HTTP headers such as
+// Last-Modified, If-Modified-Since, Expires and Retry-After.
+//
+// Dates are sent in the IMF-fixdate format only, but RFC 9110 section 5.6.7
+// requires recipients to also accept the obsolete RFC 850 and asctime
+// formats, which old clients and proxies still send:
+//
+//	Sun, 06 Nov 1994 08:49:37 GMT   IMF-fixdate
+//	Sunday, 06-Nov-94 08:49:37 GMT  RFC 850
+//	Sun Nov  6 08:49:37 1994        asctime
+package httpdate
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Layouts of the three formats, for time.Parse.
+const (
+	IMFFixdate = "Mon, 02 Jan 2006 15:04:05 GMT"
+	RFC850     = "Monday, 02-Jan-06 15:04:05 GMT"
+	ASCTime    = "Mon Jan _2 15:04:05 2006"
+)
+
+// ErrInvalid is returned by Parse for values in none of the formats.
+var ErrInvalid = errors.New("invalid HTTP date")
+
+// Format formats t as an IMF-fixdate, converting it to UTC.
+func Format(t time.Time) string {
+	return t.UTC().Format(IMFFixdate)
+}
+
+// Parse parses a date in any of the three formats. The result is in UTC.
+func Parse(value string) (time.Time, error) {
+	return parse(value, time.Now())
+}
+
+func parse(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(IMFFixdate, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(RFC850, value); err == nil {
+		return fixCentury(t, now), nil
+	}
+	if t, err := time.Parse(ASCTime, value); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%w: %q", ErrInvalid, value)
+}
+
+// fixCentury picks the century of a two-digit RFC 850 year: the year is in
+// the current century unless that puts it more than 50 years in the future,
+// in which case it is the most recent past year with the same last two digits.
+func fixCentury(t time.Time, now time.Time) time.Time {
+	year := now.Year() - now.Year()%100 + t.Year()%100
+	if year > now.Year()+50 {
+		year -= 100
+	}
+	return t.AddDate(year-t.Year(), 0, 0)
+}
@@ -0,0 +1,54 @@
+package httpdate
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	want := time.Date(1994, 11, 6, 8, 49, 37, 0, time.UTC)
+
+	tests := []struct {
+		name  string
+		value string
+		want  time.Time
+		err   error
+	}{
+		{"IMF-fixdate", "Sun, 06 Nov 1994 08:49:37 GMT", want, nil},
+		{"RFC 850", "Sunday, 06-Nov-94 08:49:37 GMT", want, nil},
+		{"RFC 850 this century", "Thursday, 01-Jan-26 00:00:00 GMT", time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), nil},
+		{"RFC 850 near future", "Friday, 01-Jan-55 00:00:00 GMT", time.Date(2055, 1, 1, 0, 0, 0, 0, time.UTC), nil},
+		{"RFC 850 more than 50 years ahead", "Friday, 01-Jan-77 00:00:00 GMT", time.Date(1977, 1, 1, 0, 0, 0, 0, time.UTC), nil},
+		{"asctime", "Sun Nov  6 08:49:37 1994", want, nil},
+		{"asctime two-digit day", "Sun Nov 16 08:49:37 1994", want.AddDate(0, 0, 10), nil},
+		{"surrounding spaces", "  Sun, 06 Nov 1994 08:49:37 GMT ", want, nil},
+		{"other time zone", "Sun, 06 Nov 1994 08:49:37 CET", time.Time{}, ErrInvalid},
+		{"ISO 8601", "1994-11-06T08:49:37Z", time.Time{}, ErrInvalid},
+		{"empty", "", time.Time{}, ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parse(tt.value, now)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	berlin := time.FixedZone("CET", 3600)
+	got := Format(time.Date(1994, 11, 6, 9, 49, 37, 123, berlin))
+	if want := "Sun, 06 Nov 1994 08:49:37 GMT"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if parsed, err := Parse(got); err != nil || !parsed.Equal(time.Date(1994, 11, 6, 8, 49, 37, 0, time.UTC)) {
+		t.Errorf("Expected the formatted date to parse back, got %v (%v)", parsed, err)
+	}
+}
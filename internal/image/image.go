@@ -0,0 +1,197 @@
+// Package image builds OCI container images for volk sites without a Dockerfile.
+//
+// The image is built from scratch: a single layer holds the volk binary,
+// a configuration file and the contents of the document root.
+package image
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+)
+
+// Locations of the files inside the image.
+const (
+	BinaryPath       = "/usr/local/bin/volk"
+	ConfigPath       = "/etc/volk/server.toml"
+	DocumentRootPath = "/srv/www"
+)
+
+// Options describes what goes into the image.
+type Options struct {
+	// Binary is the path to a linux volk binary on the host.
+	Binary string
+	// Config is the configuration the image runs with. Its document root is
+	// copied into the image and rewritten to DocumentRootPath.
+	Config config.Config
+	// Platform is the platform recorded in the image config, e.g. linux/amd64.
+	Platform v1.Platform
+}
+
+// Build creates the image described by opts.
+// The returned cleanup function removes the temporary layer file and must be
+// called once the image has been written.
+func Build(opts Options) (v1.Image, func(), error) {
+	noop := func() {}
+
+	layerFile, err := os.CreateTemp("", "volk-layer-*.tar")
+	if err != nil {
+		return nil, noop, fmt.Errorf("error creating layer file: %w", err)
+	}
+	cleanup := func() { os.Remove(layerFile.Name()) }
+
+	cfg := opts.Config
+	documentRoot := cfg.FileServer.DocumentRoot
+	cfg.FileServer.DocumentRoot = DocumentRootPath
+	cfg.Server.Host = "0.0.0.0"
+
+	err = writeLayer(layerFile, opts.Binary, documentRoot, []byte(cfg.String()+"\n"))
+	if closeErr := layerFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return nil, noop, err
+	}
+
+	layer, err := tarball.LayerFromFile(layerFile.Name())
+	if err != nil {
+		cleanup()
+		return nil, noop, fmt.Errorf("error creating layer: %w", err)
+	}
+
+	img, err := mutate.AppendLayers(empty.Image, layer)
+	if err != nil {
+		cleanup()
+		return nil, noop, fmt.Errorf("error appending layer: %w", err)
+	}
+
+	configFile, err := img.ConfigFile()
+	if err != nil {
+		cleanup()
+		return nil, noop, fmt.Errorf("error reading image config: %w", err)
+	}
+
+	port := strconv.Itoa(cfg.Server.Port) + "/tcp"
+	configFile = configFile.DeepCopy()
+	configFile.Architecture = opts.Platform.Architecture
+	configFile.OS = opts.Platform.OS
+	configFile.Created = v1.Time{Time: time.Now().UTC()}
+	configFile.Config = v1.Config{
+		Entrypoint:   []string{BinaryPath},
+		Cmd:          []string{"serve", "--config", ConfigPath},
+		WorkingDir:   DocumentRootPath,
+		ExposedPorts: map[string]struct{}{port: {}},
+	}
+
+	img, err = mutate.ConfigFile(img, configFile)
+	if err != nil {
+		cleanup()
+		return nil, noop, fmt.Errorf("error setting image config: %w", err)
+	}
+
+	return img, cleanup, nil
+}
+
+// writeLayer writes the layer tarball containing the binary, the config and the document root.
+func writeLayer(w io.Writer, binary, documentRoot string, configData []byte) error {
+	tw := tar.NewWriter(w)
+
+	for _, dir := range []string{"/usr", "/usr/local", "/usr/local/bin", "/etc", "/etc/volk", "/srv"} {
+		if err := writeDir(tw, dir); err != nil {
+			return err
+		}
+	}
+
+	if err := writeFile(tw, BinaryPath, binary, 0755); err != nil {
+		return err
+	}
+
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     ConfigPath[1:],
+		Mode:     0644,
+		Size:     int64(len(configData)),
+	}); err != nil {
+		return fmt.Errorf("error writing config: %w", err)
+	}
+	if _, err := tw.Write(configData); err != nil {
+		return fmt.Errorf("error writing config: %w", err)
+	}
+
+	err := filepath.WalkDir(documentRoot, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(documentRoot, p)
+		if err != nil {
+			return err
+		}
+		target := path.Join(DocumentRootPath, filepath.ToSlash(rel))
+
+		switch {
+		case d.IsDir():
+			return writeDir(tw, target)
+		case d.Type().IsRegular():
+			return writeFile(tw, target, p, 0644)
+		default:
+			// Symlinks and special files are not copied into the image.
+			return nil
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("error copying document root: %w", err)
+	}
+
+	return tw.Close()
+}
+
+// writeDir adds a directory entry to the tarball.
+func writeDir(tw *tar.Writer, name string) error {
+	return tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     name[1:] + "/",
+		Mode:     0755,
+	})
+}
+
+// writeFile copies a file from the host into the tarball.
+func writeFile(tw *tar.Writer, name, src string, mode int64) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name[1:],
+		Mode:     mode,
+		Size:     info.Size(),
+		ModTime:  info.ModTime(),
+	}); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tw, f)
+	return err
+}
@@ -0,0 +1,139 @@
+package image
+
+import (
+	"archive/tar"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+)
+
+// layerFiles returns the entries of the single layer of img, by name, with
+// the contents of regular files.
+func layerFiles(t *testing.T, img v1.Image) map[string]string {
+	t.Helper()
+	layers, err := img.Layers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(layers) != 1 {
+		t.Fatalf("Expected 1 layer, got %d", len(layers))
+	}
+	r, err := layers[0].Uncompressed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	files := make(map[string]string)
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[header.Name] = string(data)
+	}
+	return files
+}
+
+func TestBuild(t *testing.T) {
+	dir := t.TempDir()
+	binary := filepath.Join(dir, "volk")
+	if err := os.WriteFile(binary, []byte("binary"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.Join(dir, "www")
+	if err := os.MkdirAll(filepath.Join(root, "css"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "index.html"), []byte("<h1>hello</h1>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "css", "site.css"), []byte("body{}"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(binary, filepath.Join(root, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.FileServer.DocumentRoot = root
+	cfg.Server.Port = 8081
+	img, cleanup, err := Build(Options{
+		Binary:   binary,
+		Config:   cfg,
+		Platform: v1.Platform{OS: "linux", Architecture: "arm64"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer cleanup()
+
+	files := layerFiles(t, img)
+	tests := map[string]string{
+		"usr/local/bin/volk":   "binary",
+		"srv/www/index.html":   "<h1>hello</h1>",
+		"srv/www/css/site.css": "body{}",
+	}
+	for name, want := range tests {
+		if got, ok := files[name]; !ok || got != want {
+			t.Errorf("Expected %s to hold %q, got %q (present: %v)", name, want, got, ok)
+		}
+	}
+	if _, ok := files["srv/www/link"]; ok {
+		t.Errorf("Expected the symbolic link to be left out of the image")
+	}
+	if _, ok := files["srv/www/css/"]; !ok {
+		t.Errorf("Expected a directory entry for srv/www/css/")
+	}
+
+	server := files["etc/volk/server.toml"]
+	if !strings.Contains(server, `"`+DocumentRootPath+`"`) || strings.Contains(server, root) {
+		t.Errorf("Expected the config to serve %s instead of %s, got:\n%s", DocumentRootPath, root, server)
+	}
+	if !strings.Contains(server, `"0.0.0.0"`) {
+		t.Errorf("Expected the config to listen on all addresses, got:\n%s", server)
+	}
+
+	configFile, err := img.ConfigFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if configFile.OS != "linux" || configFile.Architecture != "arm64" {
+		t.Errorf("Expected platform linux/arm64, got %s/%s", configFile.OS, configFile.Architecture)
+	}
+	if !slices.Equal(configFile.Config.Entrypoint, []string{BinaryPath}) {
+		t.Errorf("Expected entrypoint %s, got %v", BinaryPath, configFile.Config.Entrypoint)
+	}
+	if !slices.Equal(configFile.Config.Cmd, []string{"serve", "--config", ConfigPath}) {
+		t.Errorf("Expected to serve %s, got %v", ConfigPath, configFile.Config.Cmd)
+	}
+	if _, ok := configFile.Config.ExposedPorts["8081/tcp"]; !ok {
+		t.Errorf("Expected port 8081/tcp to be exposed, got %v", configFile.Config.ExposedPorts)
+	}
+}
+
+func TestBuildMissingBinary(t *testing.T) {
+	cfg := config.DefaultConfig()
+	cfg.FileServer.DocumentRoot = t.TempDir()
+	_, cleanup, err := Build(Options{Binary: filepath.Join(t.TempDir(), "missing"), Config: cfg})
+	defer cleanup()
+	if err == nil {
+		t.Errorf("Expected an error for a missing binary")
+	}
+}
@@ -0,0 +1,182 @@
+// Package kv is a small persistent store of JSON documents by key, backed by a bbolt file.
+//
+// Every value has an ETag derived from its content, which callers use for
+// conditional reads and for optimistic concurrency on writes.
+package kv
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var bucket = []byte("kv")
+
+var (
+	ErrNotFound           = errors.New("key not found")
+	ErrInvalidJSON        = errors.New("value is not valid JSON")
+	ErrPreconditionFailed = errors.New("precondition failed")
+	ErrQuotaExceeded      = errors.New("storage quota exceeded")
+)
+
+// Store is a key-value store of JSON documents.
+type Store struct {
+	db    *bolt.DB
+	quota int64        // Most bytes of keys and values stored, 0 for no limit
+	used  atomic.Int64 // Bytes of keys and values stored
+}
+
+// Open opens the store in the file at path, creating it if needed. Writes
+// that would store more than quota bytes of keys and values fail with
+// ErrQuotaExceeded; a quota of 0 means no limit.
+func Open(path string, quota int64) (*Store, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("error opening kv database %s: %w", path, err)
+	}
+	s := &Store{db: db, quota: quota}
+	err = db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists(bucket)
+		if err != nil {
+			return err
+		}
+		return b.ForEach(func(k, v []byte) error {
+			s.used.Add(int64(len(k) + len(v)))
+			return nil
+		})
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error initializing kv database %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Usage returns the bytes of keys and values stored and the quota, 0 if there is none.
+func (s *Store) Usage() (used, quota int64) {
+	return s.used.Load(), s.quota
+}
+
+// Close closes the database file.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// ETag returns the entity tag of a value.
+func ETag(value []byte) string {
+	sum := sha256.Sum256(value)
+	return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// Get returns the value stored under key and its ETag.
+func (s *Store) Get(key string) ([]byte, string, error) {
+	var value []byte
+	err := s.db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket(bucket).Get([]byte(key)); v != nil {
+			value = append([]byte(nil), v...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	if value == nil {
+		return nil, "", ErrNotFound
+	}
+	return value, ETag(value), nil
+}
+
+// Keys returns the stored keys in order.
+func (s *Store) Keys() ([]string, error) {
+	keys := []string{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).ForEach(func(k, _ []byte) error {
+			keys = append(keys, string(k))
+			return nil
+		})
+	})
+	return keys, err
+}
+
+// Precondition restricts a write to the current state of a key, as given by the
+// If-Match and If-None-Match headers. The zero value allows every write.
+type Precondition struct {
+	IfMatch     string // The current ETag must be this, or "*" for any existing value
+	IfNoneMatch string // The current ETag must not be this, or "*" for the key not to exist
+}
+
+// check reports whether the precondition holds for the current value, which is nil if the key does not exist.
+func (p Precondition) check(current []byte) bool {
+	if p.IfMatch != "" {
+		if current == nil || (p.IfMatch != "*" && p.IfMatch != ETag(current)) {
+			return false
+		}
+	}
+	if p.IfNoneMatch != "" && current != nil {
+		if p.IfNoneMatch == "*" || p.IfNoneMatch == ETag(current) {
+			return false
+		}
+	}
+	return true
+}
+
+// Put stores a JSON value under key if the precondition holds. It returns the
+// new ETag and whether the key was created.
+func (s *Store) Put(key string, value []byte, precondition Precondition) (string, bool, error) {
+	if !json.Valid(value) {
+		return "", false, ErrInvalidJSON
+	}
+
+	created := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		current := b.Get([]byte(key))
+		if !precondition.check(current) {
+			return ErrPreconditionFailed
+		}
+		created = current == nil
+
+		change := int64(len(value) - len(current))
+		if created {
+			change += int64(len(key))
+		}
+		if s.quota > 0 && change > 0 && s.used.Load()+change > s.quota {
+			return ErrQuotaExceeded
+		}
+		if err := b.Put([]byte(key), value); err != nil {
+			return err
+		}
+		// Updates are serialized, so used cannot change in between.
+		s.used.Add(change)
+		return nil
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return ETag(value), created, nil
+}
+
+// Delete removes key if the precondition holds.
+func (s *Store) Delete(key string, precondition Precondition) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		current := b.Get([]byte(key))
+		if current == nil {
+			return ErrNotFound
+		}
+		if !precondition.check(current) {
+			return ErrPreconditionFailed
+		}
+		if err := b.Delete([]byte(key)); err != nil {
+			return err
+		}
+		s.used.Add(-int64(len(key) + len(current)))
+		return nil
+	})
+}
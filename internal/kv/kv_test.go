@@ -0,0 +1,134 @@
+package kv
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	store, err := Open(filepath.Join(t.TempDir(), "kv.db"), 0)
+	if err != nil {
+		t.Fatalf("Open returned an error: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestStore(t *testing.T) {
+	store := openTestStore(t)
+
+	if _, _, err := store.Get("todo"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for a missing key, got %v", err)
+	}
+
+	etag, created, err := store.Put("todo", []byte(`{"done":false}`), Precondition{})
+	if err != nil || !created {
+		t.Fatalf("Expected the key to be created, got %v, %v", created, err)
+	}
+
+	value, got, err := store.Get("todo")
+	if err != nil || string(value) != `{"done":false}` || got != etag {
+		t.Errorf("Unexpected Get result %q, %q, %v", value, got, err)
+	}
+
+	if _, _, err := store.Put("todo", []byte(`{"done":`), Precondition{}); !errors.Is(err, ErrInvalidJSON) {
+		t.Errorf("Expected ErrInvalidJSON, got %v", err)
+	}
+
+	keys, err := store.Keys()
+	if err != nil || len(keys) != 1 || keys[0] != "todo" {
+		t.Errorf("Expected keys [todo], got %v, %v", keys, err)
+	}
+
+	if err := store.Delete("todo", Precondition{}); err != nil {
+		t.Errorf("Delete returned an error: %v", err)
+	}
+	if err := store.Delete("todo", Precondition{}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound when deleting twice, got %v", err)
+	}
+}
+
+func TestStorePreconditions(t *testing.T) {
+	store := openTestStore(t)
+	etag, _, _ := store.Put("doc", []byte(`1`), Precondition{})
+
+	tests := []struct {
+		name         string
+		precondition Precondition
+		err          error
+	}{
+		{"matching If-Match", Precondition{IfMatch: etag}, nil},
+		{"stale If-Match", Precondition{IfMatch: `"stale"`}, ErrPreconditionFailed},
+		{"If-Match any", Precondition{IfMatch: "*"}, nil},
+		{"create only", Precondition{IfNoneMatch: "*"}, ErrPreconditionFailed},
+		{"If-None-Match other", Precondition{IfNoneMatch: `"other"`}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store.Put("doc", []byte(`1`), Precondition{})
+			if _, _, err := store.Put("doc", []byte(`1`), tt.precondition); !errors.Is(err, tt.err) {
+				t.Errorf("Expected error %v, got %v", tt.err, err)
+			}
+		})
+	}
+
+	if _, created, err := store.Put("new", []byte(`2`), Precondition{IfNoneMatch: "*"}); err != nil || !created {
+		t.Errorf("Expected If-None-Match: * to create a new key, got %v, %v", created, err)
+	}
+	if _, _, err := store.Put("missing", []byte(`2`), Precondition{IfMatch: "*"}); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("Expected If-Match: * to fail for a missing key, got %v", err)
+	}
+}
+
+func TestStoreQuota(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kv.db")
+	store, err := Open(path, 20)
+	if err != nil {
+		t.Fatalf("Open returned an error: %v", err)
+	}
+
+	steps := []struct {
+		name     string
+		key      string
+		value    string
+		delete   bool
+		wantErr  error
+		wantUsed int64
+	}{
+		{"first value", "a", `[1,2,3,4]`, false, nil, 10},
+		{"second value", "b", `"1234567"`, false, nil, 20},
+		{"over the quota", "c", `1`, false, ErrQuotaExceeded, 20},
+		{"shrinking is allowed", "b", `""`, false, nil, 13},
+		{"replacing within the quota", "a", `[1,2,3,4,5,6]`, false, nil, 17},
+		{"delete frees space", "a", "", true, nil, 3},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if step.delete {
+				err = store.Delete(step.key, Precondition{})
+			} else {
+				_, _, err = store.Put(step.key, []byte(step.value), Precondition{})
+			}
+			if !errors.Is(err, step.wantErr) {
+				t.Errorf("Expected error %v, got %v", step.wantErr, err)
+			}
+			if used, quota := store.Usage(); used != step.wantUsed || quota != 20 {
+				t.Errorf("Expected usage %d of 20, got %d of %d", step.wantUsed, used, quota)
+			}
+		})
+	}
+
+	store.Close()
+	store, err = Open(path, 20)
+	if err != nil {
+		t.Fatalf("Open returned an error: %v", err)
+	}
+	defer store.Close()
+	if used, _ := store.Usage(); used != 3 {
+		t.Errorf("Expected the usage to be counted on open, got %d", used)
+	}
+}
@@ -0,0 +1,162 @@
+package logsink
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// sink is one output of a Fanout.
+type sink struct {
+	name     string // Output name, for errors
+	format   string // plain, verbose or json; unused by system writers
+	maxLevel int    // Most verbose severity written
+	w        io.Writer
+	system   systemWriter // Set for syslog and journald, instead of w
+	file     *File        // Set for files, closed by Close
+}
+
+// Fanout writes each log line to several sinks. The log package must be
+// set up with only the Llongfile flag; sinks add the time and shorten the
+// file name as their format requires.
+type Fanout struct {
+	mu    sync.Mutex
+	sinks []*sink
+}
+
+// NewFanout opens the sinks of cfgs.
+func NewFanout(cfgs []config.LogSinkConfig) (*Fanout, error) {
+	f := &Fanout{}
+	for i, cfg := range cfgs {
+		s, err := newSink(cfg)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("log sink %d: %w", i+1, err)
+		}
+		f.sinks = append(f.sinks, s)
+	}
+	return f, nil
+}
+
+// newSink opens the sink cfg configures.
+func newSink(cfg config.LogSinkConfig) (*sink, error) {
+	s := &sink{name: cfg.Output, format: cfg.Format, maxLevel: severityDebug}
+	switch s.format {
+	case "":
+		s.format = "plain"
+	case "plain", "verbose", "json":
+	default:
+		return nil, fmt.Errorf("unknown log format %q", cfg.Format)
+	}
+	if cfg.Level != "" {
+		level, ok := parseLevel(cfg.Level)
+		if !ok {
+			return nil, fmt.Errorf("unknown log level %q", cfg.Level)
+		}
+		s.maxLevel = level
+	}
+
+	switch cfg.Output {
+	case "stdout":
+		s.w = os.Stdout
+	case "stderr":
+		s.w = os.Stderr
+	case "file":
+		if cfg.FilePath == "" {
+			return nil, errors.New("a file sink needs a file_path")
+		}
+		file, err := OpenFile(cfg.FilePath, cfg.Compress)
+		if err != nil {
+			return nil, err
+		}
+		s.w, s.file = file, file
+	default:
+		system, err := newSystem(cfg.Output, cfg.SyslogAddress, cfg.Facility, cfg.Tag)
+		if err != nil {
+			return nil, err
+		}
+		s.system = system
+	}
+	return s, nil
+}
+
+// parseLevel returns the severity of a level name.
+func parseLevel(name string) (int, bool) {
+	for severity, n := range severityNames {
+		if strings.EqualFold(name, n) {
+			return severity, true
+		}
+	}
+	return 0, false
+}
+
+// Write sends a line to every sink whose level it reaches. A sink that
+// fails does not stop the others; the failure is reported on stderr.
+func (f *Fanout) Write(p []byte) (int, error) {
+	l := parse(p)
+	now := time.Now()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, s := range f.sinks {
+		if l.severity > s.maxLevel {
+			continue
+		}
+		var err error
+		if s.system != nil {
+			err = s.system.send(l)
+		} else {
+			_, err = io.WriteString(s.w, formatLine(s.format, l, now))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing to log sink %s: %v\n", s.name, err)
+		}
+	}
+	return len(p), nil
+}
+
+// Close closes the files of the sinks.
+func (f *Fanout) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var errs []error
+	for _, s := range f.sinks {
+		if s.file != nil {
+			errs = append(errs, s.file.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// formatLine formats a line for a file or console sink. plain and verbose
+// look like the log package's output with the short and long file name.
+func formatLine(format string, l line, now time.Time) string {
+	if format == "json" {
+		data, _ := json.Marshal(struct {
+			Time    string `json:"time"`
+			Level   string `json:"level"`
+			File    string `json:"file,omitempty"`
+			Line    int    `json:"line,omitempty"`
+			Message string `json:"message"`
+		}{now.Format(time.RFC3339Nano), severityNames[l.severity], l.file, l.line, l.message})
+		return string(data) + "\n"
+	}
+
+	source := ""
+	if l.file != "" {
+		file := l.file
+		if format == "plain" {
+			file = filepath.Base(file)
+		}
+		source = fmt.Sprintf("%s:%d: ", file, l.line)
+	}
+	return now.Format("2006/01/02 15:04:05") + " " + source + l.message + "\n"
+}
@@ -0,0 +1,89 @@
+package logsink
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestFanout(t *testing.T) {
+	conn, socket := listen(t)
+	dir := t.TempDir()
+	plain, jsonFile := filepath.Join(dir, "volk.log"), filepath.Join(dir, "logs", "volk.jsonl")
+
+	fanout, err := NewFanout([]config.LogSinkConfig{
+		{Output: "file", FilePath: plain},
+		{Output: "file", FilePath: jsonFile, Format: "json", Level: "info"},
+		{Output: "syslog", SyslogAddress: "unix://" + socket, Level: "warning"},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	for _, line := range []string{
+		"/src/volk/internal/http/server.go:42: Debug: location=/ status=200\n",
+		"/src/volk/internal/http/server.go:43: Access: GET / HTTP/1.1 - 200 OK\n",
+		"/src/volk/volk/cmd/serve.go:7: Warning: Could not open log file\n",
+	} {
+		if _, err := fanout.Write([]byte(line)); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	if err := fanout.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := os.ReadFile(plain)
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || !regexp.MustCompile(`^\d{4}/\d\d/\d\d \d\d:\d\d:\d\d server\.go:42: Debug: location=/ status=200$`).MatchString(lines[0]) {
+		t.Errorf("Expected 3 plain lines starting with the debug line, got %q", lines)
+	}
+
+	data, _ = os.ReadFile(jsonFile)
+	lines = strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 JSON lines at level info, got %q", lines)
+	}
+	var entry struct {
+		Time    time.Time `json:"time"`
+		Level   string    `json:"level"`
+		File    string    `json:"file"`
+		Line    int       `json:"line"`
+		Message string    `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry.Level != "warning" || entry.File != "/src/volk/volk/cmd/serve.go" || entry.Line != 7 || entry.Message != "Warning: Could not open log file" || entry.Time.IsZero() {
+		t.Errorf("Unexpected JSON line %+v", entry)
+	}
+
+	if got := receive(t, conn); !strings.HasSuffix(got, ": /src/volk/volk/cmd/serve.go:7: Warning: Could not open log file") {
+		t.Errorf("Expected syslog to get only the warning, got %q", got)
+	}
+}
+
+func TestNewFanoutErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.LogSinkConfig
+	}{
+		{"unknown output", config.LogSinkConfig{Output: "kafka"}},
+		{"file without path", config.LogSinkConfig{Output: "file"}},
+		{"unknown format", config.LogSinkConfig{Output: "stderr", Format: "xml"}},
+		{"unknown level", config.LogSinkConfig{Output: "stderr", Level: "trace"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewFanout([]config.LogSinkConfig{{Output: "stdout"}, tt.cfg}); err == nil {
+				t.Error("Expected an error, got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,77 @@
+package logsink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// journalSocket is where systemd-journald receives native messages.
+var journalSocket = "/run/systemd/journal/socket"
+
+// journalWriter sends lines to the systemd journal.
+type journalWriter struct {
+	conn     *net.UnixConn
+	facility int
+	tag      string
+}
+
+// newJournal connects to the journal socket at path.
+func newJournal(path string, facility int, tag string) (*journalWriter, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to journald: %w", err)
+	}
+	return &journalWriter{conn: conn, facility: facility, tag: tag}, nil
+}
+
+// Write sends a line as a journal entry.
+func (w *journalWriter) Write(p []byte) (int, error) {
+	if err := w.send(parse(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// send sends a parsed line as a journal entry, with the source file and
+// line of the message as the CODE_FILE and CODE_LINE fields.
+func (w *journalWriter) send(l line) error {
+	fields := [][2]string{
+		{"MESSAGE", l.message},
+		{"PRIORITY", strconv.Itoa(l.severity)},
+		{"SYSLOG_IDENTIFIER", w.tag},
+		{"SYSLOG_FACILITY", strconv.Itoa(w.facility)},
+		{"SYSLOG_PID", strconv.Itoa(pid)},
+	}
+	if l.file != "" {
+		fields = append(fields, [2]string{"CODE_FILE", l.file}, [2]string{"CODE_LINE", strconv.Itoa(l.line)})
+	}
+
+	if _, err := w.conn.Write(journalEntry(fields)); err != nil {
+		return fmt.Errorf("error writing to journald: %w", err)
+	}
+	return nil
+}
+
+// journalEntry encodes fields in the native journal protocol: NAME=value
+// lines, or for values with a newline the name, a newline, the value's
+// length as a little-endian 64-bit integer, the value and a newline.
+func journalEntry(fields [][2]string) []byte {
+	var buf bytes.Buffer
+	for _, field := range fields {
+		name, value := field[0], field[1]
+		if !strings.Contains(value, "\n") {
+			fmt.Fprintf(&buf, "%s=%s\n", name, value)
+			continue
+		}
+		buf.WriteString(name)
+		buf.WriteByte('\n')
+		binary.Write(&buf, binary.LittleEndian, uint64(len(value)))
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,133 @@
+// Package logsink sends the server log to the system log instead of a file:
+//
+//   - syslog: lines are sent to the local syslog daemon over its Unix socket,
+//     or to a remote one over UDP or TCP, in the traditional BSD format.
+//   - journald: lines are sent to the systemd journal with its native
+//     protocol, with the source file and line as separate fields.
+//
+// A Fanout sends each line to several outputs at once, including files and
+// the console, each with its own format and minimum level.
+//
+// The log package does not know levels, so the priority of a line is taken
+// from how volk words its messages: lines starting with "Error" are errors,
+// "Warning" warnings, "Debug" debug messages and the rest informational.
+package logsink
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// DefaultTag is the program name lines are logged under.
+const DefaultTag = "volk"
+
+// ErrUnknownOutput is returned by New for an output it does not support.
+var ErrUnknownOutput = errors.New("unknown log output")
+
+// Severities of lines, as in RFC 5424.
+const (
+	severityError   = 3
+	severityWarning = 4
+	severityInfo    = 6
+	severityDebug   = 7
+)
+
+// facilities are the syslog facility codes by name.
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// IsSink reports whether output is sent to the system log by New.
+func IsSink(output string) bool {
+	return output == "syslog" || output == "journald"
+}
+
+// systemWriter is a syslog or journald writer.
+type systemWriter interface {
+	io.Writer
+	send(l line) error
+}
+
+// New creates the writer of the syslog or journald output of cfg. Each call
+// to Write must hold one log line, as the log package does.
+func New(cfg config.LogConfig) (io.Writer, error) {
+	return newSystem(cfg.Output, cfg.SyslogAddress, cfg.Facility, cfg.Tag)
+}
+
+// newSystem creates the writer of a syslog or journald output.
+func newSystem(output, address, facilityName, tag string) (systemWriter, error) {
+	if tag == "" {
+		tag = DefaultTag
+	}
+	facility, ok := facilities[strings.ToLower(facilityName)]
+	if facilityName == "" {
+		facility, ok = facilities["daemon"], true
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown syslog facility %q", facilityName)
+	}
+
+	switch output {
+	case "syslog":
+		return newSyslog(address, facility, tag)
+	case "journald":
+		return newJournal(journalSocket, facility, tag)
+	default:
+		return nil, fmt.Errorf("%w %q", ErrUnknownOutput, output)
+	}
+}
+
+// sourcePrefix matches the file and line the log package puts before the
+// message with the Lshortfile or Llongfile flag.
+var sourcePrefix = regexp.MustCompile(`^(\S+):(\d+): `)
+
+// line is a log line split into its parts.
+type line struct {
+	file     string // Source file, empty if the line does not name it
+	line     int
+	message  string
+	severity int
+}
+
+// severityNames are the names of severities in sink levels and JSON lines.
+var severityNames = map[int]string{
+	severityError:   "error",
+	severityWarning: "warning",
+	severityInfo:    "info",
+	severityDebug:   "debug",
+}
+
+// parse splits a line written by the log package without date and time flags.
+func parse(p []byte) line {
+	l := line{message: strings.TrimRight(string(p), "\n")}
+	if m := sourcePrefix.FindStringSubmatch(l.message); m != nil {
+		l.file = m[1]
+		l.line, _ = strconv.Atoi(m[2])
+		l.message = l.message[len(m[0]):]
+	}
+
+	switch {
+	case strings.HasPrefix(l.message, "Error"), strings.HasPrefix(l.message, "Fatal"):
+		l.severity = severityError
+	case strings.HasPrefix(l.message, "Warning"):
+		l.severity = severityWarning
+	case strings.HasPrefix(l.message, "Debug"):
+		l.severity = severityDebug
+	default:
+		l.severity = severityInfo
+	}
+	return l
+}
+
+// pid is the process ID lines are logged with.
+var pid = os.Getpid()
@@ -0,0 +1,152 @@
+package logsink
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// listen returns a Unix datagram socket in a temporary directory and its path.
+func listen(t *testing.T) (*net.UnixConn, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("Unix datagram sockets are not supported: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn, path
+}
+
+// receive returns the next datagram received on conn.
+func receive(t *testing.T, conn net.PacketConn) string {
+	t.Helper()
+	buf := make([]byte, 64*1024)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(buf[:n])
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected line
+	}{
+		{"server.go:42: Access: GET / HTTP/1.1 - 200 OK\n", line{"server.go", 42, "Access: GET / HTTP/1.1 - 200 OK", severityInfo}},
+		{"/src/volk/serve.go:7: Error loading configuration: bad\n", line{"/src/volk/serve.go", 7, "Error loading configuration: bad", severityError}},
+		{"Warning: Slow request\n", line{"", 0, "Warning: Slow request", severityWarning}},
+		{"kv.go:1: Debug:   > Host: example.com\n", line{"kv.go", 1, "Debug:   > Host: example.com", severityDebug}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := parse([]byte(tt.line)); got != tt.expected {
+				t.Errorf("Expected %+v, got %+v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestSyslog(t *testing.T) {
+	conn, path := listen(t)
+	w, err := New(config.LogConfig{Output: "syslog", SyslogAddress: "unix://" + path, Facility: "local3", Tag: "web"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if _, err := w.Write([]byte("server.go:42: Error writing response: broken pipe\n")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	got := receive(t, conn)
+	// local3 (19) * 8 + error (3)
+	if !strings.HasPrefix(got, "<155>") || !strings.HasSuffix(got, fmt.Sprintf(" web[%d]: server.go:42: Error writing response: broken pipe", pid)) {
+		t.Errorf("Unexpected syslog message %q", got)
+	}
+}
+
+func TestSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w, err := New(config.LogConfig{Output: "syslog", SyslogAddress: "udp://" + conn.LocalAddr().String()})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := w.Write([]byte("Access: GET / HTTP/1.1 - 200 OK\n")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// daemon (3) * 8 + info (6), with the host name for a remote daemon
+	got := receive(t, conn)
+	if !strings.HasPrefix(got, "<30>") || !strings.HasSuffix(got, fmt.Sprintf(" volk[%d]: Access: GET / HTTP/1.1 - 200 OK", pid)) {
+		t.Errorf("Unexpected syslog message %q", got)
+	}
+}
+
+func TestJournald(t *testing.T) {
+	conn, path := listen(t)
+	journalSocket = path
+	defer func() { journalSocket = "/run/systemd/journal/socket" }()
+
+	w, err := New(config.LogConfig{Output: "journald"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := w.Write([]byte("server.go:42: Warning: Slow request\n")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	got := receive(t, conn)
+	for _, field := range []string{"MESSAGE=Warning: Slow request\n", "PRIORITY=4\n", "SYSLOG_IDENTIFIER=volk\n", "SYSLOG_FACILITY=3\n", "CODE_FILE=server.go\n", "CODE_LINE=42\n"} {
+		if !strings.Contains(got, field) {
+			t.Errorf("Expected the entry to contain %q, got %q", field, got)
+		}
+	}
+}
+
+func TestJournalEntryMultiline(t *testing.T) {
+	var expected bytes.Buffer
+	expected.WriteString("PRIORITY=6\nMESSAGE\n")
+	binary.Write(&expected, binary.LittleEndian, uint64(9))
+	expected.WriteString("one\ntwo\n3\n")
+
+	if got := journalEntry([][2]string{{"PRIORITY", "6"}, {"MESSAGE", "one\ntwo\n3"}}); !bytes.Equal(got, expected.Bytes()) {
+		t.Errorf("Expected %q, got %q", expected.Bytes(), got)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.LogConfig
+	}{
+		{"unknown output", config.LogConfig{Output: "kafka"}},
+		{"unknown facility", config.LogConfig{Output: "syslog", Facility: "local9"}},
+		{"invalid address", config.LogConfig{Output: "syslog", SyslogAddress: "http://example.com"}},
+		{"no journald", config.LogConfig{Output: "journald"}},
+	}
+	journalSocket = filepath.Join(t.TempDir(), "missing.sock")
+	defer func() { journalSocket = "/run/systemd/journal/socket" }()
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.cfg); err == nil {
+				t.Error("Expected an error, got nil")
+			}
+		})
+	}
+	if _, err := New(config.LogConfig{Output: "kafka"}); !errors.Is(err, ErrUnknownOutput) {
+		t.Errorf("Expected ErrUnknownOutput, got %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package logsink
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// File is a log file whose path is a pattern of the current time, such as
+// access-%Y-%m-%d.log. When the expanded path changes, e.g. at midnight, the
+// next line goes to a new file, and the previous one is compressed with
+// gzip if requested.
+type File struct {
+	pattern  string
+	compress bool
+	now      func() time.Time
+
+	mu   sync.Mutex
+	path string // Path of the open file
+	file *os.File
+	wg   sync.WaitGroup // Running compressions
+}
+
+// OpenFile opens the log file for the current time of pattern, which may
+// contain the strftime conversions %Y, %y, %m, %d, %j, %H, %M and %%.
+func OpenFile(pattern string, compress bool) (*File, error) {
+	return openFile(pattern, compress, time.Now)
+}
+
+// openFile is OpenFile with a clock.
+func openFile(pattern string, compress bool, now func() time.Time) (*File, error) {
+	if _, err := expand(pattern, time.Time{}); err != nil {
+		return nil, err
+	}
+	f := &File{pattern: pattern, compress: compress, now: now}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open opens the file for the current time. The caller holds f.mu, except
+// in openFile.
+func (f *File) open() error {
+	path, _ := expand(f.pattern, f.now())
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating log directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening log file: %w", err)
+	}
+	f.path, f.file = path, file
+	return nil
+}
+
+// Write writes p to the file for the current time, rolling over first if
+// that is a new file.
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if path, _ := expand(f.pattern, f.now()); path != f.path {
+		old := f.path
+		f.file.Close()
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+		if f.compress {
+			f.wg.Add(1)
+			go func() {
+				defer f.wg.Done()
+				if err := compressFile(old); err != nil {
+					fmt.Fprintf(os.Stderr, "Error compressing log file: %v\n", err)
+				}
+			}()
+		}
+	}
+	return f.file.Write(p)
+}
+
+// Close closes the file and waits for running compressions.
+func (f *File) Close() error {
+	f.mu.Lock()
+	err := f.file.Close()
+	f.mu.Unlock()
+	f.wg.Wait()
+	return err
+}
+
+// expand replaces the strftime conversions of pattern with t.
+func expand(pattern string, t time.Time) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		if pattern[i] != '%' {
+			b.WriteByte(pattern[i])
+			continue
+		}
+		if i+1 == len(pattern) {
+			return "", fmt.Errorf("invalid log file pattern %q: ends with %%", pattern)
+		}
+		i++
+		switch pattern[i] {
+		case 'Y':
+			b.WriteString(t.Format("2006"))
+		case 'y':
+			b.WriteString(t.Format("06"))
+		case 'm':
+			b.WriteString(t.Format("01"))
+		case 'd':
+			b.WriteString(t.Format("02"))
+		case 'j':
+			fmt.Fprintf(&b, "%03d", t.YearDay())
+		case 'H':
+			b.WriteString(t.Format("15"))
+		case 'M':
+			b.WriteString(t.Format("04"))
+		case '%':
+			b.WriteByte('%')
+		default:
+			return "", fmt.Errorf("invalid log file pattern %q: unknown conversion %%%c", pattern, pattern[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// compressFile replaces the file at path with a gzip-compressed copy at
+// path.gz.
+func compressFile(path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.gz.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	zw := gzip.NewWriter(tmp)
+	_, err = io.Copy(zw, src)
+	if err == nil {
+		err = zw.Close()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error compressing %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), path+".gz"); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
@@ -0,0 +1,75 @@
+package logsink
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExpand(t *testing.T) {
+	now := time.Date(2026, 2, 3, 4, 5, 6, 0, time.UTC)
+
+	tests := []struct {
+		pattern  string
+		expected string
+		wantErr  bool
+	}{
+		{"volk.log", "volk.log", false},
+		{"logs/access-%Y-%m-%d.log", "logs/access-2026-02-03.log", false},
+		{"%y%j-%H%M.log", "26034-0405.log", false},
+		{"100%%.log", "100%.log", false},
+		{"access-%Q.log", "", true},
+		{"access-%", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern, func(t *testing.T) {
+			got, err := expand(tt.pattern, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestFileRollover(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 2, 3, 23, 59, 59, 0, time.UTC)
+	f, err := openFile(filepath.Join(dir, "access-%Y-%m-%d.log"), true, func() time.Time { return now })
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	f.Write([]byte("before midnight\n"))
+	now = now.Add(time.Second)
+	f.Write([]byte("after midnight\n"))
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if data, _ := os.ReadFile(filepath.Join(dir, "access-2026-02-04.log")); string(data) != "after midnight\n" {
+		t.Errorf("Expected the new file to hold the second line, got %q", data)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "access-2026-02-03.log")); !os.IsNotExist(err) {
+		t.Errorf("Expected the rotated file to be replaced by its compressed copy, got %v", err)
+	}
+
+	file, err := os.Open(filepath.Join(dir, "access-2026-02-03.log.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	zr, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := io.ReadAll(zr); string(data) != "before midnight\n" {
+		t.Errorf("Expected the compressed file to hold the first line, got %q", data)
+	}
+}
@@ -0,0 +1,117 @@
+package logsink
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// localSyslogSockets are where syslog daemons listen on the local system.
+var localSyslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// syslogWriter sends lines to a syslog daemon.
+type syslogWriter struct {
+	network  string
+	address  string
+	facility int
+	tag      string
+	hostname string // Sent to remote daemons only; the local one knows it
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// newSyslog connects to the syslog daemon at address: udp://host:port,
+// tcp://host:port or unix:///path, or the local daemon if it is empty.
+func newSyslog(address string, facility int, tag string) (*syslogWriter, error) {
+	w := &syslogWriter{facility: facility, tag: tag}
+	if address == "" {
+		for _, path := range localSyslogSockets {
+			if _, err := os.Stat(path); err == nil {
+				w.network, w.address = "unixgram", path
+				break
+			}
+		}
+		if w.address == "" {
+			return nil, fmt.Errorf("error connecting to syslog: no local syslog socket found")
+		}
+	} else {
+		u, err := url.Parse(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid syslog address %q", address)
+		}
+		switch u.Scheme {
+		case "udp", "tcp":
+			w.network, w.address = u.Scheme, u.Host
+			w.hostname, _ = os.Hostname()
+		case "unix":
+			w.network, w.address = "unixgram", u.Path
+		default:
+			return nil, fmt.Errorf("invalid syslog address %q: use udp://, tcp:// or unix://", address)
+		}
+	}
+
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// connect (re)connects to the daemon.
+func (w *syslogWriter) connect() error {
+	if w.conn != nil {
+		w.conn.Close()
+	}
+	conn, err := net.Dial(w.network, w.address)
+	if err != nil {
+		return fmt.Errorf("error connecting to syslog: %w", err)
+	}
+	w.conn = conn
+	return nil
+}
+
+// Write sends a line, reconnecting once if the daemon went away.
+func (w *syslogWriter) Write(p []byte) (int, error) {
+	if err := w.send(parse(p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// send sends a parsed line, reconnecting once if the daemon went away.
+func (w *syslogWriter) send(l line) error {
+	message := l.message
+	if l.file != "" {
+		message = fmt.Sprintf("%s:%d: %s", l.file, l.line, l.message)
+	}
+	data := []byte(w.format(l.severity, message, time.Now()))
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.conn.Write(data); err != nil {
+		if err := w.connect(); err != nil {
+			return err
+		}
+		if _, err := w.conn.Write(data); err != nil {
+			return fmt.Errorf("error writing to syslog: %w", err)
+		}
+	}
+	return nil
+}
+
+// format returns a message in the BSD syslog format of RFC 3164. TCP
+// messages end with a newline, which frames them.
+func (w *syslogWriter) format(severity int, message string, now time.Time) string {
+	host := ""
+	if w.hostname != "" {
+		host = w.hostname + " "
+	}
+	msg := fmt.Sprintf("<%d>%s %s%s[%d]: %s", w.facility*8+severity, now.Format(time.Stamp), host, w.tag, pid, message)
+	if w.network == "tcp" {
+		msg += "\n"
+	}
+	return msg
+}
@@ -0,0 +1,288 @@
+// Package metrics keeps the counters and the request duration histogram of
+// the server and writes them in the OpenMetrics text format, or the older
+// Prometheus text format for scrapers that do not ask for OpenMetrics. The
+// histogram buckets and the families exposed are configurable, since fixed
+// buckets rarely match the latency objectives of a site.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// Names of the metric families.
+const (
+	Requests          = "volk_requests"                 // Counter of answered requests by method and status code
+	RequestDuration   = "volk_request_duration_seconds" // Histogram of the time from reading a request to writing its response
+	RequestBytes      = "volk_request_bytes"            // Counter of request bytes read, by host and location
+	ResponseBytes     = "volk_response_bytes"           // Counter of response bytes written, by host and location
+	CompressionInput  = "volk_compression_input_bytes"  // Counter of response body bytes before compression, by host and location
+	CompressionOutput = "volk_compression_output_bytes" // Counter of response body bytes after compression, by host and location
+	InFlight          = "volk_requests_in_flight"       // Gauge of requests being handled
+)
+
+// Families lists the metric families in the order they are written.
+var Families = []string{Requests, RequestDuration, RequestBytes, ResponseBytes, CompressionInput, CompressionOutput, InFlight}
+
+// Content types of the two text formats.
+const (
+	OpenMetricsType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+	PrometheusType  = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// methods are the method label values; other methods are counted as "other"
+// so that clients cannot create label values at will.
+var methods = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}
+
+// otherHost is the host label of requests for hosts that are not configured,
+// and noLocation the location label of requests outside every location.
+const (
+	otherHost  = "other"
+	noLocation = "none"
+)
+
+// requestKey are the labels of the request counter.
+type requestKey struct {
+	method string
+	code   int
+}
+
+// byteKey are the labels of the byte counters.
+type byteKey struct {
+	host     string
+	location string
+}
+
+// byteCounts are the byte counters of a host and location.
+type byteCounts struct {
+	request, response, compressionInput, compressionOutput int64
+}
+
+// Observation describes an answered request.
+type Observation struct {
+	Method        string
+	Code          int
+	Duration      time.Duration
+	Host          string // Host header of the request, with or without a port
+	Location      string // Path of the request's location, empty for none
+	RequestBytes  int    // Bytes of the request read
+	ResponseBytes int    // Bytes of the response written
+	// Sizes of the response body before and after compression, both zero
+	// unless the server compressed it.
+	UncompressedBytes, CompressedBytes int
+}
+
+// Registry holds the metrics of a server. It is safe for concurrent use.
+type Registry struct {
+	buckets  []float64
+	disabled map[string]bool
+	hosts    []string // Host label values; other hosts are counted as otherHost
+
+	mu       sync.Mutex
+	requests map[requestKey]int64
+	counts   []int64 // Observations per bucket, not cumulative; the last one is +Inf
+	sum      float64
+	bytes    map[byteKey]*byteCounts
+}
+
+// New creates a Registry with the buckets and disabled families of cfg. It
+// returns an error for buckets that are not positive and strictly ascending,
+// and for unknown family names.
+func New(cfg config.MetricsConfig) (*Registry, error) {
+	for i, bound := range cfg.Buckets {
+		if bound <= 0 {
+			return nil, fmt.Errorf("metrics bucket %g is not positive", bound)
+		}
+		if i > 0 && bound <= cfg.Buckets[i-1] {
+			return nil, fmt.Errorf("metrics buckets must be ascending, %g follows %g", bound, cfg.Buckets[i-1])
+		}
+	}
+	disabled := make(map[string]bool)
+	for _, name := range cfg.Disable {
+		if !slices.Contains(Families, name) {
+			return nil, fmt.Errorf("unknown metric family %q, expected one of %s", name, strings.Join(Families, ", "))
+		}
+		disabled[name] = true
+	}
+
+	hosts := make([]string, len(cfg.Hosts))
+	for i, host := range cfg.Hosts {
+		hosts[i] = strings.ToLower(host)
+	}
+
+	return &Registry{
+		buckets:  slices.Clone(cfg.Buckets),
+		disabled: disabled,
+		hosts:    hosts,
+		requests: make(map[requestKey]int64),
+		counts:   make([]int64, len(cfg.Buckets)+1),
+		bytes:    make(map[byteKey]*byteCounts),
+	}, nil
+}
+
+// Observe records an answered request.
+func (r *Registry) Observe(o Observation) {
+	method := o.Method
+	if !slices.Contains(methods, method) {
+		method = "other"
+	}
+	seconds := o.Duration.Seconds()
+	bucket, _ := slices.BinarySearch(r.buckets, seconds)
+	key := byteKey{host: r.hostLabel(o.Host), location: o.Location}
+	if key.location == "" {
+		key.location = noLocation
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.requests[requestKey{method, o.Code}]++
+	r.counts[bucket]++
+	r.sum += seconds
+	counts, ok := r.bytes[key]
+	if !ok {
+		counts = &byteCounts{}
+		r.bytes[key] = counts
+	}
+	counts.request += int64(o.RequestBytes)
+	counts.response += int64(o.ResponseBytes)
+	counts.compressionInput += int64(o.UncompressedBytes)
+	counts.compressionOutput += int64(o.CompressedBytes)
+}
+
+// hostLabel returns the host label of a Host header: the host if it is one
+// of the configured hosts, so that clients cannot create label values at
+// will, and otherHost if not.
+func (r *Registry) hostLabel(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if slices.Contains(r.hosts, host) {
+		return host
+	}
+	return otherHost
+}
+
+// Write writes the enabled families to w, in the OpenMetrics format if
+// openMetrics is set and in the Prometheus text format otherwise. inFlight
+// is the value of the in-flight gauge.
+func (r *Registry) Write(w io.Writer, openMetrics bool, inFlight int64) error {
+	r.mu.Lock()
+	keys := make([]requestKey, 0, len(r.requests))
+	for key := range r.requests {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b requestKey) int {
+		if c := strings.Compare(a.method, b.method); c != 0 {
+			return c
+		}
+		return a.code - b.code
+	})
+	requests := make([]int64, len(keys))
+	for i, key := range keys {
+		requests[i] = r.requests[key]
+	}
+	counts := slices.Clone(r.counts)
+	sum := r.sum
+	byteKeys := make([]byteKey, 0, len(r.bytes))
+	for key := range r.bytes {
+		byteKeys = append(byteKeys, key)
+	}
+	slices.SortFunc(byteKeys, func(a, b byteKey) int {
+		if c := strings.Compare(a.host, b.host); c != 0 {
+			return c
+		}
+		return strings.Compare(a.location, b.location)
+	})
+	bytes := make([]byteCounts, len(byteKeys))
+	for i, key := range byteKeys {
+		bytes[i] = *r.bytes[key]
+	}
+	r.mu.Unlock()
+
+	var b strings.Builder
+	if r.enabled(Requests) {
+		header(&b, Requests, "counter", "Requests answered.", openMetrics)
+		for i, key := range keys {
+			fmt.Fprintf(&b, "%s_total{code=\"%d\",method=\"%s\"} %d\n", Requests, key.code, key.method, requests[i])
+		}
+	}
+	if r.enabled(RequestDuration) {
+		header(&b, RequestDuration, "histogram", "Time from reading a request to writing its response.", openMetrics)
+		var cumulative int64
+		for i, bound := range r.buckets {
+			cumulative += counts[i]
+			fmt.Fprintf(&b, "%s_bucket{le=\"%s\"} %d\n", RequestDuration, formatFloat(bound), cumulative)
+		}
+		cumulative += counts[len(r.buckets)]
+		fmt.Fprintf(&b, "%s_bucket{le=\"+Inf\"} %d\n", RequestDuration, cumulative)
+		fmt.Fprintf(&b, "%s_sum %s\n", RequestDuration, formatFloat(sum))
+		fmt.Fprintf(&b, "%s_count %d\n", RequestDuration, cumulative)
+	}
+	byteFamilies := []struct {
+		family, help string
+		value        func(c byteCounts) int64
+	}{
+		{RequestBytes, "Request bytes read.", func(c byteCounts) int64 { return c.request }},
+		{ResponseBytes, "Response bytes written.", func(c byteCounts) int64 { return c.response }},
+		{CompressionInput, "Response body bytes before compression.", func(c byteCounts) int64 { return c.compressionInput }},
+		{CompressionOutput, "Response body bytes after compression.", func(c byteCounts) int64 { return c.compressionOutput }},
+	}
+	for _, f := range byteFamilies {
+		if !r.enabled(f.family) {
+			continue
+		}
+		header(&b, f.family, "counter", f.help, openMetrics)
+		for i, key := range byteKeys {
+			fmt.Fprintf(&b, "%s_total{host=\"%s\",location=\"%s\"} %d\n", f.family, escapeLabel(key.host), escapeLabel(key.location), f.value(bytes[i]))
+		}
+	}
+	if r.enabled(InFlight) {
+		header(&b, InFlight, "gauge", "Requests being handled.", openMetrics)
+		fmt.Fprintf(&b, "%s %d\n", InFlight, inFlight)
+	}
+	if openMetrics {
+		b.WriteString("# EOF\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// enabled reports whether the family is written.
+func (r *Registry) enabled(family string) bool {
+	return !r.disabled[family]
+}
+
+// header writes the TYPE and HELP lines of a family. In the Prometheus
+// format, counters are described under their sample name, with _total.
+func header(b *strings.Builder, family, typ, help string, openMetrics bool) {
+	name := family
+	if !openMetrics && typ == "counter" {
+		name += "_total"
+	}
+	fmt.Fprintf(b, "# TYPE %s %s\n# HELP %s %s\n", name, typ, name, help)
+}
+
+// escapeLabel escapes a label value for the text formats.
+func escapeLabel(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// formatFloat formats v the shortest way that parses back to it, with a
+// decimal point so that bucket bounds read as floats.
+func formatFloat(v float64) string {
+	s := strconv.FormatFloat(v, 'f', -1, 64)
+	if !strings.Contains(s, ".") {
+		s += ".0"
+	}
+	return s
+}
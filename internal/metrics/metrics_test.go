@@ -0,0 +1,117 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   config.MetricsConfig
+		valid bool
+	}{
+		{"Default", config.DefaultConfig().Metrics, true},
+		{"No buckets", config.MetricsConfig{}, true},
+		{"Disabled family", config.MetricsConfig{Disable: []string{ResponseBytes}}, true},
+		{"Unknown family", config.MetricsConfig{Disable: []string{"volk_bytes"}}, false},
+		{"Descending buckets", config.MetricsConfig{Buckets: []float64{0.1, 0.05}}, false},
+		{"Duplicate bucket", config.MetricsConfig{Buckets: []float64{0.1, 0.1}}, false},
+		{"Zero bucket", config.MetricsConfig{Buckets: []float64{0, 1}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := New(test.cfg)
+			if test.valid && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if !test.valid && err == nil {
+				t.Errorf("Expected an error")
+			}
+		})
+	}
+}
+
+func TestWriteOpenMetrics(t *testing.T) {
+	registry, err := New(config.MetricsConfig{Buckets: []float64{0.1, 1}, Hosts: []string{"Example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry.Observe(Observation{Method: "GET", Code: 200, Duration: 50 * time.Millisecond, Host: "example.com:8080", Location: "/docs/",
+		RequestBytes: 80, ResponseBytes: 100, UncompressedBytes: 300, CompressedBytes: 60})
+	registry.Observe(Observation{Method: "GET", Code: 200, Duration: 100 * time.Millisecond, Host: "EXAMPLE.COM", Location: "/docs/", RequestBytes: 80, ResponseBytes: 100})
+	registry.Observe(Observation{Method: "GET", Code: 404, Duration: 2 * time.Second, Host: "random.example.org", RequestBytes: 70, ResponseBytes: 10})
+	registry.Observe(Observation{Method: "BREW", Code: 405, Duration: 500 * time.Millisecond, Host: "example.com"})
+
+	var b strings.Builder
+	if err := registry.Write(&b, true, 2); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# TYPE volk_requests counter
+# HELP volk_requests Requests answered.
+volk_requests_total{code="200",method="GET"} 2
+volk_requests_total{code="404",method="GET"} 1
+volk_requests_total{code="405",method="other"} 1
+# TYPE volk_request_duration_seconds histogram
+# HELP volk_request_duration_seconds Time from reading a request to writing its response.
+volk_request_duration_seconds_bucket{le="0.1"} 2
+volk_request_duration_seconds_bucket{le="1.0"} 3
+volk_request_duration_seconds_bucket{le="+Inf"} 4
+volk_request_duration_seconds_sum 2.65
+volk_request_duration_seconds_count 4
+# TYPE volk_request_bytes counter
+# HELP volk_request_bytes Request bytes read.
+volk_request_bytes_total{host="example.com",location="/docs/"} 160
+volk_request_bytes_total{host="example.com",location="none"} 0
+volk_request_bytes_total{host="other",location="none"} 70
+# TYPE volk_response_bytes counter
+# HELP volk_response_bytes Response bytes written.
+volk_response_bytes_total{host="example.com",location="/docs/"} 200
+volk_response_bytes_total{host="example.com",location="none"} 0
+volk_response_bytes_total{host="other",location="none"} 10
+# TYPE volk_compression_input_bytes counter
+# HELP volk_compression_input_bytes Response body bytes before compression.
+volk_compression_input_bytes_total{host="example.com",location="/docs/"} 300
+volk_compression_input_bytes_total{host="example.com",location="none"} 0
+volk_compression_input_bytes_total{host="other",location="none"} 0
+# TYPE volk_compression_output_bytes counter
+# HELP volk_compression_output_bytes Response body bytes after compression.
+volk_compression_output_bytes_total{host="example.com",location="/docs/"} 60
+volk_compression_output_bytes_total{host="example.com",location="none"} 0
+volk_compression_output_bytes_total{host="other",location="none"} 0
+# TYPE volk_requests_in_flight gauge
+# HELP volk_requests_in_flight Requests being handled.
+volk_requests_in_flight 2
+# EOF
+`
+	if b.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	registry, err := New(config.MetricsConfig{Disable: []string{RequestDuration, RequestBytes, CompressionInput, CompressionOutput, InFlight}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry.Observe(Observation{Method: "GET", Code: 200, Duration: time.Millisecond, Host: "example.com", ResponseBytes: 5})
+
+	var b strings.Builder
+	if err := registry.Write(&b, false, 0); err != nil {
+		t.Fatal(err)
+	}
+	expected := `# TYPE volk_requests_total counter
+# HELP volk_requests_total Requests answered.
+volk_requests_total{code="200",method="GET"} 1
+# TYPE volk_response_bytes_total counter
+# HELP volk_response_bytes_total Response bytes written.
+volk_response_bytes_total{host="other",location="none"} 5
+`
+	if b.String() != expected {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, b.String())
+	}
+}
@@ -0,0 +1,141 @@
+// Package mimetype resolves the content type of a file from its name.
+//
+// Names are looked up along a chain: the configured types, the built-in
+// types, the types of the system and finally the default type. Extensions are
+// compared case-insensitively, so files authored on Windows as PHOTO.JPG or
+// INDEX.HTML get the same type as their lower-case counterparts, and may be
+// composite, such as .tar.gz or .min.js: the longest extension of a name that
+// a link of the chain knows wins.
+package mimetype
+
+import (
+	"fmt"
+	"mime"
+	"path"
+	"strings"
+)
+
+// DefaultType is the type of files no link of the chain knows.
+const DefaultType = "application/octet-stream"
+
+// builtin holds the types of extensions that the system may not know, as
+// minimal container images ship without a mime.types file.
+var builtin = map[string]string{
+	".7z":          "application/x-7z-compressed",
+	".bz2":         "application/x-bzip2",
+	".css.map":     "application/json",
+	".csv":         "text/csv; charset=utf-8",
+	".gz":          "application/gzip",
+	".ico":         "image/vnd.microsoft.icon",
+	".js.map":      "application/json",
+	".map":         "application/json",
+	".md":          "text/markdown; charset=utf-8",
+	".min.css":     "text/css; charset=utf-8",
+	".min.js":      "text/javascript; charset=utf-8",
+	".mp3":         "audio/mpeg",
+	".mp4":         "video/mp4",
+	".ogg":         "audio/ogg",
+	".otf":         "font/otf",
+	".tar":         "application/x-tar",
+	".tar.bz2":     "application/x-bzip2",
+	".tar.gz":      "application/gzip",
+	".tar.xz":      "application/x-xz",
+	".tar.zst":     "application/zstd",
+	".tgz":         "application/gzip",
+	".ttf":         "font/ttf",
+	".txt":         "text/plain; charset=utf-8",
+	".webm":        "video/webm",
+	".webmanifest": "application/manifest+json",
+	".woff":        "font/woff",
+	".woff2":       "font/woff2",
+	".xz":          "application/x-xz",
+	".yaml":        "application/yaml",
+	".yml":         "application/yaml",
+	".zip":         "application/zip",
+	".zst":         "application/zstd",
+}
+
+// Resolver resolves content types by file name. The zero value uses the
+// built-in and system types and DefaultType.
+type Resolver struct {
+	types       map[string]string // Configured types by lower-case extension
+	defaultType string
+}
+
+// New creates a Resolver with the configured types, keyed by extension with
+// its leading dot, and the type of unknown files, DefaultType if empty. The
+// types are expected to have passed Check.
+func New(types map[string]string, defaultType string) *Resolver {
+	r := &Resolver{types: make(map[string]string, len(types)), defaultType: defaultType}
+	for ext, typ := range types {
+		r.types[strings.ToLower(ext)] = typ
+	}
+	return r
+}
+
+// Check returns an error for an extension without its leading dot, or a type
+// that is not a media type.
+func Check(types map[string]string) error {
+	for ext, typ := range types {
+		if !strings.HasPrefix(ext, ".") || len(ext) == 1 || strings.Contains(ext, "/") {
+			return fmt.Errorf("extension %q must start with a dot, as in .tar.gz", ext)
+		}
+		if _, _, err := mime.ParseMediaType(typ); err != nil {
+			return fmt.Errorf("invalid type %q for %s: %w", typ, ext, err)
+		}
+	}
+	return nil
+}
+
+// TypeByName returns the content type of the file name, which may be a path.
+func (r *Resolver) TypeByName(name string) string {
+	exts := extensions(path.Base(strings.ReplaceAll(name, "\\", "/")))
+	if len(exts) == 0 {
+		return r.fallback()
+	}
+	for _, ext := range exts {
+		if typ, ok := r.types[ext]; ok {
+			return typ
+		}
+	}
+	for _, ext := range exts {
+		if typ, ok := builtin[ext]; ok {
+			return typ
+		}
+	}
+	if typ := mime.TypeByExtension(exts[len(exts)-1]); typ != "" {
+		return typ
+	}
+	return r.fallback()
+}
+
+// fallback returns the type of files no link of the chain knows.
+func (r *Resolver) fallback() string {
+	if r.defaultType == "" {
+		return DefaultType
+	}
+	return r.defaultType
+}
+
+// extensions returns the extensions of the file name base in lower case,
+// longest first: archive.tar.gz has .tar.gz and .gz. A name that only starts
+// with a dot, like .htaccess, has no extension.
+func extensions(base string) []string {
+	base = strings.ToLower(base)
+	var exts []string
+	for i := 1; i < len(base); i++ {
+		if base[i] == '.' && i < len(base)-1 {
+			exts = append(exts, base[i:])
+		}
+	}
+	return exts
+}
+
+// defaultResolver resolves the names given to TypeByName.
+var defaultResolver = &Resolver{}
+
+// TypeByName returns the content type of the file name with the built-in and
+// system types, or DefaultType.
+func TypeByName(name string) string {
+	return defaultResolver.TypeByName(name)
+}
@@ -0,0 +1,81 @@
+package mimetype
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTypeByName(t *testing.T) {
+	resolver := New(map[string]string{
+		".TAR.GZ": "application/x-gtar",
+		".data":   "application/x-volk-data",
+	}, "text/plain")
+
+	tests := []struct {
+		name     string
+		expected string
+	}{
+		{"index.html", "text/html; charset=utf-8"},
+		{"INDEX.HTML", "text/html; charset=utf-8"},
+		{"Photo.JPG", "image/jpeg"},
+		{"C:\\Users\\me\\SCAN.PDF", "application/pdf"},
+		{"/srv/www/app.min.js", "text/javascript; charset=utf-8"},
+		{"jquery-3.7.1.MIN.JS", "text/javascript; charset=utf-8"},
+		{"release.tar.gz", "application/x-gtar"},
+		{"Release.Tar.Gz", "application/x-gtar"},
+		{"backup.gz", "application/gzip"},
+		{"release.tar.xz", "application/x-xz"},
+		{"export.DATA", "application/x-volk-data"},
+		{"fonts/Inter.WOFF2", "font/woff2"},
+		{"unknown.xyz123", "text/plain"},
+		{"Makefile", "text/plain"},
+		{".htaccess", "text/plain"},
+		{"trailing.", "text/plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := resolver.TypeByName(tt.name); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestDefaultTypeByName(t *testing.T) {
+	if result := TypeByName("ARCHIVE.ZIP"); result != "application/zip" {
+		t.Errorf("Expected application/zip, got %q", result)
+	}
+	if result := TypeByName("unknown.xyz123"); result != DefaultType {
+		t.Errorf("Expected %q, got %q", DefaultType, result)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name    string
+		types   map[string]string
+		wantErr string
+	}{
+		{"valid", map[string]string{".tar.gz": "application/gzip", ".txt": "text/plain; charset=utf-8"}, ""},
+		{"missing dot", map[string]string{"txt": "text/plain"}, "must start with a dot"},
+		{"only a dot", map[string]string{".": "text/plain"}, "must start with a dot"},
+		{"path", map[string]string{"./a.txt": "text/plain"}, "must start with a dot"},
+		{"invalid type", map[string]string{".txt": "text plain"}, "invalid type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Check(tt.types)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected an error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
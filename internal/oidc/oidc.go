@@ -0,0 +1,388 @@
+// Package oidc authenticates browser users with an OpenID Connect provider
+// using the authorization code flow.
+//
+// Unauthenticated users are sent to the provider's login page. When the
+// provider redirects back to the callback URL, the code is exchanged for an ID
+// token at the token endpoint and the user's identity is stored in a session
+// cookie signed with the configured cookie secret.
+package oidc
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+	"github.com/awaisamjad/volk/internal/session"
+)
+
+// Cookie names.
+const (
+	SessionCookie = "volk_session"
+	stateCookie   = "volk_oidc_state"
+)
+
+// stateLifetime is how long a user has to log in at the provider.
+const stateLifetime = 10 * time.Minute
+
+var (
+	ErrInvalidConfig = errors.New("invalid oidc configuration")
+	ErrInvalidState  = errors.New("invalid or expired login state")
+	ErrInvalidToken  = errors.New("invalid ID token")
+)
+
+// Identity is an authenticated user.
+type Identity struct {
+	Subject string `json:"sub"`
+	Email   string `json:"email,omitempty"`
+	Name    string `json:"name,omitempty"`
+	Expires int64  `json:"exp"` // Unix time the session expires
+}
+
+// endpoints are the provider URLs from its discovery document.
+type endpoints struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+}
+
+// Authenticator runs the login flow and checks sessions.
+type Authenticator struct {
+	config       config.OIDCConfig
+	callbackPath string
+	secure       bool
+	sessions     *session.Signer // Signs the session cookies
+	states       *session.Signer // Signs the login state cookies
+
+	// Client is used to talk to the provider.
+	Client *http.Client
+
+	mu        sync.Mutex
+	endpoints *endpoints
+
+	now func() time.Time
+}
+
+// New creates an Authenticator from the configuration.
+func New(cfg config.OIDCConfig) (*Authenticator, error) {
+	if cfg.Issuer == "" || cfg.ClientID == "" || cfg.RedirectURL == "" || cfg.CookieSecret == "" {
+		return nil, fmt.Errorf("%w: issuer, client_id, redirect_url and cookie_secret are required", ErrInvalidConfig)
+	}
+	redirect, err := url.Parse(cfg.RedirectURL)
+	if err != nil || redirect.Host == "" || redirect.Path == "" {
+		return nil, fmt.Errorf("%w: redirect_url must be an absolute URL with a path", ErrInvalidConfig)
+	}
+
+	signer := session.NewSigner(cfg.CookieSecret)
+	return &Authenticator{
+		config:       cfg,
+		callbackPath: redirect.Path,
+		secure:       redirect.Scheme == "https",
+		sessions:     signer.Derive("oidc session"),
+		states:       signer.Derive("oidc state"),
+		Client:       &http.Client{Timeout: 10 * time.Second},
+		now:          time.Now,
+	}, nil
+}
+
+// CallbackPath returns the path of the redirect URL the provider sends users back to.
+func (a *Authenticator) CallbackPath() string {
+	return a.callbackPath
+}
+
+// Session returns the identity in a valid session cookie of the Cookie header.
+func (a *Authenticator) Session(cookieHeader string) (Identity, bool) {
+	value, ok := session.Cookie(cookieHeader, SessionCookie)
+	if !ok {
+		return Identity{}, false
+	}
+	payload, ok := a.sessions.Verify(value)
+	if !ok {
+		return Identity{}, false
+	}
+
+	var identity Identity
+	if err := json.Unmarshal(payload, &identity); err != nil {
+		return Identity{}, false
+	}
+	if identity.Subject == "" || a.now().Unix() >= identity.Expires {
+		return Identity{}, false
+	}
+	return identity, true
+}
+
+// Login starts the flow for a user who wants to see returnTo. It returns the
+// provider URL to redirect the user to and a Set-Cookie value for the login state.
+func (a *Authenticator) Login(returnTo string) (string, string, error) {
+	ep, err := a.discover()
+	if err != nil {
+		return "", "", err
+	}
+
+	state := randomString()
+	nonce := randomString()
+	payload, _ := json.Marshal(loginState{
+		State:    state,
+		Nonce:    nonce,
+		ReturnTo: returnTo,
+		Expires:  a.now().Add(stateLifetime).Unix(),
+	})
+
+	query := url.Values{
+		"response_type": {"code"},
+		"client_id":     {a.config.ClientID},
+		"redirect_uri":  {a.config.RedirectURL},
+		"scope":         {strings.Join(a.scopes(), " ")},
+		"state":         {state},
+		"nonce":         {nonce},
+	}
+	location := ep.AuthorizationEndpoint
+	if strings.Contains(location, "?") {
+		location += "&" + query.Encode()
+	} else {
+		location += "?" + query.Encode()
+	}
+
+	cookie := session.SetCookie(stateCookie, a.states.Sign(payload), session.CookieOptions{
+		Path:   a.callbackPath,
+		MaxAge: int(stateLifetime.Seconds()),
+		Secure: a.secure,
+	})
+	return location, cookie, nil
+}
+
+// loginState is stored in the state cookie while the user logs in at the provider.
+type loginState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	ReturnTo string `json:"return_to"`
+	Expires  int64  `json:"exp"`
+}
+
+// Callback completes the flow with the query string of the request to the
+// callback path. It returns the user's identity, the path to send the user
+// back to and the Set-Cookie values for the session.
+func (a *Authenticator) Callback(rawQuery, cookieHeader string) (Identity, string, []string, error) {
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return Identity{}, "", nil, ErrInvalidState
+	}
+	if errCode := query.Get("error"); errCode != "" {
+		return Identity{}, "", nil, fmt.Errorf("provider returned error: %s", errCode)
+	}
+
+	value, ok := session.Cookie(cookieHeader, stateCookie)
+	if !ok {
+		return Identity{}, "", nil, ErrInvalidState
+	}
+	payload, ok := a.states.Verify(value)
+	if !ok {
+		return Identity{}, "", nil, ErrInvalidState
+	}
+	var state loginState
+	if err := json.Unmarshal(payload, &state); err != nil || a.now().Unix() >= state.Expires {
+		return Identity{}, "", nil, ErrInvalidState
+	}
+	if !hmac.Equal([]byte(query.Get("state")), []byte(state.State)) || query.Get("code") == "" {
+		return Identity{}, "", nil, ErrInvalidState
+	}
+
+	claims, err := a.exchange(query.Get("code"))
+	if err != nil {
+		return Identity{}, "", nil, err
+	}
+	if claims.Nonce != state.Nonce {
+		return Identity{}, "", nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidToken)
+	}
+
+	identity := Identity{
+		Subject: claims.Subject,
+		Email:   claims.Email,
+		Name:    claims.Name,
+		Expires: a.now().Add(a.sessionLifetime()).Unix(),
+	}
+	payload, _ = json.Marshal(identity)
+
+	cookies := []string{
+		session.SetCookie(SessionCookie, a.sessions.Sign(payload), session.CookieOptions{
+			MaxAge: int(a.sessionLifetime().Seconds()),
+			Secure: a.secure,
+		}),
+		session.SetCookie(stateCookie, "", session.CookieOptions{Path: a.callbackPath, Secure: a.secure}),
+	}
+	return identity, safeReturnTo(state.ReturnTo), cookies, nil
+}
+
+// claims are the ID token claims volk uses.
+type claims struct {
+	Issuer   string   `json:"iss"`
+	Subject  string   `json:"sub"`
+	Audience audience `json:"aud"`
+	Expires  int64    `json:"exp"`
+	Nonce    string   `json:"nonce"`
+	Email    string   `json:"email"`
+	Name     string   `json:"name"`
+}
+
+// audience is the aud claim, which is either a string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var multiple []string
+	if err := json.Unmarshal(data, &multiple); err != nil {
+		return err
+	}
+	*a = multiple
+	return nil
+}
+
+// exchange redeems the authorization code at the token endpoint and returns
+// the claims of the ID token.
+//
+// The token is received directly from the provider over TLS, which OpenID
+// Connect Core section 3.1.3.7 allows in place of checking its signature; the
+// issuer, audience, expiry and nonce are still validated.
+func (a *Authenticator) exchange(code string) (claims, error) {
+	ep, err := a.discover()
+	if err != nil {
+		return claims{}, err
+	}
+
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {a.config.RedirectURL},
+		"client_id":     {a.config.ClientID},
+		"client_secret": {a.config.ClientSecret},
+	}
+	resp, err := a.Client.PostForm(ep.TokenEndpoint, form)
+	if err != nil {
+		return claims{}, fmt.Errorf("error exchanging code: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return claims{}, fmt.Errorf("error exchanging code: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return claims{}, fmt.Errorf("error exchanging code: token endpoint returned %s", resp.Status)
+	}
+
+	var token struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &token); err != nil || token.IDToken == "" {
+		return claims{}, fmt.Errorf("%w: missing id_token in token response", ErrInvalidToken)
+	}
+
+	parts := strings.Split(token.IDToken, ".")
+	if len(parts) != 3 {
+		return claims{}, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return claims{}, fmt.Errorf("%w: malformed payload", ErrInvalidToken)
+	}
+	var c claims
+	if err := json.Unmarshal(payload, &c); err != nil {
+		return claims{}, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+
+	switch {
+	case c.Issuer != ep.Issuer:
+		return claims{}, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidToken, c.Issuer)
+	case !slices.Contains(c.Audience, a.config.ClientID):
+		return claims{}, fmt.Errorf("%w: token not issued for this client", ErrInvalidToken)
+	case a.now().Unix() >= c.Expires:
+		return claims{}, fmt.Errorf("%w: token expired", ErrInvalidToken)
+	case c.Subject == "":
+		return claims{}, fmt.Errorf("%w: missing subject", ErrInvalidToken)
+	}
+	return c, nil
+}
+
+// discover fetches and caches the provider's discovery document. The
+// document is fetched without holding the lock, so a slow provider only
+// holds up the requests that need it.
+func (a *Authenticator) discover() (*endpoints, error) {
+	a.mu.Lock()
+	cached := a.endpoints
+	a.mu.Unlock()
+	if cached != nil {
+		return cached, nil
+	}
+
+	discoveryURL := strings.TrimSuffix(a.config.Issuer, "/") + "/.well-known/openid-configuration"
+	resp, err := a.Client.Get(discoveryURL)
+	if err != nil {
+		return nil, fmt.Errorf("error fetching %s: %w", discoveryURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error fetching %s: %s", discoveryURL, resp.Status)
+	}
+
+	var ep endpoints
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&ep); err != nil {
+		return nil, fmt.Errorf("error decoding %s: %w", discoveryURL, err)
+	}
+	if ep.AuthorizationEndpoint == "" || ep.TokenEndpoint == "" {
+		return nil, fmt.Errorf("error decoding %s: missing endpoints", discoveryURL)
+	}
+	if ep.Issuer != a.config.Issuer {
+		return nil, fmt.Errorf("discovery document issuer %q does not match %q", ep.Issuer, a.config.Issuer)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.endpoints == nil {
+		a.endpoints = &ep
+	}
+	return a.endpoints, nil
+}
+
+func (a *Authenticator) scopes() []string {
+	if len(a.config.Scopes) > 0 {
+		return a.config.Scopes
+	}
+	return []string{"openid", "email", "profile"}
+}
+
+func (a *Authenticator) sessionLifetime() time.Duration {
+	if a.config.SessionLifetime > 0 {
+		return time.Duration(a.config.SessionLifetime) * time.Second
+	}
+	return 8 * time.Hour
+}
+
+// safeReturnTo only allows local paths, so the login flow cannot be used to
+// redirect users to other sites.
+func safeReturnTo(returnTo string) string {
+	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") || strings.HasPrefix(returnTo, "/\\") {
+		return "/"
+	}
+	return returnTo
+}
+
+// randomString returns 128 random bits as hex.
+func randomString() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,194 @@
+package oidc
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// fakeProvider is an OIDC provider that issues an ID token with the given claims for the code "good-code".
+type fakeProvider struct {
+	*httptest.Server
+	claims map[string]any
+}
+
+func newFakeProvider(t *testing.T) *fakeProvider {
+	t.Helper()
+	p := &fakeProvider{claims: map[string]any{}}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.URL,
+			"authorization_endpoint": p.URL + "/authorize",
+			"token_endpoint":         p.URL + "/token",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("code") != "good-code" || r.PostFormValue("client_secret") != "secret" {
+			http.Error(w, `{"error":"invalid_grant"}`, http.StatusBadRequest)
+			return
+		}
+		payload, _ := json.Marshal(p.claims)
+		token := "eyJhbGciOiJSUzI1NiJ9." + base64.RawURLEncoding.EncodeToString(payload) + ".sig"
+		json.NewEncoder(w).Encode(map[string]string{"id_token": token})
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+func newTestAuthenticator(t *testing.T, issuer string) *Authenticator {
+	t.Helper()
+	a, err := New(config.OIDCConfig{
+		Issuer:       issuer,
+		ClientID:     "volk",
+		ClientSecret: "secret",
+		RedirectURL:  "https://example.com/_oidc/callback",
+		CookieSecret: "cookie-secret",
+	})
+	if err != nil {
+		t.Fatalf("New returned an error: %v", err)
+	}
+	return a
+}
+
+// cookieValue extracts name=value from a Set-Cookie value.
+func cookieValue(setCookie string) string {
+	pair, _, _ := strings.Cut(setCookie, ";")
+	return pair
+}
+
+func TestLoginFlow(t *testing.T) {
+	provider := newFakeProvider(t)
+	a := newTestAuthenticator(t, provider.URL)
+
+	location, stateCookieValue, err := a.Login("/members/page?x=1")
+	if err != nil {
+		t.Fatalf("Login returned an error: %v", err)
+	}
+	authURL, _ := url.Parse(location)
+	query := authURL.Query()
+	if authURL.Path != "/authorize" || query.Get("client_id") != "volk" || query.Get("redirect_uri") != "https://example.com/_oidc/callback" {
+		t.Fatalf("Unexpected authorization URL %s", location)
+	}
+	if !strings.Contains(stateCookieValue, "Path=/_oidc/callback") || !strings.Contains(stateCookieValue, "Secure") {
+		t.Errorf("Expected a secure state cookie for the callback path, got %s", stateCookieValue)
+	}
+
+	provider.claims = map[string]any{
+		"iss":   provider.URL,
+		"aud":   "volk",
+		"sub":   "user-1",
+		"email": "user@example.com",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": query.Get("nonce"),
+	}
+
+	callbackQuery := "code=good-code&state=" + query.Get("state")
+	identity, returnTo, cookies, err := a.Callback(callbackQuery, cookieValue(stateCookieValue))
+	if err != nil {
+		t.Fatalf("Callback returned an error: %v", err)
+	}
+	if identity.Subject != "user-1" || identity.Email != "user@example.com" {
+		t.Errorf("Unexpected identity %+v", identity)
+	}
+	if returnTo != "/members/page?x=1" {
+		t.Errorf("Expected to return to /members/page?x=1, got %s", returnTo)
+	}
+
+	session, ok := a.Session("other=1; " + cookieValue(cookies[0]))
+	if !ok || session.Subject != "user-1" {
+		t.Errorf("Expected the session cookie to be valid, got %+v, %v", session, ok)
+	}
+
+	a.now = func() time.Time { return time.Now().Add(9 * time.Hour) }
+	if _, ok := a.Session(cookieValue(cookies[0])); ok {
+		t.Errorf("Expected the session to expire")
+	}
+}
+
+func TestSessionRejectsOtherCookies(t *testing.T) {
+	provider := newFakeProvider(t)
+	a := newTestAuthenticator(t, provider.URL)
+
+	_, stateCookieValue, err := a.Login("/")
+	if err != nil {
+		t.Fatalf("Login returned an error: %v", err)
+	}
+	_, state, _ := strings.Cut(cookieValue(stateCookieValue), "=")
+	if identity, ok := a.Session(SessionCookie + "=" + state); ok {
+		t.Errorf("Expected the login state cookie not to be accepted as a session, got %+v", identity)
+	}
+
+	payload, _ := json.Marshal(Identity{Expires: time.Now().Add(time.Hour).Unix()})
+	if identity, ok := a.Session(SessionCookie + "=" + a.sessions.Sign(payload)); ok {
+		t.Errorf("Expected a session without a subject to be rejected, got %+v", identity)
+	}
+}
+
+func TestCallbackRejects(t *testing.T) {
+	provider := newFakeProvider(t)
+	a := newTestAuthenticator(t, provider.URL)
+
+	location, stateCookieValue, err := a.Login("/")
+	if err != nil {
+		t.Fatalf("Login returned an error: %v", err)
+	}
+	authURL, _ := url.Parse(location)
+	state := authURL.Query().Get("state")
+	nonce := authURL.Query().Get("nonce")
+	validClaims := func() map[string]any {
+		return map[string]any{"iss": provider.URL, "aud": []string{"volk"}, "sub": "u", "exp": time.Now().Add(time.Hour).Unix(), "nonce": nonce}
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		cookie   string
+		modify   func(claims map[string]any)
+		expected error
+	}{
+		{"missing state cookie", "code=good-code&state=" + state, "", nil, ErrInvalidState},
+		{"wrong state", "code=good-code&state=other", cookieValue(stateCookieValue), nil, ErrInvalidState},
+		{"tampered state cookie", "code=good-code&state=" + state, cookieValue(stateCookieValue) + "x", nil, ErrInvalidState},
+		{"wrong audience", "code=good-code&state=" + state, cookieValue(stateCookieValue), func(c map[string]any) { c["aud"] = "other" }, ErrInvalidToken},
+		{"wrong issuer", "code=good-code&state=" + state, cookieValue(stateCookieValue), func(c map[string]any) { c["iss"] = "https://evil.example" }, ErrInvalidToken},
+		{"expired token", "code=good-code&state=" + state, cookieValue(stateCookieValue), func(c map[string]any) { c["exp"] = time.Now().Add(-time.Minute).Unix() }, ErrInvalidToken},
+		{"wrong nonce", "code=good-code&state=" + state, cookieValue(stateCookieValue), func(c map[string]any) { c["nonce"] = "other" }, ErrInvalidToken},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider.claims = validClaims()
+			if tt.modify != nil {
+				tt.modify(provider.claims)
+			}
+			_, _, _, err := a.Callback(tt.query, tt.cookie)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Expected error %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestSafeReturnTo(t *testing.T) {
+	tests := map[string]string{
+		"/page":                "/page",
+		"//evil.example/":      "/",
+		"/\\evil.example":      "/",
+		"https://evil.example": "/",
+	}
+	for input, expected := range tests {
+		if got := safeReturnTo(input); got != expected {
+			t.Errorf("safeReturnTo(%q) = %q, want %q", input, got, expected)
+		}
+	}
+}
@@ -0,0 +1,441 @@
+// Package openapi checks requests against an OpenAPI 3 description of an
+// API: their path and method, the required parameters and the types of the
+// parameters' values, and the content type of their body.
+//
+// It does not validate bodies against schemas, and parameters in cookies
+// are not checked.
+package openapi
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrInvalidSpec is returned for documents that are not OpenAPI 3 descriptions.
+var ErrInvalidSpec = errors.New("invalid OpenAPI description")
+
+// methods are the methods an OpenAPI path item can describe, by their key in the document.
+var methods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// ignoredHeaders are header parameters OpenAPI says to ignore, since the
+// content type, accepted types and credentials are described elsewhere.
+var ignoredHeaders = []string{"accept", "content-type", "authorization"}
+
+// Spec is a loaded OpenAPI description.
+type Spec struct {
+	// Raw is the document as it was loaded, for serving it.
+	Raw []byte
+
+	routes []route
+}
+
+// Request is what Validate needs to know of a request.
+type Request struct {
+	Method      string
+	Path        string     // Path relative to the base path of the API, starting with /
+	Query       url.Values // Parameters of the query string
+	Header      func(name string) (string, bool)
+	ContentType string // Content-Type of the body, if it has one
+	HasBody     bool
+}
+
+// Violation is a way a request does not match its operation.
+type Violation struct {
+	In      string `json:"in"`             // path, query, header or body
+	Name    string `json:"name,omitempty"` // Parameter name
+	Message string `json:"message"`
+}
+
+// Result is the outcome of validating a request.
+type Result struct {
+	// Matched is set if the path of the request is a path of the description.
+	Matched bool
+	// Allow lists the methods of the path if the request's method is not one of them.
+	Allow []string
+	// Violations are the ways the request does not match its operation.
+	Violations []Violation
+}
+
+// route is a path of the description.
+type route struct {
+	template   string
+	pattern    *regexp.Regexp
+	names      []string              // Names of the path parameters, in order
+	operations map[string]*operation // By upper-case method
+}
+
+// operation is an operation of the description with its references resolved.
+type operation struct {
+	parameters []parameter
+	body       *requestBody
+}
+
+// document is the part of an OpenAPI document the checks use.
+type document struct {
+	OpenAPI    string              `yaml:"openapi"`
+	Paths      map[string]pathItem `yaml:"paths"`
+	Components struct {
+		Parameters    map[string]parameter   `yaml:"parameters"`
+		RequestBodies map[string]requestBody `yaml:"requestBodies"`
+	} `yaml:"components"`
+}
+
+type pathItem struct {
+	Parameters []parameter   `yaml:"parameters"`
+	Get        *rawOperation `yaml:"get"`
+	Put        *rawOperation `yaml:"put"`
+	Post       *rawOperation `yaml:"post"`
+	Delete     *rawOperation `yaml:"delete"`
+	Options    *rawOperation `yaml:"options"`
+	Head       *rawOperation `yaml:"head"`
+	Patch      *rawOperation `yaml:"patch"`
+	Trace      *rawOperation `yaml:"trace"`
+}
+
+type rawOperation struct {
+	Parameters  []parameter  `yaml:"parameters"`
+	RequestBody *requestBody `yaml:"requestBody"`
+}
+
+type parameter struct {
+	Ref      string  `yaml:"$ref"`
+	Name     string  `yaml:"name"`
+	In       string  `yaml:"in"`
+	Required bool    `yaml:"required"`
+	Schema   *schema `yaml:"schema"`
+}
+
+type requestBody struct {
+	Ref      string         `yaml:"$ref"`
+	Required bool           `yaml:"required"`
+	Content  map[string]any `yaml:"content"`
+}
+
+type schema struct {
+	Type  schemaType `yaml:"type"`
+	Enum  []any      `yaml:"enum"`
+	Items *schema    `yaml:"items"`
+}
+
+// schemaType is the type of a schema: one type name in OpenAPI 3.0, one or
+// a list of them in 3.1.
+type schemaType []string
+
+func (t *schemaType) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*t = schemaType{node.Value}
+		return nil
+	}
+	var types []string
+	if err := node.Decode(&types); err != nil {
+		return err
+	}
+	*t = types
+	return nil
+}
+
+// Load reads an OpenAPI description from a JSON or YAML file.
+func Load(path string) (*Spec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading OpenAPI description: %w", err)
+	}
+	spec, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return spec, nil
+}
+
+// Parse parses an OpenAPI description in JSON or YAML.
+func Parse(data []byte) (*Spec, error) {
+	var doc document
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSpec, err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("%w: openapi version %q, only 3.x is supported", ErrInvalidSpec, doc.OpenAPI)
+	}
+
+	spec := &Spec{Raw: data}
+	for template, item := range doc.Paths {
+		r, err := newRoute(template, item, &doc)
+		if err != nil {
+			return nil, err
+		}
+		spec.routes = append(spec.routes, r)
+	}
+	// Paths without parameters win over templated ones that match the same path.
+	sort.Slice(spec.routes, func(i, j int) bool {
+		a, b := spec.routes[i], spec.routes[j]
+		if len(a.names) != len(b.names) {
+			return len(a.names) < len(b.names)
+		}
+		return a.template < b.template
+	})
+	return spec, nil
+}
+
+// newRoute compiles a path of the description.
+func newRoute(template string, item pathItem, doc *document) (route, error) {
+	if !strings.HasPrefix(template, "/") {
+		return route{}, fmt.Errorf("%w: path %q does not start with /", ErrInvalidSpec, template)
+	}
+	r := route{template: template, operations: make(map[string]*operation)}
+
+	var pattern strings.Builder
+	pattern.WriteString("^")
+	rest := template
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start == -1 {
+			pattern.WriteString(regexp.QuoteMeta(rest))
+			break
+		}
+		end := strings.IndexByte(rest[start:], '}')
+		if end == -1 {
+			return route{}, fmt.Errorf("%w: path %q has an unclosed parameter", ErrInvalidSpec, template)
+		}
+		pattern.WriteString(regexp.QuoteMeta(rest[:start]))
+		pattern.WriteString("([^/]+)")
+		r.names = append(r.names, rest[start+1:start+end])
+		rest = rest[start+end+1:]
+	}
+	pattern.WriteString("$")
+	r.pattern = regexp.MustCompile(pattern.String())
+
+	raw := []*rawOperation{item.Get, item.Put, item.Post, item.Delete, item.Options, item.Head, item.Patch, item.Trace}
+	for i, method := range methods {
+		if raw[i] == nil {
+			continue
+		}
+		op, err := newOperation(item.Parameters, raw[i], doc)
+		if err != nil {
+			return route{}, fmt.Errorf("%s %s: %w", strings.ToUpper(method), template, err)
+		}
+		r.operations[strings.ToUpper(method)] = op
+	}
+	return r, nil
+}
+
+// newOperation resolves the parameters and the request body of an
+// operation. Its own parameters replace those of the path with the same
+// name and location.
+func newOperation(pathParams []parameter, raw *rawOperation, doc *document) (*operation, error) {
+	op := &operation{}
+	for _, params := range [][]parameter{pathParams, raw.Parameters} {
+		for _, p := range params {
+			p, err := resolveParameter(p, doc)
+			if err != nil {
+				return nil, err
+			}
+			op.parameters = slices.DeleteFunc(op.parameters, func(q parameter) bool { return q.Name == p.Name && q.In == p.In })
+			op.parameters = append(op.parameters, p)
+		}
+	}
+	if raw.RequestBody != nil {
+		body := *raw.RequestBody
+		if body.Ref != "" {
+			name, ok := strings.CutPrefix(body.Ref, "#/components/requestBodies/")
+			resolved, found := doc.Components.RequestBodies[name]
+			if !ok || !found {
+				return nil, fmt.Errorf("%w: unresolved reference %q", ErrInvalidSpec, body.Ref)
+			}
+			body = resolved
+		}
+		op.body = &body
+	}
+	return op, nil
+}
+
+// resolveParameter returns the parameter a $ref points to, or p itself.
+func resolveParameter(p parameter, doc *document) (parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	name, ok := strings.CutPrefix(p.Ref, "#/components/parameters/")
+	resolved, found := doc.Components.Parameters[name]
+	if !ok || !found {
+		return parameter{}, fmt.Errorf("%w: unresolved reference %q", ErrInvalidSpec, p.Ref)
+	}
+	return resolved, nil
+}
+
+// Validate checks a request against the operation of its path and method.
+// Requests to paths the description does not have are not Matched.
+func (s *Spec) Validate(req Request) Result {
+	for _, r := range s.routes {
+		match := r.pattern.FindStringSubmatch(req.Path)
+		if match == nil {
+			continue
+		}
+		op, ok := r.operations[req.Method]
+		if !ok && req.Method == "HEAD" {
+			op, ok = r.operations["GET"]
+		}
+		if !ok {
+			return Result{Matched: true, Allow: r.methods()}
+		}
+		pathValues := make(map[string]string, len(r.names))
+		for i, name := range r.names {
+			pathValues[name] = match[i+1]
+		}
+		return Result{Matched: true, Violations: op.validate(req, pathValues)}
+	}
+	return Result{}
+}
+
+// methods returns the methods of the route, with HEAD where there is GET.
+func (r route) methods() []string {
+	var allow []string
+	for _, method := range methods {
+		method = strings.ToUpper(method)
+		if _, ok := r.operations[method]; ok {
+			allow = append(allow, method)
+		} else if method == "HEAD" && r.operations["GET"] != nil {
+			allow = append(allow, method)
+		}
+	}
+	return allow
+}
+
+// validate checks the parameters and the body of a request.
+func (op *operation) validate(req Request, pathValues map[string]string) []Violation {
+	var violations []Violation
+	for _, p := range op.parameters {
+		var values []string
+		switch p.In {
+		case "path":
+			if value, ok := pathValues[p.Name]; ok {
+				values = []string{value}
+			}
+		case "query":
+			values = req.Query[p.Name]
+		case "header":
+			if slices.Contains(ignoredHeaders, strings.ToLower(p.Name)) {
+				continue
+			}
+			if req.Header != nil {
+				if value, ok := req.Header(p.Name); ok {
+					values = []string{value}
+				}
+			}
+		default:
+			continue
+		}
+
+		if len(values) == 0 {
+			if p.Required || p.In == "path" {
+				violations = append(violations, Violation{In: p.In, Name: p.Name, Message: "is required"})
+			}
+			continue
+		}
+		if p.Schema == nil {
+			continue
+		}
+		s := p.Schema
+		if s.Type.is("array") {
+			s = s.Items
+		} else if len(values) > 1 {
+			violations = append(violations, Violation{In: p.In, Name: p.Name, Message: "must be given once"})
+			continue
+		}
+		for _, value := range values {
+			if message := s.check(value); message != "" {
+				violations = append(violations, Violation{In: p.In, Name: p.Name, Message: message})
+				break
+			}
+		}
+	}
+
+	if op.body != nil {
+		if !req.HasBody {
+			if op.body.Required {
+				violations = append(violations, Violation{In: "body", Message: "is required"})
+			}
+		} else if len(op.body.Content) > 0 && !op.body.accepts(req.ContentType) {
+			types := make([]string, 0, len(op.body.Content))
+			for mediaType := range op.body.Content {
+				types = append(types, mediaType)
+			}
+			sort.Strings(types)
+			violations = append(violations, Violation{In: "body", Message: fmt.Sprintf("content type %q is not one of %s", req.ContentType, strings.Join(types, ", "))})
+		}
+	}
+	return violations
+}
+
+// accepts reports whether a body of the content type matches one of the
+// media types of the request body, which may be ranges like image/*.
+func (b *requestBody) accepts(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for accepted := range b.Content {
+		accepted = strings.ToLower(accepted)
+		if accepted == "*/*" || accepted == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(accepted, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// is reports whether t is or includes the type name.
+func (t schemaType) is(name string) bool {
+	return slices.Contains(t, name)
+}
+
+// check returns what is wrong with a parameter value for the schema, or ""
+// if it matches. Schemas of other types than numbers and booleans accept
+// any value.
+func (s *schema) check(value string) string {
+	if s == nil {
+		return ""
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(v any) bool { return fmt.Sprint(v) == value }) {
+		allowed := make([]string, len(s.Enum))
+		for i, v := range s.Enum {
+			allowed[i] = fmt.Sprint(v)
+		}
+		return "must be one of " + strings.Join(allowed, ", ")
+	}
+	if len(s.Type) == 0 || s.Type.is("string") {
+		return ""
+	}
+	if s.Type.is("number") {
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return ""
+		}
+	}
+	if s.Type.is("integer") {
+		if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return ""
+		}
+	}
+	if s.Type.is("boolean") && (value == "true" || value == "false") {
+		return ""
+	}
+	switch {
+	case s.Type.is("integer") && !s.Type.is("number"):
+		return "must be an integer"
+	case s.Type.is("number"):
+		return "must be a number"
+	case s.Type.is("boolean"):
+		return "must be true or false"
+	}
+	return ""
+}
@@ -0,0 +1,105 @@
+package openapi
+
+import (
+	"errors"
+	"net/url"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	spec, err := Load(filepath.Join("testdata", "items.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		req        Request
+		matched    bool
+		allow      []string
+		violations []Violation
+	}{
+		{"other path", Request{Method: "GET", Path: "/other"}, false, nil, nil},
+		{"valid query", Request{Method: "GET", Path: "/items", Query: url.Values{"limit": {"10"}, "sort": {"date"}, "tag": {"1", "2"}}}, true, nil, nil},
+		{"HEAD of a GET operation", Request{Method: "HEAD", Path: "/items"}, true, nil, nil},
+		{"unknown method", Request{Method: "DELETE", Path: "/items"}, true, []string{"GET", "POST", "HEAD"}, nil},
+		{"not an integer", Request{Method: "GET", Path: "/items", Query: url.Values{"limit": {"ten"}}}, true, nil, []Violation{{In: "query", Name: "limit", Message: "must be an integer"}}},
+		{"not in the enum", Request{Method: "GET", Path: "/items", Query: url.Values{"sort": {"size"}}}, true, nil, []Violation{{In: "query", Name: "sort", Message: "must be one of name, date"}}},
+		{"array item", Request{Method: "GET", Path: "/items", Query: url.Values{"tag": {"1", "x"}}}, true, nil, []Violation{{In: "query", Name: "tag", Message: "must be an integer"}}},
+		{"repeated value", Request{Method: "GET", Path: "/items", Query: url.Values{"limit": {"1", "2"}}}, true, nil, []Violation{{In: "query", Name: "limit", Message: "must be given once"}}},
+		{"path parameter", Request{Method: "GET", Path: "/items/42", Query: url.Values{"verbose": {"true"}}}, true, nil, nil},
+		{"invalid path parameter", Request{Method: "GET", Path: "/items/abc", Query: url.Values{"verbose": {"yes"}}}, true, nil, []Violation{
+			{In: "path", Name: "id", Message: "must be an integer"},
+			{In: "query", Name: "verbose", Message: "must be true or false"},
+		}},
+		{"concrete path wins", Request{Method: "GET", Path: "/items/new"}, true, nil, nil},
+		{"valid body", Request{
+			Method: "POST", Path: "/items", HasBody: true, ContentType: "application/json; charset=utf-8",
+			Header: func(name string) (string, bool) { return "acme", name == "X-Tenant" },
+		}, true, nil, nil},
+		{"missing header and body", Request{Method: "POST", Path: "/items"}, true, nil, []Violation{
+			{In: "header", Name: "X-Tenant", Message: "is required"},
+			{In: "body", Message: "is required"},
+		}},
+		{"wrong content type", Request{
+			Method: "POST", Path: "/items", HasBody: true, ContentType: "text/plain",
+			Header: func(name string) (string, bool) { return "acme", true },
+		}, true, nil, []Violation{{In: "body", Message: `content type "text/plain" is not one of application/json`}}},
+		{"media type range", Request{Method: "PUT", Path: "/items/1", HasBody: true, ContentType: "image/png"}, true, nil, nil},
+		{"outside the media type range", Request{Method: "PUT", Path: "/items/1", HasBody: true, ContentType: "video/mp4"}, true, nil, []Violation{{In: "body", Message: `content type "video/mp4" is not one of image/*`}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := spec.Validate(tt.req)
+			if result.Matched != tt.matched {
+				t.Errorf("Expected matched %v, got %v", tt.matched, result.Matched)
+			}
+			if !reflect.DeepEqual(result.Allow, tt.allow) {
+				t.Errorf("Expected Allow %v, got %v", tt.allow, result.Allow)
+			}
+			if !reflect.DeepEqual(result.Violations, tt.violations) {
+				t.Errorf("Expected violations %v, got %v", tt.violations, result.Violations)
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{"not YAML", "paths: [unclosed"},
+		{"Swagger 2", `{"swagger": "2.0", "paths": {}}`},
+		{"relative path", `{"openapi": "3.1.0", "paths": {"items": {"get": {}}}}`},
+		{"unclosed parameter", `{"openapi": "3.1.0", "paths": {"/items/{id": {"get": {}}}}`},
+		{"unresolved reference", `{"openapi": "3.1.0", "paths": {"/items": {"get": {"parameters": [{"$ref": "#/components/parameters/Missing"}]}}}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse([]byte(tt.doc)); !errors.Is(err, ErrInvalidSpec) {
+				t.Errorf("Expected error %v, got %v", ErrInvalidSpec, err)
+			}
+		})
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	spec, err := Parse([]byte(`{"openapi": "3.1.0", "paths": {"/n": {"get": {"parameters": [{"name": "n", "in": "query", "required": true, "schema": {"type": ["number", "null"]}}]}}}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := spec.Validate(Request{Method: "GET", Path: "/n", Query: url.Values{"n": {"1.5"}}})
+	if !result.Matched || len(result.Violations) != 0 {
+		t.Errorf("Expected a valid request, got %+v", result)
+	}
+	result = spec.Validate(Request{Method: "GET", Path: "/n"})
+	expected := []Violation{{In: "query", Name: "n", Message: "is required"}}
+	if !reflect.DeepEqual(result.Violations, expected) {
+		t.Errorf("Expected violations %v, got %v", expected, result.Violations)
+	}
+}
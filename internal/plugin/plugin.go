@@ -0,0 +1,75 @@
+// Package plugin loads custom handlers from Go plugins, shared objects built
+// with go build -buildmode=plugin, so they can be shipped without forking volk.
+//
+// A plugin is a main package exporting a Handlers function:
+//
+//	func Handlers(options map[string]string) (map[string]http.Handler, error)
+//
+// It gets the options of its [[plugin]] entry and returns handlers from the
+// standard library's net/http by path pattern, with the patterns of
+// Server.Handle: a pattern ending in a slash matches every path below it.
+// The plugin must be built with the same Go version as volk, and plugins
+// are only supported on Linux, FreeBSD and macOS.
+package plugin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"plugin"
+	"strings"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// Symbol is the name of the function a plugin exports.
+const Symbol = "Handlers"
+
+// HandlersFunc is the type of the function a plugin exports.
+type HandlersFunc = func(options map[string]string) (map[string]http.Handler, error)
+
+var (
+	ErrInvalidSymbol  = errors.New("plugin symbol Handlers has the wrong type")
+	ErrInvalidPattern = errors.New("plugin handler pattern must start with /")
+)
+
+// Load opens the plugin and returns its handlers by path pattern.
+func Load(cfg config.PluginConfig) (map[string]http.Handler, error) {
+	p, err := plugin.Open(cfg.Path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening plugin %s: %w", cfg.Path, err)
+	}
+	symbol, err := p.Lookup(Symbol)
+	if err != nil {
+		return nil, fmt.Errorf("error loading plugin %s: %w", cfg.Path, err)
+	}
+	handlers, err := handlers(symbol, cfg.Options)
+	if err != nil {
+		return nil, fmt.Errorf("error loading plugin %s: %w", cfg.Path, err)
+	}
+	return handlers, nil
+}
+
+// handlers calls the Handlers function of a plugin and checks its result.
+func handlers(symbol plugin.Symbol, options map[string]string) (map[string]http.Handler, error) {
+	fn, ok := symbol.(HandlersFunc)
+	if !ok {
+		return nil, ErrInvalidSymbol
+	}
+	if options == nil {
+		options = map[string]string{}
+	}
+	handlers, err := fn(options)
+	if err != nil {
+		return nil, err
+	}
+	for pattern, handler := range handlers {
+		if !strings.HasPrefix(pattern, "/") {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPattern, pattern)
+		}
+		if handler == nil {
+			return nil, fmt.Errorf("plugin handler for %s is nil", pattern)
+		}
+	}
+	return handlers, nil
+}
@@ -0,0 +1,57 @@
+package plugin
+
+import (
+	"errors"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestHandlers(t *testing.T) {
+	hello := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	tests := []struct {
+		name      string
+		symbol    any
+		wantCount int
+		wantErr   bool
+	}{
+		{"handlers", HandlersFunc(func(options map[string]string) (map[string]http.Handler, error) {
+			return map[string]http.Handler{"/hello": hello, "/api/": hello}, nil
+		}), 2, false},
+		{"options are passed", HandlersFunc(func(options map[string]string) (map[string]http.Handler, error) {
+			return map[string]http.Handler{options["path"]: hello}, nil
+		}), 1, false},
+		{"wrong type", func() map[string]http.Handler { return nil }, 0, true},
+		{"plugin error", HandlersFunc(func(options map[string]string) (map[string]http.Handler, error) {
+			return nil, errors.New("missing option")
+		}), 0, true},
+		{"relative pattern", HandlersFunc(func(options map[string]string) (map[string]http.Handler, error) {
+			return map[string]http.Handler{"hello": hello}, nil
+		}), 0, true},
+		{"nil handler", HandlersFunc(func(options map[string]string) (map[string]http.Handler, error) {
+			return map[string]http.Handler{"/hello": nil}, nil
+		}), 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handlers, err := handlers(tt.symbol, map[string]string{"path": "/from-options"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if len(handlers) != tt.wantCount {
+				t.Errorf("Expected %d handlers, got %d", tt.wantCount, len(handlers))
+			}
+		})
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	_, err := Load(config.PluginConfig{Path: filepath.Join(t.TempDir(), "missing.so")})
+	if err == nil {
+		t.Error("Expected an error for a missing plugin, got nil")
+	}
+}
@@ -0,0 +1,133 @@
+// Package scan checks uploaded content with an antivirus scanner before volk
+// stores it. Two kinds of scanners are supported:
+//
+//   - command: the content is piped to the standard input of a command, such
+//     as clamdscan -, which exits with 0 for clean and 1 for infected content.
+//   - clamd: the content is streamed to a clamd daemon with the INSTREAM
+//     command, over a Unix socket (a path) or TCP (host:port).
+package scan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// chunkSize is the size of the chunks content is streamed to clamd in.
+const chunkSize = 64 * 1024
+
+// ErrInfected is returned by Scan for content the scanner rejected.
+var ErrInfected = errors.New("content is infected")
+
+// Scanner scans content before it is stored.
+type Scanner interface {
+	// Scan reads the content from r as it streams it to the scanner. It
+	// returns nil for clean content, an error wrapping ErrInfected for
+	// infected content and another error if scanning failed.
+	Scan(r io.Reader) error
+}
+
+// New creates the scanner cfg configures, or returns nil if it configures none.
+func New(cfg config.ScanConfig) (Scanner, error) {
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	switch {
+	case len(cfg.Command) > 0 && cfg.Clamd != "":
+		return nil, fmt.Errorf("scan: command and clamd are mutually exclusive")
+	case len(cfg.Command) > 0:
+		return &commandScanner{args: cfg.Command, timeout: timeout}, nil
+	case cfg.Clamd != "":
+		network := "tcp"
+		if strings.Contains(cfg.Clamd, "/") {
+			network = "unix"
+		}
+		return &clamdScanner{network: network, address: cfg.Clamd, timeout: timeout}, nil
+	}
+	return nil, nil
+}
+
+// commandScanner pipes content to a command.
+type commandScanner struct {
+	args    []string
+	timeout time.Duration
+}
+
+func (c *commandScanner) Scan(r io.Reader) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, c.args[0], c.args[1:]...)
+	cmd.Stdin = r
+	output, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		return fmt.Errorf("%w: %s", ErrInfected, strings.TrimSpace(string(output)))
+	default:
+		return fmt.Errorf("error running scanner: %w: %s", err, strings.TrimSpace(string(output)))
+	}
+}
+
+// clamdScanner streams content to a clamd daemon.
+type clamdScanner struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+func (c *clamdScanner) Scan(r io.Reader) error {
+	conn, err := net.DialTimeout(c.network, c.address, c.timeout)
+	if err != nil {
+		return fmt.Errorf("error connecting to clamd: %w", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(c.timeout))
+
+	w := bufio.NewWriter(conn)
+	w.WriteString("zINSTREAM\x00")
+	var size [4]byte
+	chunk := make([]byte, chunkSize)
+	for {
+		n, err := io.ReadFull(r, chunk)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size[:], uint32(n))
+			w.Write(size[:])
+			w.Write(chunk[:n])
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("error reading content to scan: %w", err)
+		}
+	}
+	binary.BigEndian.PutUint32(size[:], 0)
+	w.Write(size[:])
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("error sending to clamd: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return fmt.Errorf("error reading clamd reply: %w", err)
+	}
+	reply = strings.TrimPrefix(strings.TrimRight(reply, "\x00\n"), "stream: ")
+	switch {
+	case reply == "OK":
+		return nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return fmt.Errorf("%w: %s", ErrInfected, strings.TrimSuffix(reply, " FOUND"))
+	default:
+		return fmt.Errorf("clamd error: %s", reply)
+	}
+}
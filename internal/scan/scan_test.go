@@ -0,0 +1,108 @@
+package scan
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// eicar stands in for infected content.
+const eicar = "X5O!P%@AP[4\\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*"
+
+// fakeClamd serves the INSTREAM command, finding content that contains "EICAR".
+func fakeClamd(t *testing.T) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			r := bufio.NewReader(conn)
+			command, _ := r.ReadString(0)
+			var content strings.Builder
+			for command == "zINSTREAM\x00" {
+				var size uint32
+				if binary.Read(r, binary.BigEndian, &size) != nil || size == 0 {
+					break
+				}
+				io.CopyN(&content, r, int64(size))
+			}
+			reply := "stream: OK\x00"
+			if strings.Contains(content.String(), "EICAR") {
+				reply = "stream: Eicar-Test-Signature FOUND\x00"
+			}
+			conn.Write([]byte(reply))
+			conn.Close()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestScan(t *testing.T) {
+	grep := []string{"sh", "-c", "if grep -q EICAR; then echo found; exit 1; fi"}
+	tests := []struct {
+		name string
+		cfg  config.ScanConfig
+		data string
+		err  error
+	}{
+		{"command clean", config.ScanConfig{Command: grep}, "hello", nil},
+		{"command infected", config.ScanConfig{Command: grep}, eicar, ErrInfected},
+		{"clamd clean", config.ScanConfig{Clamd: fakeClamd(t)}, strings.Repeat("a", 3*chunkSize/2), nil},
+		{"clamd infected", config.ScanConfig{Clamd: fakeClamd(t)}, strings.Repeat("a", chunkSize) + eicar, ErrInfected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Timeout = 5
+			scanner, err := New(tt.cfg)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if err := scanner.Scan(strings.NewReader(tt.data)); !errors.Is(err, tt.err) {
+				t.Errorf("Expected error %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestScanFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.ScanConfig
+	}{
+		{"command fails", config.ScanConfig{Command: []string{"sh", "-c", "exit 2"}, Timeout: 5}},
+		{"clamd unreachable", config.ScanConfig{Clamd: "127.0.0.1:1", Timeout: 5}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scanner, _ := New(tt.cfg)
+			if err := scanner.Scan(strings.NewReader("hello")); err == nil || errors.Is(err, ErrInfected) {
+				t.Errorf("Expected a scanner error, got %v", err)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	if scanner, err := New(config.ScanConfig{}); scanner != nil || err != nil {
+		t.Errorf("Expected no scanner, got %v and %v", scanner, err)
+	}
+	if _, err := New(config.ScanConfig{Command: []string{"clamdscan"}, Clamd: "localhost:3310"}); err == nil {
+		t.Error("Expected an error for both command and clamd, got nil")
+	}
+}
@@ -0,0 +1,325 @@
+package script
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Vars are the variables an expression can use.
+var Vars = []string{"method", "path", "query", "host", "protocol", "remote_ip", "country"}
+
+// Funcs are the functions an expression can call, with a string literal as argument.
+var Funcs = []string{"header", "cookie", "param", "segment"}
+
+// ErrSyntax is returned for expressions that cannot be parsed.
+var ErrSyntax = errors.New("syntax error")
+
+// Env gives expressions access to a request.
+type Env interface {
+	// Var returns the value of one of Vars.
+	Var(name string) string
+	// Call returns the result of one of Funcs for arg.
+	Call(fn, arg string) string
+}
+
+// Expr is a compiled condition.
+type Expr struct {
+	root boolNode
+}
+
+// Compile parses a condition. Values are strings: literals in double quotes,
+// variables such as path and function calls such as header("User-Agent").
+// They are compared with ==, !=, contains, prefix, suffix and matches (a
+// regular expression literal), combined with &&, || and !, and grouped with
+// parentheses. A value on its own is true if it is not empty, and true and
+// false are literals.
+func Compile(src string) (*Expr, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, fmt.Errorf("%w: unexpected %s", ErrSyntax, p.peek())
+	}
+	return &Expr{root: root}, nil
+}
+
+// Eval evaluates the condition against env.
+func (e *Expr) Eval(env Env) bool {
+	return e.root.eval(env)
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenString
+	tokenIdent
+	tokenOp
+)
+
+type token struct {
+	kind  tokenKind
+	value string
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return strconv.Quote(t.value)
+	}
+	return fmt.Sprintf("%q", t.value)
+}
+
+// lex splits src into tokens.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '"':
+			end := i + 1
+			for end < len(src) && src[end] != '"' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(src) {
+				return nil, fmt.Errorf("%w: unterminated string", ErrSyntax)
+			}
+			value, err := strconv.Unquote(src[i : end+1])
+			if err != nil {
+				return nil, fmt.Errorf("%w: invalid string %s", ErrSyntax, src[i:end+1])
+			}
+			tokens = append(tokens, token{tokenString, value})
+			i = end + 1
+		case isIdentByte(c):
+			end := i
+			for end < len(src) && (isIdentByte(src[end]) || src[end] >= '0' && src[end] <= '9') {
+				end++
+			}
+			tokens = append(tokens, token{tokenIdent, src[i:end]})
+			i = end
+		default:
+			op := ""
+			for _, candidate := range []string{"==", "!=", "&&", "||", "!", "(", ")"} {
+				if strings.HasPrefix(src[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("%w: unexpected %q", ErrSyntax, c)
+			}
+			tokens = append(tokens, token{tokenOp, op})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+func isIdentByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_'
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(kind tokenKind, value string) bool {
+	if t := p.peek(); t.kind == kind && t.value == value {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) parseOr() (boolNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenOp, "||") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (boolNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.accept(tokenOp, "&&") {
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (boolNode, error) {
+	switch {
+	case p.accept(tokenOp, "!"):
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	case p.accept(tokenOp, "("):
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.accept(tokenOp, ")") {
+			return nil, fmt.Errorf("%w: expected ) instead of %s", ErrSyntax, p.peek())
+		}
+		return inner, nil
+	case p.accept(tokenIdent, "true"):
+		return constNode(true), nil
+	case p.accept(tokenIdent, "false"):
+		return constNode(false), nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (boolNode, error) {
+	left, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	var compare func(a, b string) bool
+	switch {
+	case t.kind == tokenOp && t.value == "==":
+		compare = func(a, b string) bool { return a == b }
+	case t.kind == tokenOp && t.value == "!=":
+		compare = func(a, b string) bool { return a != b }
+	case t.kind == tokenIdent && t.value == "contains":
+		compare = strings.Contains
+	case t.kind == tokenIdent && t.value == "prefix":
+		compare = strings.HasPrefix
+	case t.kind == tokenIdent && t.value == "suffix":
+		compare = strings.HasSuffix
+	case t.kind == tokenIdent && t.value == "matches":
+		p.next()
+		pattern := p.next()
+		if pattern.kind != tokenString {
+			return nil, fmt.Errorf("%w: matches needs a string literal, got %s", ErrSyntax, pattern)
+		}
+		re, err := regexp.Compile(pattern.value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %w", pattern.value, err)
+		}
+		return matchNode{left, re}, nil
+	default:
+		return truthyNode{left}, nil
+	}
+	p.next()
+
+	right, err := p.parseValue()
+	if err != nil {
+		return nil, err
+	}
+	return compareNode{left, right, compare}, nil
+}
+
+func (p *parser) parseValue() (stringNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenString:
+		return literalNode(t.value), nil
+	case tokenIdent:
+		if !p.accept(tokenOp, "(") {
+			if !slices.Contains(Vars, t.value) {
+				return nil, fmt.Errorf("%w: unknown variable %s", ErrSyntax, t.value)
+			}
+			return varNode(t.value), nil
+		}
+		if !slices.Contains(Funcs, t.value) {
+			return nil, fmt.Errorf("%w: unknown function %s", ErrSyntax, t.value)
+		}
+		arg := p.next()
+		if arg.kind != tokenString {
+			return nil, fmt.Errorf("%w: %s needs a string literal, got %s", ErrSyntax, t.value, arg)
+		}
+		if !p.accept(tokenOp, ")") {
+			return nil, fmt.Errorf("%w: expected ) instead of %s", ErrSyntax, p.peek())
+		}
+		return callNode{t.value, arg.value}, nil
+	}
+	return nil, fmt.Errorf("%w: expected a value instead of %s", ErrSyntax, t)
+}
+
+type boolNode interface {
+	eval(env Env) bool
+}
+
+type stringNode interface {
+	value(env Env) string
+}
+
+type (
+	orNode      struct{ left, right boolNode }
+	andNode     struct{ left, right boolNode }
+	notNode     struct{ operand boolNode }
+	constNode   bool
+	truthyNode  struct{ operand stringNode }
+	compareNode struct {
+		left, right stringNode
+		compare     func(a, b string) bool
+	}
+	matchNode struct {
+		operand stringNode
+		re      *regexp.Regexp
+	}
+	literalNode string
+	varNode     string
+	callNode    struct{ fn, arg string }
+)
+
+func (n orNode) eval(env Env) bool      { return n.left.eval(env) || n.right.eval(env) }
+func (n andNode) eval(env Env) bool     { return n.left.eval(env) && n.right.eval(env) }
+func (n notNode) eval(env Env) bool     { return !n.operand.eval(env) }
+func (n constNode) eval(env Env) bool   { return bool(n) }
+func (n truthyNode) eval(env Env) bool  { return n.operand.value(env) != "" }
+func (n compareNode) eval(env Env) bool { return n.compare(n.left.value(env), n.right.value(env)) }
+func (n matchNode) eval(env Env) bool   { return n.re.MatchString(n.operand.value(env)) }
+
+func (n literalNode) value(env Env) string { return string(n) }
+func (n varNode) value(env Env) string     { return env.Var(string(n)) }
+func (n callNode) value(env Env) string    { return env.Call(n.fn, n.arg) }
@@ -0,0 +1,117 @@
+// Package script evaluates per-location request rules: each rule has a
+// condition written in a small expression language (see Compile) and
+// redirects, rewrites the path or changes the headers of the requests that
+// match it. Rules make dynamic decisions such as sending mobile browsers to
+// another page or routing the holders of a cookie to another directory.
+package script
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// DefaultStatus is the status of redirects that leave it unset.
+const DefaultStatus = 302
+
+// Rule is a compiled rule.
+type Rule struct {
+	cond   *Expr
+	config config.ScriptConfig
+}
+
+// Header is a header set or added by a rule.
+type Header struct {
+	Name  string
+	Value string
+}
+
+// Result is what the matching rules do to a request.
+type Result struct {
+	Redirect        string // URL to redirect to, if not empty
+	Status          int    // Status of the redirect
+	Rewrite         string // Path to serve instead, if not empty
+	RequestHeaders  []Header
+	ResponseHeaders []Header
+}
+
+// New compiles the rules of a location.
+func New(rules []config.ScriptConfig) ([]Rule, error) {
+	compiled := make([]Rule, 0, len(rules))
+	for i, rule := range rules {
+		cond, err := Compile(rule.If)
+		if rule.If == "" {
+			cond, err = Compile("true")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid condition in script %d: %w", i+1, err)
+		}
+		if rule.Rewrite != "" && !strings.HasPrefix(rule.Rewrite, "/") && !strings.HasPrefix(rule.Rewrite, "$") {
+			return nil, fmt.Errorf("script %d: rewrite must be a path starting with /: %q", i+1, rule.Rewrite)
+		}
+		if rule.Redirect != "" && rule.Status == 0 {
+			rule.Status = DefaultStatus
+		}
+		if rule.Redirect != "" && (rule.Status < 300 || rule.Status > 399) {
+			return nil, fmt.Errorf("script %d: redirect status must be 3xx: %d", i+1, rule.Status)
+		}
+		compiled = append(compiled, Rule{cond: cond, config: rule})
+	}
+	return compiled, nil
+}
+
+// Run evaluates the rules in order against env. Headers of all matching
+// rules are collected and a later rewrite replaces an earlier one. A
+// matching redirect, or a matching rule marked last, ends the evaluation.
+func Run(rules []Rule, env Env) Result {
+	var result Result
+	for _, rule := range rules {
+		if !rule.cond.Eval(env) {
+			continue
+		}
+		result.RequestHeaders = append(result.RequestHeaders, expandHeaders(rule.config.RequestHeaders, env)...)
+		result.ResponseHeaders = append(result.ResponseHeaders, expandHeaders(rule.config.ResponseHeaders, env)...)
+		if rule.config.Redirect != "" {
+			result.Redirect = Expand(rule.config.Redirect, env)
+			result.Status = rule.config.Status
+			return result
+		}
+		if rule.config.Rewrite != "" {
+			result.Rewrite = Expand(rule.config.Rewrite, env)
+		}
+		if rule.config.Last {
+			break
+		}
+	}
+	return result
+}
+
+// Expand replaces $name and ${name} in s by the variables of env. Names
+// that are not in Vars are left as they are.
+func Expand(s string, env Env) string {
+	return os.Expand(s, func(name string) string {
+		if slices.Contains(Vars, name) {
+			return env.Var(name)
+		}
+		return "$" + name
+	})
+}
+
+// expandHeaders returns the headers sorted by name, with their values expanded.
+func expandHeaders(headers map[string]string, env Env) []Header {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	expanded := make([]Header, 0, len(names))
+	for _, name := range names {
+		expanded = append(expanded, Header{Name: name, Value: Expand(headers[name], env)})
+	}
+	return expanded
+}
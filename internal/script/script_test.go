@@ -0,0 +1,168 @@
+package script
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// testEnv is an Env backed by maps.
+type testEnv struct {
+	vars    map[string]string
+	headers map[string]string
+}
+
+func (e testEnv) Var(name string) string { return e.vars[name] }
+
+func (e testEnv) Call(fn, arg string) string {
+	if fn == "header" {
+		return e.headers[arg]
+	}
+	return ""
+}
+
+var env = testEnv{
+	vars:    map[string]string{"method": "GET", "path": "/docs/intro.html", "query": "v=2"},
+	headers: map[string]string{"User-Agent": "Mozilla/5.0 (iPhone; Mobile)"},
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`path == "/docs/intro.html"`, true},
+		{`path != "/docs/intro.html"`, false},
+		{`path prefix "/docs/" && method == "GET"`, true},
+		{`path suffix ".pdf" || header("User-Agent") contains "Mobile"`, true},
+		{`!(header("User-Agent") contains "Mobile")`, false},
+		{`path matches "^/docs/[a-z]+\\.html$"`, true},
+		{`header("X-Missing")`, false},
+		{`query`, true},
+		{`true && !false`, true},
+		{`method == "POST" || method == "PUT" && path == "/"`, false},
+		{`"a" == "a"`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := Compile(tt.expr)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if got := expr.Eval(env); got != tt.want {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []string{
+		``,
+		`path ==`,
+		`unknown == "x"`,
+		`shell("rm")`,
+		`header(path)`,
+		`(path == "/"`,
+		`path == "/" extra`,
+		`path matches path`,
+		`path = "/"`,
+		`"unterminated`,
+	}
+
+	for _, src := range tests {
+		t.Run(src, func(t *testing.T) {
+			if _, err := Compile(src); !errors.Is(err, ErrSyntax) {
+				t.Errorf("Expected ErrSyntax, got %v", err)
+			}
+		})
+	}
+
+	if _, err := Compile(`path matches "("`); err == nil {
+		t.Error("Expected an error for an invalid regular expression, got nil")
+	}
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []config.ScriptConfig
+		want  Result
+	}{
+		{
+			name: "redirect with variables",
+			rules: []config.ScriptConfig{
+				{If: `header("User-Agent") contains "Mobile"`, Redirect: "/m$path?$query"},
+				{Rewrite: "/never.html"},
+			},
+			want: Result{Redirect: "/m/docs/intro.html?v=2", Status: 302},
+		},
+		{
+			name: "headers and rewrite",
+			rules: []config.ScriptConfig{
+				{If: `path prefix "/docs/"`, Rewrite: "/v2$path", ResponseHeaders: map[string]string{"X-Variant": "v2", "Cache-Control": "no-store"}},
+				{If: `method == "POST"`, Rewrite: "/post.html"},
+				{RequestHeaders: map[string]string{"X-Original-Path": "$path"}},
+			},
+			want: Result{
+				Rewrite:         "/v2/docs/intro.html",
+				RequestHeaders:  []Header{{"X-Original-Path", "/docs/intro.html"}},
+				ResponseHeaders: []Header{{"Cache-Control", "no-store"}, {"X-Variant", "v2"}},
+			},
+		},
+		{
+			name: "last",
+			rules: []config.ScriptConfig{
+				{Rewrite: "/first.html", Last: true},
+				{Rewrite: "/second.html"},
+			},
+			want: Result{Rewrite: "/first.html"},
+		},
+		{
+			name:  "unknown variables are kept",
+			rules: []config.ScriptConfig{{Redirect: "https://example.com/$HOME", Status: 301}},
+			want:  Result{Redirect: "https://example.com/$HOME", Status: 301},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules, err := New(tt.rules)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			got := Run(rules, env)
+			if len(got.RequestHeaders) == 0 {
+				got.RequestHeaders = nil
+			}
+			if len(got.ResponseHeaders) == 0 {
+				got.ResponseHeaders = nil
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		rule config.ScriptConfig
+	}{
+		{"invalid condition", config.ScriptConfig{If: `path ==`}},
+		{"relative rewrite", config.ScriptConfig{Rewrite: "index.html"}},
+		{"redirect status", config.ScriptConfig{Redirect: "/", Status: 200}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New([]config.ScriptConfig{tt.rule}); err == nil {
+				t.Error("Expected an error, got nil")
+			}
+		})
+	}
+}
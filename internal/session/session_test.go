@@ -0,0 +1,68 @@
+package session
+
+import "testing"
+
+func TestSigner(t *testing.T) {
+	signer := NewSigner("secret")
+	value := signer.Sign([]byte(`{"user":"a"}`))
+
+	payload, ok := signer.Verify(value)
+	if !ok || string(payload) != `{"user":"a"}` {
+		t.Errorf("Expected the signed value to verify, got %q, %v", payload, ok)
+	}
+
+	tests := map[string]string{
+		"tampered":       value[:len(value)-2] + "xx",
+		"no mac":         "eyJ1c2VyIjoiYSJ9",
+		"other secret":   NewSigner("other").Sign([]byte(`{"user":"a"}`)),
+		"random secret":  NewSigner("").Sign([]byte(`{"user":"a"}`)),
+		"invalid base64": "!!!.!!!",
+	}
+	for name, value := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, ok := signer.Verify(value); ok {
+				t.Errorf("Expected %q not to verify", value)
+			}
+		})
+	}
+}
+
+func TestDerive(t *testing.T) {
+	signer := NewSigner("secret")
+	sessions, states := signer.Derive("session"), signer.Derive("state")
+
+	value := sessions.Sign([]byte(`{"user":"a"}`))
+	if _, ok := sessions.Verify(value); !ok {
+		t.Errorf("Expected %q to verify for its purpose", value)
+	}
+	if _, ok := states.Verify(value); ok {
+		t.Errorf("Expected %q not to verify for another purpose", value)
+	}
+	if _, ok := signer.Verify(value); ok {
+		t.Errorf("Expected %q not to verify with the secret itself", value)
+	}
+	if _, ok := NewSigner("secret").Derive("session").Verify(value); !ok {
+		t.Errorf("Expected %q to verify with a signer derived from the same secret", value)
+	}
+}
+
+func TestCookie(t *testing.T) {
+	header := "a=1; volk_session=abc.def; b="
+	if value, ok := Cookie(header, "volk_session"); !ok || value != "abc.def" {
+		t.Errorf("Expected volk_session=abc.def, got %q, %v", value, ok)
+	}
+	if value, ok := Cookie(header, "b"); !ok || value != "" {
+		t.Errorf("Expected an empty b cookie, got %q, %v", value, ok)
+	}
+	if _, ok := Cookie(header, "missing"); ok {
+		t.Errorf("Expected no missing cookie")
+	}
+}
+
+func TestSetCookie(t *testing.T) {
+	got := SetCookie("flash", "v", CookieOptions{MaxAge: -1, Secure: true})
+	expected := "flash=v; Path=/; Max-Age=0; HttpOnly; SameSite=Lax; Secure"
+	if got != expected {
+		t.Errorf("SetCookie() = %q, want %q", got, expected)
+	}
+}
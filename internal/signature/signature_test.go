@@ -0,0 +1,88 @@
+package signature
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestVerify(t *testing.T) {
+	secret := []byte("s3cret")
+	now := time.Unix(1700000000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	signed := Request{Method: "POST", Host: "api.example.com", Path: "/hook", Query: "b=2&a=1", Body: `{"a":1}`}
+	valid := Sign(secret, signed, now.Unix())
+
+	with := func(change func(*Request)) Request {
+		req := signed
+		change(&req)
+		return req
+	}
+
+	tests := []struct {
+		name      string
+		req       Request
+		timestamp string
+		signature string
+		expected  error
+	}{
+		{"valid", signed, ts, valid, nil},
+		{"reordered query", with(func(r *Request) { r.Query = "?a=1&b=2" }), ts, valid, nil},
+		{"host case", with(func(r *Request) { r.Host = "API.example.com" }), ts, valid, nil},
+		{"missing signature", signed, ts, "", ErrMissingSignature},
+		{"invalid timestamp", signed, "yesterday", valid, ErrInvalidTimestamp},
+		{"expired", signed, strconv.FormatInt(now.Add(-10*time.Minute).Unix(), 10), valid, ErrExpired},
+		{"tampered body", with(func(r *Request) { r.Body = `{"a":2}` }), ts, valid, ErrInvalidSignature},
+		{"other path", with(func(r *Request) { r.Path = "/admin" }), ts, valid, ErrInvalidSignature},
+		{"other method", with(func(r *Request) { r.Method = "DELETE" }), ts, valid, ErrInvalidSignature},
+		{"tampered query", with(func(r *Request) { r.Query = "b=2&a=9" }), ts, valid, ErrInvalidSignature},
+		{"added query", with(func(r *Request) { r.Query = "b=2&a=1&admin=1" }), ts, valid, ErrInvalidSignature},
+		{"other host", with(func(r *Request) { r.Host = "other.example.com" }), ts, valid, ErrInvalidSignature},
+		{"wrong secret", signed, ts, Sign([]byte("other"), signed, now.Unix()), ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := NewVerifier(secret, 0)
+			err := v.Verify(tt.req, tt.timestamp, tt.signature, now)
+			if !errors.Is(err, tt.expected) {
+				t.Errorf("Expected error %v, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestVerifyReplay(t *testing.T) {
+	secret := []byte("s3cret")
+	now := time.Unix(1700000000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	req := Request{Method: "GET", Path: "/data"}
+	sig := Sign(secret, req, now.Unix())
+
+	v := NewVerifier(secret, time.Minute)
+	if err := v.Verify(req, ts, sig, now); err != nil {
+		t.Fatalf("Expected the first request to verify, got %v", err)
+	}
+	if err := v.Verify(req, ts, sig, now.Add(time.Second)); !errors.Is(err, ErrReplayed) {
+		t.Errorf("Expected a replayed request to fail with ErrReplayed, got %v", err)
+	}
+	if err := v.Verify(req, ts, sig, now.Add(2*time.Minute)); !errors.Is(err, ErrExpired) {
+		t.Errorf("Expected the request to expire after the window, got %v", err)
+	}
+}
+
+func TestCanonicalQuery(t *testing.T) {
+	tests := map[string]string{
+		"":            "",
+		"?b=2&a=1":    "a=1&b=2",
+		"a=2&a=1":     "a=1&a=2",
+		"q=a+b&x=%41": "q=a+b&x=A",
+		"bad=%zz":     "bad=%zz",
+	}
+	for query, expected := range tests {
+		if got := CanonicalQuery(query); got != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, query, got)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Package sitemap generates a sitemap.xml from the HTML files in a document root.
+//
+// The document root is rescanned at most once per refresh interval; the
+// sitemap is rebuilt only when a scan finds files that were added, removed or
+// modified since the previous one.
+package sitemap
+
+import (
+	"encoding/xml"
+	"io/fs"
+	"net/url"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry is a page listed in the sitemap.
+type Entry struct {
+	Path         string    // URL path of the page
+	LastModified time.Time // Modification time of the file
+}
+
+// Sitemap is the list of pages of a document root.
+type Sitemap struct {
+	Entries      []Entry
+	LastModified time.Time // Latest modification time of all entries
+}
+
+// Generator builds the sitemap of a document root and keeps it up to date.
+type Generator struct {
+	root        string
+	defaultFile string
+	deny        []string
+	interval    time.Duration
+
+	mu        sync.Mutex
+	sitemap   Sitemap
+	signature string
+	checked   time.Time
+}
+
+// NewGenerator creates a Generator for the document root. Paths matching one of
+// the deny patterns are left out, and the root is rescanned at most once per interval.
+func NewGenerator(root, defaultFile string, deny []string, interval time.Duration) *Generator {
+	return &Generator{
+		root:        root,
+		defaultFile: defaultFile,
+		deny:        deny,
+		interval:    interval,
+	}
+}
+
+// Sitemap returns the current sitemap, rescanning the document root if the refresh interval has passed.
+func (g *Generator) Sitemap() (Sitemap, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if !g.checked.IsZero() && time.Since(g.checked) < g.interval {
+		return g.sitemap, nil
+	}
+
+	sitemap, err := g.scan()
+	if err != nil {
+		return Sitemap{}, err
+	}
+	g.checked = time.Now()
+
+	signature := sitemap.signature()
+	if signature != g.signature {
+		g.sitemap = sitemap
+		g.signature = signature
+	}
+	return g.sitemap, nil
+}
+
+// scan walks the document root and collects the HTML pages.
+// A document root that is a symbolic link, such as the current release of a
+// deploy, is resolved first so that the release it points to is walked.
+func (g *Generator) scan() (Sitemap, error) {
+	var sitemap Sitemap
+
+	root, err := filepath.EvalSymlinks(g.root)
+	if err != nil {
+		return sitemap, err
+	}
+
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		urlPath := "/" + filepath.ToSlash(rel)
+		if rel == "." {
+			urlPath = "/"
+		}
+
+		if d.IsDir() {
+			if rel != "." && (strings.HasPrefix(d.Name(), ".") || Denied(g.deny, urlPath+"/")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !d.Type().IsRegular() || strings.HasPrefix(d.Name(), ".") || !isPage(d.Name()) {
+			return nil
+		}
+
+		// Pages served as a directory's default file are listed under the directory.
+		if d.Name() == g.defaultFile {
+			urlPath = strings.TrimSuffix(urlPath, g.defaultFile)
+		}
+		if Denied(g.deny, urlPath) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		modTime := info.ModTime().UTC()
+		sitemap.Entries = append(sitemap.Entries, Entry{Path: urlPath, LastModified: modTime})
+		if modTime.After(sitemap.LastModified) {
+			sitemap.LastModified = modTime
+		}
+		return nil
+	})
+	if err != nil {
+		return Sitemap{}, err
+	}
+
+	sort.Slice(sitemap.Entries, func(i, j int) bool {
+		return sitemap.Entries[i].Path < sitemap.Entries[j].Path
+	})
+	return sitemap, nil
+}
+
+// signature identifies the set of entries and their modification times.
+func (s Sitemap) signature() string {
+	var builder strings.Builder
+	for _, entry := range s.Entries {
+		builder.WriteString(entry.Path)
+		builder.WriteString(entry.LastModified.Format(time.RFC3339Nano))
+		builder.WriteByte('\n')
+	}
+	return builder.String()
+}
+
+// XML renders the sitemap with absolute URLs under baseURL, e.g.
+// https://example.com. Paths are percent-encoded and the URLs XML-escaped, so
+// a page named "a & b.html" is listed as https://example.com/a%20&amp;%20b.html.
+func (s Sitemap) XML(baseURL string) string {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+
+	var builder strings.Builder
+	builder.WriteString(xml.Header)
+	builder.WriteString(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` + "\n")
+	for _, entry := range s.Entries {
+		builder.WriteString("  <url>\n    <loc>")
+		xml.EscapeText(&builder, []byte(baseURL+(&url.URL{Path: entry.Path}).EscapedPath()))
+		builder.WriteString("</loc>\n    <lastmod>")
+		builder.WriteString(entry.LastModified.Format("2006-01-02"))
+		builder.WriteString("</lastmod>\n  </url>\n")
+	}
+	builder.WriteString("</urlset>\n")
+	return builder.String()
+}
+
+// isPage reports whether a file name is an HTML page.
+func isPage(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".html" || ext == ".htm"
+}
+
+// Denied reports whether the URL path matches one of the deny patterns.
+// Patterns use path.Match syntax and are matched against the whole path;
+// patterns without a slash are also matched against the last path element,
+// and patterns ending in a slash match everything below that directory.
+func Denied(patterns []string, urlPath string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "/") {
+			if strings.HasPrefix(urlPath, pattern) {
+				return true
+			}
+			continue
+		}
+		if ok, _ := path.Match(pattern, urlPath); ok {
+			return true
+		}
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(urlPath)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// RobotsRules converts deny patterns to Disallow rules of robots.txt, which
+// know "*" for any characters, "/" included, and "$" for the end of the path.
+// A rule may cover more paths than its pattern, as "/drafts/*.html" covers
+// /drafts/sub/post.html, but never fewer. Patterns robots.txt cannot express,
+// with "?", character classes or a "$", and relative ones, which match no URL
+// path, are left out.
+func RobotsRules(patterns []string) []string {
+	var rules []string
+	for _, pattern := range patterns {
+		if strings.ContainsAny(pattern, `?[\$`) {
+			continue
+		}
+		// Runs of "*" match the same in robots.txt as a single one.
+		for strings.Contains(pattern, "**") {
+			pattern = strings.ReplaceAll(pattern, "**", "*")
+		}
+		end := "$"
+		if strings.HasSuffix(pattern, "*") || strings.HasSuffix(pattern, "/") {
+			end = ""
+		}
+		switch {
+		case strings.HasPrefix(pattern, "/"):
+			rules = append(rules, pattern+end)
+		case strings.Contains(pattern, "/"):
+			continue
+		case strings.HasPrefix(pattern, "*"):
+			// A pattern for the last path element, such as *.bak.
+			rules = append(rules, "/"+pattern+end)
+		default:
+			rules = append(rules, "/"+pattern+end, "/*/"+pattern+end)
+		}
+	}
+	return rules
+}
@@ -0,0 +1,150 @@
+package sitemap
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFile creates a file below root with the given modification time.
+func writeFile(t *testing.T, root, name string, modTime time.Time) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("<h1>page</h1>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestGeneratorSitemap(t *testing.T) {
+	root := t.TempDir()
+	jan := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	feb := time.Date(2024, 2, 20, 10, 0, 0, 0, time.UTC)
+
+	writeFile(t, root, "index.html", jan)
+	writeFile(t, root, "about.html", feb)
+	writeFile(t, root, "blog/index.html", jan)
+	writeFile(t, root, "blog/draft.bak.html", jan)
+	writeFile(t, root, "private/secret.html", jan)
+	writeFile(t, root, ".git/index.html", jan)
+	writeFile(t, root, "style.css", feb)
+
+	g := NewGenerator(root, "index.html", []string{"/private/", "*.bak.html"}, time.Minute)
+	sm, err := g.Sitemap()
+	if err != nil {
+		t.Fatalf("Sitemap returned an error: %v", err)
+	}
+
+	expected := []string{"/", "/about.html", "/blog/"}
+	if len(sm.Entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %d: %v", len(expected), len(sm.Entries), sm.Entries)
+	}
+	for i, path := range expected {
+		if sm.Entries[i].Path != path {
+			t.Errorf("Expected entry %d to be %s, got %s", i, path, sm.Entries[i].Path)
+		}
+	}
+	if !sm.LastModified.Equal(feb) {
+		t.Errorf("Expected LastModified %v, got %v", feb, sm.LastModified)
+	}
+
+	xml := sm.XML("https://example.com/")
+	if !strings.Contains(xml, "<loc>https://example.com/about.html</loc>\n    <lastmod>2024-02-20</lastmod>") {
+		t.Errorf("Expected about.html with its lastmod in the XML, got %s", xml)
+	}
+}
+
+func TestGeneratorRefresh(t *testing.T) {
+	root := t.TempDir()
+	modTime := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	writeFile(t, root, "index.html", modTime)
+
+	g := NewGenerator(root, "index.html", nil, 0)
+	if sm, err := g.Sitemap(); err != nil || len(sm.Entries) != 1 {
+		t.Fatalf("Expected 1 entry, got %v (error: %v)", sm.Entries, err)
+	}
+
+	writeFile(t, root, "new.html", modTime.Add(time.Hour))
+	sm, err := g.Sitemap()
+	if err != nil {
+		t.Fatalf("Sitemap returned an error: %v", err)
+	}
+	if len(sm.Entries) != 2 {
+		t.Errorf("Expected the new page after a rescan, got %v", sm.Entries)
+	}
+
+	cached := NewGenerator(root, "index.html", nil, time.Hour)
+	cached.Sitemap()
+	writeFile(t, root, "later.html", modTime)
+	if sm, _ := cached.Sitemap(); len(sm.Entries) != 2 {
+		t.Errorf("Expected the cached sitemap within the refresh interval, got %v", sm.Entries)
+	}
+}
+
+func TestDenied(t *testing.T) {
+	patterns := []string{"/private/", "*.bak", "/drafts/*.html"}
+
+	tests := []struct {
+		path     string
+		expected bool
+	}{
+		{"/private/", true},
+		{"/private/a/b.html", true},
+		{"/old/page.bak", true},
+		{"/drafts/post.html", true},
+		{"/drafts/sub/post.html", false},
+		{"/public/index.html", false},
+		{"/privateer.html", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			if got := Denied(patterns, tt.path); got != tt.expected {
+				t.Errorf("Denied(%q) = %v, want %v", tt.path, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestRobotsRules(t *testing.T) {
+	patterns := []string{"/private/", "*.bak", "/drafts/*.html", "/archive/**", "secret.txt", "/page?.html", "drafts/*", "/exact.html"}
+	expected := []string{"/private/", "/*.bak$", "/drafts/*.html$", "/archive/*", "/secret.txt$", "/*/secret.txt$", "/exact.html$"}
+
+	got := RobotsRules(patterns)
+	if strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Errorf("Expected rules %q, got %q", expected, got)
+	}
+}
+
+func TestSitemapXMLEscapes(t *testing.T) {
+	sm := Sitemap{Entries: []Entry{{Path: "/a & b/<c>.html", LastModified: time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC)}}}
+	xml := sm.XML("https://example.com")
+	if !strings.Contains(xml, "<loc>https://example.com/a%20&amp;%20b/%3Cc%3E.html</loc>") {
+		t.Errorf("Expected an encoded and escaped URL, got %s", xml)
+	}
+}
+
+func TestGeneratorSymlinkRoot(t *testing.T) {
+	dir := t.TempDir()
+	release := filepath.Join(dir, "releases", "1")
+	writeFile(t, release, "about.html", time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC))
+	root := filepath.Join(dir, "public")
+	if err := os.Symlink(release, root); err != nil {
+		t.Fatal(err)
+	}
+
+	sm, err := NewGenerator(root, "index.html", nil, 0).Sitemap()
+	if err != nil {
+		t.Fatalf("Sitemap returned an error: %v", err)
+	}
+	if len(sm.Entries) != 1 || sm.Entries[0].Path != "/about.html" {
+		t.Errorf("Expected /about.html from the linked release, got %v", sm.Entries)
+	}
+}
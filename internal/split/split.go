@@ -0,0 +1,76 @@
+// Package split assigns visitors to the weighted variants of an A/B test.
+// A visitor keeps their variant for as long as they send it back, usually in
+// a cookie; new visitors get a variant at random, in proportion to the
+// weights.
+package split
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"regexp"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// DefaultCookie is the cookie the variant is stored in when the location does
+// not name one.
+const DefaultCookie = "volk_variant"
+
+// namePattern restricts variant names to characters that need no quoting in
+// cookies and logs.
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// Splitter chooses the variant of a request.
+type Splitter struct {
+	variants []config.VariantConfig
+	total    int
+
+	intN func(n int) int // Random number in [0, n)
+}
+
+// New checks the variants and creates a Splitter for them.
+func New(variants []config.VariantConfig) (*Splitter, error) {
+	s := &Splitter{intN: rand.IntN}
+	seen := map[string]bool{}
+	for i, variant := range variants {
+		if !namePattern.MatchString(variant.Name) {
+			return nil, fmt.Errorf("variant %d: invalid name %q", i+1, variant.Name)
+		}
+		if seen[variant.Name] {
+			return nil, fmt.Errorf("variant %d: duplicate name %q", i+1, variant.Name)
+		}
+		seen[variant.Name] = true
+		if variant.Weight < 0 {
+			return nil, fmt.Errorf("variant %s: weight must not be negative", variant.Name)
+		}
+		if variant.DocumentRoot == "" {
+			return nil, fmt.Errorf("variant %s: document_root is required", variant.Name)
+		}
+		s.total += variant.Weight
+	}
+	if s.total == 0 {
+		return nil, fmt.Errorf("variants need a positive total weight")
+	}
+	s.variants = variants
+	return s, nil
+}
+
+// Choose returns the variant named current, if there is one and it still has
+// a weight, and otherwise a random variant for a new visitor, reported with
+// true.
+func (s *Splitter) Choose(current string) (config.VariantConfig, bool) {
+	for _, variant := range s.variants {
+		if variant.Name == current && variant.Weight > 0 {
+			return variant, false
+		}
+	}
+
+	n := s.intN(s.total)
+	for _, variant := range s.variants {
+		if n < variant.Weight {
+			return variant, true
+		}
+		n -= variant.Weight
+	}
+	return s.variants[len(s.variants)-1], true
+}
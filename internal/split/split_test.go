@@ -0,0 +1,74 @@
+package split
+
+import (
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+var variants = []config.VariantConfig{
+	{Name: "a", Weight: 90, DocumentRoot: "public"},
+	{Name: "b", Weight: 10, DocumentRoot: "public-b"},
+	{Name: "retired", Weight: 0, DocumentRoot: "public-old"},
+}
+
+func TestChoose(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  string
+		random   int
+		wantName string
+		wantNew  bool
+	}{
+		{"sticky", "b", 0, "b", false},
+		{"new visitor in the first share", "", 89, "a", true},
+		{"new visitor in the second share", "", 90, "b", true},
+		{"unknown variant", "c", 95, "b", true},
+		{"variant without weight", "retired", 0, "a", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(variants)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			s.intN = func(n int) int {
+				if n != 100 {
+					t.Errorf("Expected the total weight 100, got %d", n)
+				}
+				return tt.random
+			}
+
+			variant, isNew := s.Choose(tt.current)
+			if variant.Name != tt.wantName {
+				t.Errorf("Expected variant %q, got %q", tt.wantName, variant.Name)
+			}
+			if isNew != tt.wantNew {
+				t.Errorf("Expected new %v, got %v", tt.wantNew, isNew)
+			}
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		variants []config.VariantConfig
+	}{
+		{"no variants", nil},
+		{"zero weights", []config.VariantConfig{{Name: "a", DocumentRoot: "public"}}},
+		{"negative weight", []config.VariantConfig{{Name: "a", Weight: -1, DocumentRoot: "public"}}},
+		{"invalid name", []config.VariantConfig{{Name: "a b", Weight: 1, DocumentRoot: "public"}}},
+		{"duplicate name", []config.VariantConfig{{Name: "a", Weight: 1, DocumentRoot: "x"}, {Name: "a", Weight: 1, DocumentRoot: "y"}}},
+		{"missing document root", []config.VariantConfig{{Name: "a", Weight: 1}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.variants); err == nil {
+				t.Error("Expected an error, got nil")
+			}
+		})
+	}
+}
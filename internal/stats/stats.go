@@ -0,0 +1,410 @@
+// Package stats collects aggregate request counters and persists them to disk.
+//
+// Counters are kept in memory and appended to a JSON Lines file as one
+// Snapshot per flush interval. Snapshots can be loaded and merged later, which
+// is what `volk stats` does to report on a time range without a running server.
+package stats
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MaxPaths is the number of distinct paths counted per snapshot. Requests for
+// further paths are counted under OtherPaths, so scanners probing random URLs
+// cannot grow the counters without bound.
+const MaxPaths = 1000
+
+// OtherPaths is the key counting requests for paths beyond MaxPaths.
+const OtherPaths = "(other)"
+
+// MaxHosts is the number of distinct Host headers counted per snapshot. Like
+// paths, the Host header is chosen by the client, and further hosts are
+// counted under OtherHosts.
+const MaxHosts = 100
+
+// OtherHosts is the key counting requests for hosts beyond MaxHosts.
+const OtherHosts = "(other)"
+
+// MaxReferrers is the number of distinct referrer hosts counted per snapshot.
+// Beacons report referrers for the client, and further hosts are counted
+// under OtherHosts.
+const MaxReferrers = 100
+
+// Group holds the counters of the requests for a host or a location.
+type Group struct {
+	Requests      int64 `json:"requests"`
+	RequestBytes  int64 `json:"request_bytes"`
+	ResponseBytes int64 `json:"response_bytes"`
+}
+
+// Merge adds the counters of other to g.
+func (g *Group) Merge(other Group) {
+	g.Requests += other.Requests
+	g.RequestBytes += other.RequestBytes
+	g.ResponseBytes += other.ResponseBytes
+}
+
+// Snapshot holds the counters of a time interval.
+type Snapshot struct {
+	Start    time.Time        `json:"start"`
+	End      time.Time        `json:"end"`
+	Requests int64            `json:"requests"`
+	Bytes    int64            `json:"bytes"`
+	Statuses map[string]int64 `json:"statuses,omitempty"`
+	Paths    map[string]int64 `json:"paths,omitempty"`
+
+	RequestBytes int64            `json:"request_bytes,omitempty"`
+	Hosts        map[string]Group `json:"hosts,omitempty"`
+	Locations    map[string]Group `json:"locations,omitempty"`
+
+	PageViews map[string]int64 `json:"page_views,omitempty"` // Pages reported by beacons
+	Referrers map[string]int64 `json:"referrers,omitempty"`  // Hosts of the referrers reported by beacons
+}
+
+// newSnapshot creates an empty snapshot starting at the given time.
+func newSnapshot(start time.Time) Snapshot {
+	return Snapshot{
+		Start:     start,
+		Statuses:  map[string]int64{},
+		Paths:     map[string]int64{},
+		Hosts:     map[string]Group{},
+		Locations: map[string]Group{},
+		PageViews: map[string]int64{},
+		Referrers: map[string]int64{},
+	}
+}
+
+// Merge adds the counters of other to s and widens its time range.
+func (s *Snapshot) Merge(other Snapshot) {
+	if s.Statuses == nil {
+		s.Statuses = map[string]int64{}
+	}
+	if s.Paths == nil {
+		s.Paths = map[string]int64{}
+	}
+	if s.Hosts == nil {
+		s.Hosts = map[string]Group{}
+	}
+	if s.Locations == nil {
+		s.Locations = map[string]Group{}
+	}
+	if s.PageViews == nil {
+		s.PageViews = map[string]int64{}
+	}
+	if s.Referrers == nil {
+		s.Referrers = map[string]int64{}
+	}
+	if s.Start.IsZero() || other.Start.Before(s.Start) {
+		s.Start = other.Start
+	}
+	if other.End.After(s.End) {
+		s.End = other.End
+	}
+
+	s.Requests += other.Requests
+	s.Bytes += other.Bytes
+	for status, n := range other.Statuses {
+		s.Statuses[status] += n
+	}
+	for path, n := range other.Paths {
+		s.Paths[path] += n
+	}
+	s.RequestBytes += other.RequestBytes
+	for host, group := range other.Hosts {
+		merged := s.Hosts[host]
+		merged.Merge(group)
+		s.Hosts[host] = merged
+	}
+	for location, group := range other.Locations {
+		merged := s.Locations[location]
+		merged.Merge(group)
+		s.Locations[location] = merged
+	}
+	for page, n := range other.PageViews {
+		s.PageViews[page] += n
+	}
+	for referrer, n := range other.Referrers {
+		s.Referrers[referrer] += n
+	}
+}
+
+// Count is a key with its counter, used for sorted reports.
+type Count struct {
+	Key   string
+	Count int64
+}
+
+// TopPaths returns the n most requested paths, most requested first.
+func (s Snapshot) TopPaths(n int) []Count {
+	return top(s.Paths, n)
+}
+
+// TopPageViews returns the n pages with the most beacon views, most viewed first.
+func (s Snapshot) TopPageViews(n int) []Count {
+	return top(s.PageViews, n)
+}
+
+// TopReferrers returns the n referrer hosts reported most by beacons.
+func (s Snapshot) TopReferrers(n int) []Count {
+	return top(s.Referrers, n)
+}
+
+// StatusBreakdown returns the request count per status code, ordered by status code.
+func (s Snapshot) StatusBreakdown() []Count {
+	counts := top(s.Statuses, len(s.Statuses))
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Key < counts[j].Key })
+	return counts
+}
+
+// GroupCount is a host or location with its counters, used for sorted reports.
+type GroupCount struct {
+	Key string
+	Group
+}
+
+// TopHosts returns the n most requested hosts, most requested first.
+func (s Snapshot) TopHosts(n int) []GroupCount {
+	counts := groups(s.Hosts)
+	if len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+// LocationBreakdown returns the counters per location, most requested first.
+func (s Snapshot) LocationBreakdown() []GroupCount {
+	return groups(s.Locations)
+}
+
+// groups returns the groups of m, most requested first, ties broken by key.
+func groups(m map[string]Group) []GroupCount {
+	counts := make([]GroupCount, 0, len(m))
+	for key, group := range m {
+		counts = append(counts, GroupCount{Key: key, Group: group})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Requests != counts[j].Requests {
+			return counts[i].Requests > counts[j].Requests
+		}
+		return counts[i].Key < counts[j].Key
+	})
+	return counts
+}
+
+// top returns the n largest counters of m, ties broken by key.
+func top(m map[string]int64, n int) []Count {
+	counts := make([]Count, 0, len(m))
+	for key, count := range m {
+		counts = append(counts, Count{Key: key, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Key < counts[j].Key
+	})
+	if len(counts) > n {
+		counts = counts[:n]
+	}
+	return counts
+}
+
+// Collector accumulates counters in memory and flushes them to a file.
+type Collector struct {
+	path string
+
+	mu      sync.Mutex
+	current Snapshot
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewCollector creates a Collector that appends snapshots to the file at path.
+func NewCollector(path string) *Collector {
+	return &Collector{
+		path:    path,
+		current: newSnapshot(time.Now()),
+	}
+}
+
+// Entry describes an answered request, for Add.
+type Entry struct {
+	Path          string
+	Host          string // Host the request was for, empty if unknown
+	Location      string // Path of the configured location the request matched, empty if none
+	Status        int
+	RequestBytes  int // Size of the request read, head and body
+	ResponseBytes int // Size of the response written
+}
+
+// Record counts a request for the path that was answered with the status code
+// and the number of bytes written.
+func (c *Collector) Record(path string, status int, bytes int) {
+	c.Add(Entry{Path: path, Status: status, ResponseBytes: bytes})
+}
+
+// Add counts an answered request, also under its host and location when set.
+func (c *Collector) Add(e Entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.current.Requests++
+	c.current.Bytes += int64(e.ResponseBytes)
+	c.current.RequestBytes += int64(e.RequestBytes)
+	c.current.Statuses[strconv.Itoa(e.Status)]++
+	path := e.Path
+	if _, ok := c.current.Paths[path]; !ok && len(c.current.Paths) >= MaxPaths {
+		path = OtherPaths
+	}
+	c.current.Paths[path]++
+
+	group := Group{Requests: 1, RequestBytes: int64(e.RequestBytes), ResponseBytes: int64(e.ResponseBytes)}
+	if host := e.Host; host != "" {
+		if _, ok := c.current.Hosts[host]; !ok && len(c.current.Hosts) >= MaxHosts {
+			host = OtherHosts
+		}
+		merged := c.current.Hosts[host]
+		merged.Merge(group)
+		c.current.Hosts[host] = merged
+	}
+	// Locations come from the configuration, so they need no limit.
+	if e.Location != "" {
+		merged := c.current.Locations[e.Location]
+		merged.Merge(group)
+		c.current.Locations[e.Location] = merged
+	}
+}
+
+// View counts a page view reported by a beacon, and the host of its referrer
+// unless it is empty. Like paths, pages beyond MaxPaths are counted under
+// OtherPaths.
+func (c *Collector) View(page, referrer string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.current.PageViews[page]; !ok && len(c.current.PageViews) >= MaxPaths {
+		page = OtherPaths
+	}
+	c.current.PageViews[page]++
+	if referrer != "" {
+		if _, ok := c.current.Referrers[referrer]; !ok && len(c.current.Referrers) >= MaxReferrers {
+			referrer = OtherHosts
+		}
+		c.current.Referrers[referrer]++
+	}
+}
+
+// Start flushes the counters every interval until Stop is called.
+func (c *Collector) Start(interval time.Duration) {
+	c.stop = make(chan struct{})
+	c.done = make(chan struct{})
+
+	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := c.Flush(); err != nil {
+					fmt.Fprintf(os.Stderr, "Error flushing statistics: %v\n", err)
+				}
+			case <-c.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the periodic flush started by Start and flushes the remaining counters.
+func (c *Collector) Stop() error {
+	if c.stop != nil {
+		close(c.stop)
+		<-c.done
+		c.stop = nil
+	}
+	return c.Flush()
+}
+
+// Flush appends the counters collected since the last flush to the file and resets them.
+// Nothing is written if no requests or page views were recorded.
+func (c *Collector) Flush() error {
+	c.mu.Lock()
+	snapshot := c.current
+	now := time.Now()
+	c.current = newSnapshot(now)
+	c.mu.Unlock()
+
+	if snapshot.Requests == 0 && len(snapshot.PageViews) == 0 {
+		return nil
+	}
+	snapshot.End = now
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("error encoding statistics: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return fmt.Errorf("error creating statistics directory: %w", err)
+	}
+	f, err := os.OpenFile(c.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening statistics file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("error writing statistics: %w", err)
+	}
+	return nil
+}
+
+// Load reads the snapshots stored at path and merges those that end after since.
+// A zero since merges all snapshots. A missing file yields an empty snapshot.
+func Load(path string, since time.Time) (Snapshot, error) {
+	total := newSnapshot(time.Time{})
+
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return total, nil
+		}
+		return total, fmt.Errorf("error opening statistics file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var snapshot Snapshot
+		if err := json.Unmarshal(scanner.Bytes(), &snapshot); err != nil {
+			return total, fmt.Errorf("error decoding statistics file %s line %d: %w", path, line, err)
+		}
+		if !since.IsZero() && snapshot.End.Before(since) {
+			continue
+		}
+		total.Merge(snapshot)
+	}
+	if err := scanner.Err(); err != nil {
+		return total, fmt.Errorf("error reading statistics file: %w", err)
+	}
+
+	return total, nil
+}
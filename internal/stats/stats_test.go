@@ -0,0 +1,174 @@
+package stats
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCollectorFlushAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.jsonl")
+	collector := NewCollector(path)
+
+	collector.Record("/", 200, 100)
+	collector.Record("/", 200, 100)
+	collector.Record("/missing", 404, 20)
+	if err := collector.Flush(); err != nil {
+		t.Fatalf("Flush returned an error: %v", err)
+	}
+
+	// A flush without requests must not write an empty snapshot.
+	if err := collector.Flush(); err != nil {
+		t.Fatalf("Flush returned an error: %v", err)
+	}
+
+	collector.Record("/about", 200, 50)
+	if err := collector.Stop(); err != nil {
+		t.Fatalf("Stop returned an error: %v", err)
+	}
+
+	snapshot, err := Load(path, time.Time{})
+	if err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+
+	if snapshot.Requests != 4 {
+		t.Errorf("Expected 4 requests, got %d", snapshot.Requests)
+	}
+	if snapshot.Bytes != 270 {
+		t.Errorf("Expected 270 bytes, got %d", snapshot.Bytes)
+	}
+
+	breakdown := snapshot.StatusBreakdown()
+	if len(breakdown) != 2 || breakdown[0] != (Count{"200", 3}) || breakdown[1] != (Count{"404", 1}) {
+		t.Errorf("Unexpected status breakdown: %v", breakdown)
+	}
+
+	top := snapshot.TopPaths(1)
+	if len(top) != 1 || top[0] != (Count{"/", 2}) {
+		t.Errorf("Unexpected top paths: %v", top)
+	}
+}
+
+func TestLoadSince(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.jsonl")
+	collector := NewCollector(path)
+	collector.Record("/", 200, 1)
+	if err := collector.Flush(); err != nil {
+		t.Fatalf("Flush returned an error: %v", err)
+	}
+
+	snapshot, err := Load(path, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+	if snapshot.Requests != 0 {
+		t.Errorf("Expected snapshots before since to be skipped, got %d requests", snapshot.Requests)
+	}
+
+	snapshot, err = Load(filepath.Join(t.TempDir(), "missing.jsonl"), time.Time{})
+	if err != nil || snapshot.Requests != 0 {
+		t.Errorf("Expected an empty snapshot for a missing file, got %d requests, error %v", snapshot.Requests, err)
+	}
+}
+
+func TestRecordLimitsPaths(t *testing.T) {
+	collector := NewCollector(filepath.Join(t.TempDir(), "stats.jsonl"))
+	for i := 0; i < MaxPaths+5; i++ {
+		collector.Record("/"+string(rune('a'+i%26))+time.Duration(i).String(), 404, 0)
+	}
+
+	if len(collector.current.Paths) != MaxPaths+1 {
+		t.Errorf("Expected %d distinct paths, got %d", MaxPaths+1, len(collector.current.Paths))
+	}
+	if collector.current.Paths[OtherPaths] != 5 {
+		t.Errorf("Expected 5 requests counted as %s, got %d", OtherPaths, collector.current.Paths[OtherPaths])
+	}
+}
+
+func TestAddHostsAndLocations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.jsonl")
+	collector := NewCollector(path)
+	collector.Add(Entry{Path: "/", Host: "example.com", Status: 200, RequestBytes: 80, ResponseBytes: 1000})
+	collector.Add(Entry{Path: "/api/items", Host: "example.com", Location: "/api/", Status: 200, RequestBytes: 120, ResponseBytes: 300})
+	collector.Add(Entry{Path: "/api/items", Host: "api.example.com", Location: "/api/", Status: 500, RequestBytes: 100, ResponseBytes: 50})
+	if err := collector.Stop(); err != nil {
+		t.Fatalf("Stop returned an error: %v", err)
+	}
+
+	snapshot, err := Load(path, time.Time{})
+	if err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+	if snapshot.RequestBytes != 300 {
+		t.Errorf("Expected 300 request bytes, got %d", snapshot.RequestBytes)
+	}
+
+	hosts := snapshot.TopHosts(10)
+	expectedHosts := []GroupCount{
+		{"example.com", Group{Requests: 2, RequestBytes: 200, ResponseBytes: 1300}},
+		{"api.example.com", Group{Requests: 1, RequestBytes: 100, ResponseBytes: 50}},
+	}
+	if len(hosts) != len(expectedHosts) || hosts[0] != expectedHosts[0] || hosts[1] != expectedHosts[1] {
+		t.Errorf("Expected hosts %v, got %v", expectedHosts, hosts)
+	}
+
+	locations := snapshot.LocationBreakdown()
+	expectedLocation := GroupCount{"/api/", Group{Requests: 2, RequestBytes: 220, ResponseBytes: 350}}
+	if len(locations) != 1 || locations[0] != expectedLocation {
+		t.Errorf("Expected locations %v, got %v", []GroupCount{expectedLocation}, locations)
+	}
+}
+
+func TestAddLimitsHosts(t *testing.T) {
+	collector := NewCollector(filepath.Join(t.TempDir(), "stats.jsonl"))
+	for i := 0; i < MaxHosts+5; i++ {
+		collector.Add(Entry{Path: "/", Host: time.Duration(i).String() + ".example.com", Status: 200})
+	}
+
+	if len(collector.current.Hosts) != MaxHosts+1 {
+		t.Errorf("Expected %d distinct hosts, got %d", MaxHosts+1, len(collector.current.Hosts))
+	}
+	if collector.current.Hosts[OtherHosts].Requests != 5 {
+		t.Errorf("Expected 5 requests counted as %s, got %d", OtherHosts, collector.current.Hosts[OtherHosts].Requests)
+	}
+}
+
+func TestViews(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "stats.jsonl")
+	collector := NewCollector(path)
+	collector.View("/", "news.example.com")
+	collector.View("/", "")
+	collector.View("/about", "news.example.com")
+	collector.View("/blog/", "search.example")
+	if err := collector.Stop(); err != nil {
+		t.Fatalf("Stop returned an error: %v", err)
+	}
+
+	snapshot, err := Load(path, time.Time{})
+	if err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+	if pages := snapshot.TopPageViews(1); len(pages) != 1 || pages[0] != (Count{"/", 2}) {
+		t.Errorf("Expected / with 2 views, got %v", pages)
+	}
+	expectedReferrers := []Count{{"news.example.com", 2}, {"search.example", 1}}
+	if referrers := snapshot.TopReferrers(10); !slices.Equal(referrers, expectedReferrers) {
+		t.Errorf("Expected referrers %v, got %v", expectedReferrers, referrers)
+	}
+}
+
+func TestViewLimitsReferrers(t *testing.T) {
+	collector := NewCollector(filepath.Join(t.TempDir(), "stats.jsonl"))
+	for i := 0; i < MaxReferrers+5; i++ {
+		collector.View("/", time.Duration(i).String()+".example.com")
+	}
+
+	if len(collector.current.Referrers) != MaxReferrers+1 {
+		t.Errorf("Expected %d distinct referrers, got %d", MaxReferrers+1, len(collector.current.Referrers))
+	}
+	if collector.current.Referrers[OtherHosts] != 5 {
+		t.Errorf("Expected 5 views counted as %s, got %d", OtherHosts, collector.current.Referrers[OtherHosts])
+	}
+}
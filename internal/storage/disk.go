@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Disk stores objects as files below a directory, the key being the path of
+// a file relative to it.
+type Disk struct {
+	dir string
+}
+
+// NewDisk creates a Disk storing objects in dir, which is created if needed.
+func NewDisk(dir string) (*Disk, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("error creating storage directory: %w", err)
+	}
+	return &Disk{dir: dir}, nil
+}
+
+// Path returns the path of the file holding the object key.
+func (d *Disk) Path(key string) string {
+	return filepath.Join(d.dir, filepath.FromSlash(key))
+}
+
+// Put writes the object to a temporary file renamed over the old one, so
+// readers never see it half written.
+func (d *Disk) Put(key string, r io.Reader, size int64) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	filePath := d.Path(key)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0o755); err != nil {
+		return fmt.Errorf("error storing %s: %w", key, err)
+	}
+	file, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".*")
+	if err != nil {
+		return fmt.Errorf("error storing %s: %w", key, err)
+	}
+	defer os.Remove(file.Name())
+
+	n, err := io.Copy(file, io.LimitReader(r, size))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && n != size {
+		err = io.ErrUnexpectedEOF
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), 0o644)
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), filePath)
+	}
+	if err != nil {
+		return fmt.Errorf("error storing %s: %w", key, err)
+	}
+	return nil
+}
+
+func (d *Disk) Get(key string) (io.ReadCloser, error) {
+	if err := checkKey(key); err != nil {
+		return nil, err
+	}
+	file, err := os.Open(d.Path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", key, err)
+	}
+	return file, nil
+}
+
+func (d *Disk) Delete(key string) error {
+	if err := checkKey(key); err != nil {
+		return err
+	}
+	if err := os.Remove(d.Path(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("error deleting %s: %w", key, err)
+	}
+	return nil
+}
+
+// List walks the directory the prefix ends in, and the directories below it.
+// Temporary files of objects being put are left out.
+func (d *Disk) List(prefix string) ([]Object, error) {
+	start := path.Dir(prefix)
+	if strings.HasSuffix(prefix, "/") {
+		start = strings.TrimSuffix(prefix, "/")
+	}
+	if start != "." {
+		if err := checkKey(start); err != nil {
+			return nil, err
+		}
+	}
+
+	var objects []Object
+	err := filepath.WalkDir(d.Path(start), func(filePath string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			return nil
+		}
+		rel, err := filepath.Rel(d.dir, filePath)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{Key: key, Size: info.Size(), Modified: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing %s: %w", prefix, err)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (d *Disk) Append(key string, r io.Reader) (int64, error) {
+	if err := checkKey(key); err != nil {
+		return 0, err
+	}
+	file, err := os.OpenFile(d.Path(key), os.O_WRONLY|os.O_APPEND, 0)
+	if errors.Is(err, fs.ErrNotExist) {
+		return 0, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error appending to %s: %w", key, err)
+	}
+	n, err := io.Copy(file, r)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return n, fmt.Errorf("error appending to %s: %w", key, err)
+	}
+	return n, nil
+}
@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// testStorage puts, lists, reads and deletes objects in s, which must be empty.
+func testStorage(t *testing.T, s Storage) {
+	t.Helper()
+	objects := map[string]string{"a": "first", "b/c": "second", "b/d": "", "bc": "third"}
+	for key, data := range objects {
+		if err := s.Put(key, strings.NewReader(data), int64(len(data))); err != nil {
+			t.Fatalf("Put(%q) returned an error: %v", key, err)
+		}
+	}
+	if err := s.Put("a", strings.NewReader("replaced"), 8); err != nil {
+		t.Fatalf("Put returned an error replacing an object: %v", err)
+	}
+	if err := s.Put("short", strings.NewReader("abc"), 5); err == nil {
+		t.Errorf("Expected an error putting fewer bytes than announced")
+	}
+
+	listings := []struct {
+		prefix string
+		keys   string
+	}{
+		{"", "a b/c b/d bc"},
+		{"b", "b/c b/d bc"},
+		{"b/", "b/c b/d"},
+		{"b/c", "b/c"},
+		{"x", ""},
+	}
+	for _, listing := range listings {
+		listed, err := s.List(listing.prefix)
+		if err != nil {
+			t.Fatalf("List(%q) returned an error: %v", listing.prefix, err)
+		}
+		var keys []string
+		for _, object := range listed {
+			keys = append(keys, object.Key)
+		}
+		if got := strings.Join(keys, " "); got != listing.keys {
+			t.Errorf("Expected List(%q) to return %q, got %q", listing.prefix, listing.keys, got)
+		}
+	}
+
+	r, err := s.Get("a")
+	if err != nil {
+		t.Fatalf("Get returned an error: %v", err)
+	}
+	if data, _ := io.ReadAll(r); string(data) != "replaced" {
+		t.Errorf("Expected replaced, got %q", data)
+	}
+	r.Close()
+
+	for key := range objects {
+		if err := s.Delete(key); err != nil {
+			t.Errorf("Delete(%q) returned an error: %v", key, err)
+		}
+	}
+	if err := s.Delete("a"); err != nil {
+		t.Errorf("Expected no error deleting a missing object, got %v", err)
+	}
+	if _, err := s.Get("a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestDisk(t *testing.T) {
+	d, err := NewDisk(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStorage(t, d)
+
+	if err := d.Put("log", strings.NewReader("abc"), 3); err != nil {
+		t.Fatal(err)
+	}
+	if n, err := d.Append("log", strings.NewReader("def")); err != nil || n != 3 {
+		t.Errorf("Expected 3 bytes appended, got %d (%v)", n, err)
+	}
+	if listed, _ := d.List("log"); len(listed) != 1 || listed[0].Size != 6 {
+		t.Errorf("Expected a 6 byte object, got %v", listed)
+	}
+	if _, err := d.Append("missing", strings.NewReader("x")); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestCheckKey(t *testing.T) {
+	tests := []struct {
+		key   string
+		valid bool
+	}{
+		{"a", true},
+		{"uploads/a.info", true},
+		{"", false},
+		{"/etc/passwd", false},
+		{"../a", false},
+		{"a/../../b", false},
+		{"a//b", false},
+		{"a/./b", false},
+		{"a\\b", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			if err := checkKey(tt.key); (err == nil) != tt.valid {
+				t.Errorf("Expected valid %v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+// Package storage keeps objects, named by slash-separated keys, on the local
+// disk or in an S3-compatible object store, so that what volk receives can be
+// written where a deployment keeps its files.
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+var (
+	ErrNotFound   = errors.New("object not found")
+	ErrInvalidKey = errors.New("invalid object key")
+)
+
+// Object describes a stored object.
+type Object struct {
+	Key      string
+	Size     int64
+	Modified time.Time
+}
+
+// Storage stores objects. Put replaces an object as a whole, so readers see
+// either the old or the new content.
+type Storage interface {
+	// Put stores the size bytes read from r as the object key.
+	Put(key string, r io.Reader, size int64) error
+	// Get opens the object key, failing with ErrNotFound if there is none.
+	// The caller must close it.
+	Get(key string) (io.ReadCloser, error)
+	// Delete removes the object key. Removing a missing object is no error.
+	Delete(key string) error
+	// List returns the objects whose keys start with prefix, sorted by key.
+	List(prefix string) ([]Object, error)
+}
+
+// Appender is implemented by storages that can append to an object in place,
+// which saves writing an object in parts and joining them.
+type Appender interface {
+	// Append adds what is read from r to the end of the object key, which
+	// must exist, and returns the number of bytes added.
+	Append(key string, r io.Reader) (int64, error)
+}
+
+// New opens the storage configured by cfg. Disk storage keeps the objects in
+// dir.
+func New(cfg config.StorageConfig, dir string) (Storage, error) {
+	switch cfg.Type {
+	case "", "disk":
+		return NewDisk(dir)
+	case "s3":
+		return NewS3(cfg)
+	default:
+		return nil, fmt.Errorf("unknown storage type %q", cfg.Type)
+	}
+}
+
+// checkKey rejects keys that are empty or could name an object outside of
+// the storage: absolute ones, and ones with empty, . or .. segments.
+func checkKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.ContainsAny(key, "\\\x00") {
+		return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("%w: %q", ErrInvalidKey, key)
+		}
+	}
+	return nil
+}
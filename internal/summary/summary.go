@@ -0,0 +1,268 @@
+// Package summary logs a periodic digest of the requests answered over a
+// sliding window: the busiest paths, the distribution of status codes and
+// latency percentiles.
+//
+// The window is a ring of slots, one per logging interval, so a summary
+// every minute can cover the last five minutes without keeping individual
+// requests. Latencies are counted in a fixed log-linear histogram, which
+// makes recording a request a few increments under a mutex and bounds the
+// error of a percentile to the width of its bucket, about 19%.
+package summary
+
+import (
+	"fmt"
+	"log"
+	"math/bits"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaxPaths is the number of distinct paths counted per slot. Requests for
+// further paths are counted under OtherPaths, so scanners probing random URLs
+// cannot grow the counters without bound.
+const MaxPaths = 1000
+
+// OtherPaths is the key counting requests for paths beyond MaxPaths.
+const OtherPaths = "(other)"
+
+// subBuckets is the number of histogram buckets per power of two.
+const subBuckets = 4
+
+// histogramBuckets covers latencies up to 2^42 microseconds, about 50 days.
+const histogramBuckets = 41 * subBuckets
+
+// slot holds the counters of one interval.
+type slot struct {
+	requests  int64
+	statuses  map[int]int64
+	paths     map[string]int64
+	latencies [histogramBuckets]int64
+}
+
+func newSlot() *slot {
+	return &slot{statuses: map[int]int64{}, paths: map[string]int64{}}
+}
+
+// Window counts requests over the last few intervals. It is safe for
+// concurrent use.
+type Window struct {
+	interval time.Duration
+	top      int
+
+	mu      sync.Mutex
+	slots   []*slot // Ring of slots; current is being filled
+	current int
+	filled  int // Slots holding a whole interval, at most len(slots)-1
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New creates a Window logging every interval a summary of the requests of
+// the last window, which is rounded up to a whole number of intervals, with
+// the top busiest paths.
+func New(interval, window time.Duration, top int) (*Window, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("summary interval must be positive")
+	}
+	if window < interval {
+		window = interval
+	}
+	n := int((window + interval - 1) / interval)
+	w := &Window{interval: interval, top: top, slots: make([]*slot, n)}
+	for i := range w.slots {
+		w.slots[i] = newSlot()
+	}
+	return w, nil
+}
+
+// Observe counts a request for the path answered with the status code after
+// the given duration.
+func (w *Window) Observe(path string, status int, duration time.Duration) {
+	bucket := latencyBucket(duration)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	s := w.slots[w.current]
+	s.requests++
+	s.statuses[status]++
+	if _, ok := s.paths[path]; !ok && len(s.paths) >= MaxPaths {
+		path = OtherPaths
+	}
+	s.paths[path]++
+	s.latencies[bucket]++
+}
+
+// latencyBucket returns the histogram bucket of a duration. Buckets split
+// each power of two microseconds into subBuckets equal parts.
+func latencyBucket(d time.Duration) int {
+	us := uint64(max(d.Microseconds(), 0))
+	if us < subBuckets {
+		return int(us)
+	}
+	exp := bits.Len64(us) - 1 // us is in [2^exp, 2^(exp+1))
+	sub := int(us>>(exp-2)) & (subBuckets - 1)
+	bucket := (exp-1)*subBuckets + sub
+	return min(bucket, histogramBuckets-1)
+}
+
+// bucketUpper returns the largest duration counted in a bucket.
+func bucketUpper(bucket int) time.Duration {
+	if bucket < subBuckets {
+		return time.Duration(bucket) * time.Microsecond
+	}
+	exp := bucket/subBuckets + 1
+	sub := bucket % subBuckets
+	lower := uint64(1)<<exp + uint64(sub)<<(exp-2)
+	return time.Duration(lower+uint64(1)<<(exp-2)-1) * time.Microsecond
+}
+
+// Summary is the digest of a window.
+type Summary struct {
+	Window   time.Duration
+	Requests int64
+	Statuses map[int]int64
+	Top      []PathCount // Busiest paths, most requested first
+	P50      time.Duration
+	P95      time.Duration
+	P99      time.Duration
+}
+
+// PathCount is the number of requests for a path.
+type PathCount struct {
+	Path     string
+	Requests int64
+}
+
+// Summary returns the digest of the requests of the current window, which
+// includes the interval being filled.
+func (w *Window) Summary() Summary {
+	w.mu.Lock()
+	merged := newSlot()
+	slots := 0
+	for i := 0; i <= w.filled; i++ {
+		s := w.slots[(w.current-i+len(w.slots))%len(w.slots)]
+		merged.requests += s.requests
+		for status, n := range s.statuses {
+			merged.statuses[status] += n
+		}
+		for path, n := range s.paths {
+			merged.paths[path] += n
+		}
+		for b, n := range s.latencies {
+			merged.latencies[b] += n
+		}
+		slots++
+	}
+	w.mu.Unlock()
+
+	summary := Summary{
+		Window:   time.Duration(slots) * w.interval,
+		Requests: merged.requests,
+		Statuses: merged.statuses,
+		P50:      percentile(&merged.latencies, merged.requests, 0.50),
+		P95:      percentile(&merged.latencies, merged.requests, 0.95),
+		P99:      percentile(&merged.latencies, merged.requests, 0.99),
+	}
+	for path, n := range merged.paths {
+		summary.Top = append(summary.Top, PathCount{Path: path, Requests: n})
+	}
+	sort.Slice(summary.Top, func(i, j int) bool {
+		if summary.Top[i].Requests != summary.Top[j].Requests {
+			return summary.Top[i].Requests > summary.Top[j].Requests
+		}
+		return summary.Top[i].Path < summary.Top[j].Path
+	})
+	if len(summary.Top) > w.top {
+		summary.Top = summary.Top[:w.top]
+	}
+	return summary
+}
+
+// percentile returns the upper bound of the bucket holding the q quantile of
+// the total observations in the histogram.
+func percentile(latencies *[histogramBuckets]int64, total int64, q float64) time.Duration {
+	if total == 0 {
+		return 0
+	}
+	rank := int64(q*float64(total) + 0.5)
+	rank = max(rank, 1)
+	var seen int64
+	for b, n := range latencies {
+		seen += n
+		if seen >= rank {
+			return bucketUpper(b)
+		}
+	}
+	return bucketUpper(histogramBuckets - 1)
+}
+
+// advance starts a new interval, dropping the oldest one from the window.
+func (w *Window) advance() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.current = (w.current + 1) % len(w.slots)
+	w.slots[w.current] = newSlot()
+	w.filled = min(w.filled+1, len(w.slots)-1)
+}
+
+// String formats the summary as a log line.
+func (s Summary) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d requests in %s", s.Requests, s.Window)
+	if s.Requests == 0 {
+		return b.String()
+	}
+
+	codes := make([]int, 0, len(s.Statuses))
+	for code := range s.Statuses {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+	b.WriteString(", statuses")
+	for _, code := range codes {
+		b.WriteString(" " + strconv.Itoa(code) + "=" + strconv.FormatInt(s.Statuses[code], 10))
+	}
+	fmt.Fprintf(&b, ", latency p50=%s p95=%s p99=%s", s.P50, s.P95, s.P99)
+	if len(s.Top) > 0 {
+		b.WriteString(", top paths")
+		for _, p := range s.Top {
+			fmt.Fprintf(&b, " %s=%d", p.Path, p.Requests)
+		}
+	}
+	return b.String()
+}
+
+// Start logs the summary at the end of every interval until Stop is called.
+func (w *Window) Start() {
+	w.stop = make(chan struct{})
+	w.done = make(chan struct{})
+
+	go func() {
+		defer close(w.done)
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				log.Printf("Summary: %s", w.Summary())
+				w.advance()
+			case <-w.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops the periodic summaries started by Start.
+func (w *Window) Stop() {
+	if w.stop != nil {
+		close(w.stop)
+		<-w.done
+		w.stop = nil
+	}
+}
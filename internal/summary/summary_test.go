@@ -0,0 +1,136 @@
+package summary
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLatencyBucket(t *testing.T) {
+	tests := []struct {
+		duration time.Duration
+		bucket   int
+	}{
+		{0, 0},
+		{3 * time.Microsecond, 3},
+		{4 * time.Microsecond, 4},
+		{7 * time.Microsecond, 7},
+		{8 * time.Microsecond, 8},
+		{9 * time.Microsecond, 8},
+		{10 * time.Microsecond, 9},
+		{-time.Second, 0},
+		{100000 * time.Hour, histogramBuckets - 1},
+	}
+
+	for _, tt := range tests {
+		if got := latencyBucket(tt.duration); got != tt.bucket {
+			t.Errorf("Expected bucket %d for %s, got %d", tt.bucket, tt.duration, got)
+		}
+	}
+
+	// Every duration falls in a bucket whose upper bound is not below it and
+	// at most 25% above it.
+	for us := int64(1); us < 1<<20; us = us*3/2 + 1 {
+		d := time.Duration(us) * time.Microsecond
+		upper := bucketUpper(latencyBucket(d))
+		if upper < d || float64(upper) > float64(d)*1.25 {
+			t.Errorf("Expected an upper bound close above %s, got %s", d, upper)
+		}
+	}
+}
+
+func TestSummary(t *testing.T) {
+	w, err := New(time.Minute, 2*time.Minute, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range 100 {
+		w.Observe("/index.html", 200, time.Duration(i+1)*time.Millisecond)
+	}
+	w.Observe("/app.js", 200, time.Millisecond)
+	w.Observe("/app.js", 304, time.Millisecond)
+	w.Observe("/missing", 404, time.Millisecond)
+
+	s := w.Summary()
+	if s.Requests != 103 {
+		t.Errorf("Expected 103 requests, got %d", s.Requests)
+	}
+	if s.Statuses[200] != 101 || s.Statuses[304] != 1 || s.Statuses[404] != 1 {
+		t.Errorf("Expected 101/1/1 for 200/304/404, got %v", s.Statuses)
+	}
+	if len(s.Top) != 2 || s.Top[0].Path != "/index.html" || s.Top[1].Path != "/app.js" {
+		t.Errorf("Expected top paths /index.html and /app.js, got %v", s.Top)
+	}
+
+	tests := []struct {
+		name string
+		got  time.Duration
+		want time.Duration
+	}{
+		{"p50", s.P50, 50 * time.Millisecond},
+		{"p95", s.P95, 95 * time.Millisecond},
+		{"p99", s.P99, 99 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if tt.got < tt.want*8/10 || tt.got > tt.want*12/10 {
+			t.Errorf("Expected %s near %s, got %s", tt.name, tt.want, tt.got)
+		}
+	}
+
+	line := s.String()
+	for _, part := range []string{"103 requests in 1m0s", "statuses 200=101 304=1 404=1", "top paths /index.html=100 /app.js=2"} {
+		if !strings.Contains(line, part) {
+			t.Errorf("Expected %q in %q", part, line)
+		}
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	w, err := New(time.Minute, 3*time.Minute, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []struct {
+		requests int
+		want     int64
+		window   time.Duration
+	}{
+		{1, 1, time.Minute},
+		{2, 3, 2 * time.Minute},
+		{4, 7, 3 * time.Minute},
+		{8, 14, 3 * time.Minute}, // The first interval left the window
+		{0, 12, 3 * time.Minute},
+	}
+
+	for i, step := range steps {
+		for range step.requests {
+			w.Observe("/", 200, time.Millisecond)
+		}
+		s := w.Summary()
+		if s.Requests != step.want {
+			t.Errorf("Interval %d: Expected %d requests, got %d", i, step.want, s.Requests)
+		}
+		if s.Window != step.window {
+			t.Errorf("Interval %d: Expected a window of %s, got %s", i, step.window, s.Window)
+		}
+		w.advance()
+	}
+}
+
+func TestMaxPaths(t *testing.T) {
+	w, err := New(time.Minute, 0, MaxPaths+1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range MaxPaths + 5 {
+		w.Observe("/"+strings.Repeat("x", i), 200, 0)
+	}
+	s := w.Summary()
+	if len(s.Top) != MaxPaths+1 {
+		t.Errorf("Expected %d paths, got %d", MaxPaths+1, len(s.Top))
+	}
+	if s.Top[0].Path != OtherPaths || s.Top[0].Requests != 5 {
+		t.Errorf("Expected %s first with 5 requests, got %v", OtherPaths, s.Top[0])
+	}
+}
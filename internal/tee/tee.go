@@ -0,0 +1,180 @@
+// Package tee copies served responses to a mirror in the background, to warm
+// a CDN or keep a backup origin in sync. A mirror is a directory, which ends
+// up holding a static copy of the site, or a URL the responses are PUT to
+// with their headers. Responses wait in a bounded queue; when the mirror
+// cannot keep up, they are dropped rather than slowing down the server, and
+// a response whose body has not changed since it was last pushed is skipped.
+package tee
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	nethttp "net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// DefaultQueueSize is the number of responses waiting for the mirror when the
+// configuration sets none.
+const DefaultQueueSize = 1000
+
+// hopHeaders describe a single connection and are not passed to the mirror.
+var hopHeaders = []string{"Connection", "Keep-Alive", "Transfer-Encoding", "Content-Length", "Trailer", "Upgrade"}
+
+// Response is a served response to copy.
+type Response struct {
+	Path    string // Request path, such as /blog/
+	Header  nethttp.Header
+	Body    []byte
+	Default string // File name directory paths are stored under, such as index.html
+}
+
+// Tee pushes responses to the configured mirrors.
+type Tee struct {
+	dir    string
+	url    string
+	token  string
+	client *nethttp.Client
+
+	queue  chan Response
+	done   chan struct{}
+	pushed map[string][sha256.Size]byte // Body hash last pushed, by path; used by run only
+}
+
+// New creates a Tee for cfg and starts pushing in the background, or returns
+// nil if cfg configures no mirror.
+func New(cfg config.TeeConfig) (*Tee, error) {
+	if cfg.Directory == "" && cfg.URL == "" {
+		return nil, nil
+	}
+	if cfg.Directory != "" {
+		if err := os.MkdirAll(cfg.Directory, 0o755); err != nil {
+			return nil, fmt.Errorf("error creating mirror directory: %w", err)
+		}
+	}
+	size := cfg.QueueSize
+	if size <= 0 {
+		size = DefaultQueueSize
+	}
+	t := &Tee{
+		dir:    cfg.Directory,
+		url:    strings.TrimSuffix(cfg.URL, "/"),
+		token:  cfg.Token,
+		client: &nethttp.Client{Timeout: 30 * time.Second},
+		queue:  make(chan Response, size),
+		done:   make(chan struct{}),
+		pushed: map[string][sha256.Size]byte{},
+	}
+	go t.run()
+	return t, nil
+}
+
+// Push queues resp for the mirrors. It never blocks, and reports false if
+// the queue is full and resp was dropped.
+func (t *Tee) Push(resp Response) bool {
+	select {
+	case t.queue <- resp:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close pushes the responses still queued and stops the Tee.
+func (t *Tee) Close() {
+	close(t.queue)
+	<-t.done
+}
+
+func (t *Tee) run() {
+	defer close(t.done)
+	for resp := range t.queue {
+		sum := sha256.Sum256(resp.Body)
+		if t.pushed[resp.Path] == sum {
+			continue
+		}
+
+		if err := t.push(resp); err != nil {
+			log.Printf("Error mirroring %s: %v", resp.Path, err)
+			continue
+		}
+		t.pushed[resp.Path] = sum
+	}
+}
+
+// push copies resp to every mirror.
+func (t *Tee) push(resp Response) error {
+	if t.dir != "" {
+		if err := t.write(resp); err != nil {
+			return err
+		}
+	}
+	if t.url != "" {
+		return t.put(resp)
+	}
+	return nil
+}
+
+// write stores the body of resp below the mirror directory.
+func (t *Tee) write(resp Response) error {
+	name := path.Clean("/" + resp.Path)
+	if strings.HasSuffix(resp.Path, "/") {
+		name = path.Join(name, resp.Default)
+	}
+	dest := filepath.Join(t.dir, filepath.FromSlash(name[1:]))
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return fmt.Errorf("error creating directory: %w", err)
+	}
+
+	// Replace the file with a rename, so the mirror never serves half a file.
+	tmp, err := os.CreateTemp(filepath.Dir(dest), ".tee-*")
+	if err != nil {
+		return fmt.Errorf("error creating file: %w", err)
+	}
+	_, err = tmp.Write(resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), dest)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("error writing file: %w", err)
+	}
+	return nil
+}
+
+// put sends resp to the mirror URL, at the same path.
+func (t *Tee) put(resp Response) error {
+	req, err := nethttp.NewRequest(nethttp.MethodPut, t.url+resp.Path, bytes.NewReader(resp.Body))
+	if err != nil {
+		return err
+	}
+	for name, values := range resp.Header {
+		if !slices.ContainsFunc(hopHeaders, func(hop string) bool { return strings.EqualFold(name, hop) }) {
+			req.Header[name] = values
+		}
+	}
+	if t.token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.token)
+	}
+
+	res, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("mirror answered %s", res.Status)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package tee
+
+import (
+	"io"
+	nethttp "net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestTeeDirectory(t *testing.T) {
+	dir := t.TempDir()
+	mirror, err := New(config.TeeConfig{Directory: dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mirror.Push(Response{Path: "/blog/", Body: []byte("blog"), Default: "index.html"})
+	mirror.Push(Response{Path: "/style.css", Body: []byte("v1")})
+	mirror.Push(Response{Path: "/../escape.txt", Body: []byte("x")})
+	mirror.Close()
+
+	tests := map[string]string{
+		"blog/index.html": "blog",
+		"style.css":       "v1",
+		"escape.txt":      "x",
+	}
+	for name, want := range tests {
+		t.Run(name, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+			if err != nil || string(data) != want {
+				t.Errorf("Expected %q, got %q (%v)", want, data, err)
+			}
+		})
+	}
+}
+
+func TestTeeSkipsUnchanged(t *testing.T) {
+	var mu sync.Mutex
+	var puts []string
+	server := httptest.NewServer(nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		puts = append(puts, r.Method+" "+r.URL.Path+" "+string(body)+" "+r.Header.Get("Content-Type")+" "+r.Header.Get("Authorization"))
+		mu.Unlock()
+		w.WriteHeader(204)
+	}))
+	defer server.Close()
+
+	mirror, err := New(config.TeeConfig{URL: server.URL + "/mirror/", Token: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	header := nethttp.Header{"Content-Type": {"text/css"}, "Connection": {"close"}}
+	mirror.Push(Response{Path: "/style.css", Header: header, Body: []byte("v1")})
+	mirror.Push(Response{Path: "/style.css", Header: header, Body: []byte("v1")})
+	mirror.Push(Response{Path: "/style.css", Header: header, Body: []byte("v2")})
+	mirror.Close()
+
+	want := []string{
+		"PUT /mirror/style.css v1 text/css Bearer secret",
+		"PUT /mirror/style.css v2 text/css Bearer secret",
+	}
+	if len(puts) != len(want) || puts[0] != want[0] || puts[1] != want[1] {
+		t.Errorf("Expected %q, got %q", want, puts)
+	}
+}
+
+func TestTeeDropsWhenFull(t *testing.T) {
+	mirror := &Tee{queue: make(chan Response, 1), done: make(chan struct{})}
+	if !mirror.Push(Response{Path: "/a"}) {
+		t.Error("Expected the first response to be queued")
+	}
+	if mirror.Push(Response{Path: "/b"}) {
+		t.Error("Expected the second response to be dropped")
+	}
+}
+
+func TestNewWithoutMirror(t *testing.T) {
+	if mirror, err := New(config.TeeConfig{QueueSize: 10}); mirror != nil || err != nil {
+		t.Errorf("Expected no Tee, got %v and %v", mirror, err)
+	}
+}
@@ -0,0 +1,271 @@
+// Package thumbnail resizes and crops JPEG, PNG and GIF images and caches the
+// results on disk.
+//
+// Given both a width and a height, an image is scaled to cover the box and
+// cropped to it around its center. Given only one of them, with the other 0,
+// it is scaled to that width or height keeping its aspect ratio. Images are
+// never scaled up.
+package thumbnail
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // Registers the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	jpegQuality = 85         // Quality JPEG thumbnails are encoded with
+	maxPixels   = 50_000_000 // Largest source image decoded, so a small file cannot claim huge amounts of memory
+)
+
+var (
+	ErrInvalidSize       = errors.New("invalid thumbnail size")
+	ErrUnsupportedFormat = errors.New("unsupported image format")
+)
+
+// Generator creates thumbnails of image files, caching them in a directory.
+type Generator struct {
+	cacheDir string
+	maxSize  int
+}
+
+// New creates a Generator that caches thumbnails in cacheDir and creates
+// thumbnails up to maxSize pixels wide and high.
+func New(cacheDir string, maxSize int) (*Generator, error) {
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("error creating thumbnail cache: %w", err)
+	}
+	return &Generator{cacheDir: cacheDir, maxSize: maxSize}, nil
+}
+
+// Thumbnail returns the thumbnail of the image file at path and its content
+// type. Thumbnails are cached by the path, size and modification time of the
+// file, so a changed image gets a new thumbnail; Purge drops them when a
+// deploy keeps both.
+func (g *Generator) Thumbnail(path string, width, height int) ([]byte, string, error) {
+	if width < 0 || height < 0 || width == 0 && height == 0 || width > g.maxSize || height > g.maxSize {
+		return nil, "", fmt.Errorf("%w: %dx%d", ErrInvalidSize, width, height)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, "", err
+	}
+
+	version := sha256.Sum256([]byte(fmt.Sprintf("%d\x00%d\x00%d\x00%d", info.Size(), info.ModTime().UnixNano(), width, height)))
+	cached := filepath.Join(g.cacheDir, sourceKey(path)+"-"+hex.EncodeToString(version[:16]))
+	if data, err := os.ReadFile(cached); err == nil {
+		return data, contentType(data), nil
+	}
+
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", err
+	}
+	data, err := Resize(src, width, height)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// Write to a temporary file first, so concurrent requests never read a partial thumbnail.
+	tmp, err := os.CreateTemp(g.cacheDir, "tmp-*")
+	if err == nil {
+		_, err = tmp.Write(data)
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+		if err == nil {
+			err = os.Rename(tmp.Name(), cached)
+		}
+		if err != nil {
+			os.Remove(tmp.Name())
+		}
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("error caching thumbnail: %w", err)
+	}
+	return data, contentType(data), nil
+}
+
+// sourceKey returns the start of the names of the cached thumbnails of the
+// image file at path.
+func sourceKey(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return hex.EncodeToString(sum[:16])
+}
+
+// Purge removes the cached thumbnails of the image file at path, or of every
+// file below it if it is a directory. With an empty path, every thumbnail is
+// removed. It returns the number of thumbnails removed.
+func (g *Generator) Purge(path string) (int, error) {
+	var names []string
+	if path == "" {
+		entries, err := os.ReadDir(g.cacheDir)
+		if err != nil {
+			return 0, fmt.Errorf("error reading thumbnail cache: %w", err)
+		}
+		for _, entry := range entries {
+			// Temporary files belong to thumbnails being written.
+			if !strings.HasPrefix(entry.Name(), "tmp-") {
+				names = append(names, filepath.Join(g.cacheDir, entry.Name()))
+			}
+		}
+	} else {
+		// Thumbnails of a file that was removed are found by its path alone.
+		sources := []string{path}
+		filepath.WalkDir(path, func(source string, d fs.DirEntry, err error) error {
+			if err == nil && !d.IsDir() && source != path {
+				sources = append(sources, source)
+			}
+			return nil
+		})
+		for _, source := range sources {
+			matches, _ := filepath.Glob(filepath.Join(g.cacheDir, sourceKey(source)+"-*"))
+			names = append(names, matches...)
+		}
+	}
+
+	removed := 0
+	for _, name := range names {
+		if err := os.Remove(name); err == nil {
+			removed++
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return removed, fmt.Errorf("error purging thumbnail: %w", err)
+		}
+	}
+	return removed, nil
+}
+
+// Resize decodes an image, scales and crops it to width and height as
+// described in the package documentation and encodes it again. JPEG images
+// stay JPEG; PNG and GIF images become PNG.
+func Resize(src []byte, width, height int) ([]byte, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(src))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("%w: image of %dx%d pixels is too large", ErrUnsupportedFormat, config.Width, config.Height)
+	}
+	img, format, err := image.Decode(bytes.NewReader(src))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedFormat, err)
+	}
+
+	bounds := img.Bounds()
+	crop, width, height := fit(bounds.Dx(), bounds.Dy(), width, height)
+	scaled := scale(img, crop.Add(bounds.Min), width, height)
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, scaled, &jpeg.Options{Quality: jpegQuality})
+	} else {
+		err = png.Encode(&buf, scaled)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error encoding thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// fit returns the part of a srcWidth x srcHeight image that is scaled, and the
+// size it is scaled to, for a requested width and height.
+func fit(srcWidth, srcHeight, width, height int) (image.Rectangle, int, int) {
+	switch {
+	case width == 0:
+		width = max(srcWidth*height/srcHeight, 1)
+	case height == 0:
+		height = max(srcHeight*width/srcWidth, 1)
+	}
+
+	// Crop the source to the aspect ratio of the thumbnail, around its center.
+	crop := image.Rect(0, 0, srcWidth, srcHeight)
+	if srcWidth*height > srcHeight*width {
+		w := srcHeight * width / height
+		crop.Min.X = (srcWidth - w) / 2
+		crop.Max.X = crop.Min.X + w
+	} else {
+		h := srcWidth * height / width
+		crop.Min.Y = (srcHeight - h) / 2
+		crop.Max.Y = crop.Min.Y + h
+	}
+
+	// Never scale up.
+	if width > crop.Dx() || height > crop.Dy() {
+		width, height = crop.Dx(), crop.Dy()
+	}
+	return crop, width, height
+}
+
+// scale scales the src part of img to width x height, averaging the source
+// pixels that fall into each destination pixel.
+func scale(img image.Image, src image.Rectangle, width, height int) image.Image {
+	rgba := image.NewNRGBA(src)
+	draw.Draw(rgba, src, img, src.Min, draw.Src)
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := src.Min.Y + y*src.Dy()/height
+		y1 := max(src.Min.Y+(y+1)*src.Dy()/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := src.Min.X + x*src.Dx()/width
+			x1 := max(src.Min.X+(x+1)*src.Dx()/width, x0+1)
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					c := rgba.NRGBAAt(sx, sy)
+					r += uint64(c.R)
+					g += uint64(c.G)
+					b += uint64(c.B)
+					a += uint64(c.A)
+					n++
+				}
+			}
+			dst.SetNRGBA(x, y, color.NRGBA{R: uint8(r / n), G: uint8(g / n), B: uint8(b / n), A: uint8(a / n)})
+		}
+	}
+	return dst
+}
+
+// contentType returns the content type of an encoded thumbnail.
+func contentType(data []byte) string {
+	if bytes.HasPrefix(data, []byte("\xff\xd8")) {
+		return "image/jpeg"
+	}
+	return "image/png"
+}
+
+// ParseSize parses a size such as 200x100, 200x or x100.
+func ParseSize(s string) (width, height int, err error) {
+	w, h, ok := strings.Cut(s, "x")
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: %s", ErrInvalidSize, s)
+	}
+	if width, err = parseDimension(w); err != nil {
+		return 0, 0, fmt.Errorf("%w: %s", ErrInvalidSize, s)
+	}
+	if height, err = parseDimension(h); err != nil {
+		return 0, 0, fmt.Errorf("%w: %s", ErrInvalidSize, s)
+	}
+	return width, height, nil
+}
+
+func parseDimension(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
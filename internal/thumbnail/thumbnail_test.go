@@ -0,0 +1,211 @@
+package thumbnail
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		name                  string
+		srcWidth, srcHeight   int
+		width, height         int
+		wantCrop              image.Rectangle
+		wantWidth, wantHeight int
+	}{
+		{"width only", 400, 200, 100, 0, image.Rect(0, 0, 400, 200), 100, 50},
+		{"height only", 400, 200, 0, 50, image.Rect(0, 0, 400, 200), 100, 50},
+		{"crop wide image", 400, 200, 100, 100, image.Rect(100, 0, 300, 200), 100, 100},
+		{"crop tall image", 200, 400, 100, 50, image.Rect(0, 150, 200, 250), 100, 50},
+		{"never scale up", 40, 20, 100, 0, image.Rect(0, 0, 40, 20), 40, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crop, width, height := fit(tt.srcWidth, tt.srcHeight, tt.width, tt.height)
+			if crop != tt.wantCrop || width != tt.wantWidth || height != tt.wantHeight {
+				t.Errorf("Expected %v scaled to %dx%d, got %v scaled to %dx%d", tt.wantCrop, tt.wantWidth, tt.wantHeight, crop, width, height)
+			}
+		})
+	}
+}
+
+func TestResize(t *testing.T) {
+	var jpg bytes.Buffer
+	jpeg.Encode(&jpg, image.NewRGBA(image.Rect(0, 0, 300, 200)), nil)
+
+	tests := []struct {
+		name                  string
+		src                   []byte
+		width, height         int
+		wantFormat            string
+		wantWidth, wantHeight int
+		err                   error
+	}{
+		{"png", encodePNG(t, 200, 100), 50, 50, "png", 50, 50, nil},
+		{"jpeg stays jpeg", jpg.Bytes(), 150, 0, "jpeg", 150, 100, nil},
+		{"not an image", []byte("hello"), 50, 50, "", 0, 0, ErrUnsupportedFormat},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := Resize(tt.src, tt.width, tt.height)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Expected error %v, got %v", tt.err, err)
+			}
+			if err != nil {
+				return
+			}
+			config, format, err := image.DecodeConfig(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("Expected an image, got %v", err)
+			}
+			if format != tt.wantFormat || config.Width != tt.wantWidth || config.Height != tt.wantHeight {
+				t.Errorf("Expected %s of %dx%d, got %s of %dx%d", tt.wantFormat, tt.wantWidth, tt.wantHeight, format, config.Width, config.Height)
+			}
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		size                  string
+		wantWidth, wantHeight int
+		wantErr               bool
+	}{
+		{"200x100", 200, 100, false},
+		{"200x", 200, 0, false},
+		{"x100", 0, 100, false},
+		{"200", 0, 0, true},
+		{"ax100", 0, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.size, func(t *testing.T) {
+			width, height, err := ParseSize(tt.size)
+			if (err != nil) != tt.wantErr || width != tt.wantWidth || height != tt.wantHeight {
+				t.Errorf("Expected %dx%d (error %v), got %dx%d (%v)", tt.wantWidth, tt.wantHeight, tt.wantErr, width, height, err)
+			}
+		})
+	}
+}
+
+func TestGeneratorCache(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "photo.png")
+	if err := os.WriteFile(src, encodePNG(t, 200, 100), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	generator, err := New(filepath.Join(dir, "cache"), 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	thumb := func() []byte {
+		t.Helper()
+		data, contentType, err := generator.Thumbnail(src, 20, 0)
+		if err != nil || contentType != "image/png" {
+			t.Fatalf("Expected a PNG thumbnail, got %q and %v", contentType, err)
+		}
+		return data
+	}
+
+	first := thumb()
+	entries, _ := os.ReadDir(filepath.Join(dir, "cache"))
+	if len(entries) != 1 {
+		t.Fatalf("Expected one cached thumbnail, got %d", len(entries))
+	}
+	if second := thumb(); !bytes.Equal(first, second) {
+		t.Error("Expected the cached thumbnail")
+	}
+
+	// A changed image gets a new thumbnail.
+	if err := os.WriteFile(src, encodePNG(t, 100, 100), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(src, later, later)
+	if third := thumb(); bytes.Equal(first, third) {
+		t.Error("Expected a new thumbnail for the changed image")
+	}
+
+	if _, _, err := generator.Thumbnail(src, 2000, 0); !errors.Is(err, ErrInvalidSize) {
+		t.Errorf("Expected ErrInvalidSize above the maximum, got %v", err)
+	}
+}
+
+func TestGeneratorPurge(t *testing.T) {
+	dir := t.TempDir()
+	photos := filepath.Join(dir, "photos")
+	if err := os.MkdirAll(photos, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sources := []string{filepath.Join(photos, "a.png"), filepath.Join(photos, "b.png"), filepath.Join(dir, "c.png")}
+	for _, src := range sources {
+		if err := os.WriteFile(src, encodePNG(t, 40, 40), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cacheDir := filepath.Join(dir, "cache")
+	generator, err := New(cacheDir, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, src := range sources {
+		for _, width := range []int{10, 20} {
+			if _, _, err := generator.Thumbnail(src, width, 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		wantRemoved int
+		wantLeft    int
+	}{
+		{"file", sources[0], 2, 4},
+		{"same file again", sources[0], 0, 4},
+		{"directory", photos, 2, 2},
+		{"everything", "", 2, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			removed, err := generator.Purge(tt.path)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if removed != tt.wantRemoved {
+				t.Errorf("Expected %d thumbnails removed, got %d", tt.wantRemoved, removed)
+			}
+			if entries, _ := os.ReadDir(cacheDir); len(entries) != tt.wantLeft {
+				t.Errorf("Expected %d thumbnails left, got %d", tt.wantLeft, len(entries))
+			}
+		})
+	}
+}
@@ -0,0 +1,111 @@
+// Package trap implements honeypot paths: paths no real visitor requests,
+// such as /wp-login.php on a site without WordPress, whose requests come from
+// scanners. Their clients are either held up by a slow response (tarpit) or
+// banned for a while.
+package trap
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// Actions a trap can take.
+const (
+	ActionTarpit = "tarpit" // Drip a response over delay seconds
+	ActionBan    = "ban"    // Deny every request of the client for ban_minutes
+)
+
+// Defaults for traps that leave the durations unset.
+const (
+	DefaultDelay      = 60
+	DefaultBanMinutes = 60
+)
+
+// Traps holds the trap rules and the clients they banned.
+type Traps struct {
+	rules []config.TrapConfig
+
+	mu     sync.Mutex
+	banned map[string]time.Time // Until when, by client
+}
+
+// New checks the trap rules and fills in their defaults.
+func New(rules []config.TrapConfig) (*Traps, error) {
+	t := &Traps{banned: map[string]time.Time{}}
+	for i, rule := range rules {
+		if !strings.HasPrefix(rule.Path, "/") {
+			return nil, fmt.Errorf("trap %d: path must start with /: %q", i+1, rule.Path)
+		}
+		if _, err := path.Match(rule.Path, ""); err != nil {
+			return nil, fmt.Errorf("invalid path in trap %d: %w", i+1, err)
+		}
+		switch rule.Action {
+		case ActionTarpit, ActionBan:
+		default:
+			return nil, fmt.Errorf("trap %d: unknown action %q", i+1, rule.Action)
+		}
+		if rule.Delay <= 0 {
+			rule.Delay = DefaultDelay
+		}
+		if rule.BanMinutes <= 0 {
+			rule.BanMinutes = DefaultBanMinutes
+		}
+		t.rules = append(t.rules, rule)
+	}
+	return t, nil
+}
+
+// Match returns the first trap whose path matches the URL path. Paths use
+// path.Match syntax, and a path ending in a slash matches everything below it.
+func (t *Traps) Match(urlPath string) (config.TrapConfig, bool) {
+	for _, rule := range t.rules {
+		if strings.HasSuffix(rule.Path, "/") {
+			if strings.HasPrefix(urlPath, rule.Path) {
+				return rule, true
+			}
+			continue
+		}
+		if ok, _ := path.Match(rule.Path, urlPath); ok {
+			return rule, true
+		}
+	}
+	return config.TrapConfig{}, false
+}
+
+// Ban denies the client until the given time.
+func (t *Traps) Ban(client string, until time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Drop expired bans now and then so the list does not grow without bound.
+	if len(t.banned) > 10000 {
+		now := time.Now()
+		for c, end := range t.banned {
+			if !now.Before(end) {
+				delete(t.banned, c)
+			}
+		}
+	}
+	t.banned[client] = until
+}
+
+// Banned reports whether the client is banned at the given time.
+func (t *Traps) Banned(client string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	until, ok := t.banned[client]
+	if !ok {
+		return false
+	}
+	if !now.Before(until) {
+		delete(t.banned, client)
+		return false
+	}
+	return true
+}
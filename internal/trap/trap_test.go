@@ -0,0 +1,93 @@
+package trap
+
+import (
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    config.TrapConfig
+		wantErr bool
+	}{
+		{"tarpit", config.TrapConfig{Path: "/wp-login.php", Action: ActionTarpit}, false},
+		{"ban", config.TrapConfig{Path: "/phpmyadmin/", Action: ActionBan, BanMinutes: 5}, false},
+		{"relative path", config.TrapConfig{Path: "wp-login.php", Action: ActionBan}, true},
+		{"invalid pattern", config.TrapConfig{Path: "/[", Action: ActionBan}, true},
+		{"unknown action", config.TrapConfig{Path: "/x", Action: "explode"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New([]config.TrapConfig{tt.rule})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	traps, err := New([]config.TrapConfig{
+		{Path: "/wp-login.php", Action: ActionTarpit},
+		{Path: "/phpmyadmin/", Action: ActionBan},
+		{Path: "/*.env", Action: ActionBan, BanMinutes: 10},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path     string
+		expected string
+		found    bool
+	}{
+		{"/wp-login.php", ActionTarpit, true},
+		{"/phpmyadmin/index.php", ActionBan, true},
+		{"/.env", ActionBan, true},
+		{"/blog/wp-login.php", "", false},
+		{"/index.html", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rule, ok := traps.Match(tt.path)
+			if ok != tt.found || rule.Action != tt.expected {
+				t.Errorf("Expected %q (found %v), got %q (found %v)", tt.expected, tt.found, rule.Action, ok)
+			}
+		})
+	}
+
+	// Unset durations get their defaults.
+	if rule, _ := traps.Match("/wp-login.php"); rule.Delay != DefaultDelay || rule.BanMinutes != DefaultBanMinutes {
+		t.Errorf("Expected the default durations, got %+v", rule)
+	}
+	if rule, _ := traps.Match("/.env"); rule.BanMinutes != 10 {
+		t.Errorf("Expected ban_minutes 10, got %d", rule.BanMinutes)
+	}
+}
+
+func TestBan(t *testing.T) {
+	traps, err := New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	traps.Ban("192.0.2.1", now.Add(time.Minute))
+
+	if !traps.Banned("192.0.2.1", now) {
+		t.Error("Expected the client to be banned")
+	}
+	if traps.Banned("192.0.2.2", now) {
+		t.Error("Expected another client not to be banned")
+	}
+	if traps.Banned("192.0.2.1", now.Add(time.Minute)) {
+		t.Error("Expected the ban to expire")
+	}
+	if len(traps.banned) != 0 {
+		t.Errorf("Expected the expired ban to be removed, got %v", traps.banned)
+	}
+}
@@ -0,0 +1,60 @@
+// Package upgrade replaces a running server process with a new binary without
+// closing its listening socket.
+//
+// The running process starts the new binary with the listener as an inherited
+// file descriptor and waits until the new process reports that it is ready.
+// Then the old process stops accepting connections, finishes the ones it is
+// handling and exits, so no connection is refused during the switch.
+package upgrade
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Environment variables passed to the new process.
+const (
+	listenerEnv = "VOLK_UPGRADE_LISTENER_FD"
+	readyEnv    = "VOLK_UPGRADE_READY_FD"
+)
+
+var (
+	ErrNotSupported = errors.New("upgrades are not supported on this platform")
+	ErrNotRunning   = errors.New("no running volk process found")
+)
+
+// WritePIDFile writes the ID of the current process to path.
+func WritePIDFile(path string) error {
+	if err := os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644); err != nil {
+		return fmt.Errorf("error writing pid file: %w", err)
+	}
+	return nil
+}
+
+// ReadPIDFile returns the process ID stored in path.
+func ReadPIDFile(path string) (int, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, fmt.Errorf("%w: %s does not exist", ErrNotRunning, path)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("error reading pid file: %w", err)
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("invalid pid file %s", path)
+	}
+	return pid, nil
+}
+
+// inheritedFD returns the file descriptor number stored in the environment variable, or -1.
+func inheritedFD(name string) int {
+	fd, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || fd < 3 {
+		return -1
+	}
+	return fd
+}
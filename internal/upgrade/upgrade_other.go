@@ -0,0 +1,31 @@
+//go:build !unix
+
+package upgrade
+
+import (
+	"net"
+	"os"
+)
+
+// Notify does nothing: upgrades are not supported on this platform.
+func Notify(c chan<- os.Signal) {}
+
+// Request returns ErrNotSupported.
+func Request(pid int) error {
+	return ErrNotSupported
+}
+
+// Inherited returns nil: upgrades are not supported on this platform.
+func Inherited() (net.Listener, error) {
+	return nil, nil
+}
+
+// Ready does nothing: upgrades are not supported on this platform.
+func Ready() error {
+	return nil
+}
+
+// Start returns ErrNotSupported.
+func Start(ln net.Listener, executable string, args []string) (int, error) {
+	return 0, ErrNotSupported
+}
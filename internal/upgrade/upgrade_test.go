@@ -0,0 +1,33 @@
+package upgrade
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPIDFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "volk.pid")
+
+	if _, err := ReadPIDFile(path); !errors.Is(err, ErrNotRunning) {
+		t.Errorf("Expected ErrNotRunning for a missing pid file, got %v", err)
+	}
+
+	if err := WritePIDFile(path); err != nil {
+		t.Fatalf("WritePIDFile returned an error: %v", err)
+	}
+	pid, err := ReadPIDFile(path)
+	if err != nil {
+		t.Fatalf("ReadPIDFile returned an error: %v", err)
+	}
+	if pid != os.Getpid() {
+		t.Errorf("Expected pid %d, got %d", os.Getpid(), pid)
+	}
+
+	os.WriteFile(path, []byte("garbage"), 0644)
+	if _, err := ReadPIDFile(path); err == nil {
+		t.Errorf("Expected an error for an invalid pid file")
+	}
+}
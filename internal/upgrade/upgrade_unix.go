@@ -0,0 +1,131 @@
+//go:build unix
+
+package upgrade
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// readyTimeout is how long Start waits for the new process to become ready.
+const readyTimeout = 30 * time.Second
+
+// Notify relays the signal that requests an upgrade, SIGUSR2, to c.
+func Notify(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGUSR2)
+}
+
+// Request asks the process with the given ID to upgrade itself.
+func Request(pid int) error {
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrNotRunning, err)
+	}
+	if err := process.Signal(syscall.SIGUSR2); err != nil {
+		return fmt.Errorf("%w: process %d: %v", ErrNotRunning, pid, err)
+	}
+	return nil
+}
+
+// Inherited returns the listener passed on by the process that started this
+// one for an upgrade, or nil if this process was not started by an upgrade.
+func Inherited() (net.Listener, error) {
+	fd := inheritedFD(listenerEnv)
+	if fd < 0 {
+		return nil, nil
+	}
+	file := os.NewFile(uintptr(fd), "listener")
+	defer file.Close()
+	os.Unsetenv(listenerEnv)
+
+	ln, err := net.FileListener(file)
+	if err != nil {
+		return nil, fmt.Errorf("error using inherited listener: %w", err)
+	}
+	return ln, nil
+}
+
+// Ready tells the process that started this one for an upgrade that this one
+// is serving, so that it can stop. It does nothing if this process was not
+// started by an upgrade.
+func Ready() error {
+	fd := inheritedFD(readyEnv)
+	if fd < 0 {
+		return nil
+	}
+	file := os.NewFile(uintptr(fd), "ready")
+	defer file.Close()
+	os.Unsetenv(readyEnv)
+
+	if _, err := file.Write([]byte{1}); err != nil {
+		return fmt.Errorf("error reporting readiness: %w", err)
+	}
+	return nil
+}
+
+// Start runs the executable with the arguments, passing it ln, and waits until
+// the new process calls Ready. It returns the ID of the new process. If the new
+// process exits or does not become ready in time, Start returns an error and
+// the caller keeps serving.
+func Start(ln net.Listener, executable string, args []string) (int, error) {
+	filer, ok := ln.(interface{ File() (*os.File, error) })
+	if !ok {
+		return 0, fmt.Errorf("listener of type %T cannot be passed on", ln)
+	}
+	listenerFile, err := filer.File()
+	if err != nil {
+		return 0, fmt.Errorf("error duplicating listener: %w", err)
+	}
+	defer listenerFile.Close()
+
+	readyReader, readyWriter, err := os.Pipe()
+	if err != nil {
+		return 0, fmt.Errorf("error creating readiness pipe: %w", err)
+	}
+	defer readyReader.Close()
+
+	cmd := exec.Command(executable, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	// ExtraFiles become file descriptors 3, 4, ... in the new process.
+	cmd.ExtraFiles = []*os.File{listenerFile, readyWriter}
+	cmd.Env = append(os.Environ(), listenerEnv+"=3", readyEnv+"=4")
+
+	err = cmd.Start()
+	readyWriter.Close()
+	if err != nil {
+		return 0, fmt.Errorf("error starting %s: %w", executable, err)
+	}
+
+	ready := make(chan error, 1)
+	go func() {
+		buf := make([]byte, 1)
+		_, err := readyReader.Read(buf)
+		if errors.Is(err, io.EOF) {
+			// The pipe was closed without a byte: the process exited or gave up.
+			err = errors.New("new process exited before it was ready")
+		}
+		ready <- err
+	}()
+
+	select {
+	case err = <-ready:
+	case <-time.After(readyTimeout):
+		err = fmt.Errorf("new process not ready after %s", readyTimeout)
+	}
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return 0, err
+	}
+
+	// The new process outlives this one, so it is not waited for.
+	return cmd.Process.Pid, nil
+}
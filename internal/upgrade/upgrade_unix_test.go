@@ -0,0 +1,79 @@
+//go:build unix
+
+package upgrade
+
+import (
+	"io"
+	"net"
+	"os"
+	"testing"
+)
+
+// TestHelperProcess is run by the Start tests as the new process.
+func TestHelperProcess(t *testing.T) {
+	mode := os.Getenv("VOLK_UPGRADE_HELPER")
+	if mode == "" {
+		return
+	}
+	if mode == "fail" {
+		os.Exit(1)
+	}
+
+	ln, err := Inherited()
+	if err != nil || ln == nil {
+		os.Exit(2)
+	}
+	if err := Ready(); err != nil {
+		os.Exit(3)
+	}
+	conn, err := ln.Accept()
+	if err != nil {
+		os.Exit(4)
+	}
+	conn.Write([]byte("new process"))
+	conn.Close()
+	os.Exit(0)
+}
+
+func TestStart(t *testing.T) {
+	tests := []struct {
+		name    string
+		mode    string
+		wantErr bool
+	}{
+		{"takes over the listener", "serve", false},
+		{"exits before ready", "fail", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ln, err := net.Listen("tcp", "127.0.0.1:0")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer ln.Close()
+
+			t.Setenv("VOLK_UPGRADE_HELPER", tt.mode)
+			pid, err := Start(ln, os.Args[0], []string{"-test.run=^TestHelperProcess$"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Expected error %v, got %v", tt.wantErr, err)
+			}
+			if err != nil {
+				return
+			}
+			if pid <= 0 {
+				t.Errorf("Expected the ID of the new process, got %d", pid)
+			}
+
+			conn, err := net.Dial("tcp", ln.Addr().String())
+			if err != nil {
+				t.Fatalf("could not connect: %v", err)
+			}
+			defer conn.Close()
+			body, _ := io.ReadAll(conn)
+			if string(body) != "new process" {
+				t.Errorf("Expected the new process to answer, got %q", body)
+			}
+		})
+	}
+}
@@ -0,0 +1,332 @@
+// Package upload stores resumable uploads, as created and appended to through
+// the tus protocol, in a storage.Storage. Each upload is a data object named
+// by its ID and an info object holding its expected length and metadata.
+//
+// In a storage that appends in place, such as the disk, the data object grows
+// as chunks are appended, and the offset of an upload is its size. Elsewhere,
+// such as in S3, each chunk is stored as a part named by the upload and its
+// offset, and the parts are joined into the data object once the upload is
+// complete. Either way, the offset is what has been stored, so an interrupted
+// append never leaves the two out of step.
+package upload
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awaisamjad/volk/internal/storage"
+)
+
+const (
+	// infoSuffix is appended to the ID of an upload for its info object.
+	infoSuffix = ".info"
+	// partSuffix is appended to the ID of an upload, followed by the offset,
+	// for its parts in a storage that cannot append.
+	partSuffix = ".part-"
+)
+
+var (
+	ErrNotFound       = errors.New("upload not found")
+	ErrOffsetMismatch = errors.New("offset does not match the upload")
+	ErrTooLarge       = errors.New("upload is too large")
+	ErrQuotaExceeded  = errors.New("uploads would exceed the storage quota")
+)
+
+// Info describes an upload.
+type Info struct {
+	ID       string    `json:"id"`
+	Length   int64     `json:"length"`             // Bytes the upload will have when complete
+	Offset   int64     `json:"-"`                  // Bytes received so far
+	Metadata string    `json:"metadata,omitempty"` // Upload-Metadata as sent by the client
+	Created  time.Time `json:"created"`
+}
+
+// Complete reports whether every byte of the upload has been received.
+func (i Info) Complete() bool {
+	return i.Offset == i.Length
+}
+
+// Store keeps uploads in a storage.
+type Store struct {
+	storage storage.Storage
+	maxSize int64
+	quota   int64 // Bytes the uploads may announce together, 0 for no limit
+
+	mu sync.Mutex // Serializes appends, so chunks never interleave, and creations against the quota
+}
+
+// New creates a Store for the uploads in store. Uploads may be up to maxSize
+// bytes and announce up to quota bytes together, 0 for no limit.
+func New(store storage.Storage, maxSize, quota int64) *Store {
+	return &Store{storage: store, maxSize: maxSize, quota: quota}
+}
+
+// MaxSize returns the largest upload the store accepts, 0 for no limit.
+func (s *Store) MaxSize() int64 {
+	return s.maxSize
+}
+
+// Usage returns the bytes the uploads announced, which they are charged for
+// from the start, and the quota, 0 if there is none.
+func (s *Store) Usage() (used, quota int64, err error) {
+	objects, err := s.storage.List("")
+	if err != nil {
+		return 0, s.quota, fmt.Errorf("error listing uploads: %w", err)
+	}
+	for _, object := range objects {
+		id, ok := strings.CutSuffix(object.Key, infoSuffix)
+		if !ok || !validID(id) {
+			continue
+		}
+		info, err := s.readInfo(id)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return 0, s.quota, err
+		}
+		used += info.Length
+	}
+	return used, s.quota, nil
+}
+
+// Create starts an upload of length bytes. The length counts against the
+// quota at once, so that uploads cannot claim more than it in total.
+func (s *Store) Create(length int64, metadata string) (Info, error) {
+	if length < 0 || s.maxSize > 0 && length > s.maxSize {
+		return Info{}, fmt.Errorf("%w: %d bytes", ErrTooLarge, length)
+	}
+	if s.quota > 0 {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		used, _, err := s.Usage()
+		if err != nil {
+			return Info{}, err
+		}
+		if used+length > s.quota {
+			return Info{}, fmt.Errorf("%w: %d of %d bytes in use", ErrQuotaExceeded, used, s.quota)
+		}
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	info := Info{ID: hex.EncodeToString(id), Length: length, Metadata: metadata, Created: time.Now().UTC()}
+
+	data, _ := json.Marshal(info)
+	if err := s.storage.Put(info.ID+infoSuffix, bytes.NewReader(data), int64(len(data))); err != nil {
+		return Info{}, fmt.Errorf("error creating upload: %w", err)
+	}
+	// The data object is appended to, or, if it cannot be, exists once complete.
+	if _, ok := s.storage.(storage.Appender); ok || length == 0 {
+		if err := s.storage.Put(info.ID, strings.NewReader(""), 0); err != nil {
+			s.storage.Delete(info.ID + infoSuffix)
+			return Info{}, fmt.Errorf("error creating upload: %w", err)
+		}
+	}
+	return info, nil
+}
+
+// Info returns the upload with the given ID.
+func (s *Store) Info(id string) (Info, error) {
+	if !validID(id) {
+		return Info{}, ErrNotFound
+	}
+	info, err := s.readInfo(id)
+	if err != nil {
+		return Info{}, err
+	}
+
+	objects, err := s.storage.List(id)
+	if err != nil {
+		return Info{}, fmt.Errorf("error reading upload: %w", err)
+	}
+	var parts int64
+	joined := false
+	for _, object := range objects {
+		switch {
+		case object.Key == id:
+			info.Offset, joined = object.Size, true
+		case strings.HasPrefix(object.Key, id+partSuffix):
+			parts += object.Size
+		}
+	}
+	// Parts left over when joining them was interrupted are already in the data object.
+	if !joined {
+		info.Offset = parts
+	}
+	return info, nil
+}
+
+// readInfo reads the info object of an upload, without its offset.
+func (s *Store) readInfo(id string) (Info, error) {
+	r, err := s.storage.Get(id + infoSuffix)
+	if errors.Is(err, storage.ErrNotFound) {
+		return Info{}, ErrNotFound
+	}
+	if err != nil {
+		return Info{}, fmt.Errorf("error reading upload: %w", err)
+	}
+	var info Info
+	err = json.NewDecoder(r).Decode(&info)
+	r.Close()
+	if err != nil {
+		return Info{}, fmt.Errorf("error reading upload: %w", err)
+	}
+	return info, nil
+}
+
+// Append adds data to the upload at offset, which must be its current offset,
+// and returns the updated upload.
+func (s *Store) Append(id string, offset int64, data []byte) (Info, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := s.Info(id)
+	if err != nil {
+		return Info{}, err
+	}
+	if offset != info.Offset {
+		return info, fmt.Errorf("%w: at %d, not %d", ErrOffsetMismatch, info.Offset, offset)
+	}
+	if info.Offset+int64(len(data)) > info.Length {
+		return info, fmt.Errorf("%w: more than the %d bytes announced", ErrTooLarge, info.Length)
+	}
+
+	if appender, ok := s.storage.(storage.Appender); ok {
+		n, err := appender.Append(id, bytes.NewReader(data))
+		info.Offset += n
+		if err != nil {
+			return info, fmt.Errorf("error writing upload: %w", err)
+		}
+		return info, nil
+	}
+
+	if len(data) > 0 {
+		if err := s.storage.Put(partKey(id, offset), bytes.NewReader(data), int64(len(data))); err != nil {
+			return info, fmt.Errorf("error writing upload: %w", err)
+		}
+		info.Offset += int64(len(data))
+	}
+	if info.Complete() {
+		if err := s.join(id, info.Length); err != nil {
+			return info, fmt.Errorf("error writing upload: %w", err)
+		}
+	}
+	return info, nil
+}
+
+// join stores the parts of a complete upload as its data object and removes
+// them.
+func (s *Store) join(id string, length int64) error {
+	parts, err := s.storage.List(id + partSuffix)
+	if err != nil {
+		return err
+	}
+	r := &partReader{storage: s.storage, parts: parts}
+	err = s.storage.Put(id, r, length)
+	r.Close()
+	if err != nil {
+		return err
+	}
+	for _, part := range parts {
+		s.storage.Delete(part.Key)
+	}
+	return nil
+}
+
+// Open opens the data of an upload, which is complete once Info reports so.
+// The caller must close it.
+func (s *Store) Open(id string) (io.ReadCloser, error) {
+	if !validID(id) {
+		return nil, ErrNotFound
+	}
+	r, err := s.storage.Get(id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading upload: %w", err)
+	}
+	return r, nil
+}
+
+// Remove deletes an upload.
+func (s *Store) Remove(id string) error {
+	if _, err := s.Info(id); err != nil {
+		return err
+	}
+	objects, err := s.storage.List(id)
+	if err != nil {
+		return fmt.Errorf("error removing upload: %w", err)
+	}
+	// The info object goes last, so an upload is found until it is gone.
+	for _, object := range objects {
+		if object.Key == id || strings.HasPrefix(object.Key, id+partSuffix) {
+			s.storage.Delete(object.Key)
+		}
+	}
+	if err := s.storage.Delete(id + infoSuffix); err != nil {
+		return fmt.Errorf("error removing upload: %w", err)
+	}
+	return nil
+}
+
+// partKey returns the key of the part of an upload starting at offset,
+// padded so that the parts sort by offset.
+func partKey(id string, offset int64) string {
+	return fmt.Sprintf("%s%s%020d", id, partSuffix, offset)
+}
+
+// partReader reads the parts of an upload one after the other, opening each
+// when it is reached.
+type partReader struct {
+	storage storage.Storage
+	parts   []storage.Object
+	current io.ReadCloser
+}
+
+func (r *partReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.parts) == 0 {
+				return 0, io.EOF
+			}
+			current, err := r.storage.Get(r.parts[0].Key)
+			if err != nil {
+				return 0, err
+			}
+			r.current, r.parts = current, r.parts[1:]
+		}
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current.Close()
+			r.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+func (r *partReader) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	return r.current.Close()
+}
+
+// validID reports whether id has the form of the IDs Create returns, so
+// that it cannot name an object of another upload.
+func validID(id string) bool {
+	_, err := hex.DecodeString(id)
+	return len(id) == 32 && err == nil
+}
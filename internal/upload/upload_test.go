@@ -0,0 +1,161 @@
+package upload
+
+import (
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/awaisamjad/volk/internal/storage"
+)
+
+// objectStorage hides the Append method of a storage, storing uploads in
+// parts as in an object store.
+type objectStorage struct {
+	storage.Storage
+}
+
+// newStorages returns a disk storage, which appends in place, and one
+// storing parts, both in temporary directories.
+func newStorages(t *testing.T) map[string]storage.Storage {
+	t.Helper()
+	disk, err := storage.NewDisk(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts, err := storage.NewDisk(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]storage.Storage{"appending": disk, "parts": objectStorage{parts}}
+}
+
+func TestStore(t *testing.T) {
+	for name, backend := range newStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			testStore(t, backend)
+		})
+	}
+}
+
+func testStore(t *testing.T, backend storage.Storage) {
+	store := New(backend, 10, 0)
+	info, err := store.Create(8, "filename aGVsbG8udHh0")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	steps := []struct {
+		name       string
+		offset     int64
+		data       string
+		err        error
+		wantOffset int64
+	}{
+		{"first chunk", 0, "abc", nil, 3},
+		{"stale offset", 0, "abc", ErrOffsetMismatch, 3},
+		{"offset ahead", 5, "abc", ErrOffsetMismatch, 3},
+		{"beyond the length", 3, "defghi", ErrTooLarge, 3},
+		{"resume", 3, "defgh", nil, 8},
+	}
+
+	for _, step := range steps {
+		t.Run(step.name, func(t *testing.T) {
+			if _, err := store.Append(info.ID, step.offset, []byte(step.data)); !errors.Is(err, step.err) {
+				t.Errorf("Expected error %v, got %v", step.err, err)
+			}
+			got, err := store.Info(info.ID)
+			if err != nil || got.Offset != step.wantOffset {
+				t.Errorf("Expected offset %d, got %d (%v)", step.wantOffset, got.Offset, err)
+			}
+		})
+	}
+
+	got, _ := store.Info(info.ID)
+	if !got.Complete() || got.Metadata != "filename aGVsbG8udHh0" {
+		t.Errorf("Expected the complete upload with its metadata, got %+v", got)
+	}
+	r, err := store.Open(info.ID)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if data, _ := io.ReadAll(r); string(data) != "abcdefgh" {
+		t.Errorf("Expected abcdefgh, got %q", data)
+	}
+	r.Close()
+
+	if err := store.Remove(info.ID); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if _, err := store.Info(info.ID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound after removing, got %v", err)
+	}
+	if objects, _ := backend.List(""); len(objects) != 0 {
+		t.Errorf("Expected no objects left, got %v", objects)
+	}
+}
+
+func TestStoreEmptyUpload(t *testing.T) {
+	for name, backend := range newStorages(t) {
+		t.Run(name, func(t *testing.T) {
+			store := New(backend, 0, 0)
+			info, err := store.Create(0, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, err := store.Info(info.ID); err != nil || !got.Complete() {
+				t.Errorf("Expected a complete upload, got %+v (%v)", got, err)
+			}
+			r, err := store.Open(info.ID)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			r.Close()
+		})
+	}
+}
+
+func TestStoreRejects(t *testing.T) {
+	backend, err := storage.NewDisk(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := New(backend, 10, 0)
+	if _, err := store.Create(11, ""); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Expected ErrTooLarge, got %v", err)
+	}
+
+	for _, id := range []string{"", "../../etc/passwd", "0123456789abcdef0123456789abcdeg", "0123456789abcdef0123456789abcdef"} {
+		t.Run(id, func(t *testing.T) {
+			if _, err := store.Info(id); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Expected ErrNotFound, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStoreQuota(t *testing.T) {
+	backend, err := storage.NewDisk(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := New(backend, 0, 10)
+
+	first, err := store.Create(6, "")
+	if err != nil {
+		t.Fatalf("Expected the first upload to fit the quota, got %v", err)
+	}
+	// The announced length counts before any byte is appended.
+	if _, err := store.Create(5, ""); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("Expected ErrQuotaExceeded, got %v", err)
+	}
+	if used, quota, err := store.Usage(); err != nil || used != 6 || quota != 10 {
+		t.Errorf("Expected usage 6 of 10, got %d of %d (%v)", used, quota, err)
+	}
+
+	if err := store.Remove(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Create(10, ""); err != nil {
+		t.Errorf("Expected the quota to be freed by removing an upload, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+// Package watch reports changes below a directory as they happen, so caches
+// of its files can drop entries right away instead of noticing on their own.
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// Watcher watches a directory and the directories below it, including those
+// created after it started.
+type Watcher struct {
+	root    string
+	watcher *fsnotify.Watcher
+	changed func(path string)
+	done    sync.WaitGroup
+}
+
+// New starts watching root. changed is called, from a single goroutine, with
+// the path of every file or directory below root that is created, written,
+// removed or renamed; a change to a directory covers the files below it.
+func New(root string, changed func(path string)) (*Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("error watching %s: %w", root, err)
+	}
+	w := &Watcher{root: root, watcher: watcher, changed: changed}
+	if err := w.add(root); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+	w.done.Add(1)
+	go w.run()
+	return w, nil
+}
+
+// Close stops watching and waits for the last call to changed to return.
+func (w *Watcher) Close() error {
+	err := w.watcher.Close()
+	w.done.Wait()
+	return err
+}
+
+// add watches dir and the directories below it.
+func (w *Watcher) add(dir string) error {
+	return filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			// A directory removed while walking is reported as a change anyway.
+			if errors.Is(err, fs.ErrNotExist) && path != dir {
+				return nil
+			}
+			return fmt.Errorf("error watching %s: %w", path, err)
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+		if err := w.watcher.Add(path); err != nil {
+			return fmt.Errorf("error watching %s: %w", path, err)
+		}
+		return nil
+	})
+}
+
+func (w *Watcher) run() {
+	defer w.done.Done()
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Has(fsnotify.Create) {
+				// Files written into a new directory before it is watched are
+				// covered by the change to the directory itself.
+				if info, err := os.Lstat(event.Name); err == nil && info.IsDir() {
+					if err := w.add(event.Name); err != nil {
+						log.Print(err)
+					}
+				}
+			}
+			if event.Op&^fsnotify.Chmod != 0 {
+				w.changed(event.Name)
+			}
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			// An overflowed event queue loses changes, so everything may be stale.
+			if errors.Is(err, fsnotify.ErrEventOverflow) {
+				w.changed(w.root)
+			}
+			log.Printf("Error watching %s: %v", w.root, err)
+		}
+	}
+}
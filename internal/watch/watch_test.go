@@ -0,0 +1,72 @@
+package watch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitFor waits for changed to report path.
+func waitFor(t *testing.T, changed <-chan string, path string) {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case got := <-changed:
+			if got == path {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("Expected a change to %s", path)
+		}
+	}
+}
+
+func TestWatcher(t *testing.T) {
+	root := t.TempDir()
+	file := filepath.Join(root, "index.html")
+	if err := os.WriteFile(file, []byte("v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "assets"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	changed := make(chan string, 100)
+	w, err := New(root, func(path string) { changed <- path })
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	steps := []struct {
+		name   string
+		change func() error
+		path   string
+	}{
+		{"write", func() error { return os.WriteFile(file, []byte("v2"), 0o644) }, file},
+		{"existing directory", func() error {
+			return os.WriteFile(filepath.Join(root, "assets", "app.css"), nil, 0o644)
+		}, filepath.Join(root, "assets", "app.css")},
+		{"new directory", func() error { return os.Mkdir(filepath.Join(root, "img"), 0o755) }, filepath.Join(root, "img")},
+		{"file in new directory", func() error {
+			return os.WriteFile(filepath.Join(root, "img", "logo.png"), nil, 0o644)
+		}, filepath.Join(root, "img", "logo.png")},
+		{"rename", func() error { return os.Rename(file, filepath.Join(root, "old.html")) }, file},
+		{"remove", func() error { return os.RemoveAll(filepath.Join(root, "assets")) }, filepath.Join(root, "assets")},
+	}
+
+	for _, step := range steps {
+		if err := step.change(); err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		waitFor(t, changed, step.path)
+	}
+}
+
+func TestWatcherMissingRoot(t *testing.T) {
+	if _, err := New(filepath.Join(t.TempDir(), "missing"), func(string) {}); err == nil {
+		t.Errorf("Expected an error watching a missing directory")
+	}
+}
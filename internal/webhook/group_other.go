@@ -0,0 +1,8 @@
+//go:build !unix
+
+package webhook
+
+import "os/exec"
+
+// killGroup leaves cmd as it is; cancelling it kills only the shell.
+func killGroup(cmd *exec.Cmd) {}
@@ -0,0 +1,256 @@
+// Package webhook receives webhook deliveries: it verifies their signature,
+// appends them to a spool file and optionally runs a command for each event.
+//
+// Supported signature schemes:
+//
+//   - github: X-Hub-Signature-256 is "sha256=" and the hex HMAC-SHA256 of the body.
+//   - stripe: Stripe-Signature is "t=<unix time>,v1=<hex HMAC-SHA256 of "<t>.<body>">".
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+// Providers whose signature schemes are supported.
+const (
+	ProviderGitHub = "github"
+	ProviderStripe = "stripe"
+)
+
+// stripeTolerance is how old a Stripe signature timestamp may be.
+const stripeTolerance = 5 * time.Minute
+
+// Defaults of command_timeout and max_commands.
+const (
+	defaultCommandTimeout = time.Minute
+	defaultMaxCommands    = 4
+)
+
+// commandWaitDelay is how long a killed command's output is still read, for
+// programs it started that keep it open.
+const commandWaitDelay = 5 * time.Second
+
+var (
+	ErrMissingSignature = errors.New("missing webhook signature")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// Event is a verified delivery as stored in the spool.
+type Event struct {
+	Time     time.Time       `json:"time"`
+	Path     string          `json:"path"`
+	Provider string          `json:"provider"`
+	Type     string          `json:"type,omitempty"`
+	ID       string          `json:"id,omitempty"`
+	Payload  json.RawMessage `json:"payload"`
+}
+
+// Receiver handles the deliveries of one configured webhook.
+type Receiver struct {
+	config config.WebhookConfig
+
+	mu       sync.Mutex // Serializes appends to the spool
+	wg       sync.WaitGroup
+	commands chan struct{} // Semaphore of the commands running
+	timeout  time.Duration // How long a command may run
+
+	now func() time.Time
+}
+
+// New creates a Receiver for the webhook configuration.
+func New(cfg config.WebhookConfig) (*Receiver, error) {
+	switch cfg.Provider {
+	case ProviderGitHub, ProviderStripe:
+	default:
+		return nil, fmt.Errorf("webhook %s: unknown provider %q", cfg.Path, cfg.Provider)
+	}
+	if cfg.Secret == "" {
+		return nil, fmt.Errorf("webhook %s: secret is required", cfg.Path)
+	}
+	if cfg.CommandTimeout < 0 || cfg.MaxCommands < 0 {
+		return nil, fmt.Errorf("webhook %s: command_timeout and max_commands must not be negative", cfg.Path)
+	}
+	timeout := time.Duration(cfg.CommandTimeout) * time.Second
+	if timeout == 0 {
+		timeout = defaultCommandTimeout
+	}
+	maxCommands := cfg.MaxCommands
+	if maxCommands == 0 {
+		maxCommands = defaultMaxCommands
+	}
+	return &Receiver{config: cfg, now: time.Now, commands: make(chan struct{}, maxCommands), timeout: timeout}, nil
+}
+
+// Receive verifies a delivery, spools it and starts the configured command.
+// header returns the value of a request header.
+func (r *Receiver) Receive(header func(name string) string, body string) (Event, error) {
+	if err := r.verify(header, body); err != nil {
+		return Event{}, err
+	}
+
+	event := Event{
+		Time:     r.now().UTC(),
+		Path:     r.config.Path,
+		Provider: r.config.Provider,
+		Payload:  json.RawMessage(body),
+	}
+	if !json.Valid(event.Payload) {
+		// Keep non-JSON payloads, such as form-encoded GitHub deliveries, as a string.
+		event.Payload, _ = json.Marshal(body)
+	}
+
+	switch r.config.Provider {
+	case ProviderGitHub:
+		event.Type = header("X-GitHub-Event")
+		event.ID = header("X-GitHub-Delivery")
+	case ProviderStripe:
+		var payload struct {
+			ID   string `json:"id"`
+			Type string `json:"type"`
+		}
+		json.Unmarshal([]byte(body), &payload)
+		event.Type = payload.Type
+		event.ID = payload.ID
+	}
+
+	if r.config.Spool != "" {
+		if err := r.spool(event); err != nil {
+			return Event{}, err
+		}
+	}
+	if r.config.Command != "" {
+		r.wg.Add(1)
+		go func() {
+			defer r.wg.Done()
+			r.run(event, body)
+		}()
+	}
+	return event, nil
+}
+
+// Wait waits for the commands started so far to finish.
+func (r *Receiver) Wait() {
+	r.wg.Wait()
+}
+
+// verify checks the provider's signature header.
+func (r *Receiver) verify(header func(name string) string, body string) error {
+	switch r.config.Provider {
+	case ProviderGitHub:
+		signature := header("X-Hub-Signature-256")
+		if signature == "" {
+			return ErrMissingSignature
+		}
+		if !hmac.Equal([]byte(signature), []byte("sha256="+r.sign(body))) {
+			return ErrInvalidSignature
+		}
+		return nil
+
+	case ProviderStripe:
+		signature := header("Stripe-Signature")
+		if signature == "" {
+			return ErrMissingSignature
+		}
+		var timestamp string
+		var candidates []string
+		for _, part := range strings.Split(signature, ",") {
+			key, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+			switch key {
+			case "t":
+				timestamp = value
+			case "v1":
+				candidates = append(candidates, value)
+			}
+		}
+		unix, err := strconv.ParseInt(timestamp, 10, 64)
+		if err != nil {
+			return ErrInvalidSignature
+		}
+		if age := r.now().Sub(time.Unix(unix, 0)); age > stripeTolerance || age < -stripeTolerance {
+			return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+		}
+		expected := r.sign(timestamp + "." + body)
+		for _, candidate := range candidates {
+			if hmac.Equal([]byte(candidate), []byte(expected)) {
+				return nil
+			}
+		}
+		return ErrInvalidSignature
+	}
+	return ErrInvalidSignature
+}
+
+// sign returns the hex HMAC-SHA256 of the message with the secret.
+func (r *Receiver) sign(message string) string {
+	mac := hmac.New(sha256.New, []byte(r.config.Secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// spool appends the event to the spool file as a JSON line.
+func (r *Receiver) spool(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error encoding webhook event: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	file, err := os.OpenFile(r.config.Spool, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening webhook spool: %w", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("error writing webhook spool: %w", err)
+	}
+	return nil
+}
+
+// run executes the configured command with the payload on standard input and
+// the event in VOLK_WEBHOOK_* environment variables. It waits while
+// max_commands commands are running, and kills the command once it has run
+// for command_timeout.
+func (r *Receiver) run(event Event, body string) {
+	r.commands <- struct{}{}
+	defer func() { <-r.commands }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", r.config.Command)
+	killGroup(cmd)
+	cmd.WaitDelay = commandWaitDelay
+	cmd.Stdin = strings.NewReader(body)
+	cmd.Env = append(os.Environ(),
+		"VOLK_WEBHOOK_PATH="+event.Path,
+		"VOLK_WEBHOOK_PROVIDER="+event.Provider,
+		"VOLK_WEBHOOK_EVENT="+event.Type,
+		"VOLK_WEBHOOK_ID="+event.ID,
+	)
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	if err := cmd.Run(); ctx.Err() != nil {
+		log.Printf("Webhook %s: command for %s event killed after %s: %s", event.Path, event.Type, r.timeout, strings.TrimSpace(output.String()))
+	} else if err != nil {
+		log.Printf("Webhook %s: command for %s event failed: %v: %s", event.Path, event.Type, err, strings.TrimSpace(output.String()))
+	}
+}
@@ -0,0 +1,215 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+)
+
+func hexHMAC(secret, message string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(message))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func headers(values map[string]string) func(string) string {
+	return func(name string) string { return values[name] }
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  config.WebhookConfig
+		wantErr bool
+	}{
+		{"github", config.WebhookConfig{Path: "/hook", Provider: "github", Secret: "s"}, false},
+		{"stripe", config.WebhookConfig{Path: "/hook", Provider: "stripe", Secret: "s"}, false},
+		{"unknown provider", config.WebhookConfig{Path: "/hook", Provider: "gitlab", Secret: "s"}, true},
+		{"missing secret", config.WebhookConfig{Path: "/hook", Provider: "github"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestReceiveVerify(t *testing.T) {
+	const secret = "topsecret"
+	const body = `{"id":"evt_1","type":"invoice.paid"}`
+	now := time.Unix(1700000000, 0)
+	ts := strconv.FormatInt(now.Unix(), 10)
+	old := strconv.FormatInt(now.Add(-time.Hour).Unix(), 10)
+
+	tests := []struct {
+		name     string
+		provider string
+		headers  map[string]string
+		wantErr  error
+	}{
+		{"github valid", ProviderGitHub, map[string]string{"X-Hub-Signature-256": "sha256=" + hexHMAC(secret, body)}, nil},
+		{"github invalid", ProviderGitHub, map[string]string{"X-Hub-Signature-256": "sha256=" + hexHMAC("wrong", body)}, ErrInvalidSignature},
+		{"github missing", ProviderGitHub, map[string]string{}, ErrMissingSignature},
+		{"stripe valid", ProviderStripe, map[string]string{"Stripe-Signature": "t=" + ts + ",v1=" + hexHMAC(secret, ts+"."+body)}, nil},
+		{"stripe second candidate", ProviderStripe, map[string]string{"Stripe-Signature": "t=" + ts + ",v1=00,v1=" + hexHMAC(secret, ts+"."+body)}, nil},
+		{"stripe expired", ProviderStripe, map[string]string{"Stripe-Signature": "t=" + old + ",v1=" + hexHMAC(secret, old+"."+body)}, ErrInvalidSignature},
+		{"stripe invalid", ProviderStripe, map[string]string{"Stripe-Signature": "t=" + ts + ",v1=" + hexHMAC(secret, body)}, ErrInvalidSignature},
+		{"stripe missing", ProviderStripe, map[string]string{}, ErrMissingSignature},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver, err := New(config.WebhookConfig{Path: "/hook", Provider: tt.provider, Secret: secret})
+			if err != nil {
+				t.Fatalf("New returned an error: %v", err)
+			}
+			receiver.now = func() time.Time { return now }
+
+			_, err = receiver.Receive(headers(tt.headers), body)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestReceiveSpoolAndCommand(t *testing.T) {
+	dir := t.TempDir()
+	spool := filepath.Join(dir, "spool.jsonl")
+	output := filepath.Join(dir, "output")
+	const secret = "topsecret"
+
+	receiver, err := New(config.WebhookConfig{
+		Path:     "/hook",
+		Provider: ProviderGitHub,
+		Secret:   secret,
+		Spool:    spool,
+		Command:  `cat >> "` + output + `"; echo " $VOLK_WEBHOOK_EVENT" >> "` + output + `"`,
+	})
+	if err != nil {
+		t.Fatalf("New returned an error: %v", err)
+	}
+
+	for _, body := range []string{`{"n":1}`, `{"n":2}`} {
+		_, err := receiver.Receive(headers(map[string]string{
+			"X-Hub-Signature-256": "sha256=" + hexHMAC(secret, body),
+			"X-GitHub-Event":      "push",
+			"X-GitHub-Delivery":   "abc",
+		}), body)
+		if err != nil {
+			t.Fatalf("Receive returned an error: %v", err)
+		}
+		receiver.Wait()
+	}
+
+	data, err := os.ReadFile(spool)
+	if err != nil {
+		t.Fatalf("could not read spool: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 spooled events, got %d", len(lines))
+	}
+	var event Event
+	if err := json.Unmarshal([]byte(lines[1]), &event); err != nil {
+		t.Fatalf("could not decode spooled event: %v", err)
+	}
+	if event.Type != "push" || event.ID != "abc" || string(event.Payload) != `{"n":2}` {
+		t.Errorf("Expected push event abc with payload {\"n\":2}, got %+v", event)
+	}
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("could not read command output: %v", err)
+	}
+	if want := "{\"n\":1} push\n{\"n\":2} push\n"; string(got) != want {
+		t.Errorf("Expected command output %q, got %q", want, got)
+	}
+}
+
+// deliver hands receiver n signed GitHub push deliveries.
+func deliver(t *testing.T, receiver *Receiver, secret string, n int) {
+	t.Helper()
+	for i := range n {
+		body := `{"n":` + strconv.Itoa(i) + `}`
+		_, err := receiver.Receive(headers(map[string]string{
+			"X-Hub-Signature-256": "sha256=" + hexHMAC(secret, body),
+			"X-GitHub-Event":      "push",
+		}), body)
+		if err != nil {
+			t.Fatalf("Receive returned an error: %v", err)
+		}
+	}
+}
+
+func TestReceiveCommandTimeout(t *testing.T) {
+	const secret = "topsecret"
+	receiver, err := New(config.WebhookConfig{
+		Path:           "/hook",
+		Provider:       ProviderGitHub,
+		Secret:         secret,
+		Command:        "sleep 30",
+		CommandTimeout: 1,
+	})
+	if err != nil {
+		t.Fatalf("New returned an error: %v", err)
+	}
+
+	start := time.Now()
+	deliver(t, receiver, secret, 1)
+	receiver.Wait()
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Expected the command to be killed after 1s, it ran %s", elapsed)
+	}
+}
+
+func TestReceiveMaxCommands(t *testing.T) {
+	output := filepath.Join(t.TempDir(), "output")
+	const secret = "topsecret"
+	receiver, err := New(config.WebhookConfig{
+		Path:        "/hook",
+		Provider:    ProviderGitHub,
+		Secret:      secret,
+		Command:     `echo start >> "` + output + `"; sleep 0.1; echo end >> "` + output + `"`,
+		MaxCommands: 1,
+	})
+	if err != nil {
+		t.Fatalf("New returned an error: %v", err)
+	}
+
+	deliver(t, receiver, secret, 4)
+	receiver.Wait()
+
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("could not read command output: %v", err)
+	}
+	if want := strings.Repeat("start\nend\n", 4); string(got) != want {
+		t.Errorf("Expected commands to run one at a time, got %q", got)
+	}
+}
+
+func TestNewNegativeCommandLimits(t *testing.T) {
+	for _, cfg := range []config.WebhookConfig{
+		{Path: "/hook", Provider: ProviderGitHub, Secret: "s", CommandTimeout: -1},
+		{Path: "/hook", Provider: ProviderGitHub, Secret: "s", MaxCommands: -1},
+	} {
+		if _, err := New(cfg); err == nil {
+			t.Errorf("Expected an error for %+v", cfg)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/awaisamjad/volk/config"
+	"github.com/awaisamjad/volk/internal/audit"
+	"github.com/spf13/cobra"
+)
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Work with the audit log of write requests",
+}
+
+var auditVerifyCmd = &cobra.Command{
+	Use:   "verify [file]",
+	Short: "Check the hash chain of the audit log",
+	Long: `This command checks that no entry of the audit log was modified, removed or
+reordered since the server wrote it, using the key of [audit] in the configuration.
+Without an argument, it checks the file_path of [audit]. It exits with an error if the
+chain is broken.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runAuditVerify,
+}
+
+func init() {
+	auditCmd.AddCommand(auditVerifyCmd)
+}
+
+func runAuditVerify(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("error loading configuration: %w", err)
+	}
+	path := cfg.Audit.FilePath
+	if len(args) == 1 {
+		path = args[0]
+	}
+	if path == "" {
+		return fmt.Errorf("no audit log configured: set file_path in [audit] or pass a file")
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("error opening audit log: %w", err)
+	}
+	defer file.Close()
+
+	count, err := audit.Verify(file, []byte(cfg.Audit.Key))
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%s: %d entries, hash chain intact\n", path, count)
+	return nil
+}
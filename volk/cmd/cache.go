@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/awaisamjad/volk/client"
+	"github.com/awaisamjad/volk/config"
+	"github.com/awaisamjad/volk/internal/http"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cachePurgeAll   bool
+	cachePurgeURL   string
+	cachePurgeToken string
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the caches of a running server",
+}
+
+var cachePurgeCmd = &cobra.Command{
+	Use:   "purge [path|--all]",
+	Short: "Purge the file caches of a running server",
+	Long: `This command asks a running server to drop what it caches about the files at a URL
+path and below it, such as /assets/, or about every file with --all: the sums behind strong
+ETags, the parsed access files and the generated thumbnails. Run it after a deploy that
+may have kept the size and modification time of changed files, so the next requests see
+the new content.
+
+The server is reached through its admin endpoint, which must be enabled in [admin]. The
+address, path and token are taken from the configuration; --url and --token override them.`,
+	Args:         cobra.MaximumNArgs(1),
+	RunE:         runCachePurge,
+	SilenceUsage: true,
+}
+
+func init() {
+	cachePurgeCmd.Flags().BoolVar(&cachePurgeAll, "all", false, "purge the caches of every file")
+	cachePurgeCmd.Flags().StringVar(&cachePurgeURL, "url", "", "URL of the admin endpoint, e.g. http://10.0.0.5:8000/_admin (default: from the configuration)")
+	cachePurgeCmd.Flags().StringVar(&cachePurgeToken, "token", "", "admin token (default: admin.token of the configuration)")
+	cacheCmd.AddCommand(cachePurgeCmd)
+}
+
+func runCachePurge(cmd *cobra.Command, args []string) error {
+	if cachePurgeAll == (len(args) == 1) {
+		return fmt.Errorf("give either a path to purge or --all")
+	}
+	query := url.Values{}
+	if cachePurgeAll {
+		query.Set("all", "true")
+	} else {
+		if !strings.HasPrefix(args[0], "/") {
+			return fmt.Errorf("path %q must start with /", args[0])
+		}
+		query.Set("path", args[0])
+	}
+
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("error loading configuration: %w", err)
+	}
+	endpoint := cachePurgeURL
+	if endpoint == "" {
+		endpoint = adminURL(cfg)
+	}
+	token := cachePurgeToken
+	if token == "" {
+		token = cfg.Admin.Token
+	}
+
+	req, err := client.NewRequest(http.DELETE, strings.TrimSuffix(endpoint, "/")+"/cache?"+query.Encode(), "")
+	if err != nil {
+		return err
+	}
+	req.SetHeader("Authorization", "Bearer "+token)
+	resp, err := client.New().Do(req)
+	if err != nil {
+		return fmt.Errorf("error purging caches: %w", err)
+	}
+	if code := resp.GetStatusCode(); code != 200 {
+		return fmt.Errorf("error purging caches: %d %s: %s", code, resp.GetStatusText(), strings.TrimSpace(resp.GetBody()))
+	}
+
+	var result struct {
+		Purged http.PurgeResult `json:"purged"`
+	}
+	if err := json.Unmarshal([]byte(resp.GetBody()), &result); err != nil {
+		return fmt.Errorf("error reading the response: %w", err)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Purged %d ETags, %d access files and %d thumbnails\n",
+		result.Purged.ETags, result.Purged.AccessFiles, result.Purged.Thumbnails)
+	return nil
+}
+
+// adminURL returns the URL of the admin endpoint of the server configured by
+// cfg, reached on the loopback interface when it listens on every address.
+func adminURL(cfg config.Config) string {
+	host := cfg.Server.Host
+	switch host {
+	case "", "0.0.0.0":
+		host = "127.0.0.1"
+	case "::":
+		host = "::1"
+	}
+	return "http://" + net.JoinHostPort(host, strconv.Itoa(cfg.Server.Port)) + strings.TrimSuffix(cfg.Admin.Path, "/")
+}
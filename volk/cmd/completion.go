@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var completionCmd = &cobra.Command{
+	Use:   "completion bash|zsh|fish|powershell",
+	Short: "Generate shell completion scripts",
+	Long: `This command writes a completion script for the given shell to standard output.
+
+Bash:
+  volk completion bash > /etc/bash_completion.d/volk
+
+Zsh:
+  volk completion zsh > "${fpath[1]}/_volk"
+
+Fish:
+  volk completion fish > ~/.config/fish/completions/volk.fish
+
+PowerShell:
+  volk completion powershell | Out-String | Invoke-Expression`,
+	ValidArgs:             []string{"bash", "zsh", "fish", "powershell"},
+	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	DisableFlagsInUseLine: true,
+	RunE:                  runCompletion,
+}
+
+func runCompletion(cmd *cobra.Command, args []string) error {
+	switch args[0] {
+	case "bash":
+		return rootCmd.GenBashCompletionV2(os.Stdout, true)
+	case "zsh":
+		return rootCmd.GenZshCompletion(os.Stdout)
+	case "fish":
+		return rootCmd.GenFishCompletion(os.Stdout, true)
+	default:
+		return rootCmd.GenPowerShellCompletionWithDesc(os.Stdout)
+	}
+}
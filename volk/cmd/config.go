@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/awaisamjad/volk/config"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and check the configuration",
+}
+
+var configSchemaCmd = &cobra.Command{
+	Use:   "schema",
+	Short: "Print every configuration key with its type and default",
+	Long: `This command prints every key the configuration file accepts, as a dotted path
+such as server.port, with its TOML type, its default value and a description.
+Keys below an array of tables, such as location.path, are set in each [[location]] block.`,
+	Args: cobra.NoArgs,
+	RunE: runConfigSchema,
+}
+
+var configCheckCmd = &cobra.Command{
+	Use:   "check",
+	Short: "Check the configuration file for errors",
+	Long: `This command loads the configuration file and reports the first error in it,
+with its line, the key it is about and, where possible, a suggested fix, such as the
+key that was probably meant for an unknown one. It exits with an error if there is one.`,
+	Args:         cobra.NoArgs,
+	RunE:         runConfigCheck,
+	SilenceUsage: true,
+}
+
+var configMigrateCmd = &cobra.Command{
+	Use:   "migrate [file]",
+	Short: "Convert a configuration file from an older version",
+	Long: `This command reads a configuration file written for an older version of volk,
+such as one with a [security] table, and prints it in the current layout: renamed keys
+are moved, and keys that were removed or are unknown are dropped with a warning, so an
+upgrade does not silently lose settings. Without an argument, it converts the file
+given with --config or found on the search path. With --write, the file is replaced
+and the original kept with a .bak suffix. Comments are not kept.`,
+	Args:         cobra.MaximumNArgs(1),
+	RunE:         runConfigMigrate,
+	SilenceUsage: true,
+}
+
+// migrateWrite replaces the migrated file instead of printing the result.
+var migrateWrite bool
+
+func init() {
+	configMigrateCmd.Flags().BoolVarP(&migrateWrite, "write", "w", false, "replace the file, keeping the original as <file>.bak")
+	configCmd.AddCommand(configSchemaCmd, configCheckCmd, configMigrateCmd)
+}
+
+func runConfigSchema(cmd *cobra.Command, args []string) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "KEY\tTYPE\tDEFAULT\tDESCRIPTION")
+	for _, field := range config.Schema() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", field.Key, field.Type, field.Default, field.Description)
+	}
+	return w.Flush()
+}
+
+func runConfigCheck(cmd *cobra.Command, args []string) error {
+	path := configFile
+	if path == "" {
+		path = config.FindConfigFile()
+	}
+	if path == "" {
+		fmt.Println("No configuration file found, the defaults apply")
+		return nil
+	}
+	if _, err := config.LoadConfig(path); err != nil {
+		return err
+	}
+	fmt.Printf("%s is valid\n", path)
+	return nil
+}
+
+func runConfigMigrate(cmd *cobra.Command, args []string) error {
+	path := configFile
+	if len(args) == 1 {
+		path = args[0]
+	}
+	if path == "" {
+		path = config.FindConfigFile()
+	}
+	if path == "" {
+		return fmt.Errorf("no configuration file found")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %w", err)
+	}
+	migrated, warnings, err := config.Migrate(string(data))
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	if err != nil {
+		return err
+	}
+
+	if !migrateWrite {
+		fmt.Print(migrated)
+		return nil
+	}
+	if err := os.WriteFile(path+".bak", data, 0o644); err != nil {
+		return fmt.Errorf("error writing backup: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(migrated), 0o644); err != nil {
+		return fmt.Errorf("error writing config file: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Migrated %s, the original is in %s.bak\n", path, path)
+	return nil
+}
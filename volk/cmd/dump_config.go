@@ -2,17 +2,50 @@ package cmd
 
 import (
 	"fmt"
-	"github.com/spf13/cobra"
+
 	"github.com/awaisamjad/volk/config"
+	"github.com/spf13/cobra"
 )
 
 var dumpDefaultConfigCmd = &cobra.Command{
 	Use:   "dump-config",
 	Short: "Dump the default configuration to stdout",
-	Long:  "This command dumps the default configuration, which includes sensible defaults and helpful comments, to standard output and exits.",
-	Run:   dumpConfig,
+	Long: `This command dumps the default configuration, which includes sensible defaults and helpful comments, to standard output and exits.
+
+With --effective, it dumps the configuration volk serve would run with instead: the
+configuration file (given with --config or found on the search path) merged over the
+defaults, with paths made absolute and --dev applied. Tokens and secrets are hidden
+unless --show-secrets is given.`,
+	Run: dumpConfig,
+}
+
+var (
+	// dumpEffective dumps the configuration the server would use instead of the defaults.
+	dumpEffective bool
+	// dumpShowSecrets shows tokens and secrets in the effective configuration.
+	dumpShowSecrets bool
+)
+
+func init() {
+	dumpDefaultConfigCmd.Flags().BoolVar(&dumpEffective, "effective", false, "dump the configuration volk serve would use, not the defaults")
+	dumpDefaultConfigCmd.Flags().BoolVar(&dumpShowSecrets, "show-secrets", false, "with --effective, show tokens and secrets instead of hiding them")
+	dumpDefaultConfigCmd.Flags().BoolVar(&serveDev, "dev", false, "with --effective, apply volk serve --dev")
 }
 
 func dumpConfig(cmd *cobra.Command, args []string) {
-	fmt.Println(config.DefaultConfig().String())
+	if !dumpEffective {
+		fmt.Println(config.DefaultConfig().String())
+		return
+	}
+
+	cfg, path := loadServeConfig()
+	if path != "" {
+		fmt.Printf("# Effective configuration from %s\n", path)
+	} else {
+		fmt.Println("# Effective configuration: no configuration file found, defaults")
+	}
+	if !dumpShowSecrets {
+		cfg = cfg.Redacted()
+	}
+	fmt.Println(cfg.String())
 }
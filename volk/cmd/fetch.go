@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/awaisamjad/volk/client"
+	"github.com/awaisamjad/volk/internal/http"
+	"github.com/spf13/cobra"
+)
+
+var (
+	fetchOutput   string
+	fetchCacheDir string
+	fetchNoCache  bool
+	fetchParallel int
+	fetchRetries  int
+	fetchForm     []string
+	fetchURLForm  bool
+	fetchMaxSize  int64
+	fetchProgress bool
+)
+
+var fetchCmd = &cobra.Command{
+	Use:   "fetch <url>",
+	Short: "Download a URL",
+	Long: `This command downloads a URL with volk's HTTP client and writes the body to standard output
+or to the file given with --output.
+
+Responses with an ETag or Last-Modified header are kept in a cache directory. Fetching the same
+URL again sends If-None-Match/If-Modified-Since, and the cached body is used when the server
+answers 304 Not Modified.
+
+With --parallel N, the file is downloaded in N segments over concurrent Range requests. Progress
+is kept in a <output>.part file; running the same command again after an interruption only fetches
+the missing segments.
+
+With --form name=value, the URL is sent a POST of a multipart/form-data form, as browsers send
+for file uploads; name=@path adds the file at path, streamed from disk. Repeat --form for more
+fields. With --urlencoded the fields are sent as application/x-www-form-urlencoded instead.
+
+The body is streamed to its destination as it arrives, so large downloads do not need to fit in
+memory. --max-size aborts a download whose body is larger than the given number of bytes, and
+--progress reports the bytes received on standard error.`,
+	Args: cobra.ExactArgs(1),
+	RunE: runFetch,
+}
+
+func init() {
+	fetchCmd.Flags().StringVarP(&fetchOutput, "output", "o", "", "write the body to this file instead of standard output")
+	fetchCmd.Flags().StringVar(&fetchCacheDir, "cache-dir", "", "directory for cached responses (default: $XDG_CACHE_HOME/volk/fetch)")
+	fetchCmd.Flags().BoolVar(&fetchNoCache, "no-cache", false, "neither use nor update the cache")
+	fetchCmd.Flags().IntVar(&fetchParallel, "parallel", 1, "download in this many concurrent segments using Range requests (requires --output)")
+	fetchCmd.Flags().StringArrayVarP(&fetchForm, "form", "F", nil, "POST a form field, name=value or name=@file (repeatable)")
+	fetchCmd.Flags().BoolVar(&fetchURLForm, "urlencoded", false, "send the --form fields URL-encoded instead of as multipart/form-data")
+	fetchCmd.Flags().Int64Var(&fetchMaxSize, "max-size", 0, "fail if the body is larger than this many bytes (0 for no limit)")
+	fetchCmd.Flags().BoolVar(&fetchProgress, "progress", false, "report download progress on standard error")
+	fetchCmd.Flags().IntVar(&fetchRetries, "retries", client.DefaultRetryPolicy.MaxRetries, "retry connection errors and 502/503/504 responses this many times")
+}
+
+func runFetch(cmd *cobra.Command, args []string) error {
+	policy := client.DefaultRetryPolicy
+	policy.MaxRetries = fetchRetries
+	c := &client.Client{Transport: client.NewRetryTransport(nil, policy)}
+
+	if fetchParallel > 1 {
+		if fetchOutput == "" {
+			return fmt.Errorf("--parallel requires --output")
+		}
+		if len(fetchForm) > 0 {
+			return fmt.Errorf("--parallel cannot be used with --form")
+		}
+		if err := c.Download(args[0], fetchOutput, fetchParallel); err != nil {
+			return fmt.Errorf("error fetching %s: %w", args[0], err)
+		}
+		return nil
+	}
+
+	req, err := fetchRequest(args[0])
+	if err != nil {
+		return err
+	}
+	if !fetchNoCache && len(fetchForm) == 0 {
+		dir, err := fetchCacheDirectory()
+		if err != nil {
+			return err
+		}
+		c.Transport = client.NewCachingTransport(c.Transport, &client.DiskCache{Dir: dir})
+	}
+
+	opts := client.StreamOptions{MaxSize: fetchMaxSize}
+	if fetchProgress {
+		opts.Progress = fetchProgressReporter(cmd.ErrOrStderr())
+	}
+	resp, err := c.Stream(req, opts)
+	if err != nil {
+		return fmt.Errorf("error fetching %s: %w", args[0], err)
+	}
+	defer resp.Body.Close()
+	if code := resp.Head.GetStatusCode(); code < 200 || code > 299 {
+		return fmt.Errorf("error fetching %s: %d %s", args[0], code, resp.Head.GetStatusText())
+	}
+
+	if fetchOutput == "" {
+		_, err = io.Copy(cmd.OutOrStdout(), resp.Body)
+		if fetchProgress {
+			fmt.Fprintln(cmd.ErrOrStderr())
+		}
+		if err != nil {
+			return fmt.Errorf("error fetching %s: %w", args[0], err)
+		}
+		return nil
+	}
+
+	f, err := os.Create(fetchOutput)
+	if err != nil {
+		return fmt.Errorf("error writing %s: %w", fetchOutput, err)
+	}
+	_, err = io.Copy(f, resp.Body)
+	if fetchProgress {
+		fmt.Fprintln(cmd.ErrOrStderr())
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		// Do not leave a truncated file behind.
+		os.Remove(fetchOutput)
+		return fmt.Errorf("error fetching %s to %s: %w", args[0], fetchOutput, err)
+	}
+	return nil
+}
+
+// fetchProgressReporter returns a progress callback that rewrites a single
+// status line on w.
+func fetchProgressReporter(w io.Writer) func(read, total int64) {
+	return func(read, total int64) {
+		if total < 0 {
+			fmt.Fprintf(w, "\r%d bytes", read)
+			return
+		}
+		fmt.Fprintf(w, "\r%d/%d bytes (%d%%)", read, total, read*100/max(total, 1))
+	}
+}
+
+// fetchRequest returns the request for rawURL: a GET, or a POST of the --form fields.
+func fetchRequest(rawURL string) (*client.Request, error) {
+	if len(fetchForm) == 0 {
+		if fetchURLForm {
+			return nil, fmt.Errorf("--urlencoded requires --form")
+		}
+		return client.NewRequest(http.GET, rawURL, "")
+	}
+
+	values := url.Values{}
+	m := client.NewMultipart()
+	for _, field := range fetchForm {
+		name, value, ok := strings.Cut(field, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid --form %q, expected name=value or name=@file", field)
+		}
+		path, isFile := strings.CutPrefix(value, "@")
+		switch {
+		case isFile && fetchURLForm:
+			return nil, fmt.Errorf("--form %s: files cannot be sent with --urlencoded", name)
+		case isFile:
+			if err := m.AddFile(name, path); err != nil {
+				return nil, err
+			}
+		case fetchURLForm:
+			values.Add(name, value)
+		default:
+			m.AddField(name, value)
+		}
+	}
+	if fetchURLForm {
+		return client.NewFormRequest(rawURL, values)
+	}
+	return client.NewMultipartRequest(rawURL, m)
+}
+
+// fetchCacheDirectory returns the directory cached responses are kept in.
+func fetchCacheDirectory() (string, error) {
+	if fetchCacheDir != "" {
+		return fetchCacheDir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("error finding the cache directory, use --cache-dir: %w", err)
+	}
+	return filepath.Join(dir, "volk", "fetch"), nil
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+
+	"github.com/awaisamjad/volk/config"
+	"github.com/awaisamjad/volk/internal/image"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/spf13/cobra"
+)
+
+var (
+	imageTag      string
+	imageOutput   string
+	imagePush     bool
+	imageBinary   string
+	imagePlatform string
+)
+
+var imageCmd = &cobra.Command{
+	Use:   "image",
+	Short: "Build container images",
+	Long:  "The image command groups subcommands for containerizing a site served by volk.",
+}
+
+var imageBuildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Build an OCI image containing volk, its config and the document root",
+	Long: `This command builds a container image from scratch without a Dockerfile.
+The image contains the volk binary, the current configuration and the contents of the document root.
+It is written to a tarball that can be loaded with "docker load", or pushed to a registry with --push.`,
+	Args: cobra.NoArgs,
+	RunE: runImageBuild,
+}
+
+func init() {
+	imageBuildCmd.Flags().StringVarP(&imageTag, "tag", "t", "volk-site:latest", "image reference to tag or push the image as")
+	imageBuildCmd.Flags().StringVarP(&imageOutput, "output", "o", "image.tar", "path of the image tarball")
+	imageBuildCmd.Flags().BoolVar(&imagePush, "push", false, "push the image to the registry in --tag instead of writing a tarball")
+	imageBuildCmd.Flags().StringVar(&imageBinary, "binary", "", "path to a linux volk binary (default: the running binary on linux)")
+	imageBuildCmd.Flags().StringVar(&imagePlatform, "platform", "linux/"+runtime.GOARCH, "platform of the binary, as os/arch")
+	imageCmd.AddCommand(imageBuildCmd)
+}
+
+func runImageBuild(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("error loading configuration: %w", err)
+	}
+
+	platform, err := v1.ParsePlatform(imagePlatform)
+	if err != nil {
+		return fmt.Errorf("invalid platform %q: %w", imagePlatform, err)
+	}
+	if platform.OS != "linux" {
+		return fmt.Errorf("invalid platform %q: only linux images are supported", imagePlatform)
+	}
+
+	binary := imageBinary
+	if binary == "" {
+		if runtime.GOOS != "linux" || runtime.GOARCH != platform.Architecture {
+			return fmt.Errorf("--binary is required when building a %s image on %s/%s", imagePlatform, runtime.GOOS, runtime.GOARCH)
+		}
+		binary, err = os.Executable()
+		if err != nil {
+			return fmt.Errorf("could not determine the volk binary: %w", err)
+		}
+	}
+
+	ref, err := name.ParseReference(imageTag)
+	if err != nil {
+		return fmt.Errorf("invalid tag %q: %w", imageTag, err)
+	}
+
+	img, cleanup, err := image.Build(image.Options{
+		Binary:   binary,
+		Config:   cfg,
+		Platform: *platform,
+	})
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	if imagePush {
+		if err := remote.Write(ref, img, remote.WithAuthFromKeychain(authn.DefaultKeychain)); err != nil {
+			return fmt.Errorf("error pushing image: %w", err)
+		}
+		fmt.Printf("Pushed %s\n", ref)
+		return nil
+	}
+
+	if err := tarball.WriteToFile(imageOutput, ref, img); err != nil {
+		return fmt.Errorf("error writing image: %w", err)
+	}
+	fmt.Printf("Wrote %s to %s (load it with: docker load -i %s)\n", ref, imageOutput, imageOutput)
+	return nil
+}
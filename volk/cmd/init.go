@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"embed"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/awaisamjad/volk/config"
+	"github.com/spf13/cobra"
+)
+
+//go:embed templates/init
+var initTemplates embed.FS
+
+var (
+	initForce   bool
+	initSystemd bool
+	initLaunchd bool
+	initDocker  bool
+)
+
+var initCmd = &cobra.Command{
+	Use:   "init [dir]",
+	Short: "Create a starter project",
+	Long: `This command creates a starter project in the given directory (the current directory by default):
+a commented volk_config.toml, and a public/ directory with index.html and 404.html.
+Optionally a systemd unit, a launchd job and a Dockerfile are generated as well.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runInit,
+}
+
+func init() {
+	initCmd.Flags().BoolVarP(&initForce, "force", "f", false, "overwrite existing files")
+	initCmd.Flags().BoolVar(&initSystemd, "systemd", false, "generate a volk.service systemd unit")
+	initCmd.Flags().BoolVar(&initLaunchd, "launchd", false, "generate a volk.plist launchd job for macOS")
+	initCmd.Flags().BoolVar(&initDocker, "docker", false, "generate a Dockerfile")
+}
+
+// initFile describes a file created by `volk init`.
+type initFile struct {
+	template string // path inside templates/init
+	target   string // path relative to the project directory
+}
+
+// initData is passed to the init templates.
+type initData struct {
+	Dir    string
+	Config config.Config
+}
+
+func runInit(cmd *cobra.Command, args []string) error {
+	dir := "."
+	if len(args) == 1 {
+		dir = args[0]
+	}
+
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("could not determine absolute path for %s: %w", dir, err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.FileServer.DocumentRoot = "public"
+	if initDocker {
+		// Inside a container the server must be reachable from outside.
+		cfg.Server.Host = "0.0.0.0"
+	}
+
+	files := []initFile{
+		{template: "volk_config.toml.tmpl", target: "volk_config.toml"},
+		{template: "index.html", target: filepath.Join("public", "index.html")},
+		{template: "404.html", target: filepath.Join("public", "404.html")},
+	}
+	if initSystemd {
+		files = append(files, initFile{template: "volk.service.tmpl", target: "volk.service"})
+	}
+	if initLaunchd {
+		files = append(files, initFile{template: "volk.plist.tmpl", target: "volk.plist"})
+	}
+	if initDocker {
+		files = append(files, initFile{template: "Dockerfile.tmpl", target: "Dockerfile"})
+	}
+
+	data := initData{Dir: absDir, Config: cfg}
+	for _, file := range files {
+		if err := writeInitFile(absDir, file, data); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Created a new volk project in %s\n", absDir)
+	fmt.Printf("Run `volk serve` from that directory to start serving.\n")
+	return nil
+}
+
+// writeInitFile renders a template into the project directory.
+// Existing files are left untouched unless --force is given.
+func writeInitFile(dir string, file initFile, data initData) error {
+	target := filepath.Join(dir, file.target)
+	if _, err := os.Stat(target); err == nil && !initForce {
+		fmt.Printf("Skipping %s: file already exists (use --force to overwrite)\n", file.target)
+		return nil
+	}
+
+	tmpl, err := template.ParseFS(initTemplates, "templates/init/"+file.template)
+	if err != nil {
+		return fmt.Errorf("error parsing template %s: %w", file.template, err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("error creating directory for %s: %w", file.target, err)
+	}
+
+	f, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", file.target, err)
+	}
+	defer f.Close()
+
+	if err := tmpl.Execute(f, data); err != nil {
+		return fmt.Errorf("error writing %s: %w", file.target, err)
+	}
+
+	fmt.Printf("Created %s\n", file.target)
+	return nil
+}
@@ -0,0 +1,42 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/cobra/doc"
+)
+
+var manCmd = &cobra.Command{
+	Use:   "man [dir]",
+	Short: "Generate manual pages",
+	Long: `This command generates a manual page for volk and each of its subcommands
+into the given directory (./man by default), ready to be installed into a man1 directory.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runMan,
+}
+
+func runMan(cmd *cobra.Command, args []string) error {
+	dir := "man"
+	if len(args) == 1 {
+		dir = args[0]
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("error creating %s: %w", dir, err)
+	}
+
+	header := &doc.GenManHeader{
+		Title:   "VOLK",
+		Section: "1",
+		Source:  "volk " + Version,
+		Manual:  "Volk Manual",
+	}
+	if err := doc.GenManTree(rootCmd, header, dir); err != nil {
+		return fmt.Errorf("error generating manual pages: %w", err)
+	}
+
+	fmt.Printf("Manual pages written to %s\n", dir)
+	return nil
+}
@@ -10,8 +10,15 @@ var rootCmd = &cobra.Command{
 	Long:  `Volk is a lightweight HTTP server written in Go, designed to serve static files with minimal configuration.`,
 }
 
+// configFile is the path given with --config; empty means the standard search path is used.
+var configFile string
+
 func init() {
-	rootCmd.AddCommand(serveCmd, dumpDefaultConfigCmd)
+	rootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "", "path to the configuration file (default: search volk_config.toml, server.toml, $XDG_CONFIG_HOME/volk/server.toml, /etc/volk/server.toml)")
+	rootCmd.Version = versionString()
+	rootCmd.SetVersionTemplate("{{.Version}}\n")
+	rootCmd.CompletionOptions.DisableDefaultCmd = true
+	rootCmd.AddCommand(serveCmd, dumpDefaultConfigCmd, versionCmd, initCmd, imageCmd, completionCmd, manCmd, statsCmd, fetchCmd, upgradeCmd, auditCmd, configCmd, serviceCmd, cacheCmd)
 }
 
 func Execute() error {
@@ -1,17 +1,21 @@
 package cmd
 
 import (
-	"bufio"
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net"
 	"os"
-	"path/filepath"
-	"strings"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/awaisamjad/volk/config"
+	"github.com/awaisamjad/volk/internal/harden"
 	"github.com/awaisamjad/volk/internal/http"
+	"github.com/awaisamjad/volk/internal/logsink"
+	"github.com/awaisamjad/volk/internal/upgrade"
 
 	"github.com/spf13/cobra"
 )
@@ -19,118 +23,309 @@ import (
 var serveCmd = &cobra.Command{
 	Use:   "serve",
 	Short: "Serve files over HTTP",
-	Long:  `The serve command starts an HTTP server that serves files from the directory.`,
-	Run:   runServer,
+	Long: `The serve command starts an HTTP server that serves files from the directory.
+
+With --container, the configuration is read from VOLK_* environment variables instead of a file,
+the log is written to standard output as JSON lines, /healthz and /readyz answer liveness and
+readiness probes, and SIGTERM drains the server before stopping it.`,
+	Run: runServer,
 }
 
-func runServer(cmd *cobra.Command, args []string) {
+var (
+	// serveWorkers is the number of processes given with --workers.
+	serveWorkers int
+	// serveDev enables development mode, like server.dev.
+	serveDev bool
+	// serveContainer enables container mode.
+	serveContainer bool
+)
 
-	cfg, err := config.LoadConfig()
+func init() {
+	serveCmd.Flags().IntVar(&serveWorkers, "workers", 1, "number of worker processes sharing the port with SO_REUSEPORT")
+	serveCmd.Flags().BoolVar(&serveDev, "dev", false, "development mode: detailed HTML error pages with the request, resolved file, config and stack traces")
+	serveCmd.Flags().BoolVar(&serveContainer, "container", false, "container mode: configuration from VOLK_* environment variables, JSON logs on standard output, probes and draining on SIGTERM")
+}
+
+// loadServeConfig returns the configuration the server runs with: the file
+// given with --config or found on the search path, or the environment in
+// container mode, and the flags of volk serve. It also returns the path of
+// the file, empty if none was used.
+func loadServeConfig() (config.Config, string) {
+	var cfg config.Config
+	var path string
+	var err error
+	if serveContainer {
+		if configFile != "" {
+			log.Fatal("--container reads the configuration from the environment and cannot be used with --config")
+		}
+		cfg, err = config.LoadEnv(os.Environ())
+	} else {
+		path = configFile
+		if path == "" {
+			path = config.FindConfigFile()
+		}
+		cfg, err = config.LoadConfig(path)
+	}
 	if err != nil {
 		log.Fatalf("Error loading configuration: %v", err)
 	}
 
+	if serveDev {
+		cfg.Server.Dev = true
+	}
+	return cfg, path
+}
+
+func runServer(cmd *cobra.Command, args []string) {
+	serviceStop, serviceDone := serviceControl()
+	defer serviceDone()
+
+	cfg, path := loadServeConfig()
+
 	setupLogging(cfg.Logging)
 
-	ln, err := net.Listen("tcp", fmt.Sprintf("localhost:%d", cfg.Server.Port))
-	if err != nil {
-		log.Fatal(err)
+	worker := os.Getenv(workerEnv)
+	if worker == "" {
+		if serveContainer {
+			log.Printf("Container mode, using configuration from the environment")
+		} else if path != "" {
+			log.Printf("Using configuration file: %s", path)
+		} else {
+			log.Printf("No configuration file found, using defaults")
+		}
 	}
 
-	fileServer := http.NewFileServer(cfg.FileServer)
-	http.DefaultFileServer = fileServer
+	if cfg.Server.Hardened {
+		if err := harden.Check(cfg, serveWorkers); err != nil {
+			log.Fatal(err)
+		}
+		if err := harden.CheckCapabilities(); err != nil {
+			log.Fatal(err)
+		}
+	}
 
-	fmt.Printf("Listening on localhost:%d\n", cfg.Server.Port)
-	fmt.Printf("Serving files from: %s\n", cfg.FileServer.DocumentRoot)
+	if cfg.KV.Enabled && cfg.Server.PIDFile != "" {
+		log.Fatal("the key-value API keeps its database open in one process and cannot be used with pid_file, as volk upgrade starts a second one")
+	}
 
-	for {
-		conn, err := ln.Accept()
-		if err != nil {
-			log.Fatal("Error accepting connection ", err)
+	if worker == "" && serveWorkers > 1 {
+		if err := runWorkers(cfg, serveWorkers); err != nil {
+			log.Fatal(err)
 		}
-		go handleConnection(conn, cfg)
+		return
+	}
+	if worker != "" {
+		// Workers share the port, so each of them needs SO_REUSEPORT.
+		cfg.Server.ReusePort = true
+		log.SetPrefix("[worker " + worker + "] ")
 	}
 
-}
-
-func setupLogging(logConfig config.LogConfig) {
-	var logOutput *os.File
-	var err error
+	server, err := http.NewServer(cfg)
+	if err != nil {
+		log.Fatal(err)
+	}
+	server.Version = Version
 
-	if logConfig.FilePath != "" {
-		dir := filepath.Dir(logConfig.FilePath)
-		if err := os.MkdirAll(dir, 0755); err != nil {
-			log.Printf("Warning: Could not create log directory: %v", err)
+	if cfg.GeoIP.Database == "" {
+		for _, location := range cfg.Locations {
+			if len(location.AllowCountries) > 0 {
+				log.Printf("Warning: location %s has allow_countries but no [geoip] database is configured; all requests to it will be denied", location.Path)
+			}
 		}
+	}
 
-		logOutput, err = os.OpenFile(logConfig.FilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-		if err != nil {
-			log.Printf("Warning: Could not open log file: %v", err)
-		} else {
-			log.SetOutput(logOutput)
+	if serveContainer {
+		// Standard output only carries the JSON log.
+		log.Printf("Listening on %s, serving files from %s", server.Addr(), cfg.FileServer.DocumentRoot)
+	} else if worker == "" {
+		fmt.Printf("Listening on %s\n", server.Addr())
+		fmt.Printf("Serving files from: %s\n", cfg.FileServer.DocumentRoot)
+	}
+
+	// After an upgrade, the listener comes from the process being replaced.
+	ln, err := upgrade.Inherited()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if ln != nil {
+		log.Printf("Serving on the listener of the previous process")
+	} else if ln, err = server.Listen(); err != nil {
+		log.Fatal(err)
+	}
+	if cfg.Server.PIDFile != "" && worker == "" {
+		if err := upgrade.WritePIDFile(cfg.Server.PIDFile); err != nil {
+			log.Fatal(err)
 		}
 	}
-	switch logConfig.Format {
-	case "plain":
-		log.SetFlags(log.Ldate | log.Ltime | log.Lshortfile)
-	case "verbose":
-		log.SetFlags(log.Ldate | log.Ltime | log.Llongfile)
+	if err := upgrade.Ready(); err != nil {
+		log.Fatal(err)
 	}
 
-}
+	stopped := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	upgrades := make(chan os.Signal, 1)
+	if !cfg.Server.Hardened && !serveContainer {
+		// Upgrades start the new binary, which hardened mode does not allow.
+		// Containers are upgraded by replacing them.
+		upgrade.Notify(upgrades)
+	}
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case sig := <-signals:
+				if serveContainer && sig == syscall.SIGTERM {
+					drainServer(server, cfg, signals)
+					return
+				}
+				log.Printf("Received %s, shutting down", sig)
+				stopServer(server, cfg, signals)
+				return
+			case <-serviceStop:
+				log.Printf("Service stopping, shutting down")
+				stopServer(server, cfg, signals)
+				return
+			case <-upgrades:
+				if upgradeServer(server, ln, cfg) {
+					return
+				}
+			}
+		}
+	}()
 
-func handleConnection(conn net.Conn, cfg config.Config) {
-	defer conn.Close()
+	if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+	<-stopped
+}
 
-	if cfg.Server.ReadTimeout > 0 {
-		deadline := time.Now().Add(time.Duration(cfg.Server.ReadTimeout) * time.Second)
-		conn.SetReadDeadline(deadline)
+// drainServer stops the server of a container gracefully: the readiness probe
+// fails for server.drain_delay seconds while requests are still served, so
+// that the orchestrator removes the container from its endpoints, and then
+// the server stops accepting connections and waits up to
+// server.shutdown_timeout seconds for open ones. Another signal ends the
+// delay early.
+func drainServer(server *http.Server, cfg config.Config, signals <-chan os.Signal) {
+	delay := time.Duration(cfg.Server.DrainDelay) * time.Second
+	log.Printf("Received SIGTERM, draining for %s", delay)
+	server.Drain()
+	select {
+	case <-time.After(delay):
+	case sig := <-signals:
+		log.Printf("Received %s, ending the drain delay", sig)
 	}
 
-	reader := bufio.NewReader(conn)
-	var requestBuilder strings.Builder
-	startLine, err := reader.ReadString('\n')
+	stopServer(server, cfg, signals)
+}
+
+// stopServer stops the server from accepting connections and waits up to
+// server.shutdown_timeout seconds for the requests in flight before it closes
+// the key-value database, the audit log and the other resources they use.
+// Another signal stops waiting.
+func stopServer(server *http.Server, cfg config.Config, signals <-chan os.Signal) {
+	timeout := time.Duration(cfg.Server.ShutdownTimeout) * time.Second
+	log.Printf("Shutting down, waiting up to %s for %d requests in flight", timeout, server.InFlight())
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	go func() {
+		select {
+		case sig := <-signals:
+			log.Printf("Received %s, shutting down", sig)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Stopped before all connections finished: %v", err)
+	}
+}
 
+// upgradeServer starts the binary at the path of the running one, which may
+// have been replaced by a new version, hands it the listener and drains the
+// server's connections. It reports whether the server was stopped; if the new
+// process fails to start, the server keeps running.
+func upgradeServer(server *http.Server, ln net.Listener, cfg config.Config) bool {
+	if cfg.KV.Enabled {
+		log.Printf("Upgrade refused: the key-value database cannot be opened by a second process")
+		return false
+	}
+	executable, err := os.Executable()
 	if err != nil {
-		log.Printf("Error reading start line: %v", err)
-		return
+		log.Printf("Upgrade failed: error finding the volk binary: %v", err)
+		return false
+	}
+	log.Printf("Upgrading: starting %s", executable)
+	pid, err := upgrade.Start(ln, executable, os.Args[1:])
+	if err != nil {
+		log.Printf("Upgrade failed, continuing to serve: %v", err)
+		return false
 	}
-	requestBuilder.WriteString(startLine)
 
-	for {
-		line, err := reader.ReadString('\n')
+	timeout := time.Duration(cfg.Server.ShutdownTimeout) * time.Second
+	log.Printf("Process %d took over, waiting up to %s for open connections", pid, timeout)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.Printf("Stopped before all connections finished: %v", err)
+	}
+	return true
+}
+
+func setupLogging(logConfig config.LogConfig) {
+	if len(logConfig.Sinks) > 0 {
+		fanout, err := logsink.NewFanout(logConfig.Sinks)
 		if err != nil {
-			log.Printf("Error reading header line: %v", err)
-			return
+			log.Fatalf("Error loading configuration: %v", err)
 		}
+		log.SetOutput(fanout)
+		// The sinks add the time and shorten the file name themselves.
+		log.SetFlags(log.Llongfile)
+		return
+	}
 
-		requestBuilder.WriteString(line)
-
-		if line == "\r\n" || line == "\n" {
+	switch logConfig.Output {
+	case "", "file":
+		setupLogFile(logConfig.FilePath, logConfig.Compress)
+	case "stdout":
+		log.SetOutput(os.Stdout)
+	case "syslog", "journald":
+		sink, err := logsink.New(logConfig)
+		if err != nil {
+			log.Printf("Warning: Could not log to %s: %v", logConfig.Output, err)
 			break
 		}
+		log.SetOutput(sink)
+	default:
+		log.Fatalf("Error loading configuration: unknown logging output %q", logConfig.Output)
 	}
 
-	req, err := http.NewRequest(requestBuilder.String())
-	if err != nil {
-		log.Printf("Error parsing request: %v", err)
-		conn.Write([]byte("HTTP/1.1 400 Bad Request\r\nContent-Type: text/plain\r\n\r\nBad Request"))
-		return
+	var flags int
+	switch logConfig.Format {
+	case "plain":
+		flags = log.Ldate | log.Ltime | log.Lshortfile
+	case "verbose":
+		flags = log.Ldate | log.Ltime | log.Llongfile
+	default:
+		flags = log.Flags()
 	}
-
-	resp := req.Response()
-
-	_, err = conn.Write([]byte(resp.String()))
-	if err != nil {
-		log.Printf("Error writing response: %v", err)
+	if logsink.IsSink(logConfig.Output) {
+		// The system log records the time itself.
+		flags &^= log.Ldate | log.Ltime | log.Lmicroseconds
 	}
+	log.SetFlags(flags)
+}
 
-	if cfg.Logging.AccessLogs {
-		log.Printf("Access: %s %s %s - %d %s",
-			req.StartLine.Method,
-			req.StartLine.RequestTarget,
-			req.StartLine.Protocol,
-			resp.StartLine.StatusCode,
-			resp.StartLine.StatusText)
+// setupLogFile sends the log to the file at path, if set, which may be a
+// pattern of the time the file rolls over with.
+func setupLogFile(path string, compress bool) {
+	if path == "" {
+		return
+	}
+	logOutput, err := logsink.OpenFile(path, compress)
+	if err != nil {
+		log.Printf("Warning: Could not open log file: %v", err)
+		return
 	}
+	log.SetOutput(logOutput)
 }
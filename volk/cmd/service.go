@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+)
+
+// serviceName is the name given with --name.
+var serviceName string
+
+var serviceCmd = &cobra.Command{
+	Use:   "service",
+	Short: "Run volk as a Windows service",
+	Long: `These commands register volk with the Windows service manager and start or stop it.
+The service runs "volk serve" with the configuration file given with --config, which is
+made absolute at install time. On macOS use "volk init --launchd" to generate a launchd
+job, and on Linux "volk init --systemd" to generate a systemd unit.`,
+}
+
+var serviceInstallCmd = &cobra.Command{
+	Use:   "install",
+	Short: "Register volk as a service that starts automatically",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		exe, err := os.Executable()
+		if err != nil {
+			return fmt.Errorf("error finding the volk binary: %w", err)
+		}
+		serviceArgs := []string{"serve"}
+		if configFile != "" {
+			path, err := filepath.Abs(configFile)
+			if err != nil {
+				return fmt.Errorf("could not determine absolute path for %s: %w", configFile, err)
+			}
+			serviceArgs = append(serviceArgs, "--config", path)
+		}
+		if err := installService(serviceName, exe, serviceArgs); err != nil {
+			return err
+		}
+		fmt.Printf("Installed service %s\n", serviceName)
+		return nil
+	},
+}
+
+var serviceUninstallCmd = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Remove the volk service",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := uninstallService(serviceName); err != nil {
+			return err
+		}
+		fmt.Printf("Removed service %s\n", serviceName)
+		return nil
+	},
+}
+
+var serviceStartCmd = &cobra.Command{
+	Use:   "start",
+	Short: "Start the volk service",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return startService(serviceName)
+	},
+}
+
+var serviceStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the volk service",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return stopService(serviceName)
+	},
+}
+
+func init() {
+	serviceCmd.PersistentFlags().StringVar(&serviceName, "name", "volk", "name of the service")
+	serviceCmd.AddCommand(serviceInstallCmd, serviceUninstallCmd, serviceStartCmd, serviceStopCmd)
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package cmd
+
+import "errors"
+
+// errServiceUnsupported is returned by the service commands outside Windows.
+var errServiceUnsupported = errors.New(`services are only supported on Windows; use "volk init --systemd" or "volk init --launchd" instead`)
+
+func installService(name, exe string, args []string) error { return errServiceUnsupported }
+
+func uninstallService(name string) error { return errServiceUnsupported }
+
+func startService(name string) error { return errServiceUnsupported }
+
+func stopService(name string) error { return errServiceUnsupported }
+
+// serviceControl returns a nil channel: the process never runs as a Windows service.
+func serviceControl() (stop <-chan struct{}, done func()) { return nil, func() {} }
@@ -0,0 +1,153 @@
+//go:build windows
+
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// serviceStopTimeout is how long `volk service stop` waits for the service to stop.
+const serviceStopTimeout = 30 * time.Second
+
+// installService registers exe as an automatically started service that the
+// service manager restarts when it fails, like Restart=on-failure does under systemd.
+func installService(name, exe string, args []string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to the service manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	if s, err := m.OpenService(name); err == nil {
+		s.Close()
+		return fmt.Errorf("service %s already exists", name)
+	}
+
+	s, err := m.CreateService(name, exe, mgr.Config{
+		DisplayName: "Volk HTTP server",
+		Description: "Serves static files with volk",
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return fmt.Errorf("error creating service %s: %w", name, err)
+	}
+	defer s.Close()
+
+	restart := mgr.RecoveryAction{Type: mgr.ServiceRestart, Delay: 5 * time.Second}
+	if err := s.SetRecoveryActions([]mgr.RecoveryAction{restart, restart, restart}, uint32((24 * time.Hour).Seconds())); err != nil {
+		return fmt.Errorf("error setting the recovery actions of service %s: %w", name, err)
+	}
+	return nil
+}
+
+func uninstallService(name string) error {
+	return withService(name, func(s *mgr.Service) error {
+		if err := s.Delete(); err != nil {
+			return fmt.Errorf("error removing service %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+func startService(name string) error {
+	return withService(name, func(s *mgr.Service) error {
+		if err := s.Start(); err != nil {
+			return fmt.Errorf("error starting service %s: %w", name, err)
+		}
+		return nil
+	})
+}
+
+// stopService asks the service to stop and waits until it has.
+func stopService(name string) error {
+	return withService(name, func(s *mgr.Service) error {
+		status, err := s.Control(svc.Stop)
+		if err != nil {
+			return fmt.Errorf("error stopping service %s: %w", name, err)
+		}
+		deadline := time.Now().Add(serviceStopTimeout)
+		for status.State != svc.Stopped {
+			if time.Now().After(deadline) {
+				return fmt.Errorf("service %s did not stop within %s", name, serviceStopTimeout)
+			}
+			time.Sleep(300 * time.Millisecond)
+			if status, err = s.Query(); err != nil {
+				return fmt.Errorf("error querying service %s: %w", name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// withService calls fn with the named service.
+func withService(name string, fn func(s *mgr.Service) error) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("error connecting to the service manager: %w", err)
+	}
+	defer m.Disconnect()
+
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("error opening service %s: %w", name, err)
+	}
+	defer s.Close()
+	return fn(s)
+}
+
+// serviceControl reports to the service manager when the process runs as a
+// service. The returned channel is closed when the service manager asks the
+// service to stop, and done must be called once the server has stopped, so the
+// service is not reported stopped while connections are still being closed.
+// Outside a service the channel is nil.
+func serviceControl() (stop <-chan struct{}, done func()) {
+	isService, err := svc.IsWindowsService()
+	if err != nil || !isService {
+		return nil, func() {}
+	}
+
+	// Services start in the system directory; relative paths in the
+	// configuration are meant relative to the configuration file.
+	if configFile != "" {
+		if err := os.Chdir(filepath.Dir(configFile)); err != nil {
+			log.Printf("Warning: could not change to the configuration directory: %v", err)
+		}
+	}
+
+	handler := &windowsService{stop: make(chan struct{}), done: make(chan struct{})}
+	go func() {
+		if err := svc.Run(serviceName, handler); err != nil {
+			log.Printf("Error running as a service: %v", err)
+		}
+	}()
+	return handler.stop, func() { close(handler.done) }
+}
+
+// windowsService answers the requests of the service manager.
+type windowsService struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (ws *windowsService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	for req := range requests {
+		switch req.Cmd {
+		case svc.Interrogate:
+			status <- req.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			status <- svc.Status{State: svc.StopPending}
+			close(ws.stop)
+			<-ws.done
+			return false, 0
+		}
+	}
+	return false, 0
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+	"github.com/awaisamjad/volk/internal/stats"
+	"github.com/spf13/cobra"
+	"golang.org/x/net/idna"
+)
+
+var (
+	statsSince time.Duration
+	statsTop   int
+)
+
+var statsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the persisted request statistics",
+	Long: `This command reads the statistics written by the server when [stats] is enabled
+and prints the number of requests, bytes received and sent, the status breakdown, the most
+requested paths and hosts, the requests per location, and the page views and referrers
+reported to the beacon endpoint.
+It does not need a running server.`,
+	Args: cobra.NoArgs,
+	RunE: runStats,
+}
+
+func init() {
+	statsCmd.Flags().DurationVar(&statsSince, "since", 0, "only include statistics from this long ago, e.g. 24h (default: everything)")
+	statsCmd.Flags().IntVar(&statsTop, "top", 10, "number of most requested paths and hosts to show")
+}
+
+func runStats(cmd *cobra.Command, args []string) error {
+	cfg, err := config.LoadConfig(configFile)
+	if err != nil {
+		return fmt.Errorf("error loading configuration: %w", err)
+	}
+
+	var since time.Time
+	if statsSince > 0 {
+		since = time.Now().Add(-statsSince)
+	}
+
+	snapshot, err := stats.Load(cfg.Stats.FilePath, since)
+	if err != nil {
+		return err
+	}
+
+	if snapshot.Requests == 0 {
+		fmt.Printf("No statistics recorded in %s\n", cfg.Stats.FilePath)
+		return nil
+	}
+
+	fmt.Printf("Period:   %s - %s\n", snapshot.Start.Format(time.RFC3339), snapshot.End.Format(time.RFC3339))
+	fmt.Printf("Requests: %d\n", snapshot.Requests)
+	fmt.Printf("Bytes:    %d\n", snapshot.Bytes)
+	fmt.Printf("Received: %d\n", snapshot.RequestBytes)
+
+	fmt.Println("\nStatus codes:")
+	for _, count := range snapshot.StatusBreakdown() {
+		fmt.Printf("  %s  %d\n", count.Key, count.Count)
+	}
+
+	fmt.Printf("\nTop %d paths:\n", statsTop)
+	for _, count := range snapshot.TopPaths(statsTop) {
+		fmt.Printf("  %8d  %s\n", count.Count, count.Key)
+	}
+
+	if hosts := snapshot.TopHosts(statsTop); len(hosts) > 0 {
+		fmt.Printf("\nTop %d hosts (requests, bytes received, bytes sent):\n", statsTop)
+		for i := range hosts {
+			hosts[i].Key = displayHost(hosts[i].Key)
+		}
+		printGroups(hosts)
+	}
+	if locations := snapshot.LocationBreakdown(); len(locations) > 0 {
+		fmt.Println("\nLocations (requests, bytes received, bytes sent):")
+		printGroups(locations)
+	}
+	if pages := snapshot.TopPageViews(statsTop); len(pages) > 0 {
+		fmt.Printf("\nTop %d pages by beacon views:\n", statsTop)
+		for _, count := range pages {
+			fmt.Printf("  %8d  %s\n", count.Count, count.Key)
+		}
+	}
+	if referrers := snapshot.TopReferrers(statsTop); len(referrers) > 0 {
+		fmt.Printf("\nTop %d referrers:\n", statsTop)
+		for _, count := range referrers {
+			fmt.Printf("  %8d  %s\n", count.Count, displayHost(count.Key))
+		}
+	}
+
+	return nil
+}
+
+// printGroups prints the counters of hosts or locations, one per line.
+func printGroups(groups []stats.GroupCount) {
+	for _, group := range groups {
+		fmt.Printf("  %8d  %12d  %12d  %s\n", group.Requests, group.RequestBytes, group.ResponseBytes, group.Key)
+	}
+}
+
+// displayHost adds the Unicode form to an internationalized host name, which
+// the statistics store in its ASCII (punycode) form.
+func displayHost(host string) string {
+	if !strings.Contains(host, "xn--") {
+		return host
+	}
+	unicode, err := idna.ToUnicode(host)
+	if err != nil || unicode == host {
+		return host
+	}
+	return fmt.Sprintf("%s (%s)", host, unicode)
+}
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+	"github.com/awaisamjad/volk/internal/upgrade"
+
+	"github.com/spf13/cobra"
+)
+
+var upgradeTimeout time.Duration
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade",
+	Short: "Replace the running server with the current binary without dropping connections",
+	Long: `The upgrade command asks the running volk server, found through server.pid_file,
+to start the volk binary at its path again. The new process takes over the
+listening socket and the old one finishes its open connections and exits.
+Install the new binary first, then run volk upgrade.`,
+	Args: cobra.NoArgs,
+	Run:  runUpgrade,
+}
+
+func init() {
+	upgradeCmd.Flags().DurationVar(&upgradeTimeout, "timeout", 30*time.Second, "how long to wait for the new process to take over")
+}
+
+func runUpgrade(cmd *cobra.Command, args []string) {
+	path := configFile
+	if path == "" {
+		path = config.FindConfigFile()
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		log.Fatalf("Error loading configuration: %v", err)
+	}
+	if cfg.Server.PIDFile == "" {
+		log.Fatal("server.pid_file must be set to find the running server")
+	}
+
+	pid, err := upgrade.ReadPIDFile(cfg.Server.PIDFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := upgrade.Request(pid); err != nil {
+		log.Fatal(err)
+	}
+
+	// The new process writes its ID to the pid file once it is serving.
+	deadline := time.Now().Add(upgradeTimeout)
+	for time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+		if newPID, err := upgrade.ReadPIDFile(cfg.Server.PIDFile); err == nil && newPID != pid {
+			fmt.Printf("Upgraded: process %d replaced process %d\n", newPID, pid)
+			return
+		}
+	}
+	log.Fatalf("Process %d did not hand over within %s; see the server log", pid, upgradeTimeout)
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime/debug"
+
+	"github.com/spf13/cobra"
+)
+
+// Build information. These are set at build time with
+// -ldflags "-X github.com/awaisamjad/volk/volk/cmd.Version=..." and friends;
+// see the justfile.
+var (
+	Version   = "dev"
+	Commit    = "none"
+	BuildDate = "unknown"
+)
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the version information",
+	Long:  "This command prints the version, commit and build date of the volk binary.",
+	Run:   printVersion,
+}
+
+func printVersion(cmd *cobra.Command, args []string) {
+	fmt.Println(versionString())
+}
+
+// versionString returns the version line shown by `volk version` and `volk --version`.
+// When the binary was built without ldflags, the commit is taken from the Go build info if available.
+func versionString() string {
+	commit := Commit
+	if commit == "none" {
+		if info, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range info.Settings {
+				if setting.Key == "vcs.revision" {
+					commit = setting.Value
+				}
+			}
+		}
+	}
+	return fmt.Sprintf("volk %s (commit %s, built %s)", Version, commit, BuildDate)
+}
@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/awaisamjad/volk/config"
+	"github.com/awaisamjad/volk/internal/upgrade"
+)
+
+// workerEnv marks a process started by runWorkers and holds its number.
+const workerEnv = "VOLK_WORKER"
+
+// workerRestartDelay is how long a crashed worker waits before it is restarted.
+const workerRestartDelay = time.Second
+
+// runWorkers starts n copies of the running binary with the same arguments, all
+// listening on the configured port with SO_REUSEPORT, and restarts workers that
+// exit unexpectedly. SIGINT and SIGTERM are passed on to the workers, and
+// runWorkers returns once they have all exited. The main process writes the
+// pid file, but refuses upgrades: it would have to replace every worker.
+func runWorkers(cfg config.Config, n int) error {
+	if cfg.KV.Enabled {
+		return fmt.Errorf("the key-value API keeps its database open in one process and cannot be used with --workers")
+	}
+	for _, location := range cfg.Locations {
+		// Each worker would remember the signatures it has seen on its own,
+		// so a captured request could be replayed once per worker.
+		if location.SignatureSecret != "" {
+			return fmt.Errorf("location %s has a signature_secret, whose replay protection is kept per process and cannot be used with --workers", location.Path)
+		}
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("error finding the volk binary: %w", err)
+	}
+
+	var mu sync.Mutex
+	stopping := false
+	workers := make([]*exec.Cmd, n)
+
+	start := func(i int) (*exec.Cmd, error) {
+		cmd := exec.Command(executable, os.Args[1:]...)
+		cmd.Env = append(os.Environ(), workerEnv+"="+strconv.Itoa(i+1))
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("error starting worker %d: %w", i+1, err)
+		}
+		return cmd, nil
+	}
+
+	if cfg.Server.PIDFile != "" {
+		if err := upgrade.WritePIDFile(cfg.Server.PIDFile); err != nil {
+			return err
+		}
+	}
+	upgrades := make(chan os.Signal, 1)
+	upgrade.Notify(upgrades)
+	go func() {
+		for range upgrades {
+			log.Printf("Upgrade refused: upgrades are not available with --workers; restart volk to run a new binary")
+		}
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		log.Printf("Received %s, stopping %d workers", sig, n)
+		mu.Lock()
+		defer mu.Unlock()
+		stopping = true
+		for _, worker := range workers {
+			if worker != nil {
+				worker.Process.Signal(sig)
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := range workers {
+		mu.Lock()
+		if stopping {
+			mu.Unlock()
+			break
+		}
+		cmd, err := start(i)
+		if err != nil {
+			// Stop the workers started so far rather than leave them running without a parent.
+			stopping = true
+			for _, worker := range workers[:i] {
+				worker.Process.Kill()
+			}
+			mu.Unlock()
+			wg.Wait()
+			return err
+		}
+		workers[i] = cmd
+		mu.Unlock()
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				err := cmd.Wait()
+
+				mu.Lock()
+				if stopping {
+					mu.Unlock()
+					return
+				}
+				log.Printf("Worker %d (pid %d) exited: %v; restarting", i+1, cmd.Process.Pid, err)
+				mu.Unlock()
+
+				time.Sleep(workerRestartDelay)
+
+				mu.Lock()
+				if stopping {
+					mu.Unlock()
+					return
+				}
+				cmd, err = start(i)
+				if err != nil {
+					mu.Unlock()
+					log.Print(err)
+					return
+				}
+				workers[i] = cmd
+				mu.Unlock()
+			}
+		}()
+	}
+	log.Printf("Started %d workers on %s", n, net.JoinHostPort(cfg.Server.Host, strconv.Itoa(cfg.Server.Port)))
+
+	wg.Wait()
+	return nil
+}
@@ -0,0 +1,189 @@
+// Package volktest provides utilities for integration tests against an in-process volk server.
+//
+// A test creates a Server from a configuration, sends requests to it with the
+// embedded client and asserts on the requests the server recorded:
+//
+//	srv := volktest.NewServer(t, cfg)
+//	resp := srv.MustGet(t, "/index.html")
+//	srv.AssertRequested(t, "GET", "/index.html")
+package volktest
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/awaisamjad/volk/client"
+	"github.com/awaisamjad/volk/config"
+	"github.com/awaisamjad/volk/internal/http"
+)
+
+// Aliases for the HTTP types recorded by the server.
+type (
+	Request  = http.Request
+	Response = http.Response
+)
+
+// Exchange is a request received by the server together with the response it sent.
+type Exchange struct {
+	Request  Request
+	Response Response
+}
+
+// Server is a volk server listening on a random local port.
+type Server struct {
+	// URL is the base URL of the server, e.g. http://127.0.0.1:41234.
+	URL string
+	// Config is the configuration the server runs with. Its port is the one actually in use.
+	Config config.Config
+	// Client sends requests to the server.
+	Client *client.Client
+
+	server *http.Server
+
+	mu        sync.Mutex
+	exchanges []Exchange
+}
+
+// NewServer starts a server with the given configuration on a random port of 127.0.0.1.
+// The server is closed when the test finishes.
+func NewServer(t testing.TB, cfg config.Config) *Server {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("volktest: could not listen: %v", err)
+	}
+
+	cfg.Server.Host = "127.0.0.1"
+	cfg.Server.Port = ln.Addr().(*net.TCPAddr).Port
+
+	server, err := http.NewServer(cfg)
+	if err != nil {
+		ln.Close()
+		t.Fatalf("volktest: could not create server: %v", err)
+	}
+
+	s := &Server{
+		URL:    "http://" + net.JoinHostPort(cfg.Server.Host, strconv.Itoa(cfg.Server.Port)),
+		Config: cfg,
+		Client: client.New(),
+		server: server,
+	}
+	s.server.RequestHook = s.record
+
+	go s.server.Serve(ln)
+	t.Cleanup(s.Close)
+
+	return s
+}
+
+// Close stops the server. It is called automatically when the test finishes.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// record stores an exchange; it is installed as the server's RequestHook.
+func (s *Server) record(req http.Request, resp http.Response) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exchanges = append(s.exchanges, Exchange{Request: req, Response: resp})
+}
+
+// Exchanges returns the exchanges recorded so far, in the order they completed.
+func (s *Server) Exchanges() []Exchange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Exchange(nil), s.exchanges...)
+}
+
+// Requests returns the requests recorded so far, in the order they completed.
+func (s *Server) Requests() []Request {
+	exchanges := s.Exchanges()
+	requests := make([]Request, len(exchanges))
+	for i, e := range exchanges {
+		requests[i] = e.Request
+	}
+	return requests
+}
+
+// Reset forgets all recorded exchanges.
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.exchanges = nil
+}
+
+// Get sends a GET request for the path to the server.
+func (s *Server) Get(path string) (Response, error) {
+	return s.Client.Get(s.URL + path)
+}
+
+// Do sends a request with the given method, path, headers and body to the server.
+func (s *Server) Do(method http.Method, path string, headers []http.Header, body string) (Response, error) {
+	req, err := client.NewRequest(method, s.URL+path, body)
+	if err != nil {
+		return Response{}, err
+	}
+	req.Headers = headers
+	return s.Client.Do(req)
+}
+
+// MustGet is like Get but fails the test on error.
+func (s *Server) MustGet(t testing.TB, path string) Response {
+	t.Helper()
+	resp, err := s.Get(path)
+	if err != nil {
+		t.Fatalf("volktest: GET %s failed: %v", path, err)
+	}
+	return resp
+}
+
+// AssertRequested fails the test unless a request with the method and path was recorded.
+func (s *Server) AssertRequested(t testing.TB, method http.Method, path string) {
+	t.Helper()
+	for _, req := range s.Requests() {
+		if req.GetMethod() == method && req.GetRequestTarget().Path == path {
+			return
+		}
+	}
+	t.Errorf("volktest: expected a %s %s request, got %v", method, path, s.requestLines())
+}
+
+// AssertRequestCount fails the test unless exactly n requests were recorded.
+func (s *Server) AssertRequestCount(t testing.TB, n int) {
+	t.Helper()
+	if got := len(s.Requests()); got != n {
+		t.Errorf("volktest: expected %d requests, got %d: %v", n, got, s.requestLines())
+	}
+}
+
+// AssertStatus fails the test unless the response has the expected status code.
+func AssertStatus(t testing.TB, resp Response, status int) {
+	t.Helper()
+	if int(resp.GetStatusCode()) != status {
+		t.Errorf("volktest: expected status %d, got %d %s", status, resp.GetStatusCode(), resp.GetStatusText())
+	}
+}
+
+// AssertHeader fails the test unless the response has a header with the expected value.
+func AssertHeader(t testing.TB, resp Response, name, value string) {
+	t.Helper()
+	got, ok := http.GetHeader(resp.Headers, name)
+	if !ok {
+		t.Errorf("volktest: expected header %s: %s, header missing", name, value)
+	} else if got != value {
+		t.Errorf("volktest: expected header %s: %s, got %s", name, value, got)
+	}
+}
+
+// requestLines returns the start lines of the recorded requests, for error messages.
+func (s *Server) requestLines() []string {
+	requests := s.Requests()
+	lines := make([]string, len(requests))
+	for i, req := range requests {
+		lines[i] = req.StartLine.String()
+	}
+	return lines
+}
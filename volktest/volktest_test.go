@@ -0,0 +1,126 @@
+package volktest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/awaisamjad/volk/client"
+	"github.com/awaisamjad/volk/config"
+)
+
+func newTestConfig(t *testing.T) config.Config {
+	t.Helper()
+
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "index.html"), []byte("<h1>Hello World</h1>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.DefaultConfig()
+	cfg.FileServer.DocumentRoot = root
+	cfg.Logging.AccessLogs = false
+	return cfg
+}
+
+func TestServerServesFiles(t *testing.T) {
+	srv := NewServer(t, newTestConfig(t))
+
+	resp := srv.MustGet(t, "/index.html")
+	AssertStatus(t, resp, 200)
+	AssertHeader(t, resp, "Content-Type", "text/html; charset=utf-8")
+	if resp.GetBody() != "<h1>Hello World</h1>" {
+		t.Errorf("Expected body <h1>Hello World</h1>, got %q", resp.GetBody())
+	}
+
+	resp = srv.MustGet(t, "/missing.html")
+	AssertStatus(t, resp, 404)
+}
+
+func TestServerRecordsRequests(t *testing.T) {
+	srv := NewServer(t, newTestConfig(t))
+
+	srv.MustGet(t, "/")
+	srv.MustGet(t, "/index.html?x=1")
+
+	srv.AssertRequestCount(t, 2)
+	srv.AssertRequested(t, "GET", "/")
+	srv.AssertRequested(t, "GET", "/index.html")
+
+	exchanges := srv.Exchanges()
+	if exchanges[1].Response.GetStatusCode() != 200 {
+		t.Errorf("Expected recorded status 200, got %d", exchanges[1].Response.GetStatusCode())
+	}
+
+	srv.Reset()
+	srv.AssertRequestCount(t, 0)
+}
+
+func TestServerDo(t *testing.T) {
+	srv := NewServer(t, newTestConfig(t))
+
+	resp, err := srv.Do("POST", "/submit", nil, `{"name": "volk"}`)
+	if err != nil {
+		t.Fatalf("Do returned an error: %v", err)
+	}
+	AssertStatus(t, resp, 501)
+
+	req := srv.Requests()[0]
+	if req.GetMethod() != "POST" {
+		t.Errorf("Expected recorded method POST, got %s", req.GetMethod())
+	}
+}
+
+func TestSignedLocation(t *testing.T) {
+	cfg := newTestConfig(t)
+	cfg.Locations = []config.LocationConfig{{Path: "/", SignatureSecret: "s3cret"}}
+	srv := NewServer(t, cfg)
+
+	AssertStatus(t, srv.MustGet(t, "/index.html"), 401)
+
+	srv.Client.Transport = client.NewSigningTransport(nil, []byte("s3cret"))
+	AssertStatus(t, srv.MustGet(t, "/index.html"), 200)
+
+	srv.Client.Transport = client.NewSigningTransport(nil, []byte("wrong"))
+	AssertStatus(t, srv.MustGet(t, "/index.html"), 401)
+}
+
+func TestServerHardened(t *testing.T) {
+	cfg := newTestConfig(t)
+	root := cfg.FileServer.DocumentRoot
+	secret := filepath.Join(t.TempDir(), "secret.txt")
+	if err := os.WriteFile(secret, []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(secret, filepath.Join(root, "leak.txt")); err != nil {
+		t.Skipf("symbolic links not available: %v", err)
+	}
+	if err := os.Symlink("index.html", filepath.Join(root, "home.html")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without hardened mode the link out of the document root is followed.
+	srv := NewServer(t, cfg)
+	AssertStatus(t, srv.MustGet(t, "/leak.txt"), 200)
+
+	workDir := t.TempDir()
+	t.Chdir(workDir)
+
+	cfg.Server.Hardened = true
+	srv = NewServer(t, cfg)
+	AssertStatus(t, srv.MustGet(t, "/index.html"), 200)
+	AssertStatus(t, srv.MustGet(t, "/home.html"), 200)
+	resp := srv.MustGet(t, "/leak.txt")
+	AssertStatus(t, resp, 404)
+	if resp.GetBody() == "secret" {
+		t.Errorf("Expected the file outside the document root not to be served")
+	}
+	AssertStatus(t, srv.MustGet(t, "/missing.html"), 404)
+
+	if entries, err := os.ReadDir(workDir); err != nil || len(entries) != 0 {
+		t.Errorf("Expected nothing written to the working directory, got %d entries (%v)", len(entries), err)
+	}
+	if entries, err := os.ReadDir(root); err != nil || len(entries) != 3 {
+		t.Errorf("Expected nothing written to the document root, got %d entries (%v)", len(entries), err)
+	}
+}